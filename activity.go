@@ -0,0 +1,170 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"time"
+)
+
+// SpotifyActivity is the song a member is listening to on Spotify.
+type SpotifyActivity struct {
+	Song    string
+	Artists []string
+	Album   string
+	Start   time.Time
+	End     time.Time
+}
+
+// Activity is what a member is doing according to the presence cache.
+type Activity struct {
+	UserID string
+	// False when the bot doesn't receive presences or the member's presence isn't cached, every other field is empty then.
+	Available bool
+	Status    discordgo.Status
+	Game      *discordgo.Game  // What they're playing, streaming, watching or listening to besides Spotify, nil if nothing.
+	Spotify   *SpotifyActivity // The song they're listening to on Spotify, nil if nothing.
+	Custom    string           // Their custom status.
+}
+
+// PresencesAvailable reports if the session receives presences, it needs IntentsGuildPresences when intents are set
+// and the state must track them.
+func (bot *Bot) PresencesAvailable() bool {
+	if bot.Session.State == nil || !bot.Session.StateEnabled || !bot.Session.State.TrackPresences {
+		return false
+	}
+	intents := bot.Session.Identify.Intents
+	return intents == nil || *intents&discordgo.IntentsGuildPresences == discordgo.IntentsGuildPresences
+}
+
+// Activity reads a member's activity from the presence cache.
+// Check Available before using it, without presences every member looks like they're doing nothing.
+func (bot *Bot) Activity(guildID, userID string) *Activity {
+	activity := &Activity{UserID: userID}
+	if !bot.PresencesAvailable() {
+		return activity
+	}
+	presence, err := bot.Session.State.Presence(guildID, userID)
+	if err != nil || presence == nil {
+		// Offline members aren't in the cache.
+		return activity
+	}
+	activity.Available = true
+	activity.Status = presence.Status
+	games := presence.Activities
+	if len(games) == 0 && presence.Game != nil {
+		games = []*discordgo.Game{presence.Game}
+	}
+	for _, game := range games {
+		switch {
+		case game.Type == discordgo.GameTypeCustom:
+			activity.Custom = game.State
+		case game.Type == discordgo.GameTypeListening && game.Name == "Spotify":
+			activity.Spotify = spotifyActivity(game)
+		case activity.Game == nil:
+			activity.Game = game
+		}
+	}
+	return activity
+}
+
+// spotifyActivity reads the song from a Spotify activity, the artists are separated with "; "
+func spotifyActivity(game *discordgo.Game) *SpotifyActivity {
+	spotify := &SpotifyActivity{Song: game.Details, Album: game.Assets.LargeText}
+	if game.State != "" {
+		spotify.Artists = strings.Split(game.State, "; ")
+	}
+	if game.TimeStamps.StartTimestamp > 0 {
+		spotify.Start = time.Unix(0, game.TimeStamps.StartTimestamp*int64(time.Millisecond))
+	}
+	if game.TimeStamps.EndTimestamp > 0 {
+		spotify.End = time.Unix(0, game.TimeStamps.EndTimestamp*int64(time.Millisecond))
+	}
+	return spotify
+}
+
+// FormatGame formats what a member is playing e.g "Playing **Minecraft**"
+func (l *Language) FormatGame(game *discordgo.Game) string {
+	switch game.Type {
+	case discordgo.GameTypeStreaming:
+		return l.Get("ACTIVITY_STREAMING", game.Name, game.URL)
+	case discordgo.GameTypeListening:
+		return l.Get("ACTIVITY_LISTENING", game.Name)
+	case discordgo.GameTypeWatching:
+		return l.Get("ACTIVITY_WATCHING", game.Name)
+	}
+	return l.Get("ACTIVITY_PLAYING", game.Name)
+}
+
+// AsActivity returns the argument as an activity, see the activity argument type.
+func (arg *Argument) AsActivity() *Activity {
+	return arg.value.(*Activity)
+}
+
+func parseActivity(ctx *CommandContext, tag *UsageTag, raw string) (*Argument, error) {
+	member, err := parseMember(ctx, tag, raw)
+	if err != nil {
+		return nil, err
+	}
+	return arg(ctx.Bot.Activity(ctx.Guild.ID, member.AsMember().User.ID)), nil
+}
+
+// LoadStatusCommand loads the status command showing what a member is doing, their status, game, Spotify song
+// and custom status. It needs presences: IntentsGuildPresences, which is privileged, and State.TrackPresences.
+func (bot *Bot) LoadStatusCommand() *Bot {
+	return bot.AddCommand(NewCommand("status", "General", statusCommand).
+		SetDescription("Shows what a member is doing.").
+		SetUsage("[member:activity=author]").
+		AddAliases("activity", "presence").
+		SetGuildOnly(true))
+}
+
+func statusCommand(ctx *CommandContext) {
+	if !ctx.Bot.PresencesAvailable() {
+		ctx.ReplyLocale("COMMAND_STATUS_UNAVAILABLE")
+		return
+	}
+	activity := ctx.Arg(0).AsActivity()
+	member := ctx.Member(activity.UserID)
+	name := activity.UserID
+	if member != nil {
+		name = member.User.Username
+	}
+	if !activity.Available {
+		ctx.ReplyLocale("COMMAND_STATUS_OFFLINE", name)
+		return
+	}
+
+	locale := ctx.Locale
+	lines := []string{locale.Get("COMMAND_STATUS_STATUS", locale.GetDefault("STATUS_"+strings.ToUpper(string(activity.Status)), string(activity.Status)))}
+	if activity.Custom != "" {
+		lines = append(lines, locale.Get("COMMAND_STATUS_CUSTOM", Escape(activity.Custom)))
+	}
+	if activity.Game != nil {
+		lines = append(lines, locale.FormatGame(activity.Game))
+	}
+	if spotify := activity.Spotify; spotify != nil {
+		song := locale.Get("ACTIVITY_SPOTIFY", spotify.Song, strings.Join(spotify.Artists, ", "))
+		if !spotify.End.IsZero() {
+			length := spotify.End.Sub(spotify.Start)
+			position := time.Since(spotify.Start)
+			if position > length {
+				position = length
+			}
+			song += fmt.Sprintf(" (%s/%s)", formatClock(position), formatClock(length))
+		}
+		lines = append(lines, song)
+	}
+	ctx.BuildEmbed(NewEmbed().
+		SetTitle(name).
+		SetDescription(strings.Join(lines, "\n")).
+		SetColor(ctx.Bot.Color))
+}
+
+// formatClock formats a song position like 3:07
+func formatClock(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
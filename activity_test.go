@@ -0,0 +1,30 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"testing"
+	"time"
+)
+
+func TestActivity(t *testing.T) {
+	spotify := spotifyActivity(&discordgo.Game{Name: "Spotify", Type: discordgo.GameTypeListening, Details: "Song", State: "One; Two",
+		TimeStamps: discordgo.TimeStamps{StartTimestamp: 1000, EndTimestamp: 181000}})
+	if spotify.Song != "Song" || len(spotify.Artists) != 2 || spotify.End.Sub(spotify.Start) != 3*time.Minute {
+		t.Errorf("spotifyActivity = %+v", spotify)
+	}
+	if got := English.FormatGame(&discordgo.Game{Name: "Minecraft"}); got != "Playing **Minecraft**" {
+		t.Errorf("FormatGame = %q", got)
+	}
+
+	bot := &Bot{Session: &discordgo.Session{State: discordgo.NewState(), StateEnabled: true}}
+	bot.Session.State.TrackPresences = true
+	intents := discordgo.IntentsGuilds | discordgo.IntentsGuildMessages
+	bot.Session.Identify.Intents = &intents
+	if bot.PresencesAvailable() || bot.Activity("1", "2").Available {
+		t.Errorf("presences should be unavailable without the intent")
+	}
+	intents |= discordgo.IntentsGuildPresences
+	if !bot.PresencesAvailable() {
+		t.Errorf("presences should be available with the intent")
+	}
+}
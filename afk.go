@@ -0,0 +1,149 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"github.com/dustin/go-humanize"
+	"sync"
+	"time"
+)
+
+// AFKConfig is the per-guild config of the AFK module, see bot.EnableAFK
+var AFKConfig = NewConfigSchema("afk", "Lets members set an AFK message shown when they are mentioned.").
+	Add("enabled", ConfigBool, "true", "Whether AFK notices are sent in this server.").
+	Add("cooldown", ConfigInt, "60", "Seconds before the same member's AFK notice is sent again in a channel.")
+
+// afkKeyPrefix is the bot wide settings key prefix AFK statuses are stored under, followed by the user ID.
+const afkKeyPrefix = "afk."
+
+// afkGrace is how long after going AFK messages don't clear it, the afk command message itself races with the monitor.
+const afkGrace = 5 * time.Second
+
+// AFKStatus is a user's AFK status.
+type AFKStatus struct {
+	Message string    `json:"message"`
+	Since   time.Time `json:"since"`
+}
+
+type afkTracker struct {
+	// channel ID + user ID -> last notice
+	notices map[string]time.Time
+	lock    sync.Mutex
+}
+
+// EnableAFK loads the afk command and the monitor that sends AFK notices and clears the status when the user talks again.
+// AFK statuses are global to the user and kept in the settings provider, servers can turn the notices off with AFKConfig.
+func (bot *Bot) EnableAFK() *Bot {
+	if bot.afk != nil {
+		return bot
+	}
+	bot.afk = &afkTracker{notices: make(map[string]time.Time)}
+	bot.AddConfigSchema(AFKConfig)
+	bot.AddDataSubject(afkData(bot))
+	bot.AddMonitor(NewMonitor("afk", afkMonitor).SetGuildOnly(true))
+	bot.AddCommand(NewCommand("afk", "General", func(ctx *CommandContext) {
+		message := ctx.ArgString(0)
+		if message == "" {
+			message = "AFK"
+		}
+		if err := ctx.Bot.SetAFK(ctx.Author.ID, message); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_AFK_SET", Escape(message))
+	}).SetDescription("Sets you AFK, members mentioning you will be told you are away.").
+		SetUsage("[message:string...]").
+		SetCooldown(10))
+	return bot
+}
+
+// afkData is the data subject of the AFK statuses.
+func afkData(bot *Bot) DataSubject {
+	return &userData{
+		name: "afk",
+		export: func(userID string) (interface{}, error) {
+			status := &AFKStatus{}
+			if ok, err := GetJSON(bot.Settings, "", afkKeyPrefix+userID, status); err != nil || !ok {
+				return nil, err
+			}
+			return status, nil
+		},
+		delete: bot.ClearAFK,
+	}
+}
+
+// SetAFK sets the user AFK with the message.
+func (bot *Bot) SetAFK(userID, message string) error {
+	return SetJSON(bot.Settings, "", afkKeyPrefix+userID, &AFKStatus{Message: message, Since: time.Now()})
+}
+
+// ClearAFK removes the user's AFK status.
+func (bot *Bot) ClearAFK(userID string) error {
+	return bot.Settings.Delete("", afkKeyPrefix+userID)
+}
+
+// AFK returns the user's AFK status, nil if they are not AFK.
+func (bot *Bot) AFK(userID string) *AFKStatus {
+	status := &AFKStatus{}
+	if ok, err := GetJSON(bot.Settings, "", afkKeyPrefix+userID, status); err != nil || !ok {
+		return nil
+	}
+	return status
+}
+
+// canNotifyAFK checks and updates the notice cooldown of the user in the channel.
+func (bot *Bot) canNotifyAFK(channelID, userID string, cooldown time.Duration) bool {
+	bot.afk.lock.Lock()
+	defer bot.afk.lock.Unlock()
+	key := channelID + userID
+	if last, ok := bot.afk.notices[key]; ok && time.Since(last) < cooldown {
+		return false
+	}
+	bot.afk.notices[key] = time.Now()
+	// Old notices are useless after their cooldown, sweep them once in a while to not grow forever.
+	if len(bot.afk.notices) > 1000 {
+		for k, t := range bot.afk.notices {
+			if time.Since(t) > time.Hour {
+				delete(bot.afk.notices, k)
+			}
+		}
+	}
+	return true
+}
+
+func afkMonitor(bot *Bot, ctx *MonitorContext) {
+	if !AFKConfig.GetBool(bot, ctx.Guild.ID, "enabled") {
+		return
+	}
+	locale := bot.LocaleFor(ctx.Guild.ID, ctx.Channel.ID)
+
+	// They are talking so they are back.
+	if status := bot.AFK(ctx.Author.ID); status != nil && time.Since(status.Since) > afkGrace {
+		if err := bot.ClearAFK(ctx.Author.ID); err != nil {
+			bot.ErrorHandler(bot, err)
+			return
+		}
+		bot.SendLocale(ctx.Channel.ID, locale, "AFK_WELCOME_BACK", ctx.Author.Mention())
+	}
+
+	cooldown := time.Duration(AFKConfig.GetInt(bot, ctx.Guild.ID, "cooldown")) * time.Second
+	notified := make(map[string]bool)
+	for _, user := range ctx.Message.Mentions {
+		if user.ID == ctx.Author.ID || notified[user.ID] {
+			continue
+		}
+		notified[user.ID] = true
+		status := bot.AFK(user.ID)
+		if status == nil || !bot.canNotifyAFK(ctx.Channel.ID, user.ID, cooldown) {
+			continue
+		}
+		bot.SendLocale(ctx.Channel.ID, locale, "AFK_NOTICE", bot.afkName(ctx.Guild.ID, user), Escape(status.Message), humanize.Time(status.Since))
+	}
+}
+
+// afkName returns the member's nickname or username, used instead of a mention to not ping them.
+func (bot *Bot) afkName(guildID string, user *discordgo.User) string {
+	if member, err := bot.Session.State.Member(guildID, user.ID); err == nil && member.Nick != "" {
+		return member.Nick
+	}
+	return user.Username
+}
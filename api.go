@@ -0,0 +1,228 @@
+package sapphire
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultAPIPageSize and MaxAPIPageSize are the default and maximum ?limit of the read API.
+var (
+	DefaultAPIPageSize = 25
+	MaxAPIPageSize     = 100
+)
+
+// APIRecord is a record of an API resource, it's encoded as a JSON object.
+type APIRecord map[string]interface{}
+
+// APIResource lists the records of a guild for the read API, in the order they're paginated.
+type APIResource func(bot *Bot, guildID string) ([]APIRecord, error)
+
+// APIAuthorizer decides if a request may read a guild's data, e.g by checking a dashboard session.
+type APIAuthorizer func(r *http.Request, guildID string) bool
+
+// APITokenAuth authorizes requests sending "Authorization: Bearer <token>" for every guild.
+func APITokenAuth(token string) APIAuthorizer {
+	return func(r *http.Request, _ string) bool {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		return token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+	}
+}
+
+// AddAPIResource adds a resource to the read API served at /guilds/<id>/<name>, replacing the one with the same name.
+// Modules use it to expose their data to dashboards, e.g AddAPIResource("tags", ...), add them before calling APIHandler.
+func (bot *Bot) AddAPIResource(name string, resource APIResource) *Bot {
+	if bot.apiResources == nil {
+		bot.apiResources = make(map[string]APIResource)
+	}
+	bot.apiResources[name] = resource
+	return bot
+}
+
+// APIHandler returns the http handler of the read API for dashboards, it responds with JSON.
+// GET /guilds/<id> lists the guild's resources and GET /guilds/<id>/<resource> returns
+// {"items": [...], "total": n, "next_cursor": "..."}. ?limit sets the page size, pass next_cursor as ?cursor to get
+// the next page and ?fields=a,b only returns those fields of each item. Mount it with a prefix stripped
+// e.g http.Handle("/api/", http.StripPrefix("/api", bot.APIHandler(sapphire.APITokenAuth(token))))
+// The resources of modules enabled before calling it are added: settings, leaderboards/voice, leaderboards/counting
+// and tickets. There are no cases or tags modules, add resources for those with AddAPIResource.
+func (bot *Bot) APIHandler(auth APIAuthorizer) http.Handler {
+	builtins := map[string]APIResource{"settings": apiSettings}
+	if bot.voiceXP != nil {
+		builtins["leaderboards/voice"] = apiVoiceLeaderboard
+	}
+	if bot.counting != nil {
+		builtins["leaderboards/counting"] = apiCountingLeaderboard
+	}
+	if bot.tickets != nil {
+		builtins["tickets"] = apiTickets
+	}
+	for name, resource := range builtins {
+		if _, ok := bot.apiResources[name]; !ok {
+			bot.AddAPIResource(name, resource)
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			apiError(w, http.StatusMethodNotAllowed, "only GET is supported")
+			return
+		}
+		parts := strings.SplitN(strings.Trim(r.URL.Path, "/"), "/", 3)
+		if len(parts) < 2 || parts[0] != "guilds" || parts[1] == "" {
+			apiError(w, http.StatusNotFound, "not found")
+			return
+		}
+		guildID := parts[1]
+		if !auth(r, guildID) {
+			apiError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if len(parts) == 2 {
+			names := make([]string, 0, len(bot.apiResources))
+			for name := range bot.apiResources {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			apiJSON(w, map[string]interface{}{"resources": names})
+			return
+		}
+		resource, ok := bot.apiResources[parts[2]]
+		if !ok {
+			apiError(w, http.StatusNotFound, "unknown resource "+parts[2])
+			return
+		}
+
+		query := r.URL.Query()
+		limit, offset := DefaultAPIPageSize, 0
+		if value := query.Get("limit"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > MaxAPIPageSize {
+				apiError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(MaxAPIPageSize))
+				return
+			}
+			limit = n
+		}
+		if value := query.Get("cursor"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				apiError(w, http.StatusBadRequest, "invalid cursor")
+				return
+			}
+			offset = n
+		}
+
+		records, err := resource(bot, guildID)
+		if err != nil {
+			bot.ErrorHandler(bot, err)
+			apiError(w, http.StatusInternalServerError, "failed to read "+parts[2])
+			return
+		}
+		res := map[string]interface{}{"total": len(records)}
+		if offset > len(records) {
+			offset = len(records)
+		}
+		page := append([]APIRecord{}, records[offset:]...)
+		if len(page) > limit {
+			page = page[:limit]
+			res["next_cursor"] = strconv.Itoa(offset + limit)
+		}
+		if fields := query.Get("fields"); fields != "" {
+			page = selectAPIFields(page, strings.Split(fields, ","))
+		}
+		res["items"] = page
+		apiJSON(w, res)
+	})
+}
+
+// selectAPIFields returns copies of the records with only the given fields.
+func selectAPIFields(records []APIRecord, fields []string) []APIRecord {
+	selected := make([]APIRecord, len(records))
+	for i, record := range records {
+		selected[i] = make(APIRecord, len(fields))
+		for _, field := range fields {
+			if value, ok := record[strings.TrimSpace(field)]; ok {
+				selected[i][strings.TrimSpace(field)] = value
+			}
+		}
+	}
+	return selected
+}
+
+func apiJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func apiError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// apiSettings lists the config keys of the registered schemas with the guild's values.
+func apiSettings(bot *Bot, guildID string) ([]APIRecord, error) {
+	names := make([]string, 0, len(bot.ConfigSchemas))
+	for name := range bot.ConfigSchemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var records []APIRecord
+	for _, name := range names {
+		schema := bot.ConfigSchemas[name]
+		for _, key := range schema.Keys {
+			records = append(records, APIRecord{
+				"key":     schema.SettingsKey(key.Name),
+				"type":    key.Type,
+				"value":   schema.Get(bot, guildID, key.Name),
+				"default": key.Default,
+			})
+		}
+	}
+	return records, nil
+}
+
+func apiVoiceLeaderboard(bot *Bot, guildID string) ([]APIRecord, error) {
+	board, err := bot.VoiceLeaderboard(guildID, math.MaxInt32)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]APIRecord, len(board))
+	for i, stats := range board {
+		records[i] = APIRecord{"rank": i + 1, "user_id": stats.UserID, "minutes": stats.Minutes, "xp": stats.XP}
+	}
+	return records, nil
+}
+
+func apiCountingLeaderboard(bot *Bot, guildID string) ([]APIRecord, error) {
+	game, err := bot.CountingGame(guildID)
+	if err != nil {
+		return nil, err
+	}
+	board := game.Leaderboard(len(game.Scores))
+	records := make([]APIRecord, len(board))
+	for i, score := range board {
+		records[i] = APIRecord{"rank": i + 1, "user_id": score.UserID, "score": score.Score}
+	}
+	return records, nil
+}
+
+func apiTickets(bot *Bot, guildID string) ([]APIRecord, error) {
+	tickets, err := bot.Tickets(guildID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(tickets, func(i, j int) bool {
+		return tickets[i].Number < tickets[j].Number
+	})
+	records := make([]APIRecord, len(tickets))
+	for i, ticket := range tickets {
+		records[i] = APIRecord{"number": ticket.Number, "channel_id": ticket.ChannelID, "owner_id": ticket.OwnerID,
+			"subject": ticket.Subject, "opened_at": ticket.OpenedAt}
+	}
+	return records, nil
+}
@@ -0,0 +1,63 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIHandler(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	bot.AddConfigSchema(NewConfigSchema("automod", "").Add("enabled", ConfigBool, "false", "").Add("log", ConfigChannel, "", ""))
+	bot.AddAPIResource("tags", func(bot *Bot, guildID string) ([]APIRecord, error) {
+		var tags []APIRecord
+		for i := 0; i < 5; i++ {
+			tags = append(tags, APIRecord{"name": fmt.Sprintf("tag%d", i), "content": "..."})
+		}
+		return tags, nil
+	})
+	server := httptest.NewServer(bot.APIHandler(APITokenAuth("token")))
+	defer server.Close()
+
+	get := func(path string, v interface{}) int {
+		req, _ := http.NewRequest("GET", server.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer token")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		json.NewDecoder(res.Body).Decode(v)
+		return res.StatusCode
+	}
+	var page struct {
+		Items      []map[string]interface{} `json:"items"`
+		Total      int                      `json:"total"`
+		NextCursor string                   `json:"next_cursor"`
+	}
+	if status := get("/guilds/1/tags?limit=2&cursor=2&fields=name", &page); status != 200 || page.Total != 5 || page.NextCursor != "4" ||
+		len(page.Items) != 2 || page.Items[0]["name"] != "tag2" || page.Items[0]["content"] != nil {
+		t.Errorf("Unexpected page %d %+v", status, page)
+	}
+	page.NextCursor = ""
+	if get("/guilds/1/tags?cursor=4", &page); len(page.Items) != 1 || page.NextCursor != "" {
+		t.Errorf("Unexpected last page %+v", page)
+	}
+	if get("/guilds/1/settings", &page); page.Total != 2 || page.Items[0]["key"] != "automod.enabled" || page.Items[0]["value"] != "false" {
+		t.Errorf("Unexpected settings %+v", page)
+	}
+	if status := get("/guilds/1/cases", &page); status != 404 {
+		t.Errorf("Expected 404 for an unknown resource but got %d", status)
+	}
+	res, err := http.Get(server.URL + "/guilds/1/tags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 401 {
+		t.Errorf("Expected 401 without a token but got %d", res.StatusCode)
+	}
+}
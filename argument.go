@@ -3,8 +3,11 @@ package sapphire
 import (
 	"fmt"
 	"github.com/bwmarrin/discordgo"
+	"math"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // ----- Argument casting -----
@@ -19,7 +22,7 @@ type Argument struct {
 // And the errors are reported and the command execution is aborted, so the type will always be the type the user asked for.
 // But it can panic if the usage is specifying a different type than what the user used, in that case it's their fault.
 
-// We need to cover as much as types as possible because it isn't easy for the user to extend these.
+// Custom argument types added with RegisterArgumentType are read with AsType or Value.
 
 // Returns the argument as a string.
 func (arg *Argument) AsString() string {
@@ -63,10 +66,25 @@ func (arg *Argument) AsMessage() *discordgo.Message {
 	return arg.value.(*discordgo.Message)
 }
 
-func (arg *Argument) AsChannel() *discordgo.Channel{
+func (arg *Argument) AsChannel() *discordgo.Channel {
 	return arg.value.(*discordgo.Channel)
 }
 
+// AsDuration returns a duration argument.
+func (arg *Argument) AsDuration() time.Duration {
+	return arg.value.(time.Duration)
+}
+
+// Value returns the argument's value, e.g for custom argument types.
+func (arg *Argument) Value() interface{} {
+	return arg.value
+}
+
+// AsEmoji returns a custom emoji argument, only ID, Name and Animated are filled.
+func (arg *Argument) AsEmoji() *discordgo.Emoji {
+	return arg.value.(*discordgo.Emoji)
+}
+
 // ----- Argument parsing -----
 
 // quick helper so i don't repeat provided:true
@@ -80,69 +98,331 @@ var MentionRegex = regexp.MustCompile("^(?:<@!?)?(\\d{17,19})>?$")
 // The Regexp used for matching channel mentions.
 var ChannelMentionRegex = regexp.MustCompile("^(?:<#)?(\\d{17,19})>?$")
 
+// ArgumentParser parses a raw argument for the given tag in context of ctx.
+// Return an *ArgumentError for invalid input so it's replied in the user's language, see NewArgumentError
+type ArgumentParser func(ctx *CommandContext, tag *UsageTag, raw string) (*Argument, error)
+
+// NewArgument creates a provided argument with the value, for custom argument parsers.
+func NewArgument(value interface{}) *Argument {
+	return arg(value)
+}
+
+// ArgumentError is an invalid argument, it's replied to the user in their language along with the command's usage.
+type ArgumentError struct {
+	Key  string        // The locale key of the message.
+	Args []interface{} // Format arguments of the message, usually starting with the tag's name.
+}
+
+// NewArgumentError creates an ArgumentError, the message is the locale key formatted with args.
+func NewArgumentError(key string, args ...interface{}) *ArgumentError {
+	return &ArgumentError{Key: key, Args: args}
+}
+
+// Error implements error with the message in the default locale.
+func (e *ArgumentError) Error() string {
+	return English.Get(e.Key, e.Args...)
+}
+
+// RegisterArgumentType adds a usage type for all commands e.g RegisterArgumentType("color", parseColor)
+// makes <color:color> work, existing types can be replaced too. Register types before adding the commands using them.
+func RegisterArgumentType(name string, parser ArgumentParser) {
+	argumentParsers[name] = parser
+}
+
+// argumentParsers maps the usage types to their parsers, CompileUsage resolves tags against this.
+var argumentParsers = map[string]ArgumentParser{
+	"str":      parseString,
+	"string":   parseString,
+	"num":      parseInt,
+	"number":   parseInt,
+	"int":      parseInt,
+	"float":    parseFloat,
+	"bool":     parseBool,
+	"duration": parseDuration,
+	"role":     parseRole,
+	"member":   parseMember,
+	"user":     parseUser,
+	"chan":     parseChannel,
+	"channel":  parseChannel,
+	"literal":  parseLiteral,
+	"emoji":    parseEmoji,
+	"id":       parseSnowflake,
+	"size":     parseSize,
+	"percent":  parsePercent,
+	"activity": parseActivity,
+}
+
 // Parses the raw argument as specified in tag in context of ctx
 func ParseArgument(ctx *CommandContext, tag *UsageTag, raw string) (*Argument, error) {
 	if raw == "" {
 		return &Argument{provided: false}, nil
 	}
+	parser := tag.parser
+	if parser == nil {
+		// The tag wasn't compiled, e.g it came from ParseUsage directly, so look it up now.
+		p, ok := argumentParsers[tag.Type]
+		if !ok {
+			return nil, fmt.Errorf("The argument type '%s' is invalid.", tag.Type)
+		}
+		parser = p
+	}
+	arg, err := parser(ctx, tag, raw)
+	if err != nil {
+		return nil, err
+	}
+	return arg, checkRange(tag, arg)
+}
+
+// checkRange checks a parsed argument against the tag's {min,max} range. Numbers are compared by value,
+// strings by length and durations in seconds.
+func checkRange(tag *UsageTag, arg *Argument) error {
+	if tag.Min == nil && tag.Max == nil {
+		return nil
+	}
+	var n float64
+	unit := ""
+	switch value := arg.value.(type) {
+	case int:
+		n = float64(value)
+	case float64:
+		n = value
+	case string:
+		n, unit = float64(len([]rune(value))), "characters"
+	case time.Duration:
+		n, unit = value.Seconds(), "seconds"
+	default:
+		return nil
+	}
+	if tag.Min != nil && n < *tag.Min {
+		return NewArgumentError("ARGUMENT_TOO_SMALL"+rangeSuffix(unit), tag.Name, formatBound(*tag.Min, unit))
+	}
+	if tag.Max != nil && n > *tag.Max {
+		return NewArgumentError("ARGUMENT_TOO_LARGE"+rangeSuffix(unit), tag.Name, formatBound(*tag.Max, unit))
+	}
+	return nil
+}
+
+func rangeSuffix(unit string) string {
+	if unit == "characters" {
+		return "_LENGTH"
+	}
+	return ""
+}
+
+func formatBound(n float64, unit string) string {
+	if unit == "seconds" {
+		return (time.Duration(n) * time.Second).String()
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// ResolveDefault resolves the default of an optional tag that wasn't provided.
+// Contextual defaults are resolved from the context, author/self/me on user, member and activity types resolve to the invoker
+// and current/here on channel types resolve to the channel the command was ran on.
+// Any other default is parsed as if the user typed it, e.g [count:int=10]
+// Returns a non-provided argument if the tag has no default.
+func ResolveDefault(ctx *CommandContext, tag *UsageTag) (*Argument, error) {
+	if tag.Default == "" {
+		return &Argument{provided: false}, nil
+	}
 	switch tag.Type {
-	case "str":
-		fallthrough
-	case "string":
-		return arg(raw), nil
-	case "num":
-		fallthrough
-	case "number":
-		fallthrough
-	case "int":
-		val, err := strconv.Atoi(raw)
-		return arg(val), err
+	case "user":
+		if isSelfDefault(tag.Default) {
+			return arg(ctx.Author), nil
+		}
 	case "member":
-		match := MentionRegex.FindStringSubmatch(raw)
-		if len(match) < 2 {
-			return nil, fmt.Errorf("**%s** must be a valid member mention or ID.", tag.Name)
+		if isSelfDefault(tag.Default) {
+			member := ctx.Member(ctx.Author.ID)
+			if member == nil {
+				// Outside a guild there's no member to default to.
+				return &Argument{provided: false}, nil
+			}
+			return arg(member), nil
+		}
+	case "activity":
+		if isSelfDefault(tag.Default) {
+			if ctx.Guild == nil {
+				return &Argument{provided: false}, nil
+			}
+			return arg(ctx.Bot.Activity(ctx.Guild.ID, ctx.Author.ID)), nil
+		}
+	case "chan", "channel":
+		if tag.Default == "current" || tag.Default == "here" {
+			return arg(ctx.Channel), nil
+		}
+	}
+	return ParseArgument(ctx, tag, tag.Default)
+}
+
+func isSelfDefault(def string) bool {
+	return def == "author" || def == "self" || def == "me"
+}
+
+func parseString(_ *CommandContext, _ *UsageTag, raw string) (*Argument, error) {
+	return arg(raw), nil
+}
+
+func parseInt(_ *CommandContext, tag *UsageTag, raw string) (*Argument, error) {
+	val, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, NewArgumentError("ARGUMENT_INT_INVALID", tag.Name)
+	}
+	return arg(val), nil
+}
+
+func parseFloat(_ *CommandContext, tag *UsageTag, raw string) (*Argument, error) {
+	val, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(val) || math.IsInf(val, 0) {
+		return nil, NewArgumentError("ARGUMENT_FLOAT_INVALID", tag.Name)
+	}
+	return arg(val), nil
+}
+
+func parseBool(_ *CommandContext, tag *UsageTag, raw string) (*Argument, error) {
+	switch strings.ToLower(raw) {
+	case "true", "yes", "y", "on", "enable", "enabled", "1":
+		return arg(true), nil
+	case "false", "no", "n", "off", "disable", "disabled", "0":
+		return arg(false), nil
+	}
+	return nil, NewArgumentError("ARGUMENT_BOOL_INVALID", tag.Name)
+}
+
+func parseDuration(_ *CommandContext, tag *UsageTag, raw string) (*Argument, error) {
+	d, err := ParseDuration(raw)
+	if err != nil {
+		return nil, NewArgumentError("ARGUMENT_DURATION_INVALID", tag.Name)
+	}
+	return arg(d), nil
+}
+
+func parseMember(ctx *CommandContext, tag *UsageTag, raw string) (*Argument, error) {
+	match := MentionRegex.FindStringSubmatch(raw)
+	if len(match) < 2 {
+		member, err := ctx.resolveMember(tag, raw)
+		if err != nil {
+			return nil, err
 		}
-		member := ctx.Member(match[1])
 		if member == nil {
-			return nil, fmt.Errorf("That member cannot be found in this server.")
+			return nil, NewArgumentError("ARGUMENT_MEMBER_INVALID", tag.Name)
 		}
 		return arg(member), nil
-	case "user":
-		match := MentionRegex.FindStringSubmatch(raw)
+	}
+	member := ctx.Member(match[1])
+	if member == nil {
+		return nil, NewArgumentError("ARGUMENT_MEMBER_NOT_FOUND")
+	}
+	return arg(member), nil
+}
 
-		if len(match) < 2 {
-			return nil, fmt.Errorf("**%s** must be a valid user mention or ID.", tag.Name)
+// RoleMentionRegex is the Regexp used for matching role mentions.
+var RoleMentionRegex = regexp.MustCompile("^(?:<@&)?(\\d{17,19})>?$")
+
+func parseRole(ctx *CommandContext, tag *UsageTag, raw string) (*Argument, error) {
+	if ctx.Guild == nil {
+		return nil, NewArgumentError("ARGUMENT_ROLE_NOT_FOUND")
+	}
+	match := RoleMentionRegex.FindStringSubmatch(raw)
+	if len(match) < 2 {
+		role, err := ctx.resolveRole(tag, raw)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return nil, NewArgumentError("ARGUMENT_ROLE_INVALID", tag.Name)
+		}
+		return arg(role), nil
+	}
+	for _, role := range ctx.Guild.Roles {
+		if role.ID == match[1] {
+			return arg(role), nil
 		}
+	}
+	return nil, NewArgumentError("ARGUMENT_ROLE_NOT_FOUND")
+}
 
-		user, _ := ctx.FetchUser(match[1])
+func parseUser(ctx *CommandContext, tag *UsageTag, raw string) (*Argument, error) {
+	match := MentionRegex.FindStringSubmatch(raw)
 
-		if user == nil {
-			return nil, fmt.Errorf("That user cannot be found.")
+	if len(match) < 2 {
+		// Users outside the guild can only be given by mention or ID.
+		member, err := ctx.resolveMember(tag, raw)
+		if err != nil {
+			return nil, err
+		}
+		if member == nil {
+			return nil, NewArgumentError("ARGUMENT_USER_INVALID", tag.Name)
 		}
+		return arg(member.User), nil
+	}
 
-		return arg(user), nil
-	case "chan":
-		fallthrough // Alias
-	case "channel":
-		match := ChannelMentionRegex.FindStringSubmatch(raw)
+	user, _ := ctx.FetchUser(match[1])
 
-		if len(match) < 2 {
-			return nil, fmt.Errorf("**%s** must be a valid channel mention or ID.", tag.Name)
-		}
+	if user == nil {
+		return nil, NewArgumentError("ARGUMENT_USER_NOT_FOUND")
+	}
+
+	return arg(user), nil
+}
 
-		channel, _ := ctx.Session.State.Channel(match[1])
+func parseChannel(ctx *CommandContext, tag *UsageTag, raw string) (*Argument, error) {
+	match := ChannelMentionRegex.FindStringSubmatch(raw)
 
+	if len(match) < 2 {
+		channel, err := ctx.resolveChannel(tag, raw)
+		if err != nil {
+			return nil, err
+		}
 		if channel == nil {
-			return nil, fmt.Errorf("That channel cannot be found.")
+			return nil, NewArgumentError("ARGUMENT_CHANNEL_INVALID", tag.Name)
 		}
-
 		return arg(channel), nil
-	case "literal":
-		if raw != tag.Name {
-			return nil, fmt.Errorf("Literal argument must be **%s**", tag.Name)
-		}
-		return arg(raw), nil
-	default:
-		return nil, fmt.Errorf("The argument type '%s' is invalid.", tag.Type)
 	}
+
+	channel, _ := ctx.Session.State.Channel(match[1])
+
+	if channel == nil {
+		return nil, NewArgumentError("ARGUMENT_CHANNEL_NOT_FOUND")
+	}
+
+	return arg(channel), nil
+}
+
+func parseSnowflake(_ *CommandContext, tag *UsageTag, raw string) (*Argument, error) {
+	id, err := ParseSnowflake(raw)
+	if err != nil {
+		return nil, NewArgumentError("ARGUMENT_ID_INVALID", tag.Name)
+	}
+	return arg(id), nil
+}
+
+func parseSize(_ *CommandContext, tag *UsageTag, raw string) (*Argument, error) {
+	size, err := ParseBytes(raw)
+	if err != nil || size > math.MaxInt32 {
+		return nil, NewArgumentError("ARGUMENT_SIZE_INVALID", tag.Name)
+	}
+	return arg(int(size)), nil
+}
+
+func parsePercent(_ *CommandContext, tag *UsageTag, raw string) (*Argument, error) {
+	percent, err := ParsePercent(raw)
+	if err != nil {
+		return nil, NewArgumentError("ARGUMENT_PERCENT_INVALID", tag.Name)
+	}
+	return arg(percent), nil
+}
+
+func parseLiteral(_ *CommandContext, tag *UsageTag, raw string) (*Argument, error) {
+	if raw != tag.Name {
+		return nil, NewArgumentError("ARGUMENT_LITERAL", tag.Name)
+	}
+	return arg(raw), nil
+}
+
+func parseEmoji(_ *CommandContext, tag *UsageTag, raw string) (*Argument, error) {
+	emoji := ParseEmoji(raw)
+	if emoji == nil {
+		return nil, NewArgumentError("ARGUMENT_EMOJI_INVALID", tag.Name)
+	}
+	return arg(emoji), nil
 }
@@ -0,0 +1,193 @@
+package sapphire
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"path"
+	"strings"
+	"sync"
+)
+
+// ScanVerdict is the result of scanning an attachment, higher is worse.
+type ScanVerdict int
+
+const (
+	VerdictClean      ScanVerdict = iota
+	VerdictBlocked                // Not allowed by the server's rules e.g too big or an executable.
+	VerdictSuspicious             // Might be harmful, staff should look at it.
+	VerdictMalicious              // Known to be harmful.
+)
+
+// String returns the name of the verdict.
+func (v ScanVerdict) String() string {
+	switch v {
+	case VerdictBlocked:
+		return "blocked"
+	case VerdictSuspicious:
+		return "suspicious"
+	case VerdictMalicious:
+		return "malicious"
+	default:
+		return "clean"
+	}
+}
+
+// MaxScanSize is the maximum amount of bytes of an attachment downloaded for scanners.
+const MaxScanSize = 25 * 1024 * 1024
+
+// ScanFile is an attachment being scanned, the file is only downloaded if a scanner needs it.
+type ScanFile struct {
+	Attachment *discordgo.MessageAttachment
+	data       []byte
+	err        error
+	loaded     bool
+}
+
+// Data downloads the attachment once and returns it, up to MaxScanSize bytes.
+func (f *ScanFile) Data() ([]byte, error) {
+	if !f.loaded {
+		f.data, f.err = downloadAttachment(f.Attachment, MaxScanSize)
+		f.loaded = true
+	}
+	return f.data, f.err
+}
+
+// Ext returns the lowercased extension of the file e.g ".exe"
+func (f *ScanFile) Ext() string {
+	return strings.ToLower(path.Ext(f.Attachment.Filename))
+}
+
+// AttachmentScanner scans an attachment, the reason is shown to staff and the member if it's not clean.
+type AttachmentScanner interface {
+	Scan(file *ScanFile) (verdict ScanVerdict, reason string, err error)
+}
+
+// AttachmentScannerFunc is a function implementing AttachmentScanner, e.g to call an antivirus API.
+type AttachmentScannerFunc func(file *ScanFile) (ScanVerdict, string, error)
+
+// Scan implements AttachmentScanner
+func (fn AttachmentScannerFunc) Scan(file *ScanFile) (ScanVerdict, string, error) {
+	return fn(file)
+}
+
+// AttachmentLimits blocks attachments by size and type.
+type AttachmentLimits struct {
+	MaxSize int      // Maximum size in bytes, 0 for no limit. ParseBytes parses sizes like "8MB".
+	Allowed []string // Only these extensions are allowed e.g ".png", empty allows everything not blocked.
+	Blocked []string // These extensions are blocked e.g ".exe"
+}
+
+// Scan implements AttachmentScanner
+func (l *AttachmentLimits) Scan(file *ScanFile) (ScanVerdict, string, error) {
+	if l.MaxSize > 0 && file.Attachment.Size > l.MaxSize {
+		return VerdictBlocked, fmt.Sprintf("%s is too big (%s), the limit is %s", file.Attachment.Filename,
+			English.FormatBytes(int64(file.Attachment.Size)), English.FormatBytes(int64(l.MaxSize))), nil
+	}
+	ext := file.Ext()
+	for _, blocked := range l.Blocked {
+		if strings.EqualFold(blocked, ext) {
+			return VerdictBlocked, fmt.Sprintf("%s files are not allowed", ext), nil
+		}
+	}
+	if len(l.Allowed) == 0 {
+		return VerdictClean, "", nil
+	}
+	for _, allowed := range l.Allowed {
+		if strings.EqualFold(allowed, ext) {
+			return VerdictClean, "", nil
+		}
+	}
+	return VerdictBlocked, fmt.Sprintf("%s files are not allowed", ext), nil
+}
+
+// HashBlocklist flags attachments by the SHA-256 hash of their content, e.g known malware samples.
+type HashBlocklist struct {
+	hashes map[string]bool
+	lock   sync.RWMutex
+}
+
+// NewHashBlocklist creates a blocklist with hex encoded SHA-256 hashes.
+func NewHashBlocklist(hashes ...string) *HashBlocklist {
+	list := &HashBlocklist{hashes: make(map[string]bool)}
+	list.Add(hashes...)
+	return list
+}
+
+// Add adds hex encoded SHA-256 hashes to the blocklist.
+func (l *HashBlocklist) Add(hashes ...string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for _, hash := range hashes {
+		l.hashes[strings.ToLower(hash)] = true
+	}
+}
+
+// Scan implements AttachmentScanner
+func (l *HashBlocklist) Scan(file *ScanFile) (ScanVerdict, string, error) {
+	data, err := file.Data()
+	if err != nil {
+		return VerdictClean, "", err
+	}
+	sum := sha256.Sum256(data)
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	if l.hashes[hex.EncodeToString(sum[:])] {
+		return VerdictMalicious, fmt.Sprintf("%s is a known malicious file", file.Attachment.Filename), nil
+	}
+	return VerdictClean, "", nil
+}
+
+// AttachmentRule is an automod rule running scanners on every attachment, the worst verdict decides the actions.
+type AttachmentRule struct {
+	Scanners []AttachmentScanner
+	// Actions per verdict, a verdict without actions is ignored.
+	// (default: blocked deletes and warns, suspicious alerts, malicious deletes and alerts)
+	Actions map[ScanVerdict]AutomodAction
+}
+
+// NewAttachmentRule creates a rule with the scanners and the default actions.
+func NewAttachmentRule(scanners ...AttachmentScanner) *AttachmentRule {
+	return &AttachmentRule{
+		Scanners: scanners,
+		Actions: map[ScanVerdict]AutomodAction{
+			VerdictBlocked:    AutomodDelete | AutomodWarn,
+			VerdictSuspicious: AutomodAlert,
+			VerdictMalicious:  AutomodDelete | AutomodAlert,
+		},
+	}
+}
+
+// SetAction sets what is done for a verdict.
+func (r *AttachmentRule) SetAction(verdict ScanVerdict, actions AutomodAction) *AttachmentRule {
+	r.Actions[verdict] = actions
+	return r
+}
+
+// Name implements AutomodRule
+func (r *AttachmentRule) Name() string {
+	return "attachments"
+}
+
+// Check implements AutomodRule
+func (r *AttachmentRule) Check(ctx *AutomodContext) *Violation {
+	worst, reason := VerdictClean, ""
+	for _, attachment := range ctx.Message.Attachments {
+		file := &ScanFile{Attachment: attachment}
+		for _, scanner := range r.Scanners {
+			verdict, why, err := scanner.Scan(file)
+			if err != nil {
+				ctx.Bot.ErrorHandler(ctx.Bot, err)
+				continue
+			}
+			if verdict > worst {
+				worst, reason = verdict, why
+			}
+		}
+	}
+	if worst == VerdictClean || r.Actions[worst] == 0 {
+		return nil
+	}
+	return &Violation{Reason: reason, Actions: r.Actions[worst]}
+}
@@ -0,0 +1,44 @@
+package sapphire
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAttachmentScanners(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("malware"))
+	}))
+	defer server.Close()
+
+	limits := &AttachmentLimits{MaxSize: 1024, Blocked: []string{".exe"}}
+	// Some other file.
+	hashes := NewHashBlocklist("2D03A0A9A9DE5A2E6B4F5F5C1ABCA7ED4E1B1F2E2D2C9B3B2BDE4D6E1F0A7B6C")
+	scan := func(scanner AttachmentScanner, name string, size int) ScanVerdict {
+		verdict, _, err := scanner.Scan(&ScanFile{Attachment: &discordgo.MessageAttachment{Filename: name, Size: size, URL: server.URL}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return verdict
+	}
+	if v := scan(limits, "setup.EXE", 10); v != VerdictBlocked {
+		t.Errorf("Expected an executable to be blocked, got %s", v)
+	}
+	if v := scan(limits, "cat.png", 4096); v != VerdictBlocked {
+		t.Errorf("Expected a big file to be blocked, got %s", v)
+	}
+	if v := scan(limits, "cat.png", 10); v != VerdictClean {
+		t.Errorf("Expected a small image to be clean, got %s", v)
+	}
+	if v := scan(hashes, "cat.png", 10); v != VerdictClean {
+		t.Errorf("Expected an unknown hash to be clean, got %s", v)
+	}
+	hashes.Add(fmt.Sprintf("%x", sha256.Sum256([]byte("malware"))))
+	if v := scan(hashes, "cat.png", 10); v != VerdictMalicious {
+		t.Errorf("Expected a known hash to be malicious, got %s", v)
+	}
+}
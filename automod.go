@@ -0,0 +1,241 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"sync"
+	"time"
+)
+
+// AutomodConfig is the per-guild config of automod, see bot.EnableAutomod
+var AutomodConfig = NewConfigSchema("automod", "Automatic moderation of messages.").
+	Add("enabled", ConfigBool, "false", "Whether automod checks messages in this server.").
+	Add("log", ConfigChannel, "", "Channel alerts about violations are sent to.").
+	Add("staff", ConfigRole, "", "Role pinged by alerts, members with it are not checked.").
+	Add("allowlist", ConfigString, "", "Domains that are never treated as malicious, separated by spaces.")
+
+// AutomodAction is what automod does about a violation, actions can be combined e.g AutomodDelete|AutomodAlert
+type AutomodAction int
+
+const (
+	AutomodDelete AutomodAction = 1 << iota // Delete the message.
+	AutomodWarn                             // Tell the member in the channel.
+	AutomodAlert                            // Send an alert to the log channel.
+	AutomodKick                             // Kick the member.
+	AutomodBan                              // Ban the member, deleting a day of their messages.
+)
+
+// Violation is a broken automod rule.
+type Violation struct {
+	Rule    string
+	Reason  string // Shown to the member and staff.
+	Actions AutomodAction
+	Heat    float64 // Heat added by the violation, 0 uses the rule's weight. See bot.EnableHeat
+}
+
+// AutomodContext is the message being checked by automod rules.
+type AutomodContext struct {
+	Bot     *Bot
+	Message *discordgo.Message
+	Guild   *discordgo.Guild
+	Channel *discordgo.Channel
+	Member  *discordgo.Member
+	text    *string
+}
+
+// Text returns the content of the message followed by the text in its images, see bot.MessageText
+// It's extracted once and shared between rules.
+func (ctx *AutomodContext) Text() string {
+	if ctx.text == nil {
+		text := ctx.Bot.MessageText(ctx.Message)
+		ctx.text = &text
+	}
+	return *ctx.text
+}
+
+// AutomodRule checks messages, return a violation if the message breaks the rule or nil.
+type AutomodRule interface {
+	Name() string
+	Check(ctx *AutomodContext) *Violation
+}
+
+type funcRule struct {
+	name  string
+	check func(ctx *AutomodContext) *Violation
+}
+
+func (r *funcRule) Name() string                         { return r.name }
+func (r *funcRule) Check(ctx *AutomodContext) *Violation { return r.check(ctx) }
+
+// NewAutomodRule creates a rule from a function.
+func NewAutomodRule(name string, check func(ctx *AutomodContext) *Violation) AutomodRule {
+	return &funcRule{name: name, check: check}
+}
+
+// ViolationHandler is called for every violation after it's actions were taken.
+type ViolationHandler func(ctx *AutomodContext, v *Violation)
+
+type automodTracker struct {
+	rules    []AutomodRule
+	handlers []ViolationHandler
+	lock     sync.RWMutex
+}
+
+// EnableAutomod loads automod, rules added with bot.AddAutomodRule check every message and edit in guilds that
+// enabled it with AutomodConfig. The first rule a message breaks decides the actions, staff with the configured role
+// or the Manage Messages permission are not checked.
+func (bot *Bot) EnableAutomod() *Bot {
+	if bot.automod != nil {
+		return bot
+	}
+	bot.automod = &automodTracker{}
+	bot.AddConfigSchema(AutomodConfig)
+	bot.AddMonitor(NewMonitor("automod", automodMonitor).SetGuildOnly(true).AllowEdits())
+	return bot
+}
+
+// AddAutomodRule adds a rule checked by automod, rules are checked in the order they were added.
+func (bot *Bot) AddAutomodRule(rule AutomodRule) *Bot {
+	bot.EnableAutomod()
+	bot.automod.lock.Lock()
+	bot.automod.rules = append(bot.automod.rules, rule)
+	bot.automod.lock.Unlock()
+	return bot
+}
+
+// OnViolation adds a handler called for every automod violation.
+func (bot *Bot) OnViolation(handler ViolationHandler) *Bot {
+	bot.EnableAutomod()
+	bot.automod.lock.Lock()
+	bot.automod.handlers = append(bot.automod.handlers, handler)
+	bot.automod.lock.Unlock()
+	return bot
+}
+
+// automodExempt checks if the member is staff.
+func (bot *Bot) automodExempt(guild *discordgo.Guild, member *discordgo.Member) bool {
+	if member == nil {
+		return false
+	}
+	if staff := AutomodConfig.Get(bot, guild.ID, "staff"); staff != "" && hasRole(member, staff) {
+		return true
+	}
+	return PermissionsForMember(guild, member).Has(discordgo.PermissionManageMessages)
+}
+
+func automodMonitor(bot *Bot, ctx *MonitorContext) {
+	if !AutomodConfig.GetBool(bot, ctx.Guild.ID, "enabled") {
+		return
+	}
+	member := ctx.Message.Member
+	if member != nil && member.User == nil {
+		// Members of message events come without the user.
+		copied := *member
+		copied.User = ctx.Author
+		member = &copied
+	}
+	if bot.automodExempt(ctx.Guild, member) {
+		return
+	}
+	actx := &AutomodContext{Bot: bot, Message: ctx.Message, Guild: ctx.Guild, Channel: ctx.Channel, Member: member}
+	bot.automod.lock.RLock()
+	rules := bot.automod.rules
+	bot.automod.lock.RUnlock()
+	for _, rule := range rules {
+		if v := rule.Check(actx); v != nil {
+			if v.Rule == "" {
+				v.Rule = rule.Name()
+			}
+			bot.HandleViolation(actx, v)
+			return
+		}
+	}
+}
+
+// HandleViolation takes the actions of a violation and calls the violation handlers.
+// Rules don't need this, it's for reporting violations found outside of automod rules.
+func (bot *Bot) HandleViolation(ctx *AutomodContext, v *Violation) {
+	guildID := ctx.Guild.ID
+	user := ctx.Message.Author
+	locale := bot.LocaleFor(guildID, ctx.Channel.ID)
+	reason := fmt.Sprintf("Automod: %s", v.Reason)
+
+	if v.Actions&AutomodDelete != 0 && ctx.Message.ID != "" {
+		if err := bot.Session.ChannelMessageDelete(ctx.Channel.ID, ctx.Message.ID); err != nil {
+			bot.ErrorHandler(bot, err)
+		}
+	}
+	if v.Actions&AutomodWarn != 0 {
+		bot.SendLocale(ctx.Channel.ID, locale, "AUTOMOD_WARN", user.Mention(), v.Reason)
+	}
+	if v.Actions&AutomodAlert != 0 {
+		bot.automodAlert(ctx, v, locale)
+	}
+	if v.Actions&AutomodBan != 0 {
+		if err := bot.Session.GuildBanCreateWithReason(guildID, user.ID, reason, 1); err != nil {
+			bot.ErrorHandler(bot, err)
+		}
+	} else if v.Actions&AutomodKick != 0 {
+		if err := bot.Session.GuildMemberDeleteWithReason(guildID, user.ID, reason); err != nil {
+			bot.ErrorHandler(bot, err)
+		}
+	}
+
+	for _, action := range []struct {
+		flag AutomodAction
+		name string
+	}{{AutomodDelete, "delete"}, {AutomodWarn, "warn"}, {AutomodKick, "kick"}, {AutomodBan, "ban"}} {
+		// Ban wins over kick like above.
+		if v.Actions&action.flag == 0 || action.flag == AutomodKick && v.Actions&AutomodBan != 0 {
+			continue
+		}
+		bot.Publish(&ModerationAction{
+			GuildID:     guildID,
+			ChannelID:   ctx.Channel.ID,
+			UserID:      user.ID,
+			ModeratorID: bot.Session.State.User.ID,
+			Action:      action.name,
+			Reason:      v.Reason,
+			Source:      "automod",
+			At:          time.Now(),
+		})
+	}
+
+	if bot.automod == nil {
+		return
+	}
+	bot.automod.lock.RLock()
+	handlers := bot.automod.handlers
+	bot.automod.lock.RUnlock()
+	for _, handler := range handlers {
+		handler(ctx, v)
+	}
+}
+
+// automodAlert posts a violation to the log channel.
+func (bot *Bot) automodAlert(ctx *AutomodContext, v *Violation, locale *Language) {
+	logChannel := AutomodConfig.Get(bot, ctx.Guild.ID, "log")
+	if logChannel == "" {
+		return
+	}
+	user := ctx.Message.Author
+	embed := NewEmbed().
+		SetAuthor(fmt.Sprintf("%s#%s", user.Username, user.Discriminator), user.AvatarURL("64")).
+		SetTitle(locale.Get("AUTOMOD_ALERT_TITLE", v.Rule)).
+		SetDescription(ctx.Message.Content).
+		SetColor(0xE74C3C).
+		AddInlineField(locale.Get("AUTOMOD_ALERT_USER"), user.Mention()).
+		AddInlineField(locale.Get("AUTOMOD_ALERT_CHANNEL"), "<#"+ctx.Channel.ID+">").
+		AddField(locale.Get("AUTOMOD_ALERT_REASON"), v.Reason).
+		SetFooter("ID: " + user.ID).
+		TruncateDescription().
+		Build()
+	msg := &discordgo.MessageSend{Embed: embed, AllowedMentions: &discordgo.MessageAllowedMentions{Parse: []discordgo.AllowedMentionType{}}}
+	if staff := AutomodConfig.Get(bot, ctx.Guild.ID, "staff"); staff != "" {
+		msg.Content = "<@&" + staff + ">"
+		msg.AllowedMentions.Roles = []string{staff}
+	}
+	if _, err := bot.Session.ChannelMessageSendComplex(logChannel, msg); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+}
@@ -0,0 +1,537 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Limits of a guild's custom automod rules.
+var (
+	MaxCustomRules      = 25  // Maximum amount of custom rules of a guild.
+	MaxCustomRuleLength = 500 // Maximum length of a custom rule.
+)
+
+// CustomRule is an automod rule written by a guild's staff, compiled from a source like
+// `content contains "discord.gg" and author.joined < 7 => delete|warn "No invites from new members"`
+// See CompileCustomRule for the language.
+type CustomRule struct {
+	Name    string
+	Source  string
+	Actions AutomodAction
+	Reason  string // Optional reason shown to the member and staff.
+	cond    ruleCond
+}
+
+type ruleCond func(ctx *AutomodContext) bool
+
+// Check implements AutomodRule
+func (r *CustomRule) Check(ctx *AutomodContext) *Violation {
+	if !r.cond(ctx) {
+		return nil
+	}
+	reason := r.Reason
+	if reason == "" {
+		reason = ctx.Bot.LocaleFor(ctx.Guild.ID, ctx.Channel.ID).Get("AUTOMOD_CUSTOM", r.Name)
+	}
+	return &Violation{Rule: r.Name, Reason: reason, Actions: r.Actions}
+}
+
+// ruleField is a value of the message rules can check, exactly one of the functions is set.
+type ruleField struct {
+	number func(ctx *AutomodContext) float64
+	text   func(ctx *AutomodContext) string
+	set    func(ctx *AutomodContext) []string // Matches if any of the values does.
+}
+
+var ruleFields = map[string]ruleField{
+	"content": {text: func(ctx *AutomodContext) string { return ctx.Text() }},
+	"content.length": {number: func(ctx *AutomodContext) float64 {
+		return float64(len([]rune(ctx.Message.Content)))
+	}},
+	"mentions": {number: func(ctx *AutomodContext) float64 {
+		return float64(len(ctx.Message.Mentions) + len(ctx.Message.MentionRoles))
+	}},
+	"links": {number: func(ctx *AutomodContext) float64 { return float64(len(LinkDomains(ctx.Text()))) }},
+	"attachments": {number: func(ctx *AutomodContext) float64 {
+		return float64(len(ctx.Message.Attachments))
+	}},
+	"attachment.ext": {set: func(ctx *AutomodContext) []string {
+		exts := make([]string, len(ctx.Message.Attachments))
+		for i, attachment := range ctx.Message.Attachments {
+			exts[i] = (&ScanFile{Attachment: attachment}).Ext()
+		}
+		return exts
+	}},
+	"author.age": {number: func(ctx *AutomodContext) float64 {
+		return time.Since(SnowflakeTime(ctx.Message.Author.ID)).Hours() / 24
+	}},
+	"author.joined": {number: func(ctx *AutomodContext) float64 {
+		if ctx.Member == nil {
+			return 0
+		}
+		joined, err := ctx.Member.JoinedAt.Parse()
+		if err != nil {
+			return 0
+		}
+		return time.Since(joined).Hours() / 24
+	}},
+	"author.role": {set: func(ctx *AutomodContext) []string {
+		if ctx.Member == nil {
+			return nil
+		}
+		var roles []string
+		for _, id := range ctx.Member.Roles {
+			roles = append(roles, id)
+			if role := findRole(ctx.Guild, id); role != nil {
+				roles = append(roles, role.Name)
+			}
+		}
+		return roles
+	}},
+	"channel": {set: func(ctx *AutomodContext) []string {
+		return []string{ctx.Channel.ID, ctx.Channel.Name}
+	}},
+	"author.risk": {number: func(ctx *AutomodContext) float64 { return ctx.Risk() }},
+	"heat":        {number: func(ctx *AutomodContext) float64 { return ctx.Heat() }},
+}
+
+type ruleToken struct {
+	value  string
+	quoted bool
+}
+
+// tokenizeRule splits a rule into words, quoted strings and operators.
+func tokenizeRule(source string) ([]ruleToken, error) {
+	var tokens []ruleToken
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			var b strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				b.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("A string is missing it's closing quote.")
+			}
+			i++
+			tokens = append(tokens, ruleToken{value: b.String(), quoted: true})
+		case r == '(' || r == ')' || r == '|':
+			tokens = append(tokens, ruleToken{value: string(r)})
+			i++
+		case r == '<' && i+1 < len(runes) && (runes[i+1] == '#' || runes[i+1] == '@'):
+			// A mention, e.g <#123> or <@&123>
+			end := i
+			for end < len(runes) && runes[end] != '>' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("A mention is missing it's closing >.")
+			}
+			tokens = append(tokens, ruleToken{value: strings.TrimLeft(string(runes[i+1:end]), "#@&"), quoted: true})
+			i = end + 1
+		case strings.ContainsRune("=!<>", r):
+			if i+1 < len(runes) && (runes[i+1] == '=' || r == '=' && runes[i+1] == '>') {
+				tokens = append(tokens, ruleToken{value: string(runes[i : i+2])})
+				i += 2
+			} else if r == '<' || r == '>' {
+				tokens = append(tokens, ruleToken{value: string(r)})
+				i++
+			} else {
+				return nil, fmt.Errorf("Unexpected '%c', did you mean '%c='?", r, r)
+			}
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("\"()|=!<>", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, ruleToken{value: string(runes[start:i])})
+		}
+	}
+	return tokens, nil
+}
+
+type ruleParser struct {
+	tokens []ruleToken
+	pos    int
+}
+
+func (p *ruleParser) peek() string {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].quoted {
+		return ""
+	}
+	return strings.ToLower(p.tokens[p.pos].value)
+}
+
+func (p *ruleParser) next() (ruleToken, error) {
+	if p.pos >= len(p.tokens) {
+		return ruleToken{}, fmt.Errorf("The rule ended too early.")
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+// expr := and ("or" and)*
+func (p *ruleParser) expr() (ruleCond, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "or" {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(ctx *AutomodContext) bool { return l(ctx) || right(ctx) }
+	}
+	return left, nil
+}
+
+// and := unary ("and" unary)*
+func (p *ruleParser) and() (ruleCond, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "and" {
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(ctx *AutomodContext) bool { return l(ctx) && right(ctx) }
+	}
+	return left, nil
+}
+
+// unary := "not" unary | "(" expr ")" | field operator value
+func (p *ruleParser) unary() (ruleCond, error) {
+	switch p.peek() {
+	case "not":
+		p.pos++
+		cond, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *AutomodContext) bool { return !cond(ctx) }, nil
+	case "(":
+		p.pos++
+		cond, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("A '(' is missing it's closing ')'.")
+		}
+		p.pos++
+		return cond, nil
+	}
+	return p.condition()
+}
+
+func (p *ruleParser) condition() (ruleCond, error) {
+	name, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	field, ok := ruleFields[strings.ToLower(name.value)]
+	if name.quoted || !ok {
+		return nil, fmt.Errorf("'%s' is not a field, use one of %s", name.value, strings.Join(ruleFieldNames(), ", "))
+	}
+	opToken, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	op := strings.ToLower(opToken.value)
+	valueToken, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	value := valueToken.value
+
+	switch {
+	case field.number != nil:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s needs a number, '%s' is not one.", name.value, value)
+		}
+		compare, ok := map[string]func(a, b float64) bool{
+			"==": func(a, b float64) bool { return a == b },
+			"is": func(a, b float64) bool { return a == b },
+			"!=": func(a, b float64) bool { return a != b },
+			"<":  func(a, b float64) bool { return a < b },
+			">":  func(a, b float64) bool { return a > b },
+			"<=": func(a, b float64) bool { return a <= b },
+			">=": func(a, b float64) bool { return a >= b },
+		}[op]
+		if !ok {
+			return nil, fmt.Errorf("%s can't be compared with '%s', use ==, !=, <, >, <= or >=", name.value, op)
+		}
+		return func(ctx *AutomodContext) bool { return compare(field.number(ctx), n) }, nil
+	case field.text != nil:
+		var match func(text string) bool
+		lower := strings.ToLower(value)
+		switch op {
+		case "contains":
+			match = func(text string) bool { return strings.Contains(strings.ToLower(text), lower) }
+		case "startswith":
+			match = func(text string) bool { return strings.HasPrefix(strings.ToLower(text), lower) }
+		case "endswith":
+			match = func(text string) bool { return strings.HasSuffix(strings.ToLower(text), lower) }
+		case "==", "is":
+			match = func(text string) bool { return strings.EqualFold(text, value) }
+		case "!=":
+			match = func(text string) bool { return !strings.EqualFold(text, value) }
+		case "matches":
+			set, err := NewRegexSet(value)
+			if err != nil {
+				return nil, err
+			}
+			match = func(text string) bool {
+				re, _ := set.Match(text)
+				return re != nil
+			}
+		default:
+			return nil, fmt.Errorf("%s can't be compared with '%s', use contains, startswith, endswith, matches, == or !=", name.value, op)
+		}
+		return func(ctx *AutomodContext) bool { return match(field.text(ctx)) }, nil
+	default:
+		if strings.ToLower(name.value) == "attachment.ext" && !strings.HasPrefix(value, ".") {
+			value = "." + value
+		}
+		matches := func(ctx *AutomodContext) bool {
+			for _, v := range field.set(ctx) {
+				if strings.EqualFold(v, value) {
+					return true
+				}
+			}
+			return false
+		}
+		switch op {
+		case "==", "is", "has":
+			return matches, nil
+		case "!=":
+			return func(ctx *AutomodContext) bool { return !matches(ctx) }, nil
+		}
+		return nil, fmt.Errorf("%s can't be compared with '%s', use == or !=", name.value, op)
+	}
+}
+
+func ruleFieldNames() []string {
+	names := make([]string, 0, len(ruleFields))
+	for name := range ruleFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CompileCustomRule compiles a rule of the form "<conditions> => <actions> [reason]".
+// Conditions compare a field with a value and are combined with and, or, not and parentheses.
+// content is compared with contains, startswith, endswith, matches (a pattern, see CompileSafeRegex), == and !=
+// content.length, mentions, links, attachments, heat, author.risk (see bot.AccountRiskOf), author.age and author.joined (in days) with <, >, <=, >=, == and !=
+// author.role, channel and attachment.ext with == and !=, roles and channels by ID, mention or name.
+// Actions are delete, warn, alert, kick and ban separated by |, the optional reason is a quoted string.
+func CompileCustomRule(name, source string) (*CustomRule, error) {
+	if len(source) > MaxCustomRuleLength {
+		return nil, fmt.Errorf("The rule is too long, it can be at most %d characters.", MaxCustomRuleLength)
+	}
+	tokens, err := tokenizeRule(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &ruleParser{tokens: tokens}
+	cond, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != "=>" {
+		return nil, fmt.Errorf("Expected => followed by the actions after the conditions.")
+	}
+	p.pos++
+	rule := &CustomRule{Name: name, Source: source, cond: cond}
+	for {
+		token, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		action, ok := automodActionNames[strings.ToLower(token.value)]
+		if token.quoted || !ok {
+			return nil, fmt.Errorf("'%s' is not an action, use delete, warn, alert, kick or ban", token.value)
+		}
+		rule.Actions |= action
+		if p.peek() != "|" {
+			break
+		}
+		p.pos++
+	}
+	if p.pos < len(p.tokens) && p.tokens[p.pos].quoted {
+		rule.Reason = p.tokens[p.pos].value
+		p.pos++
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("Unexpected '%s' at the end of the rule.", p.tokens[p.pos].value)
+	}
+	return rule, nil
+}
+
+// The settings key of a guild's custom rules.
+const customRulesKey = "automod.rules"
+
+type customRuleTracker struct {
+	// guild ID -> compiled rules, sorted by name
+	guilds map[string][]*CustomRule
+	lock   sync.Mutex
+}
+
+// EnableCustomRules lets each guild write it's own automod rules with the automodrule command, see CompileCustomRule.
+// Rules are compiled once and cached, the first matching rule of the guild decides the actions.
+func (bot *Bot) EnableCustomRules() *Bot {
+	if bot.customRules != nil {
+		return bot
+	}
+	bot.customRules = &customRuleTracker{guilds: make(map[string][]*CustomRule)}
+	bot.AddAutomodRule(NewAutomodRule("custom", func(ctx *AutomodContext) *Violation {
+		for _, rule := range ctx.Bot.CustomRules(ctx.Guild.ID) {
+			if v := rule.Check(ctx); v != nil {
+				return v
+			}
+		}
+		return nil
+	}))
+	bot.AddCommand(NewCommand("automodrule", "Moderation", customRuleCommand).
+		SetDescription("Manages this server's own automod rules.").
+		SetUsage("<action:string> [rule:string...]").
+		AddAliases("amrule").
+		SetRequiredPermissions(discordgo.PermissionManageServer).
+		SetGuildOnly(true))
+	return bot
+}
+
+// customRuleSources returns the sources of a guild's custom rules by name.
+func (bot *Bot) customRuleSources(guildID string) map[string]string {
+	sources := make(map[string]string)
+	if _, err := GetJSON(bot.Settings, guildID, customRulesKey, &sources); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+	return sources
+}
+
+// CustomRules returns the compiled custom rules of a guild, sorted by name.
+func (bot *Bot) CustomRules(guildID string) []*CustomRule {
+	bot.customRules.lock.Lock()
+	defer bot.customRules.lock.Unlock()
+	if rules, ok := bot.customRules.guilds[guildID]; ok {
+		return rules
+	}
+	rules := compileCustomRules(bot, bot.customRuleSources(guildID))
+	bot.customRules.guilds[guildID] = rules
+	return rules
+}
+
+func compileCustomRules(bot *Bot, sources map[string]string) []*CustomRule {
+	var rules []*CustomRule
+	for name, source := range sources {
+		rule, err := CompileCustomRule(name, source)
+		if err != nil {
+			// Saved before the language changed, skip it instead of failing every message.
+			bot.ErrorHandler(bot, fmt.Errorf("custom rule %s: %v", name, err))
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Name < rules[j].Name
+	})
+	return rules
+}
+
+// SetCustomRule compiles and saves a custom rule of a guild, an empty source removes it.
+// Dashboards can use it to manage rules, the error explains what's wrong with the rule.
+func (bot *Bot) SetCustomRule(guildID, name, source string) error {
+	name = strings.ToLower(name)
+	sources := bot.customRuleSources(guildID)
+	if source == "" {
+		delete(sources, name)
+	} else {
+		if _, ok := sources[name]; !ok && len(sources) >= MaxCustomRules {
+			return fmt.Errorf("A server can have at most %d custom rules.", MaxCustomRules)
+		}
+		if _, err := CompileCustomRule(name, source); err != nil {
+			return err
+		}
+		sources[name] = source
+	}
+	var err error
+	if len(sources) == 0 {
+		err = bot.Settings.Delete(guildID, customRulesKey)
+	} else {
+		err = SetJSON(bot.Settings, guildID, customRulesKey, sources)
+	}
+	if err != nil {
+		return err
+	}
+	if bot.customRules != nil {
+		bot.customRules.lock.Lock()
+		bot.customRules.guilds[guildID] = compileCustomRules(bot, sources)
+		bot.customRules.lock.Unlock()
+	}
+	return nil
+}
+
+func customRuleCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+	args := strings.SplitN(ctx.ArgString(1), " ", 2)
+	name := strings.ToLower(args[0])
+	switch strings.ToLower(ctx.Arg(0).AsString()) {
+	case "list":
+		rules := bot.CustomRules(ctx.Guild.ID)
+		if len(rules) == 0 {
+			ctx.ReplyLocale("COMMAND_AUTOMODRULE_NONE", ctx.Prefix)
+			return
+		}
+		lines := make([]string, len(rules))
+		for i, rule := range rules {
+			lines[i] = fmt.Sprintf("**%s**: `` %s ``", rule.Name, rule.Source)
+		}
+		ctx.Reply(strings.Join(lines, "\n"))
+	case "add", "set":
+		if name == "" || len(args) < 2 {
+			ctx.ReplyLocale("COMMAND_AUTOMODRULE_USAGE", ctx.Prefix)
+			return
+		}
+		if err := bot.SetCustomRule(ctx.Guild.ID, name, strings.TrimSpace(args[1])); err != nil {
+			ctx.ReplyLocale("COMMAND_AUTOMODRULE_INVALID", Escape(err.Error()))
+			return
+		}
+		ctx.ReplyLocale("COMMAND_AUTOMODRULE_SET", Escape(name))
+	case "remove":
+		if _, ok := bot.customRuleSources(ctx.Guild.ID)[name]; !ok {
+			ctx.ReplyLocale("COMMAND_AUTOMODRULE_NOT_FOUND", Escape(name))
+			return
+		}
+		if err := bot.SetCustomRule(ctx.Guild.ID, name, ""); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_AUTOMODRULE_REMOVED", Escape(name))
+	default:
+		ctx.ReplyLocale("COMMAND_AUTOMODRULE_USAGE", ctx.Prefix)
+	}
+}
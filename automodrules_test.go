@@ -0,0 +1,57 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"testing"
+)
+
+func TestCustomRules(t *testing.T) {
+	bot := &Bot{}
+	guild := &discordgo.Guild{ID: "1", Roles: []*discordgo.Role{{ID: "10", Name: "Trusted"}}}
+	check := func(source, content string, roles ...string) bool {
+		rule, err := CompileCustomRule("test", source)
+		if err != nil {
+			t.Fatalf("Failed to compile %q: %v", source, err)
+		}
+		ctx := &AutomodContext{
+			Bot:     bot,
+			Message: &discordgo.Message{Content: content, Author: &discordgo.User{ID: "2"}},
+			Guild:   guild,
+			Channel: &discordgo.Channel{ID: "3", Name: "general"},
+			Member:  &discordgo.Member{Roles: roles},
+		}
+		ctx.text = &content
+		return rule.cond(ctx)
+	}
+	source := `content contains "discord.gg" and not author.role == Trusted => delete|warn "No invites"`
+	if !check(source, "join discord.gg/abc") {
+		t.Errorf("Expected an invite to match")
+	}
+	if check(source, "join discord.gg/abc", "10") {
+		t.Errorf("Expected trusted members to be exempt")
+	}
+	if !check(`(content.length > 5 or mentions >= 1) and channel == <#3> => alert`, "hello world") {
+		t.Errorf("Expected the length and channel to match")
+	}
+	if !check(`content matches "^h(a|e)llo" => delete`, "hello") {
+		t.Errorf("Expected the pattern to match")
+	}
+
+	rule, _ := CompileCustomRule("test", source)
+	if rule.Actions != AutomodDelete|AutomodWarn || rule.Reason != "No invites" {
+		t.Errorf("Unexpected actions %d and reason %q", rule.Actions, rule.Reason)
+	}
+	for _, invalid := range []string{
+		`content contains "x"`,
+		`content contains "x" => mute`,
+		`colour == red => delete`,
+		`mentions > many => delete`,
+		`content < 5 => delete`,
+		`(content contains "x" => delete`,
+		`content contains "x => delete`,
+	} {
+		if _, err := CompileCustomRule("test", invalid); err == nil {
+			t.Errorf("Expected %q to be invalid", invalid)
+		}
+	}
+}
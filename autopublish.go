@@ -0,0 +1,170 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"sync"
+	"time"
+)
+
+// autoPublishKeyPrefix is the guild settings key prefix the auto-publish mode is stored under, followed by the channel ID.
+const autoPublishKeyPrefix = "autopublish."
+
+// Discord allows 10 crossposts per hour in a channel.
+const (
+	autoPublishLimit  = 10
+	autoPublishWindow = time.Hour
+)
+
+// AutoPublishMode decides which messages are published automatically in an announcement channel.
+type AutoPublishMode string
+
+const (
+	AutoPublishOff   AutoPublishMode = ""
+	AutoPublishAll   AutoPublishMode = "all"   // Every message.
+	AutoPublishBots  AutoPublishMode = "bots"  // Messages from bots and webhooks, including ourselves.
+	AutoPublishUsers AutoPublishMode = "users" // Messages from users.
+)
+
+type autoPublishTracker struct {
+	// channel ID -> times of the crossposts in the current window.
+	published map[string][]time.Time
+	lock      sync.Mutex
+}
+
+// EnableAutoPublish loads the autopublish command and the monitor that publishes messages in announcement channels.
+func (bot *Bot) EnableAutoPublish() *Bot {
+	if bot.autoPublish != nil {
+		return bot
+	}
+	bot.autoPublish = &autoPublishTracker{published: make(map[string][]time.Time)}
+	bot.AddMonitor(NewMonitor("autoPublish", autoPublishMonitor).SetGuildOnly(true).AllowBots().AllowSelf().AllowWebhooks())
+	bot.AddCommand(NewCommand("autopublish", "Moderation", autoPublishCommand).
+		SetDescription("Automatically publishes messages posted in this announcement channel.").
+		SetUsage("[mode:string]").
+		SetGuildOnly(true).
+		AddAliases("autocrosspost"))
+	return bot
+}
+
+// SetAutoPublish sets which messages are published automatically in the channel, AutoPublishOff disables it.
+func (bot *Bot) SetAutoPublish(guildID, channelID string, mode AutoPublishMode) error {
+	if mode == AutoPublishOff {
+		return bot.Settings.Delete(guildID, autoPublishKeyPrefix+channelID)
+	}
+	return bot.Settings.Set(guildID, autoPublishKeyPrefix+channelID, string(mode))
+}
+
+// AutoPublish returns the auto-publish mode of the channel.
+func (bot *Bot) AutoPublish(guildID, channelID string) AutoPublishMode {
+	mode, ok, err := bot.Settings.Get(guildID, autoPublishKeyPrefix+channelID)
+	if err != nil || !ok {
+		return AutoPublishOff
+	}
+	return AutoPublishMode(mode)
+}
+
+// Crosspost publishes a message in an announcement channel to the channels following it.
+func (bot *Bot) Crosspost(channelID, messageID string) error {
+	// The bundled discordgo doesn't wrap this endpoint yet.
+	_, err := bot.Session.RequestWithBucketID("POST", discordgo.EndpointChannelMessage(channelID, messageID)+"/crosspost", nil,
+		discordgo.EndpointChannelMessages(channelID)+"/crosspost")
+	return err
+}
+
+// takePublishSlot reserves one of the channel's crossposts in the current window, false if none are left.
+func (bot *Bot) takePublishSlot(channelID string) bool {
+	bot.autoPublish.lock.Lock()
+	defer bot.autoPublish.lock.Unlock()
+	var recent []time.Time
+	for _, t := range bot.autoPublish.published[channelID] {
+		if time.Since(t) < autoPublishWindow {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= autoPublishLimit {
+		bot.autoPublish.published[channelID] = recent
+		return false
+	}
+	bot.autoPublish.published[channelID] = append(recent, time.Now())
+	return true
+}
+
+func autoPublishMonitor(bot *Bot, ctx *MonitorContext) {
+	if ctx.Channel.Type != discordgo.ChannelTypeGuildNews {
+		return
+	}
+	fromBot := ctx.Author.Bot || ctx.Message.WebhookID != ""
+	switch bot.AutoPublish(ctx.Guild.ID, ctx.Channel.ID) {
+	case AutoPublishAll:
+	case AutoPublishBots:
+		if !fromBot {
+			return
+		}
+	case AutoPublishUsers:
+		if fromBot {
+			return
+		}
+	default:
+		return
+	}
+
+	// Publishing our own messages only needs Send Messages, anyone else's needs Manage Messages.
+	perms, err := ctx.Session.State.UserChannelPermissions(ctx.Session.State.User.ID, ctx.Channel.ID)
+	if err != nil {
+		return
+	}
+	needed := discordgo.PermissionManageMessages
+	if ctx.Author.ID == ctx.Session.State.User.ID {
+		needed = discordgo.PermissionSendMessages
+	}
+	if !Permissions(perms).Has(needed) || !bot.takePublishSlot(ctx.Channel.ID) || !bot.SpendAPI(ctx.Message.GuildID, 1) {
+		return
+	}
+	if err := bot.Crosspost(ctx.Channel.ID, ctx.Message.ID); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+}
+
+func autoPublishCommand(ctx *CommandContext) {
+	if ctx.Channel.Type != discordgo.ChannelTypeGuildNews {
+		ctx.ReplyLocale("COMMAND_AUTOPUBLISH_NOT_NEWS")
+		return
+	}
+	if !ctx.Arg(0).IsProvided() {
+		mode := ctx.Bot.AutoPublish(ctx.Guild.ID, ctx.Channel.ID)
+		if mode == AutoPublishOff {
+			mode = "off"
+		}
+		ctx.ReplyLocale("COMMAND_AUTOPUBLISH_CURRENT", mode)
+		return
+	}
+	if !ctx.HasPermissions(discordgo.PermissionManageChannels) {
+		ctx.ReplyLocale("COMMAND_AUTOPUBLISH_NO_PERMISSION")
+		return
+	}
+
+	var mode AutoPublishMode
+	switch strings.ToLower(ctx.Arg(0).AsString()) {
+	case "off", "none", "disable":
+		mode = AutoPublishOff
+	case "all":
+		mode = AutoPublishAll
+	case "bots", "bot":
+		mode = AutoPublishBots
+	case "users", "user":
+		mode = AutoPublishUsers
+	default:
+		ctx.ReplyLocale("COMMAND_AUTOPUBLISH_USAGE", ctx.Prefix)
+		return
+	}
+	if err := ctx.Bot.SetAutoPublish(ctx.Guild.ID, ctx.Channel.ID, mode); err != nil {
+		ctx.Error(err)
+		return
+	}
+	if mode == AutoPublishOff {
+		ctx.ReplyLocale("COMMAND_AUTOPUBLISH_DISABLED")
+		return
+	}
+	ctx.ReplyLocale("COMMAND_AUTOPUBLISH_SET", mode)
+}
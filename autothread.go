@@ -0,0 +1,150 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// autoThreadKeyPrefix is the guild settings key prefix auto-threading is stored under, followed by the channel ID.
+const autoThreadKeyPrefix = "autothread."
+
+// permissionCreatePublicThreads is missing from the bundled discordgo.
+const permissionCreatePublicThreads = 1 << 35
+
+// ThreadArchiveDurations are the auto archive durations in minutes Discord accepts.
+var ThreadArchiveDurations = []int{60, 1440, 4320, 10080}
+
+// AutoThread is the auto-threading config of a channel.
+type AutoThread struct {
+	// Name template of the threads, {user} is replaced with the author's name, {content} with the start of the message
+	// and {date} with the date. (default: "{user}'s thread")
+	Template string `json:"template"`
+	Archive  int    `json:"archive"` // Minutes of inactivity before the thread is archived, one of ThreadArchiveDurations. (default: 1440)
+}
+
+// EnableAutoThread loads the autothread command and the monitor that creates a thread for every new message
+// in channels that turned it on, useful for media or support channels.
+func (bot *Bot) EnableAutoThread() *Bot {
+	bot.AddMonitor(NewMonitor("autoThread", autoThreadMonitor).SetGuildOnly(true))
+	return bot.AddCommand(NewCommand("autothread", "Moderation", autoThreadCommand).
+		SetDescription("Creates a thread for every new message in this channel, use --name=template and --archive=minutes to customize them.").
+		SetUsage("[toggle:string]").
+		SetGuildOnly(true))
+}
+
+// SetAutoThread enables auto-threading in the channel, pass nil to disable it.
+func (bot *Bot) SetAutoThread(guildID, channelID string, config *AutoThread) error {
+	if config == nil {
+		return bot.Settings.Delete(guildID, autoThreadKeyPrefix+channelID)
+	}
+	if config.Template == "" {
+		config.Template = "{user}'s thread"
+	}
+	if config.Archive == 0 {
+		config.Archive = 1440
+	}
+	return SetJSON(bot.Settings, guildID, autoThreadKeyPrefix+channelID, config)
+}
+
+// AutoThread returns the auto-threading config of the channel, nil if it's disabled.
+func (bot *Bot) AutoThread(guildID, channelID string) *AutoThread {
+	config := &AutoThread{}
+	if ok, err := GetJSON(bot.Settings, guildID, autoThreadKeyPrefix+channelID, config); err != nil || !ok {
+		return nil
+	}
+	return config
+}
+
+// StartThread creates a public thread from a message, archive is in minutes.
+func (bot *Bot) StartThread(channelID, messageID, name string, archive int) error {
+	// The bundled discordgo doesn't know about threads yet.
+	_, err := bot.Session.RequestWithBucketID("POST", discordgo.EndpointChannelMessage(channelID, messageID)+"/threads",
+		map[string]interface{}{"name": name, "auto_archive_duration": archive},
+		discordgo.EndpointChannelMessages(channelID)+"/threads")
+	return err
+}
+
+// threadName fills in the template, thread names are limited to 100 characters.
+func threadName(template string, msg *discordgo.Message) string {
+	content := []rune(strings.Join(strings.Fields(msg.Content), " "))
+	if len(content) > 50 {
+		content = append(content[:50], '…')
+	}
+	name := strings.NewReplacer(
+		"{user}", msg.Author.Username,
+		"{content}", string(content),
+		"{date}", time.Now().UTC().Format("2006-01-02"),
+	).Replace(template)
+	if name = strings.TrimSpace(name); name == "" {
+		name = msg.Author.Username
+	}
+	if runes := []rune(name); len(runes) > 100 {
+		name = string(runes[:100])
+	}
+	return name
+}
+
+func autoThreadMonitor(bot *Bot, ctx *MonitorContext) {
+	if ctx.Channel.Type != discordgo.ChannelTypeGuildText && ctx.Channel.Type != discordgo.ChannelTypeGuildNews {
+		return
+	}
+	config := bot.AutoThread(ctx.Guild.ID, ctx.Channel.ID)
+	if config == nil {
+		return
+	}
+	perms, err := ctx.Session.State.UserChannelPermissions(ctx.Session.State.User.ID, ctx.Channel.ID)
+	if err != nil || !Permissions(perms).Has(permissionCreatePublicThreads) || !bot.SpendAPI(ctx.Message.GuildID, 1) {
+		return
+	}
+	if err := bot.StartThread(ctx.Channel.ID, ctx.Message.ID, threadName(config.Template, ctx.Message), config.Archive); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+}
+
+func autoThreadCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+	if !ctx.Arg(0).IsProvided() {
+		config := bot.AutoThread(ctx.Guild.ID, ctx.Channel.ID)
+		if config == nil {
+			ctx.ReplyLocale("COMMAND_AUTOTHREAD_OFF")
+			return
+		}
+		ctx.ReplyLocale("COMMAND_AUTOTHREAD_CURRENT", config.Template, config.Archive)
+		return
+	}
+	if !ctx.HasPermissions(discordgo.PermissionManageChannels) {
+		ctx.ReplyLocale("COMMAND_AUTOTHREAD_NO_PERMISSION")
+		return
+	}
+
+	switch strings.ToLower(ctx.Arg(0).AsString()) {
+	case "on", "enable":
+		config := &AutoThread{Template: ctx.Flag("name")}
+		if archive := ctx.Flag("archive"); archive != "" {
+			config.Archive, _ = strconv.Atoi(archive)
+			valid := false
+			for _, d := range ThreadArchiveDurations {
+				valid = valid || d == config.Archive
+			}
+			if !valid {
+				ctx.ReplyLocale("COMMAND_AUTOTHREAD_ARCHIVE", archive)
+				return
+			}
+		}
+		if err := bot.SetAutoThread(ctx.Guild.ID, ctx.Channel.ID, config); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_AUTOTHREAD_ENABLED", config.Template, config.Archive)
+	case "off", "disable":
+		if err := bot.SetAutoThread(ctx.Guild.ID, ctx.Channel.ID, nil); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_AUTOTHREAD_DISABLED")
+	default:
+		ctx.ReplyLocale("COMMAND_AUTOTHREAD_USAGE", ctx.Prefix)
+	}
+}
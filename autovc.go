@@ -0,0 +1,207 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AutoVCConfig is the per-guild config of temporary voice channels, see bot.EnableAutoVC
+var AutoVCConfig = NewConfigSchema("autovc", "Temporary voice channels, joining the hub channel creates a personal voice channel.").
+	Add("hub", ConfigChannel, "", "Joining this voice channel creates a personal channel, leave empty to disable.").
+	Add("category", ConfigChannel, "", "Category the channels are created in, defaults to the hub's category.").
+	Add("template", ConfigString, "{user}'s channel", "Name of created channels, {user} is replaced with the member's name.").
+	Add("limit", ConfigInt, "0", "User limit of created channels, 0 for no limit.")
+
+// autoVCKeyPrefix is the guild settings key prefix temporary channels are remembered under, followed by the channel ID.
+const autoVCKeyPrefix = "autovc.temp."
+
+// autoVCGrace is how long a new channel is kept even if empty, the owner is still being moved into it.
+const autoVCGrace = 15 * time.Second
+
+type autoVCTracker struct {
+	// guild ID -> channel ID -> creation time
+	channels map[string]map[string]time.Time
+	lock     sync.Mutex
+}
+
+// EnableAutoVC enables temporary voice channels and registers AutoVCConfig so servers can set their hub channel.
+// When a member joins the hub they get their own voice channel with permissions to manage it,
+// the channel is deleted as soon as it's empty. The bot needs the Manage Channels and Move Members permissions.
+func (bot *Bot) EnableAutoVC() *Bot {
+	if bot.autoVC != nil {
+		return bot
+	}
+	bot.autoVC = &autoVCTracker{channels: make(map[string]map[string]time.Time)}
+	bot.AddConfigSchema(AutoVCConfig)
+	bot.EnableVoiceTracking()
+	for _, topic := range []string{TopicUserJoinedVoice, TopicUserMovedVoice, TopicUserLeftVoice} {
+		bot.Subscribe(topic, bot.autoVCVoiceEvent)
+	}
+	bot.AddHandler(autoVCGuildListener(bot))
+	bot.AddHandler(autoVCDeleteListener(bot))
+	return bot
+}
+
+// IsTempVoiceChannel checks if the channel is a temporary voice channel.
+func (bot *Bot) IsTempVoiceChannel(guildID, channelID string) bool {
+	if bot.autoVC == nil {
+		return false
+	}
+	bot.autoVC.lock.Lock()
+	defer bot.autoVC.lock.Unlock()
+	_, ok := bot.autoVC.channels[guildID][channelID]
+	return ok
+}
+
+func (bot *Bot) trackTempVoice(guildID, channelID string, created time.Time) {
+	bot.autoVC.lock.Lock()
+	defer bot.autoVC.lock.Unlock()
+	if bot.autoVC.channels[guildID] == nil {
+		bot.autoVC.channels[guildID] = make(map[string]time.Time)
+	}
+	bot.autoVC.channels[guildID][channelID] = created
+}
+
+func (bot *Bot) forgetTempVoice(guildID, channelID string) {
+	bot.autoVC.lock.Lock()
+	delete(bot.autoVC.channels[guildID], channelID)
+	if len(bot.autoVC.channels[guildID]) == 0 {
+		delete(bot.autoVC.channels, guildID)
+	}
+	bot.autoVC.lock.Unlock()
+	if err := bot.Settings.Delete(guildID, autoVCKeyPrefix+channelID); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+}
+
+// createTempVoice creates a channel for the member and moves them into it.
+func (bot *Bot) createTempVoice(guild *discordgo.Guild, hub *discordgo.Channel, userID string) {
+	if !bot.SpendAPI(guild.ID, 2) {
+		return
+	}
+	member, err := bot.fetchMember(guild.ID, userID)
+	if err != nil {
+		return
+	}
+	name := member.Nick
+	if name == "" {
+		name = member.User.Username
+	}
+	parent := AutoVCConfig.Get(bot, guild.ID, "category")
+	if parent == "" {
+		parent = hub.ParentID
+	}
+
+	channel, err := bot.Session.GuildChannelCreateComplex(guild.ID, discordgo.GuildChannelCreateData{
+		Name:      strings.Replace(AutoVCConfig.Get(bot, guild.ID, "template"), "{user}", name, -1),
+		Type:      discordgo.ChannelTypeGuildVoice,
+		ParentID:  parent,
+		UserLimit: AutoVCConfig.GetInt(bot, guild.ID, "limit"),
+		PermissionOverwrites: []*discordgo.PermissionOverwrite{{
+			ID:    userID,
+			Type:  "member",
+			Allow: discordgo.PermissionManageChannels | discordgo.PermissionVoiceMoveMembers | discordgo.PermissionVoiceConnect,
+		}},
+	})
+	if err != nil {
+		bot.ErrorHandler(bot, err)
+		return
+	}
+	bot.trackTempVoice(guild.ID, channel.ID, time.Now())
+	if err := bot.Settings.Set(guild.ID, autoVCKeyPrefix+channel.ID, userID); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+	// They might have left already, then nobody is going to use the channel.
+	if err := bot.Session.GuildMemberMove(guild.ID, userID, &channel.ID); err != nil {
+		bot.Session.ChannelDelete(channel.ID)
+		bot.forgetTempVoice(guild.ID, channel.ID)
+	}
+}
+
+// cleanupTempVoice deletes the guild's temporary channels that are empty, occupied maps channels to their member count.
+func (bot *Bot) cleanupTempVoice(guildID string, occupied map[string]int) {
+	var empty []string
+	bot.autoVC.lock.Lock()
+	for id, created := range bot.autoVC.channels[guildID] {
+		if occupied[id] == 0 && time.Since(created) > autoVCGrace {
+			empty = append(empty, id)
+		}
+	}
+	bot.autoVC.lock.Unlock()
+
+	for _, id := range empty {
+		if _, err := bot.Session.ChannelDelete(id); err != nil {
+			bot.ErrorHandler(bot, err)
+		}
+		bot.forgetTempVoice(guildID, id)
+	}
+}
+
+// autoVCVoiceEvent creates a channel when a member joins the hub and deletes the channels left empty.
+func (bot *Bot) autoVCVoiceEvent(event BusEvent) {
+	var member VoiceMember
+	switch e := event.(type) {
+	case *UserJoinedVoice:
+		member = e.Member
+	case *UserMovedVoice:
+		member = e.Member
+	case *UserLeftVoice:
+		member = e.Member
+		member.ChannelID = ""
+	}
+	// Cleanup first, a channel created below must not be seen empty before the owner arrives.
+	bot.cleanupTempVoice(member.GuildID, bot.Voice.Channels(member.GuildID))
+
+	hub := AutoVCConfig.Get(bot, member.GuildID, "hub")
+	if hub == "" || member.ChannelID != hub {
+		return
+	}
+	guild, err := bot.Session.State.Guild(member.GuildID)
+	if err != nil {
+		return
+	}
+	channel, err := bot.Session.State.Channel(hub)
+	if err != nil {
+		return
+	}
+	bot.createTempVoice(guild, channel, member.UserID)
+}
+
+// autoVCGuildListener picks up temporary channels from before a restart and deletes the ones left empty.
+func autoVCGuildListener(bot *Bot) func(s *discordgo.Session, g *discordgo.GuildCreate) {
+	return func(s *discordgo.Session, g *discordgo.GuildCreate) {
+		it, ok := bot.Settings.(SettingsIterator)
+		if !ok {
+			return
+		}
+		keys, err := it.Keys(g.ID)
+		if err != nil {
+			bot.ErrorHandler(bot, err)
+			return
+		}
+		for _, key := range keys {
+			if strings.HasPrefix(key, autoVCKeyPrefix) {
+				// Zero creation time so they are not in the grace period.
+				bot.trackTempVoice(g.ID, strings.TrimPrefix(key, autoVCKeyPrefix), time.Time{})
+			}
+		}
+		occupied := make(map[string]int)
+		bot.Session.State.RLock()
+		for _, state := range g.VoiceStates {
+			occupied[state.ChannelID]++
+		}
+		bot.Session.State.RUnlock()
+		bot.cleanupTempVoice(g.ID, occupied)
+	}
+}
+
+// autoVCDeleteListener forgets temporary channels that were deleted by someone else.
+func autoVCDeleteListener(bot *Bot) func(s *discordgo.Session, c *discordgo.ChannelDelete) {
+	return func(s *discordgo.Session, c *discordgo.ChannelDelete) {
+		if bot.IsTempVoiceChannel(c.GuildID, c.ID) {
+			bot.forgetTempVoice(c.GuildID, c.ID)
+		}
+	}
+}
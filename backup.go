@@ -0,0 +1,411 @@
+package sapphire
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BackupVersion is the version of backups created by this version of sapphire.
+const BackupVersion = 1
+
+// MaxBackupSize is the maximum size of a backup file restored with the backup command.
+const MaxBackupSize = 8 * 1024 * 1024
+
+// ErrBackupSignature is returned when a backup wasn't signed by this bot or was modified.
+var ErrBackupSignature = errors.New("the backup's signature is invalid, it was modified or created by another bot")
+
+// Backup is a guild's sapphire settings and optionally it's roles and channels.
+type Backup struct {
+	Version   int               `json:"version"`
+	GuildID   string            `json:"guild_id"`
+	GuildName string            `json:"guild_name"`
+	CreatedAt time.Time         `json:"created_at"`
+	Settings  map[string]string `json:"settings"`
+	Roles     []*BackupRole     `json:"roles,omitempty"`
+	Channels  []*BackupChannel  `json:"channels,omitempty"`
+}
+
+// BackupRole is a role in a backup.
+type BackupRole struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Color       int    `json:"color"`
+	Hoist       bool   `json:"hoist"`
+	Mentionable bool   `json:"mentionable"`
+	Permissions int    `json:"permissions"`
+	Position    int    `json:"position"`
+}
+
+// BackupChannel is a channel in a backup.
+type BackupChannel struct {
+	ID       string                `json:"id"`
+	Name     string                `json:"name"`
+	Type     discordgo.ChannelType `json:"type"`
+	Topic    string                `json:"topic,omitempty"`
+	ParentID string                `json:"parent_id,omitempty"`
+	Position int                   `json:"position"`
+	NSFW     bool                  `json:"nsfw,omitempty"`
+}
+
+// signedBackup is the format backups are stored in, the signature covers the raw backup.
+type signedBackup struct {
+	Signature string          `json:"signature"`
+	Backup    json.RawMessage `json:"backup"`
+}
+
+// RestoreMode decides what happens to settings that are already set to something else.
+type RestoreMode int
+
+const (
+	RestoreKeep      RestoreMode = iota // Keep the current value.
+	RestoreOverwrite                    // Use the backup's value.
+)
+
+// RestoreResult is what a restore changed.
+type RestoreResult struct {
+	Settings int // Settings written.
+	Skipped  int // Conflicting settings kept.
+	Roles    int // Roles created.
+	Channels int // Channels created.
+}
+
+// SetBackupKey sets the key backups are signed with, bots sharing backups need the same key.
+func (bot *Bot) SetBackupKey(key []byte) *Bot {
+	bot.BackupKey = key
+	return bot
+}
+
+func (bot *Bot) backupKey() []byte {
+	if len(bot.BackupKey) > 0 {
+		return bot.BackupKey
+	}
+	sum := sha256.Sum256([]byte("sapphire backup " + bot.Session.Token))
+	return sum[:]
+}
+
+// CreateBackup exports the settings of a guild, with structure it's roles and channels too.
+// The settings provider must implement SettingsIterator.
+func (bot *Bot) CreateBackup(guildID string, structure bool) (*Backup, error) {
+	iterator, ok := bot.Settings.(SettingsIterator)
+	if !ok {
+		return nil, fmt.Errorf("the settings provider can't list keys, backups need a SettingsIterator")
+	}
+	keys, err := iterator.Keys(guildID)
+	if err != nil {
+		return nil, err
+	}
+	backup := &Backup{Version: BackupVersion, GuildID: guildID, CreatedAt: time.Now().UTC(), Settings: make(map[string]string)}
+	for _, key := range keys {
+		value, ok, err := bot.Settings.Get(guildID, key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			backup.Settings[key] = value
+		}
+	}
+	if guild, err := bot.Session.State.Guild(guildID); err == nil {
+		backup.GuildName = guild.Name
+	}
+	if !structure {
+		return backup, nil
+	}
+
+	roles, err := bot.Session.GuildRoles(guildID)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		if role.Managed || role.ID == guildID {
+			continue
+		}
+		backup.Roles = append(backup.Roles, &BackupRole{
+			ID: role.ID, Name: role.Name, Color: role.Color, Hoist: role.Hoist,
+			Mentionable: role.Mentionable, Permissions: role.Permissions, Position: role.Position,
+		})
+	}
+	channels, err := bot.Session.GuildChannels(guildID)
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range channels {
+		backup.Channels = append(backup.Channels, &BackupChannel{
+			ID: channel.ID, Name: channel.Name, Type: channel.Type, Topic: channel.Topic,
+			ParentID: channel.ParentID, Position: channel.Position, NSFW: channel.NSFW,
+		})
+	}
+	return backup, nil
+}
+
+// EncodeBackup signs a backup with the backup key and encodes it as JSON.
+func (bot *Bot) EncodeBackup(backup *Backup) ([]byte, error) {
+	raw, err := json.Marshal(backup)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, bot.backupKey())
+	mac.Write(raw)
+	return json.MarshalIndent(&signedBackup{Signature: hex.EncodeToString(mac.Sum(nil)), Backup: raw}, "", "  ")
+}
+
+// DecodeBackup decodes a backup and checks it's signature, ErrBackupSignature if it doesn't match.
+func (bot *Bot) DecodeBackup(data []byte) (*Backup, error) {
+	signed := &signedBackup{}
+	if err := json.Unmarshal(data, signed); err != nil {
+		return nil, err
+	}
+	signature, err := hex.DecodeString(signed.Signature)
+	if err != nil {
+		return nil, ErrBackupSignature
+	}
+	// The signature covers the compact JSON so reformatting the file doesn't break it.
+	var raw bytes.Buffer
+	if err := json.Compact(&raw, signed.Backup); err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, bot.backupKey())
+	mac.Write(raw.Bytes())
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, ErrBackupSignature
+	}
+	backup := &Backup{}
+	if err := json.Unmarshal(signed.Backup, backup); err != nil {
+		return nil, err
+	}
+	if backup.Version > BackupVersion {
+		return nil, fmt.Errorf("the backup was created by a newer version of the bot")
+	}
+	return backup, nil
+}
+
+// BackupConflicts returns the settings keys the backup would change in the guild, sorted.
+// Restoring to another guild replaces the old guild's ID in values, roles and channels are matched on restore.
+func (bot *Bot) BackupConflicts(guildID string, backup *Backup) ([]string, error) {
+	replacer := strings.NewReplacer(backup.GuildID, guildID)
+	var conflicts []string
+	for key, value := range backup.Settings {
+		current, ok, err := bot.Settings.Get(guildID, key)
+		if err != nil {
+			return nil, err
+		}
+		if ok && current != replacer.Replace(value) {
+			conflicts = append(conflicts, key)
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts, nil
+}
+
+// RestoreBackup restores a backup to a guild, the same one or another. Roles and channels of the backup are matched
+// by name and created if they're missing, IDs of the old guild in settings are replaced with the matching new ones.
+// mode decides what happens to settings that are already set to something else, see BackupConflicts
+func (bot *Bot) RestoreBackup(guildID string, backup *Backup, mode RestoreMode) (*RestoreResult, error) {
+	result := &RestoreResult{}
+	ids := []string{backup.GuildID, guildID}
+	if len(backup.Roles) > 0 {
+		mapped, err := bot.restoreRoles(guildID, backup, result)
+		if err != nil {
+			return result, err
+		}
+		ids = append(ids, mapped...)
+	}
+	if len(backup.Channels) > 0 {
+		mapped, err := bot.restoreChannels(guildID, backup, result)
+		if err != nil {
+			return result, err
+		}
+		ids = append(ids, mapped...)
+	}
+
+	replacer := strings.NewReplacer(ids...)
+	for key, value := range backup.Settings {
+		value = replacer.Replace(value)
+		current, ok, err := bot.Settings.Get(guildID, key)
+		if err != nil {
+			return result, err
+		}
+		if ok && current == value {
+			continue
+		}
+		if ok && mode == RestoreKeep {
+			result.Skipped++
+			continue
+		}
+		if err := bot.Settings.Set(guildID, key, value); err != nil {
+			return result, err
+		}
+		result.Settings++
+	}
+	return result, nil
+}
+
+// restoreRoles creates missing roles and returns old and new IDs in pairs.
+func (bot *Bot) restoreRoles(guildID string, backup *Backup, result *RestoreResult) ([]string, error) {
+	existing, err := bot.Session.GuildRoles(guildID)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]string)
+	for _, role := range existing {
+		byName[strings.ToLower(role.Name)] = role.ID
+	}
+	roles := append([]*BackupRole(nil), backup.Roles...)
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].Position < roles[j].Position
+	})
+	var ids []string
+	for _, role := range roles {
+		id, ok := byName[strings.ToLower(role.Name)]
+		if !ok {
+			created, err := bot.Session.GuildRoleCreate(guildID)
+			if err != nil {
+				return ids, err
+			}
+			if _, err := bot.Session.GuildRoleEdit(guildID, created.ID, role.Name, role.Color, role.Hoist, role.Permissions, role.Mentionable); err != nil {
+				return ids, err
+			}
+			id = created.ID
+			result.Roles++
+		}
+		if id != role.ID {
+			ids = append(ids, role.ID, id)
+		}
+	}
+	return ids, nil
+}
+
+// restoreChannels creates missing channels, categories first, and returns old and new IDs in pairs.
+func (bot *Bot) restoreChannels(guildID string, backup *Backup, result *RestoreResult) ([]string, error) {
+	existing, err := bot.Session.GuildChannels(guildID)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]string)
+	for _, channel := range existing {
+		byName[fmt.Sprintf("%d:%s", channel.Type, strings.ToLower(channel.Name))] = channel.ID
+	}
+	channels := append([]*BackupChannel(nil), backup.Channels...)
+	sort.SliceStable(channels, func(i, j int) bool {
+		a, b := channels[i].Type == discordgo.ChannelTypeGuildCategory, channels[j].Type == discordgo.ChannelTypeGuildCategory
+		if a != b {
+			return a
+		}
+		return channels[i].Position < channels[j].Position
+	})
+	mapped := make(map[string]string)
+	var ids []string
+	for _, channel := range channels {
+		id, ok := byName[fmt.Sprintf("%d:%s", channel.Type, strings.ToLower(channel.Name))]
+		if !ok {
+			created, err := bot.Session.GuildChannelCreateComplex(guildID, discordgo.GuildChannelCreateData{
+				Name: channel.Name, Type: channel.Type, Topic: channel.Topic,
+				Position: channel.Position, ParentID: mapped[channel.ParentID], NSFW: channel.NSFW,
+			})
+			if err != nil {
+				return ids, err
+			}
+			id = created.ID
+			result.Channels++
+		}
+		mapped[channel.ID] = id
+		if id != channel.ID {
+			ids = append(ids, channel.ID, id)
+		}
+	}
+	return ids, nil
+}
+
+// LoadBackupCommands loads the backup command, create needs Manage Server and restore needs Administrator.
+func (bot *Bot) LoadBackupCommands() *Bot {
+	return bot.AddCommand(NewCommand("backup", "Settings", backupCommand).
+		SetDescription("Backs up this server's settings to a file or restores one, use --structure to include roles and channels.").
+		SetUsage("<action:string>").
+		SetGuildOnly(true))
+}
+
+func backupCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+	switch strings.ToLower(ctx.Arg(0).AsString()) {
+	case "create":
+		if !ctx.HasPermissions(discordgo.PermissionManageServer) {
+			ctx.ReplyLocale("COMMAND_BACKUP_NO_PERMISSION")
+			return
+		}
+		backup, err := bot.CreateBackup(ctx.Guild.ID, ctx.HasFlag("structure"))
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		data, err := bot.EncodeBackup(backup)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		name := fmt.Sprintf("backup-%s-%s.json", ctx.Guild.ID, backup.CreatedAt.Format("2006-01-02"))
+		if _, err := ctx.SendFile(name, strings.NewReader(string(data))); err != nil {
+			ctx.Error(err)
+		}
+	case "restore":
+		if !ctx.HasPermissions(discordgo.PermissionAdministrator) {
+			ctx.ReplyLocale("COMMAND_BACKUP_RESTORE_NO_PERMISSION")
+			return
+		}
+		attachments := ctx.Message.Attachments
+		if len(attachments) == 0 {
+			if ref, err := ctx.ReferencedMessage(); err == nil && ref != nil {
+				attachments = ref.Attachments
+			}
+		}
+		if len(attachments) == 0 {
+			ctx.ReplyLocale("COMMAND_BACKUP_NO_FILE")
+			return
+		}
+		data, err := downloadAttachment(attachments[0], MaxBackupSize)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		backup, err := bot.DecodeBackup(data)
+		if err != nil {
+			ctx.ReplyLocale("COMMAND_BACKUP_INVALID", Escape(err.Error()))
+			return
+		}
+		mode := RestoreKeep
+		if ctx.HasFlag("overwrite") {
+			mode = RestoreOverwrite
+		} else if !ctx.HasFlag("keep") {
+			conflicts, err := bot.BackupConflicts(ctx.Guild.ID, backup)
+			if err != nil {
+				ctx.Error(err)
+				return
+			}
+			if count := len(conflicts); count > 0 {
+				if count > 20 {
+					conflicts = append(conflicts[:20], "…")
+				}
+				ctx.ReplyLocale("COMMAND_BACKUP_CONFLICTS", count, Escape(strings.Join(conflicts, "\n")), ctx.Prefix)
+				return
+			}
+		}
+		if !ctx.HasFlag("structure") {
+			backup.Roles, backup.Channels = nil, nil
+		}
+		result, err := bot.RestoreBackup(ctx.Guild.ID, backup, mode)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_BACKUP_RESTORED", result.Settings, result.Skipped, result.Roles, result.Channels)
+	default:
+		ctx.ReplyLocale("COMMAND_BACKUP_USAGE", ctx.Prefix)
+	}
+}
@@ -0,0 +1,39 @@
+package sapphire
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBackup(t *testing.T) {
+	bot := &Bot{Settings: NewMemorySettings(), BackupKey: []byte("key")}
+	backup := &Backup{Version: BackupVersion, GuildID: "1", Settings: map[string]string{"prefix": "!", "config.log": "1"}}
+	data, err := bot.EncodeBackup(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := bot.DecodeBackup(data)
+	if err != nil || decoded.Settings["prefix"] != "!" {
+		t.Fatalf("Expected the backup to decode, got %+v %v", decoded, err)
+	}
+	tampered := bytes.Replace(data, []byte(`"!"`), []byte(`"?"`), 1)
+	if _, err := bot.DecodeBackup(tampered); err != ErrBackupSignature {
+		t.Errorf("Expected a modified backup to be rejected, got %v", err)
+	}
+
+	bot.Settings.Set("2", "prefix", "?")
+	conflicts, _ := bot.BackupConflicts("2", decoded)
+	if len(conflicts) != 1 || conflicts[0] != "prefix" {
+		t.Errorf("Expected the prefix to conflict, got %v", conflicts)
+	}
+	result, err := bot.RestoreBackup("2", decoded, RestoreKeep)
+	if err != nil || result.Settings != 1 || result.Skipped != 1 {
+		t.Errorf("Unexpected result %+v %v", result, err)
+	}
+	if value, _, _ := bot.Settings.Get("2", "config.log"); value != "2" {
+		t.Errorf("Expected the old guild's ID to be replaced, got %q", value)
+	}
+	if value, _, _ := bot.Settings.Get("2", "prefix"); value != "?" {
+		t.Errorf("Expected the prefix to be kept, got %q", value)
+	}
+}
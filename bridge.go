@@ -0,0 +1,300 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// bridgesKey is the bot wide settings key bridges are stored under.
+const bridgesKey = "bridges"
+
+// Bridge relays messages between channels, which can be in different guilds.
+type Bridge struct {
+	Name          string   `json:"name"`
+	Channels      []string `json:"channels"`
+	AllowBots     bool     `json:"allow_bots"`     // Relay messages from other bots. (default: false)
+	NoAttachments bool     `json:"no_attachments"` // Don't forward attachments. (default: false)
+	BlockedWords  []string `json:"blocked_words"`  // Messages containing any of these are not relayed.
+	// Filter is checked for every message after the other options, return false to not relay it.
+	// It's not saved so set it again with SetBridgeFilter after a restart.
+	Filter func(msg *discordgo.Message) bool `json:"-"`
+}
+
+// Allows checks if a message should be relayed by the bridge.
+func (b *Bridge) Allows(msg *discordgo.Message) bool {
+	if msg.Author.Bot && !b.AllowBots {
+		return false
+	}
+	content := strings.ToLower(msg.Content)
+	for _, word := range b.BlockedWords {
+		if strings.Contains(content, strings.ToLower(word)) {
+			return false
+		}
+	}
+	return b.Filter == nil || b.Filter(msg)
+}
+
+type bridgeTracker struct {
+	bridges map[string]*Bridge
+	// channel ID -> bridge
+	channels map[string]*Bridge
+	lock     sync.RWMutex
+}
+
+// EnableBridges loads the bridge command and the monitor relaying messages between bridged channels.
+// Messages are sent through webhooks with the author's name and avatar, so the bot needs the Manage Webhooks
+// permission in every bridged channel. Bridges are managed by the bot owner since they connect servers.
+func (bot *Bot) EnableBridges() *Bot {
+	if bot.bridges != nil {
+		return bot
+	}
+	bot.bridges = &bridgeTracker{bridges: make(map[string]*Bridge), channels: make(map[string]*Bridge)}
+	saved := make(map[string]*Bridge)
+	if _, err := GetJSON(bot.Settings, "", bridgesKey, &saved); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+	for _, bridge := range saved {
+		bot.indexBridge(bridge)
+	}
+	// Bots are filtered per bridge, our own webhooks are skipped in the monitor to not relay relayed messages.
+	bot.AddMonitor(NewMonitor("bridge", bridgeMonitor).SetGuildOnly(true).AllowBots().AllowWebhooks())
+	bot.AddCommand(NewCommand("bridge", "Owner", bridgeCommand).
+		SetDescription("Manages bridges relaying messages between channels across servers.").
+		SetUsage("<action:string> [name:string] [args:string...]").
+		SetOwnerOnly(true))
+	return bot
+}
+
+// indexBridge adds the bridge to the lookups, the caller must hold the lock if the bot is running.
+func (bot *Bot) indexBridge(bridge *Bridge) {
+	bot.bridges.bridges[bridge.Name] = bridge
+	for _, id := range bridge.Channels {
+		bot.bridges.channels[id] = bridge
+	}
+}
+
+// saveBridges persists the bridges, the caller must hold the lock.
+func (bot *Bot) saveBridges() error {
+	return SetJSON(bot.Settings, "", bridgesKey, bot.bridges.bridges)
+}
+
+// AddBridge creates or replaces a bridge, a channel can only be in one bridge.
+func (bot *Bot) AddBridge(bridge *Bridge) error {
+	bot.bridges.lock.Lock()
+	defer bot.bridges.lock.Unlock()
+	for _, id := range bridge.Channels {
+		if other, ok := bot.bridges.channels[id]; ok && other.Name != bridge.Name {
+			return fmt.Errorf("channel %s is already in bridge %s", id, other.Name)
+		}
+	}
+	bot.removeBridge(bridge.Name)
+	bot.indexBridge(bridge)
+	return bot.saveBridges()
+}
+
+// RemoveBridge deletes a bridge.
+func (bot *Bot) RemoveBridge(name string) error {
+	bot.bridges.lock.Lock()
+	defer bot.bridges.lock.Unlock()
+	bot.removeBridge(name)
+	return bot.saveBridges()
+}
+
+func (bot *Bot) removeBridge(name string) {
+	old, ok := bot.bridges.bridges[name]
+	if !ok {
+		return
+	}
+	for _, id := range old.Channels {
+		delete(bot.bridges.channels, id)
+	}
+	delete(bot.bridges.bridges, name)
+}
+
+// Bridge returns a copy of a bridge by name, nil if it doesn't exist.
+func (bot *Bot) Bridge(name string) *Bridge {
+	bot.bridges.lock.RLock()
+	defer bot.bridges.lock.RUnlock()
+	bridge, ok := bot.bridges.bridges[name]
+	if !ok {
+		return nil
+	}
+	copied := *bridge
+	copied.Channels = append([]string(nil), bridge.Channels...)
+	copied.BlockedWords = append([]string(nil), bridge.BlockedWords...)
+	return &copied
+}
+
+// SetBridgeFilter sets the Filter of a bridge, see Bridge.Filter
+func (bot *Bot) SetBridgeFilter(name string, filter func(msg *discordgo.Message) bool) {
+	bot.bridges.lock.Lock()
+	defer bot.bridges.lock.Unlock()
+	if bridge, ok := bot.bridges.bridges[name]; ok {
+		bridge.Filter = filter
+	}
+}
+
+// bridgeContent is the content relayed for a message, attachments are forwarded as links since webhooks can't re-upload them here.
+func bridgeContent(msg *discordgo.Message, attachments bool) string {
+	content := msg.Content
+	if attachments {
+		for _, attachment := range msg.Attachments {
+			content += "\n" + attachment.URL
+		}
+	}
+	if runes := []rune(content); len(runes) > 2000 {
+		content = string(runes[:1997]) + "..."
+	}
+	return content
+}
+
+func bridgeMonitor(bot *Bot, ctx *MonitorContext) {
+	if ctx.Message.WebhookID != "" && bot.IsOwnWebhook(ctx.Message.WebhookID) {
+		return
+	}
+	bot.bridges.lock.RLock()
+	bridge, ok := bot.bridges.channels[ctx.Channel.ID]
+	if !ok || !bridge.Allows(ctx.Message) {
+		bot.bridges.lock.RUnlock()
+		return
+	}
+	targets := make([]string, 0, len(bridge.Channels))
+	for _, id := range bridge.Channels {
+		if id != ctx.Channel.ID {
+			targets = append(targets, id)
+		}
+	}
+	content := bridgeContent(ctx.Message, !bridge.NoAttachments)
+	bot.bridges.lock.RUnlock()
+	if strings.TrimSpace(content) == "" && len(ctx.Message.Embeds) == 0 {
+		return
+	}
+
+	name := ctx.Author.Username
+	if ctx.Message.Member != nil && ctx.Message.Member.Nick != "" {
+		name = ctx.Message.Member.Nick
+	}
+	// Show where it came from, webhook names are limited to 80 characters.
+	name = fmt.Sprintf("%s (%s)", name, ctx.Guild.Name)
+	if runes := []rune(name); len(runes) > 80 {
+		name = string(runes[:80])
+	}
+	for _, id := range targets {
+		_, err := bot.SendWebhook(id, &discordgo.WebhookParams{
+			Content:   content,
+			Username:  name,
+			AvatarURL: ctx.Author.AvatarURL(""),
+			// Only rich embeds, link previews are generated again on the other side.
+			Embeds: richEmbeds(ctx.Message.Embeds),
+		})
+		if err != nil {
+			bot.ErrorHandler(bot, err)
+		}
+	}
+}
+
+func richEmbeds(embeds []*discordgo.MessageEmbed) []*discordgo.MessageEmbed {
+	var rich []*discordgo.MessageEmbed
+	for _, embed := range embeds {
+		if embed.Type == "" || embed.Type == "rich" {
+			rich = append(rich, embed)
+		}
+	}
+	return rich
+}
+
+func bridgeCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+	action := strings.ToLower(ctx.Arg(0).AsString())
+	name := ""
+	if ctx.Arg(1).IsProvided() {
+		name = strings.ToLower(ctx.Arg(1).AsString())
+	}
+	if action == "list" {
+		bot.bridges.lock.RLock()
+		lines := make([]string, 0, len(bot.bridges.bridges))
+		for _, bridge := range bot.bridges.bridges {
+			lines = append(lines, fmt.Sprintf("**%s**: %d channels", bridge.Name, len(bridge.Channels)))
+		}
+		bot.bridges.lock.RUnlock()
+		if len(lines) == 0 {
+			ctx.ReplyLocale("COMMAND_BRIDGE_NONE")
+			return
+		}
+		sort.Strings(lines)
+		ctx.Reply(strings.Join(lines, "\n"))
+		return
+	}
+	if name == "" {
+		ctx.ReplyLocale("COMMAND_BRIDGE_USAGE", ctx.Prefix)
+		return
+	}
+
+	bridge := bot.Bridge(name)
+	if bridge == nil && action != "create" {
+		ctx.ReplyLocale("COMMAND_BRIDGE_UNKNOWN", name)
+		return
+	}
+	switch action {
+	case "create":
+		if bridge != nil {
+			ctx.ReplyLocale("COMMAND_BRIDGE_EXISTS", name)
+			return
+		}
+		bridge = &Bridge{Name: name}
+	case "delete":
+		if err := bot.RemoveBridge(name); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_BRIDGE_DELETED", name)
+		return
+	case "link":
+		if !ctx.BotHasPermissions(discordgo.PermissionManageWebhooks) {
+			ctx.ReplyLocale("COMMAND_BRIDGE_NO_WEBHOOKS")
+			return
+		}
+		for _, id := range bridge.Channels {
+			if id == ctx.Channel.ID {
+				ctx.ReplyLocale("COMMAND_BRIDGE_LINKED", name)
+				return
+			}
+		}
+		bridge.Channels = append(bridge.Channels, ctx.Channel.ID)
+	case "unlink":
+		channels := bridge.Channels[:0]
+		for _, id := range bridge.Channels {
+			if id != ctx.Channel.ID {
+				channels = append(channels, id)
+			}
+		}
+		bridge.Channels = channels
+	case "bots":
+		bridge.AllowBots = ctx.Arg(2).IsProvided() && ctx.Arg(2).AsString() == "on"
+	case "attachments":
+		bridge.NoAttachments = ctx.Arg(2).IsProvided() && ctx.Arg(2).AsString() == "off"
+	case "block":
+		if word := ctx.ArgString(2); word != "" {
+			bridge.BlockedWords = append(bridge.BlockedWords, word)
+		}
+	case "unblock":
+		words := bridge.BlockedWords[:0]
+		for _, word := range bridge.BlockedWords {
+			if !strings.EqualFold(word, ctx.ArgString(2)) {
+				words = append(words, word)
+			}
+		}
+		bridge.BlockedWords = words
+	default:
+		ctx.ReplyLocale("COMMAND_BRIDGE_USAGE", ctx.Prefix)
+		return
+	}
+	if err := bot.AddBridge(bridge); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.ReplyLocale("COMMAND_BRIDGE_UPDATED", name, len(bridge.Channels), bridge.AllowBots, !bridge.NoAttachments, len(bridge.BlockedWords))
+}
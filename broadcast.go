@@ -0,0 +1,115 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"time"
+)
+
+// BroadcastConfig is the per-guild config of broadcasts, register it with bot.AddConfigSchema to let servers
+// pick the channel announcements go to or opt-out of them.
+var BroadcastConfig = NewConfigSchema("broadcast", "Announcements from the bot developers.").
+	Add("channel", ConfigChannel, "", "The channel announcements are sent to, defaults to the system channel.").
+	Add("optout", ConfigBool, "false", "Don't receive announcements at all.")
+
+// BroadcastFilter decides if a guild should receive a broadcast, nil sends to all guilds.
+type BroadcastFilter func(guild *discordgo.Guild) bool
+
+// BroadcastProgress reports how far a broadcast is.
+type BroadcastProgress struct {
+	Total   int // Guilds matching the filter.
+	Sent    int
+	Skipped int // Opted-out or no channel the bot can talk in.
+	Failed  int
+	Done    bool
+}
+
+// Broadcast sends message to the announcement channel of every guild matching filter.
+// Guilds that opted-out via BroadcastConfig are skipped, the channel is the configured one or the system channel.
+// Messages are paced by bot.BroadcastDelay to stay clear of rate-limits so this blocks for a while,
+// progress is called after every guild if not nil.
+func (bot *Bot) Broadcast(filter BroadcastFilter, message string, progress func(p *BroadcastProgress)) *BroadcastProgress {
+	var guilds []*discordgo.Guild
+	bot.Session.State.RLock()
+	for _, guild := range bot.Session.State.Guilds {
+		if !guild.Unavailable && (filter == nil || filter(guild)) {
+			guilds = append(guilds, guild)
+		}
+	}
+	bot.Session.State.RUnlock()
+
+	p := &BroadcastProgress{Total: len(guilds)}
+	for i, guild := range guilds {
+		channel := bot.broadcastChannel(guild)
+		if channel == nil {
+			p.Skipped++
+		} else if _, err := bot.Session.ChannelMessageSend(channel.ID, message); err != nil {
+			p.Failed++
+		} else {
+			p.Sent++
+		}
+		p.Done = i == len(guilds)-1
+		if progress != nil {
+			progress(p)
+		}
+		if channel != nil && !p.Done {
+			time.Sleep(bot.BroadcastDelay)
+		}
+	}
+	if len(guilds) == 0 {
+		p.Done = true
+		if progress != nil {
+			progress(p)
+		}
+	}
+	return p
+}
+
+// broadcastChannel returns where the guild wants announcements, nil if it opted-out or there's no usable channel.
+func (bot *Bot) broadcastChannel(guild *discordgo.Guild) *discordgo.Channel {
+	if BroadcastConfig.GetBool(bot, guild.ID, "optout") {
+		return nil
+	}
+	if id := BroadcastConfig.Get(bot, guild.ID, "channel"); id != "" {
+		channel, err := bot.Session.State.Channel(id)
+		if err == nil {
+			perms, err := bot.Session.State.UserChannelPermissions(bot.Session.State.User.ID, channel.ID)
+			if err == nil && Permissions(perms).Has(discordgo.PermissionReadMessages|discordgo.PermissionSendMessages) {
+				return channel
+			}
+		}
+	}
+	return bot.defaultChannel(guild)
+}
+
+func broadcastCommand(ctx *CommandContext) {
+	message := ctx.ArgString(0)
+	lastReport := time.Now()
+	ctx.ReplyLocale("COMMAND_BROADCAST_STARTED")
+	ctx.Bot.Broadcast(nil, message, func(p *BroadcastProgress) {
+		// Editing on every guild would hit the rate-limits on it's own.
+		if !p.Done && time.Since(lastReport) < 5*time.Second {
+			return
+		}
+		lastReport = time.Now()
+		key := "COMMAND_BROADCAST_PROGRESS"
+		if p.Done {
+			key = "COMMAND_BROADCAST_DONE"
+		}
+		ctx.ReplyLocale(key, p.Sent+p.Skipped+p.Failed, p.Total, p.Sent, p.Skipped, p.Failed)
+	})
+}
+
+func consoleBroadcast(ctx *ConsoleContext) {
+	if ctx.Raw == "" {
+		ctx.Printf("Usage: broadcast <message...>")
+		return
+	}
+	p := ctx.Bot.Broadcast(nil, ctx.Raw, nil)
+	ctx.Printf("Broadcast finished: %s", p)
+}
+
+// String returns a short summary of the progress.
+func (p *BroadcastProgress) String() string {
+	return fmt.Sprintf("%d/%d (%d sent, %d skipped, %d failed)", p.Sent+p.Skipped+p.Failed, p.Total, p.Sent, p.Skipped, p.Failed)
+}
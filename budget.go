@@ -0,0 +1,120 @@
+package sapphire
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrAPIBudget is returned by framework modules skipping a call because the guild used up it's API budget.
+var ErrAPIBudget = errors.New("the server used up it's API budget, try again later")
+
+// APIUsage is how many REST calls framework modules made for a guild in the current window.
+type APIUsage struct {
+	GuildID   string
+	Calls     int
+	Throttled int // Calls skipped because the budget was used up.
+}
+
+type guildBudget struct {
+	start     time.Time
+	calls     int
+	throttled int
+}
+
+type budgetTracker struct {
+	calls  int
+	window time.Duration
+	limits map[string]int // Guilds with their own budget.
+	guilds map[string]*guildBudget
+	lock   sync.Mutex
+}
+
+// EnableAPIBudget limits the REST calls framework modules make on their own for each guild, e.g sticky reposts,
+// role menus, stat channels or auto-publishing, to calls per window. Once a guild used them up those modules
+// skip their calls until the window ends, so one guild's misconfigured automation can't rate-limit the whole bot.
+// The notification webhook is told when a guild runs over, see NotifyWebhook
+func (bot *Bot) EnableAPIBudget(calls int, window time.Duration) *Bot {
+	if bot.budget != nil {
+		bot.budget.lock.Lock()
+		bot.budget.calls, bot.budget.window = calls, window
+		bot.budget.lock.Unlock()
+		return bot
+	}
+	bot.budget = &budgetTracker{calls: calls, window: window, limits: make(map[string]int), guilds: make(map[string]*guildBudget)}
+	return bot
+}
+
+// SetGuildAPIBudget gives a guild it's own budget per window e.g for big or premium guilds, 0 goes back to the default.
+func (bot *Bot) SetGuildAPIBudget(guildID string, calls int) *Bot {
+	if bot.budget == nil {
+		panic("EnableAPIBudget must be called before SetGuildAPIBudget")
+	}
+	bot.budget.lock.Lock()
+	defer bot.budget.lock.Unlock()
+	if calls <= 0 {
+		delete(bot.budget.limits, guildID)
+	} else {
+		bot.budget.limits[guildID] = calls
+	}
+	return bot
+}
+
+// SpendAPI records calls made for a guild, returns false if the guild is over it's budget and the calls should be skipped.
+// Skipped calls are counted as throttled. Always true without EnableAPIBudget
+func (bot *Bot) SpendAPI(guildID string, calls int) bool {
+	if bot.budget == nil || guildID == "" {
+		return true
+	}
+	tracker := bot.budget
+	tracker.lock.Lock()
+	now := time.Now()
+	usage, ok := tracker.guilds[guildID]
+	if !ok || now.Sub(usage.start) >= tracker.window {
+		usage = &guildBudget{start: now}
+		tracker.guilds[guildID] = usage
+	}
+	limit := tracker.calls
+	if custom, ok := tracker.limits[guildID]; ok {
+		limit = custom
+	}
+	if usage.calls+calls > limit {
+		usage.throttled += calls
+		first := usage.throttled == calls
+		window := tracker.window
+		tracker.lock.Unlock()
+		if first {
+			bot.Notify(NotifyRateLimit, "API budget used up", fmt.Sprintf("Server `%s` made %d calls in %s, it's automation is throttled until the window ends.", guildID, limit, window))
+		}
+		return false
+	}
+	usage.calls += calls
+	tracker.lock.Unlock()
+	return true
+}
+
+// APIUsage returns the usage of the guilds in their current window, the busiest first.
+func (bot *Bot) APIUsage() []APIUsage {
+	if bot.budget == nil {
+		return nil
+	}
+	bot.budget.lock.Lock()
+	defer bot.budget.lock.Unlock()
+	usages := make([]APIUsage, 0, len(bot.budget.guilds))
+	for id, usage := range bot.budget.guilds {
+		if time.Since(usage.start) >= bot.budget.window {
+			delete(bot.budget.guilds, id)
+			continue
+		}
+		usages = append(usages, APIUsage{GuildID: id, Calls: usage.calls, Throttled: usage.throttled})
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].Calls != usages[j].Calls {
+			return usages[i].Calls > usages[j].Calls
+		}
+		return usages[i].GuildID < usages[j].GuildID
+	})
+	return usages
+}
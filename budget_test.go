@@ -0,0 +1,30 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"testing"
+	"time"
+)
+
+func TestAPIBudget(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	if !bot.SpendAPI("1", 100) {
+		t.Errorf("Expected calls to be allowed without a budget")
+	}
+	bot.EnableAPIBudget(3, time.Minute).SetGuildAPIBudget("2", 5)
+	for i := 0; i < 3; i++ {
+		if !bot.SpendAPI("1", 1) {
+			t.Fatalf("Expected call %d to be within the budget", i+1)
+		}
+	}
+	if bot.SpendAPI("1", 1) {
+		t.Errorf("Expected the fourth call to be over the budget")
+	}
+	if !bot.SpendAPI("2", 5) {
+		t.Errorf("Expected the guild's own budget to apply")
+	}
+	usage := bot.APIUsage()
+	if len(usage) != 2 || usage[0].GuildID != "2" || usage[1].Calls != 3 || usage[1].Throttled != 1 {
+		t.Errorf("Unexpected usage %+v", usage)
+	}
+}
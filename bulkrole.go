@@ -0,0 +1,225 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"time"
+)
+
+// MemberFilter picks the members a bulk role change applies to, the zero value matches everyone.
+type MemberFilter struct {
+	HasRole      string    // Only members with this role ID.
+	WithoutRole  string    // Only members without this role ID.
+	JoinedBefore time.Time // Only members who joined before this.
+	JoinedAfter  time.Time // Only members who joined after this.
+	Bots         bool      // Only bots.
+	Humans       bool      // Only humans.
+}
+
+// Match checks if the member matches the filter.
+func (f *MemberFilter) Match(member *discordgo.Member) bool {
+	if f.Bots && !member.User.Bot || f.Humans && member.User.Bot {
+		return false
+	}
+	if f.HasRole != "" && !hasRole(member, f.HasRole) {
+		return false
+	}
+	if f.WithoutRole != "" && hasRole(member, f.WithoutRole) {
+		return false
+	}
+	if !f.JoinedBefore.IsZero() || !f.JoinedAfter.IsZero() {
+		joined, err := member.JoinedAt.Parse()
+		if err != nil {
+			return false
+		}
+		if !f.JoinedBefore.IsZero() && !joined.Before(f.JoinedBefore) {
+			return false
+		}
+		if !f.JoinedAfter.IsZero() && !joined.After(f.JoinedAfter) {
+			return false
+		}
+	}
+	return true
+}
+
+// BulkRoleProgress reports how far a bulk role change is.
+type BulkRoleProgress struct {
+	Total   int // Members matching the filter.
+	Changed int
+	Skipped int      // Already had the role, or didn't have it when removing.
+	Failed  []string // IDs of members the change failed for.
+	Done    bool
+}
+
+// String returns a short summary of the progress.
+func (p *BulkRoleProgress) String() string {
+	return fmt.Sprintf("%d/%d (%d changed, %d skipped, %d failed)", p.Changed+p.Skipped+len(p.Failed), p.Total, p.Changed, p.Skipped, len(p.Failed))
+}
+
+// FetchMembers fetches every member of the guild in chunks of 1000, this needs the guild members intent.
+func (bot *Bot) FetchMembers(guildID string) ([]*discordgo.Member, error) {
+	var members []*discordgo.Member
+	after := ""
+	for {
+		chunk, err := bot.Session.GuildMembers(guildID, after, 1000)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, chunk...)
+		if len(chunk) < 1000 {
+			return members, nil
+		}
+		after = chunk[len(chunk)-1].User.ID
+	}
+}
+
+// BulkRole adds or removes a role for every member of the guild matching filter.
+// Changes are paced by bot.BulkRoleDelay to stay clear of rate-limits so this blocks for a while,
+// progress is called after every member if not nil.
+func (bot *Bot) BulkRole(guildID, roleID string, add bool, filter MemberFilter, progress func(p *BulkRoleProgress)) (*BulkRoleProgress, error) {
+	members, err := bot.FetchMembers(guildID)
+	if err != nil {
+		return nil, err
+	}
+	var matched []*discordgo.Member
+	for _, member := range members {
+		if filter.Match(member) {
+			matched = append(matched, member)
+		}
+	}
+
+	p := &BulkRoleProgress{Total: len(matched)}
+	for i, member := range matched {
+		changed := false
+		if hasRole(member, roleID) == add {
+			p.Skipped++
+		} else {
+			if add {
+				err = bot.Session.GuildMemberRoleAdd(guildID, member.User.ID, roleID)
+			} else {
+				err = bot.Session.GuildMemberRoleRemove(guildID, member.User.ID, roleID)
+			}
+			if err != nil {
+				p.Failed = append(p.Failed, member.User.ID)
+			} else {
+				p.Changed++
+			}
+			changed = true
+		}
+		p.Done = i == len(matched)-1
+		if progress != nil {
+			progress(p)
+		}
+		if changed && !p.Done {
+			time.Sleep(bot.BulkRoleDelay)
+		}
+	}
+	if len(matched) == 0 {
+		p.Done = true
+		if progress != nil {
+			progress(p)
+		}
+	}
+	return p, nil
+}
+
+// findRole finds a role of the guild by mention, ID or name.
+func findRole(guild *discordgo.Guild, value string) *discordgo.Role {
+	id := strings.TrimSuffix(strings.TrimPrefix(value, "<@&"), ">")
+	for _, role := range guild.Roles {
+		if role.ID == id || strings.EqualFold(role.Name, value) {
+			return role
+		}
+	}
+	return nil
+}
+
+// highestRole returns the position of the member's highest role.
+func highestRole(guild *discordgo.Guild, member *discordgo.Member) int {
+	highest := 0
+	for _, role := range guild.Roles {
+		if role.Position > highest && hasRole(member, role.ID) {
+			highest = role.Position
+		}
+	}
+	return highest
+}
+
+// LoadRoleCommands loads the bulkrole command, it adds or removes a role for every member matching the flags.
+func (bot *Bot) LoadRoleCommands() *Bot {
+	return bot.AddCommand(NewCommand("bulkrole", "Moderation", bulkRoleCommand).
+		SetDescription("Adds or removes a role for many members, filter them with --has=role, --without=role, --before=date, --after=date, --bots or --humans.").
+		SetUsage("<action:string> <role:string...>").
+		AddAliases("massrole").
+		SetRequiredPermissions(discordgo.PermissionManageRoles).
+		SetBotPermissions(discordgo.PermissionManageRoles).
+		SetGuildOnly(true).
+		SetCooldown(30))
+}
+
+func bulkRoleCommand(ctx *CommandContext) {
+	var add bool
+	switch strings.ToLower(ctx.Arg(0).AsString()) {
+	case "add", "give":
+		add = true
+	case "remove", "take":
+	default:
+		ctx.ReplyLocale("COMMAND_BULKROLE_USAGE", ctx.Prefix)
+		return
+	}
+	role := findRole(ctx.Guild, ctx.ArgString(1))
+	if role == nil {
+		ctx.ReplyLocale("COMMAND_BULKROLE_UNKNOWN_ROLE", ctx.ArgString(1))
+		return
+	}
+
+	// Members can only hand out roles below their own, same for the bot.
+	self := ctx.Member(ctx.Session.State.User.ID)
+	if self == nil || role.Position >= highestRole(ctx.Guild, self) {
+		ctx.ReplyLocale("COMMAND_BULKROLE_BOT_HIERARCHY", role.Name)
+		return
+	}
+	if ctx.Author.ID != ctx.Guild.OwnerID && (ctx.Message.Member == nil || role.Position >= highestRole(ctx.Guild, ctx.Message.Member)) {
+		ctx.ReplyLocale("COMMAND_BULKROLE_HIERARCHY", role.Name)
+		return
+	}
+
+	filter := MemberFilter{Bots: ctx.HasFlag("bots"), Humans: ctx.HasFlag("humans")}
+	for flag, id := range map[string]*string{"has": &filter.HasRole, "without": &filter.WithoutRole} {
+		if value := ctx.Flag(flag); value != "" {
+			r := findRole(ctx.Guild, value)
+			if r == nil {
+				ctx.ReplyLocale("COMMAND_BULKROLE_UNKNOWN_ROLE", value)
+				return
+			}
+			*id = r.ID
+		}
+	}
+	for flag, t := range map[string]*time.Time{"before": &filter.JoinedBefore, "after": &filter.JoinedAfter} {
+		if value := ctx.Flag(flag); value != "" {
+			parsed, err := ctx.ParseTime(value)
+			if err != nil {
+				ctx.ReplyLocale("COMMAND_BULKROLE_INVALID_DATE", value)
+				return
+			}
+			*t = parsed
+		}
+	}
+
+	ctx.ReplyLocale("COMMAND_BULKROLE_STARTED", role.Name)
+	lastReport := time.Now()
+	p, err := ctx.Bot.BulkRole(ctx.Guild.ID, role.ID, add, filter, func(p *BulkRoleProgress) {
+		// Editing on every member would hit the rate-limits on it's own.
+		if p.Done || time.Since(lastReport) < 5*time.Second {
+			return
+		}
+		lastReport = time.Now()
+		ctx.ReplyLocale("COMMAND_BULKROLE_PROGRESS", role.Name, p.Changed+p.Skipped+len(p.Failed), p.Total)
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.ReplyLocale("COMMAND_BULKROLE_DONE", role.Name, p.Changed, p.Skipped, len(p.Failed))
+}
@@ -0,0 +1,31 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"testing"
+	"time"
+)
+
+func TestMemberFilter(t *testing.T) {
+	member := &discordgo.Member{User: &discordgo.User{ID: "1"}, Roles: []string{"10"}, JoinedAt: "2020-06-01T00:00:00+00:00"}
+	bot := &discordgo.Member{User: &discordgo.User{ID: "2", Bot: true}, JoinedAt: "2021-06-01T00:00:00+00:00"}
+	cutoff := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	filters := []struct {
+		filter        MemberFilter
+		member, robot bool
+	}{
+		{MemberFilter{}, true, true},
+		{MemberFilter{HasRole: "10"}, true, false},
+		{MemberFilter{WithoutRole: "10"}, false, true},
+		{MemberFilter{JoinedBefore: cutoff}, true, false},
+		{MemberFilter{JoinedAfter: cutoff}, false, true},
+		{MemberFilter{Bots: true}, false, true},
+		{MemberFilter{Humans: true}, true, false},
+	}
+	for i, f := range filters {
+		if f.filter.Match(member) != f.member || f.filter.Match(bot) != f.robot {
+			t.Errorf("Filter %d (%+v) didn't match as expected", i, f.filter)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"sync"
+	"time"
+)
+
+// Topics of the builtin bus events.
+const (
+	TopicModerationAction = "moderation.action"
+	TopicSettingsChanged  = "settings.changed"
+	TopicLevelUp          = "level.up"
+	TopicGuildJoined      = "guild.joined"
+	TopicCommandRan       = "command.ran"
+	TopicMemberJoined     = "member.joined"
+)
+
+// BusEvent is published on the bot's event bus, subscribers get the events of the topic.
+// Modules define their own events by implementing it, use a prefix for the topic e.g "tickets.opened".
+type BusEvent interface {
+	Topic() string
+}
+
+// ModerationAction is published when a member is moderated, e.g by automod.
+type ModerationAction struct {
+	GuildID     string
+	ChannelID   string
+	UserID      string
+	ModeratorID string // The bot for automatic actions.
+	Action      string // "delete", "warn", "kick", "ban"...
+	Reason      string
+	Source      string // What took the action e.g "automod".
+	At          time.Time
+}
+
+// Topic implements BusEvent
+func (e *ModerationAction) Topic() string { return TopicModerationAction }
+
+// SettingsChanged is published when a config key of a guild changes, Value is "" when it's reset.
+type SettingsChanged struct {
+	GuildID string
+	Schema  string
+	Key     string
+	Value   string
+}
+
+// Topic implements BusEvent
+func (e *SettingsChanged) Topic() string { return TopicSettingsChanged }
+
+// LevelUp is published by leveling modules when a member reaches a new level.
+type LevelUp struct {
+	GuildID   string
+	ChannelID string
+	UserID    string
+	Level     int
+}
+
+// Topic implements BusEvent
+func (e *LevelUp) Topic() string { return TopicLevelUp }
+
+// GuildJoined is published when the bot joins a new guild.
+type GuildJoined struct {
+	Guild *discordgo.Guild
+}
+
+// Topic implements BusEvent
+func (e *GuildJoined) Topic() string { return TopicGuildJoined }
+
+// CommandRan is published after a command's handler returned, including when it panicked.
+type CommandRan struct {
+	GuildID   string // Empty in DMs.
+	ChannelID string
+	UserID    string
+	Command   string
+	Duration  time.Duration
+	Error     string // Why it failed, empty if it succeeded.
+	// How long it took to answer the interaction, including the deferral. 0 for message commands or if it wasn't answered.
+	FirstResponse time.Duration
+}
+
+// Topic implements BusEvent
+func (e *CommandRan) Topic() string { return TopicCommandRan }
+
+// MemberJoined is published when a member joins a guild, it needs IntentsGuildMembers when intents are set.
+type MemberJoined struct {
+	GuildID string
+	User    *discordgo.User
+	At      time.Time
+}
+
+// Topic implements BusEvent
+func (e *MemberJoined) Topic() string { return TopicMemberJoined }
+
+type busSubscriber struct {
+	id      int
+	handler func(event BusEvent)
+}
+
+type eventBus struct {
+	subscribers map[string][]busSubscriber
+	next        int
+	lock        sync.RWMutex
+}
+
+// Subscribe runs handler for every event published on topic, e.g a modlog subscribing to TopicModerationAction.
+// Handlers run in their own goroutine so slow ones don't hold up the publisher, panics go to the error handler.
+// Returns a function removing the subscription.
+func (bot *Bot) Subscribe(topic string, handler func(event BusEvent)) func() {
+	bus := bot.bus
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+	bus.next++
+	id := bus.next
+	bus.subscribers[topic] = append(bus.subscribers[topic], busSubscriber{id: id, handler: handler})
+	return func() {
+		bus.lock.Lock()
+		defer bus.lock.Unlock()
+		subscribers := bus.subscribers[topic]
+		for i, sub := range subscribers {
+			if sub.id == id {
+				// Copy so a publish iterating the old slice isn't affected.
+				bus.subscribers[topic] = append(append([]busSubscriber{}, subscribers[:i]...), subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish sends an event to the subscribers of it's topic.
+func (bot *Bot) Publish(event BusEvent) {
+	bot.bus.lock.RLock()
+	subscribers := bot.bus.subscribers[event.Topic()]
+	bot.bus.lock.RUnlock()
+	for _, sub := range subscribers {
+		go func(handler func(event BusEvent)) {
+			defer func() {
+				if err := recover(); err != nil {
+					bot.ErrorHandler(bot, err)
+				}
+			}()
+			handler(event)
+		}(sub.handler)
+	}
+}
+
+// memberJoinListener publishes MemberJoined on the bus.
+func memberJoinListener(bot *Bot) func(s *discordgo.Session, m *discordgo.GuildMemberAdd) {
+	return func(s *discordgo.Session, m *discordgo.GuildMemberAdd) {
+		bot.Publish(&MemberJoined{GuildID: m.GuildID, User: m.User, At: time.Now()})
+	}
+}
@@ -0,0 +1,35 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"testing"
+	"time"
+)
+
+func TestEventBus(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	events := make(chan BusEvent, 1)
+	unsubscribe := bot.Subscribe(TopicSettingsChanged, func(event BusEvent) {
+		events <- event
+	})
+	if _, err := AutomodConfig.Set(bot, "1", "enabled", "yes"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-events:
+		changed, ok := event.(*SettingsChanged)
+		if !ok || changed.GuildID != "1" || changed.Schema != "automod" || changed.Key != "enabled" || changed.Value != "true" {
+			t.Errorf("Unexpected event %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a settings changed event")
+	}
+
+	unsubscribe()
+	AutomodConfig.Reset(bot, "1", "enabled")
+	select {
+	case event := <-events:
+		t.Errorf("Expected no events after unsubscribing, got %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,137 @@
+package sapphire
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CircuitBreaker trips a command after it failed Failures times within Window, it's re-enabled after Cooldown.
+// The first run after the cooldown is a trial, if it fails again the command trips right away.
+type CircuitBreaker struct {
+	Failures int
+	Window   time.Duration
+	Cooldown time.Duration
+}
+
+type circuitState struct {
+	failures []time.Time
+	open     time.Time // When it tripped, zero if it's closed.
+	trial    bool      // The cooldown passed and the next run decides.
+	task     *ScheduledTask
+}
+
+type circuitTracker struct {
+	breaker CircuitBreaker
+	// command name -> state
+	states map[string]*circuitState
+	lock   sync.Mutex
+}
+
+// EnableCircuitBreaker trips commands that panic failures times within window, they reply with an error until
+// cooldown passed. The owner is DMed when a command trips and when it's re-enabled, the bot owner can still run it.
+func (bot *Bot) EnableCircuitBreaker(failures int, window, cooldown time.Duration) *Bot {
+	breaker := CircuitBreaker{Failures: failures, Window: window, Cooldown: cooldown}
+	if bot.circuits != nil {
+		bot.circuits.lock.Lock()
+		bot.circuits.breaker = breaker
+		bot.circuits.lock.Unlock()
+		return bot
+	}
+	bot.circuits = &circuitTracker{breaker: breaker, states: make(map[string]*circuitState)}
+	return bot
+}
+
+// CircuitOpen checks if the command tripped, returns when it's re-enabled if so.
+func (bot *Bot) CircuitOpen(name string) (bool, time.Time) {
+	if bot.circuits == nil {
+		return false, time.Time{}
+	}
+	bot.circuits.lock.Lock()
+	defer bot.circuits.lock.Unlock()
+	state, ok := bot.circuits.states[name]
+	if !ok || state.open.IsZero() {
+		return false, time.Time{}
+	}
+	return true, state.open.Add(bot.circuits.breaker.Cooldown)
+}
+
+// ResetCircuit re-enables a tripped command and forgets its failures.
+func (bot *Bot) ResetCircuit(name string) {
+	if bot.circuits == nil {
+		return
+	}
+	bot.circuits.lock.Lock()
+	defer bot.circuits.lock.Unlock()
+	if state, ok := bot.circuits.states[name]; ok {
+		state.task.Cancel()
+		delete(bot.circuits.states, name)
+	}
+}
+
+// commandSucceeded closes the circuit of a command that passed it's trial run.
+func (bot *Bot) commandSucceeded(name string) {
+	if bot.circuits == nil {
+		return
+	}
+	bot.circuits.lock.Lock()
+	defer bot.circuits.lock.Unlock()
+	if state, ok := bot.circuits.states[name]; ok && state.trial {
+		delete(bot.circuits.states, name)
+	}
+}
+
+// commandFailed records a failure, trips the command if it failed too often.
+func (bot *Bot) commandFailed(name string, err error) {
+	if bot.circuits == nil {
+		return
+	}
+	bot.circuits.lock.Lock()
+	defer bot.circuits.lock.Unlock()
+	breaker := bot.circuits.breaker
+	state, ok := bot.circuits.states[name]
+	if !ok {
+		state = &circuitState{}
+		bot.circuits.states[name] = state
+	}
+	if !state.open.IsZero() {
+		// The owner bypasses the breaker, their failures don't count.
+		return
+	}
+	now := time.Now()
+	recent := state.failures[:0]
+	for _, t := range state.failures {
+		if now.Sub(t) < breaker.Window {
+			recent = append(recent, t)
+		}
+	}
+	state.failures = append(recent, now)
+	if !state.trial && len(state.failures) < breaker.Failures {
+		return
+	}
+
+	state.open = now
+	state.trial = false
+	state.failures = nil
+	state.task = bot.Scheduler.After(breaker.Cooldown, func() {
+		bot.circuits.lock.Lock()
+		state.open = time.Time{}
+		state.trial = true
+		bot.circuits.lock.Unlock()
+		bot.notifyOwner("CIRCUIT_CLOSED", name)
+	})
+	go bot.notifyOwner("CIRCUIT_TRIPPED", name, breaker.Failures, breaker.Window, breaker.Cooldown, err.Error())
+	bot.Notify(NotifyError, "Command "+name+" tripped", fmt.Sprintf("Disabled for %s after %d failures.\n%s", breaker.Cooldown, breaker.Failures, err.Error()))
+}
+
+// notifyOwner DMs a locale message to the bot owner.
+func (bot *Bot) notifyOwner(key string, args ...interface{}) {
+	if bot.OwnerID == "" {
+		return
+	}
+	dm, err := bot.Session.UserChannelCreate(bot.OwnerID)
+	if err != nil {
+		return
+	}
+	bot.SendLocale(dm.ID, bot.DefaultLocale, key, args...)
+}
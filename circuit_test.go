@@ -0,0 +1,37 @@
+package sapphire
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	bot := &Bot{Scheduler: NewScheduler(nil)}
+	defer bot.Scheduler.Stop()
+	bot.EnableCircuitBreaker(2, time.Minute, 20*time.Millisecond)
+	err := errors.New("boom")
+
+	bot.commandFailed("ping", err)
+	if open, _ := bot.CircuitOpen("ping"); open {
+		t.Fatal("Expected the command to trip only after 2 failures")
+	}
+	bot.commandFailed("ping", err)
+	if open, _ := bot.CircuitOpen("ping"); !open {
+		t.Fatal("Expected the command to trip after 2 failures")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if open, _ := bot.CircuitOpen("ping"); open {
+		t.Fatal("Expected the command to be re-enabled after the cooldown")
+	}
+	// The trial run after the cooldown trips it again on the first failure.
+	bot.commandFailed("ping", err)
+	if open, _ := bot.CircuitOpen("ping"); !open {
+		t.Fatal("Expected a failed trial run to trip the command again")
+	}
+	bot.ResetCircuit("ping")
+	if open, _ := bot.CircuitOpen("ping"); open {
+		t.Error("Expected ResetCircuit to re-enable the command")
+	}
+}
@@ -1,30 +1,42 @@
 package sapphire
 
 import (
+	"context"
 	"fmt"
 	"github.com/bwmarrin/discordgo"
 	"io"
 	"strings"
+	"sync"
+	"time"
 )
 
 type CommandHandler func(ctx *CommandContext)
 
 // Command represents a command in the sapphire framework.
 type Command struct {
-	Name                string         // The command's name. (default: required)
-	Aliases             []string       // Aliases that point to this command. (default: [])
-	Run                 CommandHandler // The handler that actually runs the command. (default: required)
-	Enabled             bool           // Wether this command is enabled. (default: true)
-	Description         string         // The command's brief description. (default: "No Description Provided.")
-	Category            string         // The category this command belongs to. (default: required)
-	OwnerOnly           bool           // Wether this command can only be used by the owner. (default: false)
-	GuildOnly           bool           // Wether this command can only be ran on a guild. (default: false)
-	UsageString         string         // Usage string for this command. (default: "")
-	Usage               []*UsageTag    // Parsed usage tags for this command.
-	Cooldown            int            // Command cooldown in seconds. (default: 0)
-	Editable            bool           // Wether this command's response will be editable. (default: true)
-	RequiredPermissions int            // Permissions the user needs to run this command. (default: 0)
-	BotPermissions      int            // Permissions the bot needs to perform this command. (default: 0)
+	Name                string              // The command's name. (default: required)
+	Aliases             []string            // Aliases that point to this command. (default: [])
+	Run                 CommandHandler      // The handler that actually runs the command. (default: required)
+	Enabled             bool                // Wether this command is enabled. (default: true)
+	Description         string              // The command's brief description. (default: "No Description Provided.")
+	Category            string              // The category this command belongs to. (default: required)
+	OwnerOnly           bool                // Wether this command can only be used by the owner. (default: false)
+	GuildOnly           bool                // Wether this command can only be ran on a guild. (default: false)
+	UsageString         string              // Usage string for this command. (default: "")
+	Usage               []*UsageTag         // Parsed usage tags for this command.
+	Spec                *ArgumentSpec       // Compiled usage string, built when the usage is set or the command is added.
+	Cooldown            int                 // Command cooldown in seconds. (default: 0)
+	Editable            bool                // Wether this command's response will be editable. (default: true)
+	RequiredPermissions int                 // Permissions the user needs to run this command. (default: 0)
+	BotPermissions      int                 // Permissions the bot needs to perform this command. (default: 0)
+	PermissionLevel     int                 // Permission level the user needs to run this command, see bot.AddPermissionLevel (default: 0)
+	CooldownExemptions  []CooldownExemption // Exemptions from this command's cooldown. (default: [])
+	PremiumOnly         bool                // Wether this command can only be used by premium users or in premium guilds. (default: false)
+	PremiumCooldown     int                 // Cooldown in seconds for premium users, -1 to use Cooldown. (default: -1)
+	Dependencies        []string            // Dependencies that must be healthy to run this command, see bot.AddDependency (default: [])
+	Timeout             time.Duration       // How long the command can run before it's cancelled, 0 for no limit. (default: 0)
+	Slash               bool                // Wether this command is registered as a slash command, see bot.EnableCommandSync (default: false)
+	SlashGuilds         SlashGuildFilter    // Guilds the slash command is registered in, nil registers it globally. (default: nil)
 }
 
 func NewCommand(name string, category string, run CommandHandler) *Command {
@@ -42,7 +54,10 @@ func NewCommand(name string, category string, run CommandHandler) *Command {
 		Cooldown:            0,
 		RequiredPermissions: 0,
 		BotPermissions:      0,
+		PremiumOnly:         false,
+		PremiumCooldown:     -1,
 		Usage:               make([]*UsageTag, 0),
+		Spec:                &ArgumentSpec{Tags: make([]*UsageTag, 0)},
 	}
 }
 
@@ -59,15 +74,21 @@ func (c *Command) SetDescription(description string) *Command {
 }
 
 // SetUsage sets the usage string for this command.
-// Panics if there is a parse error in the usage string.
+// Panics if there is a parse error in the usage string or it uses an unknown argument type.
 func (c *Command) SetUsage(usage string) *Command {
 	c.UsageString = usage
-	usg, err := ParseUsage(usage)
+	c.compileUsage()
+	return c
+}
+
+// compileUsage compiles UsageString into Spec, panics on errors just like SetUsage.
+func (c *Command) compileUsage() {
+	spec, err := CompileUsage(c.UsageString)
 	if err != nil {
 		panic(err)
 	}
-	c.Usage = usg
-	return c
+	c.Spec = spec
+	c.Usage = spec.Tags
 }
 
 // Disable disables the command.
@@ -88,6 +109,24 @@ func (c *Command) SetOwnerOnly(toggle bool) *Command {
 	return c
 }
 
+// SetRequiredPermissions sets the permissions the user needs to run this command.
+func (c *Command) SetRequiredPermissions(perms int) *Command {
+	c.RequiredPermissions = perms
+	return c
+}
+
+// SetBotPermissions sets the permissions the bot needs in the channel to run this command.
+func (c *Command) SetBotPermissions(perms int) *Command {
+	c.BotPermissions = perms
+	return c
+}
+
+// SetPermissionLevel sets the permission level the user needs to run this command.
+func (c *Command) SetPermissionLevel(level int) *Command {
+	c.PermissionLevel = level
+	return c
+}
+
 // SetGuildOnly toggles if this command can only be used on a guild.
 func (c *Command) SetGuildOnly(toggle bool) *Command {
 	c.GuildOnly = toggle
@@ -106,21 +145,78 @@ func (c *Command) SetCooldown(cooldown int) *Command {
 	return c
 }
 
+// SetPremiumOnly toggles wether this command can only be used by premium users or in premium guilds.
+func (c *Command) SetPremiumOnly(toggle bool) *Command {
+	c.PremiumOnly = toggle
+	return c
+}
+
+// SetPremiumCooldown sets the cooldown in seconds that applies to premium users instead of the regular cooldown.
+func (c *Command) SetPremiumCooldown(cooldown int) *Command {
+	c.PremiumCooldown = cooldown
+	return c
+}
+
+// SetTimeout sets how long the command can run, after that ctx.Context is cancelled and the user is told it timed out.
+func (c *Command) SetTimeout(timeout time.Duration) *Command {
+	c.Timeout = timeout
+	return c
+}
+
+// SetSlash toggles wether this command is also registered as a slash command.
+func (c *Command) SetSlash(toggle bool) *Command {
+	c.Slash = toggle
+	return c
+}
+
+// SetSlashGuilds registers the slash command only in the guilds filter allows instead of globally,
+// e.g SlashIn(devGuildID), SlashPremium() or SlashOptIn(schema, "key"). It also makes it a slash command.
+func (c *Command) SetSlashGuilds(filter SlashGuildFilter) *Command {
+	c.Slash = true
+	c.SlashGuilds = filter
+	return c
+}
+
+// AddDependencies declares dependencies added with bot.AddDependency, the command is unavailable while one is unhealthy.
+func (c *Command) AddDependencies(names ...string) *Command {
+	c.Dependencies = append(c.Dependencies, names...)
+	return c
+}
+
+// AddCooldownExemption adds exemptions from this command's cooldown.
+func (c *Command) AddCooldownExemption(exemptions ...CooldownExemption) *Command {
+	c.CooldownExemptions = append(c.CooldownExemptions, exemptions...)
+	return c
+}
+
 // CommandContext represents an execution context of a command.
 type CommandContext struct {
-	Command     *Command           // The currently executing command.
-	Message     *discordgo.Message // The message of this command.
-	Session     *discordgo.Session // The discordgo session.
-	Bot         *Bot               // The sapphire Bot.
-	Channel     *discordgo.Channel // The channel this command was ran on.
-	Author      *discordgo.User    // Alias of Context.Message.Author
-	Args        []*Argument        // List of arguments.
-	Prefix      string             // The prefix used to invoke this command.
-	Guild       *discordgo.Guild   // The guild this command was ran on.
-	Flags       map[string]string  // Map of flags passed to the command. e.g --flag=yo
-	Locale      *Language          // The current language.
-	RawArgs     []string           // The raw args that may not match the usage string.
-	InvokedName string             // The name this command was invoked as, this includes the used alias.
+	Command     *Command               // The currently executing command.
+	Message     *discordgo.Message     // The message of this command.
+	Session     *discordgo.Session     // The discordgo session.
+	Bot         *Bot                   // The sapphire Bot.
+	Channel     *discordgo.Channel     // The channel this command was ran on.
+	Author      *discordgo.User        // Alias of Context.Message.Author
+	Args        []*Argument            // List of arguments.
+	Prefix      string                 // The prefix used to invoke this command.
+	Guild       *discordgo.Guild       // The guild this command was ran on.
+	Flags       map[string]string      // Map of flags passed to the command. e.g --flag=yo
+	Locale      *Language              // The current language.
+	RawArgs     []string               // The raw args that may not match the usage string.
+	RawContent  string                 // The message content after the prefix, before any flags or arguments are parsed out.
+	ArgOffsets  []int                  // Byte offsets of each raw argument in RawContent.
+	InvokedName string                 // The name this command was invoked as, this includes the used alias.
+	Shared      map[string]interface{} // Data shared between commands of the same chain or ctx.Invoke calls.
+	Context     context.Context        // Cancelled when the command times out, pass it to slow calls. (default: context.Background())
+	Interaction *Interaction           // The slash command, component or modal, nil for message commands.
+	Response    Response               // Where replies go. (default: the command's channel, the interaction for interactions)
+	replies     map[string]*discordgo.Message
+	repliesLock sync.Mutex
+	referenced  *discordgo.Message
+	invoked     bool  // Set for ctx.Invoke contexts, they don't edit the invoking command's reply.
+	granted     bool  // Set when a permission override allows the command, see LoadPermissionsCommand
+	cooldowns   bool  // Wether the cooldown inhibitor applies, ctx.Invoke skips cooldowns.
+	reported    int32 // Set once the command's result is recorded, a handler finishing after it timed out is ignored.
 }
 
 // CommandError represents a panic that occured during a command execution.
@@ -139,10 +235,6 @@ func (err *CommandError) Error() string {
 // Reply replies with a string.
 // It will call Sprintf() on the content if atleast one vararg is passed.
 func (ctx *CommandContext) Reply(content string, args ...interface{}) (*discordgo.Message, error) {
-	if !ctx.Command.Editable {
-		return ctx.ReplyNoEdit(content)
-	}
-
 	// This is neccessary to avoid problems with dynamic content
 	// ctx.Reply(dynamicVariable)
 	// If the user doesn't intend to use the formatting then don't use Sprintf
@@ -152,18 +244,7 @@ func (ctx *CommandContext) Reply(content string, args ...interface{}) (*discordg
 	if len(args) > 0 {
 		content = fmt.Sprintf(content, args...)
 	}
-
-	m, ok := ctx.Bot.CommandEdits[ctx.Message.ID]
-	if !ok {
-		msg, err := ctx.Session.ChannelMessageSend(ctx.Channel.ID, content)
-		if err != nil {
-			return nil, err
-		}
-		ctx.Bot.CommandEdits[ctx.Message.ID] = msg.ID
-		return msg, nil
-	}
-	return ctx.Session.ChannelMessageEditComplex(discordgo.NewMessageEdit(ctx.Channel.ID, m).
-		SetContent(content))
+	return ctx.respond(&ResponseMessage{Content: content})
 }
 
 // ReplyNoEdit replies with content but does not consider editable option of the command.
@@ -172,54 +253,116 @@ func (ctx *CommandContext) ReplyNoEdit(content string, args ...interface{}) (*di
 	if len(args) > 0 {
 		content = fmt.Sprintf(content, args...)
 	}
-	return ctx.Session.ChannelMessageSend(ctx.Channel.ID, content)
+	return ctx.response().Send(&ResponseMessage{Content: content})
 }
 
-// ReplyLocale sends a localized key for the current context's locale.
-func (ctx *CommandContext) ReplyLocale(key string, args ...interface{}) (*discordgo.Message, error) {
-	res := ctx.Locale.Get(key, args...)
+// ReplyEphemeral replies with content only the user can see, for message commands it's a normal reply.
+// It will call Sprintf() on the content if atleast one vararg is passed.
+func (ctx *CommandContext) ReplyEphemeral(content string, args ...interface{}) (*discordgo.Message, error) {
+	// See the comments in Reply
+	if len(args) > 0 {
+		content = fmt.Sprintf(content, args...)
+	}
+	return ctx.respond(&ResponseMessage{Content: content, Ephemeral: true})
+}
+
+// Defer tells the user the reply will take a while, slash commands show "thinking..." and message commands typing.
+func (ctx *CommandContext) Defer() error {
+	return ctx.response().Defer()
+}
 
-	if res != "" {
-		return ctx.Reply(res)
+// ReplyEphemeralLike replies with content and deletes the reply after ttl has passed using the bot's scheduler.
+// If deleteTrigger is true the message that invoked the command is also deleted.
+// The reply is always sent as a new message regardless of the editable option of the command.
+// Interactions reply ephemerally and have no message to delete, their replies can only be deleted within 15 minutes.
+func (ctx *CommandContext) ReplyEphemeralLike(content string, ttl time.Duration, deleteTrigger bool) (*discordgo.Message, error) {
+	response := ctx.response()
+	msg, err := response.Send(&ResponseMessage{Content: content, Ephemeral: ctx.Interaction != nil})
+	if err != nil {
+		return nil, err
 	}
+	trigger := ctx.Message
+	deleteTrigger = deleteTrigger && ctx.Interaction == nil
+	ctx.Bot.Scheduler.After(ttl, func() {
+		response.Delete(msg)
+		if deleteTrigger {
+			ctx.Session.ChannelMessageDelete(trigger.ChannelID, trigger.ID)
+		}
+	})
+	return msg, nil
+}
 
-	// Try the default locale.
-	fallback := ctx.Bot.DefaultLocale.Get(key, args...)
-	if fallback != "" {
-		return ctx.Reply(fallback)
+// ReplyOrEdit sends content the first time it's called with key and edits that same message on later calls
+// within this command invocation, this is useful for progress messages e.g "Working..." then "Done!"
+// The messages go through ctx.Response so for interactions the first one answers the interaction.
+// It will call Sprintf() on the content if atleast one vararg is passed.
+func (ctx *CommandContext) ReplyOrEdit(key string, content string, args ...interface{}) (*discordgo.Message, error) {
+	// See the comments in Reply
+	if len(args) > 0 {
+		content = fmt.Sprintf(content, args...)
 	}
 
-	// All failed, the key isn't translated, report the error.
-	// We have to also watch out if the error message isn't translated!
-	return ctx.Reply(ctx.Locale.GetDefault("LOCALE_NO_KEY", key,
-		ctx.Bot.DefaultLocale.GetDefault("LOCALE_NO_KEY", key,
-			fmt.Sprintf("No localization found for the key \"%s\" Please report this to the developers.", key))))
+	// The response is looked up before locking, response() takes the same lock.
+	response := ctx.response()
+	ctx.repliesLock.Lock()
+	defer ctx.repliesLock.Unlock()
+
+	if msg, ok := ctx.replies[key]; ok {
+		return response.Edit(msg, &ResponseMessage{Content: content})
+	}
+
+	msg, err := response.Send(&ResponseMessage{Content: content})
+	if err != nil {
+		return nil, err
+	}
+	if ctx.replies == nil {
+		ctx.replies = make(map[string]*discordgo.Message)
+	}
+	ctx.replies[key] = msg
+	return msg, nil
+}
+
+// DeleteReplies deletes all messages sent by ReplyOrEdit in this invocation.
+// It tries to delete all of them and returns the first error it encountered.
+func (ctx *CommandContext) DeleteReplies() error {
+	ctx.repliesLock.Lock()
+	defer ctx.repliesLock.Unlock()
+
+	var first error
+	for key, msg := range ctx.replies {
+		if err := ctx.Response.Delete(msg); err != nil && first == nil {
+			first = err
+		}
+		delete(ctx.replies, key)
+	}
+	return first
+}
+
+// ReplyLocale sends a localized key for the current context's locale.
+// If the key is a localized embed (see Language.SetEmbed) it replies with the embed and it's components instead.
+func (ctx *CommandContext) ReplyLocale(key string, args ...interface{}) (*discordgo.Message, error) {
+	return ctx.respond(ctx.Bot.localizeMessage(ctx.Locale, key, args...))
 }
 
 // EditLocale edits msg with a localized key
+// If the key is a localized embed (see Language.SetEmbed) the message content is replaced with the embed.
 func (ctx *CommandContext) EditLocale(msg *discordgo.Message, key string, args ...interface{}) (*discordgo.Message, error) {
-	res := ctx.Locale.Get(key, args...)
-	if res != "" {
-		return ctx.Edit(msg, res)
-	}
-	fallback := ctx.Bot.DefaultLocale.Get(key, args...)
-	if fallback != "" {
-		return ctx.Edit(msg, fallback)
-	}
-	// All failed, the key isn't translated, report the error.                                                                              // We have to also watch out if the error message isn't translated!
-	return ctx.Edit(msg, ctx.Locale.GetDefault("LOCALE_NO_KEY", key,
-		ctx.Bot.DefaultLocale.GetDefault("LOCALE_NO_KEY", key,
-			fmt.Sprintf("No localization found for the key \"%s\" Please report this to the developers.", key))))
+	return ctx.response().Edit(msg, ctx.Bot.localizeMessage(ctx.Locale, key, args...))
+}
+
+// localize resolves key in the current locale then the default locale, see bot.Localize
+func (ctx *CommandContext) localize(key string, args ...interface{}) (string, *discordgo.MessageEmbed) {
+	return ctx.Bot.Localize(ctx.Locale, key, args...)
 }
 
-// Edit edits msg's content
+// Edit edits msg's content, msg has to be sent through the context's Response e.g by Reply.
 // It will call Sprintf() on the content if atleast one vararg is passed.
 func (ctx *CommandContext) Edit(msg *discordgo.Message, content string, args ...interface{}) (*discordgo.Message, error) {
 	// See the comments in Reply
 	if len(args) > 0 {
 		content = fmt.Sprintf(content, args...)
 	}
-	return ctx.Session.ChannelMessageEdit(msg.ChannelID, msg.ID, content)
+	return ctx.response().Edit(msg, &ResponseMessage{Content: content})
 }
 
 // HasArgs returns true if there is atleast one argument in the raw args.
@@ -229,24 +372,12 @@ func (ctx *CommandContext) HasArgs() bool {
 
 // ReplyEmbed replies with an embed.
 func (ctx *CommandContext) ReplyEmbed(embed *discordgo.MessageEmbed) (*discordgo.Message, error) {
-	if !ctx.Command.Editable {
-		return ctx.ReplyEmbedNoEdit(embed)
-	}
-	m, ok := ctx.Bot.CommandEdits[ctx.Message.ID]
-	if !ok {
-		msg, err := ctx.Session.ChannelMessageSendEmbed(ctx.Channel.ID, embed)
-		if err != nil {
-			return nil, err
-		}
-		ctx.Bot.CommandEdits[ctx.Message.ID] = msg.ID
-		return msg, nil
-	}
-	return ctx.Session.ChannelMessageEditComplex(discordgo.NewMessageEdit(ctx.Channel.ID, m).SetContent("").SetEmbed(embed))
+	return ctx.respond(&ResponseMessage{Embed: embed})
 }
 
 // ReplyEmbedNoEdits replies with an embed but not considering the editable option of the command.
 func (ctx *CommandContext) ReplyEmbedNoEdit(embed *discordgo.MessageEmbed) (*discordgo.Message, error) {
-	return ctx.Session.ChannelMessageSendEmbed(ctx.Channel.ID, embed)
+	return ctx.response().Send(&ResponseMessage{Embed: embed})
 }
 
 // BuildEmbed calls ReplyEmbed(embed.Build())
@@ -318,71 +449,97 @@ func (ctx *CommandContext) JoinedArgs(sliced ...int) string {
 	return strings.Join(ctx.RawArgs[s:], " ")
 }
 
+// ArgString returns the rest of the message starting from the raw argument at index from, exactly as the user typed it.
+// Unlike JoinedArgs this keeps the original spacing, newlines and any flags that came after it.
+// Returns an empty string if the argument doesn't exist.
+func (ctx *CommandContext) ArgString(from int) string {
+	if from < 0 || from >= len(ctx.ArgOffsets) {
+		return ""
+	}
+	return ctx.RawContent[ctx.ArgOffsets[from]:]
+}
+
 // Parses the raw args and fills in ctx.Args and returns true on success and on failure it replies with the error and returns false
 // This is called in the command handler to process the arguments, it shouldn't be used in normal code
 // It is exported to allow modification of the command handler in your own bot and avoid this line from giving errors.
 func (ctx *CommandContext) ParseArgs() bool {
-	// Helper to get an index without panicking.
-	safeGet := func(idx int) string {
-		if len(ctx.RawArgs) > idx {
-			return ctx.RawArgs[idx]
-		}
-		return ""
+	spec := ctx.Command.Spec
+
+	// Commands built by hand might have skipped AddCommand/SetUsage, compile late in that case.
+	if spec == nil || spec.Usage != ctx.Command.UsageString {
+		ctx.Command.compileUsage()
+		spec = ctx.Command.Spec
 	}
 
 	// If it doesn't need arguments we are done.
-	if ctx.Command.UsageString == "" {
+	if len(spec.Tags) == 0 {
 		return true
 	}
 
-	ctx.Args = make([]*Argument, len(ctx.Command.Usage))
-
-	for i, tag := range ctx.Command.Usage {
-		v := safeGet(i)
-
-		if tag.Required && v == "" {
-			ctx.Reply("The argument **%s** is required.", tag.Name)
+	for i, tag := range spec.Tags {
+		if tag.Required && i >= len(ctx.RawArgs) {
+			ctx.argumentError(NewArgumentError("ARGUMENT_REQUIRED", tag.Name))
 			return false
 		}
+	}
 
-		if tag.Rest {
-			cut := ctx.RawArgs[i:]
-
-			for ii, raw := range cut {
-				arg, err := ParseArgument(ctx, tag, raw)
-
-				if err != nil {
-					ctx.Reply(err.Error())
-					return false
-				}
-
-				// This ugly test is because we allocate a slice of n where n = amount of usage tags.
-				// So imagine <test:string> <another:string...> that's 2
-				// We hit rest and we don't have enough size to set that index directly. (because another can hold infinite args)
-				// So we use append, however we gotta be careful the <another:string...> tag also got it's dedicated index allocated
-				// So we do an ugly check: if we are on that index then we can just set directly otherwise we append.
-				// This could eventually be cleaned up for a better solution.
-				if ii > len(ctx.Args)-2 {
-					ctx.Args = append(ctx.Args, arg)
-				} else {
-					ctx.Args[i] = arg
-				}
+	ctx.Args = make([]*Argument, 0, len(spec.Tags))
+
+	for i, tag := range spec.Tags {
+		if !tag.Rest {
+			var arg *Argument
+			var err error
+			if len(ctx.RawArgs) > i {
+				arg, err = ParseArgument(ctx, tag, ctx.RawArgs[i])
+			} else {
+				arg, err = ResolveDefault(ctx, tag)
 			}
-		} else {
-			arg, err := ParseArgument(ctx, tag, safeGet(i))
+			if err != nil {
+				ctx.argumentError(err)
+				return false
+			}
+
+			ctx.Args = append(ctx.Args, arg)
+			continue
+		}
 
+		// Rest tags are always last, they parse every remaining raw argument.
+		if len(ctx.RawArgs) <= i {
+			arg, err := ResolveDefault(ctx, tag)
 			if err != nil {
-				ctx.Reply(err.Error())
+				ctx.argumentError(err)
 				return false
 			}
+			ctx.Args = append(ctx.Args, arg)
+			break
+		}
 
-			ctx.Args[i] = arg
+		for _, raw := range ctx.RawArgs[i:] {
+			arg, err := ParseArgument(ctx, tag, raw)
+			if err != nil {
+				ctx.argumentError(err)
+				return false
+			}
+			ctx.Args = append(ctx.Args, arg)
 		}
 	}
 
 	return true
 }
 
+// argumentError replies with an invalid argument, localized if it's an *ArgumentError, followed by the command's usage.
+func (ctx *CommandContext) argumentError(err error) {
+	msg := err.Error()
+	if argErr, ok := err.(*ArgumentError); ok {
+		msg = ctx.Locale.GetDefault(argErr.Key, msg, argErr.Args...)
+	}
+	if usage := ctx.Command.UsageString; usage != "" {
+		msg += "\n" + ctx.Locale.GetDefault("ARGUMENT_USAGE", English.Get("ARGUMENT_USAGE", ctx.Prefix, ctx.InvokedName, usage),
+			ctx.Prefix, ctx.InvokedName, usage)
+	}
+	ctx.Reply(msg)
+}
+
 // User gets a user by id, returns nil if not found.
 func (ctx *CommandContext) User(id string) *discordgo.User {
 	for _, guild := range ctx.Session.State.Guilds {
@@ -418,6 +575,99 @@ func (ctx *CommandContext) Member(id string) *discordgo.Member {
 	return member
 }
 
+// HasPermissions checks if the author has all of perms in the channel, always true in DMs.
+// It's also true when a permission override of the guild allows the author to use the command.
+func (ctx *CommandContext) HasPermissions(perms int) bool {
+	if ctx.Guild == nil || ctx.granted {
+		return true
+	}
+	p, err := ctx.Session.State.UserChannelPermissions(ctx.Author.ID, ctx.Channel.ID)
+	return err == nil && Permissions(p).Has(perms)
+}
+
+// BotHasPermissions checks if the bot has all of perms in the channel, always true in DMs.
+func (ctx *CommandContext) BotHasPermissions(perms int) bool {
+	if ctx.Guild == nil {
+		return true
+	}
+	p, err := ctx.Session.State.UserChannelPermissions(ctx.Session.State.User.ID, ctx.Channel.ID)
+	return err == nil && Permissions(p).Has(perms)
+}
+
+// Invoke runs another command by name or alias with args as if the user typed it, in the same channel and as the same user.
+// The command goes through the same validations as usual but cooldowns are skipped, use InvokeWithCooldown to apply them.
+// Flags of the current invocation are passed along. Returns ErrCommandNotFound if the command doesn't exist
+// or ErrCommandInhibited if a validation stopped it.
+func (ctx *CommandContext) Invoke(name string, args ...string) error {
+	return ctx.invoke(name, args, false)
+}
+
+// InvokeWithCooldown is like Invoke but also applies the invoked command's cooldown.
+func (ctx *CommandContext) InvokeWithCooldown(name string, args ...string) error {
+	return ctx.invoke(name, args, true)
+}
+
+func (ctx *CommandContext) invoke(name string, args []string, cooldowns bool) error {
+	cmd := ctx.Bot.GetCommand(strings.ToLower(name))
+	if cmd == nil {
+		return ErrCommandNotFound
+	}
+	raw := strings.Join(args, " ")
+	rawArgs, offsets := splitArgs(raw)
+	// Invoked commands of an interaction answer the same interaction, a new channel response would send
+	// messages the interaction never hears about.
+	var response Response
+	if ctx.Interaction != nil {
+		response = ctx.response()
+	}
+	return ctx.Bot.ExecuteCommand(&CommandContext{
+		Bot:         ctx.Bot,
+		Command:     cmd,
+		Message:     ctx.Message,
+		Channel:     ctx.Channel,
+		Session:     ctx.Session,
+		Author:      ctx.Author,
+		RawArgs:     rawArgs,
+		RawContent:  raw,
+		ArgOffsets:  offsets,
+		Prefix:      ctx.Prefix,
+		Guild:       ctx.Guild,
+		Flags:       ctx.Flags,
+		Locale:      ctx.Locale,
+		InvokedName: strings.ToLower(name),
+		Shared:      ctx.Shared,
+		Context:     ctx.Context,
+		Interaction: ctx.Interaction,
+		Response:    response,
+		invoked:     true,
+	}, cooldowns)
+}
+
+// ReferencedMessage returns the message the command invocation replied to, nil if it isn't a reply.
+// It looks in the state first then fetches it from the API, the result is remembered for this invocation.
+func (ctx *CommandContext) ReferencedMessage() (*discordgo.Message, error) {
+	if ctx.referenced != nil {
+		return ctx.referenced, nil
+	}
+	ref := ctx.Message.MessageReference
+	if ref == nil || ref.MessageID == "" {
+		return nil, nil
+	}
+	channelID := ref.ChannelID
+	if channelID == "" {
+		channelID = ctx.Message.ChannelID
+	}
+	msg, err := ctx.Session.State.Message(channelID, ref.MessageID)
+	if err != nil {
+		msg, err = ctx.Session.ChannelMessage(channelID, ref.MessageID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ctx.referenced = msg
+	return msg, nil
+}
+
 // GetFirstMentionedUser returns the first user mentioned in the message.
 func (ctx *CommandContext) GetFirstMentionedUser() *discordgo.User {
 	if len(ctx.Message.Mentions) < 1 {
@@ -433,7 +683,21 @@ func (ctx *CommandContext) CodeBlock(lang, content string, args ...interface{})
 	return ctx.Reply("```%s\n%s```", lang, content)
 }
 
-// React adds the reaction emoji to the message that triggered the command.
-func (ctx *CommandContext) React(emoji string) error {
-	return ctx.Session.MessageReactionAdd(ctx.Channel.ID, ctx.Message.ID, emoji)
+// React adds the reaction emojis in order to the message that triggered the command.
+// See AddReactions for how multiple reactions are paced and how errors are handled.
+// Interactions have no such message, they get ErrNoTriggerMessage.
+func (ctx *CommandContext) React(emojis ...string) error {
+	if ctx.Interaction != nil {
+		return ErrNoTriggerMessage
+	}
+	return AddReactions(ctx.Session, ctx.Channel.ID, ctx.Message.ID, emojis...)
+}
+
+// RemoveOwnReactions removes the bot's reactions from the message that triggered the command.
+// If no emojis are given all of the bot's reactions are removed, see RemoveOwnReactions.
+func (ctx *CommandContext) RemoveOwnReactions(emojis ...string) error {
+	if ctx.Interaction != nil {
+		return ErrNoTriggerMessage
+	}
+	return RemoveOwnReactions(ctx.Session, ctx.Channel.ID, ctx.Message.ID, emojis...)
 }
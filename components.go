@@ -0,0 +1,127 @@
+package sapphire
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+)
+
+// Component types.
+const (
+	ComponentActionRow     = 1
+	ComponentButton        = 2
+	ComponentStringSelect  = 3
+	ComponentTextInput     = 4
+	ComponentRoleSelect    = 6
+	ComponentChannelSelect = 8
+)
+
+// Button styles.
+const (
+	ButtonPrimary   = 1
+	ButtonSecondary = 2
+	ButtonSuccess   = 3
+	ButtonDanger    = 4
+)
+
+// Component is a button, select menu or text input, they are put in action rows.
+// Clicks and submits go to the component handler named by the start of the custom ID, see bot.AddComponentHandler
+type Component struct {
+	Type         int             `json:"type"`
+	CustomID     string          `json:"custom_id,omitempty"`
+	Label        string          `json:"label,omitempty"`
+	Style        int             `json:"style,omitempty"`
+	Placeholder  string          `json:"placeholder,omitempty"`
+	Options      []*SelectOption `json:"options,omitempty"`
+	ChannelTypes []int           `json:"channel_types,omitempty"`
+	Value        string          `json:"value,omitempty"`
+	Required     *bool           `json:"required,omitempty"`
+	MinValues    *int            `json:"min_values,omitempty"`
+	MaxValues    int             `json:"max_values,omitempty"`
+	Components   []*Component    `json:"components,omitempty"`
+}
+
+// SelectOption is an option of a select menu.
+type SelectOption struct {
+	Label       string          `json:"label"`
+	Value       string          `json:"value"`
+	Description string          `json:"description,omitempty"`
+	Default     bool            `json:"default,omitempty"`
+	Emoji       *ComponentEmoji `json:"emoji,omitempty"`
+}
+
+// ComponentEmoji is the emoji of a select option, only the name for unicode emojis.
+type ComponentEmoji struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"`
+}
+
+// NewActionRow creates a row of up to 5 buttons or a single select menu or text input.
+func NewActionRow(components ...*Component) *Component {
+	return &Component{Type: ComponentActionRow, Components: components}
+}
+
+// NewButton creates a button with one of the button styles e.g ButtonPrimary
+func NewButton(customID, label string, style int) *Component {
+	return &Component{Type: ComponentButton, CustomID: customID, Label: label, Style: style}
+}
+
+// NewSelectMenu creates a select menu of up to 25 options.
+func NewSelectMenu(customID, placeholder string, options ...*SelectOption) *Component {
+	return &Component{Type: ComponentStringSelect, CustomID: customID, Placeholder: placeholder, Options: options}
+}
+
+// NewChannelSelect creates a select menu of the guild's text channels.
+func NewChannelSelect(customID, placeholder string) *Component {
+	return &Component{Type: ComponentChannelSelect, CustomID: customID, Placeholder: placeholder,
+		ChannelTypes: []int{int(discordgo.ChannelTypeGuildText)}}
+}
+
+// NewRoleSelect creates a select menu of the guild's roles.
+func NewRoleSelect(customID, placeholder string) *Component {
+	return &Component{Type: ComponentRoleSelect, CustomID: customID, Placeholder: placeholder}
+}
+
+// NewTextInput creates a single line text input for modals, value is what it's filled with.
+func NewTextInput(customID, label, value string) *Component {
+	return &Component{Type: ComponentTextInput, CustomID: customID, Label: label, Style: 1, Value: value}
+}
+
+// SetRequired sets if a text input has to be filled in. (default: true)
+func (c *Component) SetRequired(required bool) *Component {
+	c.Required = &required
+	return c
+}
+
+// SetValues sets how many options of a select menu can be picked at once. (default: 1 and 1)
+func (c *Component) SetValues(min, max int) *Component {
+	c.MinValues = &min
+	c.MaxValues = max
+	return c
+}
+
+// ErrNotInteraction is returned when something only interactions can do is done for a message command.
+var ErrNotInteraction = errors.New("only interactions can do this")
+
+// UpdateMessage replaces the message of the clicked component, for message commands it's the same as Reply.
+func (ctx *CommandContext) UpdateMessage(msg *ResponseMessage) (*discordgo.Message, error) {
+	r, ok := ctx.response().(*interactionResponse)
+	if !ok || ctx.Interaction.Type == InteractionApplicationCommand {
+		return ctx.respond(msg)
+	}
+	return r.update(msg)
+}
+
+// ShowModal answers the interaction with a modal of text inputs, each in it's own action row.
+// The submit goes to the component handler named by the start of customID.
+// It has to be the first response, message commands get ErrNotInteraction.
+func (ctx *CommandContext) ShowModal(customID, title string, inputs ...*Component) error {
+	r, ok := ctx.response().(*interactionResponse)
+	if !ok || ctx.Interaction.Type == InteractionModalSubmit {
+		return ErrNotInteraction
+	}
+	rows := make([]*Component, len(inputs))
+	for i, input := range inputs {
+		rows[i] = NewActionRow(input)
+	}
+	return r.modal(map[string]interface{}{"custom_id": customID, "title": title, "components": rows})
+}
@@ -0,0 +1,320 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ConfigType is the type of a config key, it decides how input is validated and normalized.
+type ConfigType string
+
+const (
+	ConfigString  ConfigType = "string"
+	ConfigInt     ConfigType = "int"
+	ConfigBool    ConfigType = "bool"
+	ConfigChannel ConfigType = "channel" // Stored as the channel ID.
+	ConfigRole    ConfigType = "role"    // Stored as the role ID.
+)
+
+// ConfigValidator validates a normalized value before it's stored, return an error to reject it.
+// The error message is shown to the user.
+type ConfigValidator func(bot *Bot, guildID, value string) error
+
+// ConfigKey describes a single per-guild config key.
+type ConfigKey struct {
+	Name        string          `json:"name"`
+	Type        ConfigType      `json:"type"`
+	Default     string          `json:"default"`
+	Description string          `json:"description"` // Locale key or plain text.
+	Validator   ConfigValidator `json:"-"`
+}
+
+// ConfigSchema describes the per-guild config of an extension.
+// Keys are stored in the settings provider as "<schema name>.<key name>"
+// Register it with bot.AddConfigSchema to get a generated config command and dashboard JSON.
+type ConfigSchema struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"` // Locale key or plain text.
+	Keys        []*ConfigKey `json:"keys"`
+}
+
+// NewConfigSchema creates an empty schema for an extension.
+func NewConfigSchema(name, description string) *ConfigSchema {
+	return &ConfigSchema{Name: name, Description: description}
+}
+
+// Add adds a key to the schema, description can be a locale key.
+func (s *ConfigSchema) Add(name string, typ ConfigType, def string, description string) *ConfigSchema {
+	s.Keys = append(s.Keys, &ConfigKey{Name: name, Type: typ, Default: def, Description: description})
+	return s
+}
+
+// SetValidator sets the validator of a key, panics if the key doesn't exist.
+func (s *ConfigSchema) SetValidator(name string, validator ConfigValidator) *ConfigSchema {
+	key := s.Key(name)
+	if key == nil {
+		panic(fmt.Sprintf("The config key '%s' doesn't exist in '%s'", name, s.Name))
+	}
+	key.Validator = validator
+	return s
+}
+
+// Key returns the key by name, nil if not found.
+func (s *ConfigSchema) Key(name string) *ConfigKey {
+	for _, key := range s.Keys {
+		if key.Name == name {
+			return key
+		}
+	}
+	return nil
+}
+
+// SettingsKey returns the key name used in the settings provider.
+func (s *ConfigSchema) SettingsKey(name string) string {
+	return s.Name + "." + name
+}
+
+// Get returns the guild's value for the key or it's default, panics if the key doesn't exist.
+func (s *ConfigSchema) Get(bot *Bot, guildID, name string) string {
+	key := s.Key(name)
+	if key == nil {
+		panic(fmt.Sprintf("The config key '%s' doesn't exist in '%s'", name, s.Name))
+	}
+	value, ok, err := bot.Settings.Get(guildID, s.SettingsKey(name))
+	if err != nil || !ok {
+		return key.Default
+	}
+	return value
+}
+
+// GetBool is like Get but parses the value as a bool.
+func (s *ConfigSchema) GetBool(bot *Bot, guildID, name string) bool {
+	b, _ := strconv.ParseBool(s.Get(bot, guildID, name))
+	return b
+}
+
+// GetInt is like Get but parses the value as an int.
+func (s *ConfigSchema) GetInt(bot *Bot, guildID, name string) int {
+	i, _ := strconv.Atoi(s.Get(bot, guildID, name))
+	return i
+}
+
+// Set normalizes and validates the value then stores it.
+func (s *ConfigSchema) Set(bot *Bot, guildID, name, value string) (string, error) {
+	key := s.Key(name)
+	if key == nil {
+		return "", fmt.Errorf("The config key '%s' doesn't exist in '%s'", name, s.Name)
+	}
+	normalized, err := normalizeConfig(bot, guildID, key.Type, value)
+	if err != nil {
+		return "", err
+	}
+	if key.Validator != nil {
+		if err := key.Validator(bot, guildID, normalized); err != nil {
+			return "", err
+		}
+	}
+	if err := bot.Settings.Set(guildID, s.SettingsKey(name), normalized); err != nil {
+		return "", err
+	}
+	bot.Publish(&SettingsChanged{GuildID: guildID, Schema: s.Name, Key: name, Value: normalized})
+	return normalized, nil
+}
+
+// Reset removes the guild's value so the default is used again.
+func (s *ConfigSchema) Reset(bot *Bot, guildID, name string) error {
+	if err := bot.Settings.Delete(guildID, s.SettingsKey(name)); err != nil {
+		return err
+	}
+	bot.Publish(&SettingsChanged{GuildID: guildID, Schema: s.Name, Key: name})
+	return nil
+}
+
+// normalizeConfig parses user input into the stored form for the type.
+func normalizeConfig(bot *Bot, guildID string, typ ConfigType, value string) (string, error) {
+	switch typ {
+	case ConfigInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return "", fmt.Errorf("**%s** is not a valid number.", value)
+		}
+		return value, nil
+	case ConfigBool:
+		switch strings.ToLower(value) {
+		case "true", "yes", "on", "enable", "enabled", "1":
+			return "true", nil
+		case "false", "no", "off", "disable", "disabled", "0":
+			return "false", nil
+		}
+		return "", fmt.Errorf("**%s** must be yes or no.", value)
+	case ConfigChannel:
+		match := ChannelMentionRegex.FindStringSubmatch(value)
+		if len(match) < 2 {
+			return "", fmt.Errorf("**%s** must be a valid channel mention or ID.", value)
+		}
+		channel, err := bot.Session.State.Channel(match[1])
+		if err != nil || channel.GuildID != guildID {
+			return "", fmt.Errorf("That channel cannot be found in this server.")
+		}
+		return channel.ID, nil
+	case ConfigRole:
+		guild, err := bot.Session.State.Guild(guildID)
+		if err != nil {
+			return "", err
+		}
+		if role := findRole(guild, value); role != nil {
+			return role.ID, nil
+		}
+		return "", fmt.Errorf("That role cannot be found in this server.")
+	default:
+		return value, nil
+	}
+}
+
+// displayConfig formats a stored value for display.
+func displayConfig(typ ConfigType, value string) string {
+	if value == "" {
+		return "-"
+	}
+	switch typ {
+	case ConfigChannel:
+		return "<#" + value + ">"
+	case ConfigRole:
+		return "<@&" + value + ">"
+	default:
+		return value
+	}
+}
+
+// AddConfigSchema registers an extension's config schema.
+// The first registered schema also adds the builtin config command to view and edit the config.
+func (bot *Bot) AddConfigSchema(schema *ConfigSchema) *Bot {
+	bot.ConfigSchemas[schema.Name] = schema
+	if bot.GetCommand("config") == nil {
+		bot.AddCommand(NewCommand("config", "Settings", configCommand).
+			SetDescription("View or change this server's configuration.").
+			SetUsage("[extension:string] [key:string] [value:string...]").
+			SetGuildOnly(true).
+			AddAliases("conf"))
+	}
+	return bot
+}
+
+// ConfigJSON returns the registered schemas along with the guild's current values as JSON, useful for dashboards.
+// Pass an empty guild ID to get the schemas with only the defaults.
+func (bot *Bot) ConfigJSON(guildID string) ([]byte, error) {
+	type key struct {
+		*ConfigKey
+		Value string `json:"value"`
+	}
+	type schema struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Keys        []key  `json:"keys"`
+	}
+	names := make([]string, 0, len(bot.ConfigSchemas))
+	for name := range bot.ConfigSchemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	res := make([]schema, 0, len(names))
+	for _, name := range names {
+		s := bot.ConfigSchemas[name]
+		out := schema{Name: s.Name, Description: s.Description}
+		for _, k := range s.Keys {
+			value := k.Default
+			if guildID != "" {
+				value = s.Get(bot, guildID, k.Name)
+			}
+			out.Keys = append(out.Keys, key{ConfigKey: k, Value: value})
+		}
+		res = append(res, out)
+	}
+	return json.Marshal(res)
+}
+
+// describe returns the localized text for a description that may be a locale key.
+func (ctx *CommandContext) describe(description string) string {
+	if res := ctx.Locale.Get(description); res != "" {
+		return res
+	}
+	if res := ctx.Bot.DefaultLocale.Get(description); res != "" {
+		return res
+	}
+	return description
+}
+
+func configCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+
+	if !ctx.Arg(0).IsProvided() {
+		names := make([]string, 0, len(bot.ConfigSchemas))
+		for name := range bot.ConfigSchemas {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		embed := NewEmbed().SetTitle("Configuration").SetColor(bot.Color).
+			SetFooter(fmt.Sprintf("%sconfig <extension> to view an extension's keys.", ctx.Prefix))
+		for _, name := range names {
+			embed.AddField(name, ctx.describe(bot.ConfigSchemas[name].Description))
+		}
+		ctx.BuildEmbed(embed)
+		return
+	}
+
+	schema, ok := bot.ConfigSchemas[ctx.Arg(0).AsString()]
+	if !ok {
+		ctx.ReplyLocale("COMMAND_CONFIG_NO_EXTENSION", ctx.Arg(0).AsString())
+		return
+	}
+
+	if !ctx.Arg(1).IsProvided() {
+		embed := NewEmbed().SetTitle(schema.Name).SetDescription(ctx.describe(schema.Description)).SetColor(bot.Color).
+			SetFooter(fmt.Sprintf("%sconfig %s <key> <value|reset> to change a key.", ctx.Prefix, schema.Name))
+		for _, key := range schema.Keys {
+			embed.AddField(fmt.Sprintf("%s (%s)", key.Name, key.Type),
+				fmt.Sprintf("%s\n**Value:** %s", ctx.describe(key.Description), displayConfig(key.Type, schema.Get(bot, ctx.Guild.ID, key.Name))))
+		}
+		ctx.BuildEmbed(embed)
+		return
+	}
+
+	key := schema.Key(ctx.Arg(1).AsString())
+	if key == nil {
+		ctx.ReplyLocale("COMMAND_CONFIG_NO_KEY", ctx.Arg(1).AsString(), schema.Name)
+		return
+	}
+
+	if !ctx.Arg(2).IsProvided() {
+		ctx.ReplyLocale("COMMAND_CONFIG_VALUE", schema.Name, key.Name, displayConfig(key.Type, schema.Get(bot, ctx.Guild.ID, key.Name)))
+		return
+	}
+
+	// Only server managers can change the config.
+	member := ctx.Member(ctx.Author.ID)
+	if member == nil || !PermissionsForMember(ctx.Guild, member).Has(discordgo.PermissionManageServer) {
+		ctx.ReplyLocale("COMMAND_CONFIG_NO_PERMISSION")
+		return
+	}
+
+	value := ctx.ArgString(2)
+	if strings.EqualFold(value, "reset") {
+		if err := schema.Reset(bot, ctx.Guild.ID, key.Name); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_CONFIG_RESET", schema.Name, key.Name, displayConfig(key.Type, key.Default))
+		return
+	}
+
+	normalized, err := schema.Set(bot, ctx.Guild.ID, key.Name, value)
+	if err != nil {
+		ctx.Reply(err.Error())
+		return
+	}
+	ctx.ReplyLocale("COMMAND_CONFIG_SET", schema.Name, key.Name, displayConfig(key.Type, normalized))
+}
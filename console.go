@@ -0,0 +1,265 @@
+package sapphire
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"io"
+	"net"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConsoleContext is passed to console command handlers.
+type ConsoleContext struct {
+	Bot  *Bot
+	Args []string  // The arguments split by spaces.
+	Raw  string    // The arguments exactly as typed.
+	Out  io.Writer // Where output should be written, the operator's terminal or socket.
+}
+
+// Printf writes formatted output followed by a newline to the operator.
+func (c *ConsoleContext) Printf(format string, args ...interface{}) {
+	fmt.Fprintf(c.Out, format+"\n", args...)
+}
+
+// ConsoleHandler handles a console command.
+type ConsoleHandler func(c *ConsoleContext)
+
+// ConsoleCommand is a command available in the operator console.
+type ConsoleCommand struct {
+	Name        string
+	Usage       string
+	Description string
+	Run         ConsoleHandler
+}
+
+// Console reads commands from stdin or a Unix socket and maps them to owner level bot actions.
+// It's meant for headless servers where the operator has a shell but may not want to use Discord.
+// Anyone who can write to the input has full control so keep the socket's permissions tight.
+type Console struct {
+	Bot      *Bot
+	Prompt   string // Printed before reading each line. (default: "> ")
+	commands map[string]*ConsoleCommand
+	lock     sync.RWMutex
+}
+
+// NewConsole creates a console with the builtin commands:
+// help, stats, enable, disable, reload, say, broadcast
+func NewConsole(bot *Bot) *Console {
+	c := &Console{Bot: bot, Prompt: "> ", commands: make(map[string]*ConsoleCommand)}
+	c.Add("help", "", "Lists the console commands.", consoleHelp(c))
+	c.Add("stats", "", "Shows bot and runtime stats.", consoleStats)
+	c.Add("enable", "<command>", "Enables a disabled command.", consoleToggle(true))
+	c.Add("disable", "<command>", "Disables an enabled command.", consoleToggle(false))
+	c.Add("reload", "", "Runs the reload hooks e.g to reload locales from disk.", consoleReload)
+	c.Add("say", "<channel id> <message...>", "Sends a message to a channel.", consoleSay)
+	c.Add("broadcast", "<message...>", "Sends an announcement to every guild, see Bot.Broadcast", consoleBroadcast)
+	return c
+}
+
+// Add adds a console command, if a command with the same name exists it's replaced.
+func (c *Console) Add(name, usage, description string, handler ConsoleHandler) *Console {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.commands[strings.ToLower(name)] = &ConsoleCommand{Name: name, Usage: usage, Description: description, Run: handler}
+	return c
+}
+
+// Exec runs a single line of input writing the output to out.
+func (c *Console) Exec(line string, out io.Writer) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	name, raw := line, ""
+	if i := strings.IndexByte(line, ' '); i != -1 {
+		name, raw = line[:i], strings.TrimSpace(line[i+1:])
+	}
+
+	c.lock.RLock()
+	cmd, ok := c.commands[strings.ToLower(name)]
+	c.lock.RUnlock()
+	if !ok {
+		fmt.Fprintf(out, "Unknown command '%s', type help to see the available commands.\n", name)
+		return
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Fprintf(out, "Command panicked: %v\n", err)
+		}
+	}()
+	cmd.Run(&ConsoleContext{Bot: c.Bot, Args: strings.Fields(raw), Raw: raw, Out: out})
+}
+
+// Serve reads commands line by line from r until it's closed, writing output to w.
+func (c *Console) Serve(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	fmt.Fprint(w, c.Prompt)
+	for scanner.Scan() {
+		c.Exec(scanner.Text(), w)
+		fmt.Fprint(w, c.Prompt)
+	}
+	return scanner.Err()
+}
+
+// ListenUnix serves the console on a Unix socket at path, a stale socket file is removed first.
+// The socket is only accessible by the user running the bot, connect with e.g socat - UNIX-CONNECT:path
+// This blocks until the listener fails, run it in a goroutine.
+func (c *Console) ListenUnix(path string) error {
+	os.Remove(path)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	if err := os.Chmod(path, 0600); err != nil {
+		return err
+	}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			c.Serve(conn, conn)
+		}()
+	}
+}
+
+// EnableConsole creates the bot's console and starts serving it on stdin.
+// Use NewConsole and ListenUnix instead if stdin isn't available e.g when running as a service.
+func (bot *Bot) EnableConsole() *Console {
+	if bot.Console == nil {
+		bot.Console = NewConsole(bot)
+	}
+	go bot.Console.Serve(os.Stdin, os.Stdout)
+	return bot.Console
+}
+
+// OnReload adds a hook ran by bot.Reload, use it to reload things like locales or config files from disk.
+func (bot *Bot) OnReload(hook func(bot *Bot) error) *Bot {
+	bot.reloadHooks = append(bot.reloadHooks, hook)
+	return bot
+}
+
+// Reload runs all reload hooks in the order they were added, it stops at the first error.
+func (bot *Bot) Reload() error {
+	for _, hook := range bot.reloadHooks {
+		if err := hook(bot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func consoleHelp(c *Console) ConsoleHandler {
+	return func(ctx *ConsoleContext) {
+		c.lock.RLock()
+		defer c.lock.RUnlock()
+		names := make([]string, 0, len(c.commands))
+		for name := range c.commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			cmd := c.commands[name]
+			ctx.Printf("%-10s %-28s %s", cmd.Name, cmd.Usage, cmd.Description)
+		}
+	}
+}
+
+func consoleStats(ctx *ConsoleContext) {
+	bot := ctx.Bot
+	stats := &runtime.MemStats{}
+	runtime.ReadMemStats(stats)
+	var guilds, users int
+	if bot.Session != nil && bot.Session.State != nil {
+		guilds = len(bot.Session.State.Guilds)
+		for _, guild := range bot.Session.State.Guilds {
+			users += guild.MemberCount
+		}
+	}
+	ctx.Printf("Guilds: %d, Users: %d, Uptime: %s", guilds, users, humanize.RelTime(bot.Uptime, time.Now(), "", ""))
+	ctx.Printf("Commands: %d, Commands Ran: %d", len(bot.Commands), bot.TotalCommandsRan())
+	ctx.Printf("Memory: %s / %s, Goroutines: %d", humanize.Bytes(stats.Alloc), humanize.Bytes(stats.Sys), runtime.NumGoroutine())
+}
+
+func consoleToggle(enable bool) ConsoleHandler {
+	return func(ctx *ConsoleContext) {
+		if len(ctx.Args) < 1 {
+			ctx.Printf("Missing the command name.")
+			return
+		}
+		cmd := ctx.Bot.GetCommand(ctx.Args[0])
+		if cmd == nil {
+			ctx.Printf("Command '%s' not found.", ctx.Args[0])
+			return
+		}
+		if enable {
+			cmd.Enable()
+			ctx.Printf("Enabled %s", cmd.Name)
+		} else {
+			cmd.Disable()
+			ctx.Printf("Disabled %s", cmd.Name)
+		}
+	}
+}
+
+func consoleReload(ctx *ConsoleContext) {
+	if err := ctx.Bot.Reload(); err != nil {
+		ctx.Printf("Reload failed: %v", err)
+		return
+	}
+	ctx.Printf("Reloaded.")
+}
+
+func consoleSay(ctx *ConsoleContext) {
+	if len(ctx.Args) < 2 {
+		ctx.Printf("Usage: say <channel id> <message...>")
+		return
+	}
+	if _, err := ctx.Bot.Session.ChannelMessageSend(ctx.Args[0], strings.TrimSpace(ctx.Raw[len(ctx.Args[0]):])); err != nil {
+		ctx.Printf("Couldn't send the message: %v", err)
+		return
+	}
+	ctx.Printf("Sent.")
+}
+
+// EnableDMControl lets owners run console commands by DMing the bot messages starting with prefix e.g "$ stats"
+// This works even if the console isn't served on stdin or a socket, the output is sent back in a code block.
+// Only the bot owner and the extra owner IDs passed can use it, anyone else is silently ignored and edits never run commands.
+func (bot *Bot) EnableDMControl(prefix string, owners ...string) *Bot {
+	if bot.Console == nil {
+		bot.Console = NewConsole(bot)
+	}
+	allowed := make(map[string]bool, len(owners))
+	for _, id := range owners {
+		allowed[id] = true
+	}
+	return bot.AddMonitor(NewMonitor("dmControl", func(bot *Bot, ctx *MonitorContext) {
+		if ctx.Message.GuildID != "" || !strings.HasPrefix(ctx.Message.Content, prefix) {
+			return
+		}
+		if ctx.Author.ID != bot.OwnerID && !allowed[ctx.Author.ID] {
+			return
+		}
+		out := &strings.Builder{}
+		bot.Console.Exec(ctx.Message.Content[len(prefix):], out)
+		res := strings.TrimSpace(out.String())
+		if res == "" {
+			res = "Done."
+		}
+		// Keep some room for the code block within the 2000 characters limit.
+		if len(res) > 1900 {
+			res = res[:1900] + "\n..."
+		}
+		ctx.Session.ChannelMessageSend(ctx.Channel.ID, "```\n"+res+"```")
+	}))
+}
@@ -0,0 +1,39 @@
+package sapphire
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestConsoleExec(t *testing.T) {
+	bot := &Bot{Commands: make(map[string]*Command), aliases: make(map[string]string)}
+	bot.Commands["ping"] = NewCommand("ping", "General", func(ctx *CommandContext) {})
+	c := NewConsole(bot)
+	out := &bytes.Buffer{}
+
+	c.Exec("disable ping", out)
+	if bot.Commands["ping"].Enabled {
+		t.Error("Expected the disable console command to disable ping")
+	}
+
+	var raw string
+	c.Add("echo", "", "", func(ctx *ConsoleContext) { raw = ctx.Raw })
+	c.Exec("ECHO  hello   world ", out)
+	if raw != "hello   world" {
+		t.Errorf("Expected raw arguments 'hello   world' but got '%s'", raw)
+	}
+
+	out.Reset()
+	c.Exec("nope", out)
+	if !strings.Contains(out.String(), "Unknown command") {
+		t.Errorf("Expected an unknown command message but got '%s'", out.String())
+	}
+
+	out.Reset()
+	c.Add("boom", "", "", func(ctx *ConsoleContext) { panic("boom") })
+	c.Exec("boom", out)
+	if !strings.Contains(out.String(), "panicked") {
+		t.Errorf("Expected the panic to be reported but got '%s'", out.String())
+	}
+}
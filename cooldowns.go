@@ -0,0 +1,58 @@
+package sapphire
+
+// CooldownExemption decides wether the invoker in ctx skips the command's cooldown.
+// Add them globally with bot.AddCooldownExemption or per command with cmd.AddCooldownExemption
+type CooldownExemption func(ctx *CommandContext) bool
+
+// ExemptOwner exempts the bot owner from cooldowns.
+func ExemptOwner(ctx *CommandContext) bool {
+	return ctx.Author.ID == ctx.Bot.OwnerID
+}
+
+// ExemptRoles exempts members that have any of the given role IDs.
+func ExemptRoles(roleIDs ...string) CooldownExemption {
+	return func(ctx *CommandContext) bool {
+		member := ctx.Member(ctx.Author.ID)
+		if member == nil {
+			member = ctx.Message.Member
+		}
+		if member == nil {
+			return false
+		}
+		for _, role := range member.Roles {
+			for _, id := range roleIDs {
+				if role == id {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// ExemptGuilds exempts everyone in the given guild IDs, e.g a list of premium guilds.
+func ExemptGuilds(guildIDs ...string) CooldownExemption {
+	return func(ctx *CommandContext) bool {
+		for _, id := range guildIDs {
+			if ctx.Message.GuildID == id {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// IsCooldownExempt checks the global and the command's exemptions, returns true if any of them exempts the invoker.
+func (bot *Bot) IsCooldownExempt(ctx *CommandContext) bool {
+	for _, exempt := range bot.CooldownExemptions {
+		if exempt(ctx) {
+			return true
+		}
+	}
+	for _, exempt := range ctx.Command.CooldownExemptions {
+		if exempt(ctx) {
+			return true
+		}
+	}
+	return false
+}
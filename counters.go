@@ -0,0 +1,109 @@
+package sapphire
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// The counters the framework keeps in bot.Counters
+const (
+	CounterCommands             = "commands"              // Commands ran.
+	CounterCommandErrors        = "command_errors"        // Commands that panicked or timed out.
+	CounterInteractionsDeferred = "interactions_deferred" // Interaction commands deferred because they were slow to respond.
+)
+
+// CounterSource returns the counters of another shard or process, e.g fetched over your own IPC or a shared cache.
+// It's called every time totals are read so it should be quick, return nil if they're unavailable.
+type CounterSource func() map[string]int64
+
+// Counters are named counters that are safe to increment from concurrent handlers.
+type Counters struct {
+	values  map[string]*int64
+	sources []CounterSource
+	lock    sync.RWMutex
+}
+
+// NewCounters creates an empty set of counters.
+func NewCounters() *Counters {
+	return &Counters{values: make(map[string]*int64)}
+}
+
+// counter returns the counter, creating it if needed.
+func (c *Counters) counter(name string) *int64 {
+	c.lock.RLock()
+	value, ok := c.values[name]
+	c.lock.RUnlock()
+	if ok {
+		return value
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if value, ok = c.values[name]; !ok {
+		value = new(int64)
+		c.values[name] = value
+	}
+	return value
+}
+
+// Add adds delta to a counter.
+func (c *Counters) Add(name string, delta int64) {
+	atomic.AddInt64(c.counter(name), delta)
+}
+
+// Inc adds one to a counter.
+func (c *Counters) Inc(name string) {
+	c.Add(name, 1)
+}
+
+// Get returns the value of a counter in this process.
+func (c *Counters) Get(name string) int64 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if value, ok := c.values[name]; ok {
+		return atomic.LoadInt64(value)
+	}
+	return 0
+}
+
+// Snapshot returns the values of all counters in this process, e.g to send them to other shards.
+func (c *Counters) Snapshot() map[string]int64 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	snapshot := make(map[string]int64, len(c.values))
+	for name, value := range c.values {
+		snapshot[name] = atomic.LoadInt64(value)
+	}
+	return snapshot
+}
+
+// AddSource adds the counters of another shard or process to the totals.
+func (c *Counters) AddSource(source CounterSource) *Counters {
+	c.lock.Lock()
+	c.sources = append(c.sources, source)
+	c.lock.Unlock()
+	return c
+}
+
+// Totals returns all counters summed over this process and the sources.
+func (c *Counters) Totals() map[string]int64 {
+	totals := c.Snapshot()
+	c.lock.RLock()
+	sources := append([]CounterSource(nil), c.sources...)
+	c.lock.RUnlock()
+	for _, source := range sources {
+		for name, value := range source() {
+			totals[name] += value
+		}
+	}
+	return totals
+}
+
+// Total returns a counter summed over this process and the sources.
+func (c *Counters) Total(name string) int64 {
+	return c.Totals()[name]
+}
+
+// TotalCommandsRan returns how many commands ran, over all shards if sources were added to bot.Counters
+func (bot *Bot) TotalCommandsRan() int64 {
+	return bot.Counters.Total(CounterCommands)
+}
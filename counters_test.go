@@ -0,0 +1,48 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"sync"
+	"testing"
+)
+
+func TestCounters(t *testing.T) {
+	counters := NewCounters()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			counters.Inc(CounterCommands)
+		}()
+	}
+	wg.Wait()
+	if n := counters.Get(CounterCommands); n != 50 {
+		t.Errorf("Expected 50 commands but got %d", n)
+	}
+
+	counters.AddSource(func() map[string]int64 {
+		return map[string]int64{CounterCommands: 10, CounterCommandErrors: 2}
+	})
+	if n := counters.Total(CounterCommands); n != 60 {
+		t.Errorf("Expected 60 commands over all shards but got %d", n)
+	}
+	if n := counters.Total(CounterCommandErrors); n != 2 {
+		t.Errorf("Expected 2 errors over all shards but got %d", n)
+	}
+}
+
+func TestCommandsRan(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	bot.CommandTyping = false
+	bot.AddCommand(NewCommand("ping", "General", func(ctx *CommandContext) {}))
+	ctx := &CommandContext{Bot: bot, Command: bot.Commands["ping"], Message: &discordgo.Message{}, Channel: &discordgo.Channel{ID: "c"},
+		Author: &discordgo.User{ID: "u"}, Session: bot.Session, Locale: bot.DefaultLocale}
+	if err := bot.ExecuteCommand(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	bot.Counters.AddSource(func() map[string]int64 { return map[string]int64{CounterCommands: 2} })
+	if bot.Counters.Get(CounterCommands) != 1 || bot.TotalCommandsRan() != 3 {
+		t.Errorf("Expected 1 command in this process and 3 in total but got %d and %d", bot.Counters.Get(CounterCommands), bot.TotalCommandsRan())
+	}
+}
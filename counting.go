@@ -0,0 +1,265 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CountingConfig is the per-guild config of the counting game, see bot.EnableCounting
+var CountingConfig = NewConfigSchema("counting", "A counting game, members take turns counting up in a channel.").
+	Add("channel", ConfigChannel, "", "The counting channel, leave empty to disable the game.").
+	Add("reset", ConfigBool, "true", "Whether a mistake resets the count back to 0.").
+	Add("twice", ConfigBool, "false", "Whether members can count twice in a row.")
+
+// countingKey is the guild settings key the game is stored under.
+const countingKey = "counting.game"
+
+// CountingGame is the state of a guild's counting game.
+type CountingGame struct {
+	Count    int            `json:"count"`     // The last correct number.
+	LastUser string         `json:"last_user"` // Who counted last.
+	Record   int            `json:"record"`    // The highest count reached.
+	Scores   map[string]int `json:"scores"`    // user ID -> correct counts
+}
+
+// CountingScore is a leaderboard entry.
+type CountingScore struct {
+	UserID string
+	Score  int
+}
+
+type countingTracker struct {
+	// Counting is a read, check and write so messages in the same guild are handled one at a time.
+	lock sync.Mutex
+}
+
+// EnableCounting loads the counting game, servers pick their counting channel with CountingConfig.
+// Every message starting with a number is checked against the next number, a correct one gets a reaction
+// and counts towards the leaderboard while a mistake resets the count. Other messages are left alone.
+func (bot *Bot) EnableCounting() *Bot {
+	if bot.counting != nil {
+		return bot
+	}
+	bot.counting = &countingTracker{}
+	bot.AddConfigSchema(CountingConfig)
+	bot.AddDataSubject(countingData(bot))
+	bot.AddMonitor(NewMonitor("counting", countingMonitor).SetGuildOnly(true))
+	bot.AddCommand(NewCommand("counting", "Fun", countingCommand).
+		SetDescription("Shows the counting game's progress, use top for the leaderboard or reset to start over.").
+		SetUsage("[action:string]").
+		SetGuildOnly(true).
+		AddAliases("count"))
+	return bot
+}
+
+// CountingGame returns the counting game of the guild.
+func (bot *Bot) CountingGame(guildID string) (*CountingGame, error) {
+	game := &CountingGame{}
+	if _, err := GetJSON(bot.Settings, guildID, countingKey, game); err != nil {
+		return nil, err
+	}
+	if game.Scores == nil {
+		game.Scores = make(map[string]int)
+	}
+	return game, nil
+}
+
+// ResetCounting resets the count of the guild back to 0, if scores is true the leaderboard and record are cleared too.
+func (bot *Bot) ResetCounting(guildID string, scores bool) error {
+	bot.counting.lock.Lock()
+	defer bot.counting.lock.Unlock()
+	if scores {
+		return bot.Settings.Delete(guildID, countingKey)
+	}
+	game, err := bot.CountingGame(guildID)
+	if err != nil {
+		return err
+	}
+	game.Count = 0
+	game.LastUser = ""
+	return SetJSON(bot.Settings, guildID, countingKey, game)
+}
+
+// countingData is the data subject of the counting scores, exported by guild ID.
+func countingData(bot *Bot) DataSubject {
+	return &userData{
+		name: "counting",
+		export: func(userID string) (interface{}, error) {
+			guilds, err := bot.settingsGuilds()
+			if err != nil {
+				return nil, err
+			}
+			data := make(map[string]interface{})
+			for _, guildID := range guilds {
+				game, err := bot.CountingGame(guildID)
+				if err != nil {
+					return nil, err
+				}
+				score, ok := game.Scores[userID]
+				if ok || game.LastUser == userID {
+					data[guildID] = map[string]interface{}{"score": score, "counted_last": game.LastUser == userID}
+				}
+			}
+			if len(data) == 0 {
+				return nil, nil
+			}
+			return data, nil
+		},
+		delete: func(userID string) error {
+			guilds, err := bot.settingsGuilds()
+			if err != nil {
+				return err
+			}
+			bot.counting.lock.Lock()
+			defer bot.counting.lock.Unlock()
+			for _, guildID := range guilds {
+				game, err := bot.CountingGame(guildID)
+				if err != nil {
+					return err
+				}
+				if _, ok := game.Scores[userID]; !ok && game.LastUser != userID {
+					continue
+				}
+				delete(game.Scores, userID)
+				if game.LastUser == userID {
+					game.LastUser = ""
+				}
+				if err := SetJSON(bot.Settings, guildID, countingKey, game); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// Leaderboard returns the top scores of the game, highest first.
+func (game *CountingGame) Leaderboard(limit int) []CountingScore {
+	scores := make([]CountingScore, 0, len(game.Scores))
+	for id, score := range game.Scores {
+		scores = append(scores, CountingScore{UserID: id, Score: score})
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score == scores[j].Score {
+			return scores[i].UserID < scores[j].UserID
+		}
+		return scores[i].Score > scores[j].Score
+	})
+	if len(scores) > limit {
+		scores = scores[:limit]
+	}
+	return scores
+}
+
+// countingNumber parses the number a message starts with.
+func countingNumber(content string) (int, bool) {
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(fields[0])
+	return n, err == nil
+}
+
+func countingMonitor(bot *Bot, ctx *MonitorContext) {
+	if CountingConfig.Get(bot, ctx.Guild.ID, "channel") != ctx.Channel.ID {
+		return
+	}
+	n, ok := countingNumber(ctx.Message.Content)
+	if !ok {
+		return
+	}
+
+	bot.counting.lock.Lock()
+	defer bot.counting.lock.Unlock()
+	game, err := bot.CountingGame(ctx.Guild.ID)
+	if err != nil {
+		bot.ErrorHandler(bot, err)
+		return
+	}
+	locale := bot.LocaleFor(ctx.Guild.ID, ctx.Channel.ID)
+
+	twice := game.LastUser == ctx.Author.ID && !CountingConfig.GetBool(bot, ctx.Guild.ID, "twice")
+	if n != game.Count+1 || twice {
+		ctx.Session.MessageReactionAdd(ctx.Channel.ID, ctx.Message.ID, "❌")
+		if !CountingConfig.GetBool(bot, ctx.Guild.ID, "reset") {
+			// Without resets mistakes are just ignored.
+			return
+		}
+		if twice {
+			bot.SendLocale(ctx.Channel.ID, locale, "COUNTING_TWICE", ctx.Author.Mention(), game.Count)
+		} else {
+			bot.SendLocale(ctx.Channel.ID, locale, "COUNTING_WRONG", ctx.Author.Mention(), game.Count+1, game.Count)
+		}
+		game.Count = 0
+		game.LastUser = ""
+	} else {
+		ctx.Session.MessageReactionAdd(ctx.Channel.ID, ctx.Message.ID, "✅")
+		game.Count = n
+		game.LastUser = ctx.Author.ID
+		game.Scores[ctx.Author.ID]++
+		if n > game.Record {
+			game.Record = n
+		}
+	}
+	if err := SetJSON(bot.Settings, ctx.Guild.ID, countingKey, game); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+}
+
+func countingCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+	action := ""
+	if ctx.Arg(0).IsProvided() {
+		action = strings.ToLower(ctx.Arg(0).AsString())
+	}
+	switch action {
+	case "":
+		game, err := bot.CountingGame(ctx.Guild.ID)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		channel := CountingConfig.Get(bot, ctx.Guild.ID, "channel")
+		if channel == "" {
+			ctx.ReplyLocale("COUNTING_DISABLED", ctx.Prefix)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_COUNTING_STATUS", channel, game.Count, game.Count+1, game.Record)
+	case "top", "leaderboard", "lb":
+		game, err := bot.CountingGame(ctx.Guild.ID)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		scores := game.Leaderboard(10)
+		if len(scores) == 0 {
+			ctx.ReplyLocale("COMMAND_COUNTING_NO_SCORES")
+			return
+		}
+		lines := make([]string, len(scores))
+		for i, score := range scores {
+			lines[i] = fmt.Sprintf("**%d.** <@%s> - %d", i+1, score.UserID, score.Score)
+		}
+		ctx.BuildEmbed(NewEmbed().
+			SetTitle(ctx.Locale.Get("COMMAND_COUNTING_LEADERBOARD")).
+			SetDescription(strings.Join(lines, "\n")).
+			SetColor(bot.Color))
+	case "reset":
+		if !ctx.HasPermissions(discordgo.PermissionManageServer) {
+			ctx.ReplyLocale("COMMAND_COUNTING_NO_PERMISSION")
+			return
+		}
+		if err := bot.ResetCounting(ctx.Guild.ID, ctx.HasFlag("all")); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_COUNTING_RESET")
+	default:
+		ctx.ReplyLocale("COMMAND_COUNTING_USAGE", ctx.Prefix)
+	}
+}
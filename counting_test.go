@@ -0,0 +1,19 @@
+package sapphire
+
+import (
+	"testing"
+)
+
+func TestCountingLeaderboard(t *testing.T) {
+	game := &CountingGame{Scores: map[string]int{"a": 3, "b": 7, "c": 3, "d": 1}}
+	top := game.Leaderboard(3)
+	if len(top) != 3 || top[0].UserID != "b" || top[1].UserID != "a" || top[2].UserID != "c" {
+		t.Errorf("Unexpected leaderboard %v", top)
+	}
+	if n, ok := countingNumber("42 and counting"); !ok || n != 42 {
+		t.Errorf("Expected 42, got %d", n)
+	}
+	if _, ok := countingNumber("hello"); ok {
+		t.Error("Expected text to not count")
+	}
+}
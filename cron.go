@@ -0,0 +1,441 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CronSchedule is a parsed cron expression, create one with ParseCron
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+// cronShortcuts are the supported @ shortcuts and their expressions.
+var cronShortcuts = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a standard 5 field cron expression "minute hour day-of-month month day-of-week"
+// Fields support *, lists (1,2), ranges (1-5) and steps (*/15, 0-30/10), sunday is 0 or 7.
+// The shortcuts @yearly, @monthly, @weekly, @daily and @hourly are supported too.
+func ParseCron(expr string) (*CronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if shortcut, ok := cronShortcuts[strings.ToLower(expr)]; ok {
+		expr = shortcut
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron: expected 5 fields but got %d in '%s'", len(fields), expr)
+	}
+
+	var err error
+	c := &CronSchedule{domStar: fields[2] == "*", dowStar: fields[4] == "*"}
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	// 7 is also sunday.
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	return c, nil
+}
+
+// parseCronField parses a single field into a bitset of the allowed values.
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i != -1 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s < 1 {
+				return 0, fmt.Errorf("cron: invalid step in '%s'", field)
+			}
+			step = s
+			part = part[:i]
+		}
+
+		start, end := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if start, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("cron: invalid value in '%s'", field)
+			}
+			end = start
+			if len(bounds) == 2 {
+				if end, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("cron: invalid value in '%s'", field)
+				}
+			} else if step > 1 {
+				// 5/15 means starting at 5 every 15.
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("cron: '%s' is out of range %d-%d", field, min, max)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	// Like standard cron if both day fields are restricted either of them matching is enough.
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first time after t that matches the schedule, in t's location.
+// Returns the zero time if nothing matches in the next 5 years. (e.g 30th of February)
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// ScheduledCommand is a command invocation that runs on a cron schedule.
+type ScheduledCommand struct {
+	ID        string `json:"id"`
+	Cron      string `json:"cron"`     // The cron expression, see ParseCron
+	Timezone  string `json:"timezone"` // Timezone the expression is evaluated in e.g Europe/Berlin, see LoadTimezone (default: the guild's timezone)
+	ChannelID string `json:"channel_id"`
+	GuildID   string `json:"guild_id"`
+	UserID    string `json:"user_id"` // The user the command runs as, checks like owner-only apply to them.
+	Command   string `json:"command"`
+	Args      string `json:"args"`
+	schedule  *CronSchedule
+	location  *time.Location
+	task      *ScheduledTask
+}
+
+// Next returns the next time the command runs after t.
+func (sc *ScheduledCommand) Next(t time.Time) time.Time {
+	return sc.schedule.Next(t.In(sc.location))
+}
+
+// cronKeyPrefix is the bot wide settings key prefix scheduled commands are persisted under.
+const cronKeyPrefix = "cron."
+
+type cronTracker struct {
+	jobs map[string]*ScheduledCommand
+	lock sync.Mutex
+}
+
+// ScheduleCommand schedules sc to run on it's cron expression and persists it in the settings.
+// An empty ID is generated, scheduling an existing ID replaces it.
+// An empty timezone uses the guild's timezone.
+func (bot *Bot) ScheduleCommand(sc *ScheduledCommand) error {
+	if sc.Timezone == "" {
+		sc.Timezone = bot.GuildTimezone(sc.GuildID).String()
+	}
+	if err := sc.compile(); err != nil {
+		return err
+	}
+	if bot.GetCommand(sc.Command) == nil {
+		return fmt.Errorf("cron: unknown command '%s'", sc.Command)
+	}
+	if sc.ID == "" {
+		sc.ID = strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	if err := SetJSON(bot.Settings, "", cronKeyPrefix+sc.ID, sc); err != nil {
+		return err
+	}
+	bot.startCron(sc)
+	return nil
+}
+
+// UnscheduleCommand cancels and forgets the scheduled command, returns false if it doesn't exist.
+func (bot *Bot) UnscheduleCommand(id string) bool {
+	bot.cron.lock.Lock()
+	sc, ok := bot.cron.jobs[id]
+	delete(bot.cron.jobs, id)
+	bot.cron.lock.Unlock()
+	if !ok {
+		return false
+	}
+	sc.task.Cancel()
+	if err := bot.Settings.Delete("", cronKeyPrefix+id); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+	return true
+}
+
+// cronData is the data subject of the commands scheduled to run as a user, deleting unschedules them.
+func cronData(bot *Bot) DataSubject {
+	owned := func(userID string) []*ScheduledCommand {
+		var res []*ScheduledCommand
+		for _, sc := range bot.ScheduledCommands() {
+			if sc.UserID == userID {
+				res = append(res, sc)
+			}
+		}
+		return res
+	}
+	return &userData{
+		name: "cron",
+		export: func(userID string) (interface{}, error) {
+			if scheduled := owned(userID); len(scheduled) > 0 {
+				return scheduled, nil
+			}
+			return nil, nil
+		},
+		delete: func(userID string) error {
+			for _, sc := range owned(userID) {
+				bot.UnscheduleCommand(sc.ID)
+			}
+			return nil
+		},
+	}
+}
+
+// ScheduledCommands returns the scheduled commands sorted by ID.
+func (bot *Bot) ScheduledCommands() []*ScheduledCommand {
+	bot.cron.lock.Lock()
+	defer bot.cron.lock.Unlock()
+	res := make([]*ScheduledCommand, 0, len(bot.cron.jobs))
+	for _, sc := range bot.cron.jobs {
+		res = append(res, sc)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
+
+func (sc *ScheduledCommand) compile() error {
+	schedule, err := ParseCron(sc.Cron)
+	if err != nil {
+		return err
+	}
+	if sc.Timezone == "" {
+		sc.Timezone = "UTC"
+	}
+	loc, err := LoadTimezone(sc.Timezone)
+	if err != nil {
+		return fmt.Errorf("cron: %v", err)
+	}
+	sc.Command = strings.ToLower(sc.Command)
+	sc.schedule = schedule
+	sc.location = loc
+	return nil
+}
+
+// startCron schedules the next run of sc, replacing a previous job with the same ID.
+func (bot *Bot) startCron(sc *ScheduledCommand) {
+	bot.cron.lock.Lock()
+	defer bot.cron.lock.Unlock()
+	if old, ok := bot.cron.jobs[sc.ID]; ok && old != sc {
+		old.task.Cancel()
+	}
+	next := sc.Next(time.Now())
+	if next.IsZero() {
+		delete(bot.cron.jobs, sc.ID)
+		return
+	}
+	bot.cron.jobs[sc.ID] = sc
+	sc.task = bot.Scheduler.At(next, func() {
+		bot.cron.lock.Lock()
+		current := bot.cron.jobs[sc.ID] == sc
+		bot.cron.lock.Unlock()
+		// Unscheduled or replaced while we were waiting.
+		if !current {
+			return
+		}
+		bot.startCron(sc)
+		bot.runScheduledCommand(sc)
+	})
+}
+
+// runScheduledCommand executes the command as if sc.UserID typed it in the channel.
+func (bot *Bot) runScheduledCommand(sc *ScheduledCommand) {
+	cmd := bot.GetCommand(sc.Command)
+	if cmd == nil {
+		return
+	}
+	channel, err := bot.Session.State.Channel(sc.ChannelID)
+	if err != nil {
+		if channel, err = bot.Session.Channel(sc.ChannelID); err != nil {
+			bot.ErrorHandler(bot, err)
+			return
+		}
+	}
+	var guild *discordgo.Guild
+	if sc.GuildID != "" {
+		if guild, err = bot.Session.State.Guild(sc.GuildID); err != nil {
+			bot.ErrorHandler(bot, err)
+			return
+		}
+	}
+	var author *discordgo.User
+	if member, err := bot.Session.State.Member(sc.GuildID, sc.UserID); err == nil {
+		author = member.User
+	} else if author, err = bot.Session.User(sc.UserID); err != nil {
+		bot.ErrorHandler(bot, err)
+		return
+	}
+
+	msg := &discordgo.Message{
+		ChannelID: sc.ChannelID,
+		GuildID:   sc.GuildID,
+		Author:    author,
+		Content:   sc.Command + " " + sc.Args,
+	}
+	rawArgs, offsets := splitArgs(sc.Args)
+	bot.ExecuteCommand(&CommandContext{
+		Bot:         bot,
+		Command:     cmd,
+		Message:     msg,
+		Channel:     channel,
+		Session:     bot.Session,
+		Author:      author,
+		RawArgs:     rawArgs,
+		RawContent:  sc.Args,
+		ArgOffsets:  offsets,
+		Prefix:      bot.Prefix(bot, msg, sc.GuildID == ""),
+		Guild:       guild,
+		Flags:       make(map[string]string),
+		Locale:      bot.LocaleFor(sc.GuildID, sc.ChannelID),
+		InvokedName: sc.Command,
+		Shared:      make(map[string]interface{}),
+		invoked:     true,
+	}, false)
+}
+
+// restoreCron loads the scheduled commands persisted in the settings.
+// Only works if the settings provider implements SettingsIterator
+func (bot *Bot) restoreCron() {
+	it, ok := bot.Settings.(SettingsIterator)
+	if !ok {
+		return
+	}
+	keys, err := it.Keys("")
+	if err != nil {
+		bot.ErrorHandler(bot, err)
+		return
+	}
+	for _, key := range keys {
+		if !strings.HasPrefix(key, cronKeyPrefix) {
+			continue
+		}
+		sc := &ScheduledCommand{}
+		if ok, err := GetJSON(bot.Settings, "", key, sc); err != nil || !ok {
+			continue
+		}
+		if err := sc.compile(); err != nil {
+			bot.ErrorHandler(bot, err)
+			continue
+		}
+		bot.startCron(sc)
+	}
+}
+
+func cronCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+
+	if !ctx.Arg(0).IsProvided() {
+		jobs := bot.ScheduledCommands()
+		if len(jobs) == 0 {
+			ctx.ReplyLocale("COMMAND_CRON_EMPTY", ctx.Prefix)
+			return
+		}
+		embed := NewEmbed().SetTitle("Scheduled Commands").SetColor(bot.Color)
+		for _, sc := range jobs {
+			embed.AddField(sc.ID, fmt.Sprintf("`%s` (%s) in <#%s>\n**Command:** %s %s\n**Next:** %s",
+				sc.Cron, sc.Timezone, sc.ChannelID, sc.Command, sc.Args, sc.Next(time.Now()).Format(time.RFC1123)))
+		}
+		ctx.BuildEmbed(embed)
+		return
+	}
+
+	switch strings.ToLower(ctx.Arg(0).AsString()) {
+	case "add":
+		// The expression is either a single @shortcut or 5 fields, followed by the command.
+		fields := 5
+		if len(ctx.RawArgs) > 1 && strings.HasPrefix(ctx.RawArgs[1], "@") {
+			fields = 1
+		}
+		if len(ctx.RawArgs) < fields+2 {
+			ctx.ReplyLocale("COMMAND_CRON_USAGE", ctx.Prefix)
+			return
+		}
+		args := ""
+		if len(ctx.RawArgs) > fields+2 {
+			args = strings.TrimSpace(ctx.RawContent[ctx.ArgOffsets[fields+2]:])
+		}
+		sc := &ScheduledCommand{
+			Cron:      strings.Join(ctx.RawArgs[1:fields+1], " "),
+			Timezone:  ctx.Flag("tz"),
+			ChannelID: ctx.Channel.ID,
+			GuildID:   ctx.Message.GuildID,
+			UserID:    ctx.Author.ID,
+			Command:   ctx.RawArgs[fields+1],
+			Args:      args,
+		}
+		if err := bot.ScheduleCommand(sc); err != nil {
+			ctx.ReplyLocale("COMMAND_CRON_INVALID", err.Error())
+			return
+		}
+		ctx.ReplyLocale("COMMAND_CRON_ADDED", sc.ID, sc.Next(time.Now()).Format(time.RFC1123))
+	case "remove", "delete":
+		if !ctx.Arg(1).IsProvided() {
+			ctx.ReplyLocale("COMMAND_CRON_USAGE", ctx.Prefix)
+			return
+		}
+		if !bot.UnscheduleCommand(ctx.Arg(1).AsString()) {
+			ctx.ReplyLocale("COMMAND_CRON_NOT_FOUND", ctx.Arg(1).AsString())
+			return
+		}
+		ctx.ReplyLocale("COMMAND_CRON_REMOVED", ctx.Arg(1).AsString())
+	default:
+		ctx.ReplyLocale("COMMAND_CRON_USAGE", ctx.Prefix)
+	}
+}
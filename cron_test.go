@@ -0,0 +1,44 @@
+package sapphire
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2021, time.March, 3, 10, 30, 0, 0, time.UTC) // A wednesday.
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"*/15 * * * *", time.Date(2021, time.March, 3, 10, 45, 0, 0, time.UTC)},
+		{"0 9 * * 1", time.Date(2021, time.March, 8, 9, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2021, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 10 * * 7", time.Date(2021, time.March, 7, 10, 30, 0, 0, time.UTC)},
+		{"0 12 15 * 5", time.Date(2021, time.March, 5, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		c, err := ParseCron(test.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q) returned error: %v", test.expr, err)
+			continue
+		}
+		if next := c.Next(from); !next.Equal(test.next) {
+			t.Errorf("Next of %q: expected %v but got %v", test.expr, test.next, next)
+		}
+	}
+
+	c, _ := ParseCron("0 0 30 2 *")
+	if next := c.Next(from); !next.IsZero() {
+		t.Errorf("Expected an impossible schedule to return the zero time but got %v", next)
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{"* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("Expected ParseCron(%q) to fail", expr)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package sapphire
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DataSubject is implemented by stores that keep data about users, e.g settings, XP or warnings.
+// Register them with bot.AddDataSubject so privacy requests can be handled in one call with
+// bot.ExportUserData and bot.DeleteUserData
+// The builtin stores register their own: timezone, cron and warnings always, afk, voicexp, counting, modmail, tickets,
+// suggestions and permissions when their module is enabled, entitlements with an entitlement store and premium
+// with a ManualPremium provider. Most of them keep data per guild and need a SettingsIterator to find it.
+type DataSubject interface {
+	// Name is used as the key for this store's data in exports.
+	Name() string
+	// ExportUserData returns all data stored about the user, the value should be JSON encodable.
+	// Return nil if there is nothing stored about the user.
+	ExportUserData(userID string) (interface{}, error)
+	// DeleteUserData deletes all data stored about the user.
+	DeleteUserData(userID string) error
+}
+
+// DataSubjectError is returned when some data subjects failed, the rest of them still ran.
+type DataSubjectError struct {
+	Errors map[string]error // Errors by the data subject's name.
+}
+
+// Error implements the error interface.
+func (err *DataSubjectError) Error() string {
+	names := make([]string, 0, len(err.Errors))
+	for name := range err.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = fmt.Sprintf("%s: %v", name, err.Errors[name])
+	}
+	return "data subjects failed: " + strings.Join(msgs, "; ")
+}
+
+// userData implements DataSubject with functions, the builtin stores register one for the user data they keep.
+type userData struct {
+	name   string
+	export func(userID string) (interface{}, error)
+	delete func(userID string) error
+}
+
+func (d *userData) Name() string                                      { return d.name }
+func (d *userData) ExportUserData(userID string) (interface{}, error) { return d.export(userID) }
+func (d *userData) DeleteUserData(userID string) error                { return d.delete(userID) }
+
+// settingsGuilds returns the guilds with settings, user data kept per guild can only be found with a SettingsIterator.
+func (bot *Bot) settingsGuilds() ([]string, error) {
+	it, ok := bot.Settings.(SettingsIterator)
+	if !ok {
+		return nil, errors.New("the settings provider can't list keys")
+	}
+	return it.Guilds()
+}
+
+// AddDataSubject registers a data subject, registering another one with the same name replaces it.
+func (bot *Bot) AddDataSubject(subject DataSubject) *Bot {
+	bot.DataSubjects[subject.Name()] = subject
+	return bot
+}
+
+// ExportUserData collects the data every registered data subject has on the user, keyed by the subject's name.
+// If some subjects fail the data from the rest is still returned along with a *DataSubjectError
+func (bot *Bot) ExportUserData(userID string) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+	failed := make(map[string]error)
+	for name, subject := range bot.DataSubjects {
+		res, err := subject.ExportUserData(userID)
+		if err != nil {
+			failed[name] = err
+			continue
+		}
+		if res != nil {
+			data[name] = res
+		}
+	}
+	if len(failed) > 0 {
+		return data, &DataSubjectError{Errors: failed}
+	}
+	return data, nil
+}
+
+// DeleteUserData deletes the user's data from every registered data subject.
+// Every subject is attempted even if some fail, the failures are returned as a *DataSubjectError
+func (bot *Bot) DeleteUserData(userID string) error {
+	failed := make(map[string]error)
+	for name, subject := range bot.DataSubjects {
+		if err := subject.DeleteUserData(userID); err != nil {
+			failed[name] = err
+		}
+	}
+	if len(failed) > 0 {
+		return &DataSubjectError{Errors: failed}
+	}
+	return nil
+}
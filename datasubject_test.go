@@ -0,0 +1,97 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"testing"
+)
+
+func TestUserData(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	bot.EnableAFK().EnableVoiceXP().EnableCounting().EnableModmail("staff")
+	if err := bot.SetUserTimezone("u", "Europe/Berlin"); err != nil {
+		t.Fatal(err)
+	}
+	bot.SetAFK("u", "lunch")
+	SetJSON(bot.Settings, "g", voiceXPKey, map[string]VoiceStats{"u": {Minutes: 5, XP: 50}, "other": {Minutes: 1}})
+	SetJSON(bot.Settings, "g", countingKey, &CountingGame{Count: 3, LastUser: "u", Scores: map[string]int{"u": 2, "other": 1}})
+	bot.Settings.Set("", modmailUserKeyPrefix+"u", "thread")
+	bot.Settings.Set("staff", modmailThreadKeyPrefix+"thread", "u")
+
+	data, err := bot.ExportUserData("u")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"timezone", "afk", "voicexp", "counting", "modmail"} {
+		if data[name] == nil {
+			t.Errorf("Expected the export to include %s", name)
+		}
+	}
+	if tz := data["timezone"]; tz != "Europe/Berlin" {
+		t.Errorf("Unexpected timezone %v", tz)
+	}
+
+	if err := bot.DeleteUserData("u"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := bot.ExportUserData("u"); err != nil || len(data) != 0 {
+		t.Errorf("Expected nothing left after deleting but got %v %v", data, err)
+	}
+	if stats, _ := bot.VoiceStats("g", "other"); stats.Minutes != 1 {
+		t.Error("Expected other members' voice stats to be kept")
+	}
+	if game, _ := bot.CountingGame("g"); game.Scores["other"] != 1 || game.Count != 3 {
+		t.Errorf("Expected the rest of the counting game to be kept but got %+v", game)
+	}
+	if bot.ModmailUser("thread") != "" {
+		t.Error("Expected the modmail thread to be forgotten")
+	}
+}
+
+type memoryEntitlements map[string]*Entitlement
+
+func (m memoryEntitlements) SaveEntitlement(e *Entitlement) error { m[e.ID] = e; return nil }
+func (m memoryEntitlements) DeleteEntitlement(id string) error    { delete(m, id); return nil }
+func (m memoryEntitlements) UserEntitlements(userID string) ([]*Entitlement, error) {
+	var res []*Entitlement
+	for _, e := range m {
+		if e.UserID == userID {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+func TestUserDataPremiumAndOverrides(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	store := memoryEntitlements{"e1": {ID: "e1", UserID: "u"}, "e2": {ID: "e2", UserID: "other"}}
+	premium := NewManualPremium().SetUser("u", true).SetUser("other", true)
+	bot.SetEntitlementStore(store).SetPremiumProvider(premium).LoadPermissionsCommand()
+	bot.SetPermissionOverride("g", PermissionOverride{Command: "ban", Type: "user", ID: "u", Allow: true})
+	bot.SetPermissionOverride("g", PermissionOverride{Command: "ban", Type: "role", ID: "u", Allow: true})
+
+	data, err := bot.ExportUserData("u")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"entitlements", "premium", "permissions"} {
+		if data[name] == nil {
+			t.Errorf("Expected the export to include %s", name)
+		}
+	}
+	if overrides := data["permissions"].(map[string][]PermissionOverride)["g"]; len(overrides) != 1 {
+		t.Errorf("Expected only the user's override but got %+v", overrides)
+	}
+
+	if err := bot.DeleteUserData("u"); err != nil {
+		t.Fatal(err)
+	}
+	if premium.IsPremiumUser("u") || !premium.IsPremiumUser("other") {
+		t.Error("Expected only the user's premium to be revoked")
+	}
+	if _, ok := store["e1"]; ok || len(store) != 1 {
+		t.Errorf("Expected only the user's entitlement to be deleted but got %v", store)
+	}
+	if overrides := bot.PermissionOverrides("g"); len(overrides) != 1 || overrides[0].Type != "role" {
+		t.Errorf("Expected only the user override to be removed but got %+v", overrides)
+	}
+}
@@ -0,0 +1,321 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// deadLettersKey is the bot wide settings key dead letters are persisted under.
+const deadLettersKey = "deadletters"
+
+// MaxDeadLetters is how many dead letters are kept, the oldest are dropped first.
+var MaxDeadLetters = 100
+
+// DeadLetter is an event that panicked or failed processing, kept with it's payload so it can be replayed after a fix.
+type DeadLetter struct {
+	ID      int             `json:"id"`
+	Kind    string          `json:"kind"`    // "monitor" or "event"
+	Handler string          `json:"handler"` // Name of the monitor or event processor.
+	Type    string          `json:"type"`    // Gateway event type of events.
+	Payload json.RawMessage `json:"payload"`
+	Error   string          `json:"error"`
+	At      time.Time       `json:"at"`
+}
+
+var errDeadLettersDisabled = errors.New("dead letters are not enabled, see EnableDeadLetters")
+
+type deadLetterTracker struct {
+	lock sync.Mutex
+}
+
+// eventProcessor processes a raw gateway event, an error or panic makes it a dead letter.
+type eventProcessor func(s *discordgo.Session, e *discordgo.Event) error
+
+// EnableDeadLetters keeps events that panic in monitors or fail processing so they aren't lost silently,
+// they're persisted in the settings provider and the owner only deadletters command inspects and replays them.
+func (bot *Bot) EnableDeadLetters() *Bot {
+	if bot.deadLetters != nil {
+		return bot
+	}
+	bot.deadLetters = &deadLetterTracker{}
+	return bot.AddCommand(NewCommand("deadletters", "Owner", deadLettersCommand).
+		SetDescription("Lists, replays or drops events that failed processing.").
+		SetUsage("[action:string] [id:string]").
+		AddAliases("dlq").
+		SetOwnerOnly(true))
+}
+
+// DeadLetters returns the dead letters, the oldest first.
+func (bot *Bot) DeadLetters() []DeadLetter {
+	var letters []DeadLetter
+	if _, err := GetJSON(bot.Settings, "", deadLettersKey, &letters); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+	return letters
+}
+
+func (bot *Bot) saveDeadLetters(letters []DeadLetter) error {
+	if len(letters) == 0 {
+		return bot.Settings.Delete("", deadLettersKey)
+	}
+	return SetJSON(bot.Settings, "", deadLettersKey, letters)
+}
+
+// CaptureDeadLetter adds a dead letter, does nothing without EnableDeadLetters
+// Modules replaying their own kinds of payloads add a replay handler with bot.AddDeadLetterHandler
+func (bot *Bot) CaptureDeadLetter(letter DeadLetter) {
+	if bot.deadLetters == nil {
+		return
+	}
+	bot.deadLetters.lock.Lock()
+	defer bot.deadLetters.lock.Unlock()
+	letters := bot.DeadLetters()
+	letter.ID = 1
+	if len(letters) > 0 {
+		letter.ID = letters[len(letters)-1].ID + 1
+	}
+	if letter.At.IsZero() {
+		letter.At = time.Now()
+	}
+	letters = append(letters, letter)
+	if len(letters) > MaxDeadLetters {
+		letters = letters[len(letters)-MaxDeadLetters:]
+	}
+	if err := bot.saveDeadLetters(letters); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+}
+
+// AddDeadLetterHandler sets how dead letters of a kind are replayed, "monitor" and "event" are builtin.
+func (bot *Bot) AddDeadLetterHandler(kind string, replay func(letter DeadLetter) error) *Bot {
+	bot.deadLetterHandlers[kind] = replay
+	return bot
+}
+
+// ReplayDeadLetter processes a dead letter again, it's removed if it succeeds and keeps the new error if not.
+func (bot *Bot) ReplayDeadLetter(id int) error {
+	if bot.deadLetters == nil {
+		return errDeadLettersDisabled
+	}
+	var letter *DeadLetter
+	for _, l := range bot.DeadLetters() {
+		if l.ID == id {
+			letter = &l
+			break
+		}
+	}
+	if letter == nil {
+		return fmt.Errorf("there is no dead letter with the ID %d", id)
+	}
+	replay, ok := bot.deadLetterHandlers[letter.Kind]
+	if !ok {
+		return fmt.Errorf("dead letters of kind %s can't be replayed", letter.Kind)
+	}
+	err := catchPanic(func() error { return replay(*letter) })
+
+	bot.deadLetters.lock.Lock()
+	defer bot.deadLetters.lock.Unlock()
+	letters := bot.DeadLetters()
+	for i := range letters {
+		if letters[i].ID != id {
+			continue
+		}
+		if err != nil {
+			letters[i].Error = err.Error()
+			letters[i].At = time.Now()
+		} else {
+			letters = append(letters[:i], letters[i+1:]...)
+		}
+		break
+	}
+	if serr := bot.saveDeadLetters(letters); serr != nil {
+		return serr
+	}
+	return err
+}
+
+// DropDeadLetter removes a dead letter without replaying it, 0 removes all of them.
+func (bot *Bot) DropDeadLetter(id int) error {
+	if bot.deadLetters == nil {
+		return errDeadLettersDisabled
+	}
+	bot.deadLetters.lock.Lock()
+	defer bot.deadLetters.lock.Unlock()
+	if id == 0 {
+		return bot.saveDeadLetters(nil)
+	}
+	letters := bot.DeadLetters()
+	for i := range letters {
+		if letters[i].ID == id {
+			return bot.saveDeadLetters(append(letters[:i], letters[i+1:]...))
+		}
+	}
+	return fmt.Errorf("there is no dead letter with the ID %d", id)
+}
+
+// catchPanic runs fn, turning a panic into an error.
+func catchPanic(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn()
+}
+
+// processEvent wraps a raw event processor, errors and panics go to the error handler and become dead letters.
+// The processor is registered under name so it's dead letters can be replayed.
+func (bot *Bot) processEvent(name string, fn eventProcessor) func(s *discordgo.Session, e *discordgo.Event) {
+	bot.eventProcessors[name] = fn
+	return func(s *discordgo.Session, e *discordgo.Event) {
+		err := catchPanic(func() error { return fn(s, e) })
+		if err == nil {
+			return
+		}
+		bot.ErrorHandler(bot, err)
+		bot.CaptureDeadLetter(DeadLetter{Kind: "event", Handler: name, Type: e.Type, Payload: e.RawData, Error: err.Error()})
+	}
+}
+
+// runMonitor runs a monitor, a panic goes to the error handler and the message becomes a dead letter.
+func (bot *Bot) runMonitor(monitor *Monitor, ctx *MonitorContext) {
+	defer func() {
+		if err := recover(); err != nil {
+			bot.ErrorHandler(bot, err)
+			payload, _ := json.Marshal(ctx.Message)
+			bot.CaptureDeadLetter(DeadLetter{Kind: "monitor", Handler: monitor.Name, Payload: payload, Error: fmt.Sprint(err)})
+		}
+	}()
+	monitor.Run(bot, ctx)
+}
+
+func replayEvent(bot *Bot) func(letter DeadLetter) error {
+	return func(letter DeadLetter) error {
+		fn, ok := bot.eventProcessors[letter.Handler]
+		if !ok {
+			return fmt.Errorf("there is no event processor called %s", letter.Handler)
+		}
+		return fn(bot.Session, &discordgo.Event{Type: letter.Type, RawData: letter.Payload})
+	}
+}
+
+func replayMonitor(bot *Bot) func(letter DeadLetter) error {
+	return func(letter DeadLetter) error {
+		monitor, ok := bot.Monitors[letter.Handler]
+		if !ok {
+			return fmt.Errorf("there is no monitor called %s", letter.Handler)
+		}
+		m := &discordgo.Message{}
+		if err := json.Unmarshal(letter.Payload, m); err != nil {
+			return err
+		}
+		channel, err := bot.Session.State.Channel(m.ChannelID)
+		if err != nil {
+			return err
+		}
+		var guild *discordgo.Guild
+		if m.GuildID != "" {
+			if guild, err = bot.Session.State.Guild(m.GuildID); err != nil {
+				return err
+			}
+		}
+		monitor.Run(bot, &MonitorContext{
+			Session: bot.Session,
+			Message: m,
+			Author:  m.Author,
+			Channel: channel,
+			Monitor: monitor,
+			Guild:   guild,
+			Bot:     bot,
+		})
+		return nil
+	}
+}
+
+func deadLettersCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+	action := strings.ToLower(ctx.ArgString(0))
+	if action == "" || action == "list" {
+		letters := bot.DeadLetters()
+		if len(letters) == 0 {
+			ctx.ReplyLocale("COMMAND_DEADLETTERS_EMPTY")
+			return
+		}
+		if len(letters) > 20 {
+			letters = letters[len(letters)-20:]
+		}
+		lines := make([]string, 0, len(letters))
+		for _, letter := range letters {
+			handler := letter.Handler
+			if letter.Type != "" {
+				handler += " (" + letter.Type + ")"
+			}
+			lines = append(lines, fmt.Sprintf("**%d** %s `%s` %s ago: %s", letter.ID, letter.Kind, handler,
+				time.Since(letter.At).Round(time.Second), Escape(truncate(letter.Error, 100))))
+		}
+		ctx.BuildEmbed(NewEmbed().
+			SetTitle(ctx.Locale.Get("COMMAND_DEADLETTERS_TITLE")).
+			SetDescription(strings.Join(lines, "\n")).
+			SetColor(bot.Color))
+		return
+	}
+
+	all := strings.ToLower(ctx.ArgString(1)) == "all"
+	id, err := strconv.Atoi(ctx.ArgString(1))
+	if !all && err != nil {
+		ctx.ReplyLocale("COMMAND_DEADLETTERS_USAGE", ctx.Prefix)
+		return
+	}
+	switch action {
+	case "show":
+		for _, letter := range bot.DeadLetters() {
+			if letter.ID == id {
+				ctx.Reply("**%d** %s `%s` %s\n%s\n```json\n%s```", letter.ID, letter.Kind, letter.Handler, letter.Type,
+					Escape(letter.Error), truncate(strings.Replace(string(letter.Payload), "```", "`\u200b``", -1), 1500))
+				return
+			}
+		}
+		ctx.ReplyLocale("COMMAND_DEADLETTERS_NOT_FOUND", ctx.ArgString(1))
+	case "replay":
+		ids := []int{id}
+		if all {
+			ids = nil
+			for _, letter := range bot.DeadLetters() {
+				ids = append(ids, letter.ID)
+			}
+		}
+		failed := 0
+		for _, id := range ids {
+			if err := bot.ReplayDeadLetter(id); err != nil {
+				failed++
+			}
+		}
+		ctx.ReplyLocale("COMMAND_DEADLETTERS_REPLAYED", len(ids)-failed, failed)
+	case "drop":
+		if all {
+			id = 0
+		}
+		if err := bot.DropDeadLetter(id); err != nil {
+			ctx.ReplyLocale("COMMAND_DEADLETTERS_NOT_FOUND", ctx.ArgString(1))
+			return
+		}
+		ctx.ReplyLocale("COMMAND_DEADLETTERS_DROPPED")
+	default:
+		ctx.ReplyLocale("COMMAND_DEADLETTERS_USAGE", ctx.Prefix)
+	}
+}
+
+// truncate shortens s to max runes.
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max]) + "…"
+}
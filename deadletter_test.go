@@ -0,0 +1,34 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"testing"
+)
+
+func TestDeadLetters(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	fail := true
+	processor := bot.processEvent("test", func(s *discordgo.Session, e *discordgo.Event) error {
+		if fail {
+			panic("broken")
+		}
+		return nil
+	})
+	processor(nil, &discordgo.Event{Type: "TEST"})
+	if len(bot.DeadLetters()) != 0 {
+		t.Errorf("Expected no dead letters before EnableDeadLetters")
+	}
+	bot.EnableDeadLetters()
+	processor(nil, &discordgo.Event{Type: "TEST", RawData: []byte(`{"a":1}`)})
+	letters := bot.DeadLetters()
+	if len(letters) != 1 || letters[0].Handler != "test" || letters[0].Type != "TEST" || letters[0].Error != "panic: broken" {
+		t.Fatalf("Unexpected dead letters %+v", letters)
+	}
+	if err := bot.ReplayDeadLetter(letters[0].ID); err == nil || len(bot.DeadLetters()) != 1 {
+		t.Errorf("Expected the failed replay to keep the dead letter")
+	}
+	fail = false
+	if err := bot.ReplayDeadLetter(letters[0].ID); err != nil || len(bot.DeadLetters()) != 0 {
+		t.Errorf("Expected the replay to succeed and remove the dead letter, got %v", err)
+	}
+}
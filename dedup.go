@@ -0,0 +1,40 @@
+package sapphire
+
+import (
+	"sync"
+	"time"
+)
+
+// MessageDedupTTL is how long handled message events are remembered, a duplicate within it is dropped.
+// Reconnects can replay events and Discord occasionally dispatches the same event twice.
+var MessageDedupTTL = 5 * time.Minute
+
+type eventDedup struct {
+	// event key -> when it was first seen
+	seen      map[string]time.Time
+	lastSweep time.Time
+	lock      sync.Mutex
+}
+
+func newEventDedup() *eventDedup {
+	return &eventDedup{seen: make(map[string]time.Time), lastSweep: time.Now()}
+}
+
+// firstSeen records the key and reports if it wasn't seen within MessageDedupTTL
+func (d *eventDedup) firstSeen(key string, now time.Time) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if at, ok := d.seen[key]; ok && now.Sub(at) < MessageDedupTTL {
+		return false
+	}
+	d.seen[key] = now
+	if now.Sub(d.lastSweep) >= MessageDedupTTL {
+		for k, at := range d.seen {
+			if now.Sub(at) >= MessageDedupTTL {
+				delete(d.seen, k)
+			}
+		}
+		d.lastSweep = now
+	}
+	return true
+}
@@ -0,0 +1,20 @@
+package sapphire
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventDedup(t *testing.T) {
+	d := newEventDedup()
+	now := time.Now()
+	if !d.firstSeen("1", now) {
+		t.Error("Expected the first event to be handled")
+	}
+	if d.firstSeen("1", now.Add(time.Second)) {
+		t.Error("Expected a duplicate event to be dropped")
+	}
+	if !d.firstSeen("1", now.Add(MessageDedupTTL)) {
+		t.Error("Expected the event to be handled again after the TTL")
+	}
+}
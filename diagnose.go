@@ -0,0 +1,169 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strings"
+)
+
+// Finding is a configuration problem found by bot.Diagnose
+type Finding struct {
+	Check   string // What found it e.g "intents", "locale", "settings", "usage" or "aliases".
+	Problem string // What is wrong.
+	Fix     string // What to do about it.
+}
+
+// String formats the finding for logs.
+func (f Finding) String() string {
+	return fmt.Sprintf("[%s] %s %s", f.Check, f.Problem, f.Fix)
+}
+
+// intentNames are the names of the gateway intents for findings.
+var intentNames = map[discordgo.Intent]string{
+	discordgo.IntentsGuilds:                 "IntentsGuilds",
+	discordgo.IntentsGuildMembers:           "IntentsGuildMembers",
+	discordgo.IntentsGuildVoiceStates:       "IntentsGuildVoiceStates",
+	discordgo.IntentsGuildPresences:         "IntentsGuildPresences",
+	discordgo.IntentsGuildMessages:          "IntentsGuildMessages",
+	discordgo.IntentsGuildMessageReactions:  "IntentsGuildMessageReactions",
+	discordgo.IntentsDirectMessages:         "IntentsDirectMessages",
+	discordgo.IntentsDirectMessageReactions: "IntentsDirectMessageReactions",
+}
+
+// Diagnose checks the bot for common configuration problems: gateway intents the enabled modules need but the session
+// doesn't request, keys languages are missing compared to the default locale, an unreachable settings provider,
+// commands whose usage doesn't compile and aliases claimed by several commands.
+// It runs on Connect and from the owner only diagnose command, an empty result means nothing was found.
+func (bot *Bot) Diagnose() []Finding {
+	var findings []Finding
+	findings = append(findings, bot.diagnoseIntents()...)
+	findings = append(findings, bot.diagnoseLocales()...)
+	if _, _, err := bot.Settings.Get("", "diagnose"); err != nil {
+		findings = append(findings, Finding{"settings", "The settings provider can't be reached: " + err.Error() + ".",
+			"Check the database is running and the connection details are right."})
+	}
+	findings = append(findings, bot.diagnoseCommands()...)
+	return findings
+}
+
+func (bot *Bot) diagnoseIntents() []Finding {
+	// Without intents set Discord sends everything that isn't privileged.
+	if bot.Session.Identify.Intents == nil {
+		return nil
+	}
+	have := *bot.Session.Identify.Intents
+	needs := map[discordgo.Intent][]string{}
+	if len(bot.Commands) > 0 {
+		needs[discordgo.IntentsGuildMessages] = append(needs[discordgo.IntentsGuildMessages], "commands")
+	}
+	for _, feature := range moduleFeatures {
+		if !feature.enabled(bot) {
+			continue
+		}
+		for intent := range intentNames {
+			if feature.intents&intent == intent && intent != 0 {
+				needs[intent] = append(needs[intent], feature.name)
+			}
+		}
+	}
+
+	var findings []Finding
+	for intent, features := range needs {
+		if have&intent == intent {
+			continue
+		}
+		sort.Strings(features)
+		fix := fmt.Sprintf("Add discordgo.%s to Session.Identify.Intents.", intentNames[intent])
+		if intent == discordgo.IntentsGuildMembers || intent == discordgo.IntentsGuildPresences {
+			fix += " It's privileged, enable it in the developer portal too."
+		}
+		findings = append(findings, Finding{"intents", fmt.Sprintf("%s need %s but the session doesn't request it.",
+			strings.Join(features, ", "), intentNames[intent]), fix})
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Problem < findings[j].Problem })
+	return findings
+}
+
+func (bot *Bot) diagnoseLocales() []Finding {
+	var names []string
+	for name := range bot.Languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var findings []Finding
+	for _, name := range names {
+		lang := bot.Languages[name]
+		if lang == bot.DefaultLocale {
+			continue
+		}
+		var missing []string
+		for key := range bot.DefaultLocale.Keys {
+			if _, ok := lang.Keys[key]; ok {
+				continue
+			}
+			if _, ok := lang.Embeds[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		sort.Strings(missing)
+		findings = append(findings, Finding{"locale", fmt.Sprintf("%s is missing %d keys of %s, they're shown in %[3]s: %s.",
+			name, len(missing), bot.DefaultLocale.Name, truncate(strings.Join(missing, ", "), 200)),
+			"Translate the keys or Merge the language into a copy of the default locale."})
+	}
+	return findings
+}
+
+func (bot *Bot) diagnoseCommands() []Finding {
+	var names []string
+	for name := range bot.Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var findings []Finding
+	owners := map[string][]string{}
+	for _, name := range names {
+		cmd := bot.Commands[name]
+		if _, err := CompileUsage(cmd.UsageString); err != nil {
+			findings = append(findings, Finding{"usage", fmt.Sprintf("The usage of %s doesn't compile: %v.", name, err),
+				"Fix the usage string, see the Arguments guide for the syntax."})
+		}
+		for _, alias := range cmd.Aliases {
+			owners[alias] = append(owners[alias], name)
+		}
+	}
+
+	var aliases []string
+	for alias := range owners {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		if _, ok := bot.Commands[alias]; ok {
+			findings = append(findings, Finding{"aliases", fmt.Sprintf("The alias %s of %s is also a command name, the command wins.",
+				alias, strings.Join(owners[alias], ", ")), "Remove the alias or rename the command."})
+		} else if len(owners[alias]) > 1 {
+			findings = append(findings, Finding{"aliases", fmt.Sprintf("The alias %s is used by %s, only %s gets it.",
+				alias, strings.Join(owners[alias], ", "), bot.aliases[alias]), "Remove the alias from all but one command."})
+		}
+	}
+	return findings
+}
+
+func diagnoseCommand(ctx *CommandContext) {
+	findings := ctx.Bot.Diagnose()
+	if len(findings) == 0 {
+		ctx.ReplyLocale("COMMAND_DIAGNOSE_OK")
+		return
+	}
+	lines := make([]string, len(findings))
+	for i, finding := range findings {
+		lines[i] = fmt.Sprintf("**%s** %s %s", finding.Check, finding.Problem, finding.Fix)
+	}
+	ctx.ReplyLocale("COMMAND_DIAGNOSE_FINDINGS", len(findings), truncate(strings.Join(lines, "\n"), 1800))
+}
@@ -0,0 +1,25 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"testing"
+)
+
+func TestDiagnose(t *testing.T) {
+	bot := New(&discordgo.Session{State: discordgo.NewState()})
+	intents := discordgo.IntentsGuilds | discordgo.IntentsGuildMessages
+	bot.Session.Identify.Intents = &intents
+	bot.EnableStatChannels()
+	bot.AddLanguage(NewLanguage("de-DE").Set("COMMAND_PING", "Pong!"))
+	bot.AddCommand(NewCommand("one", "General", func(ctx *CommandContext) {}).AddAliases("x"))
+	bot.AddCommand(NewCommand("two", "General", func(ctx *CommandContext) {}).AddAliases("x", "one"))
+
+	checks := map[string]int{}
+	for _, finding := range bot.Diagnose() {
+		checks[finding.Check]++
+	}
+	// statchannels needs the members and presences intents.
+	if checks["intents"] != 2 || checks["locale"] != 1 || checks["aliases"] != 2 || checks["settings"] != 0 {
+		t.Errorf("Diagnose found %v", checks)
+	}
+}
@@ -0,0 +1,157 @@
+package sapphire
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// The Regexp used for matching custom emojis e.g <:name:id> or <a:name:id> for animated ones.
+var EmojiRegex = regexp.MustCompile("^<(a?):(\\w{2,32}):(\\d{17,19})>$")
+
+// MaxEmojiSize is the maximum size of an emoji image in bytes.
+const MaxEmojiSize = 256 * 1024
+
+// ErrNoEmojiSlots is returned when the guild has no free slot for the emoji.
+var ErrNoEmojiSlots = errors.New("This server has no free emoji slots left.")
+
+// ParseEmoji parses a custom emoji, returns nil if s isn't one. Only ID, Name and Animated are filled.
+func ParseEmoji(s string) *discordgo.Emoji {
+	match := EmojiRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return nil
+	}
+	return &discordgo.Emoji{Animated: match[1] == "a", Name: match[2], ID: match[3]}
+}
+
+// EmojiURL returns the CDN url of a custom emoji's image, a gif for animated emojis.
+func EmojiURL(emoji *discordgo.Emoji) string {
+	ext := ".png"
+	if emoji.Animated {
+		ext = ".gif"
+	}
+	return discordgo.EndpointCDN + "emojis/" + emoji.ID + ext
+}
+
+// EmojiSlots returns how many emojis of each kind (static and animated) the guild can have, it depends on the boost level.
+func EmojiSlots(guild *discordgo.Guild) int {
+	switch guild.PremiumTier {
+	case 1:
+		return 100
+	case 2:
+		return 150
+	case 3:
+		return 250
+	default:
+		return 50
+	}
+}
+
+// FreeEmojiSlots returns how many more static or animated emojis the guild can add.
+func FreeEmojiSlots(guild *discordgo.Guild, animated bool) int {
+	used := 0
+	for _, emoji := range guild.Emojis {
+		if emoji.Animated == animated {
+			used++
+		}
+	}
+	return EmojiSlots(guild) - used
+}
+
+// CopyEmoji downloads a custom emoji and uploads it to the guild, it's slots are checked first.
+// name can be empty to keep the emoji's name, this works for emojis of any guild, not only ones the bot is in.
+func (bot *Bot) CopyEmoji(guildID string, emoji *discordgo.Emoji, name string) (*discordgo.Emoji, error) {
+	guild, err := bot.Session.State.Guild(guildID)
+	if err != nil {
+		return nil, err
+	}
+	if FreeEmojiSlots(guild, emoji.Animated) < 1 {
+		return nil, ErrNoEmojiSlots
+	}
+	if name == "" {
+		name = emoji.Name
+	}
+
+	res, err := http.Get(EmojiURL(emoji))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Couldn't download the emoji: %s", res.Status)
+	}
+	// Read one byte more than allowed to tell if it's too big.
+	data, err := ioutil.ReadAll(io.LimitReader(res.Body, MaxEmojiSize+1))
+	if err != nil {
+		return nil, err
+	}
+	return bot.UploadEmoji(guildID, name, data, emoji.Animated)
+}
+
+// UploadEmoji uploads raw png or gif image data as a new emoji.
+func (bot *Bot) UploadEmoji(guildID, name string, data []byte, animated bool) (*discordgo.Emoji, error) {
+	if len(data) > MaxEmojiSize {
+		return nil, fmt.Errorf("The emoji is too big, it must be under %s.", English.FormatBytes(MaxEmojiSize))
+	}
+	mime := "image/png"
+	if animated {
+		mime = "image/gif"
+	}
+	image := "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data)
+	return bot.Session.GuildEmojiCreate(guildID, name, image, nil)
+}
+
+// LoadEmojiCommands loads the emoji command pack:
+// steal (copies a custom emoji to the server), emoji (shows an emoji) and emojis (shows the free slots)
+func (bot *Bot) LoadEmojiCommands() *Bot {
+	bot.AddCommand(NewCommand("steal", "Emojis", func(ctx *CommandContext) {
+		name := ""
+		if ctx.Arg(1).IsProvided() {
+			name = ctx.Arg(1).AsString()
+		}
+		emoji, err := ctx.Bot.CopyEmoji(ctx.Guild.ID, ctx.Arg(0).AsEmoji(), name)
+		if err == ErrNoEmojiSlots {
+			ctx.ReplyLocale("COMMAND_EMOJI_NO_SLOTS", ctx.Locale.FormatCount(int64(EmojiSlots(ctx.Guild))))
+			return
+		}
+		if err != nil {
+			ctx.ReplyLocale("COMMAND_EMOJI_FAILED", err.Error())
+			return
+		}
+		ctx.ReplyLocale("COMMAND_EMOJI_ADDED", emoji.MessageFormat(), emoji.Name)
+	}).SetDescription("Adds a custom emoji from another server to this one.").
+		SetUsage("<emoji:emoji> [name:string]").
+		AddAliases("stealemoji", "copyemoji").
+		SetRequiredPermissions(discordgo.PermissionManageEmojis).
+		SetBotPermissions(discordgo.PermissionManageEmojis).
+		SetGuildOnly(true).
+		SetCooldown(5))
+
+	bot.AddCommand(NewCommand("emoji", "Emojis", func(ctx *CommandContext) {
+		emoji := ctx.Arg(0).AsEmoji()
+		ctx.BuildEmbed(NewEmbed().
+			SetTitle(emoji.Name).
+			SetURL(EmojiURL(emoji)).
+			SetImage(EmojiURL(emoji)).
+			SetColor(ctx.Bot.Color).
+			SetFooter(fmt.Sprintf("ID: %s | Animated: %t", emoji.ID, emoji.Animated)))
+	}).SetDescription("Shows a custom emoji in full size.").
+		SetUsage("<emoji:emoji>").
+		AddAliases("bigemoji", "enlarge"))
+
+	bot.AddCommand(NewCommand("emojis", "Emojis", func(ctx *CommandContext) {
+		slots := EmojiSlots(ctx.Guild)
+		ctx.ReplyLocale("COMMAND_EMOJI_SLOTS",
+			slots-FreeEmojiSlots(ctx.Guild, false), slots,
+			slots-FreeEmojiSlots(ctx.Guild, true), slots)
+	}).SetDescription("Shows how many emoji slots this server has left.").
+		AddAliases("emojislots").
+		SetGuildOnly(true))
+	return bot
+}
@@ -0,0 +1,18 @@
+package sapphire
+
+import (
+	"testing"
+)
+
+func TestParseEmoji(t *testing.T) {
+	emoji := ParseEmoji("<a:party:123456789012345678>")
+	if emoji == nil || !emoji.Animated || emoji.Name != "party" || emoji.ID != "123456789012345678" {
+		t.Errorf("Unexpected parsed emoji %+v", emoji)
+	}
+	if ParseEmoji("<:ok:123456789012345678>").Animated {
+		t.Error("Expected a static emoji")
+	}
+	if ParseEmoji("🙂") != nil {
+		t.Error("Expected unicode emojis to not parse as custom emojis")
+	}
+}
@@ -0,0 +1,196 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"sync"
+	"time"
+)
+
+// Gateway event names for entitlements.
+const (
+	EntitlementCreate = "ENTITLEMENT_CREATE"
+	EntitlementUpdate = "ENTITLEMENT_UPDATE"
+	EntitlementDelete = "ENTITLEMENT_DELETE"
+)
+
+// Entitlement represents a user's or guild's access to a premium SKU of the application.
+// discordgo doesn't know about entitlements so sapphire decodes them from the raw gateway events.
+type Entitlement struct {
+	ID            string     `json:"id"`
+	SKUID         string     `json:"sku_id"`
+	ApplicationID string     `json:"application_id"`
+	UserID        string     `json:"user_id"`  // Empty for guild subscriptions.
+	GuildID       string     `json:"guild_id"` // Empty for user subscriptions.
+	Type          int        `json:"type"`
+	Deleted       bool       `json:"deleted"`
+	StartsAt      *time.Time `json:"starts_at"`
+	EndsAt        *time.Time `json:"ends_at"`
+}
+
+// Active returns true if the entitlement currently grants access.
+func (e *Entitlement) Active() bool {
+	if e.Deleted {
+		return false
+	}
+	now := time.Now()
+	if e.StartsAt != nil && now.Before(*e.StartsAt) {
+		return false
+	}
+	return e.EndsAt == nil || now.Before(*e.EndsAt)
+}
+
+// EntitlementContext is passed to entitlement handlers.
+type EntitlementContext struct {
+	Bot         *Bot
+	Session     *discordgo.Session
+	Event       string       // One of EntitlementCreate, EntitlementUpdate or EntitlementDelete
+	Entitlement *Entitlement // The entitlement, for EntitlementDelete Active() is always false.
+}
+
+// EntitlementHandler handles entitlement events, add them with bot.OnEntitlement
+type EntitlementHandler func(ctx *EntitlementContext)
+
+// EntitlementStore persists entitlements, set it with bot.SetEntitlementStore to keep track of them across restarts.
+type EntitlementStore interface {
+	SaveEntitlement(e *Entitlement) error
+	DeleteEntitlement(id string) error
+}
+
+// UserEntitlementStore is an EntitlementStore that can look up a user's entitlements, implement it so the stored
+// entitlements are included in bot.ExportUserData and bot.DeleteUserData
+type UserEntitlementStore interface {
+	EntitlementStore
+	UserEntitlements(userID string) ([]*Entitlement, error)
+}
+
+// SetEntitlementStore sets the store that entitlement events are persisted to before handlers run.
+func (bot *Bot) SetEntitlementStore(store EntitlementStore) *Bot {
+	bot.EntitlementStore = store
+	bot.AddDataSubject(entitlementData(bot))
+	return bot
+}
+
+// entitlementData is the data subject of the stored entitlements, the store has to be a UserEntitlementStore to find them.
+func entitlementData(bot *Bot) DataSubject {
+	entitlements := func(userID string) ([]*Entitlement, error) {
+		store, ok := bot.EntitlementStore.(UserEntitlementStore)
+		if !ok {
+			return nil, errors.New("the entitlement store can't look up a user's entitlements")
+		}
+		return store.UserEntitlements(userID)
+	}
+	return &userData{
+		name: "entitlements",
+		export: func(userID string) (interface{}, error) {
+			owned, err := entitlements(userID)
+			if err != nil || len(owned) == 0 {
+				return nil, err
+			}
+			return owned, nil
+		},
+		delete: func(userID string) error {
+			owned, err := entitlements(userID)
+			if err != nil {
+				return err
+			}
+			for _, e := range owned {
+				if err := bot.EntitlementStore.DeleteEntitlement(e.ID); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// OnEntitlement adds a handler called for every entitlement event.
+func (bot *Bot) OnEntitlement(handler EntitlementHandler) *Bot {
+	bot.entitlementHandlers = append(bot.entitlementHandlers, handler)
+	return bot
+}
+
+// GrantPremium returns an entitlement handler that grants or revokes premium in p as entitlements change.
+// A user or guild keeps premium while any of their entitlements is active, e.g an ended subscription doesn't revoke
+// premium bought with another SKU. e.g bot.OnEntitlement(sapphire.GrantPremium(premium))
+func GrantPremium(p *ManualPremium) EntitlementHandler {
+	// Subject ("user:<id>" or "guild:<id>") -> entitlement ID -> entitlement
+	held := make(map[string]map[string]*Entitlement)
+	var lock sync.Mutex
+	return func(ctx *EntitlementContext) {
+		e := ctx.Entitlement
+		subject := "user:" + e.UserID
+		if e.UserID == "" {
+			if e.GuildID == "" {
+				return
+			}
+			subject = "guild:" + e.GuildID
+		}
+
+		lock.Lock()
+		entitlements := held[subject]
+		if entitlements == nil {
+			entitlements = make(map[string]*Entitlement)
+			held[subject] = entitlements
+		}
+		if ctx.Event == EntitlementDelete || e.Deleted {
+			delete(entitlements, e.ID)
+		} else {
+			entitlements[e.ID] = e
+		}
+		active := false
+		for id, entitlement := range entitlements {
+			if entitlement.Active() {
+				active = true
+			} else if entitlement.EndsAt != nil && time.Now().After(*entitlement.EndsAt) {
+				delete(entitlements, id)
+			}
+		}
+		if len(entitlements) == 0 {
+			delete(held, subject)
+		}
+		lock.Unlock()
+
+		if e.UserID != "" {
+			p.SetUser(e.UserID, active)
+		} else {
+			p.SetGuild(e.GuildID, active)
+		}
+	}
+}
+
+func entitlementListener(bot *Bot) func(s *discordgo.Session, e *discordgo.Event) {
+	return bot.processEvent("entitlements", func(s *discordgo.Session, e *discordgo.Event) error {
+		if e.Type != EntitlementCreate && e.Type != EntitlementUpdate && e.Type != EntitlementDelete {
+			return nil
+		}
+
+		entitlement := &Entitlement{}
+		if err := json.Unmarshal(e.RawData, entitlement); err != nil {
+			return err
+		}
+		if e.Type == EntitlementDelete {
+			entitlement.Deleted = true
+		}
+
+		// A failed save is returned before the handlers run so a replay doesn't run them twice.
+		if bot.EntitlementStore != nil {
+			var err error
+			if entitlement.Deleted {
+				err = bot.EntitlementStore.DeleteEntitlement(entitlement.ID)
+			} else {
+				err = bot.EntitlementStore.SaveEntitlement(entitlement)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		ctx := &EntitlementContext{Bot: bot, Session: s, Event: e.Type, Entitlement: entitlement}
+		for _, handler := range bot.entitlementHandlers {
+			handler(ctx)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,35 @@
+package sapphire
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGrantPremium(t *testing.T) {
+	premium := NewManualPremium()
+	grant := GrantPremium(premium)
+	event := func(typ string, e *Entitlement) {
+		grant(&EntitlementContext{Event: typ, Entitlement: e})
+	}
+	past := time.Now().Add(-time.Hour)
+
+	event(EntitlementCreate, &Entitlement{ID: "1", SKUID: "monthly", UserID: "u"})
+	event(EntitlementCreate, &Entitlement{ID: "2", SKUID: "lifetime", UserID: "u"})
+	event(EntitlementUpdate, &Entitlement{ID: "1", SKUID: "monthly", UserID: "u", EndsAt: &past})
+	if !premium.IsPremiumUser("u") {
+		t.Error("Expected an ended entitlement not to revoke premium held through another one")
+	}
+	event(EntitlementDelete, &Entitlement{ID: "2", SKUID: "lifetime", UserID: "u"})
+	if premium.IsPremiumUser("u") {
+		t.Error("Expected premium to be revoked once no entitlement is active")
+	}
+
+	event(EntitlementCreate, &Entitlement{ID: "3", GuildID: "g"})
+	if !premium.IsPremiumGuild("g") {
+		t.Error("Expected the guild to get premium")
+	}
+	event(EntitlementDelete, &Entitlement{ID: "3", GuildID: "g"})
+	if premium.IsPremiumGuild("g") {
+		t.Error("Expected the guild's premium to be revoked")
+	}
+}
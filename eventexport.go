@@ -0,0 +1,130 @@
+package sapphire
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"time"
+)
+
+// ExportSchemaVersion is the version of ExportedEvent, it's increased when fields change incompatibly.
+const ExportSchemaVersion = 1
+
+// EventPublisher publishes exported events to a message queue, implement it with your Kafka, NATS or Redis client.
+// key is the guild ID, use it as the partition key to keep a guild's events in order.
+type EventPublisher interface {
+	Publish(subject, key string, data []byte) error
+}
+
+// EventPublisherFunc is a function implementing EventPublisher
+type EventPublisherFunc func(subject, key string, data []byte) error
+
+// Publish implements EventPublisher
+func (fn EventPublisherFunc) Publish(subject, key string, data []byte) error {
+	return fn(subject, key, data)
+}
+
+// ExportedEvent is the JSON published for every exported event.
+type ExportedEvent struct {
+	Schema  int             `json:"schema"` // ExportSchemaVersion
+	ID      string          `json:"id"`
+	Source  string          `json:"source"` // "gateway" or "bus"
+	Type    string          `json:"type"`   // The gateway event e.g MESSAGE_CREATE or the bus topic e.g command.ran
+	GuildID string          `json:"guild_id,omitempty"`
+	At      time.Time       `json:"at"`
+	Data    json.RawMessage `json:"data"` // The raw gateway payload or the bus event with it's Go field names.
+}
+
+// EventExport publishes gateway and bus events to a message queue so they can be consumed off-process, see bot.ExportEvents
+type EventExport struct {
+	Publisher EventPublisher
+	Subject   string   // Prefix of the subjects, events go to "<subject>.gateway.message_create" or "<subject>.bus.command.ran". (default: sapphire)
+	Gateway   []string // Gateway events to export e.g "MESSAGE_CREATE", "*" exports all of them.
+	Topics    []string // Bus topics to export e.g TopicCommandRan
+	// Filter is checked before publishing, return false to skip an event e.g to leave out some guilds or strip fields.
+	Filter func(event *ExportedEvent) bool
+}
+
+// ExportEvents publishes the export's gateway events and bus topics to it's publisher.
+// Events that fail to publish go to the error handler, the publisher should buffer and retry if losing them matters.
+// Returns a function stopping the export.
+func (bot *Bot) ExportEvents(export *EventExport) func() {
+	if export.Subject == "" {
+		export.Subject = "sapphire"
+	}
+	var stops []func()
+	if len(export.Gateway) > 0 {
+		stops = append(stops, bot.AddHandler(func(s *discordgo.Session, e *discordgo.Event) {
+			if !containsString(export.Gateway, "*") && !containsString(export.Gateway, e.Type) {
+				return
+			}
+			bot.exportEvent(export, "gateway", e.Type, e.RawData)
+		}))
+	}
+	for _, topic := range export.Topics {
+		stops = append(stops, bot.Subscribe(topic, func(event BusEvent) {
+			data, err := json.Marshal(event)
+			if err != nil {
+				bot.ErrorHandler(bot, err)
+				return
+			}
+			bot.exportEvent(export, "bus", event.Topic(), data)
+		}))
+	}
+	return func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+}
+
+// exportEvent wraps the data in an ExportedEvent and publishes it.
+func (bot *Bot) exportEvent(export *EventExport, source, typ string, data json.RawMessage) {
+	id := make([]byte, 12)
+	rand.Read(id)
+	event := &ExportedEvent{
+		Schema:  ExportSchemaVersion,
+		ID:      hex.EncodeToString(id),
+		Source:  source,
+		Type:    typ,
+		GuildID: exportGuildID(typ, data),
+		At:      time.Now().UTC(),
+		Data:    data,
+	}
+	if export.Filter != nil && !export.Filter(event) {
+		return
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		bot.ErrorHandler(bot, err)
+		return
+	}
+	subject := export.Subject + "." + source + "." + strings.ToLower(typ)
+	if err := export.Publisher.Publish(subject, event.GuildID, body); err != nil {
+		bot.ErrorHandler(bot, fmt.Errorf("exporting %s %s failed: %v", typ, event.ID, err))
+	}
+}
+
+// exportGuildID finds the guild of an event, gateway events use guild_id or id for GUILD_ events, bus events GuildID.
+func exportGuildID(typ string, data json.RawMessage) string {
+	var fields struct {
+		ID        string `json:"id"`
+		GuildID   string `json:"guild_id"`
+		GoGuildID string `json:"GuildID"`
+	}
+	if json.Unmarshal(data, &fields) != nil {
+		return ""
+	}
+	switch {
+	case fields.GuildID != "":
+		return fields.GuildID
+	case fields.GoGuildID != "":
+		return fields.GoGuildID
+	case typ == "GUILD_CREATE" || typ == "GUILD_UPDATE" || typ == "GUILD_DELETE":
+		return fields.ID
+	}
+	return ""
+}
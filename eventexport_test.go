@@ -0,0 +1,44 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"github.com/bwmarrin/discordgo"
+	"testing"
+	"time"
+)
+
+func TestExportEvents(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	published := make(chan [2]string, 4)
+	var exported []*ExportedEvent
+	bot.ExportEvents(&EventExport{
+		Publisher: EventPublisherFunc(func(subject, key string, data []byte) error {
+			event := &ExportedEvent{}
+			json.Unmarshal(data, event)
+			exported = append(exported, event)
+			published <- [2]string{subject, key}
+			return nil
+		}),
+		Gateway: []string{"GUILD_MEMBER_ADD"},
+		Topics:  []string{TopicModerationAction},
+		Filter:  func(event *ExportedEvent) bool { return event.GuildID != "ignored" },
+	})
+	bot.DispatchEvent("MESSAGE_CREATE", json.RawMessage(`{"guild_id":"1","content":"hi"}`))
+	bot.DispatchEvent("GUILD_MEMBER_ADD", json.RawMessage(`{"guild_id":"ignored","user":{"id":"2"}}`))
+	bot.DispatchEvent("GUILD_MEMBER_ADD", json.RawMessage(`{"guild_id":"1","user":{"id":"2"}}`))
+	if got := <-published; got != [2]string{"sapphire.gateway.guild_member_add", "1"} {
+		t.Errorf("Unexpected subject and key %v", got)
+	}
+	if len(exported) != 1 || exported[0].Schema != ExportSchemaVersion || exported[0].Source != "gateway" {
+		t.Errorf("Unexpected exported events %+v", exported)
+	}
+	bot.Publish(&ModerationAction{GuildID: "3", Action: "ban"})
+	select {
+	case got := <-published:
+		if got != [2]string{"sapphire.bus." + TopicModerationAction, "3"} {
+			t.Errorf("Unexpected subject and key %v", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the bus event wasn't exported")
+	}
+}
@@ -0,0 +1,166 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// ContentExtractor extracts text from images, e.g with an OCR service or a local tesseract install.
+// Set it with bot.SetContentExtractor, without one images are ignored.
+type ContentExtractor interface {
+	ExtractText(image []byte, filename string) (string, error)
+}
+
+// ContentExtractorFunc is a function implementing ContentExtractor
+type ContentExtractorFunc func(image []byte, filename string) (string, error)
+
+// ExtractText implements ContentExtractor
+func (fn ContentExtractorFunc) ExtractText(image []byte, filename string) (string, error) {
+	return fn(image, filename)
+}
+
+// MaxExtractSize is the maximum size of an attachment in bytes that is passed to the ContentExtractor, bigger ones are skipped.
+const MaxExtractSize = 8 * 1024 * 1024
+
+// maxExtractCache is how many attachments the extracted text is remembered for.
+const maxExtractCache = 500
+
+// imageExtensions are the attachment types passed to the ContentExtractor.
+var imageExtensions = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true, ".bmp": true}
+
+type extractCache struct {
+	// attachment ID -> text, attachments never change so there is nothing to invalidate.
+	texts map[string]string
+	lock  sync.Mutex
+}
+
+// SetContentExtractor sets the extractor used to read text from image attachments, see bot.ExtractText
+func (bot *Bot) SetContentExtractor(extractor ContentExtractor) *Bot {
+	bot.ContentExtractor = extractor
+	return bot
+}
+
+// IsImage checks if an attachment is an image by its extension.
+func IsImage(attachment *discordgo.MessageAttachment) bool {
+	return imageExtensions[strings.ToLower(path.Ext(attachment.Filename))]
+}
+
+// ExtractText returns the text of the message's image attachments read by the ContentExtractor, one per line.
+// It's empty if there is no extractor or no images, results are cached per attachment.
+func (bot *Bot) ExtractText(msg *discordgo.Message) (string, error) {
+	if bot.ContentExtractor == nil {
+		return "", nil
+	}
+	var texts []string
+	for _, attachment := range msg.Attachments {
+		if !IsImage(attachment) || attachment.Size > MaxExtractSize {
+			continue
+		}
+		text, err := bot.extractAttachment(attachment)
+		if err != nil {
+			return strings.Join(texts, "\n"), err
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// MessageText returns the content of the message followed by the text extracted from its images,
+// filters should check this instead of the content to also catch text in images.
+func (bot *Bot) MessageText(msg *discordgo.Message) string {
+	extracted, err := bot.ExtractText(msg)
+	if err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+	if extracted == "" {
+		return msg.Content
+	}
+	return msg.Content + "\n" + extracted
+}
+
+func (bot *Bot) extractAttachment(attachment *discordgo.MessageAttachment) (string, error) {
+	bot.extracted.lock.Lock()
+	text, ok := bot.extracted.texts[attachment.ID]
+	bot.extracted.lock.Unlock()
+	if ok {
+		return text, nil
+	}
+
+	data, err := downloadAttachment(attachment, MaxExtractSize)
+	if err != nil {
+		return "", err
+	}
+	text, err = bot.ContentExtractor.ExtractText(data, attachment.Filename)
+	if err != nil {
+		return "", err
+	}
+
+	bot.extracted.lock.Lock()
+	if len(bot.extracted.texts) >= maxExtractCache {
+		// Attachments are rarely checked twice after a while, starting over is good enough.
+		bot.extracted.texts = make(map[string]string)
+	}
+	bot.extracted.texts[attachment.ID] = text
+	bot.extracted.lock.Unlock()
+	return text, nil
+}
+
+// downloadAttachment downloads up to limit bytes of an attachment.
+func downloadAttachment(attachment *discordgo.MessageAttachment, limit int64) ([]byte, error) {
+	res, err := http.Get(attachment.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Couldn't download the attachment: %s", res.Status)
+	}
+	return ioutil.ReadAll(io.LimitReader(res.Body, limit))
+}
+
+// LoadExtractCommands loads the readtext command, it shows the text in the images of a message.
+// It needs a ContentExtractor, see SetContentExtractor
+func (bot *Bot) LoadExtractCommands() *Bot {
+	return bot.AddCommand(NewCommand("readtext", "Utility", func(ctx *CommandContext) {
+		if ctx.Bot.ContentExtractor == nil {
+			ctx.ReplyLocale("COMMAND_READTEXT_DISABLED")
+			return
+		}
+		// Images attached to the command itself, otherwise the ones of the message replied to.
+		msg := ctx.Message
+		if len(msg.Attachments) == 0 {
+			ref, err := ctx.ReferencedMessage()
+			if err != nil {
+				ctx.Error(err)
+				return
+			}
+			if ref != nil {
+				msg = ref
+			}
+		}
+		ctx.Session.ChannelTyping(ctx.Channel.ID)
+		text, err := ctx.Bot.ExtractText(msg)
+		if err != nil {
+			ctx.ReplyLocale("COMMAND_READTEXT_FAILED", err.Error())
+			return
+		}
+		if text == "" {
+			ctx.ReplyLocale("COMMAND_READTEXT_NONE")
+			return
+		}
+		ctx.BuildEmbed(NewEmbed().
+			SetDescription(Escape(text)).
+			SetColor(ctx.Bot.Color).
+			TruncateDescription())
+	}).SetDescription("Reads the text in the images attached to your message or the message you reply to.").
+		AddAliases("ocr").
+		SetCooldown(10))
+}
@@ -0,0 +1,33 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExtractText(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	calls := 0
+	bot := &Bot{extracted: &extractCache{texts: make(map[string]string)}}
+	bot.SetContentExtractor(ContentExtractorFunc(func(image []byte, filename string) (string, error) {
+		calls++
+		return "text of " + string(image), nil
+	}))
+	msg := &discordgo.Message{Content: "look", Attachments: []*discordgo.MessageAttachment{
+		{ID: "1", Filename: "a.PNG", URL: server.URL + "/a"},
+		{ID: "2", Filename: "notes.txt", URL: server.URL + "/notes"},
+	}}
+	if text := bot.MessageText(msg); text != "look\ntext of /a" {
+		t.Errorf("Unexpected text %q", text)
+	}
+	bot.MessageText(msg)
+	if calls != 1 {
+		t.Errorf("Expected the extracted text to be cached, the extractor was called %d times", calls)
+	}
+}
@@ -0,0 +1,440 @@
+package sapphire
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"html"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// feedsKey is the guild settings key feeds are stored under.
+const feedsKey = "feeds"
+
+// MaxFeeds is the maximum amount of feeds a guild can have.
+const MaxFeeds = 10
+
+// maxFeedSize is the maximum size of a feed document in bytes.
+const maxFeedSize = 2 * 1024 * 1024
+
+// maxFeedSeen is how many entry IDs are remembered per feed, more than a feed usually lists.
+const maxFeedSeen = 200
+
+// maxFeedPosts is the maximum amount of new entries posted per feed and poll, the rest is marked as seen.
+const maxFeedPosts = 5
+
+// FeedInterval is how often the feeds of a guild are polled.
+var FeedInterval = 10 * time.Minute
+
+// FeedClient is the http client feeds are fetched with.
+var FeedClient = &http.Client{Timeout: 15 * time.Second}
+
+var htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
+
+var feedRoleRegex = regexp.MustCompile(`<@&(\d+)>`)
+
+// Feed is an RSS or Atom feed posted to a channel.
+type Feed struct {
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	ChannelID string `json:"channel_id"`
+	// Message posted with the embed, {title}, {url} and {feed} are replaced. (default: none, only the embed)
+	Template string   `json:"template,omitempty"`
+	Seen     []string `json:"seen"` // IDs of the posted entries, newest last.
+}
+
+// FeedEntry is an item of an RSS feed or an entry of an Atom feed.
+type FeedEntry struct {
+	ID        string // The guid or id, the link if it has none.
+	Title     string
+	URL       string
+	Summary   string // Text without HTML.
+	Published time.Time
+}
+
+type feedTracker struct {
+	// guild ID -> next poll
+	tasks map[string]*ScheduledTask
+	lock  sync.Mutex
+}
+
+// EnableFeeds loads the feeds command and polls the feeds of every guild every FeedInterval, posting new entries as
+// embeds. Entries already in a feed when it's added aren't posted.
+func (bot *Bot) EnableFeeds() *Bot {
+	if bot.feeds != nil {
+		return bot
+	}
+	bot.feeds = &feedTracker{tasks: make(map[string]*ScheduledTask)}
+	bot.AddHandler(func(s *discordgo.Session, g *discordgo.GuildCreate) {
+		// Spread the first polls of all guilds over a minute instead of fetching everything on connect.
+		spread := 0
+		for _, c := range g.ID {
+			spread += int(c)
+		}
+		bot.queueFeedPoll(g.ID, time.Duration(spread%60)*time.Second)
+	})
+	bot.AddHandler(func(s *discordgo.Session, g *discordgo.GuildDelete) {
+		bot.feeds.lock.Lock()
+		bot.feeds.tasks[g.ID].Cancel()
+		delete(bot.feeds.tasks, g.ID)
+		bot.feeds.lock.Unlock()
+	})
+	bot.AddCommand(NewCommand("feeds", "Moderation", feedsCommand).
+		SetDescription("Posts new entries of RSS and Atom feeds.").
+		SetUsage("[action:string] [name:string] [args:string...]").
+		AddAliases("feed", "rss").
+		SetGuildOnly(true))
+	return bot
+}
+
+// queueFeedPoll polls the guild's feeds after delay, replacing a poll that was already queued.
+func (bot *Bot) queueFeedPoll(guildID string, delay time.Duration) {
+	bot.feeds.lock.Lock()
+	defer bot.feeds.lock.Unlock()
+	bot.feeds.tasks[guildID].Cancel()
+	bot.feeds.tasks[guildID] = bot.Scheduler.After(delay, func() {
+		bot.queueFeedPoll(guildID, FeedInterval)
+		bot.pollFeeds(guildID)
+	})
+}
+
+// Feeds returns the feeds of a guild by name.
+func (bot *Bot) Feeds(guildID string) (map[string]*Feed, error) {
+	feeds := make(map[string]*Feed)
+	if _, err := GetJSON(bot.Settings, guildID, feedsKey, &feeds); err != nil {
+		return nil, err
+	}
+	return feeds, nil
+}
+
+// AddFeed fetches a feed and adds it to a guild, the entries it has now are marked as seen.
+func (bot *Bot) AddFeed(guildID string, feed *Feed) error {
+	_, entries, err := FetchFeed(feed.URL)
+	if err != nil {
+		return err
+	}
+	feed.Seen = nil
+	for _, entry := range entries {
+		feed.Seen = append(feed.Seen, entry.ID)
+	}
+	return bot.updateFeeds(guildID, func(feeds map[string]*Feed) error {
+		if _, ok := feeds[feed.Name]; !ok && len(feeds) >= MaxFeeds {
+			return fmt.Errorf("a server can have up to %d feeds", MaxFeeds)
+		}
+		feeds[feed.Name] = feed
+		return nil
+	})
+}
+
+// RemoveFeed removes a feed of a guild.
+func (bot *Bot) RemoveFeed(guildID, name string) error {
+	return bot.updateFeeds(guildID, func(feeds map[string]*Feed) error {
+		delete(feeds, name)
+		return nil
+	})
+}
+
+// updateFeeds changes the feeds of a guild under the guild's feed lock, nothing is saved if fn fails.
+func (bot *Bot) updateFeeds(guildID string, fn func(feeds map[string]*Feed) error) error {
+	unlock, err := bot.Lock("feeds:" + guildID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	feeds, err := bot.Feeds(guildID)
+	if err != nil {
+		return err
+	}
+	if err := fn(feeds); err != nil {
+		return err
+	}
+	return SetJSON(bot.Settings, guildID, feedsKey, feeds)
+}
+
+// pollFeeds fetches every feed of a guild and posts the new entries.
+func (bot *Bot) pollFeeds(guildID string) {
+	feeds, err := bot.Feeds(guildID)
+	if err != nil {
+		bot.ErrorHandler(bot, err)
+		return
+	}
+	for _, feed := range feeds {
+		title, entries, err := FetchFeed(feed.URL)
+		if err != nil {
+			// Feeds being down now and then is normal, they're tried again next time.
+			continue
+		}
+		fresh := newFeedEntries(feed.Seen, entries)
+		if len(fresh) == 0 {
+			continue
+		}
+		// Mark them as seen before posting so a failing channel doesn't post them again every poll.
+		err = bot.updateFeeds(guildID, func(feeds map[string]*Feed) error {
+			if current, ok := feeds[feed.Name]; ok {
+				for _, entry := range fresh {
+					current.Seen = append(current.Seen, entry.ID)
+				}
+				if len(current.Seen) > maxFeedSeen {
+					current.Seen = current.Seen[len(current.Seen)-maxFeedSeen:]
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			bot.ErrorHandler(bot, err)
+			continue
+		}
+		if len(fresh) > maxFeedPosts {
+			fresh = fresh[len(fresh)-maxFeedPosts:]
+		}
+		for _, entry := range fresh {
+			if _, err := bot.Session.ChannelMessageSendComplex(feed.ChannelID, bot.feedMessage(feed, title, entry)); err != nil {
+				bot.ErrorHandler(bot, err)
+				break
+			}
+		}
+	}
+}
+
+// newFeedEntries returns the entries that weren't seen yet, oldest first.
+func newFeedEntries(seen []string, entries []*FeedEntry) []*FeedEntry {
+	var fresh []*FeedEntry
+	for _, entry := range entries {
+		if !containsString(seen, entry.ID) {
+			fresh = append(fresh, entry)
+		}
+	}
+	// Feeds usually list the newest first, when every entry has a date they're sorted by it instead.
+	dated := true
+	for i, j := 0, len(fresh)-1; i < j; i, j = i+1, j-1 {
+		fresh[i], fresh[j] = fresh[j], fresh[i]
+	}
+	for _, entry := range fresh {
+		dated = dated && !entry.Published.IsZero()
+	}
+	if dated {
+		sort.SliceStable(fresh, func(i, j int) bool { return fresh[i].Published.Before(fresh[j].Published) })
+	}
+	return fresh
+}
+
+func (bot *Bot) feedMessage(feed *Feed, title string, entry *FeedEntry) *discordgo.MessageSend {
+	embed := NewEmbed().
+		SetTitle(entry.Title).
+		SetURL(entry.URL).
+		SetDescription(entry.Summary).
+		SetColor(bot.Color)
+	if title != "" {
+		embed.SetFooter(title)
+	}
+	embed.Truncate()
+	content := ""
+	if feed.Template != "" {
+		content = strings.NewReplacer("{title}", entry.Title, "{url}", entry.URL, "{feed}", title).Replace(feed.Template)
+	}
+	// The roles in the template can be pinged, mentions coming from entries can't.
+	mentions := &discordgo.MessageAllowedMentions{Parse: []discordgo.AllowedMentionType{}}
+	for _, match := range feedRoleRegex.FindAllStringSubmatch(feed.Template, -1) {
+		mentions.Roles = append(mentions.Roles, match[1])
+	}
+	return &discordgo.MessageSend{Content: content, Embed: embed.Build(), AllowedMentions: mentions}
+}
+
+// FetchFeed downloads an RSS or Atom feed and returns it's title and entries.
+func FetchFeed(url string) (string, []*FeedEntry, error) {
+	res, err := FeedClient.Get(url)
+	if err != nil {
+		return "", nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("fetching the feed failed with status %d", res.StatusCode)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(res.Body, maxFeedSize))
+	if err != nil {
+		return "", nil, err
+	}
+	return ParseFeed(data)
+}
+
+// ParseFeed parses an RSS 2.0 or Atom feed and returns it's title and entries.
+func ParseFeed(data []byte) (string, []*FeedEntry, error) {
+	var doc struct {
+		XMLName xml.Name
+		// RSS
+		Channel struct {
+			Title string `xml:"title"`
+			Items []struct {
+				Title       string `xml:"title"`
+				Link        string `xml:"link"`
+				GUID        string `xml:"guid"`
+				PubDate     string `xml:"pubDate"`
+				Description string `xml:"description"`
+			} `xml:"item"`
+		} `xml:"channel"`
+		// Atom
+		Title   string `xml:"title"`
+		Entries []struct {
+			Title string `xml:"title"`
+			Links []struct {
+				Href string `xml:"href,attr"`
+				Rel  string `xml:"rel,attr"`
+			} `xml:"link"`
+			ID        string `xml:"id"`
+			Published string `xml:"published"`
+			Updated   string `xml:"updated"`
+			Summary   string `xml:"summary"`
+			Content   string `xml:"content"`
+		} `xml:"entry"`
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	// Feeds are often served in other charsets, the text is mostly ASCII anyway.
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) { return input, nil }
+	if err := decoder.Decode(&doc); err != nil {
+		return "", nil, err
+	}
+
+	var entries []*FeedEntry
+	switch doc.XMLName.Local {
+	case "rss":
+		for _, item := range doc.Channel.Items {
+			entry := &FeedEntry{ID: item.GUID, Title: item.Title, URL: strings.TrimSpace(item.Link), Summary: feedText(item.Description)}
+			entry.Published, _ = time.Parse(time.RFC1123Z, strings.TrimSpace(item.PubDate))
+			if entry.Published.IsZero() {
+				entry.Published, _ = time.Parse(time.RFC1123, strings.TrimSpace(item.PubDate))
+			}
+			entries = append(entries, entry)
+		}
+		doc.Title = doc.Channel.Title
+	case "feed":
+		for _, item := range doc.Entries {
+			entry := &FeedEntry{ID: item.ID, Title: item.Title, Summary: feedText(item.Summary)}
+			if entry.Summary == "" {
+				entry.Summary = feedText(item.Content)
+			}
+			for _, link := range item.Links {
+				if link.Rel == "" || link.Rel == "alternate" {
+					entry.URL = link.Href
+					break
+				}
+			}
+			published := item.Published
+			if published == "" {
+				published = item.Updated
+			}
+			entry.Published, _ = time.Parse(time.RFC3339, strings.TrimSpace(published))
+			entries = append(entries, entry)
+		}
+	default:
+		return "", nil, fmt.Errorf("%s is not an RSS or Atom feed", doc.XMLName.Local)
+	}
+	for _, entry := range entries {
+		entry.ID = strings.TrimSpace(entry.ID)
+		if entry.ID == "" {
+			entry.ID = entry.URL
+		}
+		entry.Title = strings.TrimSpace(html.UnescapeString(entry.Title))
+	}
+	return strings.TrimSpace(doc.Title), entries, nil
+}
+
+// feedText turns the HTML of a summary into text.
+func feedText(s string) string {
+	s = htmlTagRegex.ReplaceAllString(s, "")
+	return strings.TrimSpace(html.UnescapeString(s))
+}
+
+func feedsCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+	action := strings.ToLower(ctx.ArgString(0))
+	if action == "" || action == "list" {
+		feeds, err := bot.Feeds(ctx.Guild.ID)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		if len(feeds) == 0 {
+			ctx.ReplyLocale("COMMAND_FEEDS_NONE", ctx.Prefix)
+			return
+		}
+		lines := make([]string, 0, len(feeds))
+		for _, feed := range feeds {
+			lines = append(lines, fmt.Sprintf("**%s** <%s> in <#%s>", feed.Name, feed.URL, feed.ChannelID))
+		}
+		sort.Strings(lines)
+		ctx.BuildEmbed(NewEmbed().
+			SetTitle(ctx.Locale.Get("COMMAND_FEEDS_TITLE")).
+			SetDescription(strings.Join(lines, "\n")).
+			SetColor(bot.Color))
+		return
+	}
+	if !ctx.HasPermissions(discordgo.PermissionManageServer) {
+		ctx.ReplyLocale("COMMAND_FEEDS_NO_PERMISSION")
+		return
+	}
+	name := strings.ToLower(ctx.ArgString(1))
+	if name == "" {
+		ctx.ReplyLocale("COMMAND_FEEDS_USAGE", ctx.Prefix)
+		return
+	}
+	feeds, err := bot.Feeds(ctx.Guild.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	feed, exists := feeds[name]
+	if !exists && action != "add" {
+		ctx.ReplyLocale("COMMAND_FEEDS_UNKNOWN", name)
+		return
+	}
+
+	switch action {
+	case "add":
+		url := strings.Trim(ctx.ArgString(2), "<>")
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			ctx.ReplyLocale("COMMAND_FEEDS_USAGE", ctx.Prefix)
+			return
+		}
+		added := &Feed{Name: name, URL: url, ChannelID: ctx.Channel.ID}
+		if exists {
+			added.Template = feed.Template
+		}
+		if err := bot.AddFeed(ctx.Guild.ID, added); err != nil {
+			ctx.ReplyLocale("COMMAND_FEEDS_INVALID", err.Error())
+			return
+		}
+		ctx.ReplyLocale("COMMAND_FEEDS_ADDED", name)
+	case "remove", "delete":
+		if err := bot.RemoveFeed(ctx.Guild.ID, name); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_FEEDS_REMOVED", name)
+	case "template":
+		template := ""
+		if len(ctx.ArgOffsets) > 2 {
+			template = strings.TrimSpace(ctx.RawContent[ctx.ArgOffsets[2]:])
+		}
+		err := bot.updateFeeds(ctx.Guild.ID, func(feeds map[string]*Feed) error {
+			if current, ok := feeds[feed.Name]; ok {
+				current.Template = template
+			}
+			return nil
+		})
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_FEEDS_TEMPLATE", name)
+	default:
+		ctx.ReplyLocale("COMMAND_FEEDS_USAGE", ctx.Prefix)
+	}
+}
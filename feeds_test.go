@@ -0,0 +1,37 @@
+package sapphire
+
+import (
+	"testing"
+)
+
+func TestParseFeed(t *testing.T) {
+	rss := `<?xml version="1.0" encoding="ISO-8859-1"?><rss version="2.0"><channel><title>Blog</title>
+<item><title>Second &amp; last</title><link>https://blog/2</link><guid>2</guid><pubDate>Tue, 02 Jan 2024 10:00:00 +0000</pubDate><description>&lt;p&gt;Hello &lt;b&gt;world&lt;/b&gt;&lt;/p&gt;</description></item>
+<item><title>First</title><link>https://blog/1</link><pubDate>Mon, 01 Jan 2024 10:00:00 +0000</pubDate></item>
+</channel></rss>`
+	title, entries, err := ParseFeed([]byte(rss))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "Blog" || len(entries) != 2 || entries[0].Title != "Second & last" || entries[0].Summary != "Hello world" || entries[1].ID != "https://blog/1" {
+		t.Errorf("Unexpected RSS %q %+v", title, entries)
+	}
+	fresh := newFeedEntries([]string{"3"}, entries)
+	if len(fresh) != 2 || fresh[0].ID != "https://blog/1" {
+		t.Errorf("new entries aren't oldest first: %+v", fresh)
+	}
+
+	atom := `<feed xmlns="http://www.w3.org/2005/Atom"><title>News</title>
+<entry><id>tag:a</id><title>A</title><link rel="self" href="https://news/self"/><link href="https://news/a"/><updated>2024-01-01T00:00:00Z</updated><summary>Sum</summary></entry>
+</feed>`
+	title, entries, err = ParseFeed([]byte(atom))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "News" || len(entries) != 1 || entries[0].URL != "https://news/a" || entries[0].Published.Year() != 2024 {
+		t.Errorf("Unexpected Atom %q %+v", title, entries)
+	}
+	if _, _, err := ParseFeed([]byte(`<html></html>`)); err == nil {
+		t.Error("expected an error for HTML")
+	}
+}
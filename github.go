@@ -0,0 +1,378 @@
+package sapphire
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// gitHubKey is the bot wide settings key GitHub subscriptions are stored under.
+const gitHubKey = "github"
+
+// GitHubEvents are the webhook events posted, subscriptions pick from these.
+var GitHubEvents = []string{"push", "pull_request", "issues", "release"}
+
+// Embed colors of GitHub events.
+const (
+	gitHubColorOpen   = 0x2cbe4e
+	gitHubColorClosed = 0xcb2431
+	gitHubColorMerged = 0x6f42c1
+	gitHubColorPush   = 0x0366d6
+)
+
+// gitHubMaxCommits is how many commits of a push are listed.
+const gitHubMaxCommits = 5
+
+// GitHubSubscription posts the events of a repository in a channel.
+type GitHubSubscription struct {
+	Repository string   `json:"repository"` // owner/name, lowercased.
+	GuildID    string   `json:"guild_id"`
+	ChannelID  string   `json:"channel_id"`
+	Events     []string `json:"events,omitempty"` // Events of GitHubEvents to post, all of them if empty.
+}
+
+// Wants checks if the subscription posts an event.
+func (s *GitHubSubscription) Wants(event string) bool {
+	return len(s.Events) == 0 || containsString(s.Events, event)
+}
+
+type gitHubTracker struct {
+	secret        string
+	subscriptions []*GitHubSubscription
+	lock          sync.Mutex
+}
+
+// EnableGitHub loads the github command for servers to get events of repositories posted, mount bot.GitHubHandler
+// where the repositories' webhooks point to. Secret is the webhook secret, deliveries not signed with it are rejected.
+// A repository can be posted in several channels and servers, it only needs a single webhook.
+func (bot *Bot) EnableGitHub(secret string) *Bot {
+	if bot.gitHub != nil {
+		return bot
+	}
+	bot.gitHub = &gitHubTracker{secret: secret}
+	if _, err := GetJSON(bot.Settings, "", gitHubKey, &bot.gitHub.subscriptions); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+	bot.AddCommand(NewCommand("github", "Moderation", gitHubCommand).
+		SetDescription("Posts events of GitHub repositories in this channel.").
+		SetUsage("[action:string] [repository:string] [events:string...]").
+		AddAliases("gh").
+		SetGuildOnly(true))
+	return bot
+}
+
+// AddGitHubSubscription posts the events of a repository in a channel, replacing the channel's subscription to it.
+func (bot *Bot) AddGitHubSubscription(sub *GitHubSubscription) error {
+	sub.Repository = strings.ToLower(sub.Repository)
+	bot.gitHub.lock.Lock()
+	defer bot.gitHub.lock.Unlock()
+	bot.removeGitHubSubscription(sub.ChannelID, sub.Repository)
+	bot.gitHub.subscriptions = append(bot.gitHub.subscriptions, sub)
+	return SetJSON(bot.Settings, "", gitHubKey, bot.gitHub.subscriptions)
+}
+
+// RemoveGitHubSubscription stops posting a repository in a channel, false if it wasn't posted there.
+func (bot *Bot) RemoveGitHubSubscription(channelID, repository string) (bool, error) {
+	bot.gitHub.lock.Lock()
+	defer bot.gitHub.lock.Unlock()
+	if !bot.removeGitHubSubscription(channelID, strings.ToLower(repository)) {
+		return false, nil
+	}
+	return true, SetJSON(bot.Settings, "", gitHubKey, bot.gitHub.subscriptions)
+}
+
+// removeGitHubSubscription removes a subscription, the caller must hold the lock.
+func (bot *Bot) removeGitHubSubscription(channelID, repository string) bool {
+	subs := bot.gitHub.subscriptions[:0]
+	removed := false
+	for _, sub := range bot.gitHub.subscriptions {
+		if sub.ChannelID == channelID && sub.Repository == repository {
+			removed = true
+			continue
+		}
+		subs = append(subs, sub)
+	}
+	bot.gitHub.subscriptions = subs
+	return removed
+}
+
+// GitHubSubscriptions returns the GitHub subscriptions of a guild.
+func (bot *Bot) GitHubSubscriptions(guildID string) []GitHubSubscription {
+	bot.gitHub.lock.Lock()
+	defer bot.gitHub.lock.Unlock()
+	var subs []GitHubSubscription
+	for _, sub := range bot.gitHub.subscriptions {
+		if sub.GuildID == guildID {
+			subs = append(subs, *sub)
+		}
+	}
+	return subs
+}
+
+// GitHubHandler returns the http handler receiving GitHub webhook deliveries, set the webhooks' content type to
+// application/json. Deliveries are verified with the secret given to EnableGitHub.
+func (bot *Bot) GitHubHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 25<<20))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if !verifyGitHubSignature(bot.gitHub.secret, r.Header.Get("X-Hub-Signature-256"), body) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		event := r.Header.Get("X-GitHub-Event")
+		if !containsString(GitHubEvents, event) {
+			// e.g the ping sent when the webhook is created.
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		var payload struct {
+			Repository struct {
+				FullName string `json:"full_name"`
+			} `json:"repository"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		// GitHub times out deliveries after 10 seconds, posting can take longer.
+		go bot.postGitHubEvent(strings.ToLower(payload.Repository.FullName), event, body)
+	})
+}
+
+// verifyGitHubSignature checks the X-Hub-Signature-256 header of a delivery.
+func verifyGitHubSignature(secret, signature string, body []byte) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// postGitHubEvent posts an event in every channel subscribed to the repository.
+func (bot *Bot) postGitHubEvent(repository, event string, payload []byte) {
+	var subs []GitHubSubscription
+	bot.gitHub.lock.Lock()
+	for _, sub := range bot.gitHub.subscriptions {
+		if sub.Repository == repository && sub.Wants(event) {
+			subs = append(subs, *sub)
+		}
+	}
+	bot.gitHub.lock.Unlock()
+
+	for _, sub := range subs {
+		embed, err := GitHubEmbed(bot.LocaleFor(sub.GuildID, sub.ChannelID), event, payload)
+		if err != nil {
+			bot.ErrorHandler(bot, err)
+			return
+		}
+		if embed == nil {
+			return
+		}
+		if _, err := bot.Session.ChannelMessageSendEmbed(sub.ChannelID, embed.Build()); err != nil {
+			bot.ErrorHandler(bot, err)
+		}
+	}
+}
+
+type gitHubUser struct {
+	Login     string `json:"login"`
+	AvatarURL string `json:"avatar_url"`
+	HTMLURL   string `json:"html_url"`
+}
+
+// GitHubEmbed formats a webhook event, it's nil for actions that aren't posted e.g labeling an issue.
+func GitHubEmbed(locale *Language, event string, payload []byte) (*Embed, error) {
+	var p struct {
+		Action     string     `json:"action"`
+		Sender     gitHubUser `json:"sender"`
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+		// push
+		Ref     string `json:"ref"`
+		Compare string `json:"compare"`
+		Deleted bool   `json:"deleted"`
+		Commits []struct {
+			ID      string `json:"id"`
+			Message string `json:"message"`
+			URL     string `json:"url"`
+			Author  struct {
+				Name string `json:"name"`
+			} `json:"author"`
+		} `json:"commits"`
+		// pull_request and issues
+		PullRequest *struct {
+			Number  int    `json:"number"`
+			Title   string `json:"title"`
+			Body    string `json:"body"`
+			HTMLURL string `json:"html_url"`
+			Merged  bool   `json:"merged"`
+		} `json:"pull_request"`
+		Issue *struct {
+			Number  int    `json:"number"`
+			Title   string `json:"title"`
+			Body    string `json:"body"`
+			HTMLURL string `json:"html_url"`
+		} `json:"issue"`
+		// release
+		Release *struct {
+			TagName string `json:"tag_name"`
+			Name    string `json:"name"`
+			Body    string `json:"body"`
+			HTMLURL string `json:"html_url"`
+		} `json:"release"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, err
+	}
+	repo := p.Repository.FullName
+	embed := NewEmbed().SetAuthor(p.Sender.Login, p.Sender.AvatarURL, p.Sender.HTMLURL)
+
+	switch {
+	case event == "push":
+		if p.Deleted || len(p.Commits) == 0 {
+			return nil, nil
+		}
+		branch := strings.TrimPrefix(p.Ref, "refs/heads/")
+		lines := make([]string, 0, gitHubMaxCommits+1)
+		for i, commit := range p.Commits {
+			if i == gitHubMaxCommits {
+				lines = append(lines, locale.Get("GITHUB_MORE_COMMITS", len(p.Commits)-gitHubMaxCommits))
+				break
+			}
+			message := strings.SplitN(commit.Message, "\n", 2)[0]
+			lines = append(lines, fmt.Sprintf("[`%s`](%s) %s - %s", commit.ID[:7], commit.URL, Escape(message), Escape(commit.Author.Name)))
+		}
+		embed.SetTitle(locale.Get("GITHUB_PUSH", repo, branch, len(p.Commits))).
+			SetURL(p.Compare).
+			SetDescription(strings.Join(lines, "\n")).
+			SetColor(gitHubColorPush)
+	case event == "pull_request" && p.PullRequest != nil:
+		pr := p.PullRequest
+		color := gitHubColorOpen
+		key := ""
+		switch {
+		case p.Action == "opened":
+			key = "GITHUB_PR_OPENED"
+		case p.Action == "reopened":
+			key = "GITHUB_PR_REOPENED"
+		case p.Action == "closed" && pr.Merged:
+			key, color = "GITHUB_PR_MERGED", gitHubColorMerged
+		case p.Action == "closed":
+			key, color = "GITHUB_PR_CLOSED", gitHubColorClosed
+		default:
+			return nil, nil
+		}
+		embed.SetTitle(locale.Get(key, repo, pr.Number, pr.Title)).SetURL(pr.HTMLURL).SetColor(color)
+		if p.Action == "opened" {
+			embed.SetDescription(pr.Body)
+		}
+	case event == "issues" && p.Issue != nil:
+		issue := p.Issue
+		color := gitHubColorOpen
+		key := ""
+		switch p.Action {
+		case "opened":
+			key = "GITHUB_ISSUE_OPENED"
+		case "reopened":
+			key = "GITHUB_ISSUE_REOPENED"
+		case "closed":
+			key, color = "GITHUB_ISSUE_CLOSED", gitHubColorClosed
+		default:
+			return nil, nil
+		}
+		embed.SetTitle(locale.Get(key, repo, issue.Number, issue.Title)).SetURL(issue.HTMLURL).SetColor(color)
+		if p.Action == "opened" {
+			embed.SetDescription(issue.Body)
+		}
+	case event == "release" && p.Release != nil && p.Action == "published":
+		name := p.Release.Name
+		if name == "" {
+			name = p.Release.TagName
+		}
+		embed.SetTitle(locale.Get("GITHUB_RELEASE", repo, name)).
+			SetURL(p.Release.HTMLURL).
+			SetDescription(p.Release.Body).
+			SetColor(gitHubColorMerged)
+	default:
+		return nil, nil
+	}
+	return embed.Truncate(), nil
+}
+
+func gitHubCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+	action := strings.ToLower(ctx.ArgString(0))
+	if action == "" || action == "list" {
+		subs := bot.GitHubSubscriptions(ctx.Guild.ID)
+		if len(subs) == 0 {
+			ctx.ReplyLocale("COMMAND_GITHUB_NONE", ctx.Prefix)
+			return
+		}
+		lines := make([]string, len(subs))
+		for i, sub := range subs {
+			events := "all"
+			if len(sub.Events) > 0 {
+				events = strings.Join(sub.Events, ", ")
+			}
+			lines[i] = fmt.Sprintf("**%s** in <#%s> (%s)", sub.Repository, sub.ChannelID, events)
+		}
+		sort.Strings(lines)
+		ctx.BuildEmbed(NewEmbed().
+			SetTitle(ctx.Locale.Get("COMMAND_GITHUB_TITLE")).
+			SetDescription(strings.Join(lines, "\n")).
+			SetColor(bot.Color))
+		return
+	}
+	if !ctx.HasPermissions(discordgo.PermissionManageServer) {
+		ctx.ReplyLocale("COMMAND_GITHUB_NO_PERMISSION")
+		return
+	}
+	repository := strings.TrimSuffix(strings.TrimPrefix(ctx.ArgString(1), "https://github.com/"), "/")
+	if strings.Count(repository, "/") != 1 {
+		ctx.ReplyLocale("COMMAND_GITHUB_USAGE", ctx.Prefix, strings.Join(GitHubEvents, ", "))
+		return
+	}
+
+	switch action {
+	case "add":
+		sub := &GitHubSubscription{Repository: repository, GuildID: ctx.Guild.ID, ChannelID: ctx.Channel.ID}
+		for _, event := range ctx.RawArgs[2:] {
+			event = strings.ToLower(event)
+			if !containsString(GitHubEvents, event) {
+				ctx.ReplyLocale("COMMAND_GITHUB_UNKNOWN_EVENT", event, strings.Join(GitHubEvents, ", "))
+				return
+			}
+			sub.Events = append(sub.Events, event)
+		}
+		if err := bot.AddGitHubSubscription(sub); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_GITHUB_ADDED", sub.Repository)
+	case "remove", "delete":
+		removed, err := bot.RemoveGitHubSubscription(ctx.Channel.ID, repository)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		if !removed {
+			ctx.ReplyLocale("COMMAND_GITHUB_NOT_FOUND", repository)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_GITHUB_REMOVED", repository)
+	default:
+		ctx.ReplyLocale("COMMAND_GITHUB_USAGE", ctx.Prefix, strings.Join(GitHubEvents, ", "))
+	}
+}
@@ -0,0 +1,35 @@
+package sapphire
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestGitHubEmbed(t *testing.T) {
+	push := `{"ref": "refs/heads/main", "compare": "https://github.com/a/b/compare", "repository": {"full_name": "a/b"}, "sender": {"login": "octocat"},
+"commits": [{"id": "0123456789abcdef", "message": "Fix it\n\nLong text", "url": "https://github.com/a/b/commit/0123456", "author": {"name": "Octo"}}]}`
+	embed, err := GitHubEmbed(English, "push", []byte(push))
+	if err != nil || embed == nil {
+		t.Fatal(embed, err)
+	}
+	if embed.Title != "[a/b:main] 1 new commits" || embed.Description != "[`0123456`](https://github.com/a/b/commit/0123456) Fix it - Octo" {
+		t.Errorf("Unexpected push embed %q %q", embed.Title, embed.Description)
+	}
+	merged := `{"action": "closed", "repository": {"full_name": "a/b"}, "pull_request": {"number": 7, "title": "Feature", "merged": true}}`
+	if embed, _ := GitHubEmbed(English, "pull_request", []byte(merged)); embed == nil || embed.Title != "[a/b] Pull request merged: #7 Feature" {
+		t.Errorf("Unexpected merged embed %+v", embed)
+	}
+	labeled := `{"action": "labeled", "repository": {"full_name": "a/b"}, "issue": {"number": 1}}`
+	if embed, _ := GitHubEmbed(English, "issues", []byte(labeled)); embed != nil {
+		t.Errorf("labeling was posted %+v", embed)
+	}
+
+	body := []byte(`{"zen": "Keep it logically awesome."}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	if !verifyGitHubSignature("secret", "sha256="+hex.EncodeToString(mac.Sum(nil)), body) || verifyGitHubSignature("other", "sha256="+hex.EncodeToString(mac.Sum(nil)), body) {
+		t.Error("signature verification failed")
+	}
+}
@@ -0,0 +1,191 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strings"
+)
+
+// Settings keys of the guild settings, see bot.LoadGuildSettings
+const (
+	prefixKey           = "prefix"
+	languageKey         = "language"
+	disabledCommandsKey = "commands.disabled"
+)
+
+// MaxPrefixLength is the maximum length of a guild's prefix.
+var MaxPrefixLength = 10
+
+// protectedCommands can't be disabled per guild, a server could lock itself out otherwise.
+var protectedCommands = []string{"setprefix", "setlanguage", "togglecommand", "help"}
+
+// LoadGuildSettings makes the prefix, language and disabled commands configurable per guild through the settings provider.
+// The prefix and locale handlers set before are the fallback for guilds that didn't choose one, so call this after them.
+// It loads the setprefix, setlanguage and togglecommand commands, they need Manage Server.
+func (bot *Bot) LoadGuildSettings() *Bot {
+	prefix := bot.Prefix
+	language := bot.Language
+	bot.Prefix = func(b *Bot, m *discordgo.Message, dm bool) string {
+		if !dm && m.GuildID != "" {
+			if value := b.guildSetting(m.GuildID, prefixKey); value != "" {
+				return value
+			}
+		}
+		return prefix(b, m, dm)
+	}
+	bot.Language = func(b *Bot, m *discordgo.Message, dm bool) string {
+		if !dm && m.GuildID != "" {
+			if value := b.guildSetting(m.GuildID, languageKey); value != "" {
+				return value
+			}
+		}
+		return language(b, m, dm)
+	}
+	bot.guildOverrides = true
+
+	bot.AddCommand(NewCommand("setprefix", "Settings", setPrefixCommand).
+		SetDescription("Changes the prefix of this server, leave it out to reset it.").
+		SetUsage("[prefix:string]").
+		SetRequiredPermissions(discordgo.PermissionManageServer).
+		SetGuildOnly(true))
+	bot.AddCommand(NewCommand("setlanguage", "Settings", setLanguageCommand).
+		SetDescription("Changes the language of this server, leave it out to see the languages.").
+		SetUsage("[language:string]").
+		AddAliases("setlang").
+		SetRequiredPermissions(discordgo.PermissionManageServer).
+		SetGuildOnly(true))
+	return bot.AddCommand(NewCommand("togglecommand", "Settings", toggleCommandCommand).
+		SetDescription("Disables or enables a command in this server.").
+		SetUsage("<command:string>").
+		SetRequiredPermissions(discordgo.PermissionManageServer).
+		SetGuildOnly(true))
+}
+
+// guildSetting reads a key of a guild, errors are reported and read as unset.
+func (bot *Bot) guildSetting(guildID, key string) string {
+	value, _, err := bot.Settings.Get(guildID, key)
+	if err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+	return value
+}
+
+// SetGuildPrefix sets the prefix of a guild, an empty prefix resets it to the default.
+func (bot *Bot) SetGuildPrefix(guildID, prefix string) error {
+	if prefix == "" {
+		return bot.Settings.Delete(guildID, prefixKey)
+	}
+	return bot.Settings.Set(guildID, prefixKey, prefix)
+}
+
+// SetGuildLanguage sets the language of a guild, an empty name resets it to the default.
+func (bot *Bot) SetGuildLanguage(guildID, name string) error {
+	if name == "" {
+		return bot.Settings.Delete(guildID, languageKey)
+	}
+	return bot.Settings.Set(guildID, languageKey, name)
+}
+
+// DisabledCommands returns the names of the commands disabled in a guild.
+func (bot *Bot) DisabledCommands(guildID string) []string {
+	var names []string
+	if _, err := GetJSON(bot.Settings, guildID, disabledCommandsKey, &names); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+	return names
+}
+
+// SetCommandDisabled disables or enables a command in a guild.
+func (bot *Bot) SetCommandDisabled(guildID, name string, disabled bool) error {
+	names := bot.DisabledCommands(guildID)
+	kept := names[:0]
+	for _, n := range names {
+		if n != name {
+			kept = append(kept, n)
+		}
+	}
+	if disabled {
+		kept = append(kept, name)
+	}
+	if len(kept) == 0 {
+		return bot.Settings.Delete(guildID, disabledCommandsKey)
+	}
+	return SetJSON(bot.Settings, guildID, disabledCommandsKey, kept)
+}
+
+// commandDisabledIn checks if a command is disabled in the guild, the bot owner isn't affected.
+func (bot *Bot) commandDisabledIn(ctx *CommandContext) bool {
+	if !bot.guildOverrides || ctx.Message.GuildID == "" || ctx.Author.ID == bot.OwnerID {
+		return false
+	}
+	return containsString(bot.DisabledCommands(ctx.Message.GuildID), ctx.Command.Name)
+}
+
+func setPrefixCommand(ctx *CommandContext) {
+	prefix := strings.TrimSpace(ctx.ArgString(0))
+	if len(prefix) > MaxPrefixLength {
+		ctx.ReplyLocale("COMMAND_SETPREFIX_TOO_LONG", MaxPrefixLength)
+		return
+	}
+	if err := ctx.Bot.SetGuildPrefix(ctx.Guild.ID, prefix); err != nil {
+		ctx.Error(err)
+		return
+	}
+	if prefix == "" {
+		ctx.ReplyLocale("COMMAND_SETPREFIX_RESET", ctx.Bot.Prefix(ctx.Bot, ctx.Message, false))
+		return
+	}
+	ctx.ReplyLocale("COMMAND_SETPREFIX_SUCCESS", prefix)
+}
+
+func setLanguageCommand(ctx *CommandContext) {
+	names := make([]string, 0, len(ctx.Bot.Languages))
+	for name := range ctx.Bot.Languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	name := strings.TrimSpace(ctx.ArgString(0))
+	if name == "" {
+		ctx.ReplyLocale("COMMAND_SETLANGUAGE_LIST", ctx.Locale.Name, strings.Join(names, ", "))
+		return
+	}
+	// Let "en-us" match "en-US".
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			name = n
+		}
+	}
+	lang, ok := ctx.Bot.Languages[name]
+	if !ok {
+		ctx.ReplyLocale("COMMAND_SETLANGUAGE_UNKNOWN", name, strings.Join(names, ", "))
+		return
+	}
+	if err := ctx.Bot.SetGuildLanguage(ctx.Guild.ID, name); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.Locale = lang
+	ctx.ReplyLocale("COMMAND_SETLANGUAGE_SUCCESS", name)
+}
+
+func toggleCommandCommand(ctx *CommandContext) {
+	command := ctx.Bot.GetCommand(ctx.Arg(0).AsString())
+	if command == nil {
+		ctx.ReplyLocale("COMMAND_NOT_FOUND", ctx.Arg(0).AsString())
+		return
+	}
+	if containsString(protectedCommands, command.Name) {
+		ctx.ReplyLocale("COMMAND_TOGGLE_PROTECTED", command.Name)
+		return
+	}
+	disabled := !containsString(ctx.Bot.DisabledCommands(ctx.Guild.ID), command.Name)
+	if err := ctx.Bot.SetCommandDisabled(ctx.Guild.ID, command.Name, disabled); err != nil {
+		ctx.Error(err)
+		return
+	}
+	if disabled {
+		ctx.ReplyLocale("COMMAND_TOGGLE_DISABLED", command.Name)
+		return
+	}
+	ctx.ReplyLocale("COMMAND_TOGGLE_ENABLED", command.Name)
+}
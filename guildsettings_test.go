@@ -0,0 +1,53 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"testing"
+)
+
+func TestGuildSettings(t *testing.T) {
+	bot := New(&discordgo.Session{}).SetPrefix("?").LoadGuildSettings()
+	msg := &discordgo.Message{GuildID: "g"}
+	if prefix := bot.Prefix(bot, msg, false); prefix != "?" {
+		t.Errorf("Expected the default prefix but got %s", prefix)
+	}
+	bot.SetGuildPrefix("g", "$")
+	bot.SetGuildLanguage("g", "de-DE")
+	if prefix := bot.Prefix(bot, msg, false); prefix != "$" {
+		t.Errorf("Expected the guild's prefix but got %s", prefix)
+	}
+	if prefix := bot.Prefix(bot, msg, true); prefix != "?" {
+		t.Errorf("Expected the default prefix in DMs but got %s", prefix)
+	}
+	if lang := bot.Language(bot, msg, false); lang != "de-DE" {
+		t.Errorf("Expected the guild's language but got %s", lang)
+	}
+	bot.SetGuildPrefix("g", "")
+	if prefix := bot.Prefix(bot, msg, false); prefix != "?" {
+		t.Errorf("Expected the prefix to be reset but got %s", prefix)
+	}
+
+	bot.SetCommandDisabled("g", "ping", true)
+	bot.SetCommandDisabled("g", "stats", true)
+	bot.SetCommandDisabled("g", "ping", false)
+	if disabled := bot.DisabledCommands("g"); len(disabled) != 1 || disabled[0] != "stats" {
+		t.Errorf("Unexpected disabled commands %v", disabled)
+	}
+}
+
+func TestSetLanguageTrimmed(t *testing.T) {
+	bot := New(&discordgo.Session{}).LoadGuildSettings()
+	recordREST(bot)
+	bot.AddLanguage(NewLanguage("de-DE"))
+	ctx := bot.interactionContext(&Interaction{ID: "i", ApplicationID: "a", Token: "tok", ChannelID: "c", GuildID: "g",
+		User: &discordgo.User{ID: "u"}})
+	ctx.Guild = &discordgo.Guild{ID: "g"}
+	ctx.RawContent = "setlanguage de-de \n"
+	ctx.RawArgs, ctx.ArgOffsets = []string{"de-de"}, []int{12}
+
+	setLanguageCommand(ctx)
+
+	if lang := bot.guildSetting("g", languageKey); lang != "de-DE" {
+		t.Errorf("Expected the language to be set despite the trailing whitespace but it's %q", lang)
+	}
+}
@@ -0,0 +1,120 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HealthCheck checks a dependency, e.g pings the database, a nil error means it's healthy.
+type HealthCheck func() error
+
+// DependencyStatus is the result of the last health check of a dependency.
+type DependencyStatus struct {
+	Name      string    `json:"name"`
+	Healthy   bool      `json:"healthy"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type dependency struct {
+	check    HealthCheck
+	interval time.Duration
+	status   DependencyStatus
+}
+
+type healthTracker struct {
+	// dependency name -> dependency
+	deps map[string]*dependency
+	lock sync.RWMutex
+}
+
+// AddDependency registers something commands depend on, check runs every interval in the background.
+// Commands declare it with cmd.AddDependencies and are blocked while it's unhealthy, dependencies are healthy until checked.
+func (bot *Bot) AddDependency(name string, interval time.Duration, check HealthCheck) *Bot {
+	bot.health.lock.Lock()
+	bot.health.deps[name] = &dependency{check: check, interval: interval, status: DependencyStatus{Name: name, Healthy: true}}
+	bot.health.lock.Unlock()
+	bot.Scheduler.After(0, func() {
+		bot.checkDependency(name)
+	})
+	return bot
+}
+
+// checkDependency runs the health check and schedules the next one.
+func (bot *Bot) checkDependency(name string) {
+	bot.health.lock.RLock()
+	dep, ok := bot.health.deps[name]
+	bot.health.lock.RUnlock()
+	if !ok {
+		return
+	}
+	// Schedule first so a panicking check doesn't stop the checks.
+	bot.Scheduler.After(dep.interval, func() {
+		bot.checkDependency(name)
+	})
+	err := dep.check()
+	status := DependencyStatus{Name: name, Healthy: err == nil, CheckedAt: time.Now()}
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	bot.health.lock.Lock()
+	was := dep.status.Healthy
+	dep.status = status
+	bot.health.lock.Unlock()
+	if was && !status.Healthy {
+		bot.Notify(NotifyError, "Dependency "+name+" is unhealthy", status.Error)
+	} else if !was && status.Healthy {
+		bot.Notify(NotifyInfo, "Dependency "+name+" is healthy again", "Commands using it are available again.")
+	}
+}
+
+// Health returns the status of every dependency sorted by name.
+func (bot *Bot) Health() []DependencyStatus {
+	bot.health.lock.RLock()
+	defer bot.health.lock.RUnlock()
+	statuses := make([]DependencyStatus, 0, len(bot.health.deps))
+	for _, dep := range bot.health.deps {
+		statuses = append(statuses, dep.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// unhealthyDependency returns the first of the names that is unhealthy, empty if all of them are healthy.
+func (bot *Bot) unhealthyDependency(names []string) string {
+	bot.health.lock.RLock()
+	defer bot.health.lock.RUnlock()
+	for _, name := range names {
+		if dep, ok := bot.health.deps[name]; ok && !dep.status.Healthy {
+			return name
+		}
+	}
+	return ""
+}
+
+// HealthHandler returns an http handler reporting the dependencies as JSON for uptime monitors and orchestrators.
+// It responds with 503 Service Unavailable if a dependency or the gateway connection is down, mount it where you like.
+func (bot *Bot) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deps := bot.Health()
+		healthy := bot.Session.DataReady
+		for _, dep := range deps {
+			healthy = healthy && dep.Healthy
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"healthy":      healthy,
+			"connected":    bot.Session.DataReady,
+			"dependencies": deps,
+		})
+	})
+}
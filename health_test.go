@@ -0,0 +1,24 @@
+package sapphire
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDependencies(t *testing.T) {
+	bot := &Bot{Scheduler: NewScheduler(nil), health: &healthTracker{deps: make(map[string]*dependency)}}
+	defer bot.Scheduler.Stop()
+	down := errors.New("connection refused")
+	bot.AddDependency("database", time.Hour, func() error { return down })
+	bot.AddDependency("api", time.Hour, func() error { return nil })
+
+	time.Sleep(20 * time.Millisecond)
+	if dep := bot.unhealthyDependency([]string{"api", "database"}); dep != "database" {
+		t.Errorf("Expected database to be unhealthy, got %q", dep)
+	}
+	health := bot.Health()
+	if len(health) != 2 || health[0].Name != "api" || !health[0].Healthy || health[1].Error != down.Error() {
+		t.Errorf("Unexpected health %+v", health)
+	}
+}
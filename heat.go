@@ -0,0 +1,211 @@
+package sapphire
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HeatConfig is the per-guild config of the automod heat engine, see bot.EnableHeat
+var HeatConfig = NewConfigSchema("heat", "Violations add heat to members that cools down over time, too much heat escalates.").
+	Add("enabled", ConfigBool, "false", "Whether violations add heat in this server.").
+	Add("decay", ConfigInt, "5", "How many points of heat members lose per minute.").
+	Add("thresholds", ConfigString, "30:warn 60:kick|alert 100:ban|alert", "Heat thresholds and their actions, e.g 30:warn 60:kick|alert").
+	SetValidator("thresholds", func(bot *Bot, guildID, value string) error {
+		_, err := ParseHeatThresholds(value)
+		return err
+	})
+
+// DefaultHeat is the heat added by a violation if neither it nor it's rule set any.
+const DefaultHeat = 10
+
+// automodActionNames are the names of actions in heat thresholds.
+var automodActionNames = map[string]AutomodAction{
+	"delete": AutomodDelete,
+	"warn":   AutomodWarn,
+	"alert":  AutomodAlert,
+	"kick":   AutomodKick,
+	"ban":    AutomodBan,
+}
+
+// HeatThreshold is reached when a member's heat gets to Heat.
+type HeatThreshold struct {
+	Heat    float64
+	Actions AutomodAction
+}
+
+// ParseHeatThresholds parses thresholds like "30:warn 60:kick|alert", sorted from the lowest.
+func ParseHeatThresholds(value string) ([]HeatThreshold, error) {
+	var thresholds []HeatThreshold
+	for _, part := range strings.Fields(value) {
+		split := strings.SplitN(part, ":", 2)
+		if len(split) != 2 {
+			return nil, fmt.Errorf("'%s' is not a threshold, use heat:actions e.g 60:kick|alert", part)
+		}
+		heat, err := strconv.ParseFloat(split[0], 64)
+		if err != nil || heat <= 0 {
+			return nil, fmt.Errorf("'%s' is not a positive number", split[0])
+		}
+		var actions AutomodAction
+		for _, name := range strings.Split(split[1], "|") {
+			action, ok := automodActionNames[strings.ToLower(name)]
+			if !ok {
+				return nil, fmt.Errorf("'%s' is not an action, use delete, warn, alert, kick or ban", name)
+			}
+			actions |= action
+		}
+		thresholds = append(thresholds, HeatThreshold{Heat: heat, Actions: actions})
+	}
+	sort.Slice(thresholds, func(i, j int) bool {
+		return thresholds[i].Heat < thresholds[j].Heat
+	})
+	return thresholds, nil
+}
+
+type memberHeat struct {
+	heat    float64
+	updated time.Time
+	reached float64 // The highest threshold reached, so each one escalates once until the member cools down.
+}
+
+type heatTracker struct {
+	// guild ID -> user ID -> heat
+	members map[string]map[string]*memberHeat
+	// rule name -> heat added by it's violations
+	weights map[string]float64
+	lock    sync.Mutex
+}
+
+// EnableHeat loads the heat engine on top of automod. Instead of acting on every violation on it's own each
+// violation adds heat to the member, which cools down over time, and reaching a threshold of HeatConfig escalates.
+// Violations add their Heat, the weight of their rule set with SetHeatWeight or DefaultHeat.
+func (bot *Bot) EnableHeat() *Bot {
+	if bot.heat != nil {
+		return bot
+	}
+	bot.heat = &heatTracker{members: make(map[string]map[string]*memberHeat), weights: make(map[string]float64)}
+	bot.AddConfigSchema(HeatConfig)
+	bot.OnViolation(func(ctx *AutomodContext, v *Violation) {
+		if v.Rule == "heat" {
+			return
+		}
+		heat := v.Heat
+		if heat == 0 {
+			heat = bot.heatWeight(v.Rule)
+		}
+		bot.AddHeat(ctx, heat)
+	})
+	return bot
+}
+
+// SetHeatWeight sets how much heat violations of a rule add.
+func (bot *Bot) SetHeatWeight(rule string, heat float64) *Bot {
+	bot.EnableHeat()
+	bot.heat.lock.Lock()
+	bot.heat.weights[rule] = heat
+	bot.heat.lock.Unlock()
+	return bot
+}
+
+func (bot *Bot) heatWeight(rule string) float64 {
+	bot.heat.lock.Lock()
+	defer bot.heat.lock.Unlock()
+	if heat, ok := bot.heat.weights[rule]; ok {
+		return heat
+	}
+	return DefaultHeat
+}
+
+// cool applies the decay since the last update, the caller must hold the lock.
+func (h *memberHeat) cool(decay float64, now time.Time) {
+	h.heat -= decay * now.Sub(h.updated).Minutes()
+	if h.heat < 0 {
+		h.heat = 0
+	}
+	h.updated = now
+}
+
+// Heat returns the current heat of a member.
+func (bot *Bot) Heat(guildID, userID string) float64 {
+	if bot.heat == nil {
+		return 0
+	}
+	decay := float64(HeatConfig.GetInt(bot, guildID, "decay"))
+	bot.heat.lock.Lock()
+	defer bot.heat.lock.Unlock()
+	h, ok := bot.heat.members[guildID][userID]
+	if !ok {
+		return 0
+	}
+	h.cool(decay, time.Now())
+	return h.heat
+}
+
+// Heat returns the current heat of the message's author, custom rules can use it to be stricter with hot members.
+func (ctx *AutomodContext) Heat() float64 {
+	return ctx.Bot.Heat(ctx.Guild.ID, ctx.Message.Author.ID)
+}
+
+// AddHeat adds heat to the message's author and escalates if a threshold is reached, custom rules can call it
+// to add heat without a violation e.g for every message to catch spam. It does nothing if the guild didn't enable heat.
+func (bot *Bot) AddHeat(ctx *AutomodContext, heat float64) {
+	guildID := ctx.Guild.ID
+	if bot.heat == nil || !HeatConfig.GetBool(bot, guildID, "enabled") {
+		return
+	}
+	thresholds, err := ParseHeatThresholds(HeatConfig.Get(bot, guildID, "thresholds"))
+	if err != nil {
+		bot.ErrorHandler(bot, err)
+		return
+	}
+	decay := float64(HeatConfig.GetInt(bot, guildID, "decay"))
+
+	bot.heat.lock.Lock()
+	members, ok := bot.heat.members[guildID]
+	if !ok {
+		members = make(map[string]*memberHeat)
+		bot.heat.members[guildID] = members
+	}
+	now := time.Now()
+	h, ok := members[ctx.Message.Author.ID]
+	if !ok {
+		h = &memberHeat{updated: now}
+		members[ctx.Message.Author.ID] = h
+	}
+	h.cool(decay, now)
+	if h.heat < h.reached {
+		// Cooled down below the last threshold, thresholds it's still over don't escalate again.
+		h.reached = 0
+		for _, threshold := range thresholds {
+			if h.heat >= threshold.Heat {
+				h.reached = threshold.Heat
+			}
+		}
+	}
+	h.heat += heat
+	var reached *HeatThreshold
+	for i, threshold := range thresholds {
+		if h.heat >= threshold.Heat && threshold.Heat > h.reached {
+			reached = &thresholds[i]
+		}
+	}
+	// Forget members who cooled down so the map doesn't grow forever.
+	for id, other := range members {
+		if other != h && other.heat-decay*now.Sub(other.updated).Minutes() <= 0 {
+			delete(members, id)
+		}
+	}
+	if reached != nil {
+		h.reached = reached.Heat
+	}
+	current := h.heat
+	bot.heat.lock.Unlock()
+
+	if reached != nil {
+		locale := bot.LocaleFor(guildID, ctx.Channel.ID)
+		bot.HandleViolation(ctx, &Violation{Rule: "heat", Reason: locale.Get("AUTOMOD_HEAT", int(current)), Actions: reached.Actions})
+	}
+}
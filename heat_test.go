@@ -0,0 +1,28 @@
+package sapphire
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHeatThresholds(t *testing.T) {
+	thresholds, err := ParseHeatThresholds("60:kick|ALERT 30:warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(thresholds) != 2 || thresholds[0].Heat != 30 || thresholds[1].Actions != AutomodKick|AutomodAlert {
+		t.Errorf("Unexpected thresholds %+v", thresholds)
+	}
+	for _, invalid := range []string{"30", "-5:warn", "30:mute"} {
+		if _, err := ParseHeatThresholds(invalid); err == nil {
+			t.Errorf("Expected %q to be invalid", invalid)
+		}
+	}
+
+	now := time.Now()
+	h := &memberHeat{heat: 50, updated: now.Add(-4 * time.Minute)}
+	h.cool(5, now)
+	if h.heat != 30 {
+		t.Errorf("Expected 30 heat after cooling 4 minutes, got %f", h.heat)
+	}
+}
@@ -0,0 +1,109 @@
+package sapphire
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"time"
+)
+
+// ErrStopIteration can be returned from an IterateMessages callback to stop early without an error.
+var ErrStopIteration = errors.New("stop iteration")
+
+// HistoryOptions are the options of IterateMessages, the zero value walks the whole history newest first.
+type HistoryOptions struct {
+	Oldest bool      // Walk from the oldest message to the newest instead. (default: false)
+	Before string    // Start before this message ID when walking newest first.
+	After  string    // Start after this message ID when walking oldest first.
+	Since  time.Time // Skip messages older than this. (default: no bound)
+	Until  time.Time // Skip messages newer than this. (default: no bound)
+	Limit  int       // Most messages to visit, 0 for no limit.
+	// Pause between fetching pages, on top of discordgo's rate-limit handling so long walks
+	// don't starve other requests. Negative disables it. (default: 250ms)
+	Delay time.Duration
+}
+
+// historyPageSize is the most messages Discord returns per request.
+const historyPageSize = 100
+
+// IterateMessages walks over the message history of a channel, fetching it page by page as needed.
+// fn is called for every message in order, returning an error stops and returns it unless it's ErrStopIteration.
+// The bot needs the Read Message History permission.
+func (bot *Bot) IterateMessages(channelID string, opts HistoryOptions, fn func(msg *discordgo.Message) error) error {
+	if opts.Delay == 0 {
+		opts.Delay = 250 * time.Millisecond
+	}
+	// Time bounds become the starting cursor, the other side is checked per message.
+	before, after := opts.Before, opts.After
+	if !opts.Oldest && before == "" && !opts.Until.IsZero() {
+		before = SnowflakeBefore(opts.Until.Add(time.Millisecond))
+	}
+	if opts.Oldest && after == "" {
+		after = "0"
+		if !opts.Since.IsZero() {
+			after = SnowflakeAfter(opts.Since)
+		}
+	}
+
+	visited := 0
+	for first := true; ; first = false {
+		if !first && opts.Delay > 0 {
+			time.Sleep(opts.Delay)
+		}
+		count := historyPageSize
+		if opts.Limit > 0 && opts.Limit-visited < count {
+			count = opts.Limit - visited
+		}
+		var page []*discordgo.Message
+		var err error
+		if opts.Oldest {
+			page, err = bot.Session.ChannelMessages(channelID, count, "", after, "")
+		} else {
+			page, err = bot.Session.ChannelMessages(channelID, count, before, "", "")
+		}
+		if err != nil {
+			return err
+		}
+		if len(page) == 0 {
+			return nil
+		}
+		// Pages always come newest first.
+		if opts.Oldest {
+			for i, j := 0, len(page)-1; i < j; i, j = i+1, j-1 {
+				page[i], page[j] = page[j], page[i]
+			}
+			after = page[len(page)-1].ID
+		} else {
+			before = page[len(page)-1].ID
+		}
+
+		for _, msg := range page {
+			created := SnowflakeTime(msg.ID)
+			if !opts.Since.IsZero() && created.Before(opts.Since) {
+				if opts.Oldest {
+					continue
+				}
+				// Everything after this is older too.
+				return nil
+			}
+			if !opts.Until.IsZero() && created.After(opts.Until) {
+				if opts.Oldest {
+					return nil
+				}
+				continue
+			}
+			if err := fn(msg); err != nil {
+				if err == ErrStopIteration {
+					return nil
+				}
+				return err
+			}
+			visited++
+			if opts.Limit > 0 && visited >= opts.Limit {
+				return nil
+			}
+		}
+		if len(page) < count {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package sapphire
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// idempotencyKeyPrefix is the bot wide settings key prefix performed operations are remembered under.
+const idempotencyKeyPrefix = "idempotency."
+
+// IdempotencyTTL is how long a performed operation is remembered, see bot.Idempotent
+var IdempotencyTTL = 24 * time.Hour
+
+// Idempotent runs fn once per key, e.g "ticket:<message ID>", so a redelivered event or an edited command doesn't
+// repeat side effects like granting rewards or creating tickets. Returns false without running fn if it already ran.
+// A failed fn isn't remembered so it can be retried. Keys are saved in the settings provider and serialized with
+// bot.Lock, so with a shared database and Locker it holds across processes and restarts for IdempotencyTTL.
+func (bot *Bot) Idempotent(key string, fn func() error) (bool, error) {
+	unlock, err := bot.Lock("idempotency:" + key)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	if ran, ok, err := bot.Settings.Get("", idempotencyKeyPrefix+key); err != nil {
+		return false, err
+	} else if ok && !idempotencyExpired(ran, time.Now()) {
+		return false, nil
+	}
+	if err := fn(); err != nil {
+		return true, err
+	}
+	if err := bot.Settings.Set("", idempotencyKeyPrefix+key, strconv.FormatInt(time.Now().Unix(), 10)); err != nil {
+		return true, err
+	}
+	bot.Scheduler.After(IdempotencyTTL, func() {
+		bot.Settings.Delete("", idempotencyKeyPrefix+key)
+	})
+	return true, nil
+}
+
+func idempotencyExpired(ran string, now time.Time) bool {
+	unix, err := strconv.ParseInt(ran, 10, 64)
+	return err != nil || now.Sub(time.Unix(unix, 0)) >= IdempotencyTTL
+}
+
+// sweepIdempotency deletes the expired keys left over from a previous run.
+func (bot *Bot) sweepIdempotency() {
+	it, ok := bot.Settings.(SettingsIterator)
+	if !ok {
+		return
+	}
+	keys, err := it.Keys("")
+	if err != nil {
+		bot.ErrorHandler(bot, err)
+		return
+	}
+	now := time.Now()
+	for _, key := range keys {
+		if !strings.HasPrefix(key, idempotencyKeyPrefix) {
+			continue
+		}
+		if ran, ok, err := bot.Settings.Get("", key); err == nil && ok && idempotencyExpired(ran, now) {
+			bot.Settings.Delete("", key)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"testing"
+)
+
+func TestIdempotent(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	runs := 0
+	fail := true
+	fn := func() error {
+		runs++
+		if fail {
+			return fmt.Errorf("failed")
+		}
+		return nil
+	}
+	if ran, err := bot.Idempotent("reward:1", fn); !ran || err == nil {
+		t.Errorf("Expected the first run to fail")
+	}
+	fail = false
+	if ran, err := bot.Idempotent("reward:1", fn); !ran || err != nil {
+		t.Errorf("Expected a failed run to be retried, got %v %v", ran, err)
+	}
+	if ran, _ := bot.Idempotent("reward:1", fn); ran || runs != 2 {
+		t.Errorf("Expected the key to only run once, ran %d times", runs)
+	}
+	if ran, _ := bot.Idempotent("reward:2", fn); !ran {
+		t.Errorf("Expected another key to run")
+	}
+}
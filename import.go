@@ -0,0 +1,445 @@
+package sapphire
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxImportSize is the maximum size of a file imported with the import command.
+const MaxImportSize = 8 * 1024 * 1024
+
+// ImportResult is what an import changed, Errors explains the skipped entries.
+type ImportResult struct {
+	Imported int
+	Skipped  int
+	Errors   []string
+}
+
+func (r *ImportResult) skip(format string, args ...interface{}) {
+	r.Skipped++
+	r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+}
+
+// Importer converts an export of another bot into sapphire's stores, for a guild.
+type Importer interface {
+	Import(bot *Bot, guildID string, data []byte) (*ImportResult, error)
+}
+
+// ImporterFunc is a function implementing Importer
+type ImporterFunc func(bot *Bot, guildID string, data []byte) (*ImportResult, error)
+
+// Import implements Importer
+func (fn ImporterFunc) Import(bot *Bot, guildID string, data []byte) (*ImportResult, error) {
+	return fn(bot, guildID, data)
+}
+
+// ImportRecords parses the usual export formats into records of lowercased field names to values:
+// a JSON array of objects or strings, a JSON object holding such an array e.g {"words": [...]},
+// a JSON object of names to values, CSV with a header row or plain text with one value per line.
+// Plain values are put in the "value" field and object entries in "name" and "value".
+func ImportRecords(data []byte) ([]map[string]string, error) {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if len(data) == 0 {
+		return nil, nil
+	}
+	switch data[0] {
+	case '[':
+		var items []interface{}
+		if err := decodeImport(data, &items); err != nil {
+			return nil, err
+		}
+		return jsonRecords(items), nil
+	case '{':
+		var object map[string]interface{}
+		if err := decodeImport(data, &object); err != nil {
+			return nil, err
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if items, ok := object[key].([]interface{}); ok {
+				return jsonRecords(items), nil
+			}
+		}
+		records := make([]map[string]string, 0, len(keys))
+		for _, key := range keys {
+			records = append(records, map[string]string{"name": key, "value": importString(object[key])})
+		}
+		return records, nil
+	}
+
+	lines := strings.Split(string(data), "\n")
+	if !strings.Contains(lines[0], ",") {
+		var records []map[string]string
+		for _, line := range lines {
+			if line = strings.TrimSpace(line); line != "" {
+				records = append(records, map[string]string{"value": line})
+			}
+		}
+		return records, nil
+	}
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	header := rows[0]
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	records := make([]map[string]string, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		record := make(map[string]string)
+		for i, value := range row {
+			if i < len(header) {
+				record[header[i]] = value
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// decodeImport decodes JSON keeping numbers as they are written, IDs don't fit in a float64.
+func decodeImport(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+func jsonRecords(items []interface{}) []map[string]string {
+	records := make([]map[string]string, 0, len(items))
+	for _, item := range items {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			records = append(records, map[string]string{"value": importString(item)})
+			continue
+		}
+		record := make(map[string]string)
+		for key, value := range object {
+			record[strings.ToLower(key)] = importString(value)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// importString turns a JSON value into a string, numbers like IDs are kept as they are.
+func importString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
+
+// importField returns the first non empty field of the record out of names other bots use for the same thing.
+func importField(record map[string]string, names ...string) string {
+	for _, name := range names {
+		if value := strings.TrimSpace(record[name]); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// WordListImporter imports banned words into the word filter, see bot.EnableWordFilter
+var WordListImporter = ImporterFunc(func(bot *Bot, guildID string, data []byte) (*ImportResult, error) {
+	records, err := ImportRecords(data)
+	if err != nil {
+		return nil, err
+	}
+	result := &ImportResult{}
+	words := bot.FilteredWords(guildID)
+	existing := make(map[string]bool)
+	for _, word := range words {
+		existing[word] = true
+	}
+	for _, record := range records {
+		word := strings.ToLower(importField(record, "value", "word", "trigger", "keyword", "phrase", "name"))
+		if word == "" {
+			result.skip("an entry has no word")
+			continue
+		}
+		if existing[word] {
+			result.Skipped++
+			continue
+		}
+		existing[word] = true
+		words = append(words, word)
+		result.Imported++
+	}
+	return result, bot.SetFilteredWords(guildID, words)
+})
+
+// SnippetImporter imports saved replies into the modmail snippets, see bot.EnableModmail
+// Snippets are shared by the staff guild so guildID must be it.
+var SnippetImporter = ImporterFunc(func(bot *Bot, guildID string, data []byte) (*ImportResult, error) {
+	if bot.modmail == nil || bot.modmail.guildID != guildID {
+		return nil, fmt.Errorf("snippets can only be imported in the modmail staff server")
+	}
+	records, err := ImportRecords(data)
+	if err != nil {
+		return nil, err
+	}
+	result := &ImportResult{}
+	snippets := bot.ModmailSnippets()
+	for _, record := range records {
+		name := strings.ToLower(importField(record, "name", "trigger", "keyword", "command"))
+		content := importField(record, "content", "value", "response", "text", "reply")
+		if name == "" || content == "" || strings.Contains(name, " ") {
+			result.skip("%q needs a name without spaces and content", name)
+			continue
+		}
+		snippets[name] = content
+		result.Imported++
+	}
+	return result, SetJSON(bot.Settings, guildID, modmailSnippetsKey, snippets)
+})
+
+// WarningImporter imports the warn history of other bots into the members' warnings, see bot.Warnings
+// Each record is a warning with the member's ID, the moderator's ID, the reason and the date, under the field names
+// bots commonly use e.g user_id, moderator, reason and created_at. Dates can be unix timestamps or dates like
+// 2021-01-31 15:04, warnings without one are dated to the import. Warnings already in the history are skipped.
+var WarningImporter = ImporterFunc(func(bot *Bot, guildID string, data []byte) (*ImportResult, error) {
+	records, err := ImportRecords(data)
+	if err != nil {
+		return nil, err
+	}
+	warnings := make(map[string][]*Warning)
+	var users []string
+	result := &ImportResult{}
+	for _, record := range records {
+		userID := importID(importField(record, "user_id", "userid", "member_id", "target_id", "user", "member", "target"))
+		if userID == "" {
+			result.skip("a warning has no member ID")
+			continue
+		}
+		warning := &Warning{
+			ModeratorID: importID(importField(record, "moderator_id", "mod_id", "moderator", "mod", "issuer", "author_id", "author")),
+			Reason:      importField(record, "reason", "description", "note", "value"),
+			CreatedAt:   importTime(importField(record, "created_at", "date", "timestamp", "time", "created")),
+		}
+		if _, ok := warnings[userID]; !ok {
+			users = append(users, userID)
+		}
+		warnings[userID] = append(warnings[userID], warning)
+	}
+	return result, importWarnings(bot, guildID, users, warnings, result)
+})
+
+// importWarnings adds the warnings of each member to their history, counting what was added and skipped.
+func importWarnings(bot *Bot, guildID string, users []string, warnings map[string][]*Warning, result *ImportResult) error {
+	for _, userID := range users {
+		added, err := bot.addWarnings(guildID, userID, warnings[userID])
+		if err != nil {
+			return err
+		}
+		result.Imported += added
+		result.Skipped += len(warnings[userID]) - added
+	}
+	return nil
+}
+
+// RedWarningsImporter imports the warnings of Red-DiscordBot's Warnings cog, the export is the cog's settings.json
+// of the JSON storage backend. Only the warnings of the guild being imported are taken, their points are left out.
+var RedWarningsImporter = ImporterFunc(func(bot *Bot, guildID string, data []byte) (*ImportResult, error) {
+	// Config groups are under the cog's identifier, members under the guild and user IDs.
+	var export map[string]struct {
+		Members map[string]map[string]struct {
+			Warnings map[string]struct {
+				Description string      `json:"description"`
+				Mod         json.Number `json:"mod"`
+			} `json:"warnings"`
+		} `json:"MEMBER"`
+	}
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, err
+	}
+	warnings := make(map[string][]*Warning)
+	var users []string
+	for _, group := range export {
+		for userID, member := range group.Members[guildID] {
+			// The keys are the unix timestamps of the warn commands.
+			keys := make([]string, 0, len(member.Warnings))
+			for key := range member.Warnings {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				warning := member.Warnings[key]
+				if _, ok := warnings[userID]; !ok {
+					users = append(users, userID)
+				}
+				warnings[userID] = append(warnings[userID], &Warning{
+					ModeratorID: warning.Mod.String(),
+					Reason:      warning.Description,
+					CreatedAt:   importTime(key),
+				})
+			}
+		}
+	}
+	if len(users) == 0 {
+		return nil, fmt.Errorf("the export has no warnings of this server")
+	}
+	sort.Strings(users)
+	result := &ImportResult{}
+	return result, importWarnings(bot, guildID, users, warnings, result)
+})
+
+// importID returns the user ID in a mention or ID, "" if there is none.
+func importID(value string) string {
+	value = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(value, "<@"), "!"), ">")
+	if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+		return ""
+	}
+	return value
+}
+
+// importTime parses the dates of exports, unix timestamps in seconds or milliseconds and dates in UTC.
+// Dates that can't be parsed are the time of the import.
+func importTime(value string) time.Time {
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		if n > 1e11 {
+			n /= 1000
+		}
+		sec, frac := math.Modf(n)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC().Truncate(time.Millisecond)
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t
+	}
+	if t, err := ParseTimeIn(value, time.UTC, time.Now()); err == nil {
+		return t
+	}
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// ConfigImporter imports the settings of another bot into config schemas, see bot.AddConfigSchema
+// The export is a JSON object, nested objects are flattened with dots e.g {"logs": {"channel": "1"}} is "logs.channel".
+// Values are normalized and validated like the config command does, so channel and role names work too.
+type ConfigImporter struct {
+	Mapping map[string]string // Keys of the export to "schema.key" e.g "logs.channel": "automod.log"
+}
+
+// Import implements Importer
+func (c *ConfigImporter) Import(bot *Bot, guildID string, data []byte) (*ImportResult, error) {
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	flattenImport("", object, values)
+	result := &ImportResult{}
+	keys := make([]string, 0, len(c.Mapping))
+	for key := range c.Mapping {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, from := range keys {
+		value, ok := values[from]
+		if !ok {
+			continue
+		}
+		target := strings.SplitN(c.Mapping[from], ".", 2)
+		schema := bot.ConfigSchemas[target[0]]
+		if schema == nil || len(target) != 2 {
+			result.skip("%s: %s is not a config key", from, c.Mapping[from])
+			continue
+		}
+		if _, err := schema.Set(bot, guildID, target[1], value); err != nil {
+			result.skip("%s: %v", from, err)
+			continue
+		}
+		result.Imported++
+	}
+	return result, nil
+}
+
+func flattenImport(prefix string, object map[string]interface{}, values map[string]string) {
+	for key, value := range object {
+		if nested, ok := value.(map[string]interface{}); ok {
+			flattenImport(prefix+key+".", nested, values)
+			continue
+		}
+		values[prefix+key] = importString(value)
+	}
+}
+
+// AddImporter adds an importer to the import command under name, e.g a ConfigImporter for the exports of another bot.
+func (bot *Bot) AddImporter(name string, importer Importer) *Bot {
+	if bot.importers == nil {
+		bot.importers = make(map[string]Importer)
+	}
+	bot.importers[strings.ToLower(name)] = importer
+	return bot
+}
+
+// LoadImportCommands loads the import command with the words, snippets, warnings and redwarnings importers,
+// add more with bot.AddImporter
+func (bot *Bot) LoadImportCommands() *Bot {
+	bot.AddImporter("words", WordListImporter)
+	bot.AddImporter("snippets", SnippetImporter)
+	bot.AddImporter("warnings", WarningImporter)
+	bot.AddImporter("redwarnings", RedWarningsImporter)
+	return bot.AddCommand(NewCommand("import", "Settings", importCommand).
+		SetDescription("Imports an export of another bot, attach the file.").
+		SetUsage("[type:string]").
+		SetRequiredPermissions(discordgo.PermissionAdministrator).
+		SetGuildOnly(true))
+}
+
+func importCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+	names := make([]string, 0, len(bot.importers))
+	for name := range bot.importers {
+		names = append(names, "`"+name+"`")
+	}
+	sort.Strings(names)
+	importer, ok := bot.importers[strings.ToLower(ctx.ArgString(0))]
+	if !ok {
+		ctx.ReplyLocale("COMMAND_IMPORT_USAGE", ctx.Prefix, strings.Join(names, ", "))
+		return
+	}
+	attachments := ctx.Message.Attachments
+	if len(attachments) == 0 {
+		if ref, err := ctx.ReferencedMessage(); err == nil && ref != nil {
+			attachments = ref.Attachments
+		}
+	}
+	if len(attachments) == 0 {
+		ctx.ReplyLocale("COMMAND_IMPORT_NO_FILE")
+		return
+	}
+	data, err := downloadAttachment(attachments[0], MaxImportSize)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	result, err := importer.Import(bot, ctx.Guild.ID, data)
+	if err != nil {
+		ctx.ReplyLocale("COMMAND_IMPORT_FAILED", Escape(err.Error()))
+		return
+	}
+	errors := result.Errors
+	if len(errors) > 10 {
+		errors = append(errors[:10], "…")
+	}
+	ctx.ReplyLocale("COMMAND_IMPORT_DONE", result.Imported, result.Skipped, Escape(strings.Join(errors, "\n")))
+}
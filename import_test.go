@@ -0,0 +1,96 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"testing"
+	"time"
+)
+
+func TestImport(t *testing.T) {
+	for name, data := range map[string]string{
+		"json array":  `["bad", "worse"]`,
+		"json object": `{"banned_words": [{"word": "bad"}, {"word": "worse"}]}`,
+		"csv":         "word,added_by\nbad,1\nworse,2\n",
+		"text":        "bad\r\nworse\n\n",
+	} {
+		records, err := ImportRecords([]byte(data))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if len(records) != 2 || importField(records[1], "value", "word") != "worse" {
+			t.Errorf("%s: unexpected records %v", name, records)
+		}
+	}
+
+	bot := New(&discordgo.Session{})
+	bot.AddConfigSchema(AutomodConfig)
+	importer := &ConfigImporter{Mapping: map[string]string{"automod.on": "automod.enabled", "domains": "automod.allowlist", "x": "nope.key"}}
+	result, err := importer.Import(bot, "1", []byte(`{"automod": {"on": "yes"}, "domains": "example.com", "x": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 2 || result.Skipped != 1 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if !AutomodConfig.GetBool(bot, "1", "enabled") || AutomodConfig.Get(bot, "1", "allowlist") != "example.com" {
+		t.Errorf("Expected the config to be imported")
+	}
+}
+
+func TestWarningImporters(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	bot.SetSettingsProvider(NewMemorySettings())
+	if _, err := bot.Warn("1", "42", "7", "spam"); err != nil {
+		t.Fatal(err)
+	}
+	csv := "user_id,moderator,reason,created_at\n" +
+		"42,<@9>,swearing,1612345678\n" +
+		"<@!43>,9,raid,2021-02-01 10:00\n" +
+		",9,no user,1612345678\n"
+	result, err := WarningImporter(bot, "1", []byte(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 2 || result.Skipped != 1 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	warnings, _ := bot.Warnings("1", "42")
+	if len(warnings) != 2 || warnings[0].Reason != "swearing" || warnings[0].ModeratorID != "9" || warnings[1].Reason != "spam" {
+		t.Errorf("Expected the imported warning before the given one but got %+v", warnings)
+	}
+	if result, _ = WarningImporter(bot, "1", []byte(csv)); result.Imported != 0 || result.Skipped != 3 {
+		t.Errorf("Expected importing again to skip the warnings but got %+v", result)
+	}
+
+	red := `{"5757575755": {"MEMBER": {
+		"1": {"43": {"total_points": 2, "warnings": {
+			"1612345678.5": {"points": 1, "description": "caps", "mod": 123456789012345678},
+			"1612000000.25": {"points": 1, "description": "links", "mod": 123456789012345678}}}},
+		"2": {"44": {"warnings": {"1612345678.5": {"points": 1, "description": "other server", "mod": 1}}}}}}}`
+	if result, err = RedWarningsImporter(bot, "1", []byte(red)); err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 2 || result.Skipped != 0 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	warnings, _ = bot.Warnings("1", "43")
+	if len(warnings) != 3 || warnings[0].Reason != "links" || warnings[1].Reason != "raid" || warnings[2].ModeratorID != "123456789012345678" {
+		t.Errorf("Expected Red's warnings in order with their moderator but got %+v", warnings)
+	}
+	if !warnings[2].CreatedAt.Equal(time.Unix(1612345678, 5e8)) {
+		t.Errorf("Expected the warning dated by its key but got %v", warnings[2].CreatedAt)
+	}
+	if other, _ := bot.Warnings("2", "44"); len(other) != 0 {
+		t.Errorf("Expected the warnings of other servers to be left out but got %+v", other)
+	}
+	if result, err = WarningImporter(bot, "1", []byte(`[{"member": 123456789012345679, "mod": 9, "note": "ads", "timestamp": 1612345678901}]`)); err != nil {
+		t.Fatal(err)
+	}
+	if warnings, _ = bot.Warnings("1", "123456789012345679"); result.Imported != 1 || len(warnings) != 1 || warnings[0].CreatedAt.Unix() != 1612345678 {
+		t.Errorf("Expected the JSON warning with its exact ID and date but got %+v", warnings)
+	}
+	if _, err = RedWarningsImporter(bot, "3", []byte(red)); err == nil {
+		t.Errorf("Expected an error for an export without warnings of the server")
+	}
+}
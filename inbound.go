@@ -0,0 +1,282 @@
+package sapphire
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// inboundKey is the bot wide settings key inbound routes are stored under.
+const inboundKey = "inbound"
+
+// maxInboundBody is the maximum size of a JSON body posted to a route in bytes.
+const maxInboundBody = 1 << 20
+
+// InboundFields are the templates a route can set.
+var InboundFields = []string{"content", "title", "description", "url", "color", "footer", "image", "thumbnail"}
+
+// InboundRoute renders JSON posted to it into a message in a channel. The templates use Go's text/template syntax
+// with the posted JSON as data e.g "{{.alert.name}} is {{.status}}", an embed is added when title or description is set.
+type InboundRoute struct {
+	Name      string            `json:"name"`
+	Token     string            `json:"token"` // Sent as "Authorization: Bearer <token>" or ?token=<token>
+	ChannelID string            `json:"channel_id"`
+	Templates map[string]string `json:"templates"` // field of InboundFields -> template
+}
+
+// Render executes the route's templates with data and builds the message, mentions are never parsed.
+func (r *InboundRoute) Render(data interface{}) (*discordgo.MessageSend, error) {
+	fields := make(map[string]string, len(r.Templates))
+	for _, field := range InboundFields {
+		text, ok := r.Templates[field]
+		if !ok {
+			continue
+		}
+		tmpl, err := template.New(field).Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		// Missing keys of JSON objects render as <no value>, they should just be empty.
+		fields[field] = strings.TrimSpace(strings.Replace(buf.String(), "<no value>", "", -1))
+	}
+
+	msg := &discordgo.MessageSend{Content: fields["content"], AllowedMentions: &discordgo.MessageAllowedMentions{Parse: []discordgo.AllowedMentionType{}}}
+	if len(msg.Content) > 2000 {
+		msg.Content = msg.Content[:1997] + "..."
+	}
+	if fields["title"] != "" || fields["description"] != "" {
+		embed := NewEmbed().
+			SetTitle(fields["title"]).
+			SetDescription(fields["description"]).
+			SetURL(fields["url"])
+		if fields["footer"] != "" {
+			embed.SetFooter(fields["footer"])
+		}
+		if fields["image"] != "" {
+			embed.SetImage(fields["image"])
+		}
+		if fields["thumbnail"] != "" {
+			embed.SetThumbnail(fields["thumbnail"])
+		}
+		if color := strings.TrimPrefix(fields["color"], "#"); color != "" {
+			if value, err := strconv.ParseInt(color, 16, 32); err == nil {
+				embed.SetColor(int(value))
+			}
+		}
+		msg.Embed = embed.Truncate().Build()
+	}
+	if msg.Content == "" && msg.Embed == nil {
+		return nil, fmt.Errorf("the route %s rendered an empty message", r.Name)
+	}
+	return msg, nil
+}
+
+type inboundTracker struct {
+	routes map[string]*InboundRoute
+	lock   sync.RWMutex
+}
+
+// EnableInboundWebhooks loads the inbound command to manage routes, mount bot.InboundHandler to receive the JSON.
+// Routes are managed by the bot owner since they're given to external systems.
+func (bot *Bot) EnableInboundWebhooks() *Bot {
+	if bot.inbound != nil {
+		return bot
+	}
+	bot.inbound = &inboundTracker{routes: make(map[string]*InboundRoute)}
+	if _, err := GetJSON(bot.Settings, "", inboundKey, &bot.inbound.routes); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+	bot.AddCommand(NewCommand("inbound", "Owner", inboundCommand).
+		SetDescription("Manages routes posting JSON from external systems as messages.").
+		SetUsage("<action:string> [name:string] [args:string...]").
+		SetOwnerOnly(true))
+	return bot
+}
+
+// AddInboundRoute creates or replaces a route, a new token is generated if it has none.
+func (bot *Bot) AddInboundRoute(route *InboundRoute) error {
+	if route.Token == "" {
+		token := make([]byte, 24)
+		if _, err := rand.Read(token); err != nil {
+			return err
+		}
+		route.Token = hex.EncodeToString(token)
+	}
+	if route.Templates == nil {
+		route.Templates = make(map[string]string)
+	}
+	bot.inbound.lock.Lock()
+	defer bot.inbound.lock.Unlock()
+	bot.inbound.routes[route.Name] = route
+	return SetJSON(bot.Settings, "", inboundKey, bot.inbound.routes)
+}
+
+// RemoveInboundRoute deletes a route.
+func (bot *Bot) RemoveInboundRoute(name string) error {
+	bot.inbound.lock.Lock()
+	defer bot.inbound.lock.Unlock()
+	delete(bot.inbound.routes, name)
+	return SetJSON(bot.Settings, "", inboundKey, bot.inbound.routes)
+}
+
+// InboundRoute returns a copy of a route by name, nil if it doesn't exist.
+func (bot *Bot) InboundRoute(name string) *InboundRoute {
+	bot.inbound.lock.RLock()
+	defer bot.inbound.lock.RUnlock()
+	route, ok := bot.inbound.routes[name]
+	if !ok {
+		return nil
+	}
+	copied := *route
+	copied.Templates = make(map[string]string, len(route.Templates))
+	for k, v := range route.Templates {
+		copied.Templates[k] = v
+	}
+	return &copied
+}
+
+// InboundHandler returns the http handler receiving JSON for the routes, the last path segment is the route's name
+// so mount it with a trailing slash e.g http.Handle("/inbound/", bot.InboundHandler())
+// It responds 401 for a wrong token, 400 for invalid JSON or templates failing and 502 if Discord rejected the message.
+func (bot *Bot) InboundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		name := r.URL.Path[strings.LastIndexByte(r.URL.Path, '/')+1:]
+		route := bot.InboundRoute(name)
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" {
+			token = r.URL.Query().Get("token")
+		}
+		// Unknown routes look like wrong tokens to not reveal which routes exist.
+		if route == nil || subtle.ConstantTimeCompare([]byte(token), []byte(route.Token)) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxInboundBody))
+		if err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		var data interface{}
+		if err := json.Unmarshal(body, &data); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		msg, err := route.Render(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := bot.Session.ChannelMessageSendComplex(route.ChannelID, msg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func inboundCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+	action := strings.ToLower(ctx.Arg(0).AsString())
+	name := strings.ToLower(ctx.ArgString(1))
+	if action == "list" {
+		bot.inbound.lock.RLock()
+		lines := make([]string, 0, len(bot.inbound.routes))
+		for _, route := range bot.inbound.routes {
+			lines = append(lines, fmt.Sprintf("**%s** in <#%s>", route.Name, route.ChannelID))
+		}
+		bot.inbound.lock.RUnlock()
+		if len(lines) == 0 {
+			ctx.ReplyLocale("COMMAND_INBOUND_NONE")
+			return
+		}
+		sort.Strings(lines)
+		ctx.Reply(strings.Join(lines, "\n"))
+		return
+	}
+	if name == "" {
+		ctx.ReplyLocale("COMMAND_INBOUND_USAGE", ctx.Prefix, strings.Join(InboundFields, ", "))
+		return
+	}
+	route := bot.InboundRoute(name)
+	if route == nil && action != "create" {
+		ctx.ReplyLocale("COMMAND_INBOUND_UNKNOWN", name)
+		return
+	}
+
+	switch action {
+	case "create":
+		if route != nil {
+			ctx.ReplyLocale("COMMAND_INBOUND_EXISTS", name)
+			return
+		}
+		route = &InboundRoute{Name: name, ChannelID: ctx.Channel.ID, Templates: map[string]string{"content": "{{.}}"}}
+	case "token":
+		route.Token = ""
+	case "delete":
+		if err := bot.RemoveInboundRoute(name); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_INBOUND_DELETED", name)
+		return
+	case "template":
+		field := strings.ToLower(ctx.ArgString(2))
+		if !containsString(InboundFields, field) {
+			ctx.ReplyLocale("COMMAND_INBOUND_USAGE", ctx.Prefix, strings.Join(InboundFields, ", "))
+			return
+		}
+		text := ""
+		if len(ctx.ArgOffsets) > 3 {
+			text = strings.TrimSpace(ctx.RawContent[ctx.ArgOffsets[3]:])
+		}
+		if text == "" {
+			delete(route.Templates, field)
+		} else {
+			if _, err := template.New(field).Parse(text); err != nil {
+				ctx.ReplyLocale("COMMAND_INBOUND_INVALID", err.Error())
+				return
+			}
+			route.Templates[field] = text
+		}
+	default:
+		ctx.ReplyLocale("COMMAND_INBOUND_USAGE", ctx.Prefix, strings.Join(InboundFields, ", "))
+		return
+	}
+	if err := bot.AddInboundRoute(route); err != nil {
+		ctx.Error(err)
+		return
+	}
+	if action == "template" {
+		ctx.ReplyLocale("COMMAND_INBOUND_UPDATED", name)
+		return
+	}
+	// The token is only sent in DMs, anyone in the channel could post as the route otherwise.
+	dm, err := ctx.Session.UserChannelCreate(ctx.Author.ID)
+	if err == nil {
+		_, err = ctx.Session.ChannelMessageSend(dm.ID, ctx.Locale.Get("COMMAND_INBOUND_TOKEN", name, route.Token))
+	}
+	if err != nil {
+		ctx.ReplyLocale("COMMAND_INBOUND_NO_DM")
+		return
+	}
+	ctx.ReplyLocale("COMMAND_INBOUND_CREATED", name)
+}
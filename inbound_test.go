@@ -0,0 +1,50 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"github.com/bwmarrin/discordgo"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInboundRoute(t *testing.T) {
+	route := &InboundRoute{Name: "alerts", Templates: map[string]string{
+		"content":     "{{.alert.name}} is {{.status}}{{.missing}}",
+		"title":       "{{.alert.name}}",
+		"description": "{{range .hosts}}- {{.}}\n{{end}}",
+		"color":       "#ff0000",
+	}}
+	var data interface{}
+	json.Unmarshal([]byte(`{"alert": {"name": "Disk full"}, "status": "firing", "hosts": ["a", "b"]}`), &data)
+	msg, err := route.Render(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content != "Disk full is firing" || msg.Embed == nil || msg.Embed.Title != "Disk full" || msg.Embed.Description != "- a\n- b" || msg.Embed.Color != 0xff0000 {
+		t.Errorf("Unexpected message %+v %+v", msg, msg.Embed)
+	}
+	if _, err := (&InboundRoute{Name: "empty", Templates: map[string]string{"content": "{{.nothing}}"}}).Render(data); err == nil {
+		t.Error("expected an error for an empty message")
+	}
+
+	bot := New(&discordgo.Session{})
+	bot.EnableInboundWebhooks()
+	bot.inbound.routes["alerts"] = &InboundRoute{Name: "alerts", Token: "secret"}
+	for _, token := range []string{"wrong", ""} {
+		req := httptest.NewRequest("POST", "/inbound/alerts?token="+token, strings.NewReader("{}"))
+		w := httptest.NewRecorder()
+		bot.InboundHandler().ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("token %q = %d", token, w.Code)
+		}
+	}
+	req := httptest.NewRequest("POST", "/inbound/alerts", strings.NewReader("not json"))
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	bot.InboundHandler().ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid JSON = %d", w.Code)
+	}
+}
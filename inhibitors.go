@@ -0,0 +1,214 @@
+package sapphire
+
+import (
+	"strings"
+	"time"
+)
+
+// Inhibitor checks if a command may run before it does, e.g for blacklists or a maintenance mode.
+// Return false to stop the command, the reason is replied to the user. Return an empty reason if you replied yourself
+// or to stop it silently. The context's arguments are only parsed for inhibitors running after "arguments".
+type Inhibitor func(ctx *CommandContext) (bool, string)
+
+type namedInhibitor struct {
+	name string
+	fn   Inhibitor
+}
+
+// builtinInhibitors are the validations every command goes through, in order.
+// "arguments" and "cooldown" always run last since parsing replies on failure and passing takes the cooldown.
+func builtinInhibitors() []namedInhibitor {
+	return []namedInhibitor{
+		{"enabled", inhibitDisabled},
+		{"guilddisabled", inhibitGuildDisabled},
+		{"owner", inhibitOwnerOnly},
+		{"guild", inhibitGuildOnly},
+		{"overrides", inhibitOverrides},
+		{"permissions", inhibitPermissions},
+		{"level", inhibitLevel},
+		{"botpermissions", inhibitBotPermissions},
+		{"premium", inhibitPremium},
+		{"dependencies", inhibitDependencies},
+		{"circuit", inhibitCircuit},
+		{"arguments", inhibitArguments},
+		{"cooldown", inhibitCooldown},
+	}
+}
+
+// AddInhibitor adds a check to every command, replacing the one with the same name.
+// It runs after the builtin checks, right before arguments are parsed. The builtins are "enabled", "guilddisabled",
+// "owner", "guild", "overrides", "permissions", "level", "botpermissions", "premium", "dependencies", "circuit",
+// "arguments" and "cooldown".
+func (bot *Bot) AddInhibitor(name string, fn Inhibitor) *Bot {
+	for i, inhibitor := range bot.inhibitors {
+		if inhibitor.name == name {
+			bot.inhibitors[i].fn = fn
+			return bot
+		}
+	}
+	at := len(bot.inhibitors)
+	for i, inhibitor := range bot.inhibitors {
+		if inhibitor.name == "arguments" || inhibitor.name == "cooldown" {
+			at = i
+			break
+		}
+	}
+	bot.inhibitors = append(bot.inhibitors, namedInhibitor{})
+	copy(bot.inhibitors[at+1:], bot.inhibitors[at:])
+	bot.inhibitors[at] = namedInhibitor{name, fn}
+	return bot
+}
+
+// RemoveInhibitor removes an inhibitor by name, builtins can be removed too e.g to replace "permissions" with your own.
+func (bot *Bot) RemoveInhibitor(name string) *Bot {
+	for i, inhibitor := range bot.inhibitors {
+		if inhibitor.name == name {
+			bot.inhibitors = append(bot.inhibitors[:i], bot.inhibitors[i+1:]...)
+			break
+		}
+	}
+	return bot
+}
+
+// Inhibitors returns the names of the inhibitors in the order they run.
+func (bot *Bot) Inhibitors() []string {
+	names := make([]string, len(bot.inhibitors))
+	for i, inhibitor := range bot.inhibitors {
+		names[i] = inhibitor.name
+	}
+	return names
+}
+
+// inhibited runs the inhibitors, replying the reason of the first one stopping the command.
+func (bot *Bot) inhibited(ctx *CommandContext) bool {
+	for _, inhibitor := range bot.inhibitors {
+		ok, reason := inhibitor.fn(ctx)
+		if ok {
+			continue
+		}
+		if reason != "" {
+			ctx.Reply(reason)
+		}
+		return true
+	}
+	return false
+}
+
+// reason localizes key as the reason an inhibitor stopped the command.
+// A reason can only be text so keys localized as embeds are replied right away and the reason is empty.
+func (ctx *CommandContext) reason(key string, args ...interface{}) string {
+	msg := ctx.Bot.localizeMessage(ctx.Locale, key, args...)
+	if msg.Embed != nil {
+		ctx.respond(msg)
+		return ""
+	}
+	return msg.Content
+}
+
+func inhibitDisabled(ctx *CommandContext) (bool, string) {
+	if !ctx.Command.Enabled {
+		return false, ctx.reason("COMMAND_DISABLED")
+	}
+	return true, ""
+}
+
+func inhibitGuildDisabled(ctx *CommandContext) (bool, string) {
+	if ctx.Bot.commandDisabledIn(ctx) {
+		return false, ctx.reason("COMMAND_DISABLED_GUILD")
+	}
+	return true, ""
+}
+
+func inhibitOwnerOnly(ctx *CommandContext) (bool, string) {
+	if ctx.Command.OwnerOnly && ctx.Author.ID != ctx.Bot.OwnerID {
+		return false, ctx.reason("COMMAND_OWNER_ONLY")
+	}
+	return true, ""
+}
+
+func inhibitGuildOnly(ctx *CommandContext) (bool, string) {
+	if ctx.Command.GuildOnly && ctx.Message.GuildID == "" {
+		return false, ctx.reason("COMMAND_GUILD_ONLY")
+	}
+	return true, ""
+}
+
+func inhibitOverrides(ctx *CommandContext) (bool, string) {
+	if !ctx.Bot.checkOverrides(ctx) {
+		return false, ctx.reason("COMMAND_OVERRIDE_DENIED")
+	}
+	return true, ""
+}
+
+func inhibitPermissions(ctx *CommandContext) (bool, string) {
+	if ctx.Command.RequiredPermissions != 0 && !ctx.HasPermissions(ctx.Command.RequiredPermissions) {
+		p, err := ctx.Session.State.UserChannelPermissions(ctx.Author.ID, ctx.Channel.ID)
+		if err != nil {
+			return false, ctx.reason("COMMAND_MISSING_PERMISSIONS")
+		}
+		return false, ctx.reason("COMMAND_USER_MISSING_PERMISSIONS", strings.Join(PermissionNamesFor(ctx.Command.RequiredPermissions&^p), ", "))
+	}
+	return true, ""
+}
+
+// inhibitLevel checks the permission level, the owner and users allowed by a permission override pass.
+func inhibitLevel(ctx *CommandContext) (bool, string) {
+	if ctx.Command.PermissionLevel <= 0 || ctx.granted || ctx.Author.ID == ctx.Bot.OwnerID {
+		return true, ""
+	}
+	if ctx.Bot.PermissionLevelOf(ctx) < ctx.Command.PermissionLevel {
+		return false, ctx.reason("COMMAND_LEVEL_TOO_LOW", ctx.describe(ctx.Bot.levelName(ctx.Command.PermissionLevel)), ctx.Command.PermissionLevel)
+	}
+	return true, ""
+}
+
+func inhibitBotPermissions(ctx *CommandContext) (bool, string) {
+	if ctx.Command.BotPermissions != 0 && !ctx.BotHasPermissions(ctx.Command.BotPermissions) {
+		missing := ctx.Command.BotPermissions
+		if p, err := ctx.Session.State.UserChannelPermissions(ctx.Session.State.User.ID, ctx.Channel.ID); err == nil {
+			missing &^= p
+		}
+		return false, ctx.reason("COMMAND_BOT_MISSING_PERMISSIONS", strings.Join(PermissionNamesFor(missing), ", "))
+	}
+	return true, ""
+}
+
+func inhibitPremium(ctx *CommandContext) (bool, string) {
+	if ctx.Command.PremiumOnly && !ctx.Bot.IsPremium(ctx) {
+		return false, ctx.reason("COMMAND_PREMIUM_ONLY")
+	}
+	return true, ""
+}
+
+func inhibitDependencies(ctx *CommandContext) (bool, string) {
+	if dep := ctx.Bot.unhealthyDependency(ctx.Command.Dependencies); dep != "" {
+		return false, ctx.reason("COMMAND_UNAVAILABLE", dep)
+	}
+	return true, ""
+}
+
+func inhibitCircuit(ctx *CommandContext) (bool, string) {
+	if open, until := ctx.Bot.CircuitOpen(ctx.Command.Name); open && ctx.Author.ID != ctx.Bot.OwnerID {
+		return false, ctx.reason("COMMAND_TRIPPED", time.Until(until).Round(time.Second))
+	}
+	return true, ""
+}
+
+// inhibitArguments parses the arguments, ParseArgs already replies with the appropriate error.
+func inhibitArguments(ctx *CommandContext) (bool, string) {
+	return ctx.ParseArgs(), ""
+}
+
+func inhibitCooldown(ctx *CommandContext) (bool, string) {
+	if !ctx.cooldowns || ctx.Bot.IsCooldownExempt(ctx) {
+		return true, ""
+	}
+	cooldown := ctx.Command.Cooldown
+	if ctx.Command.PremiumCooldown >= 0 && ctx.Bot.IsPremium(ctx) {
+		cooldown = ctx.Command.PremiumCooldown
+	}
+	if canRun, after := ctx.Bot.CheckCooldown(ctx.Author.ID, ctx.Command.Name, cooldown); !canRun {
+		return false, ctx.reason("COMMAND_COOLDOWN", after)
+	}
+	return true, ""
+}
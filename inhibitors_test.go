@@ -0,0 +1,46 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"testing"
+)
+
+func TestInhibitors(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	bot.CommandTyping = false
+	ran := false
+	cmd := NewCommand("ping", "General", func(ctx *CommandContext) { ran = true })
+	bot.AddCommand(cmd)
+	maintenance := true
+	bot.AddInhibitor("maintenance", func(ctx *CommandContext) (bool, string) {
+		return !maintenance, ""
+	})
+	if names := bot.Inhibitors(); names[len(names)-3] != "maintenance" || names[len(names)-1] != "cooldown" {
+		t.Errorf("Expected the inhibitor before arguments and cooldown but got %v", names)
+	}
+
+	ctx := func() *CommandContext {
+		return &CommandContext{Bot: bot, Command: cmd, Session: bot.Session, Message: &discordgo.Message{},
+			Channel: &discordgo.Channel{ID: "c"}, Author: &discordgo.User{ID: "u"}, Locale: bot.DefaultLocale}
+	}
+	if err := bot.ExecuteCommand(ctx(), false); err != ErrCommandInhibited || ran {
+		t.Errorf("Expected the command to be inhibited but got %v", err)
+	}
+	maintenance = false
+	if err := bot.ExecuteCommand(ctx(), false); err != nil || !ran {
+		t.Errorf("Expected the command to run but got %v", err)
+	}
+	ran = false
+	bot.RemoveInhibitor("enabled")
+	cmd.Disable()
+	if err := bot.ExecuteCommand(ctx(), false); err != nil || !ran {
+		t.Errorf("Expected the removed inhibitor to be skipped but got %v", err)
+	}
+
+	cmd.Enable()
+	cmd.SetGuildOnly(true)
+	guildOnly, _ := bot.Localize(bot.DefaultLocale, "COMMAND_GUILD_ONLY")
+	if ok, reason := inhibitGuildOnly(ctx()); ok || reason != guildOnly {
+		t.Errorf("Expected the guild only reason but got %q", reason)
+	}
+}
@@ -0,0 +1,320 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InteractionCreate is the gateway event name of interactions.
+const InteractionCreate = "INTERACTION_CREATE"
+
+// Interaction types.
+const (
+	InteractionPing = iota + 1
+	InteractionApplicationCommand
+	InteractionComponent
+	InteractionAutocomplete
+	InteractionModalSubmit
+)
+
+// Interaction callback types.
+const (
+	ResponseChannelMessage         = 4
+	ResponseDeferredChannelMessage = 5
+	ResponseDeferredUpdate         = 6
+	ResponseUpdateMessage          = 7
+	ResponseModal                  = 9
+)
+
+// Application command types.
+const (
+	ApplicationCommandChatInput = 1
+	ApplicationCommandMessage   = 3
+)
+
+// Interaction option types, also used for slash command options.
+const (
+	OptionString  = 3
+	OptionInteger = 4
+	OptionBoolean = 5
+	OptionUser    = 6
+	OptionChannel = 7
+	OptionRole    = 8
+	OptionNumber  = 10
+)
+
+const messageFlagEphemeral = 1 << 6
+
+// Interaction is a slash command, component or modal submit.
+// discordgo doesn't know about interactions so sapphire decodes them from the raw gateway events.
+type Interaction struct {
+	ID            string             `json:"id"`
+	ApplicationID string             `json:"application_id"`
+	Type          int                `json:"type"`
+	Data          *InteractionData   `json:"data"`
+	GuildID       string             `json:"guild_id"`
+	ChannelID     string             `json:"channel_id"`
+	Member        *discordgo.Member  `json:"member"` // Set in guilds.
+	User          *discordgo.User    `json:"user"`   // Set in DMs.
+	Token         string             `json:"token"`
+	Locale        string             `json:"locale"`       // The user's client language.
+	GuildLocale   string             `json:"guild_locale"` // The guild's preferred language.
+	Message       *discordgo.Message `json:"message"`      // The message of the clicked component.
+}
+
+// InteractionData is the command of slash commands, the component of components or the fields of modals.
+type InteractionData struct {
+	ID            string               `json:"id"`
+	Name          string               `json:"name"`
+	Type          int                  `json:"type"`
+	Options       []*InteractionOption `json:"options"`
+	CustomID      string               `json:"custom_id"`
+	ComponentType int                  `json:"component_type"`
+	Values        []string             `json:"values"`     // The picked select menu options.
+	Components    []*ModalRow          `json:"components"` // The fields of a submitted modal.
+	TargetID      string               `json:"target_id"`  // The message a message command was used on.
+	Resolved      *InteractionResolved `json:"resolved"`
+}
+
+// InteractionResolved are the objects an interaction refers to by ID.
+type InteractionResolved struct {
+	Messages map[string]*discordgo.Message `json:"messages"`
+}
+
+// InteractionOption is an option the user filled in a slash command.
+type InteractionOption struct {
+	Name    string      `json:"name"`
+	Type    int         `json:"type"`
+	Value   interface{} `json:"value"`
+	Focused bool        `json:"focused"`
+}
+
+// ModalRow is a row of a submitted modal.
+type ModalRow struct {
+	Components []*ModalField `json:"components"`
+}
+
+// ModalField is a text input of a submitted modal.
+type ModalField struct {
+	CustomID string `json:"custom_id"`
+	Value    string `json:"value"`
+}
+
+// Value returns what the user typed in the modal field with customID.
+func (d *InteractionData) Value(customID string) string {
+	for _, row := range d.Components {
+		for _, field := range row.Components {
+			if field.CustomID == customID {
+				return field.Value
+			}
+		}
+	}
+	return ""
+}
+
+// Author returns who used the interaction.
+func (i *Interaction) Author() *discordgo.User {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User
+	}
+	return i.User
+}
+
+// ComponentHandler handles components and modals, see bot.AddComponentHandler
+// ctx.Command is nil, ctx.RawArgs are the parts of the custom ID after the handler's name.
+type ComponentHandler func(ctx *CommandContext)
+
+// AddComponentHandler handles clicked components and submitted modals whose custom ID is name or starts with "name:",
+// e.g the handler "vote" handles "vote:yes" with ctx.RawArgs being ["yes"].
+// ctx.Reply answers the interaction, pick what was clicked or typed from ctx.Interaction.Data
+func (bot *Bot) AddComponentHandler(name string, handler ComponentHandler) *Bot {
+	if bot.componentHandlers == nil {
+		bot.componentHandlers = make(map[string]ComponentHandler)
+	}
+	bot.componentHandlers[name] = handler
+	return bot
+}
+
+// MessageCommandHandler handles a message command, msg is the message it was used on.
+// ctx.Command is nil like for component handlers, ctx.InvokedName is the name of the message command.
+type MessageCommandHandler func(ctx *CommandContext, msg *discordgo.Message)
+
+// AddMessageCommand adds an entry to the Apps menu of messages, it's registered with the global slash commands
+// by the command sync, see bot.EnableCommandSync
+func (bot *Bot) AddMessageCommand(name string, handler MessageCommandHandler) *Bot {
+	if bot.messageCommands == nil {
+		bot.messageCommands = make(map[string]MessageCommandHandler)
+	}
+	bot.messageCommands[name] = handler
+	return bot
+}
+
+func interactionListener(bot *Bot) func(s *discordgo.Session, e *discordgo.Event) {
+	return bot.processEvent("interactions", func(s *discordgo.Session, e *discordgo.Event) error {
+		if e.Type != InteractionCreate {
+			return nil
+		}
+		interaction := &Interaction{}
+		if err := json.Unmarshal(e.RawData, interaction); err != nil {
+			return err
+		}
+		if interaction.Data == nil || interaction.Author() == nil {
+			return nil
+		}
+		switch interaction.Type {
+		case InteractionApplicationCommand:
+			bot.runInteractionCommand(interaction)
+		case InteractionComponent, InteractionModalSubmit:
+			bot.runComponent(interaction)
+		}
+		return nil
+	})
+}
+
+// interactionContext builds a command context for an interaction, the message is made up from the interaction
+// so the rest of sapphire can treat it like a message command.
+func (bot *Bot) interactionContext(i *Interaction) *CommandContext {
+	msg := &discordgo.Message{ID: i.ID, ChannelID: i.ChannelID, GuildID: i.GuildID, Author: i.Author(), Member: i.Member}
+	if i.Member != nil {
+		i.Member.GuildID = i.GuildID
+	}
+
+	var channel *discordgo.Channel
+	var guild *discordgo.Guild
+	if bot.Session.State != nil {
+		channel, _ = bot.Session.State.Channel(i.ChannelID)
+		if i.GuildID != "" {
+			guild, _ = bot.Session.State.Guild(i.GuildID)
+		}
+	}
+	if channel == nil {
+		channel = &discordgo.Channel{ID: i.ChannelID, GuildID: i.GuildID}
+		if i.GuildID == "" {
+			channel.Type = discordgo.ChannelTypeDM
+		}
+	}
+
+	ctx := &CommandContext{
+		Bot:         bot,
+		Message:     msg,
+		Channel:     channel,
+		Session:     bot.Session,
+		Author:      msg.Author,
+		Prefix:      "/",
+		Guild:       guild,
+		Flags:       make(map[string]string),
+		Shared:      make(map[string]interface{}),
+		Interaction: i,
+		Response:    &interactionResponse{bot: bot, interaction: i, received: time.Now()},
+	}
+	ctx.Locale = bot.Languages[bot.Language(bot, msg, i.GuildID == "")]
+	if ctx.Locale == nil {
+		ctx.Locale = bot.DefaultLocale
+	}
+	return ctx
+}
+
+func (bot *Bot) runInteractionCommand(i *Interaction) {
+	if i.Data.Type == ApplicationCommandMessage {
+		bot.runMessageCommand(i)
+		return
+	}
+	ctx := bot.interactionContext(i)
+	cmd := bot.GetCommand(strings.ToLower(i.Data.Name))
+	if cmd == nil {
+		// Deleted since the commands were last synced.
+		content, _ := ctx.localize("INTERACTION_UNKNOWN_COMMAND")
+		ctx.response().Send(&ResponseMessage{Content: content, Ephemeral: true})
+		return
+	}
+	ctx.Command = cmd
+	ctx.InvokedName = cmd.Name
+	ctx.RawArgs = interactionArgs(cmd, i.Data.Options)
+
+	// Lay the arguments out like they were typed so RawContent and ArgOffsets work like for message commands.
+	raw := cmd.Name
+	ctx.ArgOffsets = make([]int, len(ctx.RawArgs))
+	for n, arg := range ctx.RawArgs {
+		ctx.ArgOffsets[n] = len(raw) + 1
+		raw += " " + arg
+	}
+	ctx.RawContent = raw
+
+	bot.ExecuteCommand(ctx, true)
+}
+
+func (bot *Bot) runMessageCommand(i *Interaction) {
+	ctx := bot.interactionContext(i)
+	handler, ok := bot.messageCommands[i.Data.Name]
+	var target *discordgo.Message
+	if i.Data.Resolved != nil {
+		target = i.Data.Resolved.Messages[i.Data.TargetID]
+	}
+	if !ok || target == nil {
+		content, _ := ctx.localize("INTERACTION_UNKNOWN_COMMAND")
+		ctx.response().Send(&ResponseMessage{Content: content, Ephemeral: true})
+		return
+	}
+	ctx.InvokedName = i.Data.Name
+	defer func() {
+		if err := recover(); err != nil {
+			bot.ErrorHandler(bot, err)
+		}
+	}()
+	handler(ctx, target)
+}
+
+// interactionArgs puts the options in the order of the command's usage tags.
+// Arguments are positional so the options after one that was left empty are dropped.
+func interactionArgs(cmd *Command, options []*InteractionOption) []string {
+	if cmd.Spec == nil || cmd.Spec.Usage != cmd.UsageString {
+		cmd.compileUsage()
+	}
+	values := make(map[string]string, len(options))
+	for _, option := range options {
+		switch v := option.Value.(type) {
+		case string:
+			values[option.Name] = v
+		case float64:
+			values[option.Name] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			values[option.Name] = strconv.FormatBool(v)
+		}
+	}
+
+	var args []string
+	for _, tag := range cmd.Spec.Tags {
+		value, ok := values[tag.Name]
+		if !ok {
+			break
+		}
+		if tag.Rest {
+			rest, _ := splitArgs(value)
+			args = append(args, rest...)
+			break
+		}
+		args = append(args, value)
+	}
+	return args
+}
+
+func (bot *Bot) runComponent(i *Interaction) {
+	parts := strings.Split(i.Data.CustomID, ":")
+	handler, ok := bot.componentHandlers[parts[0]]
+	if !ok {
+		// Components of another program sharing the application, or of a handler that was removed.
+		return
+	}
+	ctx := bot.interactionContext(i)
+	ctx.RawArgs = parts[1:]
+	ctx.InvokedName = parts[0]
+	defer func() {
+		if err := recover(); err != nil {
+			bot.ErrorHandler(bot, err)
+		}
+	}()
+	handler(ctx)
+}
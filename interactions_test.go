@@ -0,0 +1,304 @@
+package sapphire
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/bwmarrin/discordgo"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type restCall struct {
+	Method   string
+	Endpoint string
+	Data     map[string]interface{}
+}
+
+// recordREST captures the bot's requests, every request succeeds with an empty message.
+// Interaction callbacks get it as their resource like with_response does. Requests discordgo makes itself,
+// like channel replies of message commands, are recorded through the session's http client.
+func recordREST(bot *Bot) func() []restCall {
+	var calls []restCall
+	var lock sync.Mutex
+	record := func(method, endpoint string, data map[string]interface{}) []byte {
+		lock.Lock()
+		defer lock.Unlock()
+		calls = append(calls, restCall{Method: method, Endpoint: strings.TrimPrefix(endpoint, discordgo.EndpointAPI), Data: data})
+		return []byte(`{"id":"m","resource":{"type":4,"message":{"id":"m"}}}`)
+	}
+	bot.requestHook = func(method, endpoint string, data interface{}, bucket string) ([]byte, error) {
+		payload, _ := data.(map[string]interface{})
+		return record(method, endpoint, payload), nil
+	}
+	bot.Session.Ratelimiter = discordgo.NewRatelimiter()
+	bot.Session.Client = &http.Client{Transport: roundTripper(func(r *http.Request) (*http.Response, error) {
+		var payload map[string]interface{}
+		if r.Body != nil {
+			json.NewDecoder(r.Body).Decode(&payload)
+		}
+		body := record(r.Method, r.URL.String(), payload)
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: ioutil.NopCloser(bytes.NewReader(body)), Request: r}, nil
+	})}
+	return func() []restCall {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]restCall(nil), calls...)
+	}
+}
+
+// roundTripper is an http.RoundTripper from a function.
+type roundTripper func(r *http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func dispatchInteraction(t *testing.T, bot *Bot, raw string) {
+	if err := bot.eventProcessors["interactions"](bot.Session, &discordgo.Event{Type: InteractionCreate, RawData: []byte(raw)}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInteractionResponse(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	calls := recordREST(bot)
+	var got []string
+	bot.AddCommand(NewCommand("say", "General", func(ctx *CommandContext) {
+		got = append(got, ctx.Arg(0).AsString(), ctx.Arg(1).AsString())
+		ctx.Reply("first")
+		ctx.Reply("second")
+		ctx.ReplyNoEdit("follow-up")
+	}).SetUsage("<text:string> [mode:string]"))
+
+	dispatchInteraction(t, bot, `{"id":"i","application_id":"a","type":2,"token":"tok","channel_id":"c",
+		"user":{"id":"u"},"data":{"name":"say","options":[{"name":"mode","type":3,"value":"loud"},{"name":"text","type":3,"value":"hello world"}]}}`)
+
+	if len(got) != 2 || got[0] != "hello world" || got[1] != "loud" {
+		t.Errorf("Expected the options as arguments but got %q", got)
+	}
+	expected := []string{
+		"POST interactions/i/tok/callback?with_response=true",
+		"PATCH webhooks/a/tok/messages/@original",
+		"POST webhooks/a/tok?wait=true",
+	}
+	requests := calls()
+	if len(requests) != len(expected) {
+		t.Fatalf("Expected %d requests but got %+v", len(expected), requests)
+	}
+	for i, call := range requests {
+		if call.Method+" "+call.Endpoint != expected[i] {
+			t.Errorf("Expected request %d to be %s but got %s %s", i, expected[i], call.Method, call.Endpoint)
+		}
+	}
+	if requests[0].Data["type"] != ResponseChannelMessage {
+		t.Errorf("Expected a channel message callback but got %v", requests[0].Data)
+	}
+	if requests[1].Data["content"] != "second" || requests[2].Data["content"] != "follow-up" {
+		t.Errorf("Expected the edit and the follow-up to have their content but got %v and %v", requests[1].Data, requests[2].Data)
+	}
+}
+
+func TestInteractionDefer(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	calls := recordREST(bot)
+	bot.AddCommand(NewCommand("slow", "General", func(ctx *CommandContext) {
+		ctx.Defer()
+		ctx.Defer()
+		ctx.ReplyNoEdit("done")
+	}))
+
+	dispatchInteraction(t, bot, `{"id":"i","application_id":"a","type":2,"token":"tok","channel_id":"c","guild_id":"g",
+		"member":{"user":{"id":"u"}},"data":{"name":"slow"}}`)
+
+	requests := calls()
+	if len(requests) != 2 {
+		t.Fatalf("Expected a deferred callback and the edit of it but got %+v", requests)
+	}
+	if requests[0].Data["type"] != ResponseDeferredChannelMessage {
+		t.Errorf("Expected a deferred callback but got %v", requests[0].Data)
+	}
+	if requests[1].Method != "PATCH" || requests[1].Data["content"] != "done" {
+		t.Errorf("Expected the reply to fill in the deferred response but got %+v", requests[1])
+	}
+}
+
+func TestComponentHandler(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	calls := recordREST(bot)
+	var args []string
+	bot.AddComponentHandler("vote", func(ctx *CommandContext) {
+		args = ctx.RawArgs
+		ctx.ReplyEphemeral("Voted %s", ctx.Interaction.Data.Values[0])
+	})
+
+	dispatchInteraction(t, bot, `{"id":"i","application_id":"a","type":3,"token":"tok","channel_id":"c",
+		"user":{"id":"u"},"data":{"custom_id":"vote:poll","component_type":3,"values":["yes"]}}`)
+	dispatchInteraction(t, bot, `{"id":"j","application_id":"a","type":3,"token":"tok","channel_id":"c",
+		"user":{"id":"u"},"data":{"custom_id":"other:poll","component_type":2}}`)
+
+	if len(args) != 1 || args[0] != "poll" {
+		t.Errorf("Expected the custom ID's arguments but got %q", args)
+	}
+	requests := calls()
+	if len(requests) != 1 {
+		t.Fatalf("Expected only the vote to be answered but got %+v", requests)
+	}
+	data, _ := json.Marshal(requests[0].Data["data"])
+	if !strings.Contains(string(data), `"Voted yes"`) || !strings.Contains(string(data), `"flags":64`) {
+		t.Errorf("Expected an ephemeral reply but got %s", data)
+	}
+}
+
+func TestModalValue(t *testing.T) {
+	data := &InteractionData{}
+	if err := json.Unmarshal([]byte(`{"custom_id":"m","components":[{"type":1,"components":[{"type":4,"custom_id":"prefix","value":"?"}]}]}`), data); err != nil {
+		t.Fatal(err)
+	}
+	if v := data.Value("prefix"); v != "?" {
+		t.Errorf("Expected the typed prefix but got %q", v)
+	}
+	if v := data.Value("missing"); v != "" {
+		t.Errorf("Expected nothing for a missing field but got %q", v)
+	}
+}
+
+func TestInteractionAutoDefer(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	bot.SetDeferAfter(20 * time.Millisecond)
+	calls := recordREST(bot)
+	bot.AddCommand(NewCommand("slow", "General", func(ctx *CommandContext) {
+		time.Sleep(60 * time.Millisecond)
+		ctx.Reply("done")
+	}))
+	bot.AddCommand(NewCommand("fast", "General", func(ctx *CommandContext) {
+		ctx.Reply("done")
+	}))
+	events := make(chan *CommandRan, 2)
+	bot.Subscribe(TopicCommandRan, func(event BusEvent) {
+		events <- event.(*CommandRan)
+	})
+
+	for _, name := range []string{"slow", "fast"} {
+		dispatchInteraction(t, bot, `{"id":"i","application_id":"a","type":2,"token":"tok","channel_id":"c",
+			"user":{"id":"u"},"data":{"name":"`+name+`"}}`)
+		select {
+		case ran := <-events:
+			if name == "slow" && (ran.FirstResponse < 20*time.Millisecond || ran.FirstResponse >= 60*time.Millisecond) {
+				t.Errorf("Expected the slow command to be answered by the deferral but it took %s", ran.FirstResponse)
+			}
+			if name == "fast" && (ran.FirstResponse <= 0 || ran.FirstResponse >= 20*time.Millisecond) {
+				t.Errorf("Expected the fast command to answer right away but it took %s", ran.FirstResponse)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected a command ran event")
+		}
+	}
+
+	requests := calls()
+	if len(requests) < 3 || requests[0].Data["type"] != ResponseDeferredChannelMessage || requests[1].Method != "PATCH" {
+		t.Fatalf("Expected the slow command to be deferred and then filled in but got %+v", requests)
+	}
+	if requests[2].Data["type"] != ResponseChannelMessage {
+		t.Errorf("Expected the fast command to answer itself but got %+v", requests[2])
+	}
+	if n := bot.Counters.Get(CounterInteractionsDeferred); n != 1 {
+		t.Errorf("Expected one deferral to be counted but got %d", n)
+	}
+}
+
+func TestInteractionReplyOrEdit(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	calls := recordREST(bot)
+	bot.AddCommand(NewCommand("progress", "General", func(ctx *CommandContext) {
+		ctx.ReplyOrEdit("status", "Working...")
+		ctx.ReplyOrEdit("status", "Done!")
+	}))
+
+	dispatchInteraction(t, bot, `{"id":"i","application_id":"a","type":2,"token":"tok","channel_id":"c",
+		"user":{"id":"u"},"data":{"name":"progress"}}`)
+
+	requests := calls()
+	if len(requests) != 2 {
+		t.Fatalf("Expected the answer and the edit but got %+v", requests)
+	}
+	if requests[0].Endpoint != "interactions/i/tok/callback?with_response=true" {
+		t.Errorf("Expected the first message to answer the interaction but got %s %s", requests[0].Method, requests[0].Endpoint)
+	}
+	if edit := requests[1]; edit.Method != "PATCH" || edit.Endpoint != "webhooks/a/tok/messages/m" || edit.Data["content"] != "Done!" {
+		t.Errorf("Expected the answer to be edited through the interaction's webhook but got %+v", edit)
+	}
+}
+
+func TestInteractionEdit(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	calls := recordREST(bot)
+	bot.AddCommand(NewCommand("ping", "General", func(ctx *CommandContext) {
+		msg, err := ctx.Reply("Ping?")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		ctx.Edit(msg, "Pinging...")
+		ctx.EditLocale(msg, "COMMAND_PING_PONG", 5, 10)
+	}))
+
+	dispatchInteraction(t, bot, `{"id":"i","application_id":"a","type":2,"token":"tok","channel_id":"c",
+		"user":{"id":"u"},"data":{"name":"ping"}}`)
+
+	requests := calls()
+	if len(requests) != 3 {
+		t.Fatalf("Expected the answer and two edits but got %+v", requests)
+	}
+	for i, content := range []string{"Pinging...", "Pong! Latency: **5**ms, API Latency: **10**ms"} {
+		if edit := requests[i+1]; edit.Method != "PATCH" || edit.Endpoint != "webhooks/a/tok/messages/m" || edit.Data["content"] != content {
+			t.Errorf("Expected the answer to be edited to %q through the interaction's webhook but got %+v", content, edit)
+		}
+	}
+}
+
+func TestInteractionReplyEphemeralLike(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	calls := recordREST(bot)
+	bot.AddCommand(NewCommand("temp", "General", func(ctx *CommandContext) {
+		ctx.ReplyEphemeralLike("Gone soon", 10*time.Millisecond, true)
+	}))
+
+	dispatchInteraction(t, bot, `{"id":"i","application_id":"a","type":2,"token":"tok","channel_id":"c",
+		"user":{"id":"u"},"data":{"name":"temp"}}`)
+
+	deadline := time.Now().Add(time.Second)
+	for len(calls()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	requests := calls()
+	if len(requests) != 2 {
+		t.Fatalf("Expected the answer and deleting it but got %+v", requests)
+	}
+	data, _ := json.Marshal(requests[0].Data["data"])
+	if !strings.Contains(string(data), `"flags":64`) {
+		t.Errorf("Expected an ephemeral answer but got %s", data)
+	}
+	if requests[1].Method != "DELETE" || requests[1].Endpoint != "webhooks/a/tok/messages/m" {
+		t.Errorf("Expected the answer to be deleted through the interaction's webhook but got %+v", requests[1])
+	}
+}
+
+func TestInteractionReact(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	recordREST(bot)
+	var err error
+	bot.AddCommand(NewCommand("react", "General", func(ctx *CommandContext) {
+		err = ctx.React("✅")
+	}))
+
+	dispatchInteraction(t, bot, `{"id":"i","application_id":"a","type":2,"token":"tok","channel_id":"c",
+		"user":{"id":"u"},"data":{"name":"react"}}`)
+
+	if err != ErrNoTriggerMessage {
+		t.Errorf("Expected reacting to an interaction to fail with ErrNoTriggerMessage but got %v", err)
+	}
+}
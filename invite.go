@@ -0,0 +1,143 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"strings"
+)
+
+// PermissionNames are the names of the permission bits, in the order Discord lists them.
+var PermissionNames = []struct {
+	Bit  int
+	Name string
+}{
+	{discordgo.PermissionAdministrator, "Administrator"},
+	{discordgo.PermissionViewAuditLogs, "View Audit Log"},
+	{discordgo.PermissionManageServer, "Manage Server"},
+	{discordgo.PermissionManageRoles, "Manage Roles"},
+	{discordgo.PermissionManageChannels, "Manage Channels"},
+	{discordgo.PermissionKickMembers, "Kick Members"},
+	{discordgo.PermissionBanMembers, "Ban Members"},
+	{discordgo.PermissionCreateInstantInvite, "Create Invite"},
+	{discordgo.PermissionChangeNickname, "Change Nickname"},
+	{discordgo.PermissionManageNicknames, "Manage Nicknames"},
+	{discordgo.PermissionManageEmojis, "Manage Emojis"},
+	{discordgo.PermissionManageWebhooks, "Manage Webhooks"},
+	{discordgo.PermissionReadMessages, "View Channels"},
+	{discordgo.PermissionSendMessages, "Send Messages"},
+	{discordgo.PermissionSendTTSMessages, "Send TTS Messages"},
+	{discordgo.PermissionManageMessages, "Manage Messages"},
+	{discordgo.PermissionEmbedLinks, "Embed Links"},
+	{discordgo.PermissionAttachFiles, "Attach Files"},
+	{discordgo.PermissionReadMessageHistory, "Read Message History"},
+	{discordgo.PermissionMentionEveryone, "Mention Everyone"},
+	{discordgo.PermissionUseExternalEmojis, "Use External Emojis"},
+	{discordgo.PermissionAddReactions, "Add Reactions"},
+	{discordgo.PermissionVoiceConnect, "Connect"},
+	{discordgo.PermissionVoiceSpeak, "Speak"},
+	{discordgo.PermissionVoiceMuteMembers, "Mute Members"},
+	{discordgo.PermissionVoiceDeafenMembers, "Deafen Members"},
+	{discordgo.PermissionVoiceMoveMembers, "Move Members"},
+	{discordgo.PermissionVoiceUseVAD, "Use Voice Activity"},
+	{discordgo.PermissionVoicePrioritySpeaker, "Priority Speaker"},
+}
+
+// PermissionNamesFor returns the names of the permissions in bits e.g ["Manage Roles", "Kick Members"]
+func PermissionNamesFor(bits int) []string {
+	var names []string
+	for _, perm := range PermissionNames {
+		if bits&perm.Bit == perm.Bit {
+			names = append(names, perm.Name)
+		}
+	}
+	return names
+}
+
+// moduleFeature is a module with the permissions and gateway intents it needs to work.
+type moduleFeature struct {
+	name    string
+	perms   int
+	intents discordgo.Intent
+	enabled func(bot *Bot) bool
+}
+
+// moduleFeatures are the modules InvitePermissions and Diagnose know about, the names can be passed to bot.InviteURL
+var moduleFeatures = []moduleFeature{
+	// Role menus are always available through bot.SendRoleMenu so they're only included when named.
+	{"rolemenus", discordgo.PermissionManageRoles, 0, func(bot *Bot) bool { return false }},
+	{"autovc", discordgo.PermissionManageChannels | discordgo.PermissionVoiceMoveMembers, discordgo.IntentsGuildVoiceStates, func(bot *Bot) bool { return bot.autoVC != nil }},
+	{"stickies", discordgo.PermissionManageMessages, discordgo.IntentsGuildMessages, func(bot *Bot) bool { return bot.stickies != nil }},
+	{"autopublish", discordgo.PermissionManageMessages, discordgo.IntentsGuildMessages, func(bot *Bot) bool { return bot.autoPublish != nil }},
+	{"counting", discordgo.PermissionManageMessages | discordgo.PermissionAddReactions, discordgo.IntentsGuildMessages, func(bot *Bot) bool { return bot.counting != nil }},
+	{"suggestions", discordgo.PermissionEmbedLinks, 0, func(bot *Bot) bool { return bot.suggestions != nil }},
+	{"tickets", discordgo.PermissionManageChannels | discordgo.PermissionManageRoles | discordgo.PermissionAttachFiles, 0, func(bot *Bot) bool { return bot.tickets != nil }},
+	{"statchannels", discordgo.PermissionManageChannels, discordgo.IntentsGuildMembers | discordgo.IntentsGuildPresences, func(bot *Bot) bool { return bot.statChannels != nil }},
+	{"bridges", discordgo.PermissionManageWebhooks, discordgo.IntentsGuildMessages, func(bot *Bot) bool { return bot.bridges != nil }},
+	{"modmail", discordgo.PermissionManageChannels | discordgo.PermissionAttachFiles, discordgo.IntentsDirectMessages, func(bot *Bot) bool { return bot.modmail != nil }},
+	{"automod", discordgo.PermissionManageMessages | discordgo.PermissionKickMembers | discordgo.PermissionBanMembers, discordgo.IntentsGuildMessages, func(bot *Bot) bool { return bot.automod != nil }},
+	{"quotes", discordgo.PermissionEmbedLinks | discordgo.PermissionReadMessageHistory, discordgo.IntentsGuildMessages, func(bot *Bot) bool { return bot.Monitors["quotes"] != nil }},
+	{"emoji", discordgo.PermissionManageEmojis, 0, func(bot *Bot) bool { return bot.Commands["emoji"] != nil }},
+	{"voice", 0, discordgo.IntentsGuildVoiceStates, func(bot *Bot) bool { return bot.Voice != nil }},
+	{"status", 0, discordgo.IntentsGuildPresences, func(bot *Bot) bool { return bot.Commands["status"] != nil }},
+	{"bulkrole", discordgo.PermissionManageRoles, discordgo.IntentsGuildMembers, func(bot *Bot) bool { return bot.Commands["bulkrole"] != nil }},
+	{"streams", 0, discordgo.IntentsGuildPresences | discordgo.IntentsGuildVoiceStates, func(bot *Bot) bool { return bot.streams != nil }},
+	{"liverole", discordgo.PermissionManageRoles, discordgo.IntentsGuildMembers, func(bot *Bot) bool { return bot.ConfigSchemas["liverole"] != nil }},
+	{"streamalerts", discordgo.PermissionEmbedLinks | discordgo.PermissionMentionEveryone, 0, func(bot *Bot) bool { return bot.streamAlerts != nil }},
+	{"feeds", discordgo.PermissionEmbedLinks, 0, func(bot *Bot) bool { return bot.feeds != nil }},
+	{"github", discordgo.PermissionEmbedLinks, 0, func(bot *Bot) bool { return bot.gitHub != nil }},
+}
+
+// InvitePermissions returns the permissions the bot needs: InvitePerms, the BotPermissions of every command and
+// what the enabled modules need, along with the modules named in features even if they aren't enabled yet.
+func (bot *Bot) InvitePermissions(features ...string) int {
+	perms := bot.InvitePerms
+	for _, cmd := range bot.Commands {
+		perms |= cmd.BotPermissions
+	}
+	for _, feature := range moduleFeatures {
+		if feature.enabled(bot) || containsString(features, feature.name) {
+			perms |= feature.perms
+		}
+	}
+	return perms
+}
+
+// InviteURL returns the OAuth2 link inviting the bot with the permissions from bot.InvitePermissions, features
+// are modules that are enabled later on e.g "tickets". The applications.commands scope is requested too.
+func (bot *Bot) InviteURL(features ...string) string {
+	return fmt.Sprintf("https://discord.com/oauth2/authorize?client_id=%s&permissions=%d&scope=bot%%20applications.commands",
+		bot.Session.State.User.ID, bot.InvitePermissions(features...))
+}
+
+// MissingPermissions returns the permissions from bot.InvitePermissions the bot doesn't have in a guild.
+func (bot *Bot) MissingPermissions(guild *discordgo.Guild, features ...string) int {
+	member, err := bot.Session.State.Member(guild.ID, bot.Session.State.User.ID)
+	if err != nil {
+		return bot.InvitePermissions(features...)
+	}
+	have := PermissionsForMember(guild, member)
+	for _, role := range guild.Roles {
+		// The @everyone role has the guild's ID and isn't in the member's roles.
+		if role.ID == guild.ID {
+			have |= Permissions(role.Permissions)
+		}
+	}
+	if have.Has(discordgo.PermissionAdministrator) {
+		return 0
+	}
+	return bot.InvitePermissions(features...) &^ int(have)
+}
+
+func inviteCommand(ctx *CommandContext) {
+	url := ctx.Bot.InviteURL()
+	if ctx.Guild == nil {
+		ctx.ReplyLocale("COMMAND_INVITE", url)
+		return
+	}
+	missing := ctx.Bot.MissingPermissions(ctx.Guild)
+	if missing == 0 {
+		ctx.ReplyLocale("COMMAND_INVITE", url)
+		return
+	}
+	ctx.ReplyLocale("COMMAND_INVITE_MISSING", url, strings.Join(PermissionNamesFor(missing), ", "))
+}
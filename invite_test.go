@@ -0,0 +1,22 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"testing"
+)
+
+func TestInvitePermissions(t *testing.T) {
+	bot := &Bot{InvitePerms: 3072, Commands: map[string]*Command{"purge": {BotPermissions: discordgo.PermissionManageMessages}}}
+	perms := bot.InvitePermissions()
+	if perms != 3072|discordgo.PermissionManageMessages {
+		t.Errorf("InvitePermissions() = %d", perms)
+	}
+	if !Permissions(bot.InvitePermissions("tickets")).Has(discordgo.PermissionManageChannels | discordgo.PermissionManageRoles) {
+		t.Errorf("InvitePermissions(tickets) doesn't include what tickets need")
+	}
+	names := PermissionNamesFor(discordgo.PermissionKickMembers | discordgo.PermissionManageRoles)
+	if strings.Join(names, ", ") != "Manage Roles, Kick Members" {
+		t.Errorf("PermissionNamesFor = %v", names)
+	}
+}
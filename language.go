@@ -2,16 +2,38 @@ package sapphire
 
 import (
 	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"strings"
 )
 
 type Language struct {
-	Name string
-	Keys map[string]string
+	Name   string
+	Keys   map[string]string
+	Embeds map[string]*LocaleEmbed
+}
+
+// LocaleEmbed describes a localized embed, texts with verbs are formatted with the locale args just like Get,
+// texts without them e.g a static title are used as is. Since not every part might need every argument
+// use explicit argument indexes e.g %[2]s to pick them.
+type LocaleEmbed struct {
+	Title       string
+	Description string
+	Footer      string
+	Color       int // The embed color, if 0 the bot's color is used.
+	Fields      []*LocaleEmbedField
+	Components  []*Component // Action rows sent with the embed, their texts and custom IDs are formatted like the embed's.
+}
+
+// LocaleEmbedField is a field in a LocaleEmbed.
+type LocaleEmbedField struct {
+	Name   string
+	Value  string
+	Inline bool
 }
 
 // NewLanguage creates a new language with the specified name.
 func NewLanguage(name string) *Language {
-	return &Language{Name: name, Keys: make(map[string]string)}
+	return &Language{Name: name, Keys: make(map[string]string), Embeds: make(map[string]*LocaleEmbed)}
 }
 
 // Merge merges the keys from the other language
@@ -19,6 +41,9 @@ func (l *Language) Merge(other *Language) *Language {
 	for k, v := range other.Keys {
 		l.Keys[k] = v
 	}
+	for k, v := range other.Embeds {
+		l.SetEmbed(k, v)
+	}
 	return l
 }
 
@@ -35,6 +60,82 @@ func (l *Language) Get(key string, args ...interface{}) string {
 	return ""
 }
 
+// SetEmbed sets key to reply with an embed instead of a plain string.
+func (l *Language) SetEmbed(key string, embed *LocaleEmbed) *Language {
+	if l.Embeds == nil {
+		l.Embeds = make(map[string]*LocaleEmbed)
+	}
+	l.Embeds[key] = embed
+	return l
+}
+
+// GetEmbed builds the embed for key formatted with args, returns nil if the key isn't an embed.
+func (l *Language) GetEmbed(key string, args ...interface{}) *discordgo.MessageEmbed {
+	e, ok := l.Embeds[key]
+	if !ok {
+		return nil
+	}
+	format := func(str string) string {
+		return localeFormat(str, args)
+	}
+	embed := NewEmbed().
+		SetTitle(format(e.Title)).
+		SetDescription(format(e.Description)).
+		SetColor(e.Color)
+	if e.Footer != "" {
+		embed.SetFooter(format(e.Footer))
+	}
+	for _, field := range e.Fields {
+		if field.Inline {
+			embed.AddInlineField(format(field.Name), format(field.Value))
+		} else {
+			embed.AddField(format(field.Name), format(field.Value))
+		}
+	}
+	return embed.Build()
+}
+
+// GetComponents builds the components of the embed for key formatted with args, returns nil if it has none.
+func (l *Language) GetComponents(key string, args ...interface{}) []*Component {
+	e, ok := l.Embeds[key]
+	if !ok || len(e.Components) == 0 {
+		return nil
+	}
+	components := make([]*Component, len(e.Components))
+	for i, component := range e.Components {
+		components[i] = component.localize(args)
+	}
+	return components
+}
+
+// localize returns a copy of the component and it's children with their texts formatted with args.
+func (c *Component) localize(args []interface{}) *Component {
+	copied := *c
+	copied.CustomID = localeFormat(c.CustomID, args)
+	copied.Label = localeFormat(c.Label, args)
+	copied.Placeholder = localeFormat(c.Placeholder, args)
+	copied.Value = localeFormat(c.Value, args)
+	copied.Options = make([]*SelectOption, len(c.Options))
+	for i, option := range c.Options {
+		copied.Options[i] = &SelectOption{Label: localeFormat(option.Label, args), Value: localeFormat(option.Value, args),
+			Description: localeFormat(option.Description, args), Default: option.Default}
+	}
+	copied.Components = make([]*Component, len(c.Components))
+	for i, child := range c.Components {
+		copied.Components[i] = child.localize(args)
+	}
+	return &copied
+}
+
+// localeFormat formats a text of a localized embed, texts without verbs are used as is.
+func localeFormat(str string, args []interface{}) string {
+	// Formatting a text without verbs would append %!(EXTRA ...) for the unused args.
+	if !strings.Contains(strings.Replace(str, "%%", "", -1), "%") {
+		return strings.Replace(str, "%%", "%", -1)
+	}
+	return fmt.Sprintf(str, args...)
+}
+
 func (l *Language) GetDefault(key string, def string, args ...interface{}) string {
 	v := l.Get(key, args...)
 	if v == "" {
@@ -54,7 +155,360 @@ var English = NewLanguage("en-US").
 	Set("COMMAND_DISABLE_SUCCESS", "Successfully disabled the command **%s**").
 	Set("COMMAND_NOT_FOUND", "Command '%s' not found.").
 	Set("COMMAND_INVITE", "To invite me to your server: <%s>").
+	Set("COMMAND_INVITE_MISSING", "To invite me to your server: <%s>\nI'm missing these permissions here, some features won't work without them: **%s**\nAn admin can grant them or re-invite me with the link.").
 	Set("COMMAND_OWNER_ONLY", "This command is for the bot owner only!").
 	Set("COMMAND_GUILD_ONLY", "This command can only be used in a server!").
+	Set("COMMAND_PREMIUM_ONLY", "This command is only available to premium users and servers.").
 	Set("COMMAND_COOLDOWN", "You can use this command again in %d seconds.").
-	Set("COMMAND_DISABLED", "This command has been disabled globally by the bot owner.")
+	Set("COMMAND_DISABLED", "This command has been disabled globally by the bot owner.").
+	Set("COMMAND_UNAVAILABLE", "This command is temporarily unavailable because **%s** is down, try again later.").
+	Set("INTERACTION_UNKNOWN_COMMAND", "This command doesn't exist anymore.").
+	Set("COMMAND_TIMEOUT", "This command took too long and was cancelled, try again later.").
+	Set("COMMAND_OVERRIDE_DENIED", "You are not allowed to use this command here.").
+	Set("COMMAND_MISSING_PERMISSIONS", "You don't have the permissions to use this command.").
+	Set("COMMAND_TRIPPED", "This command is temporarily disabled because it keeps failing, try again in %s.").
+	Set("CIRCUIT_TRIPPED", "The command **%s** failed %d times within %s and is disabled for %s. Last error: %s").
+	Set("CIRCUIT_CLOSED", "The command **%s** is enabled again, it's disabled right away if it fails again.").
+	Set("COMMAND_CONFIG_NO_EXTENSION", "There is no configuration for '%s'.").
+	Set("COMMAND_CONFIG_NO_KEY", "There is no key '%s' in '%s'.").
+	Set("COMMAND_CONFIG_VALUE", "**%s.%s** is set to %s").
+	Set("COMMAND_CONFIG_SET", "Successfully set **%s.%s** to %s").
+	Set("COMMAND_CONFIG_RESET", "Successfully reset **%s.%s** to the default %s").
+	Set("COMMAND_CONFIG_NO_PERMISSION", "You need the Manage Server permission to change the configuration.").
+	Set("COMMAND_CHAIN_TOO_LONG", "You can only chain up to %d commands.").
+	Set("COMMAND_CHAIN_UNKNOWN", "Stopped the chain, `%s` is not a command.").
+	Set("COMMAND_TIMEZONE_CURRENT", "Your timezone is **%s**, it's currently %s").
+	Set("COMMAND_TIMEZONE_SERVER", "This server's timezone is **%s**").
+	Set("COMMAND_TIMEZONE_SET", "Your timezone is now **%s**, it's currently %s").
+	Set("COMMAND_TIMEZONE_SERVER_SET", "This server's timezone is now **%s**").
+	Set("COMMAND_TIMEZONE_INVALID", "**%s** is not a valid timezone, use a name like `Europe/Berlin` or an offset like `UTC+2`").
+	Set("COMMAND_TIMEZONE_NO_PERMISSION", "You need the Manage Server permission to change this server's timezone.").
+	Set("COMMAND_BROADCAST_STARTED", "Starting the broadcast...").
+	Set("COMMAND_BROADCAST_PROGRESS", "Broadcasting... %d/%d servers (%d sent, %d skipped, %d failed)").
+	Set("COMMAND_BROADCAST_DONE", "Broadcast finished, %[2]d servers: %[3]d sent, %[4]d skipped, %[5]d failed.").
+	Set("COMMAND_EMOJI_NO_SLOTS", "This server has used all of it's %s emoji slots for that kind of emoji.").
+	Set("COMMAND_EMOJI_FAILED", "Couldn't add the emoji: %s").
+	Set("COMMAND_EMOJI_ADDED", "Added %s as **%s**").
+	Set("COMMAND_EMOJI_SLOTS", "**Static:** %d/%d\n**Animated:** %d/%d").
+	Set("COMMAND_AFK_SET", "You are now AFK: %s").
+	Set("AFK_WELCOME_BACK", "Welcome back %s, I removed your AFK status.").
+	Set("AFK_NOTICE", "**%s** is AFK: %s (%s)").
+	Set("ROLEMENU_PLACEHOLDER", "Pick your roles").
+	Set("ROLEMENU_UPDATED", "Your roles have been updated.").
+	Set("ROLEMENU_REQUIRED_ROLE", "You need the <@&%s> role to use this menu.").
+	Set("ROLEMENU_TOO_MANY", "You can pick up to %d roles.").
+	Set("ROLEMENU_FAILED", "I couldn't change your roles, I might be missing the Manage Roles permission.").
+	Set("COMMAND_STICKY_USAGE", "Usage: `%[1]ssticky set <message...>` or `%[1]ssticky remove`").
+	Set("COMMAND_STICKY_SET", "The sticky message of this channel has been set.").
+	Set("COMMAND_STICKY_REMOVED", "The sticky message of this channel has been removed.").
+	Set("COMMAND_STICKY_NONE", "This channel has no sticky message.").
+	Set("COMMAND_AUTOPUBLISH_NOT_NEWS", "This is not an announcement channel.").
+	Set("COMMAND_AUTOPUBLISH_CURRENT", "Auto-publish in this channel is set to **%s**").
+	Set("COMMAND_AUTOPUBLISH_NO_PERMISSION", "You need the Manage Channels permission to change auto-publishing.").
+	Set("COMMAND_AUTOPUBLISH_USAGE", "Usage: `%sautopublish <off|all|bots|users>`").
+	Set("COMMAND_AUTOPUBLISH_SET", "Messages from **%s** in this channel will now be published automatically.").
+	Set("COMMAND_AUTOPUBLISH_DISABLED", "Messages in this channel will no longer be published automatically.").
+	Set("COMMAND_AUTOTHREAD_OFF", "Auto-threading is off in this channel.").
+	Set("COMMAND_AUTOTHREAD_CURRENT", "New messages in this channel get a thread named `%s`, archived after %d minutes of inactivity.").
+	Set("COMMAND_AUTOTHREAD_NO_PERMISSION", "You need the Manage Channels permission to change auto-threading.").
+	Set("COMMAND_AUTOTHREAD_USAGE", "Usage: `%sautothread <on|off> [--name=template] [--archive=minutes]`").
+	Set("COMMAND_AUTOTHREAD_ARCHIVE", "`%s` is not a valid archive duration, pick one of 60, 1440, 4320 or 10080 minutes.").
+	Set("COMMAND_AUTOTHREAD_ENABLED", "New messages in this channel will get a thread named `%s`, archived after %d minutes of inactivity.").
+	Set("COMMAND_AUTOTHREAD_DISABLED", "New messages in this channel will no longer get a thread.").
+	Set("COUNTING_WRONG", "%s ruined it! The next number was **%d**, the count reached **%d** and starts over at **1**.").
+	Set("COUNTING_TWICE", "%s ruined it! You can't count twice in a row, the count reached **%d** and starts over at **1**.").
+	Set("COUNTING_DISABLED", "There is no counting channel in this server, set one with `%sconfig counting channel <channel>`").
+	Set("COMMAND_COUNTING_STATUS", "Counting in <#%s> is at **%d**, the next number is **%d**. The record is **%d**.").
+	Set("COMMAND_COUNTING_NO_SCORES", "Nobody has counted yet.").
+	Set("COMMAND_COUNTING_LEADERBOARD", "Counting Leaderboard").
+	Set("COMMAND_COUNTING_NO_PERMISSION", "You need the Manage Server permission to reset the count.").
+	Set("COMMAND_COUNTING_RESET", "The count has been reset.").
+	Set("COMMAND_COUNTING_USAGE", "Usage: `%scounting [top|reset]`, add `--all` to reset to also clear the leaderboard.").
+	Set("SUGGESTION_TITLE", "Suggestion #%d").
+	Set("SUGGESTION_STATUS_PENDING", "Pending").
+	Set("SUGGESTION_STATUS_APPROVED", "Approved").
+	Set("SUGGESTION_STATUS_DENIED", "Denied").
+	Set("SUGGESTION_REASON", "Reason").
+	Set("SUGGESTION_NO_REASON", "No reason given.").
+	Set("SUGGESTION_REVIEWER", "Reviewed by").
+	Set("SUGGESTION_VOTES", "Votes").
+	Set("SUGGESTION_APPROVE", "Approve").
+	Set("SUGGESTION_DENY", "Deny").
+	Set("SUGGESTION_REVIEW_TITLE", "Suggestion #%d: %s").
+	Set("SUGGESTION_VOTING_CLOSED", "Suggestion #%d has already been reviewed, voting is closed.").
+	Set("SUGGESTION_REVIEWED", "Your suggestion #%d has been **%s**: %s").
+	Set("COMMAND_SUGGEST_DISABLED", "Suggestions are not enabled in this server, set a channel with `%sconfig suggestions channel <channel>`").
+	Set("COMMAND_SUGGEST_POSTED", "Your suggestion #%d has been posted in <#%s>").
+	Set("COMMAND_SUGGESTION_NOT_FOUND", "There is no suggestion #%d.").
+	Set("COMMAND_SUGGESTION_NO_PERMISSION", "You need the staff role or the Manage Server permission to review suggestions.").
+	Set("COMMAND_SUGGESTION_REVIEWED", "Suggestion #%d has been **%s**.").
+	Set("COMMAND_SUGGESTION_USAGE", "Usage: `%ssuggestion <show|approve|deny> <id> [reason]`").
+	Set("TICKET_WELCOME", "%s thanks for opening a ticket, the staff will be with you shortly.\n%s\nUse the button below or `%sticket close [reason]` once you are done.").
+	Set("TICKET_LIMIT", "You can only have %s tickets open at once, close one first.").
+	Set("TICKET_TRANSCRIPT", "Ticket #%d opened by <@%s> was closed by <@%s>: %s").
+	Set("TICKET_NO_REASON", "No reason given.").
+	Set("TICKET_PANEL_TITLE", "Support").
+	Set("TICKET_PANEL", "Click the button below to open a ticket and talk to the staff privately.").
+	Set("TICKET_OPEN_BUTTON", "Open a ticket").
+	Set("TICKET_CLOSE_BUTTON", "Close ticket").
+	Set("COMMAND_TICKET_OPENED", "Your ticket has been opened in <#%s>").
+	Set("COMMAND_TICKET_NOT_TICKET", "This channel is not a ticket.").
+	Set("COMMAND_TICKET_NO_PERMISSION", "Only the ticket's owner and the staff can do that.").
+	Set("COMMAND_TICKET_PANEL_NO_PERMISSION", "You need the Manage Server permission to post a ticket panel.").
+	Set("COMMAND_TICKET_CLOSING", "Closing the ticket...").
+	Set("COMMAND_TICKET_USAGE", "Usage: `%[1]sticket open [subject]`, `%[1]sticket close [reason]`, `%[1]sticket <add|remove> <@user>` or `%[1]sticket panel [text]`").
+	Set("COMMAND_BULKROLE_USAGE", "Usage: `%sbulkrole <add|remove> <role> [--has=role] [--without=role] [--before=date] [--after=date] [--bots|--humans]`").
+	Set("COMMAND_BULKROLE_UNKNOWN_ROLE", "I can't find a role called **%s**.").
+	Set("COMMAND_BULKROLE_BOT_HIERARCHY", "**%s** is not below my highest role so I can't manage it.").
+	Set("COMMAND_BULKROLE_HIERARCHY", "**%s** is not below your highest role.").
+	Set("COMMAND_BULKROLE_INVALID_DATE", "`%s` is not a date I understand, try something like 2021-01-31.").
+	Set("COMMAND_BULKROLE_STARTED", "Changing **%s** for the matching members, this might take a while...").
+	Set("COMMAND_BULKROLE_PROGRESS", "Changing **%s**... %d/%d members done.").
+	Set("COMMAND_BULKROLE_DONE", "Done changing **%s**: %d changed, %d skipped, %d failed.").
+	Set("STATS_DASHBOARD", "%s Stats").
+	Set("STATS_UPDATED", "Last updated").
+	Set("STATS_MEMBERS", "Members").
+	Set("STATS_HUMANS", "Humans").
+	Set("STATS_BOTS", "Bots").
+	Set("STATS_ONLINE", "Online").
+	Set("STATS_CHANNELS", "Channels").
+	Set("STATS_ROLES", "Roles").
+	Set("STATS_BOOSTS", "Boosts").
+	Set("COMMAND_STATS_BOT_NO_PERMISSION", "I need the Manage Channels permission to create stat channels.").
+	Set("COMMAND_STATS_USAGE", "Usage: `%[1]sstatchannels add <template>`, `%[1]sstatchannels remove <channel ID>`, `%[1]sstatchannels list` or `%[1]sstatchannels dashboard`").
+	Set("COMMAND_STATS_ADDED", "Created the stat channel **%s**, it's updated a little after members join or leave.").
+	Set("COMMAND_STATS_NOT_STAT", "That is not a stat channel.").
+	Set("COMMAND_STATS_REMOVED", "That channel is no longer updated, you can delete it now.").
+	Set("COMMAND_STATS_NONE", "There are no stat channels, create one with `%sstatchannels add Members: {members}`").
+	Set("COMMAND_BRIDGE_USAGE", "Usage: `%[1]sbridge <create|delete|link|unlink> <name>`, `%[1]sbridge <bots|attachments> <name> <on|off>`, `%[1]sbridge <block|unblock> <name> <word>` or `%[1]sbridge list`").
+	Set("COMMAND_BRIDGE_NONE", "There are no bridges.").
+	Set("COMMAND_BRIDGE_UNKNOWN", "There is no bridge called **%s**.").
+	Set("COMMAND_BRIDGE_EXISTS", "There already is a bridge called **%s**.").
+	Set("COMMAND_BRIDGE_DELETED", "Deleted the bridge **%s**.").
+	Set("COMMAND_BRIDGE_NO_WEBHOOKS", "I need the Manage Webhooks permission in this channel to relay messages.").
+	Set("COMMAND_BRIDGE_LINKED", "This channel is already linked to **%s**.").
+	Set("COMMAND_BRIDGE_UPDATED", "Bridge **%s**: %d channels, bots: %t, attachments: %t, %d blocked words.").
+	Set("MODMAIL_OPENED", "New modmail").
+	Set("MODMAIL_USER", "User").
+	Set("MODMAIL_CREATED", "Account created").
+	Set("MODMAIL_JOINED", "Joined the server").
+	Set("MODMAIL_STAFF", "Staff").
+	Set("MODMAIL_ANONYMOUS", "%s (anonymous)").
+	Set("MODMAIL_REPLY", "**%s:** %s").
+	Set("MODMAIL_NO_REASON", "No reason given").
+	Set("MODMAIL_TRANSCRIPT", "Modmail of <@%s> closed by <@%s>: %s").
+	Set("COMMAND_MODMAIL_NOT_MODMAIL", "This command can only be used in a modmail channel.").
+	Set("COMMAND_MODMAIL_DM_FAILED", "I couldn't DM the user, they might have left or closed their DMs.").
+	Set("COMMAND_MODMAIL_CLOSING", "Closing this modmail...").
+	Set("COMMAND_MODMAIL_BLOCKED", "<@%s> can no longer use modmail.").
+	Set("COMMAND_MODMAIL_UNBLOCKED", "<@%s> can use modmail again.").
+	Set("COMMAND_SNIPPET_USAGE", "Usage: `%[1]ssnippet <name> [--anon]`, `%[1]ssnippet add <name> <content>`, `%[1]ssnippet remove <name>` or `%[1]ssnippet list`").
+	Set("COMMAND_SNIPPET_NONE", "There are no snippets, add one with `%ssnippet add <name> <content>`").
+	Set("COMMAND_SNIPPET_NOT_FOUND", "There is no snippet called **%s**.").
+	Set("COMMAND_TRANSLATE_NOTHING", "Give me some text to translate or reply to a message.").
+	Set("COMMAND_TRANSLATE_FAILED", "Couldn't translate that: %s").
+	Set("COMMAND_TRANSLATE_FOOTER", "%s → %s").
+	Set("COMMAND_READTEXT_DISABLED", "Reading text from images is not set up on this bot.").
+	Set("COMMAND_READTEXT_FAILED", "Couldn't read the image: %s").
+	Set("COMMAND_READTEXT_NONE", "I didn't find any text, attach an image or reply to a message with one.").
+	Set("AUTOMOD_WARN", "%s, your message was removed: %s").
+	Set("AUTOMOD_ALERT_TITLE", "Automod: %s").
+	Set("AUTOMOD_ALERT_USER", "User").
+	Set("AUTOMOD_ALERT_CHANNEL", "Channel").
+	Set("AUTOMOD_ALERT_REASON", "Reason").
+	Set("AUTOMOD_HEAT", "Too many violations in a short time (heat %d)").
+	Set("AUTOMOD_REGEX", "Matched a filtered pattern").
+	Set("COMMAND_REGEX_USAGE", "Usage: `%[1]sregex add <pattern>`, `%[1]sregex remove <pattern>` or `%[1]sregex list`").
+	Set("COMMAND_REGEX_NONE", "There are no filtered patterns, add one with `%sregex add <pattern>`").
+	Set("COMMAND_REGEX_INVALID", "That pattern can't be used: %s").
+	Set("COMMAND_REGEX_ADDED", "The pattern has been added to the filter.").
+	Set("COMMAND_REGEX_REMOVED", "The pattern has been removed from the filter.").
+	Set("COMMAND_REGEX_NOT_FOUND", "That pattern is not in the filter.").
+	Set("AUTOMOD_WORDS", "Contains a filtered word").
+	Set("COMMAND_WORDS_USAGE", "Usage: `%[1]swords add <words...>`, `%[1]swords remove <words...>` or `%[1]swords list`, use `--phrase` to add the words as one phrase").
+	Set("COMMAND_WORDS_NONE", "There are no filtered words, add some with `%swords add <words...>`").
+	Set("COMMAND_WORDS_ADDED", "The words have been added, the filter has **%d** words.").
+	Set("COMMAND_WORDS_REMOVED", "Removed **%d** words from the filter.").
+	Set("AUTOMOD_CUSTOM", "Broke the server rule %s").
+	Set("COMMAND_AUTOMODRULE_USAGE", "Usage: `%[1]sautomodrule add <name> <conditions> => <actions> [\"reason\"]`, `%[1]sautomodrule remove <name>` or `%[1]sautomodrule list`\nExample: `%[1]sautomodrule add invites content contains \"discord.gg\" and author.joined < 7 => delete|warn`").
+	Set("COMMAND_AUTOMODRULE_NONE", "There are no custom rules, add one with `%sautomodrule add <name> <rule>`").
+	Set("COMMAND_AUTOMODRULE_INVALID", "That rule can't be used: %s").
+	Set("COMMAND_AUTOMODRULE_SET", "The rule **%s** has been saved.").
+	Set("COMMAND_AUTOMODRULE_REMOVED", "The rule **%s** has been removed.").
+	Set("COMMAND_AUTOMODRULE_NOT_FOUND", "There is no rule called **%s**.").
+	Set("AUTOMOD_NATIVE", "Blocked by Discord's AutoMod").
+	Set("AUTOMOD_NATIVE_KEYWORD", "Blocked by Discord's AutoMod for `%s`").
+	Set("AUTOMOD_PHISHING", "Link to a known malicious site (%s)").
+	Set("COMMAND_BACKUP_USAGE", "Usage: `%[1]sbackup create [--structure]` or `%[1]sbackup restore [--structure] [--overwrite|--keep]` with the backup file attached").
+	Set("COMMAND_BACKUP_NO_PERMISSION", "You need the Manage Server permission to create backups.").
+	Set("COMMAND_BACKUP_RESTORE_NO_PERMISSION", "You need the Administrator permission to restore backups.").
+	Set("COMMAND_BACKUP_NO_FILE", "Attach the backup file or reply to the message with it.").
+	Set("COMMAND_BACKUP_INVALID", "That backup can't be restored: %s").
+	Set("COMMAND_BACKUP_CONFLICTS", "**%d** settings are already set to something else:\n%s\nRun `%sbackup restore` again with `--overwrite` to use the backup's values or `--keep` to keep the current ones.").
+	Set("COMMAND_BACKUP_RESTORED", "The backup has been restored, **%d** settings written, **%d** kept, **%d** roles and **%d** channels created.").
+	Set("COMMAND_IMPORT_USAGE", "Usage: `%simport <type>` with the exported file attached, the types are %s").
+	Set("COMMAND_IMPORT_NO_FILE", "Attach the exported file or reply to the message with it.").
+	Set("COMMAND_IMPORT_FAILED", "That file can't be imported: %s").
+	Set("COMMAND_IMPORT_DONE", "Imported **%d** entries, skipped **%d**.\n%s").
+	Set("COMMAND_SETUP_NOTHING", "There is nothing to set up.").
+	Set("COMMAND_SETUP_START", "Let's set up the bot, there are **%d** steps. Skip keeps the current value.").
+	Set("COMMAND_SETUP_CURRENT", "Current value").
+	Set("COMMAND_SETUP_ANSWER", "Enter a value").
+	Set("COMMAND_SETUP_SKIP", "Skip").
+	Set("COMMAND_SETUP_CANCEL", "Cancel").
+	Set("COMMAND_SETUP_MODAL_LABEL", "New value").
+	Set("COMMAND_SETUP_NOT_YOURS", "This setup belongs to <@%s>, use the setup command to start your own.").
+	Set("COMMAND_SETUP_CANCELLED", "Setup cancelled after changing **%d** settings.").
+	Set("COMMAND_SETUP_DONE", "Setup done, **%d** settings changed. Use the config command to change them later.").
+	Set("COMMAND_PERMISSIONS_NO_PERMISSION", "You need the Administrator permission to change command permissions.").
+	Set("COMMAND_PERMISSIONS_USAGE", "Usage: `%[1]spermissions <allow|deny|reset> <command|*> <role|user|channel> <target>` or `%[1]spermissions list [command]`").
+	Set("COMMAND_PERMISSIONS_UNKNOWN_COMMAND", "There is no command called **%s**.").
+	Set("COMMAND_PERMISSIONS_UNKNOWN_TARGET", "I can't find that %s: **%s**.").
+	Set("COMMAND_PERMISSIONS_ALLOWED", "`%s` is now allowed for %s.").
+	Set("COMMAND_PERMISSIONS_DENIED", "`%s` is now denied for %s.").
+	Set("COMMAND_PERMISSIONS_RESET", "Removed the override of `%s` for %s.").
+	Set("COMMAND_PERMISSIONS_NOT_FOUND", "There is no override of `%s` for %s.").
+	Set("COMMAND_PERMISSIONS_EMPTY", "There are no permission overrides, add one with `%spermissions allow <command> <role|user|channel> <target>`.").
+	Set("COMMAND_PERMISSIONS_TITLE", "Permission overrides").
+	Set("COMMAND_DEADLETTERS_USAGE", "Usage: `%[1]sdeadletters [list]`, `%[1]sdeadletters show <id>` or `%[1]sdeadletters <replay|drop> <id|all>`").
+	Set("COMMAND_DEADLETTERS_EMPTY", "There are no dead letters, nothing failed.").
+	Set("COMMAND_DEADLETTERS_TITLE", "Dead letters").
+	Set("COMMAND_DEADLETTERS_NOT_FOUND", "There is no dead letter with the ID **%s**").
+	Set("COMMAND_DEADLETTERS_REPLAYED", "Replayed **%d** dead letters, **%d** failed again and were kept.").
+	Set("COMMAND_DEADLETTERS_DROPPED", "Dropped the dead letters.").
+	Set("NUMBER_GROUP_SEPARATOR", ",").
+	Set("NUMBER_DECIMAL_SEPARATOR", ".").
+	Set("NUMBER_PERCENT", "%s%%").
+	Set("COMMAND_DIAGNOSE_OK", "No problems found.").
+	Set("COMMAND_DIAGNOSE_FINDINGS", "Found **%d** problems:\n%s").
+	Set("ACTIVITY_PLAYING", "Playing **%s**").
+	Set("ACTIVITY_STREAMING", "Streaming **%s** <%s>").
+	Set("ACTIVITY_LISTENING", "Listening to **%s**").
+	Set("ACTIVITY_WATCHING", "Watching **%s**").
+	Set("ACTIVITY_SPOTIFY", "Listening to **%s** by %s on Spotify").
+	Set("STATUS_ONLINE", "Online").
+	Set("STATUS_IDLE", "Idle").
+	Set("STATUS_DND", "Do Not Disturb").
+	Set("COMMAND_STATUS_STATUS", "**Status:** %s").
+	Set("COMMAND_STATUS_CUSTOM", "**Custom status:** %s").
+	Set("COMMAND_STATUS_OFFLINE", "**%s** is offline or I can't see what they're doing.").
+	Set("COMMAND_STATUS_UNAVAILABLE", "I can't see what members are doing, the bot doesn't receive presences.").
+	Set("STAGE_ANNOUNCEMENT", "🎙️ **%s** starts <t:%d:R> in <#%s>%s\n%s").
+	Set("COMMAND_VOICE_STATS", "You spent **%s** in voice and earned **%s** XP.").
+	Set("COMMAND_VOICE_NO_STATS", "Nobody has spent time in voice yet.").
+	Set("COMMAND_VOICE_LEADERBOARD", "Voice Leaderboard").
+	Set("COMMAND_VOICE_NO_PERMISSION", "You need the Manage Server permission to reset the voice stats.").
+	Set("COMMAND_VOICE_RESET", "The voice stats have been reset.").
+	Set("COMMAND_VOICE_USAGE", "Usage: `%svoice [top|reset]`").
+	Set("VOICE_ANNOUNCE_JOINED", "%s joined").
+	Set("VOICE_ANNOUNCE_LEFT", "%s left").
+	Set("STREAM_ALERT", "{role} **{name}** is now live!").
+	Set("STREAM_ALERT_GAME", "Streaming **%s**").
+	Set("COMMAND_STREAMALERTS_NONE", "No stream alerts are set up, add one with `%sstreamalerts add <provider> <channel> [role]`").
+	Set("COMMAND_STREAMALERTS_TITLE", "Stream Alerts").
+	Set("COMMAND_STREAMALERTS_NO_PERMISSION", "You need the Manage Server permission to change stream alerts.").
+	Set("COMMAND_STREAMALERTS_USAGE", "Usage: `%[1]sstreamalerts add <provider> <channel> [role]`, `%[1]sstreamalerts remove <provider> <channel>` or `%[1]sstreamalerts template <provider> <channel> [template]`").
+	Set("COMMAND_STREAMALERTS_UNKNOWN_PROVIDER", "Unknown provider **%s**, available are: %s").
+	Set("COMMAND_STREAMALERTS_UNKNOWN_ROLE", "Couldn't find the role **%s**.").
+	Set("COMMAND_STREAMALERTS_ADDED", "This channel will be notified when **%s** goes live on %s.").
+	Set("COMMAND_STREAMALERTS_NOT_FOUND", "This channel has no alert for **%s** on %s.").
+	Set("COMMAND_STREAMALERTS_REMOVED", "This channel won't be notified about **%s** on %s anymore.").
+	Set("COMMAND_STREAMALERTS_TEMPLATE", "Updated the message of the alert for **%s** on %s.").
+	Set("COMMAND_FEEDS_NONE", "No feeds are set up, add one with `%sfeeds add <name> <url>`").
+	Set("COMMAND_FEEDS_TITLE", "Feeds").
+	Set("COMMAND_FEEDS_NO_PERMISSION", "You need the Manage Server permission to change feeds.").
+	Set("COMMAND_FEEDS_USAGE", "Usage: `%[1]sfeeds add <name> <url>`, `%[1]sfeeds remove <name>` or `%[1]sfeeds template <name> [template]`").
+	Set("COMMAND_FEEDS_UNKNOWN", "There is no feed called **%s**.").
+	Set("COMMAND_FEEDS_INVALID", "Couldn't add that feed: %s").
+	Set("COMMAND_FEEDS_ADDED", "New entries of **%s** will be posted in this channel.").
+	Set("COMMAND_FEEDS_REMOVED", "Removed the feed **%s**.").
+	Set("COMMAND_FEEDS_TEMPLATE", "Updated the message of the feed **%s**.").
+	Set("GITHUB_PUSH", "[%s:%s] %d new commits").
+	Set("GITHUB_MORE_COMMITS", "and %d more").
+	Set("GITHUB_PR_OPENED", "[%s] Pull request opened: #%d %s").
+	Set("GITHUB_PR_REOPENED", "[%s] Pull request reopened: #%d %s").
+	Set("GITHUB_PR_MERGED", "[%s] Pull request merged: #%d %s").
+	Set("GITHUB_PR_CLOSED", "[%s] Pull request closed: #%d %s").
+	Set("GITHUB_ISSUE_OPENED", "[%s] Issue opened: #%d %s").
+	Set("GITHUB_ISSUE_REOPENED", "[%s] Issue reopened: #%d %s").
+	Set("GITHUB_ISSUE_CLOSED", "[%s] Issue closed: #%d %s").
+	Set("GITHUB_RELEASE", "[%s] New release: %s").
+	Set("COMMAND_GITHUB_NONE", "No repositories are posted here, add one with `%sgithub add <owner/repo> [events...]`").
+	Set("COMMAND_GITHUB_TITLE", "GitHub Repositories").
+	Set("COMMAND_GITHUB_NO_PERMISSION", "You need the Manage Server permission to change GitHub repositories.").
+	Set("COMMAND_GITHUB_USAGE", "Usage: `%[1]sgithub add <owner/repo> [events...]` or `%[1]sgithub remove <owner/repo>`, events are %[2]s").
+	Set("COMMAND_GITHUB_UNKNOWN_EVENT", "Unknown event **%s**, available are: %s").
+	Set("COMMAND_GITHUB_ADDED", "Events of **%s** will be posted in this channel, point the repository's webhook to the bot.").
+	Set("COMMAND_GITHUB_NOT_FOUND", "**%s** isn't posted in this channel.").
+	Set("COMMAND_GITHUB_REMOVED", "Events of **%s** won't be posted in this channel anymore.").
+	Set("COMMAND_INBOUND_NONE", "There are no inbound routes.").
+	Set("COMMAND_INBOUND_USAGE", "Usage: `%[1]sinbound create <name>`, `%[1]sinbound template <name> <field> [template]`, `%[1]sinbound token <name>` or `%[1]sinbound delete <name>`, fields are %[2]s").
+	Set("COMMAND_INBOUND_UNKNOWN", "There is no inbound route called **%s**.").
+	Set("COMMAND_INBOUND_EXISTS", "An inbound route called **%s** already exists.").
+	Set("COMMAND_INBOUND_DELETED", "Deleted the inbound route **%s**.").
+	Set("COMMAND_INBOUND_INVALID", "That template is invalid: %s").
+	Set("COMMAND_INBOUND_UPDATED", "Updated the inbound route **%s**.").
+	Set("COMMAND_INBOUND_TOKEN", "The token of the inbound route **%s** is `%s`, keep it secret.").
+	Set("COMMAND_INBOUND_NO_DM", "I couldn't DM you the token, allow DMs and run `token` to get a new one.").
+	Set("COMMAND_INBOUND_CREATED", "The inbound route **%s** posts in this channel, I sent you it's token in DMs.").
+	Set("COMMAND_DISABLED_GUILD", "This command has been disabled in this server.").
+	Set("COMMAND_SETPREFIX_SUCCESS", "The prefix of this server is now `%s`").
+	Set("COMMAND_SETPREFIX_RESET", "The prefix of this server was reset to `%s`").
+	Set("COMMAND_SETPREFIX_TOO_LONG", "The prefix can't be longer than %d characters.").
+	Set("COMMAND_SETLANGUAGE_LIST", "The language of this server is **%s**, available languages: %s").
+	Set("COMMAND_SETLANGUAGE_UNKNOWN", "There is no language called **%s**, available languages: %s").
+	Set("COMMAND_SETLANGUAGE_SUCCESS", "The language of this server is now **%s**").
+	Set("COMMAND_SETTINGS_GENERAL", "General").
+	Set("COMMAND_SETTINGS_PREFIX", "The prefix of commands in this server.").
+	Set("COMMAND_SETTINGS_LANGUAGE", "The language I reply in.").
+	Set("COMMAND_SETTINGS_PICK_SECTION", "Pick what to change.").
+	Set("COMMAND_SETTINGS_PICK_KEY", "Pick a setting to change.").
+	Set("COMMAND_SETTINGS_PICK_VALUE", "Pick the new value of **%s**.").
+	Set("COMMAND_SETTINGS_YES", "Yes").
+	Set("COMMAND_SETTINGS_NO", "No").
+	Set("COMMAND_SETTINGS_RESET", "Reset to default").
+	Set("COMMAND_SETTINGS_MODAL_TITLE", "Change %s").
+	Set("COMMAND_SETTINGS_MODAL_LABEL", "New value, leave it empty to reset it").
+	Set("COMMAND_SETTINGS_SET", "**%s** is now %s").
+	Set("COMMAND_SETTINGS_RESET_DONE", "**%s** was reset to the default.").
+	Set("COMMAND_SETTINGS_INVALID", "**%s** isn't a valid value.").
+	Set("COMMAND_SETTINGS_NOT_YOURS", "This menu belongs to <@%s>, use the settings command to get your own.").
+	Set("COMMAND_TOGGLE_PROTECTED", "The command **%s** can't be disabled.").
+	Set("COMMAND_TOGGLE_DISABLED", "Disabled the command **%s** in this server.").
+	Set("COMMAND_TOGGLE_ENABLED", "Enabled the command **%s** in this server.").
+	Set("COMMAND_LEVEL_TOO_LOW", "You need to be **%s** (level %d) to use this command.").
+	Set("COMMAND_USER_MISSING_PERMISSIONS", "You need the permissions **%s** in this channel to use this command.").
+	Set("COMMAND_BOT_MISSING_PERMISSIONS", "I need the permissions **%s** in this channel to run this command.").
+	Set("ARGUMENT_REQUIRED", "The argument **%s** is required.").
+	Set("ARGUMENT_USAGE", "Usage: `%s%s %s`").
+	Set("ARGUMENT_INT_INVALID", "**%s** must be a whole number.").
+	Set("ARGUMENT_FLOAT_INVALID", "**%s** must be a number.").
+	Set("ARGUMENT_BOOL_INVALID", "**%s** must be yes or no.").
+	Set("ARGUMENT_DURATION_INVALID", "**%s** must be a duration like 10m, 1h30m or 2d.").
+	Set("ARGUMENT_MEMBER_INVALID", "**%s** must be a valid member mention, ID or name.").
+	Set("ARGUMENT_MEMBER_NOT_FOUND", "That member cannot be found in this server.").
+	Set("ARGUMENT_USER_INVALID", "**%s** must be a valid user mention, ID or member name.").
+	Set("ARGUMENT_USER_NOT_FOUND", "That user cannot be found.").
+	Set("ARGUMENT_CHANNEL_INVALID", "**%s** must be a valid channel mention, ID or name.").
+	Set("ARGUMENT_CHANNEL_NOT_FOUND", "That channel cannot be found.").
+	Set("ARGUMENT_ROLE_INVALID", "**%s** must be a valid role mention, ID or name.").
+	Set("ARGUMENT_ROLE_NOT_FOUND", "That role cannot be found in this server.").
+	Set("ARGUMENT_ID_INVALID", "**%s** must be a valid ID or mention.").
+	Set("ARGUMENT_SIZE_INVALID", "**%s** must be a size like 8MB.").
+	Set("ARGUMENT_PERCENT_INVALID", "**%s** must be a percentage like 50%%.").
+	Set("ARGUMENT_LITERAL", "Literal argument must be **%s**").
+	Set("ARGUMENT_EMOJI_INVALID", "**%s** must be a custom emoji.").
+	Set("ARGUMENT_TOO_SMALL", "**%s** must be at least %s.").
+	Set("ARGUMENT_TOO_LARGE", "**%s** must be at most %s.").
+	Set("ARGUMENT_TOO_SMALL_LENGTH", "**%s** must be at least %s characters long.").
+	Set("ARGUMENT_TOO_LARGE_LENGTH", "**%s** must be at most %s characters long.").
+	Set("ARGUMENT_CHOOSE", "Multiple matches found for **%s**, reply with the number of the one you meant:\n%s").
+	Set("ARGUMENT_CHOICE_CANCELLED", "No valid choice was made for **%s**.").
+	Set("ARGUMENT_TOO_MANY_MATCHES", "**%s** has %d matches for **%s**, please be more specific.").
+	Set("COMMAND_CRON_USAGE", "Usage: `%[1]scron add <cron expression> <command> [args...]` or `%[1]scron remove <id>`").
+	Set("COMMAND_CRON_EMPTY", "There are no scheduled commands, add one with `%scron add`").
+	Set("COMMAND_CRON_INVALID", "Couldn't schedule that: %s").
+	Set("COMMAND_CRON_ADDED", "Scheduled as **%s**, it will first run on %s").
+	Set("COMMAND_CRON_REMOVED", "Removed the scheduled command **%s**").
+	Set("COMMAND_CRON_NOT_FOUND", "There is no scheduled command with the ID **%s**").
+	Set("GUILD_ONBOARDING", "Thanks for adding me! My prefix here is `%[1]s`, use `%[1]shelp` to see what I can do.")
@@ -0,0 +1,73 @@
+package sapphire
+
+import (
+	"testing"
+)
+
+func TestLanguageGetEmbed(t *testing.T) {
+	lang := NewLanguage("test").SetEmbed("COMMAND_INFO", &LocaleEmbed{
+		Title:       "Info for %[1]s",
+		Description: "%[1]s has %[2]d points.",
+		Fields:      []*LocaleEmbedField{{Name: "Points", Value: "%[2]d", Inline: true}},
+	})
+
+	if lang.GetEmbed("MISSING") != nil {
+		t.Error("Expected a missing key to return nil")
+	}
+
+	embed := lang.GetEmbed("COMMAND_INFO", "Alice", 5)
+	if embed.Title != "Info for Alice" {
+		t.Errorf("Unexpected title \"%s\"", embed.Title)
+	}
+	if embed.Description != "Alice has 5 points." {
+		t.Errorf("Unexpected description \"%s\"", embed.Description)
+	}
+	if len(embed.Fields) != 1 || embed.Fields[0].Value != "5" || !embed.Fields[0].Inline {
+		t.Error("Expected a single inline field with the value 5")
+	}
+}
+
+func TestLanguageGetEmbedStatic(t *testing.T) {
+	lang := NewLanguage("test").SetEmbed("COMMAND_STATS", &LocaleEmbed{
+		Title:       "Statistics",
+		Description: "Serving %d guilds at 100%%.",
+		Footer:      "100%% uptime",
+		Fields:      []*LocaleEmbedField{{Name: "Guilds", Value: "%d"}},
+	})
+
+	embed := lang.GetEmbed("COMMAND_STATS", 42)
+	if embed.Title != "Statistics" {
+		t.Errorf("Unexpected title \"%s\"", embed.Title)
+	}
+	if embed.Description != "Serving 42 guilds at 100%." {
+		t.Errorf("Unexpected description \"%s\"", embed.Description)
+	}
+	if embed.Footer == nil || embed.Footer.Text != "100% uptime" {
+		t.Errorf("Unexpected footer %+v", embed.Footer)
+	}
+	if len(embed.Fields) != 1 || embed.Fields[0].Name != "Guilds" || embed.Fields[0].Value != "42" {
+		t.Errorf("Unexpected fields %+v", embed.Fields)
+	}
+}
+
+func TestLanguageGetComponents(t *testing.T) {
+	lang := NewLanguage("test").SetEmbed("COMMAND_CONFIRM", &LocaleEmbed{
+		Title:      "Delete %[1]s?",
+		Components: []*Component{NewActionRow(NewButton("confirm:%[2]s", "Delete %[1]s", ButtonDanger), NewButton("cancel", "Cancel", ButtonSecondary))},
+	})
+
+	if lang.GetComponents("MISSING") != nil || NewLanguage("empty").SetEmbed("E", &LocaleEmbed{}).GetComponents("E") != nil {
+		t.Error("Expected no components for a missing key or an embed without them")
+	}
+
+	rows := lang.GetComponents("COMMAND_CONFIRM", "notes", "42")
+	if len(rows) != 1 || len(rows[0].Components) != 2 {
+		t.Fatalf("Expected a row of two buttons but got %+v", rows)
+	}
+	if button := rows[0].Components[0]; button.CustomID != "confirm:42" || button.Label != "Delete notes" {
+		t.Errorf("Expected the button to be formatted but got %+v", button)
+	}
+	if label := lang.Embeds["COMMAND_CONFIRM"].Components[0].Components[0].Label; label != "Delete %[1]s" {
+		t.Errorf("Expected the locale's components to be left alone but the label is %q", label)
+	}
+}
@@ -0,0 +1,75 @@
+package sapphire
+
+import (
+	"sort"
+)
+
+// PermissionLevel is a step of the bot's permission level ladder, see bot.AddPermissionLevel
+type PermissionLevel struct {
+	Level int
+	Name  string                         // Shown when a member's level is too low e.g "Moderator", can be a locale key.
+	Check func(ctx *CommandContext) bool // Wether the author has this level.
+}
+
+// AddPermissionLevel adds a step to the ladder, replacing the one with the same level. Everyone has level 0,
+// a member's level is the highest one whose check passes e.g
+//
+//	bot.AddPermissionLevel(5, "Moderator", sapphire.LevelRoles("Moderator", "Admin")).
+//	  AddPermissionLevel(8, "Administrator", sapphire.LevelPermissions(discordgo.PermissionAdministrator))
+func (bot *Bot) AddPermissionLevel(level int, name string, check func(ctx *CommandContext) bool) *Bot {
+	for i, l := range bot.PermissionLevels {
+		if l.Level == level {
+			bot.PermissionLevels[i] = PermissionLevel{level, name, check}
+			return bot
+		}
+	}
+	bot.PermissionLevels = append(bot.PermissionLevels, PermissionLevel{level, name, check})
+	sort.Slice(bot.PermissionLevels, func(i, j int) bool {
+		return bot.PermissionLevels[i].Level < bot.PermissionLevels[j].Level
+	})
+	return bot
+}
+
+// PermissionLevelOf returns the author's level, the highest level of the ladder whose check passes or 0.
+func (bot *Bot) PermissionLevelOf(ctx *CommandContext) int {
+	for i := len(bot.PermissionLevels) - 1; i >= 0; i-- {
+		if bot.PermissionLevels[i].Check(ctx) {
+			return bot.PermissionLevels[i].Level
+		}
+	}
+	return 0
+}
+
+// levelName returns the name of the lowest step that reaches level.
+func (bot *Bot) levelName(level int) string {
+	for _, l := range bot.PermissionLevels {
+		if l.Level >= level {
+			return l.Name
+		}
+	}
+	return ""
+}
+
+// LevelRoles is a level check passing for members with any of the roles, given by ID or name.
+func LevelRoles(roles ...string) func(ctx *CommandContext) bool {
+	return func(ctx *CommandContext) bool {
+		member := ctx.Member(ctx.Author.ID)
+		if member == nil {
+			return false
+		}
+		for _, value := range roles {
+			if role := findRole(ctx.Guild, value); role != nil && hasRole(member, role.ID) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// LevelPermissions is a level check passing for members with all of the permissions in the guild.
+func LevelPermissions(perms int) func(ctx *CommandContext) bool {
+	return func(ctx *CommandContext) bool {
+		member := ctx.Member(ctx.Author.ID)
+		return member != nil && PermissionsForMember(ctx.Guild, member).Has(perms)
+	}
+}
@@ -0,0 +1,28 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"testing"
+)
+
+func TestPermissionLevels(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	is := func(ids ...string) func(ctx *CommandContext) bool {
+		return func(ctx *CommandContext) bool { return containsString(ids, ctx.Author.ID) }
+	}
+	bot.AddPermissionLevel(8, "Administrator", is("admin")).
+		AddPermissionLevel(5, "Moderator", is("mod", "admin")).
+		AddPermissionLevel(5, "Moderator", is("mod"))
+	if len(bot.PermissionLevels) != 2 || bot.PermissionLevels[0].Level != 5 {
+		t.Errorf("Expected the ladder to be sorted without duplicates but got %+v", bot.PermissionLevels)
+	}
+	for id, want := range map[string]int{"member": 0, "mod": 5, "admin": 8} {
+		ctx := &CommandContext{Bot: bot, Author: &discordgo.User{ID: id}}
+		if got := bot.PermissionLevelOf(ctx); got != want {
+			t.Errorf("Expected %s to have level %d but got %d", id, want, got)
+		}
+	}
+	if name := bot.levelName(6); name != "Administrator" {
+		t.Errorf("Expected level 6 to need Administrator but got %s", name)
+	}
+}
@@ -0,0 +1,175 @@
+package sapphire
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrLockTimeout is returned when a lock couldn't be acquired in time.
+var ErrLockTimeout = errors.New("timed out waiting for the lock")
+
+// Locker serializes work on a key, unlock must be called once the work is done.
+// The default is an in-memory KeyedMutex, set a SQLLocker with bot.SetLocker when the bot runs as several processes.
+type Locker interface {
+	Lock(key string) (unlock func(), err error)
+}
+
+type keyedLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// KeyedMutex is a mutex per key, keys nobody holds or waits for don't use memory. The zero value is ready to use.
+type KeyedMutex struct {
+	locks map[string]*keyedLock
+	lock  sync.Mutex
+}
+
+// Lock implements Locker, it never fails.
+func (m *KeyedMutex) Lock(key string) (func(), error) {
+	m.lock.Lock()
+	if m.locks == nil {
+		m.locks = make(map[string]*keyedLock)
+	}
+	l, ok := m.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		m.locks[key] = l
+	}
+	l.refs++
+	m.lock.Unlock()
+
+	l.mu.Lock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Unlock()
+			m.lock.Lock()
+			l.refs--
+			if l.refs == 0 {
+				delete(m.locks, key)
+			}
+			m.lock.Unlock()
+		})
+	}, nil
+}
+
+// SQLLocker is a Locker shared by every process using the same database, e.g shards running as separate processes.
+// A lock expires after TTL so a crashed process doesn't hold it forever, keep the work under a lock shorter than that.
+type SQLLocker struct {
+	DB      *sql.DB
+	Dialect SQLDialect
+	TTL     time.Duration // How long a lock is held at most. (default: 30s)
+	Wait    time.Duration // How long Lock waits before returning ErrLockTimeout. (default: 10s)
+	Poll    time.Duration // How often a held lock is tried again. (default: 50ms)
+	owner   string
+	acquire *sql.Stmt
+	release *sql.Stmt
+	expire  *sql.Stmt
+}
+
+// NewSQLLocker creates the locks table if it doesn't exist and prepares the statements, table defaults to sapphire_locks.
+func NewSQLLocker(db *sql.DB, dialect SQLDialect, table string) (*SQLLocker, error) {
+	if table == "" {
+		table = "sapphire_locks"
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	l := &SQLLocker{DB: db, Dialect: dialect, TTL: 30 * time.Second, Wait: 10 * time.Second, Poll: 50 * time.Millisecond, owner: hex.EncodeToString(id)}
+	p := dialect.placeholder
+
+	_, err := db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	key TEXT NOT NULL PRIMARY KEY,
+	owner TEXT NOT NULL,
+	expires BIGINT NOT NULL
+)`, table))
+	if err != nil {
+		return nil, err
+	}
+	queries := []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&l.acquire, fmt.Sprintf("INSERT INTO %s (key, owner, expires) VALUES (%s, %s, %s) ON CONFLICT (key) DO NOTHING", table, p(1), p(2), p(3))},
+		{&l.release, fmt.Sprintf("DELETE FROM %s WHERE key = %s AND owner = %s", table, p(1), p(2))},
+		{&l.expire, fmt.Sprintf("DELETE FROM %s WHERE key = %s AND expires < %s", table, p(1), p(2))},
+	}
+	for _, q := range queries {
+		stmt, err := db.Prepare(q.query)
+		if err != nil {
+			l.Close()
+			return nil, err
+		}
+		*q.stmt = stmt
+	}
+	return l, nil
+}
+
+// Lock implements Locker
+func (l *SQLLocker) Lock(key string) (func(), error) {
+	// The owner is unique per acquisition so a late unlock can't release someone else's lock after it expired.
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	owner := l.owner + ":" + hex.EncodeToString(token)
+	deadline := time.Now().Add(l.Wait)
+	for {
+		now := time.Now()
+		if _, err := l.expire.Exec(key, now.UnixNano()); err != nil {
+			return nil, err
+		}
+		res, err := l.acquire.Exec(key, owner, now.Add(l.TTL).UnixNano())
+		if err != nil {
+			return nil, err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if n == 1 {
+			var once sync.Once
+			return func() {
+				once.Do(func() {
+					l.release.Exec(key, owner)
+				})
+			}, nil
+		}
+		if now.After(deadline) {
+			return nil, ErrLockTimeout
+		}
+		time.Sleep(l.Poll)
+	}
+}
+
+// Close closes the prepared statements, the *sql.DB is left open.
+func (l *SQLLocker) Close() error {
+	for _, stmt := range []*sql.Stmt{l.acquire, l.release, l.expire} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+	return nil
+}
+
+// SetLocker sets the Locker behind bot.Lock and bot.GuildLock, e.g a SQLLocker for multi-process deployments.
+func (bot *Bot) SetLocker(locker Locker) *Bot {
+	bot.Locker = locker
+	return bot
+}
+
+// Lock locks a key with the bot's Locker, modules use it to serialize changes e.g "tickets:<guild ID>".
+func (bot *Bot) Lock(key string) (func(), error) {
+	return bot.Locker.Lock(key)
+}
+
+// GuildLock locks a whole guild, for changes spanning several of it's settings.
+// Prefer bot.Lock with a narrower key when the work only touches one module.
+func (bot *Bot) GuildLock(guildID string) (func(), error) {
+	return bot.Locker.Lock("guild:" + guildID)
+}
@@ -0,0 +1,37 @@
+package sapphire
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestKeyedMutex(t *testing.T) {
+	m := &KeyedMutex{}
+	counter := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, _ := m.Lock("guild")
+			defer unlock()
+			current := counter
+			runtime.Gosched()
+			counter = current + 1
+		}()
+	}
+	wg.Wait()
+	if counter != 50 {
+		t.Errorf("Expected 50 serialized increments, got %d", counter)
+	}
+	// Other keys aren't blocked.
+	unlock, _ := m.Lock("a")
+	other, _ := m.Lock("b")
+	other()
+	unlock()
+	unlock()
+	if len(m.locks) != 0 {
+		t.Errorf("Expected unused keys to be removed, got %d", len(m.locks))
+	}
+}
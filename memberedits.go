@@ -0,0 +1,175 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"github.com/bwmarrin/discordgo"
+	"sync"
+	"time"
+)
+
+// MemberEdit is a change of a member's roles and nickname, see bot.EditMember
+type MemberEdit struct {
+	AddRoles    []string // Role IDs to add.
+	RemoveRoles []string // Role IDs to remove.
+	Nick        *string  // New nickname, "" resets it. (default: nil, keep it)
+}
+
+// merge applies a later edit on top of this one, the later edit wins where they disagree.
+func (e *MemberEdit) merge(other MemberEdit) {
+	for _, id := range other.AddRoles {
+		e.RemoveRoles = removeString(e.RemoveRoles, id)
+		if !containsString(e.AddRoles, id) {
+			e.AddRoles = append(e.AddRoles, id)
+		}
+	}
+	for _, id := range other.RemoveRoles {
+		e.AddRoles = removeString(e.AddRoles, id)
+		if !containsString(e.RemoveRoles, id) {
+			e.RemoveRoles = append(e.RemoveRoles, id)
+		}
+	}
+	if other.Nick != nil {
+		e.Nick = other.Nick
+	}
+}
+
+// Roles returns the roles a member with roles has after the edit.
+func (e *MemberEdit) Roles(roles []string) []string {
+	result := make([]string, 0, len(roles)+len(e.AddRoles))
+	for _, id := range roles {
+		if !containsString(e.RemoveRoles, id) {
+			result = append(result, id)
+		}
+	}
+	for _, id := range e.AddRoles {
+		if !containsString(result, id) {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func removeString(list []string, s string) []string {
+	for i, item := range list {
+		if item == s {
+			return append(list[:i], list[i+1:]...)
+		}
+	}
+	return list
+}
+
+type pendingMemberEdit struct {
+	edit    MemberEdit
+	waiters []chan error
+}
+
+type memberEditTracker struct {
+	pending map[string]*pendingMemberEdit // guildID:userID
+	next    map[string]time.Time          // When the next edit of a guild may be sent.
+	lock    sync.Mutex
+}
+
+// EditMember changes a member's roles and nickname, blocking until it's done.
+// Edits of the same member within bot.MemberEditWindow are coalesced into a single request
+// and requests of a guild are paced by bot.MemberEditDelay, so many role changes at once don't run into rate-limits.
+func (bot *Bot) EditMember(guildID, userID string, edit MemberEdit) error {
+	done := make(chan error, 1)
+	key := guildID + ":" + userID
+	tracker := bot.memberEdits
+	tracker.lock.Lock()
+	if pending, ok := tracker.pending[key]; ok {
+		pending.edit.merge(edit)
+		pending.waiters = append(pending.waiters, done)
+		tracker.lock.Unlock()
+		return <-done
+	}
+	pending := &pendingMemberEdit{waiters: []chan error{done}}
+	pending.edit.merge(edit)
+	tracker.pending[key] = pending
+	tracker.lock.Unlock()
+
+	go func() {
+		time.Sleep(bot.MemberEditWindow)
+		tracker.lock.Lock()
+		now := time.Now()
+		at := tracker.next[guildID]
+		if at.Before(now) {
+			at = now
+		}
+		tracker.next[guildID] = at.Add(bot.MemberEditDelay)
+		tracker.lock.Unlock()
+		time.Sleep(time.Until(at))
+
+		// Edits coming in from now on start a new batch.
+		tracker.lock.Lock()
+		delete(tracker.pending, key)
+		tracker.lock.Unlock()
+		err := bot.applyMemberEdit(guildID, userID, pending.edit)
+		for _, waiter := range pending.waiters {
+			waiter <- err
+		}
+	}()
+	return <-done
+}
+
+func (bot *Bot) applyMemberEdit(guildID, userID string, edit MemberEdit) error {
+	data := make(map[string]interface{})
+	if len(edit.AddRoles) > 0 || len(edit.RemoveRoles) > 0 {
+		member, err := bot.fetchMember(guildID, userID)
+		if err != nil {
+			return err
+		}
+		roles := edit.Roles(member.Roles)
+		if !sameRoles(roles, member.Roles) {
+			data["roles"] = roles
+		}
+	}
+	if edit.Nick != nil {
+		data["nick"] = *edit.Nick
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if !bot.SpendAPI(guildID, 1) {
+		return ErrAPIBudget
+	}
+	endpoint := discordgo.EndpointGuildMember(guildID, userID)
+	body, err := bot.Session.RequestWithBucketID("PATCH", endpoint, data, discordgo.EndpointGuildMember(guildID, ""))
+	if err != nil {
+		return err
+	}
+	// Update the state right away so the next batch starts from the new roles.
+	member := &discordgo.Member{}
+	if json.Unmarshal(body, member) == nil && member.User != nil {
+		member.GuildID = guildID
+		bot.Session.State.MemberAdd(member)
+	}
+	return nil
+}
+
+func sameRoles(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, id := range a {
+		if !containsString(b, id) {
+			return false
+		}
+	}
+	return true
+}
+
+// AddMemberRole adds a role to a member through bot.EditMember
+func (bot *Bot) AddMemberRole(guildID, userID, roleID string) error {
+	return bot.EditMember(guildID, userID, MemberEdit{AddRoles: []string{roleID}})
+}
+
+// RemoveMemberRole removes a role from a member through bot.EditMember
+func (bot *Bot) RemoveMemberRole(guildID, userID, roleID string) error {
+	return bot.EditMember(guildID, userID, MemberEdit{RemoveRoles: []string{roleID}})
+}
+
+// SetMemberNick changes a member's nickname through bot.EditMember, "" resets it.
+func (bot *Bot) SetMemberNick(guildID, userID, nick string) error {
+	return bot.EditMember(guildID, userID, MemberEdit{Nick: &nick})
+}
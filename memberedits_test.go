@@ -0,0 +1,19 @@
+package sapphire
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMemberEditMerge(t *testing.T) {
+	edit := MemberEdit{AddRoles: []string{"a", "b"}}
+	nick := "nick"
+	edit.merge(MemberEdit{RemoveRoles: []string{"b", "c"}})
+	edit.merge(MemberEdit{AddRoles: []string{"c"}, Nick: &nick})
+	if strings.Join(edit.AddRoles, " ") != "a c" || strings.Join(edit.RemoveRoles, " ") != "b" || edit.Nick != &nick {
+		t.Errorf("Unexpected merged edit %+v", edit)
+	}
+	if roles := edit.Roles([]string{"b", "d"}); strings.Join(roles, " ") != "d a c" {
+		t.Errorf("Unexpected roles after the edit %v", roles)
+	}
+}
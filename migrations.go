@@ -0,0 +1,125 @@
+package sapphire
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// MigrationFunc applies or reverts a migration step against a settings provider.
+type MigrationFunc func(p SettingsProvider) error
+
+// Migration is a single versioned step.
+type Migration struct {
+	Version int           // Version this step migrates to, must be unique and greater than 0.
+	Name    string        // Short description of the step.
+	Up      MigrationFunc // Applies the step.
+	Down    MigrationFunc // Reverts the step, nil if the step can't be reverted.
+}
+
+// Migrations runs versioned migration steps for a namespace, usually an extension's name.
+// The applied version is tracked in the provider's bot wide scope under "migrations.<namespace>"
+// so every extension can evolve it's own stored schema independently.
+type Migrations struct {
+	Namespace string
+	steps     []*Migration
+}
+
+// NewMigrations creates a migration runner for namespace.
+func NewMigrations(namespace string) *Migrations {
+	return &Migrations{Namespace: namespace}
+}
+
+// Add adds a migration step, steps can be added in any order, they are sorted by version.
+// Panics if the version is not positive or already added.
+func (m *Migrations) Add(version int, name string, up, down MigrationFunc) *Migrations {
+	if version <= 0 {
+		panic(fmt.Sprintf("Migration version must be greater than 0, got %d", version))
+	}
+	for _, step := range m.steps {
+		if step.Version == version {
+			panic(fmt.Sprintf("Migration version %d is already added to '%s'", version, m.Namespace))
+		}
+	}
+	m.steps = append(m.steps, &Migration{Version: version, Name: name, Up: up, Down: down})
+	sort.Slice(m.steps, func(i, j int) bool { return m.steps[i].Version < m.steps[j].Version })
+	return m
+}
+
+// Latest returns the highest version added, 0 if there are no steps.
+func (m *Migrations) Latest() int {
+	if len(m.steps) == 0 {
+		return 0
+	}
+	return m.steps[len(m.steps)-1].Version
+}
+
+func (m *Migrations) key() string {
+	return "migrations." + m.Namespace
+}
+
+// Version returns the currently applied version, 0 if no steps were applied yet.
+func (m *Migrations) Version(p SettingsProvider) (int, error) {
+	value, ok, err := p.Get("", m.key())
+	if err != nil || !ok {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
+
+func (m *Migrations) setVersion(p SettingsProvider, version int) error {
+	if version == 0 {
+		return p.Delete("", m.key())
+	}
+	return p.Set("", m.key(), strconv.Itoa(version))
+}
+
+// Migrate applies all pending steps up to the latest version.
+func (m *Migrations) Migrate(p SettingsProvider) error {
+	return m.MigrateTo(p, m.Latest())
+}
+
+// MigrateTo applies or reverts steps until the applied version is target.
+// The version is recorded after every step so a failure leaves the provider at the last successful step.
+func (m *Migrations) MigrateTo(p SettingsProvider, target int) error {
+	current, err := m.Version(p)
+	if err != nil {
+		return err
+	}
+
+	if target >= current {
+		for _, step := range m.steps {
+			if step.Version <= current || step.Version > target {
+				continue
+			}
+			if err := step.Up(p); err != nil {
+				return fmt.Errorf("migration %s %d (%s) failed: %v", m.Namespace, step.Version, step.Name, err)
+			}
+			if err := m.setVersion(p, step.Version); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i := len(m.steps) - 1; i >= 0; i-- {
+		step := m.steps[i]
+		if step.Version > current || step.Version <= target {
+			continue
+		}
+		if step.Down == nil {
+			return fmt.Errorf("migration %s %d (%s) cannot be reverted", m.Namespace, step.Version, step.Name)
+		}
+		if err := step.Down(p); err != nil {
+			return fmt.Errorf("reverting migration %s %d (%s) failed: %v", m.Namespace, step.Version, step.Name, err)
+		}
+		previous := 0
+		if i > 0 {
+			previous = m.steps[i-1].Version
+		}
+		if err := m.setVersion(p, previous); err != nil {
+			return err
+		}
+	}
+	return nil
+}
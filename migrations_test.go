@@ -0,0 +1,45 @@
+package sapphire
+
+import (
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	settings := NewMemorySettings()
+	settings.Set("1", "prefix", "?")
+
+	m := NewMigrations("test").
+		Add(2, "rename prefix", func(p SettingsProvider) error {
+			value, _, _ := p.Get("1", "prefix")
+			p.Delete("1", "prefix")
+			return p.Set("1", "core.prefix", value)
+		}, func(p SettingsProvider) error {
+			value, _, _ := p.Get("1", "core.prefix")
+			p.Delete("1", "core.prefix")
+			return p.Set("1", "prefix", value)
+		}).
+		Add(1, "seed", func(p SettingsProvider) error {
+			return p.Set("1", "seeded", "true")
+		}, nil)
+
+	if err := m.Migrate(settings); err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := m.Version(settings); version != 2 {
+		t.Errorf("Expected version 2 but got %d", version)
+	}
+	if value, _, _ := settings.Get("1", "core.prefix"); value != "?" {
+		t.Errorf("Expected core.prefix to be migrated but got \"%s\"", value)
+	}
+
+	if err := m.MigrateTo(settings, 1); err != nil {
+		t.Fatal(err)
+	}
+	if value, _, _ := settings.Get("1", "prefix"); value != "?" {
+		t.Errorf("Expected prefix to be restored but got \"%s\"", value)
+	}
+
+	if err := m.MigrateTo(settings, 0); err == nil {
+		t.Error("Expected reverting an irreversible step to fail")
+	}
+}
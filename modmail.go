@@ -0,0 +1,431 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ModmailConfig is the config of modmail in the staff guild, see bot.EnableModmail
+var ModmailConfig = NewConfigSchema("modmail", "Members DM the bot to talk to the staff.").
+	Add("category", ConfigChannel, "", "Category modmail channels are created in, staff permissions are inherited from it.").
+	Add("log", ConfigChannel, "", "Channel transcripts of closed modmails are sent to, leave empty to not keep transcripts.").
+	Add("format", ConfigString, "text", "Format of transcripts, text or html.").
+	Add("greeting", ConfigString, "Thanks for your message, the staff will get back to you soon.", "Sent to members when they open a modmail.").
+	Add("closing", ConfigString, "This conversation has been closed, DM me again if you need anything else.", "Sent to members when their modmail is closed.")
+
+// The settings keys of modmail, user threads and blocks are bot wide, channels and snippets belong to the staff guild.
+const (
+	modmailUserKeyPrefix    = "modmail.user."
+	modmailBlockedKeyPrefix = "modmail.blocked."
+	modmailThreadKeyPrefix  = "modmail.thread."
+	modmailSnippetsKey      = "modmail.snippets"
+)
+
+type modmailTracker struct {
+	guildID string
+	// Opening is check then create, a burst of DMs must not open two channels.
+	lock sync.Mutex
+}
+
+// EnableModmail lets members DM the bot to talk to the staff. Every member gets a channel in the staff guild
+// where their DMs are relayed through a webhook, staff answer with the reply commands and close it when done.
+// The bot needs Manage Channels and Manage Webhooks in the staff guild, configure it there with ModmailConfig.
+// Messages starting with the prefix are treated as commands and not relayed.
+func (bot *Bot) EnableModmail(staffGuildID string) *Bot {
+	if bot.modmail != nil {
+		return bot
+	}
+	bot.modmail = &modmailTracker{guildID: staffGuildID}
+	bot.AddConfigSchema(ModmailConfig)
+	bot.AddDataSubject(modmailData(bot))
+	bot.AddMonitor(NewMonitor("modmail", modmailMonitor))
+	bot.AddHandler(func(s *discordgo.Session, c *discordgo.ChannelDelete) {
+		if c.GuildID != staffGuildID {
+			return
+		}
+		if userID, ok, _ := bot.Settings.Get(staffGuildID, modmailThreadKeyPrefix+c.ID); ok {
+			bot.forgetModmail(userID, c.ID)
+		}
+	})
+
+	bot.AddCommand(NewCommand("reply", "Modmail", func(ctx *CommandContext) {
+		modmailReply(ctx, false)
+	}).SetDescription("Replies to the member of this modmail.").
+		SetUsage("<message:string...>").
+		AddAliases("r").
+		SetGuildOnly(true))
+	bot.AddCommand(NewCommand("areply", "Modmail", func(ctx *CommandContext) {
+		modmailReply(ctx, true)
+	}).SetDescription("Replies to the member of this modmail without showing your name.").
+		SetUsage("<message:string...>").
+		AddAliases("ar").
+		SetGuildOnly(true))
+	bot.AddCommand(NewCommand("snippet", "Modmail", modmailSnippetCommand).
+		SetDescription("Sends a saved reply to the member of this modmail, or manages them with add, remove and list.").
+		SetUsage("<name:string> [args:string...]").
+		AddAliases("s").
+		SetGuildOnly(true))
+	bot.AddCommand(NewCommand("close", "Modmail", modmailCloseCommand).
+		SetDescription("Closes this modmail, the transcript is saved to the log channel.").
+		SetUsage("[reason:string...]").
+		SetGuildOnly(true))
+	bot.AddCommand(NewCommand("mmblock", "Modmail", func(ctx *CommandContext) {
+		modmailBlockCommand(ctx, true)
+	}).SetDescription("Blocks a member from using modmail, defaults to the member of this modmail.").
+		SetUsage("[user:user]").
+		SetGuildOnly(true))
+	bot.AddCommand(NewCommand("mmunblock", "Modmail", func(ctx *CommandContext) {
+		modmailBlockCommand(ctx, false)
+	}).SetDescription("Unblocks a member from using modmail.").
+		SetUsage("<user:user>").
+		SetGuildOnly(true))
+	return bot
+}
+
+// BlockModmail blocks or unblocks a user from opening modmails, their messages are ignored while blocked.
+func (bot *Bot) BlockModmail(userID string, blocked bool) error {
+	if !blocked {
+		return bot.Settings.Delete("", modmailBlockedKeyPrefix+userID)
+	}
+	return bot.Settings.Set("", modmailBlockedKeyPrefix+userID, time.Now().UTC().Format(time.RFC3339))
+}
+
+// ModmailBlocked checks if a user is blocked from modmail.
+func (bot *Bot) ModmailBlocked(userID string) bool {
+	_, ok, err := bot.Settings.Get("", modmailBlockedKeyPrefix+userID)
+	return err == nil && ok
+}
+
+// modmailData is the data subject of the modmail threads and blocks.
+// Deleting forgets the user's open thread, it's channel is left to the staff to close.
+func modmailData(bot *Bot) DataSubject {
+	return &userData{
+		name: "modmail",
+		export: func(userID string) (interface{}, error) {
+			data := make(map[string]string)
+			if channelID, ok, err := bot.Settings.Get("", modmailUserKeyPrefix+userID); err != nil {
+				return nil, err
+			} else if ok {
+				data["channel_id"] = channelID
+			}
+			if since, ok, err := bot.Settings.Get("", modmailBlockedKeyPrefix+userID); err != nil {
+				return nil, err
+			} else if ok {
+				data["blocked_since"] = since
+			}
+			if len(data) == 0 {
+				return nil, nil
+			}
+			return data, nil
+		},
+		delete: func(userID string) error {
+			bot.modmail.lock.Lock()
+			defer bot.modmail.lock.Unlock()
+			if channelID := bot.ModmailChannel(userID); channelID != "" {
+				if err := bot.Settings.Delete(bot.modmail.guildID, modmailThreadKeyPrefix+channelID); err != nil {
+					return err
+				}
+				if err := bot.Settings.Delete("", modmailUserKeyPrefix+userID); err != nil {
+					return err
+				}
+			}
+			return bot.Settings.Delete("", modmailBlockedKeyPrefix+userID)
+		},
+	}
+}
+
+// ModmailChannel returns the channel of the user's open modmail, empty if they have none.
+func (bot *Bot) ModmailChannel(userID string) string {
+	id, ok, err := bot.Settings.Get("", modmailUserKeyPrefix+userID)
+	if err != nil || !ok {
+		return ""
+	}
+	return id
+}
+
+// ModmailUser returns the user a modmail channel belongs to, empty if it's not a modmail.
+func (bot *Bot) ModmailUser(channelID string) string {
+	id, ok, err := bot.Settings.Get(bot.modmail.guildID, modmailThreadKeyPrefix+channelID)
+	if err != nil || !ok {
+		return ""
+	}
+	return id
+}
+
+// ModmailSnippets returns the saved replies of the staff, by name.
+func (bot *Bot) ModmailSnippets() map[string]string {
+	snippets := make(map[string]string)
+	if _, err := GetJSON(bot.Settings, bot.modmail.guildID, modmailSnippetsKey, &snippets); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+	return snippets
+}
+
+// SetModmailSnippet saves a reply under name, an empty content removes it.
+func (bot *Bot) SetModmailSnippet(name, content string) error {
+	snippets := bot.ModmailSnippets()
+	if content == "" {
+		delete(snippets, name)
+	} else {
+		snippets[name] = content
+	}
+	return SetJSON(bot.Settings, bot.modmail.guildID, modmailSnippetsKey, snippets)
+}
+
+// openModmail returns the user's modmail channel, creating it if they have none.
+func (bot *Bot) openModmail(user *discordgo.User) (string, error) {
+	bot.modmail.lock.Lock()
+	defer bot.modmail.lock.Unlock()
+	if id := bot.ModmailChannel(user.ID); id != "" {
+		return id, nil
+	}
+
+	guildID := bot.modmail.guildID
+	channel, err := bot.Session.GuildChannelCreateComplex(guildID, discordgo.GuildChannelCreateData{
+		Name:     fmt.Sprintf("%s-%s", user.Username, user.Discriminator),
+		Type:     discordgo.ChannelTypeGuildText,
+		Topic:    fmt.Sprintf("Modmail of %s#%s (%s)", user.Username, user.Discriminator, user.ID),
+		ParentID: ModmailConfig.Get(bot, guildID, "category"),
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := bot.Settings.Set("", modmailUserKeyPrefix+user.ID, channel.ID); err != nil {
+		return "", err
+	}
+	if err := bot.Settings.Set(guildID, modmailThreadKeyPrefix+channel.ID, user.ID); err != nil {
+		return "", err
+	}
+
+	locale := bot.LocaleFor(guildID, channel.ID)
+	embed := NewEmbed().
+		SetAuthor(fmt.Sprintf("%s#%s", user.Username, user.Discriminator), user.AvatarURL("64")).
+		SetTitle(locale.Get("MODMAIL_OPENED")).
+		SetColor(bot.Color).
+		AddInlineField(locale.Get("MODMAIL_USER"), user.Mention()).
+		AddInlineField(locale.Get("MODMAIL_CREATED"), SnowflakeTime(user.ID).UTC().Format("2006-01-02")).
+		SetFooter("ID: " + user.ID)
+	if member, err := bot.Session.State.Member(guildID, user.ID); err == nil {
+		if joined, err := member.JoinedAt.Parse(); err == nil {
+			embed.AddInlineField(locale.Get("MODMAIL_JOINED"), joined.UTC().Format("2006-01-02"))
+		}
+	}
+	bot.Session.ChannelMessageSendEmbed(channel.ID, embed.Build())
+
+	if greeting := ModmailConfig.Get(bot, guildID, "greeting"); greeting != "" {
+		if dm, err := bot.Session.UserChannelCreate(user.ID); err == nil {
+			bot.Session.ChannelMessageSend(dm.ID, greeting)
+		}
+	}
+	return channel.ID, nil
+}
+
+// forgetModmail removes the mapping between a user and their modmail channel.
+func (bot *Bot) forgetModmail(userID, channelID string) {
+	if err := bot.Settings.Delete("", modmailUserKeyPrefix+userID); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+	if err := bot.Settings.Delete(bot.modmail.guildID, modmailThreadKeyPrefix+channelID); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+}
+
+// CloseModmail saves the transcript of a modmail to the log channel, tells the user and deletes the channel.
+func (bot *Bot) CloseModmail(channelID string, closer *discordgo.User, reason string) error {
+	guildID := bot.modmail.guildID
+	userID := bot.ModmailUser(channelID)
+	if userID == "" {
+		return fmt.Errorf("channel %s is not a modmail", channelID)
+	}
+	if logChannel := ModmailConfig.Get(bot, guildID, "log"); logChannel != "" {
+		format := TranscriptFormat(ModmailConfig.Get(bot, guildID, "format"))
+		transcript, err := bot.ExportTranscript(channelID, TranscriptOptions{Format: format})
+		if err != nil {
+			return err
+		}
+		locale := bot.LocaleFor(guildID, logChannel)
+		if reason == "" {
+			reason = locale.Get("MODMAIL_NO_REASON")
+		}
+		content := locale.Get("MODMAIL_TRANSCRIPT", userID, closer.ID, Escape(reason))
+		if _, err := bot.Session.ChannelFileSendWithMessage(logChannel, content, transcript.Name, strings.NewReader(transcript.Content)); err != nil {
+			return err
+		}
+	}
+	if closing := ModmailConfig.Get(bot, guildID, "closing"); closing != "" {
+		if dm, err := bot.Session.UserChannelCreate(userID); err == nil {
+			bot.Session.ChannelMessageSend(dm.ID, closing)
+		}
+	}
+	bot.forgetModmail(userID, channelID)
+	_, err := bot.Session.ChannelDelete(channelID)
+	return err
+}
+
+func modmailMonitor(bot *Bot, ctx *MonitorContext) {
+	if ctx.Message.GuildID != "" || bot.ModmailBlocked(ctx.Author.ID) {
+		return
+	}
+	if prefix := bot.Prefix(bot, ctx.Message, true); prefix != "" && strings.HasPrefix(ctx.Message.Content, prefix) {
+		return
+	}
+	channelID, err := bot.openModmail(ctx.Author)
+	if err != nil {
+		bot.ErrorHandler(bot, err)
+		ctx.Session.MessageReactionAdd(ctx.Channel.ID, ctx.Message.ID, "❌")
+		return
+	}
+	_, err = bot.SendWebhook(channelID, &discordgo.WebhookParams{
+		Content:   bridgeContent(ctx.Message, true),
+		Username:  ctx.Author.Username,
+		AvatarURL: ctx.Author.AvatarURL(""),
+	})
+	if err != nil {
+		bot.ErrorHandler(bot, err)
+		ctx.Session.MessageReactionAdd(ctx.Channel.ID, ctx.Message.ID, "❌")
+		return
+	}
+	ctx.Session.MessageReactionAdd(ctx.Channel.ID, ctx.Message.ID, "✅")
+}
+
+// modmailUser returns the user of the modmail the command was ran in, replying with an error if it's not one.
+func modmailUser(ctx *CommandContext) string {
+	if ctx.Bot.modmail == nil || ctx.Guild.ID != ctx.Bot.modmail.guildID {
+		ctx.ReplyLocale("COMMAND_MODMAIL_NOT_MODMAIL")
+		return ""
+	}
+	userID := ctx.Bot.ModmailUser(ctx.Channel.ID)
+	if userID == "" {
+		ctx.ReplyLocale("COMMAND_MODMAIL_NOT_MODMAIL")
+	}
+	return userID
+}
+
+// sendModmailReply sends a staff reply to the user and echoes it in the modmail channel.
+func sendModmailReply(ctx *CommandContext, userID, content string, anonymous bool) {
+	name := ctx.Author.Username
+	if ctx.Message.Member != nil && ctx.Message.Member.Nick != "" {
+		name = ctx.Message.Member.Nick
+	}
+	shown := name
+	avatar := ctx.Author.AvatarURL("")
+	if anonymous {
+		shown = ctx.Locale.Get("MODMAIL_STAFF")
+		avatar = ctx.Session.State.User.AvatarURL("")
+	}
+	dm, err := ctx.Session.UserChannelCreate(userID)
+	if err == nil {
+		_, err = ctx.Session.ChannelMessageSend(dm.ID, ctx.Locale.Get("MODMAIL_REPLY", shown, content))
+	}
+	if err != nil {
+		ctx.ReplyLocale("COMMAND_MODMAIL_DM_FAILED")
+		return
+	}
+	// Replace the command with the reply itself so the channel reads like the conversation.
+	if anonymous {
+		name = ctx.Locale.Get("MODMAIL_ANONYMOUS", name)
+	}
+	if _, err := ctx.Bot.SendWebhook(ctx.Channel.ID, &discordgo.WebhookParams{Content: content, Username: name, AvatarURL: avatar}); err != nil {
+		ctx.React("✅")
+		return
+	}
+	ctx.Session.ChannelMessageDelete(ctx.Channel.ID, ctx.Message.ID)
+}
+
+func modmailReply(ctx *CommandContext, anonymous bool) {
+	userID := modmailUser(ctx)
+	if userID == "" {
+		return
+	}
+	sendModmailReply(ctx, userID, ctx.ArgString(0), anonymous)
+}
+
+func modmailSnippetCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+	if bot.modmail == nil || ctx.Guild.ID != bot.modmail.guildID {
+		ctx.ReplyLocale("COMMAND_MODMAIL_NOT_MODMAIL")
+		return
+	}
+	name := strings.ToLower(ctx.Arg(0).AsString())
+	switch name {
+	case "list":
+		snippets := bot.ModmailSnippets()
+		if len(snippets) == 0 {
+			ctx.ReplyLocale("COMMAND_SNIPPET_NONE", ctx.Prefix)
+			return
+		}
+		names := make([]string, 0, len(snippets))
+		for name := range snippets {
+			names = append(names, "`"+name+"`")
+		}
+		sort.Strings(names)
+		ctx.Reply(strings.Join(names, ", "))
+		return
+	case "add", "remove":
+		args := strings.SplitN(ctx.ArgString(1), " ", 2)
+		snippet := strings.ToLower(args[0])
+		content := ""
+		if len(args) == 2 {
+			content = strings.TrimSpace(args[1])
+		}
+		if snippet == "" || name == "add" && content == "" {
+			ctx.ReplyLocale("COMMAND_SNIPPET_USAGE", ctx.Prefix)
+			return
+		}
+		if name == "remove" {
+			content = ""
+		}
+		if err := bot.SetModmailSnippet(snippet, content); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.React("✅")
+		return
+	}
+
+	content, ok := bot.ModmailSnippets()[name]
+	if !ok {
+		ctx.ReplyLocale("COMMAND_SNIPPET_NOT_FOUND", name)
+		return
+	}
+	if userID := modmailUser(ctx); userID != "" {
+		sendModmailReply(ctx, userID, content, ctx.HasFlag("anon"))
+	}
+}
+
+func modmailCloseCommand(ctx *CommandContext) {
+	if modmailUser(ctx) == "" {
+		return
+	}
+	ctx.ReplyLocale("COMMAND_MODMAIL_CLOSING")
+	if err := ctx.Bot.CloseModmail(ctx.Channel.ID, ctx.Author, ctx.ArgString(0)); err != nil {
+		ctx.Error(err)
+	}
+}
+
+func modmailBlockCommand(ctx *CommandContext, block bool) {
+	bot := ctx.Bot
+	if bot.modmail == nil || ctx.Guild.ID != bot.modmail.guildID {
+		ctx.ReplyLocale("COMMAND_MODMAIL_NOT_MODMAIL")
+		return
+	}
+	var userID string
+	if ctx.Arg(0).IsProvided() {
+		userID = ctx.Arg(0).AsUser().ID
+	} else if userID = modmailUser(ctx); userID == "" {
+		return
+	}
+	if err := bot.BlockModmail(userID, block); err != nil {
+		ctx.Error(err)
+		return
+	}
+	if block {
+		ctx.ReplyLocale("COMMAND_MODMAIL_BLOCKED", userID)
+		return
+	}
+	ctx.ReplyLocale("COMMAND_MODMAIL_UNBLOCKED", userID)
+}
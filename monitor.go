@@ -1,10 +1,14 @@
 package sapphire
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/bwmarrin/discordgo"
 	"regexp"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 type MonitorHandler func(bot *Bot, ctx *MonitorContext)
@@ -16,7 +20,7 @@ type Monitor struct {
 	GuildOnly      bool           // Wether this monitor should only run on guilds. (default: false)
 	IgnoreWebhooks bool           // Wether to ignore messages sent by webhooks (default: true)
 	IgnoreBots     bool           // Wether to ignore messages sent by bots (default: true)
-	IgnoreSelf     bool           // Wether to ignore the bot itself. (default: true)
+	IgnoreSelf     bool           // Wether to ignore the bot itself, including messages sent through its webhooks. (default: true)
 	IgnoreEdits    bool           // Wether to ignore edited messages. (default: true)
 }
 
@@ -81,6 +85,21 @@ func monitorHandler(bot *Bot, m *discordgo.Message, edit bool) {
 		}
 	}()
 
+	if bot.isIgnored(m) {
+		return
+	}
+
+	// Edits are keyed by their edit time so every real edit is handled once.
+	key := m.ID
+	if edit {
+		key += ":" + string(m.EditedTimestamp)
+	}
+	if !bot.dedup.firstSeen(key, time.Now()) {
+		return
+	}
+	// Messages we sent through our own webhooks are echoes of ourselves.
+	self := m.Author.ID == bot.Session.State.User.ID || m.WebhookID != "" && bot.IsOwnWebhook(m.WebhookID)
+
 	for _, monitor := range bot.Monitors {
 		if !monitor.Enabled {
 			continue
@@ -103,7 +122,7 @@ func monitorHandler(bot *Bot, m *discordgo.Message, edit bool) {
 			continue
 		}
 
-		if m.Author.ID == bot.Session.State.User.ID && monitor.IgnoreSelf {
+		if self && monitor.IgnoreSelf {
 			continue
 		}
 
@@ -120,7 +139,7 @@ func monitorHandler(bot *Bot, m *discordgo.Message, edit bool) {
 			continue
 		}
 
-		go monitor.Run(bot, &MonitorContext{
+		go bot.runMonitor(monitor, &MonitorContext{
 			Session: bot.Session,
 			Message: m,
 			Author:  m.Author,
@@ -132,6 +151,35 @@ func monitorHandler(bot *Bot, m *discordgo.Message, edit bool) {
 	}
 }
 
+// isIgnored checks the message against the bot's ignore lists.
+func (bot *Bot) isIgnored(m *discordgo.Message) bool {
+	if len(bot.IgnoreChannels) == 0 && len(bot.IgnoreCategories) == 0 && len(bot.IgnoreRoles) == 0 {
+		return false
+	}
+	for _, id := range bot.IgnoreChannels {
+		if id == m.ChannelID {
+			return true
+		}
+	}
+	if len(bot.IgnoreCategories) > 0 {
+		if channel, err := bot.Session.State.Channel(m.ChannelID); err == nil && channel.ParentID != "" {
+			for _, id := range bot.IgnoreCategories {
+				if id == channel.ParentID {
+					return true
+				}
+			}
+		}
+	}
+	if m.Member != nil {
+		for _, id := range bot.IgnoreRoles {
+			if hasRole(m.Member, id) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func monitorListener(bot *Bot) func(s *discordgo.Session, m *discordgo.MessageCreate) {
 	return func(s *discordgo.Session, m *discordgo.MessageCreate) {
 		monitorHandler(bot, m.Message, false)
@@ -147,7 +195,32 @@ func monitorEditListener(bot *Bot) func(s *discordgo.Session, m *discordgo.Messa
 // The regexp used to parse command flags.
 // Taken from Klasa https://github.com/dirigeants/klasa
 var flagsRegex = regexp.MustCompile("(?:--|—)(\\w[\\w-]+)(?:=(?:[\"]((?:[^\"\\\\]|\\\\.)*)[\"]|[']((?:[^'\\\\]|\\\\.)*)[']|[“”]((?:[^“”\\\\]|\\\\.)*)[“”]|[‘’]((?:[^‘’\\\\]|\\\\.)*)[‘’]|([\\w-]+)))?")
-var delim = regexp.MustCompile("(\\s)(?:\\s)+")
+
+// splitArgs splits content by spaces ignoring empty arguments.
+// It also returns the byte offset of each argument in content.
+func splitArgs(content string) ([]string, []int) {
+	var args []string
+	var offsets []int
+	start := 0
+	for i := 0; i <= len(content); i++ {
+		if i < len(content) && content[i] != ' ' {
+			continue
+		}
+		part := content[start:i]
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			args = append(args, trimmed)
+			offsets = append(offsets, start+strings.Index(part, trimmed))
+		}
+		start = i + 1
+	}
+	return args, offsets
+}
+
+// ErrCommandInhibited is returned by ExecuteCommand when a validation stopped the command, the user was already told why.
+var ErrCommandInhibited = errors.New("command execution was inhibited")
+
+// ErrCommandNotFound is returned by ctx.Invoke when there's no command with the given name.
+var ErrCommandNotFound = errors.New("command not found")
 
 // This is the builtin monitor responsible for running commands.
 func CommandHandlerMonitor(bot *Bot, ctx *MonitorContext) {
@@ -174,10 +247,77 @@ func CommandHandlerMonitor(bot *Bot, ctx *MonitorContext) {
 		}
 	}
 
+	// Everything after the prefix exactly as the user typed it.
+	raw := ctx.Message.Content[len(prefix):]
+
+	segments := []string{raw}
+	if bot.ChainSeparator != "" {
+		segments = bot.splitChain(raw, prefix)
+	}
+
+	cctx := newCommandContext(bot, ctx, prefix, segments[0])
+	if cctx == nil {
+		return
+	}
+
+	lang := bot.Language(bot, ctx.Message, ctx.Channel.Type == discordgo.ChannelTypeDM)
+	locale, ok := bot.Languages[lang]
+
+	// Shouldn't happen unless the user made a mistake returning an invalid string, let's help them find the problem.
+	if !ok {
+		fmt.Printf("WARNING: bot.Language handler returned a non-existent language '%s' (command execution aborted)\n", lang)
+		return
+	}
+
+	// Set the context's locale.
+	cctx.Locale = locale
+
+	if bot.ChainSeparator != "" && bot.MaxChain > 0 && len(segments) > bot.MaxChain {
+		cctx.ReplyLocale("COMMAND_CHAIN_TOO_LONG", bot.MaxChain)
+		return
+	}
+
+	if bot.ExecuteCommand(cctx, true) != nil {
+		return
+	}
+
+	// Chained commands run one after another, like a shell's && the chain stops at the first command that didn't run.
+	for _, segment := range segments[1:] {
+		next := newCommandContext(bot, ctx, prefix, segment)
+		if next == nil {
+			cctx.ReplyLocale("COMMAND_CHAIN_UNKNOWN", segment)
+			return
+		}
+		next.Locale = locale
+		next.Shared = cctx.Shared
+		next.invoked = true
+		if bot.ExecuteCommand(next, true) != nil {
+			return
+		}
+	}
+}
+
+// splitChain splits raw into the chained command segments, each chained command may repeat the prefix.
+func (bot *Bot) splitChain(raw, prefix string) []string {
+	parts := strings.Split(raw, bot.ChainSeparator)
+	segments := []string{parts[0]}
+	for _, part := range parts[1:] {
+		part = strings.TrimPrefix(strings.TrimSpace(part), prefix)
+		if strings.TrimSpace(part) != "" {
+			segments = append(segments, part)
+		}
+	}
+	return segments
+}
+
+// newCommandContext parses raw (the content after the prefix) into a command context.
+// Returns nil if raw doesn't start with a command, the locale is left for the caller to fill.
+func newCommandContext(bot *Bot, ctx *MonitorContext, prefix, raw string) *CommandContext {
 	// Parsing flags
-	// It fills the flags maps and strips them out of the original content.
+	// It fills the flags maps and blanks them out of the content, blanking instead of removing
+	// keeps the byte offsets of the remaining arguments intact.
 	flags := make(map[string]string)
-	content := strings.Trim(delim.ReplaceAllString(flagsRegex.ReplaceAllStringFunc(ctx.Message.Content, func(m string) string {
+	content := flagsRegex.ReplaceAllStringFunc(raw, func(m string) string {
 		sub := flagsRegex.FindStringSubmatch(m)
 		for _, elem := range sub[2:] {
 			if elem != "" {
@@ -187,30 +327,32 @@ func CommandHandlerMonitor(bot *Bot, ctx *MonitorContext) {
 				flags[sub[1]] = sub[1]
 			}
 		}
-		return ""
-	}), "$1"), " ")
+		return strings.Repeat(" ", len(m))
+	})
 
-	split := strings.Split(content[len(prefix):], " ")
+	split, offsets := splitArgs(content)
 
 	if len(split) < 1 {
-		return
+		return nil
 	}
 
 	input := strings.ToLower(split[0])
 	var args []string
+	var argOffsets []int
 
 	if len(split) > 1 {
 		args = split[1:]
+		argOffsets = offsets[1:]
 	}
 
 	cmd := bot.GetCommand(input)
 	if cmd == nil {
-		return
+		return nil
 	}
 
 	// Start constructing a context early so we can call reply and apply the editing rules.
 	// Thanks to monitors most of our fields are filled in our monitor context already so we just redirect them.
-	cctx := &CommandContext{
+	return &CommandContext{
 		Bot:         bot,
 		Command:     cmd,
 		Message:     ctx.Message,
@@ -218,63 +360,123 @@ func CommandHandlerMonitor(bot *Bot, ctx *MonitorContext) {
 		Session:     ctx.Session,
 		Author:      ctx.Author,
 		RawArgs:     args,
+		RawContent:  raw,
+		ArgOffsets:  argOffsets,
 		Prefix:      prefix,
 		Guild:       ctx.Guild,
 		Flags:       flags,
 		InvokedName: input,
+		Shared:      make(map[string]interface{}),
 	}
+}
 
-	lang := bot.Language(bot, ctx.Message, ctx.Channel.Type == discordgo.ChannelTypeDM)
-	locale, ok := bot.Languages[lang]
-
-	// Shouldn't happen unless the user made a mistake returning an invalid string, let's help them find the problem.
-	if !ok {
-		fmt.Printf("WARNING: bot.Language handler returned a non-existent language '%s' (command execution aborted)\n", lang)
-		return
+// ExecuteCommand runs the command in ctx through the inhibitors, which parse the arguments and optionally take the cooldown, then runs it.
+// ctx must have it's Command, RawArgs and Locale filled in. Failed inhibitors reply to the user.
+// Returns nil if the command ran, ErrCommandInhibited if an inhibitor stopped it or a *CommandError if it panicked.
+// This is used by the command handler and ctx.Invoke, it shouldn't be needed in normal code.
+func (bot *Bot) ExecuteCommand(cctx *CommandContext, cooldowns bool) (result error) {
+	cmd := cctx.Command
+	cctx.cooldowns = cooldowns
+	atomic.StoreInt32(&cctx.reported, 0)
+	defer bot.autoDefer(cctx)()
+	if bot.inhibited(cctx) {
+		return ErrCommandInhibited
 	}
 
-	// Set the context's locale.
-	cctx.Locale = locale
-
-	// Validations.
-	if !cmd.Enabled {
-		cctx.ReplyLocale("COMMAND_DISABLED")
-		return
+	// Interactions show "thinking..." when deferred, typing would be a second indicator.
+	if bot.CommandTyping && cctx.Interaction == nil {
+		cctx.Session.ChannelTyping(cctx.Channel.ID)
 	}
 
-	if cmd.OwnerOnly && ctx.Author.ID != bot.OwnerID {
-		cctx.ReplyLocale("COMMAND_OWNER_ONLY")
-		return
-	}
+	bot.Counters.Inc(CounterCommands)
 
-	if cmd.GuildOnly && ctx.Message.GuildID == "" {
-		cctx.ReplyLocale("COMMAND_GUILD_ONLY")
-		return
+	if cctx.Context == nil {
+		cctx.Context = context.Background()
 	}
-
-	// If parse args failed it returns false
-	// We don't need to reply since ParseArgs already reports the appropriate error before returning.
-	if !cctx.ParseArgs() {
-		return
+	if cmd.Timeout <= 0 {
+		return bot.runCommand(cctx)
 	}
 
-	if bot.CommandTyping {
-		ctx.Session.ChannelTyping(ctx.Message.ChannelID)
+	start := time.Now()
+	c, cancel := context.WithTimeout(cctx.Context, cmd.Timeout)
+	defer cancel()
+	cctx.Context = c
+	done := make(chan error, 1)
+	go func() {
+		done <- bot.runCommand(cctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-c.Done():
+		// The handler keeps running until it notices, we just stop waiting for it.
+		// If it finished right as the timeout hit it already recorded it's result.
+		if !cctx.claimResult() {
+			return <-done
+		}
+		cctx.ReplyLocale("COMMAND_TIMEOUT")
+		cerr := &CommandError{Err: fmt.Sprintf("timed out after %s", time.Since(start).Round(time.Millisecond)), Context: cctx}
+		bot.Counters.Inc(CounterCommandErrors)
+		bot.ErrorHandler(bot, cerr)
+		bot.commandFailed(cmd.Name, cerr)
+		bot.Publish(&CommandRan{GuildID: cctx.Message.GuildID, ChannelID: cctx.Channel.ID, UserID: cctx.Author.ID,
+			Command: cmd.Name, Duration: time.Since(start), Error: fmt.Sprint(cerr.Err), FirstResponse: cctx.firstResponse()})
+		return cerr
 	}
+}
 
-	canRun, after := bot.CheckCooldown(ctx.Author.ID, cmd.Name, cmd.Cooldown)
-	if !canRun {
-		cctx.ReplyLocale("COMMAND_COOLDOWN", after)
-		return
+// autoDefer defers an interaction command that didn't answer within bot.DeferAfter of it's arrival so Discord doesn't
+// give up on it. The returned func stops waiting.
+func (bot *Bot) autoDefer(cctx *CommandContext) func() bool {
+	r, ok := cctx.Response.(*interactionResponse)
+	if !ok || bot.DeferAfter <= 0 {
+		return func() bool { return false }
 	}
+	return time.AfterFunc(bot.DeferAfter-time.Since(r.received), func() {
+		deferred, err := r.deferPending()
+		if err != nil {
+			bot.ErrorHandler(bot, err)
+			return
+		}
+		if deferred {
+			bot.Counters.Inc(CounterInteractionsDeferred)
+		}
+	}).Stop
+}
 
-	bot.CommandsRan++
+// claimResult returns true the first time it's called for an execution, only that caller records the result.
+func (cctx *CommandContext) claimResult() bool {
+	return atomic.CompareAndSwapInt32(&cctx.reported, 0, 1)
+}
 
+// runCommand runs the command's handler, recovering a panic into a *CommandError.
+func (bot *Bot) runCommand(cctx *CommandContext) (result error) {
+	start := time.Now()
 	defer func() {
-		if err := recover(); err != nil {
-			bot.ErrorHandler(bot, &CommandError{Err: err, Context: cctx})
+		err := recover()
+		if !cctx.claimResult() {
+			// Timed out, ExecuteCommand already recorded the failure.
+			if err != nil {
+				result = &CommandError{Err: err, Context: cctx}
+			}
+			return
+		}
+		ran := &CommandRan{GuildID: cctx.Message.GuildID, ChannelID: cctx.Channel.ID, UserID: cctx.Author.ID, Command: cctx.Command.Name}
+		if err != nil {
+			cerr := &CommandError{Err: err, Context: cctx}
+			bot.Counters.Inc(CounterCommandErrors)
+			bot.ErrorHandler(bot, cerr)
+			bot.commandFailed(cctx.Command.Name, cerr)
+			result = cerr
+			ran.Error = fmt.Sprint(err)
+		} else {
+			bot.commandSucceeded(cctx.Command.Name)
 		}
+		ran.Duration = time.Since(start)
+		ran.FirstResponse = cctx.firstResponse()
+		bot.Publish(ran)
 	}()
 
-	cmd.Run(cctx)
+	cctx.Command.Run(cctx)
+	return nil
 }
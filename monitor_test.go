@@ -0,0 +1,113 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"testing"
+	"time"
+)
+
+func TestSplitArgs(t *testing.T) {
+	content := "ban  <@1234>\tnow   spamming\nlinks "
+	args, offsets := splitArgs(content)
+	expect := []string{"ban", "<@1234>\tnow", "spamming\nlinks"}
+	if len(args) != len(expect) {
+		t.Fatalf("Expected %d arguments but got %d: %q", len(expect), len(args), args)
+	}
+	for i, arg := range args {
+		if arg != expect[i] {
+			t.Errorf("Expected argument %d to be %q but got %q", i, expect[i], arg)
+		}
+		if content[offsets[i]:offsets[i]+len(arg)] != arg {
+			t.Errorf("Offset %d for argument %q points to the wrong place", offsets[i], arg)
+		}
+	}
+}
+
+func TestIgnoreLists(t *testing.T) {
+	bot := (&Bot{}).IgnoreChannel("logs").IgnoreRole("staff")
+	if !bot.isIgnored(&discordgo.Message{ChannelID: "logs"}) {
+		t.Error("Expected messages in an ignored channel to be ignored")
+	}
+	if !bot.isIgnored(&discordgo.Message{ChannelID: "general", Member: &discordgo.Member{Roles: []string{"member", "staff"}}}) {
+		t.Error("Expected messages from members with an ignored role to be ignored")
+	}
+	if bot.isIgnored(&discordgo.Message{ChannelID: "general", Member: &discordgo.Member{Roles: []string{"member"}}}) {
+		t.Error("Expected other messages not to be ignored")
+	}
+}
+
+func TestCommandTimeout(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	bot.CommandTyping = false
+	calls := recordREST(bot)
+	finished := make(chan struct{})
+	cmd := NewCommand("slow", "General", func(ctx *CommandContext) {
+		time.Sleep(60 * time.Millisecond)
+		close(finished)
+		panic("late failure")
+	}).SetTimeout(20 * time.Millisecond).SetEditable(false)
+	bot.AddCommand(cmd)
+	events := make(chan *CommandRan, 4)
+	bot.Subscribe(TopicCommandRan, func(event BusEvent) {
+		events <- event.(*CommandRan)
+	})
+
+	ctx := &CommandContext{Bot: bot, Command: cmd, Session: bot.Session, Message: &discordgo.Message{},
+		Channel: &discordgo.Channel{ID: "c"}, Author: &discordgo.User{ID: "u"}, Locale: bot.DefaultLocale}
+	if _, ok := bot.ExecuteCommand(ctx, false).(*CommandError); !ok {
+		t.Fatal("Expected the command to time out")
+	}
+	if requests := calls(); len(requests) != 1 || requests[0].Endpoint != "channels/c/messages" {
+		t.Errorf("Expected the timeout to be answered in the channel but got %+v", requests)
+	}
+	<-finished
+	time.Sleep(20 * time.Millisecond)
+	if n := bot.Counters.Get(CounterCommandErrors); n != 1 {
+		t.Errorf("Expected the timeout to be counted once but got %d errors", n)
+	}
+	select {
+	case ran := <-events:
+		if ran.Error == "" {
+			t.Error("Expected the published run to have the timeout error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a command ran event")
+	}
+	select {
+	case ran := <-events:
+		t.Errorf("Expected a single command ran event but got another %+v", ran)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestChainLimit(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	bot.CommandTyping = false
+	calls := recordREST(bot)
+	runs := 0
+	bot.AddCommand(NewCommand("ping", "General", func(ctx *CommandContext) { runs++ }).SetEditable(false))
+	run := func(content string) {
+		msg := &discordgo.Message{Content: content, Author: &discordgo.User{ID: "u"}}
+		CommandHandlerMonitor(bot, &MonitorContext{Message: msg, Channel: &discordgo.Channel{ID: "c"}, Session: bot.Session,
+			Author: msg.Author, Bot: bot})
+	}
+
+	bot.SetChaining("", 0)
+	run("!ping")
+	if runs != 1 {
+		t.Errorf("Expected the command to run with chaining disabled but it ran %d times", runs)
+	}
+	bot.SetChaining("&&", 2)
+	run("!ping && ping && ping")
+	if runs != 1 {
+		t.Errorf("Expected a chain over the limit to be rejected but it ran %d times", runs)
+	}
+	if requests := calls(); len(requests) != 1 || requests[0].Endpoint != "channels/c/messages" {
+		t.Errorf("Expected the rejected chain to be answered in the channel but got %+v", requests)
+	}
+	bot.SetChaining("&&", 0)
+	run("!ping && ping && ping")
+	if runs != 4 {
+		t.Errorf("Expected an unlimited chain to run every command but it ran %d times", runs)
+	}
+}
@@ -0,0 +1,292 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"github.com/bwmarrin/discordgo"
+	"time"
+)
+
+// AutoModerationActionExecution is the gateway event Discord sends when one of it's AutoMod rules acts.
+const AutoModerationActionExecution = "AUTO_MODERATION_ACTION_EXECUTION"
+
+// IntentsAutoModerationExecution is the gateway intent needed to receive AutoModerationActionExecution
+// The bundled discordgo doesn't know about AutoMod yet.
+const IntentsAutoModerationExecution discordgo.Intent = 1 << 21
+
+// Trigger types of native AutoMod rules.
+const (
+	NativeTriggerKeyword       = 1
+	NativeTriggerSpam          = 3
+	NativeTriggerKeywordPreset = 4
+	NativeTriggerMentionSpam   = 5
+)
+
+// Action types of native AutoMod rules.
+const (
+	NativeActionBlockMessage     = 1
+	NativeActionSendAlertMessage = 2
+	NativeActionTimeout          = 3
+)
+
+// Limits of native keyword rules.
+const (
+	MaxNativeKeywords      = 1000
+	MaxNativeRegexPatterns = 10
+	MaxNativeRegexLength   = 260
+)
+
+// NativeAutomodRule is one of Discord's built-in AutoMod rules.
+type NativeAutomodRule struct {
+	ID              string                 `json:"id,omitempty"`
+	GuildID         string                 `json:"guild_id,omitempty"`
+	Name            string                 `json:"name"`
+	CreatorID       string                 `json:"creator_id,omitempty"`
+	EventType       int                    `json:"event_type"` // 1 for messages.
+	TriggerType     int                    `json:"trigger_type"`
+	TriggerMetadata *NativeTriggerMetadata `json:"trigger_metadata,omitempty"`
+	Actions         []*NativeAutomodAction `json:"actions"`
+	Enabled         bool                   `json:"enabled"`
+	ExemptRoles     []string               `json:"exempt_roles"`
+	ExemptChannels  []string               `json:"exempt_channels"`
+}
+
+// NativeTriggerMetadata configures the trigger of a native rule, which fields are used depends on the trigger type.
+type NativeTriggerMetadata struct {
+	KeywordFilter     []string `json:"keyword_filter,omitempty"`
+	RegexPatterns     []string `json:"regex_patterns,omitempty"`
+	Presets           []int    `json:"presets,omitempty"`
+	AllowList         []string `json:"allow_list,omitempty"`
+	MentionTotalLimit int      `json:"mention_total_limit,omitempty"`
+}
+
+// NativeAutomodAction is what a native rule does when it triggers.
+type NativeAutomodAction struct {
+	Type     int                          `json:"type"`
+	Metadata *NativeAutomodActionMetadata `json:"metadata,omitempty"`
+}
+
+// NativeAutomodActionMetadata configures an action, ChannelID for alerts and DurationSeconds for timeouts.
+type NativeAutomodActionMetadata struct {
+	ChannelID       string `json:"channel_id,omitempty"`
+	DurationSeconds int    `json:"duration_seconds,omitempty"`
+	CustomMessage   string `json:"custom_message,omitempty"`
+}
+
+// NativeAutomodExecution is the payload of AutoModerationActionExecution
+type NativeAutomodExecution struct {
+	GuildID              string               `json:"guild_id"`
+	Action               *NativeAutomodAction `json:"action"`
+	RuleID               string               `json:"rule_id"`
+	RuleTriggerType      int                  `json:"rule_trigger_type"`
+	UserID               string               `json:"user_id"`
+	ChannelID            string               `json:"channel_id"`
+	MessageID            string               `json:"message_id"` // Empty if the message was blocked.
+	AlertSystemMessageID string               `json:"alert_system_message_id"`
+	Content              string               `json:"content"` // Needs the message content intent.
+	MatchedKeyword       string               `json:"matched_keyword"`
+	MatchedContent       string               `json:"matched_content"`
+}
+
+func nativeAutomodEndpoint(guildID string) string {
+	return discordgo.EndpointGuild(guildID) + "/auto-moderation/rules"
+}
+
+// NativeAutomodRules returns Discord's AutoMod rules of a guild.
+func (bot *Bot) NativeAutomodRules(guildID string) ([]*NativeAutomodRule, error) {
+	body, err := bot.Session.RequestWithBucketID("GET", nativeAutomodEndpoint(guildID), nil, nativeAutomodEndpoint(guildID))
+	if err != nil {
+		return nil, err
+	}
+	var rules []*NativeAutomodRule
+	err = json.Unmarshal(body, &rules)
+	return rules, err
+}
+
+// CreateNativeAutomodRule creates one of Discord's AutoMod rules, the bot needs the Manage Server permission.
+func (bot *Bot) CreateNativeAutomodRule(guildID string, rule *NativeAutomodRule) (*NativeAutomodRule, error) {
+	return bot.nativeAutomodRequest("POST", guildID, nativeAutomodEndpoint(guildID), rule)
+}
+
+// EditNativeAutomodRule replaces one of Discord's AutoMod rules, the trigger type can't be changed.
+func (bot *Bot) EditNativeAutomodRule(guildID, ruleID string, rule *NativeAutomodRule) (*NativeAutomodRule, error) {
+	return bot.nativeAutomodRequest("PATCH", guildID, nativeAutomodEndpoint(guildID)+"/"+ruleID, map[string]interface{}{
+		"name":             rule.Name,
+		"event_type":       rule.EventType,
+		"trigger_metadata": rule.TriggerMetadata,
+		"actions":          rule.Actions,
+		"enabled":          rule.Enabled,
+		"exempt_roles":     rule.ExemptRoles,
+		"exempt_channels":  rule.ExemptChannels,
+	})
+}
+
+// DeleteNativeAutomodRule deletes one of Discord's AutoMod rules.
+func (bot *Bot) DeleteNativeAutomodRule(guildID, ruleID string) error {
+	_, err := bot.Session.RequestWithBucketID("DELETE", nativeAutomodEndpoint(guildID)+"/"+ruleID, nil, nativeAutomodEndpoint(guildID))
+	return err
+}
+
+func (bot *Bot) nativeAutomodRequest(method, guildID, endpoint string, data interface{}) (*NativeAutomodRule, error) {
+	body, err := bot.Session.RequestWithBucketID(method, endpoint, data, nativeAutomodEndpoint(guildID))
+	if err != nil {
+		return nil, err
+	}
+	rule := &NativeAutomodRule{}
+	err = json.Unmarshal(body, rule)
+	return rule, err
+}
+
+// NativeWordFilterRule is the name of the native rule SyncNativeAutomod manages.
+const NativeWordFilterRule = "Sapphire word filter"
+
+// SyncNativeAutomod mirrors the guild's word filter and regex filter into a native keyword rule, so Discord blocks
+// those messages before they're even sent. The rule alerts the automod log channel and exempts the staff role
+// of AutomodConfig, it's removed once both filters are empty. Words and patterns past Discord's limits stay
+// sapphire only, Discord's regex flavor is close enough to RE2 for the patterns sapphire allows.
+func (bot *Bot) SyncNativeAutomod(guildID string) error {
+	var words, patterns []string
+	if bot.wordFilter != nil {
+		words = bot.FilteredWords(guildID)
+	}
+	if bot.regexFilter != nil {
+		for _, pattern := range bot.RegexFilter(guildID) {
+			if len(pattern) <= MaxNativeRegexLength && len(patterns) < MaxNativeRegexPatterns {
+				patterns = append(patterns, pattern)
+			}
+		}
+	}
+	if len(words) > MaxNativeKeywords {
+		words = words[:MaxNativeKeywords]
+	}
+
+	rules, err := bot.NativeAutomodRules(guildID)
+	if err != nil {
+		return err
+	}
+	var existing *NativeAutomodRule
+	for _, rule := range rules {
+		if rule.Name == NativeWordFilterRule && rule.TriggerType == NativeTriggerKeyword {
+			existing = rule
+			break
+		}
+	}
+	if len(words) == 0 && len(patterns) == 0 {
+		if existing == nil {
+			return nil
+		}
+		return bot.DeleteNativeAutomodRule(guildID, existing.ID)
+	}
+
+	rule := &NativeAutomodRule{
+		Name:            NativeWordFilterRule,
+		EventType:       1,
+		TriggerType:     NativeTriggerKeyword,
+		TriggerMetadata: &NativeTriggerMetadata{KeywordFilter: words, RegexPatterns: patterns},
+		Actions:         []*NativeAutomodAction{{Type: NativeActionBlockMessage}},
+		Enabled:         true,
+		ExemptRoles:     []string{},
+		ExemptChannels:  []string{},
+	}
+	if log := AutomodConfig.Get(bot, guildID, "log"); log != "" {
+		rule.Actions = append(rule.Actions, &NativeAutomodAction{
+			Type:     NativeActionSendAlertMessage,
+			Metadata: &NativeAutomodActionMetadata{ChannelID: log},
+		})
+	}
+	if staff := AutomodConfig.Get(bot, guildID, "staff"); staff != "" {
+		rule.ExemptRoles = append(rule.ExemptRoles, staff)
+	}
+	if existing == nil {
+		_, err = bot.CreateNativeAutomodRule(guildID, rule)
+	} else {
+		_, err = bot.EditNativeAutomodRule(guildID, existing.ID, rule)
+	}
+	return err
+}
+
+type nativeAutomodTracker struct {
+	actions AutomodAction
+}
+
+// EnableNativeAutomod routes the actions of Discord's AutoMod into sapphire automod, each triggered rule is
+// handled as a violation of the "discord" rule with actions, by default AutomodAlert. So it's posted to the
+// automod log, added to the member's heat and passed to the violation handlers.
+// Changes to the word and regex filters are synced to Discord with SyncNativeAutomod from now on.
+// The IntentsAutoModerationExecution intent is added if the session sets intents.
+func (bot *Bot) EnableNativeAutomod(actions AutomodAction) *Bot {
+	if bot.nativeAutomod != nil {
+		return bot
+	}
+	bot.EnableAutomod()
+	if actions == 0 {
+		actions = AutomodAlert
+	}
+	bot.nativeAutomod = &nativeAutomodTracker{actions: actions}
+	if bot.Session.Identify.Intents != nil {
+		intents := *bot.Session.Identify.Intents | IntentsAutoModerationExecution
+		bot.Session.Identify.Intents = &intents
+	}
+	bot.AddHandler(bot.processEvent("nativeAutomod", func(s *discordgo.Session, e *discordgo.Event) error {
+		if e.Type != AutoModerationActionExecution {
+			return nil
+		}
+		execution := &NativeAutomodExecution{}
+		if err := json.Unmarshal(e.RawData, execution); err != nil {
+			return err
+		}
+		bot.nativeAutomodExecuted(execution)
+		return nil
+	}))
+	return bot
+}
+
+// filtersChanged syncs a guild's filters to Discord in the background if native AutoMod is enabled.
+func (bot *Bot) filtersChanged(guildID string) {
+	if bot.nativeAutomod == nil {
+		return
+	}
+	go func() {
+		if err := bot.SyncNativeAutomod(guildID); err != nil {
+			bot.ErrorHandler(bot, err)
+		}
+	}()
+}
+
+func (bot *Bot) nativeAutomodExecuted(execution *NativeAutomodExecution) {
+	actions := bot.nativeAutomod.actions
+	// Discord sends an event for every action of the rule, handle the trigger once.
+	key := "automod:" + execution.RuleID + ":" + execution.UserID + ":" + execution.ChannelID + ":" + execution.Content
+	if !bot.dedup.firstSeen(key, time.Now()) {
+		return
+	}
+	guild, err := bot.Session.State.Guild(execution.GuildID)
+	if err != nil {
+		guild = &discordgo.Guild{ID: execution.GuildID}
+	}
+	channel, err := bot.Session.State.Channel(execution.ChannelID)
+	if err != nil {
+		channel = &discordgo.Channel{ID: execution.ChannelID, GuildID: execution.GuildID}
+	}
+	user := &discordgo.User{ID: execution.UserID}
+	member, err := bot.Session.State.Member(execution.GuildID, execution.UserID)
+	if err == nil && member.User != nil {
+		user = member.User
+	}
+	ctx := &AutomodContext{
+		Bot:     bot,
+		Message: &discordgo.Message{ID: execution.MessageID, ChannelID: execution.ChannelID, GuildID: execution.GuildID, Content: execution.Content, Author: user},
+		Guild:   guild,
+		Channel: channel,
+		Member:  member,
+	}
+	locale := bot.LocaleFor(guild.ID, channel.ID)
+	reason := locale.Get("AUTOMOD_NATIVE")
+	if execution.MatchedKeyword != "" {
+		reason = locale.Get("AUTOMOD_NATIVE_KEYWORD", execution.MatchedKeyword)
+	}
+	if execution.MessageID == "" {
+		// Blocked by Discord, there's nothing to delete.
+		actions &^= AutomodDelete
+	}
+	bot.HandleViolation(ctx, &Violation{Rule: "discord", Reason: reason, Actions: actions})
+}
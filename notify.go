@@ -0,0 +1,219 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The kinds of notifications sent to the notification webhook.
+const (
+	NotifyError      = "error"
+	NotifyDisconnect = "disconnect"
+	NotifyRateLimit  = "ratelimit"
+	NotifyInfo       = "info"
+)
+
+// The regexp matching webhook urls, captures the ID and token.
+var webhookURLRegex = regexp.MustCompile(`^https://(?:(?:canary|ptb)\.)?discord(?:app)?\.com/api(?:/v\d+)?/webhooks/(\d+)/([\w-]+)/?$`)
+
+// NotifyDedupWindow is how long the same notification is not sent again, repeats are counted and reported with the next one.
+var NotifyDedupWindow = 10 * time.Minute
+
+// NotifyMaxPerMinute is how many notifications are sent per minute at most, more are dropped and counted.
+var NotifyMaxPerMinute = 5
+
+// rateLimitStorm is how many rate-limits within a minute are reported as a storm.
+const rateLimitStorm = 20
+
+var notifyColors = map[string]int{
+	NotifyError:      0xE74C3C,
+	NotifyDisconnect: 0xE67E22,
+	NotifyRateLimit:  0xF1C40F,
+	NotifyInfo:       0x3498DB,
+}
+
+type notifySeen struct {
+	at      time.Time
+	repeats int
+}
+
+type notifier struct {
+	id, token string
+	// dedup key -> when it was last sent
+	seen map[string]*notifySeen
+	// When notifications were sent in the last minute.
+	sent       []time.Time
+	dropped    int
+	rateLimits []time.Time
+	disconnect time.Time
+	lock       sync.Mutex
+}
+
+// NotifyWebhook posts panics, disconnects and rate-limit storms to a Discord webhook url so problems are noticed.
+// The same notification is only sent once per NotifyDedupWindow and at most NotifyMaxPerMinute are sent per minute,
+// so an incident doesn't flood the channel. It wraps the current ErrorHandler, call it after SetErrorHandler.
+// It panics if url is not a webhook url.
+func (bot *Bot) NotifyWebhook(url string) *Bot {
+	match := webhookURLRegex.FindStringSubmatch(url)
+	if match == nil {
+		panic(fmt.Sprintf("'%s' is not a webhook url", url))
+	}
+	if bot.notifier != nil {
+		bot.notifier.lock.Lock()
+		bot.notifier.id, bot.notifier.token = match[1], match[2]
+		bot.notifier.lock.Unlock()
+		return bot
+	}
+	bot.notifier = &notifier{id: match[1], token: match[2], seen: make(map[string]*notifySeen)}
+
+	handler := bot.ErrorHandler
+	bot.ErrorHandler = func(b *Bot, err interface{}) {
+		handler(b, err)
+		if cerr, ok := err.(*CommandError); ok {
+			b.Notify(NotifyError, commandErrorTitle(cerr), cerr.Error())
+			return
+		}
+		b.Notify(NotifyError, "Error", fmt.Sprint(err))
+	}
+	bot.AddHandler(func(s *discordgo.Session, d *discordgo.Disconnect) {
+		bot.notifier.lock.Lock()
+		bot.notifier.disconnect = time.Now()
+		bot.notifier.lock.Unlock()
+		bot.Notify(NotifyDisconnect, "Disconnected", fmt.Sprintf("Shard %d/%d lost the gateway connection.", s.ShardID, s.ShardCount))
+	})
+	bot.AddHandler(func(s *discordgo.Session, r *discordgo.Resumed) {
+		bot.notifyReconnect(s)
+	})
+	bot.AddHandler(func(s *discordgo.Session, r *discordgo.Ready) {
+		bot.notifyReconnect(s)
+	})
+	bot.AddHandler(func(s *discordgo.Session, r *discordgo.RateLimit) {
+		if bot.notifier.rateLimited() {
+			bot.Notify(NotifyRateLimit, "Rate-limit storm", fmt.Sprintf("Hit %d rate-limits in the last minute.\nLast on `%s`", rateLimitStorm, r.URL))
+		}
+	})
+	return bot
+}
+
+// commandErrorTitle names where a command error happened, component and modal handlers have no command.
+func commandErrorTitle(cerr *CommandError) string {
+	if cerr.Context == nil {
+		return "Error"
+	}
+	if cerr.Context.Command == nil {
+		return "Component " + cerr.Context.InvokedName
+	}
+	return "Command " + cerr.Context.Command.Name
+}
+
+// notifyReconnect reports the reconnection if the shard was disconnected.
+func (bot *Bot) notifyReconnect(s *discordgo.Session) {
+	bot.notifier.lock.Lock()
+	disconnect := bot.notifier.disconnect
+	bot.notifier.disconnect = time.Time{}
+	bot.notifier.lock.Unlock()
+	if !disconnect.IsZero() {
+		bot.Notify(NotifyInfo, "Reconnected", fmt.Sprintf("Shard %d/%d is back after %s.", s.ShardID, s.ShardCount, time.Since(disconnect).Round(time.Second)))
+	}
+}
+
+// rateLimited records a rate-limit, returns true once the storm threshold is reached.
+func (n *notifier) rateLimited() bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	now := time.Now()
+	recent := n.rateLimits[:0]
+	for _, t := range n.rateLimits {
+		if now.Sub(t) < time.Minute {
+			recent = append(recent, t)
+		}
+	}
+	n.rateLimits = append(recent, now)
+	return len(n.rateLimits) >= rateLimitStorm
+}
+
+// allow decides if a notification is sent, returns how often it was repeated and how many were dropped since the last one.
+func (n *notifier) allow(key string, now time.Time) (ok bool, repeats int, dropped int) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if seen, ok := n.seen[key]; ok && now.Sub(seen.at) < NotifyDedupWindow {
+		seen.repeats++
+		return false, 0, 0
+	}
+	recent := n.sent[:0]
+	for _, t := range n.sent {
+		if now.Sub(t) < time.Minute {
+			recent = append(recent, t)
+		}
+	}
+	n.sent = recent
+	if len(n.sent) >= NotifyMaxPerMinute {
+		n.dropped++
+		return false, 0, 0
+	}
+	n.sent = append(n.sent, now)
+	if seen, ok := n.seen[key]; ok {
+		repeats = seen.repeats
+	}
+	n.seen[key] = &notifySeen{at: now}
+	// Forget old notifications so the map doesn't grow forever, repeats are kept a little longer to be reported.
+	for k, seen := range n.seen {
+		if age := now.Sub(seen.at); age >= NotifyDedupWindow && seen.repeats == 0 || age >= 2*NotifyDedupWindow {
+			delete(n.seen, k)
+		}
+	}
+	dropped = n.dropped
+	n.dropped = 0
+	return true, repeats, dropped
+}
+
+// Notify posts a notification to the webhook set with NotifyWebhook, it does nothing without one.
+// kind is one of the Notify constants and picks the embed's color, it's sent in the background.
+func (bot *Bot) Notify(kind, title, description string) {
+	if bot.notifier == nil {
+		return
+	}
+	// Dedup on the first line, later lines are usually stack traces or IDs that differ.
+	key := kind + ":" + title + ":" + strings.SplitN(description, "\n", 2)[0]
+	ok, repeats, dropped := bot.notifier.allow(key, time.Now())
+	if !ok {
+		return
+	}
+	embed := NewEmbed().
+		SetTitle(title).
+		SetDescription(description).
+		SetColor(notifyColors[kind]).
+		TruncateDescription()
+	var footer []string
+	if repeats > 0 {
+		footer = append(footer, fmt.Sprintf("Repeated %d times since last sent", repeats))
+	}
+	if dropped > 0 {
+		footer = append(footer, fmt.Sprintf("%d notifications dropped", dropped))
+	}
+	if len(footer) > 0 {
+		embed.SetFooter(strings.Join(footer, " • "))
+	}
+	built := embed.Build()
+	built.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	bot.notifier.lock.Lock()
+	id, token := bot.notifier.id, bot.notifier.token
+	bot.notifier.lock.Unlock()
+	params := &discordgo.WebhookParams{Embeds: []*discordgo.MessageEmbed{built}}
+	if bot.Session.State.User != nil {
+		params.Username = bot.Session.State.User.Username
+	}
+	go func() {
+		endpoint := discordgo.EndpointWebhookToken(id, token)
+		_, err := bot.request("POST", endpoint, params, endpoint)
+		if err != nil {
+			// Not the ErrorHandler, it would try to notify about this again.
+			fmt.Printf("Couldn't send a notification: %v\n", err)
+		}
+	}()
+}
@@ -0,0 +1,62 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"testing"
+	"time"
+)
+
+func TestNotifyDedup(t *testing.T) {
+	n := &notifier{seen: make(map[string]*notifySeen)}
+	now := time.Now()
+	if ok, _, _ := n.allow("error:a", now); !ok {
+		t.Error("Expected the first notification to be sent")
+	}
+	if ok, _, _ := n.allow("error:a", now.Add(time.Minute)); ok {
+		t.Error("Expected a repeated notification to be deduplicated")
+	}
+	ok, repeats, _ := n.allow("error:a", now.Add(NotifyDedupWindow))
+	if !ok || repeats != 1 {
+		t.Errorf("Expected the notification to be sent again with 1 repeat, got %t and %d", ok, repeats)
+	}
+
+	for i := 0; i < NotifyMaxPerMinute+2; i++ {
+		n.allow(fmt.Sprintf("error:%d", i), now.Add(time.Hour))
+	}
+	ok, _, dropped := n.allow("error:last", now.Add(time.Hour+time.Minute))
+	if !ok || dropped != 2 {
+		t.Errorf("Expected 2 dropped notifications to be reported, got %t and %d", ok, dropped)
+	}
+}
+
+func TestNotifyComponentError(t *testing.T) {
+	bot := New(&discordgo.Session{State: discordgo.NewState()})
+	bot.SetErrorHandler(func(bot *Bot, err interface{}) {})
+	bot.NotifyWebhook("https://discord.com/api/webhooks/1/token")
+	recordREST(bot)
+	titles := make(chan string, 1)
+	interactions := bot.requestHook
+	bot.requestHook = func(method, endpoint string, data interface{}, bucket string) ([]byte, error) {
+		if params, ok := data.(*discordgo.WebhookParams); ok {
+			titles <- params.Embeds[0].Title
+			return nil, nil
+		}
+		return interactions(method, endpoint, data, bucket)
+	}
+	bot.AddComponentHandler("settings", func(ctx *CommandContext) {
+		ctx.Error("boom")
+	})
+
+	dispatchInteraction(t, bot, `{"id":"i","application_id":"a","type":3,"token":"tok","channel_id":"c",
+		"user":{"id":"u"},"data":{"custom_id":"settings:section:u","component_type":3,"values":["logs"]}}`)
+
+	select {
+	case title := <-titles:
+		if title != "Component settings" {
+			t.Errorf("Expected the component to be named in the notification but got %q", title)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the component's error to be notified")
+	}
+}
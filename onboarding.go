@@ -0,0 +1,149 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"sync"
+)
+
+// GuildJoinHandler is called when the bot joins a new guild, see bot.OnGuildJoin
+type GuildJoinHandler func(bot *Bot, guild *discordgo.Guild)
+
+// Onboarding configures what the bot does when it joins a new guild.
+type Onboarding struct {
+	MessageKey string            // Locale key sent to the guild, it's formatted with the prefix. "" to not send anything. (default: GUILD_ONBOARDING)
+	Defaults   map[string]string // Settings seeded for the guild if they aren't already set.
+}
+
+// NewOnboarding creates an onboarding config sending the default GUILD_ONBOARDING message.
+func NewOnboarding() *Onboarding {
+	return &Onboarding{MessageKey: "GUILD_ONBOARDING", Defaults: make(map[string]string)}
+}
+
+// SetDefault sets a setting seeded for new guilds.
+func (o *Onboarding) SetDefault(key, value string) *Onboarding {
+	o.Defaults[key] = value
+	return o
+}
+
+// guildTracker remembers which guilds the bot is in so GuildCreate events can be told apart.
+// Discord sends GuildCreate for every guild on startup and after outages, not just on joins.
+type guildTracker struct {
+	known map[string]bool
+	lock  sync.Mutex
+}
+
+// SetOnboarding sets the onboarding config, nil disables onboarding messages and defaults.
+// Join handlers still run without it.
+func (bot *Bot) SetOnboarding(onboarding *Onboarding) *Bot {
+	bot.Onboarding = onboarding
+	return bot
+}
+
+// OnGuildJoin adds a handler called when the bot joins a guild it wasn't in before.
+func (bot *Bot) OnGuildJoin(handler GuildJoinHandler) *Bot {
+	bot.guildJoinHandlers = append(bot.guildJoinHandlers, handler)
+	return bot
+}
+
+// onboard seeds the defaults and sends the onboarding message.
+func (bot *Bot) onboard(guild *discordgo.Guild) {
+	o := bot.Onboarding
+	for key, value := range o.Defaults {
+		if _, ok, err := bot.Settings.Get(guild.ID, key); err == nil && !ok {
+			bot.Settings.Set(guild.ID, key, value)
+		}
+	}
+
+	if o.MessageKey == "" {
+		return
+	}
+	channel := bot.defaultChannel(guild)
+	if channel == nil {
+		return
+	}
+	prefix := bot.Prefix(bot, &discordgo.Message{GuildID: guild.ID, ChannelID: channel.ID}, false)
+	bot.SendLocale(channel.ID, bot.LocaleFor(guild.ID, channel.ID), o.MessageKey, prefix)
+}
+
+// defaultChannel picks the system channel if the bot can talk there, otherwise the top most text channel it can talk in.
+func (bot *Bot) defaultChannel(guild *discordgo.Guild) *discordgo.Channel {
+	canSend := func(channel *discordgo.Channel) bool {
+		if channel.Type != discordgo.ChannelTypeGuildText {
+			return false
+		}
+		perms, err := bot.Session.State.UserChannelPermissions(bot.Session.State.User.ID, channel.ID)
+		return err == nil && Permissions(perms).Has(discordgo.PermissionReadMessages|discordgo.PermissionSendMessages)
+	}
+
+	channels := make([]*discordgo.Channel, len(guild.Channels))
+	copy(channels, guild.Channels)
+	sort.Slice(channels, func(i, j int) bool { return channels[i].Position < channels[j].Position })
+
+	for _, channel := range channels {
+		if channel.ID == guild.SystemChannelID && canSend(channel) {
+			return channel
+		}
+	}
+	for _, channel := range channels {
+		if canSend(channel) {
+			return channel
+		}
+	}
+	return nil
+}
+
+func guildReadyListener(bot *Bot) func(s *discordgo.Session, r *discordgo.Ready) {
+	return func(s *discordgo.Session, r *discordgo.Ready) {
+		bot.guilds.lock.Lock()
+		defer bot.guilds.lock.Unlock()
+		// Ready lists every guild we are in (unavailable until their GuildCreate arrives)
+		// A fresh session means we might have missed leaves so start over.
+		bot.guilds.known = make(map[string]bool)
+		for _, guild := range r.Guilds {
+			bot.guilds.known[guild.ID] = true
+		}
+	}
+}
+
+func guildCreateListener(bot *Bot) func(s *discordgo.Session, g *discordgo.GuildCreate) {
+	return func(s *discordgo.Session, g *discordgo.GuildCreate) {
+		bot.guilds.lock.Lock()
+		known := bot.guilds.known[g.ID]
+		bot.guilds.known[g.ID] = true
+		bot.guilds.lock.Unlock()
+
+		if known {
+			return
+		}
+
+		// We are back before the data got cleaned up.
+		bot.cancelRetention(g.ID)
+
+		defer func() {
+			if err := recover(); err != nil {
+				bot.ErrorHandler(bot, err)
+			}
+		}()
+
+		if bot.Onboarding != nil {
+			bot.onboard(g.Guild)
+		}
+		for _, handler := range bot.guildJoinHandlers {
+			handler(bot, g.Guild)
+		}
+		bot.Publish(&GuildJoined{Guild: g.Guild})
+	}
+}
+
+func guildDeleteListener(bot *Bot) func(s *discordgo.Session, g *discordgo.GuildDelete) {
+	return func(s *discordgo.Session, g *discordgo.GuildDelete) {
+		// Unavailable means an outage, we are still in the guild.
+		if g.Unavailable {
+			return
+		}
+		bot.guilds.lock.Lock()
+		delete(bot.guilds.known, g.ID)
+		bot.guilds.lock.Unlock()
+	}
+}
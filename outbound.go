@@ -0,0 +1,100 @@
+package sapphire
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// OutgoingWebhookClient is the http client events are delivered with.
+var OutgoingWebhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// outgoingRetries are the delays before retrying a failed delivery.
+var outgoingRetries = []time.Duration{10 * time.Second, time.Minute, 10 * time.Minute}
+
+// OutgoingWebhook mirrors events of the bus to an HTTP endpoint as signed JSON, see bot.AddOutgoingWebhook
+type OutgoingWebhook struct {
+	URL    string
+	Secret string   // Deliveries are signed with it in the X-Sapphire-Signature header as sha256=<hex HMAC of the body>.
+	Topics []string // The bus topics to deliver e.g TopicCommandRan and TopicModerationAction
+	// Filter is checked before delivering an event, return false to skip it e.g to only deliver some guilds.
+	Filter func(event BusEvent) bool
+}
+
+// OutgoingDelivery is the JSON body of a delivery, Event is the bus event with it's Go field names.
+type OutgoingDelivery struct {
+	ID     string    `json:"id"` // The same across retries of a delivery so endpoints can skip duplicates.
+	Topic  string    `json:"topic"`
+	SentAt time.Time `json:"sent_at"`
+	Event  BusEvent  `json:"event"`
+}
+
+// AddOutgoingWebhook delivers the events of the webhook's topics to it's URL, e.g for analytics or dashboards.
+// Failed deliveries are retried after 10 seconds, a minute and 10 minutes, then they go to the error handler.
+// Returns a function removing the webhook.
+func (bot *Bot) AddOutgoingWebhook(hook *OutgoingWebhook) func() {
+	unsubscribes := make([]func(), len(hook.Topics))
+	for i, topic := range hook.Topics {
+		unsubscribes[i] = bot.Subscribe(topic, func(event BusEvent) {
+			if hook.Filter != nil && !hook.Filter(event) {
+				return
+			}
+			id := make([]byte, 12)
+			rand.Read(id)
+			bot.deliverOutgoing(hook, &OutgoingDelivery{ID: hex.EncodeToString(id), Topic: event.Topic(), SentAt: time.Now().UTC(), Event: event}, 0)
+		})
+	}
+	return func() {
+		for _, unsubscribe := range unsubscribes {
+			unsubscribe()
+		}
+	}
+}
+
+// deliverOutgoing posts a delivery, scheduling a retry if it fails.
+func (bot *Bot) deliverOutgoing(hook *OutgoingWebhook, delivery *OutgoingDelivery, attempt int) {
+	err := postOutgoing(hook, delivery)
+	if err == nil {
+		return
+	}
+	if attempt >= len(outgoingRetries) {
+		bot.ErrorHandler(bot, fmt.Errorf("delivering %s %s to %s failed: %v", delivery.Topic, delivery.ID, hook.URL, err))
+		return
+	}
+	bot.Scheduler.After(outgoingRetries[attempt], func() {
+		bot.deliverOutgoing(hook, delivery, attempt+1)
+	})
+}
+
+// postOutgoing posts a delivery once, any response but 2xx is a failure.
+func postOutgoing(hook *OutgoingWebhook, delivery *OutgoingDelivery) error {
+	body, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, []byte(hook.Secret))
+	mac.Write(body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sapphire-Event", delivery.Topic)
+	req.Header.Set("X-Sapphire-Delivery", delivery.ID)
+	req.Header.Set("X-Sapphire-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	res, err := OutgoingWebhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("status %d", res.StatusCode)
+	}
+	return nil
+}
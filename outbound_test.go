@@ -0,0 +1,57 @@
+package sapphire
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/bwmarrin/discordgo"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOutgoingWebhook(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	bot := New(&discordgo.Session{})
+	remove := bot.AddOutgoingWebhook(&OutgoingWebhook{URL: server.URL, Secret: "secret", Topics: []string{TopicModerationAction},
+		Filter: func(event BusEvent) bool { return event.(*ModerationAction).GuildID == "g" }})
+	bot.Publish(&ModerationAction{GuildID: "other", Action: "ban"})
+	bot.Publish(&ModerationAction{GuildID: "g", Action: "kick"})
+	var r *http.Request
+	select {
+	case r = <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("nothing was delivered")
+	}
+	body := <-bodies
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	if r.Header.Get("X-Sapphire-Signature") != "sha256="+hex.EncodeToString(mac.Sum(nil)) || r.Header.Get("X-Sapphire-Event") != TopicModerationAction {
+		t.Errorf("Unexpected headers %v", r.Header)
+	}
+	var delivery struct {
+		Topic string
+		Event ModerationAction
+	}
+	if err := json.Unmarshal(body, &delivery); err != nil || delivery.Event.Action != "kick" {
+		t.Errorf("Unexpected body %s", body)
+	}
+	remove()
+	bot.Publish(&ModerationAction{GuildID: "g", Action: "warn"})
+	select {
+	case <-received:
+		t.Error("delivered after removing the webhook")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,288 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strings"
+)
+
+const permissionOverridesKey = "permissions.overrides"
+
+// MaxPermissionOverrides is the maximum amount of permission overrides per guild.
+var MaxPermissionOverrides = 100
+
+// PermissionOverride grants or denies a command to a role, user or channel of a guild.
+type PermissionOverride struct {
+	Command string `json:"command"` // Name of the command, "*" for every command.
+	Type    string `json:"type"`    // "user", "role" or "channel"
+	ID      string `json:"id"`
+	Allow   bool   `json:"allow"`
+}
+
+// ResolveOverride decides if a command is allowed for a user with roles in a channel, found is false if no override applies.
+// User overrides win over role overrides which win over channel overrides, an allow on any of the roles wins over denies on others.
+// On the same level an override for the command wins over one for "*".
+func ResolveOverride(overrides []PermissionOverride, command, userID string, roles []string, channelID string) (allowed, found bool) {
+	levels := []struct {
+		typ string
+		ids []string
+	}{
+		{"user", []string{userID}},
+		{"role", roles},
+		{"channel", []string{channelID}},
+	}
+	for _, level := range levels {
+		for _, name := range []string{command, "*"} {
+			for _, o := range overrides {
+				if o.Type != level.typ || o.Command != name || !containsString(level.ids, o.ID) {
+					continue
+				}
+				found = true
+				if o.Allow {
+					return true, true
+				}
+			}
+			if found {
+				return false, true
+			}
+		}
+	}
+	return false, false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionOverrides returns the command permission overrides of a guild.
+func (bot *Bot) PermissionOverrides(guildID string) []PermissionOverride {
+	var overrides []PermissionOverride
+	GetJSON(bot.Settings, guildID, permissionOverridesKey, &overrides)
+	return overrides
+}
+
+// SetPermissionOverride adds an override to a guild, replacing the one for the same command and target.
+func (bot *Bot) SetPermissionOverride(guildID string, override PermissionOverride) error {
+	overrides := bot.PermissionOverrides(guildID)
+	for i, o := range overrides {
+		if o.Command == override.Command && o.Type == override.Type && o.ID == override.ID {
+			overrides[i] = override
+			return SetJSON(bot.Settings, guildID, permissionOverridesKey, overrides)
+		}
+	}
+	if len(overrides) >= MaxPermissionOverrides {
+		return fmt.Errorf("a server can't have more than %d permission overrides", MaxPermissionOverrides)
+	}
+	return SetJSON(bot.Settings, guildID, permissionOverridesKey, append(overrides, override))
+}
+
+// RemovePermissionOverride removes the override for the command and target, returns false if there was none.
+func (bot *Bot) RemovePermissionOverride(guildID, command, typ, id string) (bool, error) {
+	overrides := bot.PermissionOverrides(guildID)
+	for i, o := range overrides {
+		if o.Command == command && o.Type == typ && o.ID == id {
+			overrides = append(overrides[:i], overrides[i+1:]...)
+			if len(overrides) == 0 {
+				return true, bot.Settings.Delete(guildID, permissionOverridesKey)
+			}
+			return true, SetJSON(bot.Settings, guildID, permissionOverridesKey, overrides)
+		}
+	}
+	return false, nil
+}
+
+// overrideData is the data subject of the permission overrides for users, exported by guild ID.
+func overrideData(bot *Bot) DataSubject {
+	return &userData{
+		name: "permissions",
+		export: func(userID string) (interface{}, error) {
+			guilds, err := bot.settingsGuilds()
+			if err != nil {
+				return nil, err
+			}
+			data := make(map[string][]PermissionOverride)
+			for _, guildID := range guilds {
+				for _, o := range bot.PermissionOverrides(guildID) {
+					if o.Type == "user" && o.ID == userID {
+						data[guildID] = append(data[guildID], o)
+					}
+				}
+			}
+			if len(data) == 0 {
+				return nil, nil
+			}
+			return data, nil
+		},
+		delete: func(userID string) error {
+			guilds, err := bot.settingsGuilds()
+			if err != nil {
+				return err
+			}
+			for _, guildID := range guilds {
+				for _, o := range bot.PermissionOverrides(guildID) {
+					if o.Type != "user" || o.ID != userID {
+						continue
+					}
+					if _, err := bot.RemovePermissionOverride(guildID, o.Command, o.Type, o.ID); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// checkOverrides applies the guild's overrides to the command, returns false if it's denied.
+// Administrators and the guild owner are never affected so they can't lock themselves out.
+func (bot *Bot) checkOverrides(ctx *CommandContext) bool {
+	if !bot.commandOverrides || ctx.Guild == nil {
+		return true
+	}
+	member := ctx.Member(ctx.Author.ID)
+	if member == nil || PermissionsForMember(ctx.Guild, member).Has(discordgo.PermissionAdministrator) {
+		return true
+	}
+	allowed, found := ResolveOverride(bot.PermissionOverrides(ctx.Guild.ID), ctx.Command.Name, ctx.Author.ID, member.Roles, ctx.Channel.ID)
+	if !found {
+		return true
+	}
+	ctx.granted = allowed
+	return allowed
+}
+
+// LoadPermissionsCommand loads the permissions command and enforces the overrides it sets.
+func (bot *Bot) LoadPermissionsCommand() *Bot {
+	bot.commandOverrides = true
+	bot.AddDataSubject(overrideData(bot))
+	return bot.AddCommand(NewCommand("permissions", "Settings", permissionsCommand).
+		SetDescription("Grants or denies commands to roles, users or channels.").
+		SetUsage("[action:string] [command:string] [type:string] [target:string...]").
+		AddAliases("perms").
+		SetGuildOnly(true))
+}
+
+func permissionsCommand(ctx *CommandContext) {
+	if !ctx.HasPermissions(discordgo.PermissionAdministrator) {
+		ctx.ReplyLocale("COMMAND_PERMISSIONS_NO_PERMISSION")
+		return
+	}
+	bot := ctx.Bot
+	action := strings.ToLower(ctx.ArgString(0))
+	if action == "" || action == "list" {
+		permissionsList(ctx, strings.ToLower(ctx.ArgString(1)))
+		return
+	}
+	if action != "allow" && action != "deny" && action != "reset" {
+		ctx.ReplyLocale("COMMAND_PERMISSIONS_USAGE", ctx.Prefix)
+		return
+	}
+
+	name := strings.ToLower(ctx.ArgString(1))
+	if name != "*" {
+		cmd := bot.GetCommand(name)
+		if cmd == nil {
+			ctx.ReplyLocale("COMMAND_PERMISSIONS_UNKNOWN_COMMAND", Escape(name))
+			return
+		}
+		name = cmd.Name
+	}
+	typ, id, display := permissionsTarget(ctx, strings.ToLower(ctx.ArgString(2)), ctx.ArgString(3))
+	if typ == "" {
+		ctx.ReplyLocale("COMMAND_PERMISSIONS_USAGE", ctx.Prefix)
+		return
+	}
+	if id == "" {
+		ctx.ReplyLocale("COMMAND_PERMISSIONS_UNKNOWN_TARGET", typ, Escape(ctx.ArgString(3)))
+		return
+	}
+
+	if action == "reset" {
+		removed, err := bot.RemovePermissionOverride(ctx.Guild.ID, name, typ, id)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		if !removed {
+			ctx.ReplyLocale("COMMAND_PERMISSIONS_NOT_FOUND", name, display)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_PERMISSIONS_RESET", name, display)
+		return
+	}
+	override := PermissionOverride{Command: name, Type: typ, ID: id, Allow: action == "allow"}
+	if err := bot.SetPermissionOverride(ctx.Guild.ID, override); err != nil {
+		ctx.Reply(Escape(err.Error()))
+		return
+	}
+	if override.Allow {
+		ctx.ReplyLocale("COMMAND_PERMISSIONS_ALLOWED", name, display)
+	} else {
+		ctx.ReplyLocale("COMMAND_PERMISSIONS_DENIED", name, display)
+	}
+}
+
+// permissionsTarget resolves the target of an override, id is empty if it can't be found.
+func permissionsTarget(ctx *CommandContext, typ, value string) (string, string, string) {
+	switch typ {
+	case "user", "member":
+		match := MentionRegex.FindStringSubmatch(value)
+		if len(match) < 2 {
+			return "user", "", ""
+		}
+		member := ctx.Member(match[1])
+		if member == nil {
+			return "user", "", ""
+		}
+		return "user", member.User.ID, member.User.Mention()
+	case "role":
+		role := findRole(ctx.Guild, value)
+		if role == nil {
+			return "role", "", ""
+		}
+		return "role", role.ID, "**" + Escape(role.Name) + "**"
+	case "channel":
+		id, err := normalizeConfig(ctx.Bot, ctx.Guild.ID, ConfigChannel, value)
+		if err != nil {
+			return "channel", "", ""
+		}
+		return "channel", id, "<#" + id + ">"
+	}
+	return "", "", ""
+}
+
+func permissionsList(ctx *CommandContext, command string) {
+	var lines []string
+	for _, o := range ctx.Bot.PermissionOverrides(ctx.Guild.ID) {
+		if command != "" && o.Command != command {
+			continue
+		}
+		target := "<@&" + o.ID + ">"
+		switch o.Type {
+		case "user":
+			target = "<@" + o.ID + ">"
+		case "channel":
+			target = "<#" + o.ID + ">"
+		}
+		state := "deny"
+		if o.Allow {
+			state = "allow"
+		}
+		lines = append(lines, fmt.Sprintf("`%s` %s %s %s", o.Command, state, o.Type, target))
+	}
+	if len(lines) == 0 {
+		ctx.ReplyLocale("COMMAND_PERMISSIONS_EMPTY", ctx.Prefix)
+		return
+	}
+	sort.Strings(lines)
+	ctx.BuildEmbed(NewEmbed().
+		SetTitle(ctx.Locale.Get("COMMAND_PERMISSIONS_TITLE")).
+		SetDescription(strings.Join(lines, "\n")).
+		SetColor(ctx.Bot.Color))
+}
@@ -0,0 +1,37 @@
+package sapphire
+
+import (
+	"testing"
+)
+
+func TestResolveOverride(t *testing.T) {
+	overrides := []PermissionOverride{
+		{Command: "mute", Type: "role", ID: "helper", Allow: true},
+		{Command: "mute", Type: "role", ID: "muted", Allow: false},
+		{Command: "mute", Type: "user", ID: "troll", Allow: false},
+		{Command: "*", Type: "channel", ID: "general", Allow: false},
+		{Command: "ping", Type: "channel", ID: "general", Allow: true},
+	}
+	tests := []struct {
+		command, user string
+		roles         []string
+		channel       string
+		allowed       bool
+		found         bool
+	}{
+		{"mute", "someone", []string{"helper"}, "bots", true, true},
+		{"mute", "someone", []string{"helper", "muted"}, "bots", true, true}, // An allowing role wins.
+		{"mute", "someone", []string{"muted"}, "bots", false, true},
+		{"mute", "troll", []string{"helper"}, "bots", false, true},     // Users win over roles.
+		{"mute", "someone", []string{"helper"}, "general", true, true}, // Roles win over channels.
+		{"ban", "someone", nil, "general", false, true},
+		{"ping", "someone", nil, "general", true, true}, // The command wins over "*".
+		{"ban", "someone", nil, "bots", false, false},
+	}
+	for _, test := range tests {
+		allowed, found := ResolveOverride(overrides, test.command, test.user, test.roles, test.channel)
+		if allowed != test.allowed || found != test.found {
+			t.Errorf("ResolveOverride(%s, %s, %v, %s) = %v, %v want %v, %v", test.command, test.user, test.roles, test.channel, allowed, found, test.allowed, test.found)
+		}
+	}
+}
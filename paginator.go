@@ -84,11 +84,7 @@ func (p *Paginator) addReactions() {
 	if p.Message == nil {
 		return
 	}
-	p.Session.MessageReactionAdd(p.ChannelID, p.Message.ID, EmojiFirst)
-	p.Session.MessageReactionAdd(p.ChannelID, p.Message.ID, EmojiLeft)
-	p.Session.MessageReactionAdd(p.ChannelID, p.Message.ID, EmojiStop)
-	p.Session.MessageReactionAdd(p.ChannelID, p.Message.ID, EmojiRight)
-	p.Session.MessageReactionAdd(p.ChannelID, p.Message.ID, EmojiLast)
+	AddReactions(p.Session, p.ChannelID, p.Message.ID, EmojiFirst, EmojiLeft, EmojiStop, EmojiRight, EmojiLast)
 }
 
 // Stops the paginator by sending the signal to the Stop Channel.
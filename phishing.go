@@ -0,0 +1,182 @@
+package sapphire
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DomainReputation tells if a domain is known to be malicious, e.g phishing or malware.
+type DomainReputation interface {
+	IsMalicious(domain string) (bool, error)
+}
+
+// DomainReputationFunc is a function implementing DomainReputation, e.g to call an external API.
+type DomainReputationFunc func(domain string) (bool, error)
+
+// IsMalicious implements DomainReputation
+func (fn DomainReputationFunc) IsMalicious(domain string) (bool, error) {
+	return fn(domain)
+}
+
+// DomainBlocklist is a DomainReputation with a fixed list of domains, subdomains of listed domains match too.
+type DomainBlocklist struct {
+	domains map[string]bool
+	lock    sync.RWMutex
+}
+
+// NewDomainBlocklist creates a blocklist with the domains.
+func NewDomainBlocklist(domains ...string) *DomainBlocklist {
+	list := &DomainBlocklist{domains: make(map[string]bool)}
+	list.Add(domains...)
+	return list
+}
+
+// LoadDomainBlocklist reads a blocklist file with one domain per line, empty lines and lines starting with # are skipped.
+func LoadDomainBlocklist(path string) (*DomainBlocklist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	list := NewDomainBlocklist()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			list.Add(line)
+		}
+	}
+	return list, scanner.Err()
+}
+
+// Add adds domains to the blocklist.
+func (l *DomainBlocklist) Add(domains ...string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for _, domain := range domains {
+		l.domains[strings.ToLower(strings.TrimSuffix(domain, "."))] = true
+	}
+}
+
+// IsMalicious implements DomainReputation
+func (l *DomainBlocklist) IsMalicious(domain string) (bool, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	return domainMatches(l.domains, domain), nil
+}
+
+// domainMatches checks if the domain or one of it's parent domains is in the set.
+func domainMatches(set map[string]bool, domain string) bool {
+	domain = strings.ToLower(domain)
+	for {
+		if set[domain] {
+			return true
+		}
+		i := strings.IndexByte(domain, '.')
+		if i < 0 {
+			return false
+		}
+		domain = domain[i+1:]
+	}
+}
+
+type reputationEntry struct {
+	malicious bool
+	at        time.Time
+}
+
+type cachedReputation struct {
+	provider DomainReputation
+	ttl      time.Duration
+	cache    map[string]reputationEntry
+	lock     sync.Mutex
+}
+
+// CachedReputation caches the answers of a provider for ttl, wrap external APIs with it so every link isn't a request.
+func CachedReputation(provider DomainReputation, ttl time.Duration) DomainReputation {
+	return &cachedReputation{provider: provider, ttl: ttl, cache: make(map[string]reputationEntry)}
+}
+
+func (c *cachedReputation) IsMalicious(domain string) (bool, error) {
+	c.lock.Lock()
+	entry, ok := c.cache[domain]
+	c.lock.Unlock()
+	if ok && time.Since(entry.at) < c.ttl {
+		return entry.malicious, nil
+	}
+	malicious, err := c.provider.IsMalicious(domain)
+	if err != nil {
+		return false, err
+	}
+	c.lock.Lock()
+	c.cache[domain] = reputationEntry{malicious: malicious, at: time.Now()}
+	c.lock.Unlock()
+	return malicious, nil
+}
+
+// The regexp matching links in messages, captures the domain.
+var linkRegex = regexp.MustCompile(`(?i)\bhttps?://(?:[^\s/@<>]*@)?([a-z0-9.-]+\.[a-z]{2,})`)
+
+// LinkDomains returns the domains of the links in text, without duplicates.
+func LinkDomains(text string) []string {
+	var domains []string
+	seen := make(map[string]bool)
+	for _, match := range linkRegex.FindAllStringSubmatch(text, -1) {
+		domain := strings.ToLower(match[1])
+		if !seen[domain] {
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// PhishingRule is an automod rule catching links to malicious domains.
+type PhishingRule struct {
+	Reputation DomainReputation
+	Allowlist  []string      // Domains that are never malicious, in addition to the guild's allowlist config.
+	Actions    AutomodAction // (default: AutomodDelete|AutomodAlert)
+}
+
+// NewPhishingRule creates a rule checking links with the reputation provider.
+func NewPhishingRule(reputation DomainReputation) *PhishingRule {
+	return &PhishingRule{Reputation: reputation, Actions: AutomodDelete | AutomodAlert}
+}
+
+// Name implements AutomodRule
+func (r *PhishingRule) Name() string {
+	return "phishing"
+}
+
+// Check implements AutomodRule
+func (r *PhishingRule) Check(ctx *AutomodContext) *Violation {
+	domains := LinkDomains(ctx.Text())
+	if len(domains) == 0 {
+		return nil
+	}
+	allowed := make(map[string]bool)
+	for _, domain := range r.Allowlist {
+		allowed[strings.ToLower(domain)] = true
+	}
+	for _, domain := range strings.Fields(AutomodConfig.Get(ctx.Bot, ctx.Guild.ID, "allowlist")) {
+		allowed[strings.ToLower(domain)] = true
+	}
+	for _, domain := range domains {
+		if domainMatches(allowed, domain) {
+			continue
+		}
+		malicious, err := r.Reputation.IsMalicious(domain)
+		if err != nil {
+			ctx.Bot.ErrorHandler(ctx.Bot, err)
+			continue
+		}
+		if malicious {
+			return &Violation{Reason: ctx.Bot.LocaleFor(ctx.Guild.ID, ctx.Channel.ID).Get("AUTOMOD_PHISHING", domain), Actions: r.Actions}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package sapphire
+
+import (
+	"testing"
+)
+
+func TestDomainBlocklist(t *testing.T) {
+	list := NewDomainBlocklist("evil.com", "Steam-Gift.ru.")
+	domains := LinkDomains("free nitro https://discord.gift.evil.com/claim and http://user@STEAM-gift.ru, not https://discord.com or evil.com")
+	if len(domains) != 3 {
+		t.Fatalf("Expected 3 domains but got %q", domains)
+	}
+	for i, expected := range []bool{true, true, false} {
+		if malicious, _ := list.IsMalicious(domains[i]); malicious != expected {
+			t.Errorf("Expected %s to be malicious: %t", domains[i], expected)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package sapphire
+
+import (
+	"sync"
+)
+
+// PremiumProvider tells the bot who has premium, implement it with your own database or payment system.
+// Set it with bot.SetPremiumProvider, without a provider nobody is premium.
+type PremiumProvider interface {
+	IsPremiumUser(userID string) bool
+	IsPremiumGuild(guildID string) bool
+}
+
+// ManualPremium is an in-memory PremiumProvider where users and guilds are granted premium by hand.
+// It's safe for concurrent use.
+type ManualPremium struct {
+	users  map[string]bool
+	guilds map[string]bool
+	lock   sync.RWMutex
+}
+
+// NewManualPremium creates an empty ManualPremium.
+func NewManualPremium() *ManualPremium {
+	return &ManualPremium{
+		users:  make(map[string]bool),
+		guilds: make(map[string]bool),
+	}
+}
+
+// IsPremiumUser implements PremiumProvider.
+func (p *ManualPremium) IsPremiumUser(userID string) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.users[userID]
+}
+
+// IsPremiumGuild implements PremiumProvider.
+func (p *ManualPremium) IsPremiumGuild(guildID string) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.guilds[guildID]
+}
+
+// SetUser grants or revokes premium for a user.
+func (p *ManualPremium) SetUser(userID string, premium bool) *ManualPremium {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if premium {
+		p.users[userID] = true
+	} else {
+		delete(p.users, userID)
+	}
+	return p
+}
+
+// manualPremiumData is the data subject of the users granted premium by hand, deleting revokes it.
+func manualPremiumData(p *ManualPremium) DataSubject {
+	return &userData{
+		name: "premium",
+		export: func(userID string) (interface{}, error) {
+			if p.IsPremiumUser(userID) {
+				return true, nil
+			}
+			return nil, nil
+		},
+		delete: func(userID string) error {
+			p.SetUser(userID, false)
+			return nil
+		},
+	}
+}
+
+// SetGuild grants or revokes premium for a guild.
+func (p *ManualPremium) SetGuild(guildID string, premium bool) *ManualPremium {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if premium {
+		p.guilds[guildID] = true
+	} else {
+		delete(p.guilds, guildID)
+	}
+	return p
+}
+
+// IsPremium returns true if the invoker or the guild the command was ran on has premium.
+func (bot *Bot) IsPremium(ctx *CommandContext) bool {
+	if bot.Premium == nil {
+		return false
+	}
+	if bot.Premium.IsPremiumUser(ctx.Author.ID) {
+		return true
+	}
+	return ctx.Message.GuildID != "" && bot.Premium.IsPremiumGuild(ctx.Message.GuildID)
+}
+
+// ExemptPremium exempts premium users and guilds from cooldowns, see bot.IsPremium
+func ExemptPremium(ctx *CommandContext) bool {
+	return ctx.Bot.IsPremium(ctx)
+}
@@ -0,0 +1,95 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"regexp"
+	"strings"
+)
+
+// The Regexp used for matching message links, the groups are the guild, channel and message IDs.
+var MessageLinkRegex = regexp.MustCompile("https?://(?:(?:ptb|canary)\\.)?discord(?:app)?\\.com/channels/(\\d{17,19})/(\\d{17,19})/(\\d{17,19})")
+
+// QuoteConfig is the per-guild config of message link quotes, see bot.EnableQuotes
+var QuoteConfig = NewConfigSchema("quotes", "Quotes the messages linked in chat.").
+	Add("enabled", ConfigBool, "true", "Whether linked messages are quoted in this server.")
+
+// MaxQuotes is the maximum amount of links quoted from one message.
+const MaxQuotes = 3
+
+// EnableQuotes adds the monitor that replies to message links with an embed quoting the linked message.
+// Messages are only quoted if the person who posted the link can read them, so links can't be used to peek
+// into private channels, and NSFW messages are only quoted in NSFW channels.
+func (bot *Bot) EnableQuotes() *Bot {
+	bot.AddConfigSchema(QuoteConfig)
+	return bot.AddMonitor(NewMonitor("quotes", quoteMonitor).SetGuildOnly(true))
+}
+
+// QuoteEmbed builds the embed quoting msg, which was posted in channel.
+func QuoteEmbed(msg *discordgo.Message, channel *discordgo.Channel, color int) *discordgo.MessageEmbed {
+	embed := NewEmbed().
+		SetAuthor(msg.Author.Username, msg.Author.AvatarURL("64")).
+		SetDescription(msg.Content).
+		SetColor(color).
+		SetFooter("#" + channel.Name)
+
+	var files []string
+	for _, attachment := range msg.Attachments {
+		if embed.Image == nil && attachment.Width > 0 {
+			embed.SetImage(attachment.URL)
+			continue
+		}
+		files = append(files, fmt.Sprintf("[%s](%s)", attachment.Filename, attachment.URL))
+	}
+	if len(files) > 0 {
+		embed.AddField("Attachments", strings.Join(files, "\n"))
+	}
+	// An embed only message, show the text of it's first embed instead.
+	if msg.Content == "" && len(msg.Embeds) > 0 {
+		embed.SetDescription(msg.Embeds[0].Description)
+	}
+	embed.Timestamp = string(msg.Timestamp)
+	return embed.Truncate().Build()
+}
+
+// canQuote checks if userID can read the channel and the bot can read it's history.
+func (bot *Bot) canQuote(userID string, channel *discordgo.Channel) bool {
+	perms, err := bot.Session.State.UserChannelPermissions(userID, channel.ID)
+	if err != nil || !Permissions(perms).Has(discordgo.PermissionReadMessages|discordgo.PermissionReadMessageHistory) {
+		return false
+	}
+	perms, err = bot.Session.State.UserChannelPermissions(bot.Session.State.User.ID, channel.ID)
+	return err == nil && Permissions(perms).Has(discordgo.PermissionReadMessages|discordgo.PermissionReadMessageHistory)
+}
+
+func quoteMonitor(bot *Bot, ctx *MonitorContext) {
+	links := MessageLinkRegex.FindAllStringSubmatch(ctx.Message.Content, MaxQuotes)
+	if len(links) == 0 || !QuoteConfig.GetBool(bot, ctx.Guild.ID, "enabled") {
+		return
+	}
+	perms, err := ctx.Session.State.UserChannelPermissions(ctx.Session.State.User.ID, ctx.Channel.ID)
+	if err != nil || !Permissions(perms).Has(discordgo.PermissionSendMessages|discordgo.PermissionEmbedLinks) {
+		return
+	}
+
+	for _, link := range links {
+		channel, err := ctx.Session.State.Channel(link[2])
+		if err != nil || channel.GuildID != link[1] {
+			continue
+		}
+		if channel.NSFW && !ctx.Channel.NSFW {
+			continue
+		}
+		if !bot.canQuote(ctx.Author.ID, channel) {
+			continue
+		}
+		msg, err := ctx.Session.State.Message(channel.ID, link[3])
+		if err != nil {
+			msg, err = ctx.Session.ChannelMessage(channel.ID, link[3])
+			if err != nil {
+				continue
+			}
+		}
+		ctx.Session.ChannelMessageSendEmbed(ctx.Channel.ID, QuoteEmbed(msg, channel, bot.Color))
+	}
+}
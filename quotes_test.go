@@ -0,0 +1,15 @@
+package sapphire
+
+import (
+	"testing"
+)
+
+func TestMessageLinkRegex(t *testing.T) {
+	match := MessageLinkRegex.FindStringSubmatch("look https://canary.discord.com/channels/123456789012345678/223456789012345678/323456789012345678 here")
+	if len(match) != 4 || match[1] != "123456789012345678" || match[2] != "223456789012345678" || match[3] != "323456789012345678" {
+		t.Errorf("Unexpected match %v", match)
+	}
+	if MessageLinkRegex.MatchString("https://discord.com/channels/@me/223456789012345678/323456789012345678") {
+		t.Error("Expected DM links to not match")
+	}
+}
@@ -0,0 +1,65 @@
+package sapphire
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"time"
+)
+
+// ErrNoTriggerMessage is returned by ctx.React and ctx.RemoveOwnReactions for interactions, they have no message to react to.
+var ErrNoTriggerMessage = errors.New("interactions have no message to react to")
+
+// ReactionDelay is the delay between consecutive reaction calls made by the reaction helpers.
+// Discord only allows a few reactions per second on a channel, spacing them out avoids hitting the rate limit.
+var ReactionDelay = 250 * time.Millisecond
+
+// AddReactions adds the emojis to the message in order, waiting ReactionDelay between each.
+// A failing reaction doesn't stop the rest, the first error encountered is returned.
+func AddReactions(s *discordgo.Session, channelID, messageID string, emojis ...string) error {
+	var first error
+	for i, emoji := range emojis {
+		if i > 0 {
+			time.Sleep(ReactionDelay)
+		}
+		if err := s.MessageReactionAdd(channelID, messageID, emoji); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// RemoveOwnReactions removes the reactions the bot added to the message.
+// If no emojis are given it fetches the message and removes every reaction the bot has on it.
+// Like AddReactions a failing removal doesn't stop the rest and the first error is returned.
+func RemoveOwnReactions(s *discordgo.Session, channelID, messageID string, emojis ...string) error {
+	if len(emojis) == 0 {
+		msg, err := s.ChannelMessage(channelID, messageID)
+		if err != nil {
+			return err
+		}
+		for _, reaction := range msg.Reactions {
+			if reaction.Me && reaction.Emoji != nil {
+				emojis = append(emojis, reactionName(reaction.Emoji))
+			}
+		}
+	}
+
+	var first error
+	for i, emoji := range emojis {
+		if i > 0 {
+			time.Sleep(ReactionDelay)
+		}
+		if err := s.MessageReactionRemove(channelID, messageID, emoji, "@me"); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// reactionName returns the name used by the API to refer to the emoji, custom emojis are name:id
+func reactionName(emoji *discordgo.Emoji) string {
+	if emoji.ID == "" {
+		return emoji.Name
+	}
+	return emoji.Name + ":" + emoji.ID
+}
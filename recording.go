@@ -0,0 +1,195 @@
+package sapphire
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// RecordedEvent is a raw gateway event in a recording, one JSON object per line.
+type RecordedEvent struct {
+	Type     string          `json:"t"`
+	Sequence int64           `json:"s"`
+	At       time.Time       `json:"at"`
+	Data     json.RawMessage `json:"d"`
+}
+
+// eventTypes creates the typed events replays decode payloads into, other events reach *discordgo.Event handlers only.
+var eventTypes = map[string]func() interface{}{
+	"READY":                   func() interface{} { return &discordgo.Ready{} },
+	"RESUMED":                 func() interface{} { return &discordgo.Resumed{} },
+	"MESSAGE_CREATE":          func() interface{} { return &discordgo.MessageCreate{} },
+	"MESSAGE_UPDATE":          func() interface{} { return &discordgo.MessageUpdate{} },
+	"MESSAGE_DELETE":          func() interface{} { return &discordgo.MessageDelete{} },
+	"MESSAGE_DELETE_BULK":     func() interface{} { return &discordgo.MessageDeleteBulk{} },
+	"MESSAGE_REACTION_ADD":    func() interface{} { return &discordgo.MessageReactionAdd{} },
+	"MESSAGE_REACTION_REMOVE": func() interface{} { return &discordgo.MessageReactionRemove{} },
+	"GUILD_CREATE":            func() interface{} { return &discordgo.GuildCreate{} },
+	"GUILD_UPDATE":            func() interface{} { return &discordgo.GuildUpdate{} },
+	"GUILD_DELETE":            func() interface{} { return &discordgo.GuildDelete{} },
+	"GUILD_MEMBER_ADD":        func() interface{} { return &discordgo.GuildMemberAdd{} },
+	"GUILD_MEMBER_UPDATE":     func() interface{} { return &discordgo.GuildMemberUpdate{} },
+	"GUILD_MEMBER_REMOVE":     func() interface{} { return &discordgo.GuildMemberRemove{} },
+	"CHANNEL_CREATE":          func() interface{} { return &discordgo.ChannelCreate{} },
+	"CHANNEL_UPDATE":          func() interface{} { return &discordgo.ChannelUpdate{} },
+	"CHANNEL_DELETE":          func() interface{} { return &discordgo.ChannelDelete{} },
+	"PRESENCE_UPDATE":         func() interface{} { return &discordgo.PresenceUpdate{} },
+	"VOICE_STATE_UPDATE":      func() interface{} { return &discordgo.VoiceStateUpdate{} },
+}
+
+var eventType = reflect.TypeOf(&discordgo.Event{})
+
+// eventHandler holds a handler added with bot.AddHandler, funcs can't be compared so removing looks for the entry.
+type eventHandler struct {
+	fn interface{}
+}
+
+// AddHandler adds a discordgo event handler like Session.AddHandler, handlers added this way are reached by
+// bot.ReplayEvents too. Returns a function removing the handler from the session and from replays.
+func (bot *Bot) AddHandler(handler interface{}) func() {
+	entry := &eventHandler{handler}
+	bot.handlersLock.Lock()
+	bot.handlers = append(bot.handlers, entry)
+	bot.handlersLock.Unlock()
+	remove := bot.Session.AddHandler(handler)
+	return func() {
+		remove()
+		bot.handlersLock.Lock()
+		defer bot.handlersLock.Unlock()
+		for i, h := range bot.handlers {
+			if h == entry {
+				// Copied so a dispatch still ranging over the old slice isn't changed under it.
+				bot.handlers = append(bot.handlers[:i:i], bot.handlers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// AddHandlerOnce adds a handler like bot.AddHandler that is removed after the first event it gets.
+func (bot *Bot) AddHandlerOnce(handler interface{}) func() {
+	fn := reflect.ValueOf(handler)
+	if fn.Kind() != reflect.Func {
+		return bot.AddHandler(handler)
+	}
+	var once sync.Once
+	var lock sync.Mutex
+	var remove func()
+	// Held until remove is set in case the event comes before AddHandler returns.
+	lock.Lock()
+	defer lock.Unlock()
+	remove = bot.AddHandler(reflect.MakeFunc(fn.Type(), func(args []reflect.Value) []reflect.Value {
+		once.Do(func() {
+			lock.Lock()
+			lock.Unlock()
+			remove()
+			fn.Call(args)
+		})
+		return nil
+	}).Interface())
+	return remove
+}
+
+// RecordEvents appends every raw gateway event the session receives to the file at path, for replaying them
+// later with bot.ReplayEvents. Recordings contain message contents and member data, keep them private.
+// Returns a function stopping the recording.
+func (bot *Bot) RecordEvents(path string) (func() error, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	var lock sync.Mutex
+	encoder := json.NewEncoder(file)
+	remove := bot.Session.AddHandler(func(s *discordgo.Session, e *discordgo.Event) {
+		lock.Lock()
+		defer lock.Unlock()
+		if err := encoder.Encode(RecordedEvent{Type: e.Type, Sequence: e.Sequence, At: time.Now(), Data: e.RawData}); err != nil {
+			bot.ErrorHandler(bot, err)
+		}
+	})
+	return func() error {
+		remove()
+		lock.Lock()
+		defer lock.Unlock()
+		return file.Close()
+	}, nil
+}
+
+// ReplayEvents dispatches a recording made with bot.RecordEvents without connecting to Discord, so monitors and
+// modules can be debugged against the same events over and over. Events update the session's state and run the
+// handlers added with bot.AddHandler, along with the monitors and commands. speed scales the time between events,
+// 2 replays twice as fast and 0 as fast as possible. Replies and other REST calls still go to Discord if the
+// session has a token, use a session without one to replay fully offline.
+func (bot *Bot) ReplayEvents(path string, speed float64) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if bot.Session.State == nil {
+		bot.Session.State = discordgo.NewState()
+		bot.Session.StateEnabled = true
+	}
+
+	scanner := bufio.NewScanner(file)
+	// Guild creates of big guilds are large.
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	var last time.Time
+	line := 0
+	for scanner.Scan() {
+		line++
+		recorded := RecordedEvent{}
+		if err := json.Unmarshal(scanner.Bytes(), &recorded); err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		if speed > 0 && !last.IsZero() {
+			time.Sleep(time.Duration(float64(recorded.At.Sub(last)) / speed))
+		}
+		last = recorded.At
+		if err := bot.DispatchEvent(recorded.Type, recorded.Data); err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// DispatchEvent decodes a raw gateway event and runs it through the state and the handlers added with bot.AddHandler
+func (bot *Bot) DispatchEvent(typ string, data json.RawMessage) error {
+	event := &discordgo.Event{Type: typ, RawData: data}
+	if create, ok := eventTypes[typ]; ok {
+		event.Struct = create()
+		if err := json.Unmarshal(data, event.Struct); err != nil {
+			return err
+		}
+		if bot.Session.State != nil && bot.Session.StateEnabled {
+			if err := bot.Session.State.OnInterface(bot.Session, event.Struct); err != nil {
+				bot.ErrorHandler(bot, err)
+			}
+		}
+	}
+
+	bot.handlersLock.Lock()
+	handlers := bot.handlers
+	bot.handlersLock.Unlock()
+	session := reflect.ValueOf(bot.Session)
+	for _, handler := range handlers {
+		fn := reflect.ValueOf(handler.fn)
+		if fn.Kind() != reflect.Func || fn.Type().NumIn() != 2 {
+			continue
+		}
+		switch in := fn.Type().In(1); {
+		case in == eventType:
+			fn.Call([]reflect.Value{session, reflect.ValueOf(event)})
+		case event.Struct != nil && in == reflect.TypeOf(event.Struct):
+			fn.Call([]reflect.Value{session, reflect.ValueOf(event.Struct)})
+		case event.Struct != nil && in.Kind() == reflect.Interface && reflect.TypeOf(event.Struct).Implements(in):
+			fn.Call([]reflect.Value{session, reflect.ValueOf(event.Struct)})
+		}
+	}
+	return nil
+}
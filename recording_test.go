@@ -0,0 +1,67 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplayEvents(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	var joined []string
+	var types []string
+	bot.AddHandler(func(s *discordgo.Session, m *discordgo.GuildMemberAdd) {
+		joined = append(joined, m.User.ID)
+	})
+	bot.AddHandler(func(s *discordgo.Session, e *discordgo.Event) {
+		types = append(types, e.Type)
+	})
+	dir, err := ioutil.TempDir("", "sapphire")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.jsonl")
+	recording := `{"t":"GUILD_MEMBER_ADD","s":1,"at":"2021-01-01T00:00:00Z","d":{"guild_id":"1","user":{"id":"2"}}}
+{"t":"SOMETHING_NEW","s":2,"at":"2021-01-01T00:00:01Z","d":{}}
+`
+	if err := ioutil.WriteFile(path, []byte(recording), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.ReplayEvents(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(joined) != 1 || joined[0] != "2" {
+		t.Errorf("Expected the member add handler to get the member, got %v", joined)
+	}
+	if len(types) != 2 || types[1] != "SOMETHING_NEW" {
+		t.Errorf("Expected the raw handler to get every event, got %v", types)
+	}
+}
+
+func TestAddHandlerRemove(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	added, once := 0, 0
+	remove := bot.AddHandler(func(s *discordgo.Session, m *discordgo.GuildMemberAdd) {
+		added++
+	})
+	bot.AddHandlerOnce(func(s *discordgo.Session, m *discordgo.GuildMemberAdd) {
+		once++
+	})
+	data := []byte(`{"guild_id":"1","user":{"id":"2"}}`)
+	if err := bot.DispatchEvent("GUILD_MEMBER_ADD", data); err != nil {
+		t.Fatal(err)
+	}
+	remove()
+	if err := bot.DispatchEvent("GUILD_MEMBER_ADD", data); err != nil {
+		t.Fatal(err)
+	}
+	if added != 1 {
+		t.Errorf("Expected the removed handler to run once, ran %d times", added)
+	}
+	if once != 1 {
+		t.Errorf("Expected the once handler to run once, ran %d times", once)
+	}
+}
@@ -0,0 +1,225 @@
+package sapphire
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits of user provided regular expressions, Go's regexp uses RE2 semantics so matching is linear in the input,
+// these keep the constant factor small.
+var (
+	MaxRegexLength       = 200                   // Maximum length of a pattern.
+	MaxRegexInstructions = 2000                  // Maximum size of a compiled pattern, big repetitions like (abc){1000} blow it up.
+	MaxRegexInput        = 4000                  // Only this many bytes of the input are matched.
+	MaxRegexPatterns     = 50                    // Maximum amount of patterns of a guild's regex filter.
+	RegexBudget          = time.Millisecond * 20 // Time spent matching one input against a RegexSet before giving up.
+)
+
+// ErrRegexBudget is returned when matching a RegexSet took longer than it's budget.
+var ErrRegexBudget = errors.New("regex matching exceeded it's time budget")
+
+// CompileSafeRegex compiles a user provided pattern after checking it against the limits.
+func CompileSafeRegex(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) > MaxRegexLength {
+		return nil, fmt.Errorf("The pattern is too long, it can be at most %d characters.", MaxRegexLength)
+	}
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return nil, err
+	}
+	if len(prog.Inst) > MaxRegexInstructions {
+		return nil, fmt.Errorf("The pattern is too complex, try smaller repetitions.")
+	}
+	return regexp.Compile(pattern)
+}
+
+// RegexSet matches an input against many user provided patterns within a time budget.
+type RegexSet struct {
+	Patterns []*regexp.Regexp
+	Budget   time.Duration // (default: RegexBudget)
+}
+
+// NewRegexSet compiles the patterns with CompileSafeRegex
+func NewRegexSet(patterns ...string) (*RegexSet, error) {
+	set := &RegexSet{Budget: RegexBudget}
+	for _, pattern := range patterns {
+		re, err := CompileSafeRegex(pattern)
+		if err != nil {
+			return nil, err
+		}
+		set.Patterns = append(set.Patterns, re)
+	}
+	return set, nil
+}
+
+// Match returns the first pattern matching the input, nil if none does.
+// Only MaxRegexInput bytes are matched, if the budget runs out before every pattern was tried ErrRegexBudget is returned.
+func (s *RegexSet) Match(input string) (*regexp.Regexp, error) {
+	if len(input) > MaxRegexInput {
+		input = input[:MaxRegexInput]
+	}
+	start := time.Now()
+	for _, re := range s.Patterns {
+		if time.Since(start) > s.Budget {
+			return nil, ErrRegexBudget
+		}
+		if re.MatchString(input) {
+			return re, nil
+		}
+	}
+	return nil, nil
+}
+
+// The settings key of a guild's regex filter patterns.
+const regexFilterKey = "automod.regex"
+
+type regexFilterTracker struct {
+	actions AutomodAction
+	// guild ID -> compiled patterns
+	sets map[string]*RegexSet
+	lock sync.Mutex
+}
+
+// EnableRegexFilter adds an automod rule matching messages against patterns set by each guild with the regex command.
+// Patterns are compiled with CompileSafeRegex and matched within RegexBudget so a bad pattern can't stall automod.
+// actions is what's done about matching messages, 0 deletes and warns.
+func (bot *Bot) EnableRegexFilter(actions AutomodAction) *Bot {
+	if bot.regexFilter != nil {
+		return bot
+	}
+	if actions == 0 {
+		actions = AutomodDelete | AutomodWarn
+	}
+	bot.regexFilter = &regexFilterTracker{actions: actions, sets: make(map[string]*RegexSet)}
+	bot.AddAutomodRule(NewAutomodRule("regex", regexFilterRule))
+	bot.AddCommand(NewCommand("regex", "Moderation", regexFilterCommand).
+		SetDescription("Manages the patterns automod removes messages for.").
+		SetUsage("<action:string> [pattern:string...]").
+		SetRequiredPermissions(discordgo.PermissionManageServer).
+		SetGuildOnly(true))
+	return bot
+}
+
+// RegexFilter returns the patterns of a guild's regex filter.
+func (bot *Bot) RegexFilter(guildID string) []string {
+	var patterns []string
+	if _, err := GetJSON(bot.Settings, guildID, regexFilterKey, &patterns); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+	return patterns
+}
+
+// SetRegexFilter replaces the patterns of a guild's regex filter, they must all compile with CompileSafeRegex.
+func (bot *Bot) SetRegexFilter(guildID string, patterns []string) error {
+	if len(patterns) > MaxRegexPatterns {
+		return fmt.Errorf("A server can have at most %d patterns.", MaxRegexPatterns)
+	}
+	set, err := NewRegexSet(patterns...)
+	if err != nil {
+		return err
+	}
+	if len(patterns) == 0 {
+		err = bot.Settings.Delete(guildID, regexFilterKey)
+	} else {
+		err = SetJSON(bot.Settings, guildID, regexFilterKey, patterns)
+	}
+	if err != nil {
+		return err
+	}
+	if bot.regexFilter != nil {
+		bot.regexFilter.lock.Lock()
+		bot.regexFilter.sets[guildID] = set
+		bot.regexFilter.lock.Unlock()
+	}
+	bot.filtersChanged(guildID)
+	return nil
+}
+
+// regexSet returns the compiled patterns of a guild, compiling them once.
+func (bot *Bot) regexSet(guildID string) *RegexSet {
+	bot.regexFilter.lock.Lock()
+	set, ok := bot.regexFilter.sets[guildID]
+	bot.regexFilter.lock.Unlock()
+	if ok {
+		return set
+	}
+	set, err := NewRegexSet(bot.RegexFilter(guildID)...)
+	if err != nil {
+		// Stored before the limits changed, skip the filter instead of failing every message.
+		bot.ErrorHandler(bot, err)
+		set = &RegexSet{}
+	}
+	bot.regexFilter.lock.Lock()
+	bot.regexFilter.sets[guildID] = set
+	bot.regexFilter.lock.Unlock()
+	return set
+}
+
+func regexFilterRule(ctx *AutomodContext) *Violation {
+	bot := ctx.Bot
+	set := bot.regexSet(ctx.Guild.ID)
+	if len(set.Patterns) == 0 {
+		return nil
+	}
+	re, err := set.Match(ctx.Text())
+	if err != nil {
+		bot.ErrorHandler(bot, fmt.Errorf("regex filter of guild %s: %v", ctx.Guild.ID, err))
+		return nil
+	}
+	if re == nil {
+		return nil
+	}
+	return &Violation{Reason: bot.LocaleFor(ctx.Guild.ID, ctx.Channel.ID).Get("AUTOMOD_REGEX"), Actions: bot.regexFilter.actions}
+}
+
+func regexFilterCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+	patterns := bot.RegexFilter(ctx.Guild.ID)
+	pattern := ctx.ArgString(1)
+	switch strings.ToLower(ctx.Arg(0).AsString()) {
+	case "list":
+		if len(patterns) == 0 {
+			ctx.ReplyLocale("COMMAND_REGEX_NONE", ctx.Prefix)
+			return
+		}
+		lines := make([]string, len(patterns))
+		for i, pattern := range patterns {
+			lines[i] = fmt.Sprintf("**%d.** `` %s ``", i+1, pattern)
+		}
+		ctx.Reply(strings.Join(lines, "\n"))
+	case "add":
+		if pattern == "" {
+			ctx.ReplyLocale("COMMAND_REGEX_USAGE", ctx.Prefix)
+			return
+		}
+		if err := bot.SetRegexFilter(ctx.Guild.ID, append(patterns, pattern)); err != nil {
+			ctx.ReplyLocale("COMMAND_REGEX_INVALID", Escape(err.Error()))
+			return
+		}
+		ctx.ReplyLocale("COMMAND_REGEX_ADDED")
+	case "remove":
+		for i, existing := range patterns {
+			if existing == pattern {
+				if err := bot.SetRegexFilter(ctx.Guild.ID, append(patterns[:i], patterns[i+1:]...)); err != nil {
+					ctx.Error(err)
+					return
+				}
+				ctx.ReplyLocale("COMMAND_REGEX_REMOVED")
+				return
+			}
+		}
+		ctx.ReplyLocale("COMMAND_REGEX_NOT_FOUND")
+	default:
+		ctx.ReplyLocale("COMMAND_REGEX_USAGE", ctx.Prefix)
+	}
+}
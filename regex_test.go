@@ -0,0 +1,28 @@
+package sapphire
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSafeRegex(t *testing.T) {
+	for _, invalid := range []string{"(", "(abc){1000}", strings.Repeat("a", MaxRegexLength+1)} {
+		if _, err := CompileSafeRegex(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+	set, err := NewRegexSet(`(?i)free\s+nitro`, `discord\.gift`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if re, err := set.Match("Get FREE  nitro here"); err != nil || re == nil || re != set.Patterns[0] {
+		t.Errorf("Expected the first pattern to match, got %v %v", re, err)
+	}
+	if re, _ := set.Match("hello"); re != nil {
+		t.Errorf("Expected no match, got %v", re)
+	}
+	set.Budget = -1
+	if _, err := set.Match("hello"); err != ErrRegexBudget {
+		t.Errorf("Expected the budget to run out, got %v", err)
+	}
+}
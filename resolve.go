@@ -0,0 +1,161 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxCandidates is the most matches a name can have for the user to be asked which one they meant,
+// with more they're asked to be more specific.
+var MaxCandidates = 10
+
+// CandidateTimeout is how long the user has to pick one of the matches of an ambiguous name.
+var CandidateTimeout = 30 * time.Second
+
+// AwaitMessage waits for the next message of the user in the channel, nil if none came within timeout.
+func (bot *Bot) AwaitMessage(channelID, userID string, timeout time.Duration) *discordgo.Message {
+	messages := make(chan *discordgo.Message, 1)
+	remove := bot.Session.AddHandler(func(s *discordgo.Session, m *discordgo.MessageCreate) {
+		if m.ChannelID != channelID || m.Author == nil || m.Author.ID != userID {
+			return
+		}
+		select {
+		case messages <- m.Message:
+		default:
+		}
+	})
+	defer remove()
+	select {
+	case msg := <-messages:
+		return msg
+	case <-time.After(timeout):
+		return nil
+	}
+}
+
+// Name match strengths, the strongest ones win e.g an exact name beats names only containing it.
+const (
+	matchNone = iota
+	matchPartial
+	matchFold
+	matchExact
+)
+
+// matchName returns how well query matches one of the names.
+func matchName(query string, names ...string) int {
+	best := matchNone
+	lower := strings.ToLower(query)
+	for _, name := range names {
+		switch {
+		case name == "":
+		case name == query:
+			return matchExact
+		case strings.EqualFold(name, query):
+			best = matchFold
+		case best < matchPartial && strings.Contains(strings.ToLower(name), lower):
+			best = matchPartial
+		}
+	}
+	return best
+}
+
+// resolveByName finds the best matches of query among count entities, names returns the names an entity goes by.
+// A single best match is returned right away, when several match equally well the user is asked which one they meant.
+// Returns -1 if nothing matched.
+func (ctx *CommandContext) resolveByName(tag *UsageTag, query string, count int, names func(i int) []string, label func(i int) string) (int, error) {
+	best := matchNone
+	var matches []int
+	for i := 0; i < count; i++ {
+		score := matchName(query, names(i)...)
+		if score == matchNone || score < best {
+			continue
+		}
+		if score > best {
+			best, matches = score, nil
+		}
+		matches = append(matches, i)
+	}
+	switch {
+	case len(matches) == 0:
+		return -1, nil
+	case len(matches) == 1:
+		return matches[0], nil
+	case len(matches) > MaxCandidates:
+		return -1, NewArgumentError("ARGUMENT_TOO_MANY_MATCHES", tag.Name, len(matches), query)
+	}
+
+	lines := make([]string, len(matches))
+	for i, match := range matches {
+		lines[i] = fmt.Sprintf("**%d.** %s", i+1, label(match))
+	}
+	prompt, _ := ctx.localize("ARGUMENT_CHOOSE", query, strings.Join(lines, "\n"))
+	ctx.ReplyNoEdit(prompt)
+	reply := ctx.Bot.AwaitMessage(ctx.Channel.ID, ctx.Author.ID, CandidateTimeout)
+	if reply == nil {
+		return -1, NewArgumentError("ARGUMENT_CHOICE_CANCELLED", tag.Name)
+	}
+	choice, err := strconv.Atoi(strings.TrimSpace(reply.Content))
+	if err != nil || choice < 1 || choice > len(matches) {
+		return -1, NewArgumentError("ARGUMENT_CHOICE_CANCELLED", tag.Name)
+	}
+	return matches[choice-1], nil
+}
+
+// resolveMember finds a member of the current guild by username, nickname or username#discriminator.
+func (ctx *CommandContext) resolveMember(tag *UsageTag, query string) (*discordgo.Member, error) {
+	if ctx.Guild == nil {
+		return nil, nil
+	}
+	members := ctx.Guild.Members
+	i, err := ctx.resolveByName(tag, query, len(members), func(i int) []string {
+		user := members[i].User
+		return []string{user.Username, members[i].Nick, user.Username + "#" + user.Discriminator}
+	}, func(i int) string {
+		user := members[i].User
+		if members[i].Nick != "" {
+			return fmt.Sprintf("%s (%s#%s)", Escape(members[i].Nick), Escape(user.Username), user.Discriminator)
+		}
+		return Escape(user.Username) + "#" + user.Discriminator
+	})
+	if i < 0 {
+		return nil, err
+	}
+	return members[i], nil
+}
+
+// resolveRole finds a role of the current guild by name.
+func (ctx *CommandContext) resolveRole(tag *UsageTag, query string) (*discordgo.Role, error) {
+	if ctx.Guild == nil {
+		return nil, nil
+	}
+	roles := ctx.Guild.Roles
+	i, err := ctx.resolveByName(tag, strings.TrimPrefix(query, "@"), len(roles), func(i int) []string {
+		return []string{roles[i].Name}
+	}, func(i int) string {
+		return "@" + Escape(roles[i].Name)
+	})
+	if i < 0 {
+		return nil, err
+	}
+	return roles[i], nil
+}
+
+// resolveChannel finds a channel of the current guild by name.
+func (ctx *CommandContext) resolveChannel(tag *UsageTag, query string) (*discordgo.Channel, error) {
+	if ctx.Guild == nil {
+		return nil, nil
+	}
+	channels := ctx.Guild.Channels
+	i, err := ctx.resolveByName(tag, strings.TrimPrefix(query, "#"), len(channels), func(i int) []string {
+		return []string{channels[i].Name}
+	}, func(i int) string {
+		return "<#" + channels[i].ID + ">"
+	})
+	if i < 0 {
+		return nil, err
+	}
+	return channels[i], nil
+}
@@ -0,0 +1,61 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"testing"
+)
+
+func TestResolveByName(t *testing.T) {
+	member := func(id, name, nick string) *discordgo.Member {
+		return &discordgo.Member{User: &discordgo.User{ID: id, Username: name, Discriminator: "0001"}, Nick: nick}
+	}
+	ctx := &CommandContext{Guild: &discordgo.Guild{
+		Members:  []*discordgo.Member{member("1", "alice", ""), member("2", "Bob", "bobby"), member("3", "bobcat", "")},
+		Roles:    []*discordgo.Role{{ID: "10", Name: "Moderators"}, {ID: "11", Name: "mod"}, {ID: "12", Name: "Admins"}},
+		Channels: []*discordgo.Channel{{ID: "20", Name: "general"}, {ID: "21", Name: "bot-commands"}},
+	}}
+	tag := &UsageTag{Name: "target", Type: "member"}
+
+	expect := func(typ, raw, id string) {
+		tag.Type = typ
+		arg, err := ParseArgument(ctx, tag, raw)
+		if err != nil {
+			t.Errorf("Expected %q to resolve to %s but got %v", raw, id, err)
+			return
+		}
+		var got string
+		switch v := arg.Value().(type) {
+		case *discordgo.Member:
+			got = v.User.ID
+		case *discordgo.User:
+			got = v.ID
+		case *discordgo.Role:
+			got = v.ID
+		case *discordgo.Channel:
+			got = v.ID
+		}
+		if got != id {
+			t.Errorf("Expected %q to resolve to %s but got %s", raw, id, got)
+		}
+	}
+	expect("member", "alice", "1")
+	expect("member", "BOB", "2")
+	expect("member", "bobby", "2")
+	expect("member", "bobcat#0001", "3")
+	expect("member", "ali", "1")
+	expect("user", "bobc", "3")
+	expect("role", "mod", "11")
+	expect("role", "@admin", "12")
+	expect("role", "Moder", "10")
+	expect("channel", "#bot", "21")
+
+	tag.Type = "member"
+	if _, err := ParseArgument(ctx, tag, "nobody"); err == nil || err.(*ArgumentError).Key != "ARGUMENT_MEMBER_INVALID" {
+		t.Errorf("Expected ARGUMENT_MEMBER_INVALID but got %v", err)
+	}
+	defer func(max int) { MaxCandidates = max }(MaxCandidates)
+	MaxCandidates = 1
+	if _, err := ParseArgument(ctx, tag, "bo"); err == nil || err.(*ArgumentError).Key != "ARGUMENT_TOO_MANY_MATCHES" {
+		t.Errorf("Expected ARGUMENT_TOO_MANY_MATCHES but got %v", err)
+	}
+}
@@ -0,0 +1,319 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"sync"
+	"time"
+)
+
+// ResponseMessage is a message sent through a Response.
+type ResponseMessage struct {
+	Content    string
+	Embed      *discordgo.MessageEmbed
+	Ephemeral  bool         // Only shown to the user who ran the command, interactions only.
+	Components []*Component // Action rows of buttons and select menus, see NewActionRow
+}
+
+// Response is where a command's replies go. Message commands reply in their channel, slash commands,
+// components and modals answer their interaction first and send follow-ups after that.
+// ctx.Reply and the other reply functions go through it so the same command works for both.
+type Response interface {
+	// Reply sends the command's reply, later calls edit that same reply.
+	Reply(msg *ResponseMessage) (*discordgo.Message, error)
+	// Send sends a new message, for interactions the first one still answers the interaction.
+	Send(msg *ResponseMessage) (*discordgo.Message, error)
+	// Edit edits a message sent through this response.
+	Edit(sent *discordgo.Message, msg *ResponseMessage) (*discordgo.Message, error)
+	// Delete deletes a message sent through this response.
+	Delete(sent *discordgo.Message) error
+	// Defer tells the user the reply is on it's way, typing in channels and "thinking..." for interactions.
+	Defer() error
+}
+
+// response returns the context's Response, replying in the command's channel unless one was set.
+func (ctx *CommandContext) response() Response {
+	ctx.repliesLock.Lock()
+	defer ctx.repliesLock.Unlock()
+	if ctx.Response == nil {
+		ctx.Response = &channelResponse{bot: ctx.Bot, channelID: ctx.Channel.ID, messageID: ctx.Message.ID}
+	}
+	return ctx.Response
+}
+
+// respond replies with msg, editing the previous reply if the command is editable.
+func (ctx *CommandContext) respond(msg *ResponseMessage) (*discordgo.Message, error) {
+	if ctx.Command == nil || !ctx.Command.Editable || ctx.invoked {
+		return ctx.response().Send(msg)
+	}
+	return ctx.response().Reply(msg)
+}
+
+// firstResponse returns how long it took to answer the context's interaction, 0 for message commands.
+func (ctx *CommandContext) firstResponse() time.Duration {
+	if r, ok := ctx.Response.(*interactionResponse); ok {
+		return r.firstResponse()
+	}
+	return 0
+}
+
+// channelResponse replies to message commands, the reply is remembered in bot.CommandEdits so editing the
+// command's message edits the reply.
+type channelResponse struct {
+	bot       *Bot
+	channelID string
+	messageID string
+}
+
+func (r *channelResponse) Reply(msg *ResponseMessage) (*discordgo.Message, error) {
+	m, ok := r.bot.CommandEdits[r.messageID]
+	if !ok {
+		sent, err := r.Send(msg)
+		if err != nil {
+			return nil, err
+		}
+		r.bot.CommandEdits[r.messageID] = sent.ID
+		return sent, nil
+	}
+	return r.Edit(&discordgo.Message{ID: m, ChannelID: r.channelID}, msg)
+}
+
+func (r *channelResponse) Send(msg *ResponseMessage) (*discordgo.Message, error) {
+	switch {
+	case msg.Components != nil:
+		// discordgo doesn't know about components, they are sent with a raw request.
+		endpoint := discordgo.EndpointChannelMessages(r.channelID)
+		return decodeMessage(r.bot.request("POST", endpoint, messagePayload(msg), endpoint))
+	case msg.Embed == nil:
+		return r.bot.Session.ChannelMessageSend(r.channelID, msg.Content)
+	case msg.Content == "":
+		return r.bot.Session.ChannelMessageSendEmbed(r.channelID, msg.Embed)
+	}
+	return r.bot.Session.ChannelMessageSendComplex(r.channelID, &discordgo.MessageSend{Content: msg.Content, Embed: msg.Embed})
+}
+
+func (r *channelResponse) Edit(sent *discordgo.Message, msg *ResponseMessage) (*discordgo.Message, error) {
+	if msg.Components != nil {
+		endpoint := discordgo.EndpointChannelMessage(sent.ChannelID, sent.ID)
+		return decodeMessage(r.bot.request("PATCH", endpoint, messagePayload(msg), discordgo.EndpointChannelMessages(sent.ChannelID)))
+	}
+	edit := discordgo.NewMessageEdit(sent.ChannelID, sent.ID).SetContent(msg.Content)
+	if msg.Embed != nil {
+		edit = edit.SetEmbed(msg.Embed)
+	}
+	return r.bot.Session.ChannelMessageEditComplex(edit)
+}
+
+func (r *channelResponse) Delete(sent *discordgo.Message) error {
+	return r.bot.Session.ChannelMessageDelete(sent.ChannelID, sent.ID)
+}
+
+func (r *channelResponse) Defer() error {
+	return r.bot.Session.ChannelTyping(r.channelID)
+}
+
+// Interaction response states.
+const (
+	responsePending = iota
+	responseDeferred
+	responseReplied
+)
+
+// interactionResponse answers an interaction. The first message is the interaction's callback, or fills in the
+// deferred response, later replies edit it and later sends are follow-ups.
+type interactionResponse struct {
+	bot         *Bot
+	interaction *Interaction
+	lock        sync.Mutex
+	state       int
+	received    time.Time // When the interaction arrived.
+	answered    time.Time // When the interaction was first answered.
+}
+
+func (r *interactionResponse) Reply(msg *ResponseMessage) (*discordgo.Message, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	switch r.state {
+	case responsePending:
+		return r.callback(msg)
+	case responseDeferred:
+		r.state = responseReplied
+	}
+	return r.editOriginal(msg)
+}
+
+func (r *interactionResponse) Send(msg *ResponseMessage) (*discordgo.Message, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	switch r.state {
+	case responsePending:
+		return r.callback(msg)
+	case responseDeferred:
+		// The deferred "thinking..." message is the first message, the user is waiting for it to be filled in.
+		r.state = responseReplied
+		return r.editOriginal(msg)
+	}
+	endpoint := interactionWebhookEndpoint(r.interaction)
+	body, err := r.bot.request("POST", endpoint+"?wait=true", messagePayload(msg), endpoint)
+	return decodeMessage(body, err)
+}
+
+// Edit edits the answer or a follow-up through the interaction's webhook, ephemeral messages can't be edited otherwise.
+func (r *interactionResponse) Edit(sent *discordgo.Message, msg *ResponseMessage) (*discordgo.Message, error) {
+	endpoint := interactionWebhookEndpoint(r.interaction)
+	return decodeMessage(r.bot.request("PATCH", endpoint+"/messages/"+sent.ID, messagePayload(msg), endpoint))
+}
+
+// Delete deletes the answer or a follow-up, interaction tokens expire after 15 minutes so it can't be done later than that.
+func (r *interactionResponse) Delete(sent *discordgo.Message) error {
+	endpoint := interactionWebhookEndpoint(r.interaction)
+	_, err := r.bot.request("DELETE", endpoint+"/messages/"+sent.ID, nil, endpoint)
+	return err
+}
+
+func (r *interactionResponse) Defer() error {
+	_, err := r.deferPending()
+	return err
+}
+
+// deferPending defers the interaction if it wasn't answered yet, returns true if it did.
+func (r *interactionResponse) deferPending() (bool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.state != responsePending {
+		return false, nil
+	}
+	endpoint := interactionCallbackEndpoint(r.interaction)
+	if _, err := r.bot.request("POST", endpoint, map[string]interface{}{"type": ResponseDeferredChannelMessage}, endpoint); err != nil {
+		return false, err
+	}
+	r.answer(responseDeferred)
+	return true, nil
+}
+
+// answer moves on from pending, the first answer's time is kept for CommandRan.FirstResponse
+func (r *interactionResponse) answer(state int) {
+	if r.answered.IsZero() {
+		r.answered = time.Now()
+	}
+	r.state = state
+}
+
+// firstResponse returns how long it took to answer the interaction, 0 if it wasn't.
+func (r *interactionResponse) firstResponse() time.Duration {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.answered.IsZero() {
+		return 0
+	}
+	return r.answered.Sub(r.received)
+}
+
+// callback answers the interaction with msg, with_response has Discord return the sent message in the same request.
+func (r *interactionResponse) callback(msg *ResponseMessage) (*discordgo.Message, error) {
+	endpoint := interactionCallbackEndpoint(r.interaction)
+	data := map[string]interface{}{"type": ResponseChannelMessage, "data": messagePayload(msg)}
+	body, err := r.bot.request("POST", endpoint+"?with_response=true", data, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	r.answer(responseReplied)
+	return decodeCallback(body)
+}
+
+// update replaces the message of the clicked component.
+func (r *interactionResponse) update(msg *ResponseMessage) (*discordgo.Message, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.state != responsePending {
+		return r.editOriginal(msg)
+	}
+	endpoint := interactionCallbackEndpoint(r.interaction)
+	data := map[string]interface{}{"type": ResponseUpdateMessage, "data": messagePayload(msg)}
+	if _, err := r.bot.request("POST", endpoint, data, endpoint); err != nil {
+		return nil, err
+	}
+	r.answer(responseReplied)
+	return r.interaction.Message, nil
+}
+
+// modal answers the interaction with a modal, it can't be shown after the interaction was answered.
+func (r *interactionResponse) modal(data map[string]interface{}) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.state != responsePending {
+		return ErrNotInteraction
+	}
+	endpoint := interactionCallbackEndpoint(r.interaction)
+	if _, err := r.bot.request("POST", endpoint, map[string]interface{}{"type": ResponseModal, "data": data}, endpoint); err != nil {
+		return err
+	}
+	r.answer(responseReplied)
+	return nil
+}
+
+func (r *interactionResponse) editOriginal(msg *ResponseMessage) (*discordgo.Message, error) {
+	endpoint := interactionWebhookEndpoint(r.interaction)
+	return decodeMessage(r.bot.request("PATCH", endpoint+"/messages/@original", messagePayload(msg), endpoint))
+}
+
+// messagePayload is the JSON body of msg for interaction callbacks and webhooks.
+// The content is always set so an embed reply clears the previous content like it does in channels.
+func messagePayload(msg *ResponseMessage) map[string]interface{} {
+	data := map[string]interface{}{"content": msg.Content}
+	if msg.Embed != nil {
+		data["embeds"] = []*discordgo.MessageEmbed{msg.Embed}
+	}
+	if msg.Ephemeral {
+		data["flags"] = messageFlagEphemeral
+	}
+	if msg.Components != nil {
+		data["components"] = msg.Components
+	}
+	return data
+}
+
+func decodeMessage(body []byte, err error) (*discordgo.Message, error) {
+	if err != nil {
+		return nil, err
+	}
+	msg := &discordgo.Message{}
+	if err := json.Unmarshal(body, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// decodeCallback returns the message of an interaction callback sent with_response.
+func decodeCallback(body []byte) (*discordgo.Message, error) {
+	res := struct {
+		Resource struct {
+			Message *discordgo.Message `json:"message"`
+		} `json:"resource"`
+	}{}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	if res.Resource.Message == nil {
+		return nil, errors.New("the interaction callback didn't return the message")
+	}
+	return res.Resource.Message, nil
+}
+
+// request does a REST request, interactions use this so tests can see what would be sent.
+func (bot *Bot) request(method, endpoint string, data interface{}, bucket string) ([]byte, error) {
+	if bot.requestHook != nil {
+		return bot.requestHook(method, endpoint, data, bucket)
+	}
+	return bot.Session.RequestWithBucketID(method, endpoint, data, bucket)
+}
+
+func interactionCallbackEndpoint(i *Interaction) string {
+	return discordgo.EndpointAPI + "interactions/" + i.ID + "/" + i.Token + "/callback"
+}
+
+// interactionWebhookEndpoint is the webhook of an interaction, it edits the response and sends follow-ups.
+func interactionWebhookEndpoint(i *Interaction) string {
+	return discordgo.EndpointAPI + "webhooks/" + i.ApplicationID + "/" + i.Token
+}
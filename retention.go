@@ -0,0 +1,225 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GuildDataSubject is optionally implemented by data subjects that also store guild scoped data.
+// It's used by bot.ExportGuildData, bot.DeleteGuildData and retention policies.
+type GuildDataSubject interface {
+	// ExportGuildData returns all data stored about the guild, nil if there is nothing.
+	ExportGuildData(guildID string) (interface{}, error)
+	// DeleteGuildData deletes all data stored about the guild.
+	DeleteGuildData(guildID string) error
+}
+
+// RetentionPolicy decides what happens to a guild's data after the bot is removed from it.
+type RetentionPolicy struct {
+	After time.Duration // How long to keep the data after leaving, rejoining before that cancels the cleanup.
+	// Archive is called with the exported data before it's deleted, if it returns an error nothing is deleted.
+	// Leave it nil to purge without archiving.
+	Archive func(guildID string, data map[string]interface{}) error
+}
+
+// retentionKeyPrefix is the bot wide settings key prefix used to remember pending cleanups across restarts.
+const retentionKeyPrefix = "retention.pending."
+
+type retentionTracker struct {
+	tasks map[string]*ScheduledTask
+	lock  sync.Mutex
+}
+
+// SetRetentionPolicy sets the policy applied to guilds the bot is removed from, nil keeps data forever.
+func (bot *Bot) SetRetentionPolicy(policy *RetentionPolicy) *Bot {
+	bot.RetentionPolicy = policy
+	return bot
+}
+
+// ExportGuildData collects the guild's settings and the data of every data subject implementing GuildDataSubject.
+// Settings are included under the "settings" key when the settings provider implements SettingsIterator.
+func (bot *Bot) ExportGuildData(guildID string) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+	failed := make(map[string]error)
+
+	if it, ok := bot.Settings.(SettingsIterator); ok {
+		settings, err := bot.guildSettings(guildID, it)
+		if err != nil {
+			failed["settings"] = err
+		} else if len(settings) > 0 {
+			data["settings"] = settings
+		}
+	}
+
+	for name, subject := range bot.DataSubjects {
+		guildSubject, ok := subject.(GuildDataSubject)
+		if !ok {
+			continue
+		}
+		res, err := guildSubject.ExportGuildData(guildID)
+		if err != nil {
+			failed[name] = err
+			continue
+		}
+		if res != nil {
+			data[name] = res
+		}
+	}
+
+	if len(failed) > 0 {
+		return data, &DataSubjectError{Errors: failed}
+	}
+	return data, nil
+}
+
+// DeleteGuildData deletes the guild's settings and data from every data subject implementing GuildDataSubject.
+func (bot *Bot) DeleteGuildData(guildID string) error {
+	failed := make(map[string]error)
+
+	if it, ok := bot.Settings.(SettingsIterator); ok {
+		keys, err := it.Keys(guildID)
+		if err != nil {
+			failed["settings"] = err
+		}
+		for _, key := range keys {
+			if err := bot.Settings.Delete(guildID, key); err != nil {
+				failed["settings"] = err
+			}
+		}
+	}
+
+	for name, subject := range bot.DataSubjects {
+		if guildSubject, ok := subject.(GuildDataSubject); ok {
+			if err := guildSubject.DeleteGuildData(guildID); err != nil {
+				failed[name] = err
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return &DataSubjectError{Errors: failed}
+	}
+	return nil
+}
+
+func (bot *Bot) guildSettings(guildID string, it SettingsIterator) (map[string]string, error) {
+	keys, err := it.Keys(guildID)
+	if err != nil {
+		return nil, err
+	}
+	settings := make(map[string]string, len(keys))
+	for _, key := range keys {
+		value, ok, err := bot.Settings.Get(guildID, key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			settings[key] = value
+		}
+	}
+	return settings, nil
+}
+
+// scheduleRetention schedules the cleanup of a guild at the given time and remembers it in the settings.
+func (bot *Bot) scheduleRetention(guildID string, at time.Time) {
+	bot.retention.lock.Lock()
+	defer bot.retention.lock.Unlock()
+
+	if task, ok := bot.retention.tasks[guildID]; ok {
+		task.Cancel()
+	}
+	bot.Settings.Set("", retentionKeyPrefix+guildID, at.Format(time.RFC3339))
+	bot.retention.tasks[guildID] = bot.Scheduler.At(at, func() {
+		bot.runRetention(guildID)
+	})
+}
+
+// cancelRetention cancels a pending cleanup, e.g when the bot rejoins the guild.
+func (bot *Bot) cancelRetention(guildID string) {
+	bot.retention.lock.Lock()
+	defer bot.retention.lock.Unlock()
+
+	if task, ok := bot.retention.tasks[guildID]; ok {
+		task.Cancel()
+		delete(bot.retention.tasks, guildID)
+	}
+	bot.Settings.Delete("", retentionKeyPrefix+guildID)
+}
+
+func (bot *Bot) runRetention(guildID string) {
+	bot.retention.lock.Lock()
+	delete(bot.retention.tasks, guildID)
+	bot.retention.lock.Unlock()
+
+	policy := bot.RetentionPolicy
+	if policy == nil {
+		return
+	}
+
+	if policy.Archive != nil {
+		data, err := bot.ExportGuildData(guildID)
+		if err != nil {
+			bot.ErrorHandler(bot, fmt.Errorf("retention: exporting guild %s failed: %v", guildID, err))
+			return
+		}
+		if err := policy.Archive(guildID, data); err != nil {
+			bot.ErrorHandler(bot, fmt.Errorf("retention: archiving guild %s failed: %v", guildID, err))
+			return
+		}
+	}
+
+	if err := bot.DeleteGuildData(guildID); err != nil {
+		bot.ErrorHandler(bot, fmt.Errorf("retention: deleting guild %s failed: %v", guildID, err))
+	}
+	bot.Settings.Delete("", retentionKeyPrefix+guildID)
+}
+
+// restoreRetention reschedules cleanups remembered in the settings from before a restart.
+// Guilds we are in again are cancelled instead. Only works if the settings provider implements SettingsIterator
+func (bot *Bot) restoreRetention(ready *discordgo.Ready) {
+	current := make(map[string]bool, len(ready.Guilds))
+	for _, guild := range ready.Guilds {
+		current[guild.ID] = true
+	}
+
+	it, ok := bot.Settings.(SettingsIterator)
+	if !ok {
+		return
+	}
+	keys, err := it.Keys("")
+	if err != nil {
+		bot.ErrorHandler(bot, err)
+		return
+	}
+	for _, key := range keys {
+		if !strings.HasPrefix(key, retentionKeyPrefix) {
+			continue
+		}
+		value, ok, err := bot.Settings.Get("", key)
+		if err != nil || !ok {
+			continue
+		}
+		guildID := strings.TrimPrefix(key, retentionKeyPrefix)
+		if current[guildID] {
+			bot.cancelRetention(guildID)
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			continue
+		}
+		bot.scheduleRetention(guildID, at)
+	}
+}
+
+func retentionRemoveListener(bot *Bot) func(s *discordgo.Session, g *discordgo.GuildDelete) {
+	return func(s *discordgo.Session, g *discordgo.GuildDelete) {
+		if g.Unavailable || bot.RetentionPolicy == nil {
+			return
+		}
+		bot.scheduleRetention(g.ID, time.Now().Add(bot.RetentionPolicy.After))
+	}
+}
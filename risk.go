@@ -0,0 +1,163 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// RiskCacheTTL is how long the risk of an account is cached, see bot.AccountRiskOf
+var RiskCacheTTL = time.Minute * 10
+
+// RiskFactor is one reason an account looks risky.
+type RiskFactor struct {
+	Name  string
+	Score float64
+}
+
+// RiskAssessment is how likely an account is a raid or alt account, Score goes from 0 to 100.
+type RiskAssessment struct {
+	Score   float64
+	Factors []RiskFactor
+}
+
+// Add adds a factor to the assessment, the score is capped at 100.
+func (r *RiskAssessment) Add(name string, score float64) {
+	r.Factors = append(r.Factors, RiskFactor{Name: name, Score: score})
+	r.Score += score
+	if r.Score > 100 {
+		r.Score = 100
+	}
+}
+
+// AccountRisk assesses accounts, set it with bot.SetAccountRisk
+type AccountRisk interface {
+	Assess(user *discordgo.User) (*RiskAssessment, error)
+}
+
+// AccountRiskFunc is a function implementing AccountRisk, e.g to call an external API.
+type AccountRiskFunc func(user *discordgo.User) (*RiskAssessment, error)
+
+// Assess implements AccountRisk
+func (fn AccountRiskFunc) Assess(user *discordgo.User) (*RiskAssessment, error) {
+	return fn(user)
+}
+
+// HeuristicRisk scores accounts by what raid and alt accounts usually look like.
+type HeuristicRisk struct {
+	NewAccount         time.Duration    // Accounts younger than this are new. (default: 7 days)
+	NewAccountScore    float64          // Score of new accounts, scaled down as they get older. (default: 50)
+	DefaultAvatarScore float64          // Score of accounts without an avatar. (default: 20)
+	UsernamePatterns   []*regexp.Regexp // Usernames matching any of these are suspicious. (default: DefaultRiskPatterns)
+	UsernameScore      float64          // Score of suspicious usernames. (default: 30)
+}
+
+// DefaultRiskPatterns are usernames typical of generated accounts and impersonators.
+var DefaultRiskPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^[a-z]+[0-9]{4,}$`),                                    // Generated e.g john48213
+	regexp.MustCompile(`^[a-zA-Z0-9]{16,}$`),                                   // Random strings.
+	regexp.MustCompile(`(?i)(discord|nitro|steam).*(support|staff|team|gift)`), // Impersonators.
+}
+
+// NewHeuristicRisk creates heuristics with the defaults.
+func NewHeuristicRisk() *HeuristicRisk {
+	return &HeuristicRisk{
+		NewAccount:         time.Hour * 24 * 7,
+		NewAccountScore:    50,
+		DefaultAvatarScore: 20,
+		UsernamePatterns:   DefaultRiskPatterns,
+		UsernameScore:      30,
+	}
+}
+
+// Assess implements AccountRisk
+func (h *HeuristicRisk) Assess(user *discordgo.User) (*RiskAssessment, error) {
+	risk := &RiskAssessment{}
+	if age := time.Since(SnowflakeTime(user.ID)); age < h.NewAccount {
+		// A minute old account is riskier than a six day old one.
+		risk.Add("new account", h.NewAccountScore*(1-float64(age)/float64(h.NewAccount)))
+	}
+	if user.Avatar == "" {
+		risk.Add("default avatar", h.DefaultAvatarScore)
+	}
+	for _, pattern := range h.UsernamePatterns {
+		if pattern.MatchString(user.Username) {
+			risk.Add("suspicious username", h.UsernameScore)
+			break
+		}
+	}
+	return risk, nil
+}
+
+// CombinedRisk adds up the assessments of providers, e.g heuristics and an external API.
+// A failing provider is skipped so the others still count, the error is returned with what was assessed.
+func CombinedRisk(providers ...AccountRisk) AccountRisk {
+	return AccountRiskFunc(func(user *discordgo.User) (*RiskAssessment, error) {
+		combined := &RiskAssessment{}
+		var lastErr error
+		for _, provider := range providers {
+			risk, err := provider.Assess(user)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			for _, factor := range risk.Factors {
+				combined.Add(factor.Name, factor.Score)
+			}
+		}
+		return combined, lastErr
+	})
+}
+
+type cachedRisk struct {
+	risk *RiskAssessment
+	at   time.Time
+}
+
+type riskCache struct {
+	users map[string]cachedRisk
+	lock  sync.Mutex
+}
+
+// SetAccountRisk sets the provider assessing accounts, e.g sapphire.NewHeuristicRisk()
+func (bot *Bot) SetAccountRisk(provider AccountRisk) *Bot {
+	bot.AccountRisk = provider
+	return bot
+}
+
+// AccountRiskOf assesses an account with the AccountRisk provider, answers are cached for RiskCacheTTL.
+// Without a provider every account has no risk.
+func (bot *Bot) AccountRiskOf(user *discordgo.User) *RiskAssessment {
+	if bot.AccountRisk == nil {
+		return &RiskAssessment{}
+	}
+	bot.risks.lock.Lock()
+	cached, ok := bot.risks.users[user.ID]
+	bot.risks.lock.Unlock()
+	if ok && time.Since(cached.at) < RiskCacheTTL {
+		return cached.risk
+	}
+	risk, err := bot.AccountRisk.Assess(user)
+	if err != nil {
+		bot.ErrorHandler(bot, err)
+		if risk == nil {
+			return &RiskAssessment{}
+		}
+	}
+	now := time.Now()
+	bot.risks.lock.Lock()
+	for id, other := range bot.risks.users {
+		if now.Sub(other.at) >= RiskCacheTTL {
+			delete(bot.risks.users, id)
+		}
+	}
+	bot.risks.users[user.ID] = cachedRisk{risk: risk, at: now}
+	bot.risks.lock.Unlock()
+	return risk
+}
+
+// Risk returns the risk of the message's author, see bot.AccountRiskOf
+func (ctx *AutomodContext) Risk() float64 {
+	return ctx.Bot.AccountRiskOf(ctx.Message.Author).Score
+}
@@ -0,0 +1,29 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"testing"
+	"time"
+)
+
+func TestHeuristicRisk(t *testing.T) {
+	h := NewHeuristicRisk()
+	old := &discordgo.User{ID: "175928847299117063", Username: "sapphire", Avatar: "abc"}
+	if risk, _ := h.Assess(old); risk.Score != 0 {
+		t.Errorf("Expected an old account with an avatar to have no risk, got %+v", risk)
+	}
+	fresh := &discordgo.User{ID: TimeSnowflake(time.Now()), Username: "john48213"}
+	risk, _ := h.Assess(fresh)
+	if len(risk.Factors) != 3 || risk.Score < 99 {
+		t.Errorf("Expected a brand new generated account to be risky, got %+v", risk)
+	}
+
+	failing := AccountRiskFunc(func(user *discordgo.User) (*RiskAssessment, error) {
+		return nil, fmt.Errorf("API down")
+	})
+	risk, err := CombinedRisk(failing, h).Assess(fresh)
+	if err == nil || len(risk.Factors) != 3 {
+		t.Errorf("Expected the heuristics to count despite the error, got %+v %v", risk, err)
+	}
+}
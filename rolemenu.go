@@ -0,0 +1,245 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"sync"
+)
+
+// roleMenuKeyPrefix is the guild settings key prefix role menus are persisted under, followed by the message ID.
+const roleMenuKeyPrefix = "rolemenu."
+
+// RoleMenuOption is a role of a RoleMenu with the emoji shown next to it.
+type RoleMenuOption struct {
+	Emoji       string `json:"emoji"` // Unicode emoji or name:id for custom emojis.
+	RoleID      string `json:"role_id"`
+	Description string `json:"description"`
+}
+
+// RoleMenu is a message with a select menu of self-assignable roles, up to 25.
+// Members get the roles they pick and lose the roles of the menu they leave out.
+// Build it with NewRoleMenu and send it with bot.SendRoleMenu, the menus are stored in the settings provider
+// so they keep working after restarts.
+type RoleMenu struct {
+	GuildID      string            `json:"guild_id"`
+	ChannelID    string            `json:"channel_id"`
+	MessageID    string            `json:"message_id"`
+	Title        string            `json:"title"`
+	Description  string            `json:"description"`
+	Options      []*RoleMenuOption `json:"options"`
+	Single       bool              `json:"single"`        // Only one role of the menu at a time. (default: false)
+	MaxChoices   int               `json:"max_choices"`   // Maximum roles of the menu a member can have, 0 for no limit. (default: 0)
+	RequiredRole string            `json:"required_role"` // Role a member needs to use the menu, empty for everyone. (default: "")
+}
+
+type roleMenuTracker struct {
+	menus map[string]*RoleMenu
+	lock  sync.Mutex
+}
+
+// NewRoleMenu creates an empty role menu.
+func NewRoleMenu(title string) *RoleMenu {
+	return &RoleMenu{Title: title}
+}
+
+// SetDescription sets the text shown above the options.
+func (m *RoleMenu) SetDescription(description string) *RoleMenu {
+	m.Description = description
+	return m
+}
+
+// AddOption adds an option, emoji can be a unicode emoji or a custom emoji like <:name:id>
+func (m *RoleMenu) AddOption(emoji, roleID, description string) *RoleMenu {
+	if custom := ParseEmoji(emoji); custom != nil {
+		emoji = reactionName(custom)
+	}
+	m.Options = append(m.Options, &RoleMenuOption{Emoji: emoji, RoleID: roleID, Description: description})
+	return m
+}
+
+// SetSingle toggles single choice mode, the select menu lets members pick one role.
+func (m *RoleMenu) SetSingle(toggle bool) *RoleMenu {
+	m.Single = toggle
+	return m
+}
+
+// SetMaxChoices sets how many roles of the menu a member can pick at once, 0 for no limit.
+func (m *RoleMenu) SetMaxChoices(max int) *RoleMenu {
+	m.MaxChoices = max
+	return m
+}
+
+// SetRequiredRole sets a role members need to use the menu.
+func (m *RoleMenu) SetRequiredRole(roleID string) *RoleMenu {
+	m.RequiredRole = roleID
+	return m
+}
+
+// maxValues is how many options the select menu allows picking.
+func (m *RoleMenu) maxValues() int {
+	switch {
+	case m.Single:
+		return 1
+	case m.MaxChoices > 0 && m.MaxChoices < len(m.Options):
+		return m.MaxChoices
+	}
+	return len(m.Options)
+}
+
+// Embed builds the embed listing the options.
+func (m *RoleMenu) Embed(color int) *discordgo.MessageEmbed {
+	var lines []string
+	if m.Description != "" {
+		lines = append(lines, m.Description, "")
+	}
+	for _, option := range m.Options {
+		emoji := option.Emoji
+		if strings.Contains(emoji, ":") {
+			emoji = "<:" + emoji + ">"
+		}
+		line := fmt.Sprintf("%s <@&%s>", emoji, option.RoleID)
+		if option.Description != "" {
+			line += " - " + option.Description
+		}
+		lines = append(lines, line)
+	}
+	embed := NewEmbed().SetTitle(m.Title).SetDescription(strings.Join(lines, "\n")).SetColor(color)
+	switch {
+	case m.Single:
+		embed.SetFooter("You can pick one role.")
+	case m.MaxChoices > 0:
+		embed.SetFooter(fmt.Sprintf("You can pick up to %d roles.", m.MaxChoices))
+	}
+	return embed.Build()
+}
+
+// SendRoleMenu sends the menu to the channel and starts handling it, picks go to the "rolemenu" component handler.
+func (bot *Bot) SendRoleMenu(channelID string, menu *RoleMenu) (*discordgo.Message, error) {
+	channel, err := bot.Session.State.Channel(channelID)
+	if err != nil {
+		return nil, err
+	}
+	menu.GuildID = channel.GuildID
+	menu.ChannelID = channelID
+	msg, err := (&channelResponse{bot: bot, channelID: channelID}).Send(&ResponseMessage{
+		Embed:      menu.Embed(bot.Color),
+		Components: []*Component{NewActionRow(bot.roleMenuSelect(menu))},
+	})
+	if err != nil {
+		return nil, err
+	}
+	menu.MessageID = msg.ID
+	if err := SetJSON(bot.Settings, menu.GuildID, roleMenuKeyPrefix+msg.ID, menu); err != nil {
+		return msg, err
+	}
+	bot.roleMenus.lock.Lock()
+	bot.roleMenus.menus[msg.ID] = menu
+	bot.roleMenus.lock.Unlock()
+	return msg, nil
+}
+
+// roleMenuSelect builds the select menu of the options, labelled with the role names.
+func (bot *Bot) roleMenuSelect(menu *RoleMenu) *Component {
+	options := make([]*SelectOption, len(menu.Options))
+	for i, option := range menu.Options {
+		label := option.RoleID
+		if role, err := bot.Session.State.Role(menu.GuildID, option.RoleID); err == nil {
+			label = role.Name
+		}
+		emoji := &ComponentEmoji{Name: option.Emoji}
+		if parts := strings.SplitN(option.Emoji, ":", 2); len(parts) == 2 {
+			emoji = &ComponentEmoji{Name: parts[0], ID: parts[1]}
+		}
+		options[i] = &SelectOption{Label: label, Value: option.RoleID, Description: optionDescription(option.Description), Emoji: emoji}
+	}
+	placeholder, _ := bot.Localize(bot.LocaleFor(menu.GuildID, menu.ChannelID), "ROLEMENU_PLACEHOLDER")
+	return NewSelectMenu("rolemenu", placeholder, options...).SetValues(0, menu.maxValues())
+}
+
+// RemoveRoleMenu stops handling the menu and forgets it, the message itself is left alone.
+func (bot *Bot) RemoveRoleMenu(guildID, messageID string) error {
+	bot.roleMenus.lock.Lock()
+	delete(bot.roleMenus.menus, messageID)
+	bot.roleMenus.lock.Unlock()
+	return bot.Settings.Delete(guildID, roleMenuKeyPrefix+messageID)
+}
+
+// RoleMenu returns the role menu on the message, nil if there is none.
+func (bot *Bot) RoleMenu(guildID, messageID string) *RoleMenu {
+	bot.roleMenus.lock.Lock()
+	defer bot.roleMenus.lock.Unlock()
+	if menu, ok := bot.roleMenus.menus[messageID]; ok {
+		return menu
+	}
+	menu := &RoleMenu{}
+	if ok, err := GetJSON(bot.Settings, guildID, roleMenuKeyPrefix+messageID, menu); err != nil || !ok {
+		return nil
+	}
+	bot.roleMenus.menus[messageID] = menu
+	return menu
+}
+
+func hasRole(member *discordgo.Member, roleID string) bool {
+	for _, id := range member.Roles {
+		if id == roleID {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchMember gets the member from the state, falling back to the API.
+func (bot *Bot) fetchMember(guildID, userID string) (*discordgo.Member, error) {
+	if member, err := bot.Session.State.Member(guildID, userID); err == nil {
+		return member, nil
+	}
+	return bot.Session.GuildMember(guildID, userID)
+}
+
+// roleMenuComponent handles picks in role menus, the member ends up with exactly the picked roles of the menu.
+func roleMenuComponent(ctx *CommandContext) {
+	i := ctx.Interaction
+	if i.Message == nil || i.Member == nil {
+		return
+	}
+	menu := ctx.Bot.RoleMenu(i.GuildID, i.Message.ID)
+	if menu == nil {
+		return
+	}
+	reply := func(key string, args ...interface{}) {
+		content, _ := ctx.localize(key, args...)
+		ctx.response().Send(&ResponseMessage{Content: content, Ephemeral: true})
+	}
+	if menu.RequiredRole != "" && !hasRole(i.Member, menu.RequiredRole) {
+		reply("ROLEMENU_REQUIRED_ROLE", menu.RequiredRole)
+		return
+	}
+	// Discord enforces the limits of the select but picks of an outdated menu can still come in.
+	if len(i.Data.Values) > menu.maxValues() {
+		reply("ROLEMENU_TOO_MANY", menu.maxValues())
+		return
+	}
+
+	picked := make(map[string]bool, len(i.Data.Values))
+	for _, value := range i.Data.Values {
+		picked[value] = true
+	}
+	edit := MemberEdit{}
+	for _, option := range menu.Options {
+		has := hasRole(i.Member, option.RoleID)
+		switch {
+		case picked[option.RoleID] && !has:
+			edit.AddRoles = append(edit.AddRoles, option.RoleID)
+		case !picked[option.RoleID] && has:
+			edit.RemoveRoles = append(edit.RemoveRoles, option.RoleID)
+		}
+	}
+	if len(edit.AddRoles) > 0 || len(edit.RemoveRoles) > 0 {
+		if err := ctx.Bot.EditMember(i.GuildID, ctx.Author.ID, edit); err != nil {
+			reply("ROLEMENU_FAILED")
+			return
+		}
+	}
+	reply("ROLEMENU_UPDATED")
+}
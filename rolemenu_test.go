@@ -0,0 +1,45 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"testing"
+)
+
+func TestRoleMenuSelect(t *testing.T) {
+	state := discordgo.NewState()
+	state.GuildAdd(&discordgo.Guild{ID: "g", Roles: []*discordgo.Role{{ID: "r1", Name: "Red"}}})
+	bot := New(&discordgo.Session{State: state})
+	menu := NewRoleMenu("Colors").
+		AddOption("🔴", "r1", "").
+		AddOption("<:blurple:123456789012345679>", "r2", "Blurple").
+		SetMaxChoices(1)
+	menu.GuildID = "g"
+	menu.MessageID = "m"
+
+	selectMenu := bot.roleMenuSelect(menu)
+	if selectMenu.CustomID != "rolemenu" || selectMenu.MinValues == nil || *selectMenu.MinValues != 0 || selectMenu.MaxValues != 1 {
+		t.Errorf("Expected a rolemenu select of 0 to 1 picks but got %+v", selectMenu)
+	}
+	options := selectMenu.Options
+	if len(options) != 2 || options[0].Label != "Red" || options[0].Emoji.Name != "🔴" || options[1].Label != "r2" {
+		t.Fatalf("Expected the options labelled with the role names but got %+v", options)
+	}
+	if options[1].Emoji.Name != "blurple" || options[1].Emoji.ID != "123456789012345679" {
+		t.Errorf("Expected the custom emoji split into name and ID but got %+v", options[1].Emoji)
+	}
+
+	calls := recordREST(bot)
+	bot.roleMenus.menus["m"] = menu.SetRequiredRole("verified")
+	dispatchInteraction(t, bot, `{"id":"i","application_id":"a","type":3,"token":"tok","channel_id":"c","guild_id":"g",
+		"member":{"user":{"id":"u"},"roles":[]},"message":{"id":"m"},"data":{"custom_id":"rolemenu","component_type":3,"values":["r1"]}}`)
+	requests := calls()
+	if len(requests) != 1 || requests[0].Method != "POST" || requests[0].Endpoint != "interactions/i/tok/callback?with_response=true" {
+		t.Fatalf("Expected only the answer and no role changes but got %+v", requests)
+	}
+	data, _ := json.Marshal(requests[0].Data["data"])
+	if !strings.Contains(string(data), "verified") || !strings.Contains(string(data), `"flags":64`) {
+		t.Errorf("Expected an ephemeral reply about the required role but got %s", data)
+	}
+}
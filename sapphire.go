@@ -7,8 +7,10 @@ import (
 	"github.com/dustin/go-humanize"
 	"os"
 	"os/signal"
+	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -25,26 +27,111 @@ type ErrorHandler func(b *Bot, err interface{})
 
 // Bot represents a bot with sapphire framework features.
 type Bot struct {
-	Session          *discordgo.Session  // The discordgo session.
-	Prefix           PrefixHandler       // The handler called to get the prefix. (default: !)
-	Language         LocaleHandler       // The handler called to get the language (default: en-US)
-	Commands         map[string]*Command // Map of commands.
-	CommandsRan      int                 // Commands ran.
-	Monitors         map[string]*Monitor // Map of monitors.
-	aliases          map[string]string
-	CommandCooldowns map[string]map[string]time.Time
-	CommandEdits     map[string]string
-	OwnerID          string               // Bot owner's ID (default: fetched from application info)
-	InvitePerms      int                  // Permissions bits to use for the invite link. (default: 3072)
-	Languages        map[string]*Language // Map of languages.
-	DefaultLocale    *Language            // Default locale to fallback. (default: en-US)
-	CommandTyping    bool                 // Wether to start typing when a command is being ran. (default: true)
-	ErrorHandler     ErrorHandler         // The handler to catch panics in monitors (which includes commands).
-	MentionPrefix    bool                 // Wether to allow @mention of the bot to be used as a prefix too. (default: true)
-	sweepTicker      *time.Ticker
-	Application      *discordgo.Application // The bot's application.
-	Uptime           time.Time              // The time the bot hit ready event.
-	Color            int                    // The color used in builtin commands's embeds.
+	Session             *discordgo.Session  // The discordgo session.
+	Prefix              PrefixHandler       // The handler called to get the prefix. (default: !)
+	Language            LocaleHandler       // The handler called to get the language (default: en-US)
+	Commands            map[string]*Command // Map of commands.
+	Counters            *Counters           // Counters like commands ran, safe for concurrent use.
+	IgnoreChannels      []string            // Channels where messages are ignored by all monitors, including commands. (default: [])
+	IgnoreCategories    []string            // Categories whose channels are ignored like IgnoreChannels. (default: [])
+	IgnoreRoles         []string            // Members with any of these roles are ignored by all monitors. (default: [])
+	Monitors            map[string]*Monitor // Map of monitors.
+	aliases             map[string]string
+	CommandCooldowns    map[string]map[string]time.Time
+	CommandEdits        map[string]string
+	OwnerID             string               // Bot owner's ID (default: fetched from application info)
+	InvitePerms         int                  // Permissions bits to use for the invite link. (default: 3072)
+	Languages           map[string]*Language // Map of languages.
+	DefaultLocale       *Language            // Default locale to fallback. (default: en-US)
+	CommandTyping       bool                 // Wether to start typing when a command is being ran. (default: true)
+	ErrorHandler        ErrorHandler         // The handler to catch panics in monitors (which includes commands).
+	MentionPrefix       bool                 // Wether to allow @mention of the bot to be used as a prefix too. (default: true)
+	sweepTicker         *time.Ticker
+	Application         *discordgo.Application // The bot's application.
+	Uptime              time.Time              // The time the bot hit ready event.
+	Color               int                    // The color used in builtin commands's embeds.
+	Scheduler           *Scheduler             // Scheduler for delayed tasks, stopped when the bot is closed via Wait.
+	CooldownExemptions  []CooldownExemption    // Exemptions from command cooldowns that apply to all commands.
+	Premium             PremiumProvider        // Decides who has premium, see SetPremiumProvider. (default: nil, nobody is premium)
+	ContentExtractor    ContentExtractor       // Reads text from image attachments, see SetContentExtractor. (default: nil)
+	AccountRisk         AccountRisk            // Assesses how likely accounts are raid or alt accounts, see SetAccountRisk. (default: nil)
+	BackupKey           []byte                 // Key backups are signed with, see SetBackupKey. (default: derived from the token)
+	Locker              Locker                 // Serializes changes of modules, see SetLocker. (default: in-memory KeyedMutex)
+	EntitlementStore    EntitlementStore       // Where entitlement events are persisted, see SetEntitlementStore. (default: nil)
+	TTS                 TTSProvider            // Speaks text in voice channels, see SetTTSProvider. (default: nil)
+	PermissionLevels    []PermissionLevel      // The permission level ladder, see AddPermissionLevel. (default: [])
+	entitlementHandlers []EntitlementHandler
+	DataSubjects        map[string]DataSubject   // Stores holding user data, see AddDataSubject.
+	Settings            SettingsProvider         // Where guild settings are stored. (default: in-memory, see SetSettingsProvider)
+	Onboarding          *Onboarding              // What to do when joining a new guild, see SetOnboarding. (default: nil)
+	ChainSeparator      string                   // Separator to run multiple commands from one message e.g "&&", "" disables chaining. (default: "")
+	MaxChain            int                      // Maximum amount of commands in one chain, 0 for no limit. (default: 3)
+	ConfigSchemas       map[string]*ConfigSchema // Per-guild config schemas of extensions, see AddConfigSchema.
+	RetentionPolicy     *RetentionPolicy         // What to do with a guild's data after leaving it, see SetRetentionPolicy. (default: nil, keep forever)
+	guilds              *guildTracker
+	retention           *retentionTracker
+	cron                *cronTracker
+	roleMenus           *roleMenuTracker
+	autoVC              *autoVCTracker
+	afk                 *afkTracker
+	stickies            *stickyTracker
+	autoPublish         *autoPublishTracker
+	counting            *countingTracker
+	suggestions         *suggestionTracker
+	tickets             *ticketTracker
+	statChannels        *statsTracker
+	webhooks            *webhookCache
+	bridges             *bridgeTracker
+	modmail             *modmailTracker
+	automod             *automodTracker
+	heat                *heatTracker
+	regexFilter         *regexFilterTracker
+	wordFilter          *wordFilterTracker
+	customRules         *customRuleTracker
+	nativeAutomod       *nativeAutomodTracker
+	importers           map[string]Importer
+	setupSteps          []SetupStep
+	commandOverrides    bool
+	guildOverrides      bool
+	inhibitors          []namedInhibitor
+	apiResources        map[string]APIResource
+	extracted           *extractCache
+	risks               *riskCache
+	memberEdits         *memberEditTracker
+	budget              *budgetTracker
+	deadLetters         *deadLetterTracker
+	deadLetterHandlers  map[string]func(letter DeadLetter) error
+	eventProcessors     map[string]eventProcessor
+	handlers            []*eventHandler
+	bus                 *eventBus
+	services            *serviceRegistry
+	handlersLock        sync.Mutex
+	notifier            *notifier
+	circuits            *circuitTracker
+	health              *healthTracker
+	dedup               *eventDedup
+	voiceXP             *voiceXPTracker
+	clips               *clipTracker
+	streams             *streamTracker
+	streamAlerts        *streamAlertTracker
+	feeds               *feedTracker
+	gitHub              *gitHubTracker
+	inbound             *inboundTracker
+	BroadcastDelay      time.Duration // Delay between messages of a broadcast. (default: 1s)
+	BulkRoleDelay       time.Duration // Delay between role changes of a bulk role change. (default: 500ms)
+	MemberEditWindow    time.Duration // How long member edits wait to be coalesced with later ones, see EditMember. (default: 250ms)
+	MemberEditDelay     time.Duration // Delay between member edits of the same guild. (default: 250ms)
+	Console             *Console      // The operator console, see EnableConsole. (default: nil)
+	Voice               *VoiceTracker // Who's in which voice channel, see EnableVoiceTracking. (default: nil)
+	reloadHooks         []func(bot *Bot) error
+	DefaultTimezone     *time.Location // Timezone used when the guild or user didn't choose one, see SetDefaultTimezone. (default: UTC)
+	guildJoinHandlers   []GuildJoinHandler
+	componentHandlers   map[string]ComponentHandler
+	messageCommands     map[string]MessageCommandHandler
+	ApplicationID       string        // The application slash commands are registered to. (default: the bot's user ID on ready)
+	DeferAfter          time.Duration // How long interaction commands can take to answer before they are deferred, 0 to never defer. (default: 2s)
+	commandSync         *commandSync
+	requestHook         func(method, endpoint string, data interface{}, bucket string) ([]byte, error)
 }
 
 // New creates a new sapphire bot, pass in a discordgo instance configured with your token.
@@ -63,24 +150,72 @@ func New(s *discordgo.Session) *Bot {
 		Commands:         make(map[string]*Command),
 		aliases:          make(map[string]string),
 		Languages:        make(map[string]*Language),
-		CommandsRan:      0,
+		Counters:         NewCounters(),
 		InvitePerms:      3072,
 		CommandCooldowns: make(map[string]map[string]time.Time),
 		CommandEdits:     make(map[string]string),
 		Monitors:         make(map[string]*Monitor),
+		DataSubjects:     make(map[string]DataSubject),
+		Settings:         NewMemorySettings(),
+		inhibitors:       builtinInhibitors(),
+		Locker:           &KeyedMutex{},
+		ConfigSchemas:    make(map[string]*ConfigSchema),
+		MaxChain:         3,
+		DeferAfter:       2 * time.Second,
+		DefaultTimezone:  time.UTC,
+		BroadcastDelay:   time.Second,
+		BulkRoleDelay:    500 * time.Millisecond,
+		MemberEditWindow: 250 * time.Millisecond,
+		MemberEditDelay:  250 * time.Millisecond,
+		guilds:           &guildTracker{known: make(map[string]bool)},
+		retention:        &retentionTracker{tasks: make(map[string]*ScheduledTask)},
+		cron:             &cronTracker{jobs: make(map[string]*ScheduledCommand)},
+		roleMenus:        &roleMenuTracker{menus: make(map[string]*RoleMenu)},
+		webhooks:         &webhookCache{hooks: make(map[string]*discordgo.Webhook), own: make(map[string]bool)},
+		extracted:        &extractCache{texts: make(map[string]string)},
+		risks:            &riskCache{users: make(map[string]cachedRisk)},
+		memberEdits:      &memberEditTracker{pending: make(map[string]*pendingMemberEdit), next: make(map[string]time.Time)},
+		health:           &healthTracker{deps: make(map[string]*dependency)},
+		dedup:            newEventDedup(),
+		clips:            &clipTracker{queues: make(map[string][]queuedClip)},
+		eventProcessors:  make(map[string]eventProcessor),
+		bus:              &eventBus{subscribers: make(map[string][]busSubscriber)},
+		services:         &serviceRegistry{services: make(map[reflect.Type]interface{})},
 		CommandTyping:    true,
 		sweepTicker:      time.NewTicker(1 * time.Hour),
 		Application:      nil,
 		MentionPrefix:    true,
 		Color:            COLOR,
 	}
+	bot.Scheduler = NewScheduler(func(err interface{}) {
+		bot.ErrorHandler(bot, err)
+	})
+	bot.deadLetterHandlers = map[string]func(letter DeadLetter) error{
+		"monitor": replayMonitor(bot),
+		"event":   replayEvent(bot),
+	}
 	bot.AddLanguage(English)
 	bot.SetDefaultLocale("en-US")
+	bot.AddDataSubject(timezoneData(bot))
+	bot.AddDataSubject(cronData(bot))
+	bot.AddDataSubject(warningData(bot))
 	bot.AddMonitor(NewMonitor("commandHandler", CommandHandlerMonitor).AllowEdits())
-	s.AddHandler(monitorListener(bot))
-	s.AddHandler(monitorEditListener(bot))
-	s.AddHandlerOnce(func(s *discordgo.Session, ready *discordgo.Ready) {
+	bot.AddHandler(monitorListener(bot))
+	bot.AddHandler(monitorEditListener(bot))
+	bot.AddHandler(entitlementListener(bot))
+	bot.AddHandler(interactionListener(bot))
+	bot.AddHandler(stageListener(bot))
+	bot.AddHandler(guildReadyListener(bot))
+	bot.AddHandler(guildCreateListener(bot))
+	bot.AddHandler(guildDeleteListener(bot))
+	bot.AddHandler(memberJoinListener(bot))
+	bot.AddHandler(retentionRemoveListener(bot))
+	bot.AddComponentHandler("rolemenu", roleMenuComponent)
+	bot.AddHandlerOnce(func(s *discordgo.Session, ready *discordgo.Ready) {
 		bot.Uptime = time.Now()
+		bot.restoreRetention(ready)
+		bot.restoreCron()
+		bot.sweepIdempotency()
 
 		// Sweeps all cooldowns/edits every hour to prevent infinite memory usage
 		// While even active cooldowns gets reset it is fine though, as its only hourly
@@ -110,13 +245,66 @@ func (bot *Bot) SetMentionPrefix(toggle bool) *Bot {
 	return bot
 }
 
+// SetChaining enables running multiple commands from one message separated by separator e.g "&&"
+// Commands run in order and the chain stops at the first one that fails, max limits the amount of commands in a chain
+// or 0 for no limit.
+// Pass an empty separator to disable chaining.
+func (bot *Bot) SetChaining(separator string, max int) *Bot {
+	bot.ChainSeparator = separator
+	bot.MaxChain = max
+	return bot
+}
+
+// SetDeferAfter sets how long interaction commands can take to answer before they are deferred,
+// Discord gives up on interactions that aren't answered within 3 seconds. Pass 0 to never defer.
+func (bot *Bot) SetDeferAfter(budget time.Duration) *Bot {
+	bot.DeferAfter = budget
+	return bot
+}
+
+// IgnoreChannel adds channels where no monitor runs, e.g log channels. Set ignore lists before connecting.
+func (bot *Bot) IgnoreChannel(ids ...string) *Bot {
+	bot.IgnoreChannels = append(bot.IgnoreChannels, ids...)
+	return bot
+}
+
+// IgnoreCategory adds categories whose channels are ignored by all monitors, e.g staff categories.
+func (bot *Bot) IgnoreCategory(ids ...string) *Bot {
+	bot.IgnoreCategories = append(bot.IgnoreCategories, ids...)
+	return bot
+}
+
+// IgnoreRole adds roles whose members are ignored by all monitors.
+func (bot *Bot) IgnoreRole(ids ...string) *Bot {
+	bot.IgnoreRoles = append(bot.IgnoreRoles, ids...)
+	return bot
+}
+
 // SetInvitePerms sets the permissions to request for in the bot invite link.
-// The default is 3072 which is [VIEW_CHANNEL, SEND_MESSAGES]
+// The default is 3072 which is [VIEW_CHANNEL, SEND_MESSAGES], what enabled modules need is added, see InvitePermissions
 func (bot *Bot) SetInvitePerms(bits int) *Bot {
 	bot.InvitePerms = bits
 	return bot
 }
 
+// SetPremiumProvider sets the provider consulted for premium only commands and premium cooldowns.
+// The users of a ManualPremium are included in bot.ExportUserData and bot.DeleteUserData
+func (bot *Bot) SetPremiumProvider(provider PremiumProvider) *Bot {
+	bot.Premium = provider
+	if manual, ok := provider.(*ManualPremium); ok {
+		bot.AddDataSubject(manualPremiumData(manual))
+	} else {
+		delete(bot.DataSubjects, "premium")
+	}
+	return bot
+}
+
+// SetSettingsProvider sets where guild settings are stored.
+func (bot *Bot) SetSettingsProvider(provider SettingsProvider) *Bot {
+	bot.Settings = provider
+	return bot
+}
+
 // SetErrorHandler sets the function to handle panics that happens in monitors (which includes commands)
 func (bot *Bot) SetErrorHandler(fn ErrorHandler) *Bot {
 	bot.ErrorHandler = fn
@@ -139,6 +327,60 @@ func (bot *Bot) SetLocaleHandler(handler LocaleHandler) *Bot {
 	return bot
 }
 
+// Localize resolves key in lang then the bot's default locale.
+// It returns either the string or the embed for that key, if the key isn't found anywhere it returns the LOCALE_NO_KEY message.
+func (bot *Bot) Localize(lang *Language, key string, args ...interface{}) (string, *discordgo.MessageEmbed) {
+	for _, l := range []*Language{lang, bot.DefaultLocale} {
+		if embed := l.GetEmbed(key, args...); embed != nil {
+			if embed.Color == 0 {
+				embed.Color = bot.Color
+			}
+			return "", embed
+		}
+		if res := l.Get(key, args...); res != "" {
+			return res, nil
+		}
+	}
+
+	// All failed, the key isn't translated, report the error.
+	// We have to also watch out if the error message isn't translated!
+	return lang.GetDefault("LOCALE_NO_KEY", bot.DefaultLocale.GetDefault("LOCALE_NO_KEY",
+		fmt.Sprintf("No localization found for the key \"%s\" Please report this to the developers.", key), key), key), nil
+}
+
+// localizeMessage is Localize with the components of a localized embed, taken from the language the embed came from.
+func (bot *Bot) localizeMessage(lang *Language, key string, args ...interface{}) *ResponseMessage {
+	content, embed := bot.Localize(lang, key, args...)
+	msg := &ResponseMessage{Content: content, Embed: embed}
+	if embed == nil {
+		return msg
+	}
+	for _, l := range []*Language{lang, bot.DefaultLocale} {
+		if _, ok := l.Embeds[key]; ok {
+			msg.Components = l.GetComponents(key, args...)
+			break
+		}
+	}
+	return msg
+}
+
+// SendLocale sends a localized key to a channel using lang, see bot.Localize
+// Components of a localized embed are sent with it.
+func (bot *Bot) SendLocale(channelID string, lang *Language, key string, args ...interface{}) (*discordgo.Message, error) {
+	return (&channelResponse{bot: bot, channelID: channelID}).Send(bot.localizeMessage(lang, key, args...))
+}
+
+// LocaleFor returns the language for a guild and channel outside of a command, e.g in event handlers.
+// The locale handler is called with a message that only has the guild and channel IDs filled.
+// Falls back to the default locale if the handler returns an unknown language.
+func (bot *Bot) LocaleFor(guildID, channelID string) *Language {
+	name := bot.Language(bot, &discordgo.Message{GuildID: guildID, ChannelID: channelID}, guildID == "")
+	if lang, ok := bot.Languages[name]; ok {
+		return lang
+	}
+	return bot.DefaultLocale
+}
+
 // SetPrefixHandler sets the prefix handler, the function is responsible to return the right prefix for the command call.
 // Use this for dynamic prefixes, e.g fetch prefix from database.
 func (bot *Bot) SetPrefixHandler(prefix PrefixHandler) *Bot {
@@ -164,9 +406,15 @@ func (bot *Bot) Wait() {
 	// Cleanly close down the Discord session.
 	bot.Session.Close()
 	bot.sweepTicker.Stop()
+	bot.Scheduler.Stop()
 }
 
+// AddCommand registers the command, if a command with the same name exists it's replaced.
+// The command's usage string is compiled here if it wasn't already, this panics on an invalid usage string.
 func (bot *Bot) AddCommand(cmd *Command) *Bot {
+	if cmd.Spec == nil || cmd.Spec.Usage != cmd.UsageString {
+		cmd.compileUsage()
+	}
 	c, ok := bot.Commands[cmd.Name]
 	// If we are overriding an existing command ensure we unload any state it loaded in the bot, mainly the aliases.
 	if ok {
@@ -194,8 +442,11 @@ func (bot *Bot) GetCommand(name string) *Command {
 	return nil
 }
 
-// Connect is an alias to discordgo's Session.Open
+// Connect is an alias to discordgo's Session.Open, the findings of bot.Diagnose are printed first.
 func (bot *Bot) Connect() error {
+	for _, finding := range bot.Diagnose() {
+		fmt.Printf("WARNING: %s\n", finding)
+	}
 	return bot.Session.Open()
 }
 
@@ -250,8 +501,15 @@ func (bot *Bot) CheckCooldown(userID, command string, cooldownSec int) (bool, in
 	return true, 0
 }
 
+// AddCooldownExemption adds exemptions from cooldowns that apply to every command.
+// e.g bot.AddCooldownExemption(sapphire.ExemptOwner)
+func (bot *Bot) AddCooldownExemption(exemptions ...CooldownExemption) *Bot {
+	bot.CooldownExemptions = append(bot.CooldownExemptions, exemptions...)
+	return bot
+}
+
 // LoadBuiltins loads the default set of builtin command, they are:
-// ping, help, stats, invite, enable, disable, gc
+// ping, help, stats, invite, enable, disable, gc, diagnose, timezone, cron, broadcast
 // Some of the must have commands. (or rather commands that i feel good to have.)
 func (bot *Bot) LoadBuiltins() *Bot {
 	// To keep things simple all commands are declared here, we shouldn't need that much of builtins anyway.
@@ -292,7 +550,7 @@ func (bot *Bot) LoadBuiltins() *Bot {
 					cmd.Description,
 					cmd.Category,
 					aliases,
-					fmt.Sprintf("%s%s %s", ctx.Prefix, cmd.Name, HumanizeUsage(cmd.UsageString)),
+					fmt.Sprintf("%s%s %s", ctx.Prefix, cmd.Name, cmd.Spec.Humanized),
 				)).SetColor(bot.Color).SetTitle("Command Help"))
 			return
 		}
@@ -352,16 +610,17 @@ func (bot *Bot) LoadBuiltins() *Bot {
 			AddField("Go Version", strings.TrimPrefix(runtime.Version(), "go")).
 			AddField("DiscordGo Version", discordgo.VERSION).
 			AddField("Sapphire Version", VERSION).
-			AddField("Bot Stats", fmt.Sprintf("**Guilds:** %d\n**Users:** %d\n**Channels:** %d\n**Uptime:** %s", guilds, users, channels, humanize.RelTime(bot.Uptime, time.Now(), "", ""))).
-			AddField("Command Stats", fmt.Sprintf("**Total Commands:** %d\n**Commands Ran:** %d", len(bot.Commands), bot.CommandsRan)).
+			AddField("Bot Stats", fmt.Sprintf("**Guilds:** %s\n**Users:** %s\n**Channels:** %s\n**Uptime:** %s",
+				ctx.Locale.FormatCount(int64(guilds)), ctx.Locale.FormatCount(int64(users)), ctx.Locale.FormatCount(int64(channels)), humanize.RelTime(bot.Uptime, time.Now(), "", ""))).
+			AddField("Command Stats", fmt.Sprintf("**Total Commands:** %d\n**Commands Ran:** %s", len(bot.Commands), ctx.Locale.FormatCount(bot.TotalCommandsRan()))).
 			AddField("Memory Stats", fmt.Sprintf("**Used:** %s / %s\n**Garbage Collected:** %s\n**GC Cycles:** %d\n**Forced GC Cycles:** %d\n**Last GC:** %s\n**Next GC Target:** %s\n**Goroutines:** %d",
-				humanize.Bytes(stats.Alloc),
-				humanize.Bytes(stats.Sys),
-				humanize.Bytes(stats.TotalAlloc-stats.Alloc),
+				ctx.Locale.FormatBytes(int64(stats.Alloc)),
+				ctx.Locale.FormatBytes(int64(stats.Sys)),
+				ctx.Locale.FormatBytes(int64(stats.TotalAlloc-stats.Alloc)),
 				stats.NumGC,
 				stats.NumForcedGC,
 				humanize.Time(time.Unix(0, int64(stats.LastGC))),
-				humanize.Bytes(stats.NextGC),
+				ctx.Locale.FormatBytes(int64(stats.NextGC)),
 				runtime.NumGoroutine(),
 			)).
 			AddField("Technical Info", fmt.Sprintf("**CPU Cores:** %d\n**OS/Arch:** %s/%s",
@@ -371,10 +630,8 @@ func (bot *Bot) LoadBuiltins() *Bot {
 			)))
 	}).SetDescription("Stats for nerds.").AddAliases("botstats", "info"))
 
-	bot.AddCommand(NewCommand("invite", "General", func(ctx *CommandContext) {
-		ctx.ReplyLocale("COMMAND_INVITE", fmt.Sprintf("https://discordapp.com/oauth2/authorize?client_id=%s&permissions=%d&scope=bot",
-			ctx.Session.State.User.ID, bot.InvitePerms))
-	}).SetDescription("Invite me to your server!").AddAliases("inv"))
+	bot.AddCommand(NewCommand("invite", "General", inviteCommand).
+		SetDescription("Invite me to your server! Shows the permissions I'm missing here too.").AddAliases("inv"))
 
 	bot.AddCommand(NewCommand("enable", "Owner", func(ctx *CommandContext) {
 		command := ctx.Bot.GetCommand(ctx.Arg(0).AsString())
@@ -418,5 +675,27 @@ func (bot *Bot) LoadBuiltins() *Bot {
 		ctx.Reply("Forced Garbage Collection.\n  - Freed **%s**\n  - %d Objects Collected.\n  - Took **%d**μs",
 			humanize.Bytes(before.Alloc-after.Alloc), after.Frees-before.Frees, after.PauseTotalNs-before.PauseTotalNs)
 	}).SetDescription("Forces a garbage collection cycle.").AddAliases("garbagecollect", "forcegc", "runtime.GC()").SetOwnerOnly(true))
+
+	bot.AddCommand(NewCommand("diagnose", "Owner", diagnoseCommand).
+		SetDescription("Checks for configuration problems like missing intents or untranslated keys.").
+		AddAliases("doctor").
+		SetOwnerOnly(true))
+
+	bot.AddCommand(NewCommand("timezone", "General", timezoneCommand).
+		SetDescription("Shows or sets your timezone, use --server to show or set the server's timezone.").
+		SetUsage("[timezone:string]").
+		AddAliases("tz"))
+
+	bot.AddCommand(NewCommand("broadcast", "Owner", broadcastCommand).
+		SetDescription("Sends an announcement to every server.").
+		SetUsage("<message:string...>").
+		AddAliases("announce").
+		SetOwnerOnly(true))
+
+	bot.AddCommand(NewCommand("cron", "Owner", cronCommand).
+		SetDescription("Schedules commands to run in this channel, use --tz=Zone/Name to pick a timezone.").
+		SetUsage("[action:string] [args:string...]").
+		AddAliases("schedule").
+		SetOwnerOnly(true))
 	return bot
 }
@@ -0,0 +1,98 @@
+package sapphire
+
+import (
+	"sync"
+	"time"
+)
+
+// Scheduler runs functions at a later time, it keeps track of pending tasks so they can be cancelled
+// and stopped all at once when the bot shuts down.
+type Scheduler struct {
+	OnPanic func(err interface{}) // Called when a task panics. (default: the bot's error handler)
+	tasks   map[uint64]*time.Timer
+	next    uint64
+	stopped bool
+	lock    sync.Mutex
+}
+
+// ScheduledTask is a handle to a task that was scheduled, use it to cancel the task.
+type ScheduledTask struct {
+	id        uint64
+	scheduler *Scheduler
+}
+
+// NewScheduler creates a new scheduler, onPanic is called with the recovered value if a task panics.
+func NewScheduler(onPanic func(err interface{})) *Scheduler {
+	return &Scheduler{
+		OnPanic: onPanic,
+		tasks:   make(map[uint64]*time.Timer),
+	}
+}
+
+// After schedules fn to run after d has passed.
+// Returns nil if the scheduler is stopped.
+func (s *Scheduler) After(d time.Duration, fn func()) *ScheduledTask {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.stopped {
+		return nil
+	}
+
+	s.next++
+	id := s.next
+	s.tasks[id] = time.AfterFunc(d, func() {
+		s.lock.Lock()
+		delete(s.tasks, id)
+		s.lock.Unlock()
+		s.run(fn)
+	})
+	return &ScheduledTask{id: id, scheduler: s}
+}
+
+// At schedules fn to run at t, if t is in the past it runs as soon as possible.
+func (s *Scheduler) At(t time.Time, fn func()) *ScheduledTask {
+	return s.After(time.Until(t), fn)
+}
+
+// Pending returns the amount of tasks waiting to run.
+func (s *Scheduler) Pending() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.tasks)
+}
+
+// Stop cancels all pending tasks, after this call the scheduler will not accept new tasks.
+func (s *Scheduler) Stop() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for id, timer := range s.tasks {
+		timer.Stop()
+		delete(s.tasks, id)
+	}
+	s.stopped = true
+}
+
+func (s *Scheduler) run(fn func()) {
+	defer func() {
+		if err := recover(); err != nil && s.OnPanic != nil {
+			s.OnPanic(err)
+		}
+	}()
+	fn()
+}
+
+// Cancel cancels the task, returns false if it already ran or was cancelled.
+func (t *ScheduledTask) Cancel() bool {
+	if t == nil {
+		return false
+	}
+	t.scheduler.lock.Lock()
+	defer t.scheduler.lock.Unlock()
+	timer, ok := t.scheduler.tasks[t.id]
+	if !ok {
+		return false
+	}
+	delete(t.scheduler.tasks, t.id)
+	return timer.Stop()
+}
@@ -0,0 +1,31 @@
+package sapphire
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduler(t *testing.T) {
+	s := NewScheduler(nil)
+	ran := make(chan bool, 1)
+	s.After(time.Millisecond, func() { ran <- true })
+	cancelled := s.After(time.Hour, func() { t.Error("Cancelled task ran") })
+
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Error("Expected the task to run")
+	}
+
+	if !cancelled.Cancel() {
+		t.Error("Expected Cancel to return true for a pending task")
+	}
+	if s.Pending() != 0 {
+		t.Errorf("Expected no pending tasks but got %d", s.Pending())
+	}
+
+	s.Stop()
+	if s.After(time.Millisecond, func() {}) != nil {
+		t.Error("Expected a stopped scheduler to reject tasks")
+	}
+}
@@ -0,0 +1,82 @@
+package sapphire
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+type serviceRegistry struct {
+	services map[reflect.Type]interface{}
+	lock     sync.RWMutex
+}
+
+// Provide registers a shared dependency e.g a database pool or HTTP client, commands get it with ctx.Service
+// Services are keyed by their type, providing another value of the same type replaces it.
+func (bot *Bot) Provide(service interface{}) *Bot {
+	bot.services.lock.Lock()
+	defer bot.services.lock.Unlock()
+	bot.services.services[reflect.TypeOf(service)] = service
+	return bot
+}
+
+// ProvideAs registers a service under an interface so it can be swapped e.g for a fake in tests,
+// iface is a nil pointer to the interface: bot.ProvideAs((*Store)(nil), &PostgresStore{})
+// It panics if service doesn't implement the interface.
+func (bot *Bot) ProvideAs(iface interface{}, service interface{}) *Bot {
+	typ := reflect.TypeOf(iface)
+	if typ == nil || typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Interface {
+		panic("ProvideAs needs a nil pointer to an interface e.g (*Store)(nil)")
+	}
+	if !reflect.TypeOf(service).Implements(typ.Elem()) {
+		panic(fmt.Sprintf("%T doesn't implement %s", service, typ.Elem()))
+	}
+	bot.services.lock.Lock()
+	defer bot.services.lock.Unlock()
+	bot.services.services[typ.Elem()] = service
+	return bot
+}
+
+// Service sets target, a pointer to a variable of the service's type, to the provided service.
+// For an interface variable a service registered with ProvideAs is used first, then any provided service implementing it.
+// Returns false if there is no such service.
+func (bot *Bot) Service(target interface{}) bool {
+	ptr := reflect.ValueOf(target)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		panic("Service needs a pointer to set")
+	}
+	typ := ptr.Elem().Type()
+	bot.services.lock.RLock()
+	defer bot.services.lock.RUnlock()
+	if service, ok := bot.services.services[typ]; ok {
+		ptr.Elem().Set(reflect.ValueOf(service))
+		return true
+	}
+	if typ.Kind() != reflect.Interface {
+		return false
+	}
+	for key, service := range bot.services.services {
+		if key.Kind() != reflect.Interface && key.Implements(typ) {
+			ptr.Elem().Set(reflect.ValueOf(service))
+			return true
+		}
+	}
+	return false
+}
+
+// MustService is like Service but panics if there is no such service.
+func (bot *Bot) MustService(target interface{}) {
+	if !bot.Service(target) {
+		panic(fmt.Sprintf("No service of type %s was provided", reflect.TypeOf(target).Elem()))
+	}
+}
+
+// Service sets target to a service provided with bot.Provide, see bot.Service
+func (ctx *CommandContext) Service(target interface{}) bool {
+	return ctx.Bot.Service(target)
+}
+
+// MustService sets target to a service provided with bot.Provide, the panic is reported like any other command error.
+func (ctx *CommandContext) MustService(target interface{}) {
+	ctx.Bot.MustService(target)
+}
@@ -0,0 +1,39 @@
+package sapphire
+
+import (
+	"bytes"
+	"github.com/bwmarrin/discordgo"
+	"net/http"
+	"testing"
+)
+
+type testStore interface {
+	Name() string
+}
+
+type memoryStore struct{ name string }
+
+func (s *memoryStore) Name() string { return s.name }
+
+func TestServices(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	client := &http.Client{}
+	bot.Provide(client).Provide(&memoryStore{"provided"})
+
+	var got *http.Client
+	if !bot.Service(&got) || got != client {
+		t.Errorf("Expected the provided client")
+	}
+	var store testStore
+	if !bot.Service(&store) || store.Name() != "provided" {
+		t.Errorf("Expected a provided service implementing the interface")
+	}
+	bot.ProvideAs((*testStore)(nil), &memoryStore{"explicit"})
+	if !bot.Service(&store) || store.Name() != "explicit" {
+		t.Errorf("Expected the service provided as the interface to win")
+	}
+	var missing *bytes.Buffer
+	if bot.Service(&missing) {
+		t.Errorf("Expected no service for a type that wasn't provided")
+	}
+}
@@ -0,0 +1,112 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// SettingsProvider stores guild scoped settings as string values.
+// An empty guild ID is used for bot wide settings, structured data can be stored as JSON.
+// Implement it with your database of choice and set it with bot.SetSettingsProvider
+type SettingsProvider interface {
+	// Get returns the value of key for the guild, the bool is false if the key isn't set.
+	Get(guildID, key string) (string, bool, error)
+	// Set sets the value of key for the guild.
+	Set(guildID, key, value string) error
+	// Delete removes key from the guild, deleting a key that isn't set is not an error.
+	Delete(guildID, key string) error
+}
+
+// SettingsIterator is optionally implemented by settings providers that can list what they store.
+// Migrations and data cleanup need it to walk over every guild.
+type SettingsIterator interface {
+	// Guilds returns the IDs of all guilds that have atleast one key set.
+	Guilds() ([]string, error)
+	// Keys returns all keys set for the guild.
+	Keys(guildID string) ([]string, error)
+}
+
+// MemorySettings is an in-memory SettingsProvider, this is the default provider.
+// Settings are lost when the bot restarts, it's safe for concurrent use.
+type MemorySettings struct {
+	guilds map[string]map[string]string
+	lock   sync.RWMutex
+}
+
+// NewMemorySettings creates an empty MemorySettings
+func NewMemorySettings() *MemorySettings {
+	return &MemorySettings{guilds: make(map[string]map[string]string)}
+}
+
+// Get implements SettingsProvider
+func (m *MemorySettings) Get(guildID, key string) (string, bool, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	value, ok := m.guilds[guildID][key]
+	return value, ok, nil
+}
+
+// Set implements SettingsProvider
+func (m *MemorySettings) Set(guildID, key, value string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	guild, ok := m.guilds[guildID]
+	if !ok {
+		guild = make(map[string]string)
+		m.guilds[guildID] = guild
+	}
+	guild[key] = value
+	return nil
+}
+
+// Delete implements SettingsProvider
+func (m *MemorySettings) Delete(guildID, key string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.guilds[guildID], key)
+	if len(m.guilds[guildID]) == 0 {
+		delete(m.guilds, guildID)
+	}
+	return nil
+}
+
+// Guilds implements SettingsIterator
+func (m *MemorySettings) Guilds() ([]string, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	ids := make([]string, 0, len(m.guilds))
+	for id := range m.guilds {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Keys implements SettingsIterator
+func (m *MemorySettings) Keys(guildID string) ([]string, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	keys := make([]string, 0, len(m.guilds[guildID]))
+	for key := range m.guilds[guildID] {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// GetJSON reads key from the provider and decodes it's JSON value into v.
+// Returns false if the key isn't set.
+func GetJSON(p SettingsProvider, guildID, key string, v interface{}) (bool, error) {
+	value, ok, err := p.Get(guildID, key)
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(value), v)
+}
+
+// SetJSON encodes v as JSON and stores it in key.
+func SetJSON(p SettingsProvider, guildID, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return p.Set(guildID, key, string(data))
+}
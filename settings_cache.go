@@ -0,0 +1,229 @@
+package sapphire
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// SettingsCache is the cache layer used by CachedSettings.
+// LRUSettingsCache is the builtin local cache, implement this with your Redis client for a shared cache.
+// Entries also remember keys that aren't set so guilds using the defaults don't hit the backend on every message.
+type SettingsCache interface {
+	// Lookup returns the cached entry, hit is false if nothing is cached, set is false if the key is cached as not set.
+	Lookup(guildID, key string) (value string, set bool, hit bool)
+	// Store caches the value of key, set is false to cache that the key isn't set.
+	Store(guildID, key, value string, set bool)
+	// Invalidate removes a cached key.
+	Invalidate(guildID, key string)
+	// InvalidateGuild removes all cached keys of a guild.
+	InvalidateGuild(guildID string)
+}
+
+// CachedSettings wraps any SettingsProvider with a cache, writes go to the backend first then update the cache.
+type CachedSettings struct {
+	Backend      SettingsProvider
+	Cache        SettingsCache
+	keys         map[string]bool
+	invalidators []func(guildID, key string)
+}
+
+// NewCachedSettings wraps backend with cache, if keys are given only those keys are cached.
+// e.g NewCachedSettings(db, NewLRUSettingsCache(10000, time.Hour), "prefix", "language")
+func NewCachedSettings(backend SettingsProvider, cache SettingsCache, keys ...string) *CachedSettings {
+	c := &CachedSettings{Backend: backend, Cache: cache}
+	if len(keys) > 0 {
+		c.keys = make(map[string]bool)
+		for _, key := range keys {
+			c.keys[key] = true
+		}
+	}
+	return c
+}
+
+func (c *CachedSettings) cached(key string) bool {
+	return c.keys == nil || c.keys[key]
+}
+
+// OnInvalidate adds a hook called after this provider changes a key.
+// Use it to tell other processes sharing the backend to drop their cached copy, e.g publish it over Redis.
+func (c *CachedSettings) OnInvalidate(fn func(guildID, key string)) *CachedSettings {
+	c.invalidators = append(c.invalidators, fn)
+	return c
+}
+
+// Invalidate drops a cached key, call it when another process changed the key in the backend.
+func (c *CachedSettings) Invalidate(guildID, key string) {
+	c.Cache.Invalidate(guildID, key)
+}
+
+// InvalidateGuild drops all cached keys of a guild.
+func (c *CachedSettings) InvalidateGuild(guildID string) {
+	c.Cache.InvalidateGuild(guildID)
+}
+
+func (c *CachedSettings) changed(guildID, key string) {
+	for _, fn := range c.invalidators {
+		fn(guildID, key)
+	}
+}
+
+// Get implements SettingsProvider
+func (c *CachedSettings) Get(guildID, key string) (string, bool, error) {
+	if !c.cached(key) {
+		return c.Backend.Get(guildID, key)
+	}
+	if value, set, hit := c.Cache.Lookup(guildID, key); hit {
+		return value, set, nil
+	}
+	value, set, err := c.Backend.Get(guildID, key)
+	if err != nil {
+		return "", false, err
+	}
+	c.Cache.Store(guildID, key, value, set)
+	return value, set, nil
+}
+
+// Set implements SettingsProvider
+func (c *CachedSettings) Set(guildID, key, value string) error {
+	if err := c.Backend.Set(guildID, key, value); err != nil {
+		// The backend might have partially applied it, don't trust the cache.
+		c.Cache.Invalidate(guildID, key)
+		return err
+	}
+	if c.cached(key) {
+		c.Cache.Store(guildID, key, value, true)
+	}
+	c.changed(guildID, key)
+	return nil
+}
+
+// Delete implements SettingsProvider
+func (c *CachedSettings) Delete(guildID, key string) error {
+	if err := c.Backend.Delete(guildID, key); err != nil {
+		c.Cache.Invalidate(guildID, key)
+		return err
+	}
+	if c.cached(key) {
+		c.Cache.Store(guildID, key, "", false)
+	}
+	c.changed(guildID, key)
+	return nil
+}
+
+// Guilds implements SettingsIterator if the backend does, otherwise it returns nil.
+func (c *CachedSettings) Guilds() ([]string, error) {
+	if it, ok := c.Backend.(SettingsIterator); ok {
+		return it.Guilds()
+	}
+	return nil, nil
+}
+
+// Keys implements SettingsIterator if the backend does, otherwise it returns nil.
+func (c *CachedSettings) Keys(guildID string) ([]string, error) {
+	if it, ok := c.Backend.(SettingsIterator); ok {
+		return it.Keys(guildID)
+	}
+	return nil, nil
+}
+
+type lruKey struct {
+	guildID string
+	key     string
+}
+
+type lruEntry struct {
+	key     lruKey
+	value   string
+	set     bool
+	expires time.Time
+}
+
+// LRUSettingsCache is a local least recently used SettingsCache, safe for concurrent use.
+type LRUSettingsCache struct {
+	size    int
+	ttl     time.Duration
+	entries map[lruKey]*list.Element
+	order   *list.List
+	lock    sync.Mutex
+}
+
+// NewLRUSettingsCache creates a cache holding up to size entries, entries expire after ttl. (0 to never expire)
+func NewLRUSettingsCache(size int, ttl time.Duration) *LRUSettingsCache {
+	return &LRUSettingsCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[lruKey]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Lookup implements SettingsCache
+func (c *LRUSettingsCache) Lookup(guildID, key string) (string, bool, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	elem, ok := c.entries[lruKey{guildID, key}]
+	if !ok {
+		return "", false, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.remove(elem)
+		return "", false, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, entry.set, true
+}
+
+// Store implements SettingsCache
+func (c *LRUSettingsCache) Store(guildID, key, value string, set bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	k := lruKey{guildID, key}
+	var expires time.Time
+	if c.ttl > 0 {
+		expires = time.Now().Add(c.ttl)
+	}
+	if elem, ok := c.entries[k]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.value, entry.set, entry.expires = value, set, expires
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[k] = c.order.PushFront(&lruEntry{key: k, value: value, set: set, expires: expires})
+	for c.size > 0 && c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// Invalidate implements SettingsCache
+func (c *LRUSettingsCache) Invalidate(guildID, key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if elem, ok := c.entries[lruKey{guildID, key}]; ok {
+		c.remove(elem)
+	}
+}
+
+// InvalidateGuild implements SettingsCache
+func (c *LRUSettingsCache) InvalidateGuild(guildID string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for k, elem := range c.entries {
+		if k.guildID == guildID {
+			c.remove(elem)
+		}
+	}
+}
+
+// Len returns the amount of cached entries.
+func (c *LRUSettingsCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRUSettingsCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*lruEntry).key)
+}
@@ -0,0 +1,46 @@
+package sapphire
+
+import (
+	"testing"
+)
+
+type countingSettings struct {
+	*MemorySettings
+	gets int
+}
+
+func (c *countingSettings) Get(guildID, key string) (string, bool, error) {
+	c.gets++
+	return c.MemorySettings.Get(guildID, key)
+}
+
+func TestCachedSettings(t *testing.T) {
+	backend := &countingSettings{MemorySettings: NewMemorySettings()}
+	cache := NewLRUSettingsCache(2, 0)
+	settings := NewCachedSettings(backend, cache)
+
+	// Unset keys are cached too.
+	settings.Get("1", "prefix")
+	settings.Get("1", "prefix")
+	if backend.gets != 1 {
+		t.Errorf("Expected 1 backend read but got %d", backend.gets)
+	}
+
+	settings.Set("1", "prefix", "?")
+	if value, set, _ := settings.Get("1", "prefix"); !set || value != "?" {
+		t.Errorf("Expected the cache to be updated on write but got \"%s\"", value)
+	}
+
+	settings.Get("2", "prefix")
+	settings.Get("3", "prefix")
+	if cache.Len() != 2 {
+		t.Errorf("Expected the cache to evict down to 2 entries but got %d", cache.Len())
+	}
+
+	var invalidated string
+	settings.OnInvalidate(func(guildID, key string) { invalidated = guildID + "/" + key })
+	settings.Delete("1", "prefix")
+	if invalidated != "1/prefix" {
+		t.Errorf("Expected the invalidation hook to run but got \"%s\"", invalidated)
+	}
+}
@@ -0,0 +1,182 @@
+package sapphire
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SQLDialect selects the SQL flavour used by SQLSettings.
+type SQLDialect int
+
+const (
+	SQLDialectPostgres SQLDialect = iota // PostgreSQL, values are stored as TEXT.
+	SQLDialectSQLite                     // SQLite 3.24 or newer.
+)
+
+// placeholder returns the nth (1 based) bind parameter for the dialect.
+func (d SQLDialect) placeholder(n int) string {
+	if d == SQLDialectPostgres {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// SQLSettingsOptions configures SQLSettings, zero values use the defaults.
+type SQLSettingsOptions struct {
+	Table           string        // Table name. (default: sapphire_settings)
+	MaxOpenConns    int           // Passed to db.SetMaxOpenConns if not 0.
+	MaxIdleConns    int           // Passed to db.SetMaxIdleConns if not 0.
+	ConnMaxLifetime time.Duration // Passed to db.SetConnMaxLifetime if not 0.
+}
+
+// SQLSettings is a SettingsProvider backed by a SQL database using prepared statements.
+// Sapphire doesn't import any driver, open the *sql.DB with the driver of your choice.
+// Structured values can be stored with SetJSON and read back with GetJSON.
+type SQLSettings struct {
+	DB      *sql.DB
+	Dialect SQLDialect
+	table   string
+	get     *sql.Stmt
+	set     *sql.Stmt
+	del     *sql.Stmt
+	guilds  *sql.Stmt
+	keys    *sql.Stmt
+	prefix  *sql.Stmt
+}
+
+// NewSQLSettings creates the settings table if it doesn't exist and prepares the statements.
+func NewSQLSettings(db *sql.DB, dialect SQLDialect, opts SQLSettingsOptions) (*SQLSettings, error) {
+	if opts.Table == "" {
+		opts.Table = "sapphire_settings"
+	}
+	if opts.MaxOpenConns != 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime != 0 {
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+
+	s := &SQLSettings{DB: db, Dialect: dialect, table: opts.Table}
+	p := dialect.placeholder
+
+	_, err := db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	guild_id TEXT NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT NOT NULL,
+	PRIMARY KEY (guild_id, key)
+)`, s.table))
+	if err != nil {
+		return nil, err
+	}
+
+	queries := []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&s.get, fmt.Sprintf("SELECT value FROM %s WHERE guild_id = %s AND key = %s", s.table, p(1), p(2))},
+		{&s.set, fmt.Sprintf("INSERT INTO %s (guild_id, key, value) VALUES (%s, %s, %s) ON CONFLICT (guild_id, key) DO UPDATE SET value = excluded.value", s.table, p(1), p(2), p(3))},
+		{&s.del, fmt.Sprintf("DELETE FROM %s WHERE guild_id = %s AND key = %s", s.table, p(1), p(2))},
+		{&s.guilds, fmt.Sprintf("SELECT DISTINCT guild_id FROM %s", s.table)},
+		{&s.keys, fmt.Sprintf("SELECT key FROM %s WHERE guild_id = %s", s.table, p(1))},
+		{&s.prefix, fmt.Sprintf("SELECT key, value FROM %s WHERE guild_id = %s AND key LIKE %s ESCAPE '\\'", s.table, p(1), p(2))},
+	}
+	for _, q := range queries {
+		stmt, err := db.Prepare(q.query)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		*q.stmt = stmt
+	}
+	return s, nil
+}
+
+// Get implements SettingsProvider
+func (s *SQLSettings) Get(guildID, key string) (string, bool, error) {
+	var value string
+	err := s.get.QueryRow(guildID, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+// Set implements SettingsProvider
+func (s *SQLSettings) Set(guildID, key, value string) error {
+	_, err := s.set.Exec(guildID, key, value)
+	return err
+}
+
+// Delete implements SettingsProvider
+func (s *SQLSettings) Delete(guildID, key string) error {
+	_, err := s.del.Exec(guildID, key)
+	return err
+}
+
+// Guilds implements SettingsIterator
+func (s *SQLSettings) Guilds() ([]string, error) {
+	return s.strings(s.guilds)
+}
+
+// Keys implements SettingsIterator
+func (s *SQLSettings) Keys(guildID string) ([]string, error) {
+	return s.strings(s.keys, guildID)
+}
+
+// GetPrefixed returns all keys of the guild starting with prefix, useful to load all settings of an extension at once.
+func (s *SQLSettings) GetPrefixed(guildID, prefix string) (map[string]string, error) {
+	// Escape LIKE wildcards so they are matched literally.
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix)
+	rows, err := s.prefix.Query(guildID, escaped+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	values := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		// Double check in case the database didn't honour the escapes.
+		if strings.HasPrefix(key, prefix) {
+			values[key] = value
+		}
+	}
+	return values, rows.Err()
+}
+
+func (s *SQLSettings) strings(stmt *sql.Stmt, args ...interface{}) ([]string, error) {
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []string
+	for rows.Next() {
+		var str string
+		if err := rows.Scan(&str); err != nil {
+			return nil, err
+		}
+		res = append(res, str)
+	}
+	return res, rows.Err()
+}
+
+// Close closes the prepared statements, it doesn't close the database.
+func (s *SQLSettings) Close() error {
+	for _, stmt := range []*sql.Stmt{s.get, s.set, s.del, s.guilds, s.keys, s.prefix} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+	return nil
+}
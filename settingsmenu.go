@@ -0,0 +1,279 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strings"
+)
+
+// settingsGeneral is the section of the prefix and language, see bot.LoadGuildSettings
+const settingsGeneral = "general"
+
+// EnableSettingsMenu loads the settings command, it shows the guild's settings with select menus to pick what to
+// change: the prefix and language (with LoadGuildSettings) and the keys of every config schema. Channels and roles
+// are picked from select menus, yes or no keys and the language from a list and the rest is typed in a modal.
+// Everything is written through the settings provider like the config command does. It needs Manage Server.
+func (bot *Bot) EnableSettingsMenu() *Bot {
+	bot.AddComponentHandler("settings", settingsComponent)
+	return bot.AddCommand(NewCommand("settings", "Settings", settingsCommand).
+		SetDescription("Shows this server's settings with menus to change them.").
+		SetRequiredPermissions(discordgo.PermissionManageServer).
+		SetGuildOnly(true).
+		SetSlash(true))
+}
+
+func settingsCommand(ctx *CommandContext) {
+	ctx.respond(ctx.settingsView(ctx.Author.ID, "", ""))
+}
+
+// settingsView renders a section of the settings, or the section picker for "". notice is shown above it.
+func (ctx *CommandContext) settingsView(userID, section, notice string) *ResponseMessage {
+	bot := ctx.Bot
+	var sections []*SelectOption
+	if bot.guildOverrides {
+		label, _ := ctx.localize("COMMAND_SETTINGS_GENERAL")
+		sections = append(sections, &SelectOption{Label: label, Value: settingsGeneral, Default: section == settingsGeneral})
+	}
+	names := make([]string, 0, len(bot.ConfigSchemas))
+	for name := range bot.ConfigSchemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sections = append(sections, &SelectOption{Label: name, Value: name, Default: section == name,
+			Description: optionDescription(ctx.describe(bot.ConfigSchemas[name].Description))})
+	}
+	if len(sections) > 25 {
+		sections = sections[:25]
+	}
+	placeholder, _ := ctx.localize("COMMAND_SETTINGS_PICK_SECTION")
+	sectionMenu := NewActionRow(NewSelectMenu("settings:section:"+userID, placeholder, sections...))
+	if section == "" {
+		if notice == "" {
+			notice = placeholder
+		}
+		return &ResponseMessage{Content: notice, Components: []*Component{sectionMenu}}
+	}
+
+	embed := NewEmbed().SetTitle(section).SetColor(bot.Color)
+	var keys []*SelectOption
+	for _, key := range ctx.settingsKeys(section) {
+		embed.AddField(key.Name, ctx.describe(key.Description)+"\n"+displayConfig(key.Type, ctx.settingValue(section, key)))
+		keys = append(keys, &SelectOption{Label: key.Name, Value: key.Name, Description: optionDescription(ctx.describe(key.Description))})
+	}
+	if schema, ok := bot.ConfigSchemas[section]; ok {
+		embed.SetDescription(ctx.describe(schema.Description))
+	}
+	if len(keys) > 25 {
+		keys = keys[:25]
+	}
+	placeholder, _ = ctx.localize("COMMAND_SETTINGS_PICK_KEY")
+	keyMenu := NewActionRow(NewSelectMenu("settings:key:"+userID+":"+section, placeholder, keys...))
+	return &ResponseMessage{Content: notice, Embed: embed.Build(), Components: []*Component{keyMenu, sectionMenu}}
+}
+
+// settingsKeys returns the keys of a section, the general section's keys are made up to describe the prefix
+// and language like config keys.
+func (ctx *CommandContext) settingsKeys(section string) []*ConfigKey {
+	if section == settingsGeneral {
+		return []*ConfigKey{
+			{Name: prefixKey, Type: ConfigString, Description: "COMMAND_SETTINGS_PREFIX"},
+			{Name: languageKey, Type: ConfigString, Description: "COMMAND_SETTINGS_LANGUAGE"},
+		}
+	}
+	if schema, ok := ctx.Bot.ConfigSchemas[section]; ok {
+		return schema.Keys
+	}
+	return nil
+}
+
+// settingValue returns the current value of a key in the guild, the general keys show the fallback when unset.
+func (ctx *CommandContext) settingValue(section string, key *ConfigKey) string {
+	guildID := ctx.Message.GuildID
+	if section == settingsGeneral {
+		if key.Name == prefixKey {
+			return ctx.Bot.Prefix(ctx.Bot, ctx.Message, false)
+		}
+		return ctx.Bot.Language(ctx.Bot, ctx.Message, false)
+	}
+	return ctx.Bot.ConfigSchemas[section].Get(ctx.Bot, guildID, key.Name)
+}
+
+// settingsComponent handles the settings menus, the custom IDs are "settings:<step>:<user ID>[:<section>[:<key>]]"
+func settingsComponent(ctx *CommandContext) {
+	args := ctx.RawArgs
+	if len(args) < 2 {
+		return
+	}
+	step, userID := args[0], args[1]
+	// Only who ran the command passed it's permission checks.
+	if userID != ctx.Author.ID {
+		content, _ := ctx.localize("COMMAND_SETTINGS_NOT_YOURS", userID)
+		ctx.response().Send(&ResponseMessage{Content: content, Ephemeral: true})
+		return
+	}
+	data := ctx.Interaction.Data
+	picked := ""
+	if len(data.Values) > 0 {
+		picked = data.Values[0]
+	}
+
+	switch {
+	case step == "section":
+		ctx.UpdateMessage(ctx.settingsView(userID, picked, ""))
+	case step == "key" && len(args) > 2:
+		ctx.settingsPrompt(userID, args[2], picked)
+	case step == "value" && len(args) > 3:
+		if ctx.Interaction.Type == InteractionModalSubmit {
+			picked = data.Value("value")
+		}
+		notice, _ := ctx.applySetting(args[2], args[3], picked)
+		ctx.UpdateMessage(ctx.settingsView(userID, args[2], notice))
+	case step == "reset" && len(args) > 3:
+		notice, _ := ctx.applySetting(args[2], args[3], "")
+		ctx.UpdateMessage(ctx.settingsView(userID, args[2], notice))
+	}
+}
+
+// settingsPrompt asks for the new value of a key, with a select menu if there's a list to pick from and a modal otherwise.
+func (ctx *CommandContext) settingsPrompt(userID, section, name string) {
+	var key *ConfigKey
+	for _, k := range ctx.settingsKeys(section) {
+		if k.Name == name {
+			key = k
+		}
+	}
+	if key == nil {
+		ctx.UpdateMessage(ctx.settingsView(userID, section, ""))
+		return
+	}
+	id := "settings:value:" + userID + ":" + section + ":" + key.Name
+	placeholder, _ := ctx.localize("COMMAND_SETTINGS_PICK_VALUE", key.Name)
+	current := ctx.settingValue(section, key)
+	menu := ctx.settingsMenu(id, placeholder, section, key, current)
+	if menu == nil {
+		title, _ := ctx.localize("COMMAND_SETTINGS_MODAL_TITLE", key.Name)
+		label, _ := ctx.localize("COMMAND_SETTINGS_MODAL_LABEL")
+		if err := ctx.ShowModal(id, truncateTitle(title), NewTextInput("value", label, current).SetRequired(false)); err != nil {
+			ctx.Bot.ErrorHandler(ctx.Bot, &CommandError{Err: err, Context: ctx})
+		}
+		return
+	}
+	reset, _ := ctx.localize("COMMAND_SETTINGS_RESET")
+	ctx.UpdateMessage(&ResponseMessage{
+		Content: placeholder,
+		Components: []*Component{
+			NewActionRow(menu),
+			NewActionRow(NewButton("settings:reset:"+userID+":"+section+":"+key.Name, reset, ButtonDanger)),
+		},
+	})
+}
+
+// settingsMenu returns the select menu to pick a key's value from, nil if the value is typed in instead.
+func (ctx *CommandContext) settingsMenu(id, placeholder, section string, key *ConfigKey, current string) *Component {
+	switch {
+	case key.Type == ConfigChannel:
+		return NewChannelSelect(id, placeholder)
+	case key.Type == ConfigRole:
+		return NewRoleSelect(id, placeholder)
+	case key.Type == ConfigBool:
+		yes, _ := ctx.localize("COMMAND_SETTINGS_YES")
+		no, _ := ctx.localize("COMMAND_SETTINGS_NO")
+		return NewSelectMenu(id, placeholder, &SelectOption{Label: yes, Value: "true", Default: current == "true"},
+			&SelectOption{Label: no, Value: "false", Default: current == "false"})
+	case section == settingsGeneral && key.Name == languageKey:
+		names := make([]string, 0, len(ctx.Bot.Languages))
+		for name := range ctx.Bot.Languages {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) > 25 {
+			names = names[:25]
+		}
+		options := make([]*SelectOption, len(names))
+		for i, name := range names {
+			options[i] = &SelectOption{Label: name, Value: name, Default: name == current}
+		}
+		return NewSelectMenu(id, placeholder, options...)
+	}
+	return nil
+}
+
+// applySetting writes a key, an empty value resets it. Returns the notice telling the user what happened
+// and if the value was written.
+func (ctx *CommandContext) applySetting(section, name, value string) (string, bool) {
+	bot := ctx.Bot
+	guildID := ctx.Message.GuildID
+	value = strings.TrimSpace(value)
+	if section == settingsGeneral {
+		var err error
+		switch name {
+		case prefixKey:
+			if len(value) > MaxPrefixLength {
+				notice, _ := ctx.localize("COMMAND_SETPREFIX_TOO_LONG", MaxPrefixLength)
+				return notice, false
+			}
+			err = bot.SetGuildPrefix(guildID, value)
+		case languageKey:
+			if _, ok := bot.Languages[value]; !ok && value != "" {
+				notice, _ := ctx.localize("COMMAND_SETTINGS_INVALID", value)
+				return notice, false
+			}
+			err = bot.SetGuildLanguage(guildID, value)
+		}
+		if err != nil {
+			return ctx.settingsError(err), false
+		}
+		return ctx.settingsChanged(name, value), true
+	}
+
+	schema, ok := bot.ConfigSchemas[section]
+	if !ok || schema.Key(name) == nil {
+		return "", false
+	}
+	if value == "" {
+		if err := schema.Reset(bot, guildID, name); err != nil {
+			return ctx.settingsError(err), false
+		}
+		return ctx.settingsChanged(name, ""), true
+	}
+	normalized, err := schema.Set(bot, guildID, name, value)
+	if err != nil {
+		// Set's errors are meant for the user, see the config command.
+		return err.Error(), false
+	}
+	return ctx.settingsChanged(name, displayConfig(schema.Key(name).Type, normalized)), true
+}
+
+// settingsError reports a failed write and tells the user something went wrong, like ctx.Error
+func (ctx *CommandContext) settingsError(err error) string {
+	ctx.Bot.ErrorHandler(ctx.Bot, &CommandError{Err: err, Context: ctx})
+	notice, _ := ctx.localize("COMMAND_ERROR")
+	return notice
+}
+
+func (ctx *CommandContext) settingsChanged(name, value string) string {
+	if value == "" {
+		notice, _ := ctx.localize("COMMAND_SETTINGS_RESET_DONE", name)
+		return notice
+	}
+	notice, _ := ctx.localize("COMMAND_SETTINGS_SET", name, value)
+	return notice
+}
+
+// optionDescription fits s into the 100 characters Discord allows for select option descriptions, they are optional.
+func optionDescription(s string) string {
+	if s == "" {
+		return ""
+	}
+	return truncateDescription(s)
+}
+
+// truncateTitle fits s into the 45 characters Discord allows for modal titles.
+func truncateTitle(s string) string {
+	runes := []rune(s)
+	if len(runes) > 45 {
+		return string(runes[:44]) + "…"
+	}
+	return s
+}
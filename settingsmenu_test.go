@@ -0,0 +1,74 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSettingsMenu(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	bot.LoadGuildSettings().EnableSettingsMenu()
+	logs := NewConfigSchema("logs", "Where things are logged.").
+		Add("channel", ConfigChannel, "", "The log channel.").
+		Add("greeting", ConfigString, "Welcome!", "The welcome message.")
+	bot.AddConfigSchema(logs)
+	calls := recordREST(bot)
+	click := func(typ int, user, data string) map[string]interface{} {
+		before := len(calls())
+		dispatchInteraction(t, bot, `{"id":"i","application_id":"a","type":`+strconv.Itoa(typ)+`,"token":"tok","channel_id":"c",
+			"guild_id":"g","member":{"user":{"id":"`+user+`"}},"data":`+data+`}`)
+		requests := calls()
+		if len(requests) == before {
+			t.Fatal("Expected the interaction to be answered")
+		}
+		return requests[before].Data
+	}
+	payload := func(response map[string]interface{}) string {
+		data, _ := json.Marshal(response["data"])
+		return string(data)
+	}
+
+	response := click(InteractionComponent, "u", `{"custom_id":"settings:section:u","component_type":3,"values":["logs"]}`)
+	if response["type"] != ResponseUpdateMessage || !strings.Contains(payload(response), `"settings:key:u:logs"`) {
+		t.Errorf("Expected the section to replace the menu with it's keys but got %v", response)
+	}
+
+	response = click(InteractionComponent, "u", `{"custom_id":"settings:key:u:logs","component_type":3,"values":["channel"]}`)
+	if !strings.Contains(payload(response), `"type":8`) {
+		t.Errorf("Expected channels to be picked from a channel select but got %s", payload(response))
+	}
+
+	response = click(InteractionComponent, "u", `{"custom_id":"settings:key:u:logs","component_type":3,"values":["greeting"]}`)
+	if response["type"] != ResponseModal || !strings.Contains(payload(response), `"settings:value:u:logs:greeting"`) {
+		t.Errorf("Expected a modal for the text key but got %v", response)
+	}
+
+	response = click(InteractionModalSubmit, "u", `{"custom_id":"settings:value:u:logs:greeting",
+		"components":[{"type":1,"components":[{"type":4,"custom_id":"value","value":"Hi there"}]}]}`)
+	if value := logs.Get(bot, "g", "greeting"); value != "Hi there" {
+		t.Errorf("Expected the modal to set the greeting but it's %q", value)
+	}
+	if response["type"] != ResponseUpdateMessage || !strings.Contains(payload(response), "greeting") {
+		t.Errorf("Expected the menu to show what changed but got %v", response)
+	}
+
+	click(InteractionComponent, "u", `{"custom_id":"settings:reset:u:logs:greeting","component_type":2}`)
+	if value := logs.Get(bot, "g", "greeting"); value != "Welcome!" {
+		t.Errorf("Expected the greeting to be reset but it's %q", value)
+	}
+
+	click(InteractionModalSubmit, "u", `{"custom_id":"settings:value:u:general:prefix",
+		"components":[{"type":1,"components":[{"type":4,"custom_id":"value","value":"?"}]}]}`)
+	if prefix := bot.guildSetting("g", prefixKey); prefix != "?" {
+		t.Errorf("Expected the prefix to be changed but it's %q", prefix)
+	}
+
+	response = click(InteractionModalSubmit, "x", `{"custom_id":"settings:value:u:general:prefix",
+		"components":[{"type":1,"components":[{"type":4,"custom_id":"value","value":"!"}]}]}`)
+	if prefix := bot.guildSetting("g", prefixKey); prefix != "?" || !strings.Contains(payload(response), `"flags":64`) {
+		t.Errorf("Expected someone else's menu to be refused but the prefix is %q", prefix)
+	}
+}
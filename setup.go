@@ -0,0 +1,199 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strconv"
+)
+
+// SetupStep is a setting the setup wizard asks about, a config key or the prefix or language when Schema is nil.
+type SetupStep struct {
+	Schema *ConfigSchema
+	Key    string
+}
+
+// section returns the settings menu section of the step, see EnableSettingsMenu
+func (step SetupStep) section() string {
+	if step.Schema == nil {
+		return settingsGeneral
+	}
+	return step.Schema.Name
+}
+
+// AddSetupStep adds a config key to the setup wizard, steps are asked in the order they're added.
+// Without steps the wizard asks for every enabled and channel key of the registered schemas.
+func (bot *Bot) AddSetupStep(schema *ConfigSchema, key string) *Bot {
+	if schema.Key(key) == nil {
+		panic(fmt.Sprintf("The config key '%s' doesn't exist in '%s'", key, schema.Name))
+	}
+	bot.setupSteps = append(bot.setupSteps, SetupStep{Schema: schema, Key: key})
+	return bot
+}
+
+// SetupSteps returns the steps of the setup wizard, with LoadGuildSettings the prefix and language come first.
+func (bot *Bot) SetupSteps() []SetupStep {
+	var steps []SetupStep
+	if bot.guildOverrides {
+		steps = append(steps, SetupStep{Key: prefixKey}, SetupStep{Key: languageKey})
+	}
+	if len(bot.setupSteps) > 0 {
+		return append(steps, bot.setupSteps...)
+	}
+	names := make([]string, 0, len(bot.ConfigSchemas))
+	for name := range bot.ConfigSchemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		schema := bot.ConfigSchemas[name]
+		for _, key := range schema.Keys {
+			if key.Type == ConfigChannel || key.Type == ConfigBool && key.Name == "enabled" {
+				steps = append(steps, SetupStep{Schema: schema, Key: key.Name})
+			}
+		}
+	}
+	return steps
+}
+
+// LoadSetupCommand loads the setup command, a wizard walking server managers through each setup step in turn.
+// Channels, roles, yes or no keys and the language are picked from select menus and the rest is typed in a modal,
+// answers are validated like the config command does and asked again if they're invalid. It needs Manage Server.
+func (bot *Bot) LoadSetupCommand() *Bot {
+	bot.AddComponentHandler("setup", setupComponent)
+	return bot.AddCommand(NewCommand("setup", "Settings", setupCommand).
+		SetDescription("Walks you through configuring the bot for this server.").
+		SetRequiredPermissions(discordgo.PermissionManageServer).
+		SetGuildOnly(true).
+		SetSlash(true))
+}
+
+func setupCommand(ctx *CommandContext) {
+	steps := ctx.Bot.SetupSteps()
+	if len(steps) == 0 {
+		ctx.ReplyLocale("COMMAND_SETUP_NOTHING")
+		return
+	}
+	start, _ := ctx.localize("COMMAND_SETUP_START", len(steps))
+	ctx.respond(ctx.setupView(ctx.Author.ID, steps, 0, 0, start))
+}
+
+// setupKey returns the config key a step asks about, the prefix and language are described like settingsKeys does.
+func (ctx *CommandContext) setupKey(step SetupStep) *ConfigKey {
+	for _, key := range ctx.settingsKeys(step.section()) {
+		if key.Name == step.Key {
+			return key
+		}
+	}
+	return nil
+}
+
+// setupView renders the step at index, or the summary after the last one. changed is how many settings were
+// changed so far and notice is shown above it.
+func (ctx *CommandContext) setupView(userID string, steps []SetupStep, index, changed int, notice string) *ResponseMessage {
+	bot := ctx.Bot
+	if index >= len(steps) {
+		done, _ := ctx.localize("COMMAND_SETUP_DONE", changed)
+		return &ResponseMessage{Content: notice, Embed: NewEmbed().SetDescription(done).SetColor(bot.Color).Build(),
+			Components: []*Component{}}
+	}
+	step := steps[index]
+	key := ctx.setupKey(step)
+	if key == nil {
+		return ctx.setupView(userID, steps, index+1, changed, notice)
+	}
+	id := func(action string) string {
+		return fmt.Sprintf("setup:%s:%s:%d:%d", action, userID, index, changed)
+	}
+
+	section := step.section()
+	if step.Schema == nil {
+		section, _ = ctx.localize("COMMAND_SETTINGS_GENERAL")
+	}
+	current := ctx.settingValue(step.section(), key)
+	currentLabel, _ := ctx.localize("COMMAND_SETUP_CURRENT")
+	embed := NewEmbed().
+		SetTitle(fmt.Sprintf("%s %s (%d/%d)", section, key.Name, index+1, len(steps))).
+		SetDescription(ctx.describe(key.Description)).
+		AddField(currentLabel, displayConfig(key.Type, current)).
+		SetColor(bot.Color)
+
+	skip, _ := ctx.localize("COMMAND_SETUP_SKIP")
+	cancel, _ := ctx.localize("COMMAND_SETUP_CANCEL")
+	buttons := []*Component{NewButton(id("skip"), skip, ButtonSecondary), NewButton(id("cancel"), cancel, ButtonDanger)}
+	var components []*Component
+	placeholder, _ := ctx.localize("COMMAND_SETTINGS_PICK_VALUE", key.Name)
+	if menu := ctx.settingsMenu(id("value"), placeholder, step.section(), key, current); menu != nil {
+		components = append(components, NewActionRow(menu))
+	} else {
+		// Modals can only open in answer to a click.
+		answer, _ := ctx.localize("COMMAND_SETUP_ANSWER")
+		buttons = append([]*Component{NewButton(id("prompt"), answer, ButtonPrimary)}, buttons...)
+	}
+	components = append(components, NewActionRow(buttons...))
+	return &ResponseMessage{Content: notice, Embed: embed.Build(), Components: components}
+}
+
+// setupComponent handles the setup wizard, the custom IDs are "setup:<action>:<user ID>:<step>:<settings changed>"
+func setupComponent(ctx *CommandContext) {
+	args := ctx.RawArgs
+	if len(args) < 4 {
+		return
+	}
+	action, userID := args[0], args[1]
+	index, err := strconv.Atoi(args[2])
+	if err != nil {
+		return
+	}
+	changed, err := strconv.Atoi(args[3])
+	if err != nil {
+		return
+	}
+	// Only who ran the command passed it's permission checks.
+	if userID != ctx.Author.ID {
+		content, _ := ctx.localize("COMMAND_SETUP_NOT_YOURS", userID)
+		ctx.response().Send(&ResponseMessage{Content: content, Ephemeral: true})
+		return
+	}
+	steps := ctx.Bot.SetupSteps()
+	if index < 0 || index >= len(steps) {
+		return
+	}
+	step := steps[index]
+
+	switch action {
+	case "prompt":
+		key := ctx.setupKey(step)
+		if key == nil {
+			return
+		}
+		title, _ := ctx.localize("COMMAND_SETTINGS_MODAL_TITLE", key.Name)
+		label, _ := ctx.localize("COMMAND_SETUP_MODAL_LABEL")
+		id := fmt.Sprintf("setup:value:%s:%d:%d", userID, index, changed)
+		input := NewTextInput("value", label, ctx.settingValue(step.section(), key))
+		if err := ctx.ShowModal(id, truncateTitle(title), input); err != nil {
+			ctx.Bot.ErrorHandler(ctx.Bot, &CommandError{Err: err, Context: ctx})
+		}
+	case "value":
+		data := ctx.Interaction.Data
+		value := ""
+		if ctx.Interaction.Type == InteractionModalSubmit {
+			value = data.Value("value")
+		} else if len(data.Values) > 0 {
+			value = data.Values[0]
+		}
+		notice, ok := ctx.applySetting(step.section(), step.Key, value)
+		if !ok {
+			// Ask again until it's valid or skipped.
+			ctx.UpdateMessage(ctx.setupView(userID, steps, index, changed, notice))
+			return
+		}
+		ctx.UpdateMessage(ctx.setupView(userID, steps, index+1, changed+1, notice))
+	case "skip":
+		ctx.UpdateMessage(ctx.setupView(userID, steps, index+1, changed, ""))
+	case "cancel":
+		cancelled, _ := ctx.localize("COMMAND_SETUP_CANCELLED", changed)
+		ctx.UpdateMessage(&ResponseMessage{Embed: NewEmbed().SetDescription(cancelled).SetColor(ctx.Bot.Color).Build(),
+			Components: []*Component{}})
+	}
+}
@@ -0,0 +1,82 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSetupSteps(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	bot.AddConfigSchema(AutomodConfig).AddConfigSchema(HeatConfig)
+	var keys []string
+	for _, step := range bot.SetupSteps() {
+		keys = append(keys, step.Schema.Name+"."+step.Key)
+	}
+	if strings.Join(keys, " ") != "automod.enabled automod.log heat.enabled" {
+		t.Errorf("Unexpected default steps %v", keys)
+	}
+	bot.AddSetupStep(HeatConfig, "decay")
+	if steps := bot.SetupSteps(); len(steps) != 1 || steps[0].Key != "decay" {
+		t.Errorf("Expected only the added step, got %v", steps)
+	}
+}
+
+func TestSetupWizard(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	bot.LoadGuildSettings().LoadSetupCommand()
+	bot.AddConfigSchema(NewConfigSchema("logs", "Where things are logged.").Add("channel", ConfigChannel, "", "The log channel."))
+	if steps := bot.SetupSteps(); len(steps) != 3 || steps[0].Key != prefixKey || steps[1].Key != languageKey {
+		t.Fatalf("Expected the prefix and language steps first, got %v", steps)
+	}
+	calls := recordREST(bot)
+	click := func(typ int, user, data string) (map[string]interface{}, string) {
+		before := len(calls())
+		dispatchInteraction(t, bot, `{"id":"i","application_id":"a","type":`+strconv.Itoa(typ)+`,"token":"tok","channel_id":"c",
+			"guild_id":"g","member":{"user":{"id":"`+user+`"}},"data":`+data+`}`)
+		requests := calls()
+		if len(requests) == before {
+			t.Fatal("Expected the interaction to be answered")
+		}
+		payload, _ := json.Marshal(requests[before].Data["data"])
+		return requests[before].Data, string(payload)
+	}
+	prefix := func(value string) string {
+		return `{"custom_id":"setup:value:u:0:0","components":[{"type":1,"components":[{"type":4,"custom_id":"value","value":"` + value + `"}]}]}`
+	}
+
+	response, payload := click(InteractionComponent, "u", `{"custom_id":"setup:prompt:u:0:0","component_type":2}`)
+	if response["type"] != ResponseModal || !strings.Contains(payload, `"setup:value:u:0:0"`) {
+		t.Errorf("Expected a modal for the prefix but got %v", response)
+	}
+
+	_, payload = click(InteractionModalSubmit, "u", prefix(strings.Repeat("!", MaxPrefixLength+1)))
+	if !strings.Contains(payload, `"setup:prompt:u:0:0"`) {
+		t.Errorf("Expected a too long prefix to be asked again but got %s", payload)
+	}
+
+	_, payload = click(InteractionModalSubmit, "u", prefix("?"))
+	if value := bot.guildSetting("g", prefixKey); value != "?" {
+		t.Errorf("Expected the prefix to be changed but it's %q", value)
+	}
+	if !strings.Contains(payload, `"setup:value:u:1:1"`) || !strings.Contains(payload, `"setup:skip:u:1:1"`) {
+		t.Errorf("Expected the language step with a select menu but got %s", payload)
+	}
+
+	_, payload = click(InteractionComponent, "u", `{"custom_id":"setup:skip:u:1:1","component_type":2}`)
+	if !strings.Contains(payload, `"setup:value:u:2:1"`) || !strings.Contains(payload, `"type":8`) {
+		t.Errorf("Expected the log channel to be picked from a channel select but got %s", payload)
+	}
+
+	_, payload = click(InteractionComponent, "x", `{"custom_id":"setup:skip:u:2:1","component_type":2}`)
+	if !strings.Contains(payload, "belongs to") || !strings.Contains(payload, `"flags":64`) {
+		t.Errorf("Expected others to be told the setup isn't theirs but got %s", payload)
+	}
+
+	_, payload = click(InteractionComponent, "u", `{"custom_id":"setup:skip:u:2:1","component_type":2}`)
+	if !strings.Contains(payload, "**1** settings changed") || !strings.Contains(payload, `"components":[]`) {
+		t.Errorf("Expected the summary without components but got %s", payload)
+	}
+}
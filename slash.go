@@ -0,0 +1,360 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SlashGuildFilter decides if a slash command is registered in a guild, see cmd.SetSlashGuilds
+type SlashGuildFilter func(bot *Bot, guildID string) bool
+
+// SlashIn registers the command only in the given guilds, e.g development servers.
+func SlashIn(guildIDs ...string) SlashGuildFilter {
+	return func(_ *Bot, guildID string) bool {
+		for _, id := range guildIDs {
+			if id == guildID {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// SlashPremium registers the command only in premium guilds, see bot.SetPremiumProvider
+func SlashPremium() SlashGuildFilter {
+	return func(bot *Bot, guildID string) bool {
+		return bot.Premium != nil && bot.Premium.IsPremiumGuild(guildID)
+	}
+}
+
+// SlashOptIn registers the command only in guilds that turned on the bool config key, the commands are synced
+// again when it changes.
+func SlashOptIn(schema *ConfigSchema, key string) SlashGuildFilter {
+	return func(bot *Bot, guildID string) bool {
+		return schema.GetBool(bot, guildID, key)
+	}
+}
+
+// ApplicationCommand is a slash command or message command as it's registered with Discord.
+type ApplicationCommand struct {
+	Type                     int                         `json:"type,omitempty"` // Slash commands leave it out.
+	Name                     string                      `json:"name"`
+	NameLocalizations        map[string]string           `json:"name_localizations,omitempty"`
+	Description              string                      `json:"description"`
+	DescriptionLocalizations map[string]string           `json:"description_localizations,omitempty"`
+	Options                  []*ApplicationCommandOption `json:"options,omitempty"`
+	DefaultMemberPermissions *string                     `json:"default_member_permissions,omitempty"` // nil lets everyone use it.
+	DMPermission             bool                        `json:"dm_permission"`
+}
+
+// ApplicationCommandOption is an option of a slash command, made from the command's usage tags.
+type ApplicationCommandOption struct {
+	Type                     int               `json:"type"`
+	Name                     string            `json:"name"`
+	NameLocalizations        map[string]string `json:"name_localizations,omitempty"`
+	Description              string            `json:"description"`
+	DescriptionLocalizations map[string]string `json:"description_localizations,omitempty"`
+	Required                 bool              `json:"required,omitempty"`
+	MinValue                 *float64          `json:"min_value,omitempty"`
+	MaxValue                 *float64          `json:"max_value,omitempty"`
+	MinLength                *int              `json:"min_length,omitempty"`
+	MaxLength                *int              `json:"max_length,omitempty"`
+}
+
+// The option type of usage tag types, the rest are strings parsed by the command's argument parser.
+var optionTypes = map[string]int{
+	"num":     OptionInteger,
+	"number":  OptionInteger,
+	"int":     OptionInteger,
+	"float":   OptionNumber,
+	"bool":    OptionBoolean,
+	"member":  OptionUser,
+	"user":    OptionUser,
+	"chan":    OptionChannel,
+	"channel": OptionChannel,
+	"role":    OptionRole,
+}
+
+type commandSync struct {
+	lock   sync.Mutex
+	synced map[string]string // Guild ID ("" for global) -> the last commands registered there.
+}
+
+// ErrNoApplicationID is returned when commands are synced before the application ID is known.
+var ErrNoApplicationID = errors.New("the application ID is unknown, set bot.ApplicationID or wait for the ready event")
+
+// EnableCommandSync registers the slash commands with Discord and keeps them up to date, global commands on ready
+// and guild commands (see cmd.SetSlashGuilds) when joining a guild and when it's settings change.
+// Only commands with SetSlash(true) are registered.
+func (bot *Bot) EnableCommandSync() *Bot {
+	if bot.commandSync != nil {
+		return bot
+	}
+	bot.commandSync = &commandSync{synced: make(map[string]string)}
+	bot.AddHandler(func(s *discordgo.Session, r *discordgo.Ready) {
+		if bot.ApplicationID == "" {
+			bot.ApplicationID = r.User.ID
+		}
+		// Guild commands follow with the guild create events.
+		if err := bot.SyncGlobalCommands(); err != nil {
+			bot.ErrorHandler(bot, err)
+		}
+	})
+	bot.AddHandler(func(s *discordgo.Session, g *discordgo.GuildCreate) {
+		if err := bot.SyncGuildCommands(g.ID); err != nil {
+			bot.ErrorHandler(bot, err)
+		}
+	})
+	bot.AddHandler(func(s *discordgo.Session, g *discordgo.GuildDelete) {
+		if g.Unavailable {
+			return
+		}
+		// Discord drops our guild commands with us, they are registered again if we come back.
+		bot.commandSync.lock.Lock()
+		delete(bot.commandSync.synced, g.ID)
+		bot.commandSync.lock.Unlock()
+	})
+	bot.Subscribe(TopicSettingsChanged, func(event BusEvent) {
+		if err := bot.SyncGuildCommands(event.(*SettingsChanged).GuildID); err != nil {
+			bot.ErrorHandler(bot, err)
+		}
+	})
+	return bot
+}
+
+// SyncCommands registers the global slash commands and the guild commands of every guild the bot is in.
+func (bot *Bot) SyncCommands() error {
+	if err := bot.SyncGlobalCommands(); err != nil {
+		return err
+	}
+	bot.guilds.lock.Lock()
+	guilds := make([]string, 0, len(bot.guilds.known))
+	for id := range bot.guilds.known {
+		guilds = append(guilds, id)
+	}
+	bot.guilds.lock.Unlock()
+	for _, id := range guilds {
+		if err := bot.SyncGuildCommands(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SyncGlobalCommands registers the slash commands available everywhere, the ones without guild filter.
+func (bot *Bot) SyncGlobalCommands() error {
+	return bot.syncCommands("", discordgo.EndpointAPI+"applications/"+bot.ApplicationID+"/commands")
+}
+
+// SyncGuildCommands registers the slash commands whose guild filter allows guildID there, commands that
+// aren't allowed anymore are deleted.
+func (bot *Bot) SyncGuildCommands(guildID string) error {
+	return bot.syncCommands(guildID, discordgo.EndpointAPI+"applications/"+bot.ApplicationID+"/guilds/"+guildID+"/commands")
+}
+
+// syncCommands overwrites the commands at endpoint, nothing is sent if they didn't change since the last sync.
+func (bot *Bot) syncCommands(guildID, endpoint string) error {
+	if bot.ApplicationID == "" {
+		return ErrNoApplicationID
+	}
+	if bot.commandSync == nil {
+		bot.commandSync = &commandSync{synced: make(map[string]string)}
+	}
+	commands := bot.ApplicationCommands(guildID)
+	body, err := json.Marshal(commands)
+	if err != nil {
+		return err
+	}
+
+	// Locked across the request so concurrent syncs of a guild can't finish out of order.
+	bot.commandSync.lock.Lock()
+	defer bot.commandSync.lock.Unlock()
+	last, ok := bot.commandSync.synced[guildID]
+	if ok && last == string(body) {
+		return nil
+	}
+	// A PUT replaces every command, the ones left out are deleted. The first sync of a guild is sent even when
+	// it's empty, an earlier run of the bot may have registered commands there.
+	if _, err := bot.request("PUT", endpoint, commands, endpoint); err != nil {
+		return err
+	}
+	bot.commandSync.synced[guildID] = string(body)
+	return nil
+}
+
+// ApplicationCommands returns the slash commands registered in a guild, or the global ones and the message commands for "".
+func (bot *Bot) ApplicationCommands(guildID string) []*ApplicationCommand {
+	names := make([]string, 0)
+	for name, cmd := range bot.Commands {
+		if !cmd.Slash || !cmd.Enabled {
+			continue
+		}
+		if guildID == "" && cmd.SlashGuilds != nil || guildID != "" && (cmd.SlashGuilds == nil || !cmd.SlashGuilds(bot, guildID)) {
+			continue
+		}
+		names = append(names, name)
+	}
+	// Sorted so the same commands are always sent the same way.
+	sort.Strings(names)
+	commands := make([]*ApplicationCommand, len(names))
+	for i, name := range names {
+		commands[i] = bot.applicationCommand(bot.Commands[name])
+	}
+	if guildID != "" {
+		return commands
+	}
+	names = names[:0]
+	for name := range bot.messageCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key := "MESSAGE_COMMAND_" + strings.ToUpper(strings.Replace(name, " ", "_", -1))
+		commands = append(commands, &ApplicationCommand{Type: ApplicationCommandMessage, Name: name,
+			NameLocalizations: bot.slashLocalizations(key, name, strings.TrimSpace), DMPermission: true})
+	}
+	return commands
+}
+
+// applicationCommand describes cmd for Discord.
+func (bot *Bot) applicationCommand(cmd *Command) *ApplicationCommand {
+	if cmd.Spec == nil || cmd.Spec.Usage != cmd.UsageString {
+		cmd.compileUsage()
+	}
+	key := "SLASH_" + strings.ToUpper(cmd.Name)
+	command := &ApplicationCommand{Name: strings.ToLower(cmd.Name), Description: truncateDescription(bot.describe(cmd.Description))}
+	command.NameLocalizations = bot.slashLocalizations(key, command.Name, slashName)
+	command.DescriptionLocalizations = bot.slashLocalizations(cmd.Description, command.Description, truncateDescription)
+	command.DefaultMemberPermissions = slashPermissions(cmd)
+	command.DMPermission = !cmd.GuildOnly
+	// Discord wants the required options first, arguments are positional so optionals stay where they are
+	// and the required ones after them become optional, the argument parser still asks for them.
+	optional := false
+	for _, tag := range cmd.Spec.Tags {
+		optionKey := key + "_" + strings.ToUpper(tag.Name)
+		option := &ApplicationCommandOption{Type: OptionString, Name: strings.ToLower(tag.Name),
+			Description: truncateDescription(bot.DefaultLocale.GetDefault(optionKey+"_DESCRIPTION", tag.Name))}
+		option.NameLocalizations = bot.slashLocalizations(optionKey, option.Name, slashName)
+		option.DescriptionLocalizations = bot.slashLocalizations(optionKey+"_DESCRIPTION", option.Description, truncateDescription)
+		// Rest arguments take several values, the user types them like in a message.
+		if typ, ok := optionTypes[tag.Type]; ok && !tag.Rest {
+			option.Type = typ
+		}
+		optional = optional || !tag.Required
+		option.Required = !optional
+		switch option.Type {
+		case OptionInteger, OptionNumber:
+			option.MinValue, option.MaxValue = tag.Min, tag.Max
+		case OptionString:
+			if !tag.Rest && (tag.Type == "str" || tag.Type == "string") {
+				option.MinLength, option.MaxLength = intLimit(tag.Min), intLimit(tag.Max)
+			}
+		}
+		command.Options = append(command.Options, option)
+	}
+	return command
+}
+
+// slashPermissions returns the permissions members need to see cmd in Discord, matching the permissions inhibitor.
+// Owner only commands are hidden from everyone but administrators, permission levels can't be known up front so
+// they are still checked when the command runs. Server admins can change who sees a command in the server settings.
+func slashPermissions(cmd *Command) *string {
+	perms := ""
+	switch {
+	case cmd.OwnerOnly:
+		perms = "0"
+	case cmd.RequiredPermissions != 0:
+		perms = strconv.FormatInt(int64(cmd.RequiredPermissions), 10)
+	default:
+		return nil
+	}
+	return &perms
+}
+
+// Locales of Discord clients, preferred ones first for languages with several.
+var discordLocales = []string{"en-US", "en-GB", "bg", "zh-CN", "zh-TW", "hr", "cs", "da", "nl", "fi", "fr", "de", "el",
+	"hi", "hu", "id", "it", "ja", "ko", "lt", "no", "pl", "pt-BR", "ro", "ru", "es-ES", "es-419", "sv-SE", "th", "tr", "uk", "vi"}
+
+// discordLocale returns the Discord locale of a language name e.g "fr-FR" is "fr" and "pt" is "pt-BR",
+// "" if Discord doesn't have the language.
+func discordLocale(name string) string {
+	base := strings.SplitN(name, "-", 2)[0]
+	match := ""
+	for _, locale := range discordLocales {
+		if strings.EqualFold(locale, name) {
+			return locale
+		}
+		if match == "" && strings.EqualFold(strings.SplitN(locale, "-", 2)[0], base) {
+			match = locale
+		}
+	}
+	return match
+}
+
+// slashNameRegex matches the names Discord accepts for slash commands and their options.
+var slashNameRegex = regexp.MustCompile(`^[-_\p{L}\p{N}]{1,32}$`)
+
+// slashName lowercases a translated name, empty if Discord would reject it.
+func slashName(name string) string {
+	name = strings.ToLower(name)
+	if !slashNameRegex.MatchString(name) {
+		return ""
+	}
+	return name
+}
+
+// slashLocalizations returns the translations of key in every language Discord knows, fit by clean.
+// Translations that are the same as the default text or that clean empties are left out, nil if there are none.
+func (bot *Bot) slashLocalizations(key, text string, clean func(string) string) map[string]string {
+	var localizations map[string]string
+	for name, language := range bot.Languages {
+		locale := discordLocale(name)
+		value := language.Get(key)
+		if locale == "" || value == "" {
+			continue
+		}
+		// An exact match like es-419 wins over a language guessed from it's base like es-MX.
+		if _, ok := localizations[locale]; ok && !strings.EqualFold(locale, name) {
+			continue
+		}
+		if value = clean(value); value == "" || value == text {
+			continue
+		}
+		if localizations == nil {
+			localizations = make(map[string]string)
+		}
+		localizations[locale] = value
+	}
+	return localizations
+}
+
+// describe returns the default locale's text for a description that may be a locale key.
+func (bot *Bot) describe(description string) string {
+	return bot.DefaultLocale.GetDefault(description, description)
+}
+
+// truncateDescription fits s into the 100 characters Discord allows for descriptions.
+func truncateDescription(s string) string {
+	runes := []rune(s)
+	if len(runes) > 100 {
+		return string(runes[:99]) + "…"
+	}
+	if len(runes) == 0 {
+		return "-"
+	}
+	return s
+}
+
+func intLimit(f *float64) *int {
+	if f == nil {
+		return nil
+	}
+	i := int(*f)
+	return &i
+}
@@ -0,0 +1,171 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"testing"
+)
+
+func TestApplicationCommand(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	cmd := NewCommand("Ban", "Moderation", func(ctx *CommandContext) {}).
+		SetDescription("Bans a member.").
+		SetUsage("[user:member] <days:int{0,7}> <reason:string{1,50}> <tags:role...>")
+	command := bot.applicationCommand(cmd)
+	if command.Name != "ban" || command.Description != "Bans a member." {
+		t.Errorf("Expected the command's name and description but got %s: %s", command.Name, command.Description)
+	}
+	expected := []struct {
+		typ      int
+		required bool
+	}{{OptionUser, false}, {OptionInteger, false}, {OptionString, false}, {OptionString, false}}
+	if len(command.Options) != len(expected) {
+		t.Fatalf("Expected %d options but got %d", len(expected), len(command.Options))
+	}
+	for i, option := range command.Options {
+		if option.Type != expected[i].typ || option.Required != expected[i].required {
+			t.Errorf("Expected option %s to be type %d required %v but got %d %v", option.Name, expected[i].typ,
+				expected[i].required, option.Type, option.Required)
+		}
+	}
+	if days := command.Options[1]; days.MinValue == nil || *days.MinValue != 0 || days.MaxValue == nil || *days.MaxValue != 7 {
+		t.Errorf("Expected the range of days to be 0-7")
+	}
+	if reason := command.Options[2]; reason.MaxLength == nil || *reason.MaxLength != 50 {
+		t.Errorf("Expected reason to be at most 50 characters")
+	}
+}
+
+func TestSyncCommands(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	calls := recordREST(bot)
+	beta := NewConfigSchema("beta", "Beta features.").Add("enabled", ConfigBool, "false", "Whether beta commands are on.")
+	bot.AddConfigSchema(beta)
+	run := func(ctx *CommandContext) {}
+	bot.AddCommand(NewCommand("ping", "General", run).SetSlash(true))
+	bot.AddCommand(NewCommand("debug", "Owner", run).SetSlashGuilds(SlashIn("dev")))
+	bot.AddCommand(NewCommand("beta", "General", run).SetSlashGuilds(SlashOptIn(beta, "enabled")))
+	bot.AddCommand(NewCommand("text", "General", run))
+
+	if err := bot.SyncGlobalCommands(); err != ErrNoApplicationID {
+		t.Errorf("Expected syncing without an application ID to fail but got %v", err)
+	}
+	bot.ApplicationID = "app"
+	for _, guild := range []string{"", "dev", "other", ""} {
+		var err error
+		if guild == "" {
+			err = bot.SyncGlobalCommands()
+		} else {
+			err = bot.SyncGuildCommands(guild)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	requests := calls()
+	if len(requests) != 3 || requests[0].Endpoint != "applications/app/commands" || requests[1].Endpoint != "applications/app/guilds/dev/commands" {
+		t.Fatalf("Expected the global and the dev guild commands to be registered once but got %+v", requests)
+	}
+	// The other guild has no commands but it's first sync clears what an earlier run may have left there.
+	if clear := requests[2]; clear.Method != "PUT" || clear.Endpoint != "applications/app/guilds/other/commands" {
+		t.Errorf("Expected the first sync of the other guild to clear it's commands but got %+v", clear)
+	}
+	if names := commandNames(bot.ApplicationCommands("")); names != "ping" {
+		t.Errorf("Expected only ping to be global but got %s", names)
+	}
+
+	// Opting in registers the beta command, opting out deletes it again.
+	if _, err := beta.Set(bot, "other", "enabled", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if names := commandNames(bot.ApplicationCommands("other")); names != "beta" {
+		t.Errorf("Expected the opted in guild to get beta but got %s", names)
+	}
+	bot.SyncGuildCommands("other")
+	beta.Reset(bot, "other", "enabled")
+	bot.SyncGuildCommands("other")
+	requests = calls()[3:]
+	if len(requests) != 2 || requests[0].Endpoint != "applications/app/guilds/other/commands" || requests[1].Endpoint != requests[0].Endpoint {
+		t.Errorf("Expected the opted in guild to be synced twice but got %+v", requests)
+	}
+}
+
+func commandNames(commands []*ApplicationCommand) string {
+	names := ""
+	for i, command := range commands {
+		if i > 0 {
+			names += ","
+		}
+		names += command.Name
+	}
+	return names
+}
+
+func TestSlashLocalizations(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	bot.AddLanguage(NewLanguage("en-GB").Set("SLASH_TEST_KICK", "Kicks a member."))
+	bot.SetDefaultLocale("en-GB")
+	bot.AddLanguage(NewLanguage("fr-FR").
+		Set("SLASH_KICK", "Expulser").
+		Set("SLASH_TEST_KICK", "Expulse un membre.").
+		Set("SLASH_KICK_REASON", "raison").
+		Set("SLASH_KICK_REASON_DESCRIPTION", "Pourquoi ?"))
+	bot.AddLanguage(NewLanguage("pt").Set("SLASH_KICK", "expulsar"))
+	bot.AddLanguage(NewLanguage("tlh").Set("SLASH_KICK", "chIj"))
+	bot.AddLanguage(NewLanguage("de-DE").
+		Set("SLASH_KICK", "Mitglied rauswerfen").
+		Set("SLASH_KICK_REASON", "grund").
+		Set("SLASH_KICK_REASON_DESCRIPTION", "Warum?"))
+	bot.AddLanguage(NewLanguage("it").Set("SLASH_KICK_REASON", strings.Repeat("m", 33)))
+
+	cmd := NewCommand("kick", "Moderation", func(ctx *CommandContext) {}).
+		SetDescription("SLASH_TEST_KICK").
+		SetUsage("[reason:string...]")
+	command := bot.applicationCommand(cmd)
+	if command.Description != "Kicks a member." {
+		t.Errorf("Expected the description key in the default locale but got %q", command.Description)
+	}
+	if len(command.NameLocalizations) != 2 || command.NameLocalizations["fr"] != "expulser" || command.NameLocalizations["pt-BR"] != "expulsar" {
+		t.Errorf("Expected lowercase names for Discord's French and Portuguese without the German one that has a space but got %v", command.NameLocalizations)
+	}
+	if command.DescriptionLocalizations["fr"] != "Expulse un membre." {
+		t.Errorf("Expected a French description but got %v", command.DescriptionLocalizations)
+	}
+	reason := command.Options[0]
+	if reason.NameLocalizations["fr"] != "raison" || reason.DescriptionLocalizations["fr"] != "Pourquoi ?" {
+		t.Errorf("Expected the option to be translated but got %v %v", reason.NameLocalizations, reason.DescriptionLocalizations)
+	}
+	if reason.NameLocalizations["de"] != "grund" || reason.DescriptionLocalizations["de"] != "Warum?" {
+		t.Errorf("Expected a German option but got %v %v", reason.NameLocalizations, reason.DescriptionLocalizations)
+	}
+	if _, ok := reason.NameLocalizations["it"]; ok {
+		t.Errorf("Expected the name longer than 32 characters to be dropped but got %v", reason.NameLocalizations)
+	}
+
+	if locale := discordLocale("es-MX"); locale != "es-ES" {
+		t.Errorf("Expected es-MX to fall back to es-ES but got %s", locale)
+	}
+}
+
+func TestSlashPermissions(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	run := func(ctx *CommandContext) {}
+	for _, test := range []struct {
+		cmd   *Command
+		perms string
+		dm    bool
+	}{
+		{NewCommand("ping", "General", run), "", true},
+		{NewCommand("purge", "Moderation", run).SetRequiredPermissions(discordgo.PermissionManageMessages).SetGuildOnly(true), "8192", false},
+		{NewCommand("eval", "Owner", run).SetOwnerOnly(true), "0", true},
+	} {
+		command := bot.applicationCommand(test.cmd)
+		perms := ""
+		if command.DefaultMemberPermissions != nil {
+			perms = *command.DefaultMemberPermissions
+		}
+		if perms != test.perms || command.DMPermission != test.dm {
+			t.Errorf("Expected %s to need %q with DMs %v but got %q %v", test.cmd.Name, test.perms, test.dm, perms, command.DMPermission)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package sapphire
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// MaxSoundSize is the maximum size of a soundboard sound in bytes.
+const MaxSoundSize = 512 * 1024
+
+// MaxClipSize is the maximum size of a clip loaded from a URL in bytes.
+const MaxClipSize = 5 * 1024 * 1024
+
+// MaxQueuedClips is the maximum amount of clips waiting to be played in a guild.
+const MaxQueuedClips = 10
+
+// clipIdle is how long the bot stays connected after the queue ran empty.
+const clipIdle = 30 * time.Second
+
+// ErrClipQueueFull is returned by PlayClip when MaxQueuedClips are already waiting.
+var ErrClipQueueFull = errors.New("too many clips are queued in this server")
+
+// SoundboardSound is a sound of a guild's soundboard.
+type SoundboardSound struct {
+	SoundID   string          `json:"sound_id"`
+	GuildID   string          `json:"guild_id,omitempty"`
+	Name      string          `json:"name"`
+	Volume    float64         `json:"volume"`
+	EmojiID   string          `json:"emoji_id,omitempty"`
+	EmojiName string          `json:"emoji_name,omitempty"`
+	Available bool            `json:"available"`
+	User      *discordgo.User `json:"user,omitempty"` // Who uploaded it, needs Manage Expressions.
+}
+
+func soundboardEndpoint(guildID string) string {
+	return discordgo.EndpointGuild(guildID) + "/soundboard-sounds"
+}
+
+func (bot *Bot) soundboardRequest(method, endpoint, guildID string, data, v interface{}) error {
+	body, err := bot.Session.RequestWithBucketID(method, endpoint, data, soundboardEndpoint(guildID))
+	if err != nil || v == nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// SoundboardSounds returns the soundboard sounds of a guild.
+func (bot *Bot) SoundboardSounds(guildID string) ([]*SoundboardSound, error) {
+	var list struct {
+		Items []*SoundboardSound `json:"items"`
+	}
+	if err := bot.soundboardRequest("GET", soundboardEndpoint(guildID), guildID, nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// UploadSound adds an mp3 or ogg sound to the guild's soundboard, volume is 0 to 1 and emoji an optional unicode emoji.
+// The bot needs the Manage Expressions permission and the sound must be under MaxSoundSize and 5 seconds.
+func (bot *Bot) UploadSound(guildID, name string, data []byte, volume float64, emoji string) (*SoundboardSound, error) {
+	if len(data) > MaxSoundSize {
+		return nil, fmt.Errorf("The sound is too big, it must be under %s.", English.FormatBytes(MaxSoundSize))
+	}
+	mime := "audio/mpeg"
+	if bytes.HasPrefix(data, []byte("OggS")) {
+		mime = "audio/ogg"
+	}
+	body := map[string]interface{}{
+		"name":   name,
+		"sound":  "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data),
+		"volume": volume,
+	}
+	if emoji != "" {
+		body["emoji_name"] = emoji
+	}
+	sound := &SoundboardSound{}
+	if err := bot.soundboardRequest("POST", soundboardEndpoint(guildID), guildID, body, sound); err != nil {
+		return nil, err
+	}
+	return sound, nil
+}
+
+// EditSound renames a soundboard sound and changes it's volume.
+func (bot *Bot) EditSound(guildID, soundID, name string, volume float64) (*SoundboardSound, error) {
+	sound := &SoundboardSound{}
+	body := map[string]interface{}{"name": name, "volume": volume}
+	if err := bot.soundboardRequest("PATCH", soundboardEndpoint(guildID)+"/"+soundID, guildID, body, sound); err != nil {
+		return nil, err
+	}
+	return sound, nil
+}
+
+// DeleteSound removes a sound from the guild's soundboard.
+func (bot *Bot) DeleteSound(guildID, soundID string) error {
+	return bot.soundboardRequest("DELETE", soundboardEndpoint(guildID)+"/"+soundID, guildID, nil, nil)
+}
+
+// SendSound plays a soundboard sound in the voice channel the bot is connected to, sourceGuildID is the guild the
+// sound belongs to if it's from another server.
+func (bot *Bot) SendSound(channelID, soundID, sourceGuildID string) error {
+	body := map[string]string{"sound_id": soundID}
+	if sourceGuildID != "" {
+		body["source_guild_id"] = sourceGuildID
+	}
+	endpoint := discordgo.EndpointAPI + "channels/" + channelID + "/send-soundboard-sound"
+	_, err := bot.Session.RequestWithBucketID("POST", endpoint, body, endpoint)
+	return err
+}
+
+// Clip is a short audio clip of opus frames, 20ms each, played with bot.PlayClip
+type Clip struct {
+	Frames [][]byte
+}
+
+// Duration is how long the clip plays.
+func (c *Clip) Duration() time.Duration {
+	return time.Duration(len(c.Frames)) * 20 * time.Millisecond
+}
+
+// LoadClip reads a clip in the DCA format, opus frames each prefixed with their length as a little endian int16.
+// Convert audio files with ffmpeg and dca e.g `ffmpeg -i clip.mp3 -f s16le -ar 48000 -ac 2 pipe:1 | dca > clip.dca`
+func LoadClip(r io.Reader) (*Clip, error) {
+	clip := &Clip{}
+	for {
+		var length int16
+		if err := binary.Read(r, binary.LittleEndian, &length); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if length <= 0 {
+			return nil, fmt.Errorf("invalid opus frame length %d", length)
+		}
+		frame := make([]byte, length)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return nil, err
+		}
+		clip.Frames = append(clip.Frames, frame)
+	}
+	if len(clip.Frames) == 0 {
+		return nil, errors.New("the clip is empty")
+	}
+	return clip, nil
+}
+
+// LoadClipFile reads a DCA clip from a file, see LoadClip
+func LoadClipFile(path string) (*Clip, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LoadClip(file)
+}
+
+// LoadClipURL downloads a DCA clip of up to MaxClipSize bytes, see LoadClip
+func LoadClipURL(url string) (*Clip, error) {
+	res, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading the clip failed with status %d", res.StatusCode)
+	}
+	// Read one byte more than allowed to tell if it's too big.
+	data, err := ioutil.ReadAll(io.LimitReader(res.Body, MaxClipSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxClipSize {
+		return nil, fmt.Errorf("The clip is too big, it must be under %s.", English.FormatBytes(MaxClipSize))
+	}
+	return LoadClip(bytes.NewReader(data))
+}
+
+type queuedClip struct {
+	channelID string
+	clip      *Clip
+}
+
+type clipTracker struct {
+	// guild ID -> clips waiting to be played, a guild has a player goroutine while it's in here.
+	queues map[string][]queuedClip
+	lock   sync.Mutex
+}
+
+// PlayClip queues a clip to be played in a voice channel, clips of a guild are played one after another.
+// The bot joins the channel, moving from another one of the guild if needed, and leaves after being idle for a bit.
+func (bot *Bot) PlayClip(guildID, channelID string, clip *Clip) error {
+	bot.clips.lock.Lock()
+	defer bot.clips.lock.Unlock()
+	queue, playing := bot.clips.queues[guildID]
+	if len(queue) >= MaxQueuedClips {
+		return ErrClipQueueFull
+	}
+	bot.clips.queues[guildID] = append(queue, queuedClip{channelID: channelID, clip: clip})
+	if !playing {
+		go bot.playClips(guildID)
+	}
+	return nil
+}
+
+// QueuedClips returns how many clips are waiting to be played in a guild.
+func (bot *Bot) QueuedClips(guildID string) int {
+	bot.clips.lock.Lock()
+	defer bot.clips.lock.Unlock()
+	return len(bot.clips.queues[guildID])
+}
+
+// SkipClips empties the guild's queue, the clip being played is finished.
+func (bot *Bot) SkipClips(guildID string) {
+	bot.clips.lock.Lock()
+	defer bot.clips.lock.Unlock()
+	if _, ok := bot.clips.queues[guildID]; ok {
+		bot.clips.queues[guildID] = nil
+	}
+}
+
+// nextClip pops the next clip of a guild, waiting up to clipIdle for one. Returns false once the player should stop.
+func (bot *Bot) nextClip(guildID string) (queuedClip, bool) {
+	deadline := time.Now().Add(clipIdle)
+	for {
+		bot.clips.lock.Lock()
+		if queue := bot.clips.queues[guildID]; len(queue) > 0 {
+			bot.clips.queues[guildID] = queue[1:]
+			bot.clips.lock.Unlock()
+			return queue[0], true
+		}
+		if time.Now().After(deadline) {
+			delete(bot.clips.queues, guildID)
+			bot.clips.lock.Unlock()
+			return queuedClip{}, false
+		}
+		bot.clips.lock.Unlock()
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// playClips plays the queue of a guild until it stayed empty for clipIdle.
+func (bot *Bot) playClips(guildID string) {
+	var vc *discordgo.VoiceConnection
+	defer func() {
+		if err := recover(); err != nil {
+			bot.ErrorHandler(bot, err)
+			bot.clips.lock.Lock()
+			delete(bot.clips.queues, guildID)
+			bot.clips.lock.Unlock()
+		}
+		if vc != nil {
+			vc.Disconnect()
+		}
+	}()
+	for {
+		next, ok := bot.nextClip(guildID)
+		if !ok {
+			return
+		}
+		if vc == nil || vc.ChannelID != next.channelID {
+			var err error
+			// Joining while connected in the guild moves the connection to the new channel.
+			if vc, err = bot.Session.ChannelVoiceJoin(guildID, next.channelID, false, true); err != nil {
+				bot.ErrorHandler(bot, err)
+				vc = nil
+				continue
+			}
+		}
+		vc.Speaking(true)
+		for _, frame := range next.clip.Frames {
+			vc.OpusSend <- frame
+		}
+		vc.Speaking(false)
+	}
+}
@@ -0,0 +1,24 @@
+package sapphire
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestLoadClip(t *testing.T) {
+	data := []byte{3, 0, 'a', 'b', 'c', 1, 0, 'd'}
+	clip, err := LoadClip(bytes.NewReader(data))
+	if err != nil || len(clip.Frames) != 2 || string(clip.Frames[0]) != "abc" || string(clip.Frames[1]) != "d" {
+		t.Errorf("LoadClip = %v, %v", clip, err)
+	}
+	if clip.Duration() != 40*time.Millisecond {
+		t.Errorf("Duration = %v", clip.Duration())
+	}
+	if _, err := LoadClip(bytes.NewReader(data[:4])); err == nil {
+		t.Error("expected an error for a cut off frame")
+	}
+	if _, err := LoadClip(bytes.NewReader(nil)); err == nil {
+		t.Error("expected an error for an empty clip")
+	}
+}
@@ -0,0 +1,202 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"time"
+)
+
+// Gateway events of stage instances and scheduled events, the bundled discordgo doesn't know about them yet.
+const (
+	StageInstanceCreate       = "STAGE_INSTANCE_CREATE"
+	StageInstanceUpdate       = "STAGE_INSTANCE_UPDATE"
+	StageInstanceDelete       = "STAGE_INSTANCE_DELETE"
+	GuildScheduledEventCreate = "GUILD_SCHEDULED_EVENT_CREATE"
+	GuildScheduledEventUpdate = "GUILD_SCHEDULED_EVENT_UPDATE"
+	GuildScheduledEventDelete = "GUILD_SCHEDULED_EVENT_DELETE"
+)
+
+// ChannelTypeGuildStageVoice is the channel type of stage channels.
+const ChannelTypeGuildStageVoice discordgo.ChannelType = 13
+
+// IntentsGuildScheduledEvents is the gateway intent needed to receive the scheduled event events.
+const IntentsGuildScheduledEvents discordgo.Intent = 1 << 16
+
+// Topics of the stage bus events.
+const (
+	TopicStage          = "stage.instance"
+	TopicScheduledEvent = "scheduled.event"
+)
+
+// StageInstance is a live stage, it exists while the stage is running.
+type StageInstance struct {
+	ID                    string `json:"id,omitempty"`
+	GuildID               string `json:"guild_id,omitempty"`
+	ChannelID             string `json:"channel_id"`
+	Topic                 string `json:"topic"`
+	PrivacyLevel          int    `json:"privacy_level,omitempty"` // 2 for guild only.
+	GuildScheduledEventID string `json:"guild_scheduled_event_id,omitempty"`
+}
+
+// ScheduledEvent is an event of a guild shown in it's events list, e.g an upcoming stage.
+type ScheduledEvent struct {
+	ID                 string     `json:"id,omitempty"`
+	GuildID            string     `json:"guild_id,omitempty"`
+	ChannelID          string     `json:"channel_id,omitempty"`
+	CreatorID          string     `json:"creator_id,omitempty"`
+	Name               string     `json:"name"`
+	Description        string     `json:"description,omitempty"`
+	ScheduledStartTime time.Time  `json:"scheduled_start_time"`
+	ScheduledEndTime   *time.Time `json:"scheduled_end_time,omitempty"`
+	PrivacyLevel       int        `json:"privacy_level"` // 2 for guild only.
+	Status             int        `json:"status,omitempty"`
+	EntityType         int        `json:"entity_type"` // 1 for stages.
+	UserCount          int        `json:"user_count,omitempty"`
+}
+
+// URL is the link to the event, posting it shows the event's card.
+func (e *ScheduledEvent) URL() string {
+	return fmt.Sprintf("https://discord.com/events/%s/%s", e.GuildID, e.ID)
+}
+
+// StageChanged is published on the bus when a stage starts, changes it's topic or ends.
+type StageChanged struct {
+	Action   string // "create", "update" or "delete"
+	Instance *StageInstance
+}
+
+// Topic implements BusEvent
+func (e *StageChanged) Topic() string { return TopicStage }
+
+// ScheduledEventChanged is published on the bus when a scheduled event is created, updated e.g started, or deleted.
+// It needs IntentsGuildScheduledEvents when intents are set.
+type ScheduledEventChanged struct {
+	Action string // "create", "update" or "delete"
+	Event  *ScheduledEvent
+}
+
+// Topic implements BusEvent
+func (e *ScheduledEventChanged) Topic() string { return TopicScheduledEvent }
+
+func (bot *Bot) stageRequest(method, endpoint, bucket string, data, v interface{}) error {
+	body, err := bot.Session.RequestWithBucketID(method, endpoint, data, bucket)
+	if err != nil || v == nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// StartStage starts a stage in a stage channel, notify pings everyone following the server's stages.
+// The bot needs Manage Channels, Mute Members and Move Members in the channel.
+func (bot *Bot) StartStage(channelID, topic string, notify bool) (*StageInstance, error) {
+	data := struct {
+		*StageInstance
+		SendStartNotification bool `json:"send_start_notification"`
+	}{&StageInstance{ChannelID: channelID, Topic: topic, PrivacyLevel: 2}, notify}
+	instance := &StageInstance{}
+	endpoint := discordgo.EndpointAPI + "stage-instances"
+	if err := bot.stageRequest("POST", endpoint, endpoint, data, instance); err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+// Stage returns the running stage of a channel.
+func (bot *Bot) Stage(channelID string) (*StageInstance, error) {
+	instance := &StageInstance{}
+	endpoint := discordgo.EndpointAPI + "stage-instances/" + channelID
+	if err := bot.stageRequest("GET", endpoint, discordgo.EndpointAPI+"stage-instances", nil, instance); err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+// SetStageTopic changes the topic of a running stage.
+func (bot *Bot) SetStageTopic(channelID, topic string) (*StageInstance, error) {
+	instance := &StageInstance{}
+	endpoint := discordgo.EndpointAPI + "stage-instances/" + channelID
+	if err := bot.stageRequest("PATCH", endpoint, discordgo.EndpointAPI+"stage-instances", map[string]string{"topic": topic}, instance); err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+// EndStage ends the running stage of a channel, everyone stays connected to the channel.
+func (bot *Bot) EndStage(channelID string) error {
+	return bot.stageRequest("DELETE", discordgo.EndpointAPI+"stage-instances/"+channelID, discordgo.EndpointAPI+"stage-instances", nil, nil)
+}
+
+// SetSpeaker makes a member in a stage channel a speaker or moves them back to the audience.
+// Pass the bot's own ID to make the bot speak.
+func (bot *Bot) SetSpeaker(guildID, channelID, userID string, speaker bool) error {
+	user := userID
+	if userID == bot.Session.State.User.ID {
+		user = "@me"
+	}
+	endpoint := discordgo.EndpointGuild(guildID) + "/voice-states/" + user
+	data := map[string]interface{}{"channel_id": channelID, "suppress": !speaker}
+	return bot.stageRequest("PATCH", endpoint, discordgo.EndpointGuild(guildID)+"/voice-states", data, nil)
+}
+
+// MoveSpeaker moves a member connected to voice into a stage channel and makes them a speaker.
+func (bot *Bot) MoveSpeaker(guildID, channelID, userID string) error {
+	if err := bot.Session.GuildMemberMove(guildID, userID, &channelID); err != nil {
+		return err
+	}
+	return bot.SetSpeaker(guildID, channelID, userID, true)
+}
+
+// ScheduleStage adds a stage to the server's events list, it shows up for members who can see the channel.
+// The bot needs the Manage Events permission in the guild.
+func (bot *Bot) ScheduleStage(guildID, channelID, name, description string, start time.Time) (*ScheduledEvent, error) {
+	data := &ScheduledEvent{ChannelID: channelID, Name: name, Description: description, ScheduledStartTime: start.UTC(), PrivacyLevel: 2, EntityType: 1}
+	event := &ScheduledEvent{}
+	endpoint := discordgo.EndpointGuild(guildID) + "/scheduled-events"
+	if err := bot.stageRequest("POST", endpoint, endpoint, data, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// AnnounceStage posts an announcement of a scheduled stage in a channel, with the start time in every reader's
+// timezone and the event's link. In an announcement channel crosspost it with bot.Crosspost
+func (bot *Bot) AnnounceStage(channelID string, event *ScheduledEvent) (*discordgo.Message, error) {
+	locale := bot.LocaleFor(event.GuildID, channelID)
+	description := ""
+	if event.Description != "" {
+		description = "\n" + event.Description
+	}
+	return bot.SendLocale(channelID, locale, "STAGE_ANNOUNCEMENT", event.Name, event.ScheduledStartTime.Unix(), event.ChannelID, description, event.URL())
+}
+
+// stageListener publishes the stage and scheduled event gateway events on the bus.
+func stageListener(bot *Bot) func(s *discordgo.Session, e *discordgo.Event) {
+	return bot.processEvent("stage", func(s *discordgo.Session, e *discordgo.Event) error {
+		var event BusEvent
+		switch e.Type {
+		case StageInstanceCreate, StageInstanceUpdate, StageInstanceDelete:
+			instance := &StageInstance{}
+			if err := json.Unmarshal(e.RawData, instance); err != nil {
+				return err
+			}
+			event = &StageChanged{Action: eventAction(e.Type), Instance: instance}
+		case GuildScheduledEventCreate, GuildScheduledEventUpdate, GuildScheduledEventDelete:
+			scheduled := &ScheduledEvent{}
+			if err := json.Unmarshal(e.RawData, scheduled); err != nil {
+				return err
+			}
+			event = &ScheduledEventChanged{Action: eventAction(e.Type), Event: scheduled}
+		default:
+			return nil
+		}
+		bot.Publish(event)
+		return nil
+	})
+}
+
+// eventAction returns "create", "update" or "delete" for an event type like STAGE_INSTANCE_CREATE
+func eventAction(typ string) string {
+	return strings.ToLower(typ[strings.LastIndexByte(typ, '_')+1:])
+}
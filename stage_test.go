@@ -0,0 +1,27 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"github.com/bwmarrin/discordgo"
+	"testing"
+	"time"
+)
+
+func TestStageEvents(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	events := make(chan *StageChanged, 1)
+	bot.Subscribe(TopicStage, func(event BusEvent) {
+		events <- event.(*StageChanged)
+	})
+	if err := bot.DispatchEvent(StageInstanceCreate, json.RawMessage(`{"id":"1","guild_id":"2","channel_id":"3","topic":"Town hall"}`)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-events:
+		if event.Action != "create" || event.Instance.Topic != "Town hall" {
+			t.Errorf("got %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("the stage event wasn't published")
+	}
+}
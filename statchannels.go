@@ -0,0 +1,293 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// statsKey is the guild settings key stat channels are stored under.
+const statsKey = "stats"
+
+// statsDebounce is how long after a member joins or leaves the stats are updated, joins often come in waves.
+const statsDebounce = 30 * time.Second
+
+// statsRenameInterval is the least time between two renames of a channel, Discord allows 2 per 10 minutes.
+const statsRenameInterval = 5 * time.Minute
+
+// StatNames are the placeholders stat channel templates can use, e.g "Members: {members}"
+var StatNames = []string{"members", "humans", "bots", "online", "channels", "roles", "boosts"}
+
+// GuildStats are the stat channels and dashboard of a guild.
+type GuildStats struct {
+	Channels         map[string]string `json:"channels"` // channel ID -> name template
+	DashboardChannel string            `json:"dashboard_channel"`
+	DashboardMessage string            `json:"dashboard_message"`
+}
+
+type statsTracker struct {
+	// guild ID -> pending update
+	tasks map[string]*ScheduledTask
+	// channel ID -> last rename
+	renamed map[string]time.Time
+	lock    sync.Mutex
+}
+
+// EnableStatChannels loads the statchannels command and keeps stat channels up to date. A stat channel is a voice channel
+// nobody can join whose name shows a live stat, e.g "Members: 1,234", a dashboard is a message showing all stats.
+// Updates happen a little after members join or leave and channels are renamed at most every 5 minutes,
+// counting humans and bots needs the guild members intent and online members the presences intent.
+func (bot *Bot) EnableStatChannels() *Bot {
+	if bot.statChannels != nil {
+		return bot
+	}
+	bot.statChannels = &statsTracker{tasks: make(map[string]*ScheduledTask), renamed: make(map[string]time.Time)}
+	bot.AddHandler(func(s *discordgo.Session, m *discordgo.GuildMemberAdd) {
+		bot.QueueStatsUpdate(m.GuildID, statsDebounce)
+	})
+	bot.AddHandler(func(s *discordgo.Session, m *discordgo.GuildMemberRemove) {
+		bot.QueueStatsUpdate(m.GuildID, statsDebounce)
+	})
+	// Catch up with what happened while we were offline.
+	bot.AddHandler(func(s *discordgo.Session, g *discordgo.GuildCreate) {
+		bot.QueueStatsUpdate(g.ID, statsDebounce)
+	})
+	bot.AddCommand(NewCommand("statchannels", "Moderation", statsCommand).
+		SetDescription("Manages channels showing server stats, placeholders are "+strings.Join(statPlaceholders(), ", ")+".").
+		SetUsage("<action:string> [args:string...]").
+		AddAliases("statchannel", "serverstats").
+		SetRequiredPermissions(discordgo.PermissionManageChannels).
+		SetGuildOnly(true))
+	return bot
+}
+
+func statPlaceholders() []string {
+	placeholders := make([]string, len(StatNames))
+	for i, name := range StatNames {
+		placeholders[i] = "{" + name + "}"
+	}
+	return placeholders
+}
+
+// CountStats counts the stats of a guild.
+func (bot *Bot) CountStats(guild *discordgo.Guild) map[string]int {
+	bot.Session.State.RLock()
+	defer bot.Session.State.RUnlock()
+	stats := map[string]int{
+		"members":  guild.MemberCount,
+		"channels": len(guild.Channels),
+		"roles":    len(guild.Roles) - 1, // Without @everyone
+		"boosts":   guild.PremiumSubscriptionCount,
+	}
+	for _, member := range guild.Members {
+		if member.User.Bot {
+			stats["bots"]++
+		}
+	}
+	stats["humans"] = stats["members"] - stats["bots"]
+	for _, presence := range guild.Presences {
+		if presence.Status != discordgo.StatusOffline && presence.Status != "" {
+			stats["online"]++
+		}
+	}
+	return stats
+}
+
+// RenderStats fills the placeholders of a stat template.
+func RenderStats(template string, stats map[string]int) string {
+	return RenderStatsLocale(English, template, stats)
+}
+
+// RenderStatsLocale fills the placeholders of a stat template, numbers are formatted for the locale.
+func RenderStatsLocale(locale *Language, template string, stats map[string]int) string {
+	for _, name := range StatNames {
+		template = strings.Replace(template, "{"+name+"}", locale.FormatCount(int64(stats[name])), -1)
+	}
+	return template
+}
+
+// GuildStats returns the stat channels and dashboard of a guild.
+func (bot *Bot) GuildStats(guildID string) (*GuildStats, error) {
+	stats := &GuildStats{}
+	if _, err := GetJSON(bot.Settings, guildID, statsKey, stats); err != nil {
+		return nil, err
+	}
+	if stats.Channels == nil {
+		stats.Channels = make(map[string]string)
+	}
+	return stats, nil
+}
+
+// SetGuildStats saves the stat channels and dashboard of a guild and updates them.
+func (bot *Bot) SetGuildStats(guildID string, stats *GuildStats) error {
+	if err := SetJSON(bot.Settings, guildID, statsKey, stats); err != nil {
+		return err
+	}
+	bot.QueueStatsUpdate(guildID, 0)
+	return nil
+}
+
+// QueueStatsUpdate updates the guild's stats after delay, replacing an update that was already queued.
+func (bot *Bot) QueueStatsUpdate(guildID string, delay time.Duration) {
+	bot.statChannels.lock.Lock()
+	defer bot.statChannels.lock.Unlock()
+	bot.statChannels.tasks[guildID].Cancel()
+	bot.statChannels.tasks[guildID] = bot.Scheduler.After(delay, func() {
+		bot.statChannels.lock.Lock()
+		delete(bot.statChannels.tasks, guildID)
+		bot.statChannels.lock.Unlock()
+		bot.updateStats(guildID)
+	})
+}
+
+func (bot *Bot) updateStats(guildID string) {
+	config, err := bot.GuildStats(guildID)
+	if err != nil {
+		bot.ErrorHandler(bot, err)
+		return
+	}
+	if len(config.Channels) == 0 && config.DashboardMessage == "" {
+		return
+	}
+	guild, err := bot.Session.State.Guild(guildID)
+	if err != nil {
+		return
+	}
+	stats := bot.CountStats(guild)
+	locale := bot.LocaleFor(guildID, "")
+
+	var retry time.Duration
+	for channelID, template := range config.Channels {
+		channel, err := bot.Session.State.Channel(channelID)
+		if err != nil {
+			continue
+		}
+		name := RenderStatsLocale(locale, template, stats)
+		if channel.Name == name {
+			continue
+		}
+		bot.statChannels.lock.Lock()
+		wait := statsRenameInterval - time.Since(bot.statChannels.renamed[channelID])
+		if wait <= 0 {
+			bot.statChannels.renamed[channelID] = time.Now()
+		}
+		bot.statChannels.lock.Unlock()
+		if wait > 0 {
+			// Renamed too recently, come back when it's allowed again.
+			if retry == 0 || wait < retry {
+				retry = wait
+			}
+			continue
+		}
+		if !bot.SpendAPI(guildID, 1) {
+			break
+		}
+		if _, err := bot.Session.ChannelEdit(channelID, name); err != nil {
+			bot.ErrorHandler(bot, err)
+		}
+	}
+
+	if config.DashboardMessage != "" && bot.SpendAPI(guildID, 1) {
+		_, err := bot.Session.ChannelMessageEditEmbed(config.DashboardChannel, config.DashboardMessage, bot.statsEmbed(guild, stats))
+		if err != nil {
+			bot.ErrorHandler(bot, err)
+		}
+	}
+	if retry > 0 {
+		bot.QueueStatsUpdate(guildID, retry)
+	}
+}
+
+// statsEmbed builds the dashboard embed.
+func (bot *Bot) statsEmbed(guild *discordgo.Guild, stats map[string]int) *discordgo.MessageEmbed {
+	locale := bot.LocaleFor(guild.ID, "")
+	embed := NewEmbed().
+		SetTitle(locale.Get("STATS_DASHBOARD", guild.Name)).
+		SetColor(bot.Color).
+		SetFooter(locale.Get("STATS_UPDATED"))
+	for _, name := range StatNames {
+		embed.AddInlineField(locale.Get("STATS_"+strings.ToUpper(name)), locale.FormatCount(int64(stats[name])))
+	}
+	embed.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	return embed.Build()
+}
+
+func statsCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+	config, err := bot.GuildStats(ctx.Guild.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	switch strings.ToLower(ctx.Arg(0).AsString()) {
+	case "add", "create":
+		template := ctx.ArgString(1)
+		if template == "" {
+			ctx.ReplyLocale("COMMAND_STATS_USAGE", ctx.Prefix)
+			return
+		}
+		if !ctx.BotHasPermissions(discordgo.PermissionManageChannels) {
+			ctx.ReplyLocale("COMMAND_STATS_BOT_NO_PERMISSION")
+			return
+		}
+		channel, err := ctx.Session.GuildChannelCreateComplex(ctx.Guild.ID, discordgo.GuildChannelCreateData{
+			Name: RenderStatsLocale(ctx.Locale, template, bot.CountStats(ctx.Guild)),
+			Type: discordgo.ChannelTypeGuildVoice,
+			PermissionOverwrites: []*discordgo.PermissionOverwrite{
+				{ID: ctx.Guild.ID, Type: "role", Deny: discordgo.PermissionVoiceConnect},
+				{ID: ctx.Session.State.User.ID, Type: "member", Allow: discordgo.PermissionVoiceConnect | discordgo.PermissionManageChannels},
+			},
+		})
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		config.Channels[channel.ID] = template
+		if err := bot.SetGuildStats(ctx.Guild.ID, config); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_STATS_ADDED", channel.Name)
+	case "remove", "delete":
+		id := strings.TrimSuffix(strings.TrimPrefix(ctx.ArgString(1), "<#"), ">")
+		if _, ok := config.Channels[id]; !ok {
+			ctx.ReplyLocale("COMMAND_STATS_NOT_STAT")
+			return
+		}
+		delete(config.Channels, id)
+		if err := bot.SetGuildStats(ctx.Guild.ID, config); err != nil {
+			ctx.Error(err)
+			return
+		}
+		// The channel is kept, it might have been renamed into something else by now.
+		ctx.ReplyLocale("COMMAND_STATS_REMOVED")
+	case "list":
+		if len(config.Channels) == 0 {
+			ctx.ReplyLocale("COMMAND_STATS_NONE", ctx.Prefix)
+			return
+		}
+		lines := make([]string, 0, len(config.Channels))
+		for id, template := range config.Channels {
+			lines = append(lines, fmt.Sprintf("<#%s> `%s` (%s)", id, template, id))
+		}
+		sort.Strings(lines)
+		ctx.Reply(strings.Join(lines, "\n"))
+	case "dashboard":
+		msg, err := ctx.ReplyEmbedNoEdit(bot.statsEmbed(ctx.Guild, bot.CountStats(ctx.Guild)))
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		config.DashboardChannel = ctx.Channel.ID
+		config.DashboardMessage = msg.ID
+		if err := SetJSON(bot.Settings, ctx.Guild.ID, statsKey, config); err != nil {
+			ctx.Error(err)
+		}
+	default:
+		ctx.ReplyLocale("COMMAND_STATS_USAGE", ctx.Prefix)
+	}
+}
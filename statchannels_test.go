@@ -0,0 +1,12 @@
+package sapphire
+
+import (
+	"testing"
+)
+
+func TestRenderStats(t *testing.T) {
+	name := RenderStats("Members: {members} | Bots: {bots} {unknown}", map[string]int{"members": 12345, "bots": 3})
+	if name != "Members: 12,345 | Bots: 3 {unknown}" {
+		t.Errorf("Unexpected stats %q", name)
+	}
+}
@@ -0,0 +1,200 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stickyKeyPrefix is the guild settings key prefix sticky messages are stored under, followed by the channel ID.
+const stickyKeyPrefix = "sticky."
+
+// stickyMinInterval is the least time between two reposts in a channel, reposting is a delete and a send
+// so busy channels would otherwise hit the rate-limits.
+const stickyMinInterval = 3 * time.Second
+
+// Sticky is a message kept at the bottom of a channel.
+type Sticky struct {
+	GuildID   string `json:"guild_id"`
+	ChannelID string `json:"channel_id"`
+	Content   string `json:"content"`
+	Messages  int    `json:"messages"`   // Repost after this many new messages. (default: 5)
+	Delay     int    `json:"delay"`      // Or repost after this many seconds of silence after a new message. (default: 15)
+	MessageID string `json:"message_id"` // The currently posted sticky.
+}
+
+type stickyState struct {
+	sticky   *Sticky // nil if the channel has no sticky.
+	count    int
+	lastPost time.Time
+	task     *ScheduledTask
+}
+
+type stickyTracker struct {
+	channels map[string]*stickyState
+	lock     sync.Mutex
+}
+
+// EnableStickies loads the sticky command and the monitor that keeps sticky messages at the bottom of their channels.
+func (bot *Bot) EnableStickies() *Bot {
+	if bot.stickies != nil {
+		return bot
+	}
+	bot.stickies = &stickyTracker{channels: make(map[string]*stickyState)}
+	bot.AddMonitor(NewMonitor("sticky", stickyMonitor).SetGuildOnly(true).AllowBots())
+	bot.AddCommand(NewCommand("sticky", "Moderation", stickyCommand).
+		SetDescription("Keeps a message at the bottom of this channel, use --messages=N and --delay=seconds to pick how often it's reposted.").
+		SetUsage("<action:string> [content:string...]").
+		SetRequiredPermissions(discordgo.PermissionManageMessages).
+		SetGuildOnly(true))
+	return bot
+}
+
+// SetSticky sets the sticky message of a channel and posts it, messages and delay below 1 use the defaults.
+func (bot *Bot) SetSticky(guildID, channelID, content string, messages, delay int) error {
+	if messages < 1 {
+		messages = 5
+	}
+	if delay < 1 {
+		delay = 15
+	}
+	old := bot.Sticky(guildID, channelID)
+	sticky := &Sticky{GuildID: guildID, ChannelID: channelID, Content: content, Messages: messages, Delay: delay}
+	if old != nil {
+		sticky.MessageID = old.MessageID
+	}
+	bot.stickies.lock.Lock()
+	state := bot.stickyState(channelID)
+	state.sticky = sticky
+	state.task.Cancel()
+	bot.stickies.lock.Unlock()
+	return bot.repostSticky(channelID)
+}
+
+// RemoveSticky removes the sticky message of a channel and deletes the posted message.
+func (bot *Bot) RemoveSticky(guildID, channelID string) error {
+	bot.stickies.lock.Lock()
+	state := bot.stickyState(channelID)
+	old := state.sticky
+	state.sticky = nil
+	state.task.Cancel()
+	bot.stickies.lock.Unlock()
+	if old != nil && old.MessageID != "" {
+		bot.Session.ChannelMessageDelete(channelID, old.MessageID)
+	}
+	return bot.Settings.Delete(guildID, stickyKeyPrefix+channelID)
+}
+
+// Sticky returns the sticky message of a channel, nil if there is none.
+func (bot *Bot) Sticky(guildID, channelID string) *Sticky {
+	bot.stickies.lock.Lock()
+	defer bot.stickies.lock.Unlock()
+	state, ok := bot.stickies.channels[channelID]
+	if !ok {
+		state = bot.stickyState(channelID)
+		sticky := &Sticky{}
+		if ok, err := GetJSON(bot.Settings, guildID, stickyKeyPrefix+channelID, sticky); err == nil && ok {
+			state.sticky = sticky
+		}
+	}
+	return state.sticky
+}
+
+// stickyState returns the state of the channel, the caller must hold the lock.
+func (bot *Bot) stickyState(channelID string) *stickyState {
+	state, ok := bot.stickies.channels[channelID]
+	if !ok {
+		state = &stickyState{}
+		bot.stickies.channels[channelID] = state
+	}
+	return state
+}
+
+// repostSticky deletes the old sticky message and sends it again at the bottom.
+func (bot *Bot) repostSticky(channelID string) error {
+	bot.stickies.lock.Lock()
+	state := bot.stickyState(channelID)
+	sticky := state.sticky
+	if sticky == nil {
+		bot.stickies.lock.Unlock()
+		return nil
+	}
+	old := sticky.MessageID
+	state.count = 0
+	state.lastPost = time.Now()
+	bot.stickies.lock.Unlock()
+	if !bot.SpendAPI(sticky.GuildID, 2) {
+		return nil
+	}
+
+	if old != "" {
+		bot.Session.ChannelMessageDelete(channelID, old)
+	}
+	msg, err := bot.Session.ChannelMessageSend(channelID, sticky.Content)
+	if err != nil {
+		return err
+	}
+	bot.stickies.lock.Lock()
+	sticky.MessageID = msg.ID
+	bot.stickies.lock.Unlock()
+	return SetJSON(bot.Settings, sticky.GuildID, stickyKeyPrefix+channelID, sticky)
+}
+
+func stickyMonitor(bot *Bot, ctx *MonitorContext) {
+	sticky := bot.Sticky(ctx.Guild.ID, ctx.Channel.ID)
+	if sticky == nil {
+		return
+	}
+
+	bot.stickies.lock.Lock()
+	defer bot.stickies.lock.Unlock()
+	state := bot.stickyState(ctx.Channel.ID)
+	state.count++
+	state.task.Cancel()
+
+	// Enough messages piled up, repost right away unless we just did then wait out the interval.
+	// Otherwise wait for the channel to calm down, every new message pushes the repost back.
+	delay := time.Duration(sticky.Delay) * time.Second
+	if state.count >= sticky.Messages {
+		delay = stickyMinInterval - time.Since(state.lastPost)
+	}
+	channelID := ctx.Channel.ID
+	state.task = bot.Scheduler.After(delay, func() {
+		if err := bot.repostSticky(channelID); err != nil {
+			bot.ErrorHandler(bot, err)
+		}
+	})
+}
+
+func stickyCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+	switch strings.ToLower(ctx.Arg(0).AsString()) {
+	case "set":
+		content := ctx.ArgString(1)
+		if content == "" {
+			ctx.ReplyLocale("COMMAND_STICKY_USAGE", ctx.Prefix)
+			return
+		}
+		messages, _ := strconv.Atoi(ctx.Flag("messages"))
+		delay, _ := strconv.Atoi(ctx.Flag("delay"))
+		if err := bot.SetSticky(ctx.Guild.ID, ctx.Channel.ID, Escape(content), messages, delay); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_STICKY_SET")
+	case "remove", "delete":
+		if bot.Sticky(ctx.Guild.ID, ctx.Channel.ID) == nil {
+			ctx.ReplyLocale("COMMAND_STICKY_NONE")
+			return
+		}
+		if err := bot.RemoveSticky(ctx.Guild.ID, ctx.Channel.ID); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_STICKY_REMOVED")
+	default:
+		ctx.ReplyLocale("COMMAND_STICKY_USAGE", ctx.Prefix)
+	}
+}
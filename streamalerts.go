@@ -0,0 +1,507 @@
+package sapphire
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// streamAlertsKey is the bot wide settings key stream alerts are stored under.
+const streamAlertsKey = "streamalerts"
+
+// streamAlertsAnnouncedKey is the bot wide settings key of the last announced stream of every channel.
+const streamAlertsAnnouncedKey = "streamalerts.announced"
+
+// StreamAlertInterval is how often polling stream providers are asked who's live.
+var StreamAlertInterval = 5 * time.Minute
+
+// StreamAlertClient is the http client used by the built-in polling providers.
+var StreamAlertClient = &http.Client{Timeout: 10 * time.Second}
+
+// LiveStream is a stream that went live on a platform.
+type LiveStream struct {
+	Provider  string // The provider's name e.g "twitch"
+	Channel   string // The channel on the platform, as subscribed to e.g the Twitch login or YouTube channel ID.
+	ID        string // The stream's ID, a stream is only announced once.
+	Name      string // The channel's display name.
+	Title     string
+	Game      string
+	URL       string
+	Thumbnail string
+	StartedAt time.Time
+}
+
+// StreamProvider finds live streams on a platform. Push providers, like Twitch EventSub, call bot.AnnounceStream
+// themselves and return nil from Poll.
+type StreamProvider interface {
+	Name() string
+	// Poll returns which of the channels are live, it's called every StreamAlertInterval with every subscribed channel.
+	Poll(channels []string) ([]*LiveStream, error)
+}
+
+// StreamAlert posts a message in a Discord channel when a channel on a platform goes live.
+type StreamAlert struct {
+	Provider  string `json:"provider"`
+	Channel   string `json:"channel"` // The channel on the platform, matched case insensitively.
+	GuildID   string `json:"guild_id"`
+	ChannelID string `json:"channel_id"`
+	RoleID    string `json:"role_id,omitempty"` // Pinged when the stream goes live.
+	// Message posted with the embed, {name}, {title}, {game}, {url} and {role} are replaced. (default: STREAM_ALERT locale key)
+	Template string `json:"template,omitempty"`
+}
+
+type streamAlertTracker struct {
+	providers map[string]StreamProvider
+	alerts    []*StreamAlert
+	// provider/channel -> the last announced stream ID
+	announced map[string]string
+	lock      sync.Mutex
+}
+
+// EnableStreamAlerts loads the streamalerts command for servers to get notified when someone goes live on one of the
+// providers, e.g &TwitchEventSub{} and &YouTubePoller{}. Polling providers are asked every StreamAlertInterval.
+func (bot *Bot) EnableStreamAlerts(providers ...StreamProvider) *Bot {
+	if bot.streamAlerts != nil {
+		return bot
+	}
+	bot.streamAlerts = &streamAlertTracker{providers: make(map[string]StreamProvider), announced: make(map[string]string)}
+	for _, provider := range providers {
+		bot.streamAlerts.providers[provider.Name()] = provider
+	}
+	if _, err := GetJSON(bot.Settings, "", streamAlertsKey, &bot.streamAlerts.alerts); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+	if _, err := GetJSON(bot.Settings, "", streamAlertsAnnouncedKey, &bot.streamAlerts.announced); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+	bot.AddCommand(NewCommand("streamalerts", "Moderation", streamAlertsCommand).
+		SetDescription("Posts in this channel when a streamer goes live.").
+		SetUsage("[action:string] [provider:string] [channel:string] [args:string...]").
+		SetGuildOnly(true).
+		AddAliases("livealerts"))
+	bot.Scheduler.After(StreamAlertInterval, bot.pollStreams)
+	return bot
+}
+
+// AddStreamAlert subscribes a Discord channel to a channel of a provider, replacing an existing alert for it.
+func (bot *Bot) AddStreamAlert(alert *StreamAlert) error {
+	if _, ok := bot.streamAlerts.providers[alert.Provider]; !ok {
+		return fmt.Errorf("unknown stream provider %s", alert.Provider)
+	}
+	bot.streamAlerts.lock.Lock()
+	defer bot.streamAlerts.lock.Unlock()
+	bot.removeStreamAlert(alert.ChannelID, alert.Provider, alert.Channel)
+	bot.streamAlerts.alerts = append(bot.streamAlerts.alerts, alert)
+	return SetJSON(bot.Settings, "", streamAlertsKey, bot.streamAlerts.alerts)
+}
+
+// RemoveStreamAlert unsubscribes a Discord channel from a channel of a provider, false if it wasn't subscribed.
+func (bot *Bot) RemoveStreamAlert(channelID, provider, channel string) (bool, error) {
+	bot.streamAlerts.lock.Lock()
+	defer bot.streamAlerts.lock.Unlock()
+	if !bot.removeStreamAlert(channelID, provider, channel) {
+		return false, nil
+	}
+	return true, SetJSON(bot.Settings, "", streamAlertsKey, bot.streamAlerts.alerts)
+}
+
+// removeStreamAlert removes an alert, the caller must hold the lock.
+func (bot *Bot) removeStreamAlert(channelID, provider, channel string) bool {
+	alerts := bot.streamAlerts.alerts[:0]
+	removed := false
+	for _, alert := range bot.streamAlerts.alerts {
+		if alert.ChannelID == channelID && alert.Provider == provider && strings.EqualFold(alert.Channel, channel) {
+			removed = true
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+	bot.streamAlerts.alerts = alerts
+	return removed
+}
+
+// StreamAlerts returns the stream alerts of a guild.
+func (bot *Bot) StreamAlerts(guildID string) []StreamAlert {
+	bot.streamAlerts.lock.Lock()
+	defer bot.streamAlerts.lock.Unlock()
+	var alerts []StreamAlert
+	for _, alert := range bot.streamAlerts.alerts {
+		if alert.GuildID == guildID {
+			alerts = append(alerts, *alert)
+		}
+	}
+	return alerts
+}
+
+// pollStreams asks every polling provider which of the subscribed channels are live and schedules the next poll.
+func (bot *Bot) pollStreams() {
+	bot.Scheduler.After(StreamAlertInterval, bot.pollStreams)
+	channels := make(map[string][]string)
+	bot.streamAlerts.lock.Lock()
+	for _, alert := range bot.streamAlerts.alerts {
+		if !containsString(channels[alert.Provider], alert.Channel) {
+			channels[alert.Provider] = append(channels[alert.Provider], alert.Channel)
+		}
+	}
+	bot.streamAlerts.lock.Unlock()
+	for name, list := range channels {
+		provider, ok := bot.streamAlerts.providers[name]
+		if !ok {
+			continue
+		}
+		streams, err := provider.Poll(list)
+		if err != nil {
+			bot.ErrorHandler(bot, err)
+			continue
+		}
+		for _, stream := range streams {
+			bot.AnnounceStream(stream)
+		}
+	}
+}
+
+// AnnounceStream posts a live stream in every channel subscribed to it, a stream already announced is skipped so
+// providers can report a stream as often as they see it.
+func (bot *Bot) AnnounceStream(stream *LiveStream) {
+	key := stream.Provider + "/" + strings.ToLower(stream.Channel)
+	bot.streamAlerts.lock.Lock()
+	if bot.streamAlerts.announced[key] == stream.ID {
+		bot.streamAlerts.lock.Unlock()
+		return
+	}
+	bot.streamAlerts.announced[key] = stream.ID
+	if err := SetJSON(bot.Settings, "", streamAlertsAnnouncedKey, bot.streamAlerts.announced); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+	var alerts []StreamAlert
+	for _, alert := range bot.streamAlerts.alerts {
+		if alert.Provider == stream.Provider && strings.EqualFold(alert.Channel, stream.Channel) {
+			alerts = append(alerts, *alert)
+		}
+	}
+	bot.streamAlerts.lock.Unlock()
+
+	for _, alert := range alerts {
+		if _, err := bot.Session.ChannelMessageSendComplex(alert.ChannelID, bot.streamAlertMessage(&alert, stream)); err != nil {
+			bot.ErrorHandler(bot, err)
+		}
+	}
+}
+
+// streamAlertMessage builds the message of an alert, only the alert's role can be pinged.
+func (bot *Bot) streamAlertMessage(alert *StreamAlert, stream *LiveStream) *discordgo.MessageSend {
+	locale := bot.LocaleFor(alert.GuildID, alert.ChannelID)
+	template := alert.Template
+	if template == "" {
+		template = locale.Get("STREAM_ALERT")
+	}
+	role := ""
+	mentions := &discordgo.MessageAllowedMentions{Parse: []discordgo.AllowedMentionType{}}
+	if alert.RoleID != "" {
+		role = "<@&" + alert.RoleID + ">"
+		mentions.Roles = []string{alert.RoleID}
+	}
+	content := strings.NewReplacer(
+		"{name}", Escape(stream.Name),
+		"{title}", Escape(stream.Title),
+		"{game}", Escape(stream.Game),
+		"{url}", stream.URL,
+		"{role}", role,
+	).Replace(template)
+
+	title := stream.Title
+	if title == "" {
+		title = stream.Name
+	}
+	embed := NewEmbed().
+		SetAuthor(stream.Name).
+		SetTitle(title).
+		SetURL(stream.URL).
+		SetColor(bot.Color).
+		SetFooter(stream.Provider)
+	if stream.Game != "" {
+		embed.SetDescription(locale.Get("STREAM_ALERT_GAME", stream.Game))
+	}
+	if stream.Thumbnail != "" {
+		embed.SetImage(stream.Thumbnail)
+	}
+	return &discordgo.MessageSend{Content: strings.TrimSpace(content), Embed: embed.Truncate().Build(), AllowedMentions: mentions}
+}
+
+// TwitchEventSub receives Twitch stream.online notifications, mount it's Handler where the EventSub subscriptions
+// point to. Subscriptions are created with the Twitch API, see https://dev.twitch.tv/docs/eventsub
+type TwitchEventSub struct {
+	Secret string // The secret of the subscriptions, notifications not signed with it are rejected.
+}
+
+// Name implements StreamProvider
+func (t *TwitchEventSub) Name() string { return "twitch" }
+
+// Poll implements StreamProvider, notifications are pushed to the Handler.
+func (t *TwitchEventSub) Poll(channels []string) ([]*LiveStream, error) { return nil, nil }
+
+// Handler returns the http handler receiving the notifications, it answers the verification of new subscriptions
+// and announces the streams going live with bot.AnnounceStream
+func (t *TwitchEventSub) Handler(bot *Bot) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if !t.verify(r.Header, body) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var payload struct {
+			Challenge    string `json:"challenge"`
+			Subscription struct {
+				Type string `json:"type"`
+			} `json:"subscription"`
+			Event struct {
+				ID        string    `json:"id"`
+				Login     string    `json:"broadcaster_user_login"`
+				Name      string    `json:"broadcaster_user_name"`
+				Type      string    `json:"type"`
+				StartedAt time.Time `json:"started_at"`
+			} `json:"event"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.Header.Get("Twitch-Eventsub-Message-Type") {
+		case "webhook_callback_verification":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(payload.Challenge))
+			return
+		case "notification":
+			event := payload.Event
+			if payload.Subscription.Type == "stream.online" && event.Type == "live" {
+				// Announcing can take a while, Twitch retries notifications not answered quickly.
+				go bot.AnnounceStream(&LiveStream{Provider: "twitch", Channel: event.Login, ID: event.ID, Name: event.Name,
+					URL: "https://twitch.tv/" + event.Login, StartedAt: event.StartedAt})
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// verify checks the signature of a notification.
+func (t *TwitchEventSub) verify(header http.Header, body []byte) bool {
+	mac := hmac.New(sha256.New, []byte(t.Secret))
+	mac.Write([]byte(header.Get("Twitch-Eventsub-Message-Id") + header.Get("Twitch-Eventsub-Message-Timestamp")))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(header.Get("Twitch-Eventsub-Message-Signature")))
+}
+
+// YouTubePoller finds live YouTube streams of channels by their ID (starting with UC). It reads the latest uploads
+// from the channel feeds and checks them with one API call, which keeps the API quota usage low.
+type YouTubePoller struct {
+	APIKey  string // A YouTube Data API v3 key.
+	FeedURL string // (default: https://www.youtube.com/feeds/videos.xml)
+	APIURL  string // (default: https://www.googleapis.com/youtube/v3)
+}
+
+// youtubeFeedVideos is how many of the latest uploads of a channel are checked for being live.
+const youtubeFeedVideos = 5
+
+// Name implements StreamProvider
+func (y *YouTubePoller) Name() string { return "youtube" }
+
+// Poll implements StreamProvider
+func (y *YouTubePoller) Poll(channels []string) ([]*LiveStream, error) {
+	feedURL, apiURL := y.FeedURL, y.APIURL
+	if feedURL == "" {
+		feedURL = "https://www.youtube.com/feeds/videos.xml"
+	}
+	if apiURL == "" {
+		apiURL = "https://www.googleapis.com/youtube/v3"
+	}
+	var ids []string
+	for _, channel := range channels {
+		var feed struct {
+			Entries []struct {
+				VideoID string `xml:"videoId"`
+			} `xml:"entry"`
+		}
+		if err := youtubeGet(feedURL+"?channel_id="+url.QueryEscape(channel), func(data []byte) error { return xml.Unmarshal(data, &feed) }); err != nil {
+			return nil, err
+		}
+		for i, entry := range feed.Entries {
+			if i == youtubeFeedVideos {
+				break
+			}
+			ids = append(ids, entry.VideoID)
+		}
+	}
+
+	var streams []*LiveStream
+	// The API takes up to 50 IDs at once.
+	for start := 0; start < len(ids); start += 50 {
+		end := start + 50
+		if end > len(ids) {
+			end = len(ids)
+		}
+		var videos struct {
+			Items []struct {
+				ID      string `json:"id"`
+				Snippet struct {
+					ChannelID            string `json:"channelId"`
+					ChannelTitle         string `json:"channelTitle"`
+					Title                string `json:"title"`
+					LiveBroadcastContent string `json:"liveBroadcastContent"`
+					Thumbnails           map[string]struct {
+						URL string `json:"url"`
+					} `json:"thumbnails"`
+				} `json:"snippet"`
+				LiveStreamingDetails struct {
+					ActualStartTime time.Time `json:"actualStartTime"`
+				} `json:"liveStreamingDetails"`
+			} `json:"items"`
+		}
+		query := url.Values{"part": {"snippet,liveStreamingDetails"}, "id": {strings.Join(ids[start:end], ",")}, "key": {y.APIKey}}
+		if err := youtubeGet(apiURL+"/videos?"+query.Encode(), func(data []byte) error { return json.Unmarshal(data, &videos) }); err != nil {
+			return nil, err
+		}
+		for _, video := range videos.Items {
+			if video.Snippet.LiveBroadcastContent != "live" {
+				continue
+			}
+			streams = append(streams, &LiveStream{Provider: "youtube", Channel: video.Snippet.ChannelID, ID: video.ID,
+				Name: video.Snippet.ChannelTitle, Title: video.Snippet.Title, URL: "https://youtu.be/" + video.ID,
+				Thumbnail: video.Snippet.Thumbnails["high"].URL, StartedAt: video.LiveStreamingDetails.ActualStartTime})
+		}
+	}
+	return streams, nil
+}
+
+// youtubeGet fetches a YouTube URL and decodes the response with decode.
+func youtubeGet(endpoint string, decode func(data []byte) error) error {
+	res, err := StreamAlertClient.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("youtube responded with status %d", res.StatusCode)
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	return decode(data)
+}
+
+func streamAlertsCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+	action := strings.ToLower(ctx.ArgString(0))
+	if action == "" || action == "list" {
+		alerts := bot.StreamAlerts(ctx.Guild.ID)
+		if len(alerts) == 0 {
+			ctx.ReplyLocale("COMMAND_STREAMALERTS_NONE", ctx.Prefix)
+			return
+		}
+		lines := make([]string, len(alerts))
+		for i, alert := range alerts {
+			lines[i] = fmt.Sprintf("**%s** %s in <#%s>", alert.Provider, alert.Channel, alert.ChannelID)
+			if alert.RoleID != "" {
+				lines[i] += fmt.Sprintf(" (<@&%s>)", alert.RoleID)
+			}
+		}
+		sort.Strings(lines)
+		ctx.BuildEmbed(NewEmbed().
+			SetTitle(ctx.Locale.Get("COMMAND_STREAMALERTS_TITLE")).
+			SetDescription(strings.Join(lines, "\n")).
+			SetColor(bot.Color))
+		return
+	}
+	if !ctx.HasPermissions(discordgo.PermissionManageServer) {
+		ctx.ReplyLocale("COMMAND_STREAMALERTS_NO_PERMISSION")
+		return
+	}
+	provider, channel := strings.ToLower(ctx.ArgString(1)), ctx.ArgString(2)
+	if provider == "" || channel == "" {
+		ctx.ReplyLocale("COMMAND_STREAMALERTS_USAGE", ctx.Prefix)
+		return
+	}
+	if _, ok := bot.streamAlerts.providers[provider]; !ok {
+		names := make([]string, 0, len(bot.streamAlerts.providers))
+		for name := range bot.streamAlerts.providers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		ctx.ReplyLocale("COMMAND_STREAMALERTS_UNKNOWN_PROVIDER", provider, strings.Join(names, ", "))
+		return
+	}
+
+	switch action {
+	case "add":
+		alert := &StreamAlert{Provider: provider, Channel: channel, GuildID: ctx.Guild.ID, ChannelID: ctx.Channel.ID}
+		if ctx.Arg(3).IsProvided() {
+			role := findRole(ctx.Guild, ctx.ArgString(3))
+			if role == nil {
+				ctx.ReplyLocale("COMMAND_STREAMALERTS_UNKNOWN_ROLE", ctx.ArgString(3))
+				return
+			}
+			alert.RoleID = role.ID
+		}
+		// Keep the template of the alert being replaced.
+		for _, old := range bot.StreamAlerts(ctx.Guild.ID) {
+			if old.ChannelID == alert.ChannelID && old.Provider == provider && strings.EqualFold(old.Channel, channel) {
+				alert.Template = old.Template
+			}
+		}
+		if err := bot.AddStreamAlert(alert); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_STREAMALERTS_ADDED", channel, provider)
+	case "remove", "delete":
+		removed, err := bot.RemoveStreamAlert(ctx.Channel.ID, provider, channel)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		if !removed {
+			ctx.ReplyLocale("COMMAND_STREAMALERTS_NOT_FOUND", channel, provider)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_STREAMALERTS_REMOVED", channel, provider)
+	case "template":
+		var alert *StreamAlert
+		for _, old := range bot.StreamAlerts(ctx.Guild.ID) {
+			if old.ChannelID == ctx.Channel.ID && old.Provider == provider && strings.EqualFold(old.Channel, channel) {
+				copied := old
+				alert = &copied
+			}
+		}
+		if alert == nil {
+			ctx.ReplyLocale("COMMAND_STREAMALERTS_NOT_FOUND", channel, provider)
+			return
+		}
+		alert.Template = ""
+		if len(ctx.ArgOffsets) > 3 {
+			alert.Template = strings.TrimSpace(ctx.RawContent[ctx.ArgOffsets[3]:])
+		}
+		if err := bot.AddStreamAlert(alert); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_STREAMALERTS_TEMPLATE", channel, provider)
+	default:
+		ctx.ReplyLocale("COMMAND_STREAMALERTS_USAGE", ctx.Prefix)
+	}
+}
@@ -0,0 +1,66 @@
+package sapphire
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTwitchEventSub(t *testing.T) {
+	twitch := &TwitchEventSub{Secret: "secret"}
+	body := `{"challenge": "pogchamp", "subscription": {"type": "stream.online"}}`
+	sign := func(body string) string {
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write([]byte("id" + "2022-01-01T00:00:00Z" + body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	for _, signature := range []string{sign(body), "sha256=00"} {
+		req := httptest.NewRequest("POST", "/twitch", strings.NewReader(body))
+		req.Header.Set("Twitch-Eventsub-Message-Id", "id")
+		req.Header.Set("Twitch-Eventsub-Message-Timestamp", "2022-01-01T00:00:00Z")
+		req.Header.Set("Twitch-Eventsub-Message-Signature", signature)
+		req.Header.Set("Twitch-Eventsub-Message-Type", "webhook_callback_verification")
+		w := httptest.NewRecorder()
+		twitch.Handler(nil).ServeHTTP(w, req)
+		valid := signature != "sha256=00"
+		if valid && (w.Code != http.StatusOK || w.Body.String() != "pogchamp") {
+			t.Errorf("verification = %d %q", w.Code, w.Body.String())
+		}
+		if !valid && w.Code != http.StatusForbidden {
+			t.Errorf("bad signature = %d", w.Code)
+		}
+	}
+}
+
+func TestYouTubePoller(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed":
+			if r.URL.Query().Get("channel_id") != "UCabc" {
+				t.Errorf("Unexpected feed query %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`<feed xmlns="http://www.w3.org/2005/Atom" xmlns:yt="http://www.youtube.com/xml/schemas/2015">
+<entry><yt:videoId>live1</yt:videoId></entry><entry><yt:videoId>old1</yt:videoId></entry></feed>`))
+		case "/api/videos":
+			if r.URL.Query().Get("id") != "live1,old1" || r.URL.Query().Get("key") != "key" {
+				t.Errorf("Unexpected videos query %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"items": [
+{"id": "live1", "snippet": {"channelId": "UCabc", "channelTitle": "Abc", "title": "Live now", "liveBroadcastContent": "live"}},
+{"id": "old1", "snippet": {"channelId": "UCabc", "channelTitle": "Abc", "title": "Old", "liveBroadcastContent": "none"}}]}`))
+		}
+	}))
+	defer server.Close()
+
+	streams, err := (&YouTubePoller{APIKey: "key", FeedURL: server.URL + "/feed", APIURL: server.URL + "/api"}).Poll([]string{"UCabc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(streams) != 1 || streams[0].ID != "live1" || streams[0].Channel != "UCabc" || streams[0].URL != "https://youtu.be/live1" {
+		t.Errorf("Unexpected streams %+v", streams)
+	}
+}
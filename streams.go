@@ -0,0 +1,315 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"github.com/bwmarrin/discordgo"
+	"sync"
+	"time"
+)
+
+// Topics of the stream bus events.
+const (
+	TopicStreamStarted = "stream.started"
+	TopicStreamEnded   = "stream.ended"
+)
+
+// StreamKind is how a member streams.
+type StreamKind string
+
+const (
+	StreamPresence StreamKind = "presence" // Streaming on e.g Twitch or YouTube, shown in their presence.
+	StreamGoLive   StreamKind = "golive"   // Go Live, streaming their screen to a voice channel.
+)
+
+// Stream is a member streaming, a member streams on Twitch and to a voice channel at the same time as two streams.
+type Stream struct {
+	GuildID   string
+	UserID    string
+	Kind      StreamKind
+	Name      string // The stream's title for presence streams.
+	URL       string // The stream's link for presence streams.
+	ChannelID string // The voice channel of Go Live streams.
+	StartedAt time.Time
+}
+
+// StreamStarted is published when a member starts streaming.
+type StreamStarted struct {
+	Stream Stream
+}
+
+// Topic implements BusEvent
+func (e *StreamStarted) Topic() string { return TopicStreamStarted }
+
+// StreamEnded is published when a member stops streaming, Stream is how it was before it ended.
+type StreamEnded struct {
+	Stream   Stream
+	Duration time.Duration
+}
+
+// Topic implements BusEvent
+func (e *StreamEnded) Topic() string { return TopicStreamEnded }
+
+// LiveRoleConfig is the per-guild config of the live role, see bot.EnableLiveRole
+var LiveRoleConfig = NewConfigSchema("liverole", "A role given to members while they're streaming.").
+	Add("role", ConfigRole, "", "The role given while streaming, none to turn it off.").
+	Add("required", ConfigRole, "", "Only members with this role get the live role, e.g a streamer role.").
+	Add("presence", ConfigBool, "true", "Whether streaming on e.g Twitch counts.").
+	Add("golive", ConfigBool, "true", "Whether Go Live in a voice channel counts.")
+
+type streamKey struct {
+	userID string
+	kind   StreamKind
+}
+
+type streamTracker struct {
+	// guild ID -> streams
+	guilds map[string]map[streamKey]*Stream
+	lock   sync.RWMutex
+}
+
+// Raw payloads, the bundled discordgo doesn't know about self_stream.
+type rawStreamActivity struct {
+	Type    discordgo.GameType `json:"type"`
+	Name    string             `json:"name"`
+	Details string             `json:"details"`
+	URL     string             `json:"url"`
+}
+
+type rawStreamPresence struct {
+	User struct {
+		ID string `json:"id"`
+	} `json:"user"`
+	GuildID    string              `json:"guild_id"`
+	Activities []rawStreamActivity `json:"activities"`
+}
+
+type rawStreamVoiceState struct {
+	GuildID    string `json:"guild_id"`
+	UserID     string `json:"user_id"`
+	ChannelID  string `json:"channel_id"`
+	SelfStream bool   `json:"self_stream"`
+}
+
+type rawStreamGuild struct {
+	ID          string                `json:"id"`
+	Presences   []rawStreamPresence   `json:"presences"`
+	VoiceStates []rawStreamVoiceState `json:"voice_states"`
+}
+
+// EnableStreamEvents publishes StreamStarted and StreamEnded on the event bus when members start or stop streaming
+// on e.g Twitch or with Go Live, for live roles or stream announcements. Presence streams need IntentsGuildPresences
+// and Go Live IntentsGuildVoiceStates when intents are set.
+// Members already streaming when the bot connects don't start a stream, streams that changed while the bot was
+// disconnected are published when the guild is received again.
+func (bot *Bot) EnableStreamEvents() *Bot {
+	if bot.streams != nil {
+		return bot
+	}
+	bot.streams = &streamTracker{guilds: make(map[string]map[streamKey]*Stream)}
+	bot.AddHandler(bot.processEvent("streams", func(s *discordgo.Session, e *discordgo.Event) error {
+		var events []BusEvent
+		now := time.Now()
+		switch e.Type {
+		case "GUILD_CREATE":
+			guild := &rawStreamGuild{}
+			if err := json.Unmarshal(e.RawData, guild); err != nil {
+				return err
+			}
+			events = bot.streams.seed(guild, now)
+		case "GUILD_DELETE":
+			guild := &rawStreamGuild{}
+			if err := json.Unmarshal(e.RawData, guild); err != nil {
+				return err
+			}
+			bot.streams.lock.Lock()
+			delete(bot.streams.guilds, guild.ID)
+			bot.streams.lock.Unlock()
+		case "PRESENCE_UPDATE":
+			presence := &rawStreamPresence{}
+			if err := json.Unmarshal(e.RawData, presence); err != nil {
+				return err
+			}
+			events = append(events, bot.streams.update(presence.GuildID, presence.User.ID, StreamPresence, presenceStream(presence), now))
+		case "VOICE_STATE_UPDATE":
+			state := &rawStreamVoiceState{}
+			if err := json.Unmarshal(e.RawData, state); err != nil {
+				return err
+			}
+			events = append(events, bot.streams.update(state.GuildID, state.UserID, StreamGoLive, goLiveStream(state), now))
+		}
+		for _, event := range events {
+			if event != nil {
+				bot.Publish(event)
+			}
+		}
+		return nil
+	}))
+	return bot
+}
+
+// presenceStream returns the stream of a presence, nil if they aren't streaming.
+func presenceStream(presence *rawStreamPresence) *Stream {
+	for _, activity := range presence.Activities {
+		if activity.Type == discordgo.GameTypeStreaming {
+			name := activity.Details
+			if name == "" {
+				name = activity.Name
+			}
+			return &Stream{GuildID: presence.GuildID, UserID: presence.User.ID, Kind: StreamPresence, Name: name, URL: activity.URL}
+		}
+	}
+	return nil
+}
+
+// goLiveStream returns the Go Live stream of a voice state, nil if they aren't streaming.
+func goLiveStream(state *rawStreamVoiceState) *Stream {
+	if !state.SelfStream || state.ChannelID == "" {
+		return nil
+	}
+	return &Stream{GuildID: state.GuildID, UserID: state.UserID, Kind: StreamGoLive, ChannelID: state.ChannelID}
+}
+
+// update applies the current stream of a member, nil if they aren't streaming, and returns the event to publish.
+func (t *streamTracker) update(guildID, userID string, kind StreamKind, stream *Stream, now time.Time) BusEvent {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	streams := t.guilds[guildID]
+	if streams == nil {
+		streams = make(map[streamKey]*Stream)
+		t.guilds[guildID] = streams
+	}
+	key := streamKey{userID, kind}
+	old, live := streams[key]
+	switch {
+	case stream == nil && live:
+		delete(streams, key)
+		return &StreamEnded{Stream: *old, Duration: now.Sub(old.StartedAt)}
+	case stream == nil:
+		return nil
+	case live:
+		// A changed title or channel is the same stream.
+		stream.StartedAt = old.StartedAt
+		streams[key] = stream
+		return nil
+	}
+	stream.StartedAt = now
+	streams[key] = stream
+	return &StreamStarted{Stream: *stream}
+}
+
+// seed replaces a guild's streams with the ones it was received with, the first time a guild is seen nothing is
+// published, after that the differences are.
+func (t *streamTracker) seed(guild *rawStreamGuild, now time.Time) []BusEvent {
+	streams := make(map[streamKey]*Stream)
+	for i := range guild.Presences {
+		presence := &guild.Presences[i]
+		presence.GuildID = guild.ID
+		if stream := presenceStream(presence); stream != nil {
+			streams[streamKey{stream.UserID, stream.Kind}] = stream
+		}
+	}
+	for i := range guild.VoiceStates {
+		state := &guild.VoiceStates[i]
+		state.GuildID = guild.ID
+		if stream := goLiveStream(state); stream != nil {
+			streams[streamKey{stream.UserID, stream.Kind}] = stream
+		}
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	old, known := t.guilds[guild.ID]
+	var events []BusEvent
+	for key, stream := range streams {
+		if prev, ok := old[key]; ok {
+			stream.StartedAt = prev.StartedAt
+			continue
+		}
+		stream.StartedAt = now
+		if known {
+			events = append(events, &StreamStarted{Stream: *stream})
+		}
+	}
+	for key, prev := range old {
+		if _, ok := streams[key]; !ok {
+			events = append(events, &StreamEnded{Stream: *prev, Duration: now.Sub(prev.StartedAt)})
+		}
+	}
+	t.guilds[guild.ID] = streams
+	return events
+}
+
+// Streams returns the streams of a guild, see EnableStreamEvents
+func (bot *Bot) Streams(guildID string) []Stream {
+	if bot.streams == nil {
+		return nil
+	}
+	bot.streams.lock.RLock()
+	defer bot.streams.lock.RUnlock()
+	streams := make([]Stream, 0, len(bot.streams.guilds[guildID]))
+	for _, stream := range bot.streams.guilds[guildID] {
+		streams = append(streams, *stream)
+	}
+	return streams
+}
+
+// UserStreams returns the streams of a member, empty if they aren't streaming.
+func (bot *Bot) UserStreams(guildID, userID string) []Stream {
+	if bot.streams == nil {
+		return nil
+	}
+	bot.streams.lock.RLock()
+	defer bot.streams.lock.RUnlock()
+	var streams []Stream
+	for _, kind := range []StreamKind{StreamPresence, StreamGoLive} {
+		if stream, ok := bot.streams.guilds[guildID][streamKey{userID, kind}]; ok {
+			streams = append(streams, *stream)
+		}
+	}
+	return streams
+}
+
+// EnableLiveRole gives members a role while they're streaming once a server sets it with LiveRoleConfig.
+// The bot needs Manage Roles and it's highest role above the live role.
+func (bot *Bot) EnableLiveRole() *Bot {
+	bot.EnableStreamEvents()
+	bot.AddConfigSchema(LiveRoleConfig)
+	bot.Subscribe(TopicStreamStarted, func(event BusEvent) {
+		bot.updateLiveRole(event.(*StreamStarted).Stream)
+	})
+	bot.Subscribe(TopicStreamEnded, func(event BusEvent) {
+		bot.updateLiveRole(event.(*StreamEnded).Stream)
+	})
+	return bot
+}
+
+// updateLiveRole gives or takes the live role of a member depending on if they have a counted stream left.
+func (bot *Bot) updateLiveRole(stream Stream) {
+	role := LiveRoleConfig.Get(bot, stream.GuildID, "role")
+	if role == "" {
+		return
+	}
+	member, err := bot.Session.State.Member(stream.GuildID, stream.UserID)
+	if err != nil {
+		return
+	}
+	if required := LiveRoleConfig.Get(bot, stream.GuildID, "required"); required != "" && !containsString(member.Roles, required) {
+		return
+	}
+	live := false
+	for _, s := range bot.UserStreams(stream.GuildID, stream.UserID) {
+		if LiveRoleConfig.GetBool(bot, stream.GuildID, string(s.Kind)) {
+			live = true
+		}
+	}
+	has := containsString(member.Roles, role)
+	switch {
+	case live && !has:
+		err = bot.AddMemberRole(stream.GuildID, stream.UserID, role)
+	case !live && has:
+		err = bot.RemoveMemberRole(stream.GuildID, stream.UserID, role)
+	}
+	if err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+}
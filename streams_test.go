@@ -0,0 +1,42 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamTracker(t *testing.T) {
+	tracker := &streamTracker{guilds: make(map[string]map[streamKey]*Stream)}
+	start := time.Now()
+	guild := &rawStreamGuild{ID: "g", VoiceStates: []rawStreamVoiceState{{UserID: "a", ChannelID: "1", SelfStream: true}}}
+	if events := tracker.seed(guild, start); len(events) != 0 {
+		t.Errorf("first seed published %d events", len(events))
+	}
+	presence := &rawStreamPresence{GuildID: "g", Activities: []rawStreamActivity{{Type: discordgo.GameTypeStreaming, Name: "Twitch", Details: "Speedrun", URL: "https://twitch.tv/b"}}}
+	presence.User.ID = "b"
+	started, ok := tracker.update("g", "b", StreamPresence, presenceStream(presence), start).(*StreamStarted)
+	if !ok || started.Stream.Name != "Speedrun" || started.Stream.URL != "https://twitch.tv/b" {
+		t.Errorf("b starting = %+v", started)
+	}
+	presence.Activities[0].Details = "Any%"
+	if event := tracker.update("g", "b", StreamPresence, presenceStream(presence), start.Add(time.Minute)); event != nil {
+		t.Errorf("a changed title published %+v", event)
+	}
+	ended, ok := tracker.update("g", "b", StreamPresence, nil, start.Add(time.Hour)).(*StreamEnded)
+	if !ok || ended.Duration != time.Hour || ended.Stream.Name != "Any%" {
+		t.Errorf("b ending = %+v", ended)
+	}
+	// a stopped and c started while disconnected.
+	guild.VoiceStates = []rawStreamVoiceState{{UserID: "a", ChannelID: "1"}, {UserID: "c", ChannelID: "2", SelfStream: true}}
+	topics := []string{}
+	for _, event := range tracker.seed(guild, start.Add(2*time.Hour)) {
+		topics = append(topics, event.Topic())
+	}
+	sort.Strings(topics)
+	if strings.Join(topics, ",") != "stream.ended,stream.started" {
+		t.Errorf("reseed published %v", topics)
+	}
+}
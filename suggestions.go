@@ -0,0 +1,471 @@
+package sapphire
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SuggestionConfig is the per-guild config of suggestions, see bot.EnableSuggestions
+var SuggestionConfig = NewConfigSchema("suggestions", "Lets members post suggestions for staff to review.").
+	Add("channel", ConfigChannel, "", "Channel suggestions are posted in, leave empty to disable suggestions.").
+	Add("staff", ConfigRole, "", "Role that can approve and deny suggestions, members with Manage Server always can.").
+	Add("dm", ConfigBool, "true", "Whether the author is sent a DM when their suggestion is reviewed.")
+
+// The guild settings keys suggestions are stored under, followed by the suggestion ID and the last used ID.
+const (
+	suggestionKeyPrefix = "suggestion."
+	suggestionCountKey  = "suggestions.count"
+)
+
+// The emojis of the vote buttons, suggestions posted before the buttons were voted on with these reactions.
+const (
+	SuggestionUpvote   = "👍"
+	SuggestionDownvote = "👎"
+)
+
+// SuggestionStatus is the review status of a suggestion.
+type SuggestionStatus string
+
+const (
+	SuggestionPending  SuggestionStatus = "pending"
+	SuggestionApproved SuggestionStatus = "approved"
+	SuggestionDenied   SuggestionStatus = "denied"
+)
+
+// Suggestion is a suggestion posted by a member.
+type Suggestion struct {
+	ID         int              `json:"id"` // Numbered per guild starting at 1.
+	GuildID    string           `json:"guild_id"`
+	ChannelID  string           `json:"channel_id"`
+	MessageID  string           `json:"message_id"`
+	AuthorID   string           `json:"author_id"`
+	Content    string           `json:"content"`
+	Status     SuggestionStatus `json:"status"`
+	Reason     string           `json:"reason"`
+	ReviewerID string           `json:"reviewer_id"`
+	CreatedAt  time.Time        `json:"created_at"`
+	Upvotes    []string         `json:"upvotes,omitempty"`   // IDs of the members who voted with the buttons.
+	Downvotes  []string         `json:"downvotes,omitempty"` // IDs of the members who voted with the buttons.
+}
+
+type suggestionTracker struct {
+	// Guards the ID counter and reviews.
+	lock sync.Mutex
+}
+
+// EnableSuggestions loads the suggest and suggestion commands, servers pick the channel suggestions go to with SuggestionConfig.
+// Suggestions are posted as an embed with buttons to vote and for staff to approve or deny them, staff can also
+// review them with the suggestion command. Reviewing updates the embed with the status, reason and final votes.
+func (bot *Bot) EnableSuggestions() *Bot {
+	if bot.suggestions != nil {
+		return bot
+	}
+	bot.suggestions = &suggestionTracker{}
+	bot.AddComponentHandler("suggestion", suggestionComponent)
+	bot.AddConfigSchema(SuggestionConfig)
+	bot.AddDataSubject(suggestionData(bot))
+	bot.AddCommand(NewCommand("suggest", "General", suggestCommand).
+		SetDescription("Posts a suggestion for the server.").
+		SetUsage("<suggestion:string...>").
+		SetGuildOnly(true).
+		SetCooldown(60))
+	bot.AddCommand(NewCommand("suggestion", "Moderation", suggestionCommand).
+		SetDescription("Shows, approves or denies a suggestion.").
+		SetUsage("<action:string> <id:int> [reason:string...]").
+		SetGuildOnly(true))
+	return bot
+}
+
+// Suggest posts a new suggestion in the guild's suggestion channel.
+func (bot *Bot) Suggest(guildID string, author *discordgo.User, content string) (*Suggestion, error) {
+	channelID := SuggestionConfig.Get(bot, guildID, "channel")
+	if channelID == "" {
+		return nil, fmt.Errorf("suggestions are not enabled in guild %s", guildID)
+	}
+
+	bot.suggestions.lock.Lock()
+	defer bot.suggestions.lock.Unlock()
+	id := 1
+	if last, ok, err := bot.Settings.Get(guildID, suggestionCountKey); err != nil {
+		return nil, err
+	} else if ok {
+		n, _ := strconv.Atoi(last)
+		id = n + 1
+	}
+	suggestion := &Suggestion{
+		ID:        id,
+		GuildID:   guildID,
+		ChannelID: channelID,
+		AuthorID:  author.ID,
+		Content:   content,
+		Status:    SuggestionPending,
+		CreatedAt: time.Now(),
+	}
+	msg, err := (&channelResponse{bot: bot, channelID: channelID}).Send(&ResponseMessage{
+		Embed:      bot.SuggestionEmbed(suggestion, author, 0, 0),
+		Components: bot.suggestionButtons(suggestion),
+	})
+	if err != nil {
+		return nil, err
+	}
+	suggestion.MessageID = msg.ID
+
+	if err := bot.Settings.Set(guildID, suggestionCountKey, strconv.Itoa(id)); err != nil {
+		return nil, err
+	}
+	return suggestion, SetJSON(bot.Settings, guildID, suggestionKeyPrefix+strconv.Itoa(id), suggestion)
+}
+
+// Suggestion returns a suggestion by it's ID, nil if it doesn't exist.
+func (bot *Bot) Suggestion(guildID string, id int) (*Suggestion, error) {
+	suggestion := &Suggestion{}
+	ok, err := GetJSON(bot.Settings, guildID, suggestionKeyPrefix+strconv.Itoa(id), suggestion)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return suggestion, nil
+}
+
+// VoteSuggestion toggles a member's vote on a pending suggestion, voting the other way moves the vote.
+// Returns nil if the suggestion doesn't exist, reviewed suggestions are returned unchanged.
+func (bot *Bot) VoteSuggestion(guildID string, id int, userID string, up bool) (*Suggestion, error) {
+	bot.suggestions.lock.Lock()
+	defer bot.suggestions.lock.Unlock()
+	suggestion, err := bot.Suggestion(guildID, id)
+	if err != nil || suggestion == nil || suggestion.Status != SuggestionPending {
+		return suggestion, err
+	}
+	votes, other := &suggestion.Upvotes, &suggestion.Downvotes
+	if !up {
+		votes, other = other, votes
+	}
+	voted := containsString(*votes, userID)
+	*votes = removeString(*votes, userID)
+	*other = removeString(*other, userID)
+	if !voted {
+		*votes = append(*votes, userID)
+	}
+	return suggestion, SetJSON(bot.Settings, guildID, suggestionKeyPrefix+strconv.Itoa(id), suggestion)
+}
+
+// suggestionData is the data subject of the suggestions a user posted, reviewed or voted on.
+// Deleting removes their suggestions, the reviewer from the ones they reviewed and their votes, posted messages are left alone.
+func suggestionData(bot *Bot) DataSubject {
+	// each calls fn with the suggestions the user posted or reviewed.
+	each := func(userID string, fn func(suggestion *Suggestion) error) error {
+		it, ok := bot.Settings.(SettingsIterator)
+		if !ok {
+			return errors.New("the settings provider can't list keys")
+		}
+		guilds, err := it.Guilds()
+		if err != nil {
+			return err
+		}
+		for _, guildID := range guilds {
+			keys, err := it.Keys(guildID)
+			if err != nil {
+				return err
+			}
+			for _, key := range keys {
+				if !strings.HasPrefix(key, suggestionKeyPrefix) {
+					continue
+				}
+				suggestion := &Suggestion{}
+				if ok, err := GetJSON(bot.Settings, guildID, key, suggestion); err != nil {
+					return err
+				} else if !ok || (suggestion.AuthorID != userID && suggestion.ReviewerID != userID &&
+					!containsString(suggestion.Upvotes, userID) && !containsString(suggestion.Downvotes, userID)) {
+					continue
+				}
+				if err := fn(suggestion); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return &userData{
+		name: "suggestions",
+		export: func(userID string) (interface{}, error) {
+			var suggestions []*Suggestion
+			err := each(userID, func(suggestion *Suggestion) error {
+				suggestions = append(suggestions, suggestion)
+				return nil
+			})
+			if err != nil || len(suggestions) == 0 {
+				return nil, err
+			}
+			return suggestions, nil
+		},
+		delete: func(userID string) error {
+			bot.suggestions.lock.Lock()
+			defer bot.suggestions.lock.Unlock()
+			return each(userID, func(suggestion *Suggestion) error {
+				key := suggestionKeyPrefix + strconv.Itoa(suggestion.ID)
+				if suggestion.AuthorID == userID {
+					return bot.Settings.Delete(suggestion.GuildID, key)
+				}
+				if suggestion.ReviewerID == userID {
+					suggestion.ReviewerID = ""
+				}
+				suggestion.Upvotes = removeString(suggestion.Upvotes, userID)
+				suggestion.Downvotes = removeString(suggestion.Downvotes, userID)
+				return SetJSON(bot.Settings, suggestion.GuildID, key, suggestion)
+			})
+		},
+	}
+}
+
+// ReviewSuggestion sets the status of a suggestion and updates it's message, the author is sent a DM unless disabled.
+func (bot *Bot) ReviewSuggestion(guildID string, id int, status SuggestionStatus, reviewer *discordgo.User, reason string) (*Suggestion, error) {
+	bot.suggestions.lock.Lock()
+	defer bot.suggestions.lock.Unlock()
+	suggestion, err := bot.Suggestion(guildID, id)
+	if err != nil || suggestion == nil {
+		return nil, err
+	}
+	suggestion.Status = status
+	suggestion.Reason = reason
+	suggestion.ReviewerID = reviewer.ID
+	if err := SetJSON(bot.Settings, guildID, suggestionKeyPrefix+strconv.Itoa(id), suggestion); err != nil {
+		return nil, err
+	}
+
+	// The message might be gone, the status is still saved.
+	up, down := bot.suggestionVotes(suggestion)
+	author, err := bot.Session.User(suggestion.AuthorID)
+	if err != nil {
+		author = &discordgo.User{ID: suggestion.AuthorID, Username: "Unknown"}
+	}
+	// No components removes the buttons, the votes are in the embed from now on.
+	(&channelResponse{bot: bot, channelID: suggestion.ChannelID}).Edit(
+		&discordgo.Message{ID: suggestion.MessageID, ChannelID: suggestion.ChannelID},
+		&ResponseMessage{Embed: bot.SuggestionEmbed(suggestion, author, up, down), Components: []*Component{}})
+
+	if SuggestionConfig.GetBool(bot, guildID, "dm") {
+		if dm, err := bot.Session.UserChannelCreate(suggestion.AuthorID); err == nil {
+			locale := bot.LocaleFor(guildID, "")
+			bot.SendLocale(dm.ID, locale, "SUGGESTION_REVIEWED", suggestion.ID, locale.Get("SUGGESTION_STATUS_"+strings.ToUpper(string(status))), suggestionReason(locale, reason))
+		}
+	}
+	return suggestion, nil
+}
+
+// SuggestionEmbed builds the embed of a suggestion, up and down are the votes shown once it's reviewed.
+func (bot *Bot) SuggestionEmbed(suggestion *Suggestion, author *discordgo.User, up, down int) *discordgo.MessageEmbed {
+	locale := bot.LocaleFor(suggestion.GuildID, suggestion.ChannelID)
+	embed := NewEmbed().
+		SetAuthor(author.Username, author.AvatarURL("64")).
+		SetTitle(locale.Get("SUGGESTION_TITLE", suggestion.ID)).
+		SetDescription(suggestion.Content).
+		SetFooter(locale.Get("SUGGESTION_STATUS_" + strings.ToUpper(string(suggestion.Status))))
+	embed.Timestamp = suggestion.CreatedAt.Format(time.RFC3339)
+
+	switch suggestion.Status {
+	case SuggestionApproved:
+		embed.SetColor(0x43B581)
+	case SuggestionDenied:
+		embed.SetColor(0xF04747)
+	default:
+		return embed.SetColor(bot.Color).Truncate().Build()
+	}
+	embed.AddField(locale.Get("SUGGESTION_REASON"), suggestionReason(locale, suggestion.Reason)).
+		AddInlineField(locale.Get("SUGGESTION_REVIEWER"), "<@"+suggestion.ReviewerID+">").
+		AddInlineField(locale.Get("SUGGESTION_VOTES"), fmt.Sprintf("%s %d %s %d", SuggestionUpvote, up, SuggestionDownvote, down))
+	return embed.Truncate().Build()
+}
+
+func suggestionReason(locale *Language, reason string) string {
+	if reason == "" {
+		return locale.Get("SUGGESTION_NO_REASON")
+	}
+	return reason
+}
+
+// suggestionButtons builds the vote buttons with their counts and the buttons for staff to review a suggestion.
+func (bot *Bot) suggestionButtons(suggestion *Suggestion) []*Component {
+	locale := bot.LocaleFor(suggestion.GuildID, suggestion.ChannelID)
+	id := strconv.Itoa(suggestion.ID)
+	return []*Component{NewActionRow(
+		NewButton("suggestion:up:"+id, fmt.Sprintf("%s %d", SuggestionUpvote, len(suggestion.Upvotes)), ButtonSecondary),
+		NewButton("suggestion:down:"+id, fmt.Sprintf("%s %d", SuggestionDownvote, len(suggestion.Downvotes)), ButtonSecondary),
+		NewButton("suggestion:approve:"+id, locale.Get("SUGGESTION_APPROVE"), ButtonSuccess),
+		NewButton("suggestion:deny:"+id, locale.Get("SUGGESTION_DENY"), ButtonDanger),
+	)}
+}
+
+// suggestionVotes counts the votes of a suggestion, the reactions are only counted for suggestions posted
+// before the vote buttons.
+func (bot *Bot) suggestionVotes(suggestion *Suggestion) (up, down int) {
+	if len(suggestion.Upvotes) > 0 || len(suggestion.Downvotes) > 0 {
+		return len(suggestion.Upvotes), len(suggestion.Downvotes)
+	}
+	if msg, err := bot.Session.ChannelMessage(suggestion.ChannelID, suggestion.MessageID); err == nil {
+		return reactionVotes(msg)
+	}
+	return 0, 0
+}
+
+// reactionVotes counts the votes on a suggestion's message, not counting the bot's own reactions.
+func reactionVotes(msg *discordgo.Message) (up, down int) {
+	for _, reaction := range msg.Reactions {
+		count := reaction.Count
+		if reaction.Me {
+			count--
+		}
+		switch reaction.Emoji.Name {
+		case SuggestionUpvote:
+			up = count
+		case SuggestionDownvote:
+			down = count
+		}
+	}
+	return
+}
+
+// canReviewSuggestions checks if the command's author has the staff role or Manage Server.
+func canReviewSuggestions(ctx *CommandContext) bool {
+	if ctx.HasPermissions(discordgo.PermissionManageServer) {
+		return true
+	}
+	role := SuggestionConfig.Get(ctx.Bot, ctx.Message.GuildID, "staff")
+	return role != "" && ctx.Message.Member != nil && hasRole(ctx.Message.Member, role)
+}
+
+func suggestCommand(ctx *CommandContext) {
+	if SuggestionConfig.Get(ctx.Bot, ctx.Guild.ID, "channel") == "" {
+		ctx.ReplyLocale("COMMAND_SUGGEST_DISABLED", ctx.Prefix)
+		return
+	}
+	suggestion, err := ctx.Bot.Suggest(ctx.Guild.ID, ctx.Author, Escape(ctx.ArgString(0)))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.ReplyLocale("COMMAND_SUGGEST_POSTED", suggestion.ID, suggestion.ChannelID)
+}
+
+func suggestionCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+	id := ctx.Arg(1).AsInt()
+	var status SuggestionStatus
+	switch strings.ToLower(ctx.Arg(0).AsString()) {
+	case "show", "view":
+		suggestion, err := bot.Suggestion(ctx.Guild.ID, id)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		if suggestion == nil {
+			ctx.ReplyLocale("COMMAND_SUGGESTION_NOT_FOUND", id)
+			return
+		}
+		author, err := bot.Session.User(suggestion.AuthorID)
+		if err != nil {
+			author = &discordgo.User{ID: suggestion.AuthorID, Username: "Unknown"}
+		}
+		up, down := bot.suggestionVotes(suggestion)
+		ctx.ReplyEmbed(bot.SuggestionEmbed(suggestion, author, up, down))
+		return
+	case "approve", "accept":
+		status = SuggestionApproved
+	case "deny", "reject":
+		status = SuggestionDenied
+	default:
+		ctx.ReplyLocale("COMMAND_SUGGESTION_USAGE", ctx.Prefix)
+		return
+	}
+
+	if !canReviewSuggestions(ctx) {
+		ctx.ReplyLocale("COMMAND_SUGGESTION_NO_PERMISSION")
+		return
+	}
+	suggestion, err := bot.ReviewSuggestion(ctx.Guild.ID, id, status, ctx.Author, Escape(ctx.ArgString(2)))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if suggestion == nil {
+		ctx.ReplyLocale("COMMAND_SUGGESTION_NOT_FOUND", id)
+		return
+	}
+	ctx.ReplyLocale("COMMAND_SUGGESTION_REVIEWED", suggestion.ID, ctx.Locale.Get("SUGGESTION_STATUS_"+strings.ToUpper(string(status))))
+}
+
+// suggestionComponent handles the buttons of suggestions, the custom IDs are "suggestion:<up|down|approve|deny>:<id>".
+// Approving and denying asks for the reason in a modal, it's submit is "suggestion:<approved|denied>:<id>".
+func suggestionComponent(ctx *CommandContext) {
+	args := ctx.RawArgs
+	guildID := ctx.Message.GuildID
+	if len(args) < 2 || guildID == "" {
+		return
+	}
+	id, err := strconv.Atoi(args[1])
+	if err != nil {
+		return
+	}
+	bot := ctx.Bot
+	reply := func(key string, args ...interface{}) {
+		content, _ := ctx.localize(key, args...)
+		ctx.response().Send(&ResponseMessage{Content: content, Ephemeral: true})
+	}
+
+	switch action := args[0]; action {
+	case "up", "down":
+		suggestion, err := bot.VoteSuggestion(guildID, id, ctx.Author.ID, action == "up")
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		if suggestion == nil {
+			reply("COMMAND_SUGGESTION_NOT_FOUND", id)
+			return
+		}
+		if suggestion.Status != SuggestionPending {
+			reply("SUGGESTION_VOTING_CLOSED", id)
+			return
+		}
+		update := &ResponseMessage{Components: bot.suggestionButtons(suggestion)}
+		if msg := ctx.Interaction.Message; msg != nil && len(msg.Embeds) > 0 {
+			update.Embed = msg.Embeds[0]
+		}
+		ctx.UpdateMessage(update)
+	case "approve", "deny":
+		if !canReviewSuggestions(ctx) {
+			reply("COMMAND_SUGGESTION_NO_PERMISSION")
+			return
+		}
+		status := SuggestionApproved
+		if action == "deny" {
+			status = SuggestionDenied
+		}
+		title, _ := ctx.localize("SUGGESTION_REVIEW_TITLE", id, ctx.Locale.Get("SUGGESTION_STATUS_"+strings.ToUpper(string(status))))
+		label, _ := ctx.localize("SUGGESTION_REASON")
+		if err := ctx.ShowModal("suggestion:"+string(status)+":"+args[1], truncateTitle(title),
+			NewTextInput("reason", label, "").SetRequired(false)); err != nil {
+			ctx.Bot.ErrorHandler(ctx.Bot, &CommandError{Err: err, Context: ctx})
+		}
+	case string(SuggestionApproved), string(SuggestionDenied):
+		// Checked again, the modal could have been opened before the staff role was taken away.
+		if !canReviewSuggestions(ctx) {
+			reply("COMMAND_SUGGESTION_NO_PERMISSION")
+			return
+		}
+		status := SuggestionStatus(action)
+		suggestion, err := bot.ReviewSuggestion(guildID, id, status, ctx.Author, Escape(ctx.Interaction.Data.Value("reason")))
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		if suggestion == nil {
+			reply("COMMAND_SUGGESTION_NOT_FOUND", id)
+			return
+		}
+		reply("COMMAND_SUGGESTION_REVIEWED", suggestion.ID, ctx.Locale.Get("SUGGESTION_STATUS_"+strings.ToUpper(string(status))))
+	}
+}
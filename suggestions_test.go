@@ -0,0 +1,48 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"testing"
+)
+
+func TestSuggestionVoteButtons(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	bot.SetSettingsProvider(NewMemorySettings())
+	bot.EnableSuggestions()
+	calls := recordREST(bot)
+	bot.Settings.Set("g", SuggestionConfig.SettingsKey("channel"), "c")
+
+	if _, err := bot.Suggest("g", &discordgo.User{ID: "a", Username: "author"}, "More cats"); err != nil {
+		t.Fatal(err)
+	}
+	posted, _ := json.Marshal(calls()[0].Data)
+	if !strings.Contains(string(posted), `"suggestion:up:1"`) || !strings.Contains(string(posted), `"suggestion:approve:1"`) {
+		t.Errorf("Expected the suggestion to be posted with buttons but got %s", posted)
+	}
+
+	vote := func(userID, button string) {
+		dispatchInteraction(t, bot, `{"id":"i","application_id":"a","type":3,"token":"tok","channel_id":"c","guild_id":"g",
+			"member":{"user":{"id":"`+userID+`"}},"message":{"id":"m","channel_id":"c"},
+			"data":{"custom_id":"suggestion:`+button+`:1","component_type":2}}`)
+	}
+	vote("u1", "up")
+	vote("u2", "up")
+	vote("u1", "down")
+	vote("u2", "up")
+
+	suggestion, err := bot.Suggestion("g", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suggestion.Upvotes) != 0 || len(suggestion.Downvotes) != 1 || suggestion.Downvotes[0] != "u1" {
+		t.Errorf("Expected the votes to be moved and toggled but got %v up and %v down", suggestion.Upvotes, suggestion.Downvotes)
+	}
+	requests := calls()
+	last := requests[len(requests)-1]
+	updated, _ := json.Marshal(last.Data)
+	if last.Data["type"] != ResponseUpdateMessage || !strings.Contains(string(updated), SuggestionDownvote+" 1") {
+		t.Errorf("Expected the buttons to be updated with the counts but got %s", updated)
+	}
+}
@@ -0,0 +1,389 @@
+package sapphire
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TicketConfig is the per-guild config of support tickets, see bot.EnableTickets
+var TicketConfig = NewConfigSchema("tickets", "Support tickets, private channels between a member and the staff.").
+	Add("category", ConfigChannel, "", "Category ticket channels are created in.").
+	Add("staff", ConfigRole, "", "Role that can see and close all tickets.").
+	Add("log", ConfigChannel, "", "Channel the transcripts of closed tickets are sent to, leave empty to not keep transcripts.").
+	Add("format", ConfigString, "text", "Format of transcripts, text or html.").
+	Add("limit", ConfigInt, "1", "How many tickets a member can have open at once.").
+	SetValidator("format", func(bot *Bot, guildID, value string) error {
+		if value != "text" && value != "html" {
+			return errors.New("format must be text or html")
+		}
+		return nil
+	})
+
+// The guild settings keys tickets are stored under, followed by the channel ID and the last used number.
+const (
+	ticketKeyPrefix = "ticket."
+	ticketCountKey  = "tickets.count"
+)
+
+// TicketEmoji is shown on the button of ticket panels that opens a ticket.
+const TicketEmoji = "🎫"
+
+// ErrTicketLimit is returned by OpenTicket when the member already has as many tickets open as allowed.
+var ErrTicketLimit = errors.New("ticket limit reached")
+
+// Ticket is an open support ticket.
+type Ticket struct {
+	Number    int       `json:"number"` // Numbered per guild starting at 1.
+	GuildID   string    `json:"guild_id"`
+	ChannelID string    `json:"channel_id"`
+	OwnerID   string    `json:"owner_id"`
+	Subject   string    `json:"subject"`
+	OpenedAt  time.Time `json:"opened_at"`
+}
+
+type ticketTracker struct{}
+
+// EnableTickets loads the ticket command and the buttons of ticket panels and tickets, servers configure tickets
+// with TicketConfig. A ticket is a private channel only the member, the staff role and the bot can see. Closing it
+// saves a transcript to the log channel and deletes the channel. The bot needs the Manage Channels permission.
+func (bot *Bot) EnableTickets() *Bot {
+	if bot.tickets != nil {
+		return bot
+	}
+	bot.tickets = &ticketTracker{}
+	bot.AddConfigSchema(TicketConfig)
+	bot.AddDataSubject(ticketData(bot))
+	bot.AddHandler(ticketDeleteListener(bot))
+	bot.AddComponentHandler("ticket", ticketComponent)
+	bot.AddCommand(NewCommand("ticket", "General", ticketCommand).
+		SetDescription("Opens or closes a support ticket, staff can post a panel members open tickets from with a button.").
+		SetUsage("<action:string> [text:string...]").
+		SetGuildOnly(true).
+		SetCooldown(10))
+	return bot
+}
+
+// Ticket returns the ticket of a channel, nil if it's not a ticket.
+func (bot *Bot) Ticket(guildID, channelID string) *Ticket {
+	ticket := &Ticket{}
+	if ok, err := GetJSON(bot.Settings, guildID, ticketKeyPrefix+channelID, ticket); err != nil || !ok {
+		return nil
+	}
+	return ticket
+}
+
+// Tickets returns the open tickets of the guild, the settings provider must implement SettingsIterator.
+func (bot *Bot) Tickets(guildID string) ([]*Ticket, error) {
+	it, ok := bot.Settings.(SettingsIterator)
+	if !ok {
+		return nil, errors.New("the settings provider can't list keys")
+	}
+	keys, err := it.Keys(guildID)
+	if err != nil {
+		return nil, err
+	}
+	var tickets []*Ticket
+	for _, key := range keys {
+		if !strings.HasPrefix(key, ticketKeyPrefix) {
+			continue
+		}
+		if ticket := bot.Ticket(guildID, strings.TrimPrefix(key, ticketKeyPrefix)); ticket != nil {
+			tickets = append(tickets, ticket)
+		}
+	}
+	return tickets, nil
+}
+
+// OpenTicket creates a ticket channel for the member, returns ErrTicketLimit if they have too many open.
+func (bot *Bot) OpenTicket(guildID, userID, subject string) (*Ticket, error) {
+	// Guards opening tickets so the limit and numbering hold, across processes with a shared Locker.
+	unlock, err := bot.Lock("tickets:" + guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Without a way to list tickets the limit can't be checked, then it's not enforced.
+	if tickets, err := bot.Tickets(guildID); err == nil {
+		open := 0
+		for _, ticket := range tickets {
+			if ticket.OwnerID == userID {
+				open++
+			}
+		}
+		if open >= TicketConfig.GetInt(bot, guildID, "limit") {
+			return nil, ErrTicketLimit
+		}
+	}
+
+	number := 1
+	if last, ok, err := bot.Settings.Get(guildID, ticketCountKey); err != nil {
+		return nil, err
+	} else if ok {
+		n, _ := strconv.Atoi(last)
+		number = n + 1
+	}
+
+	visible := discordgo.PermissionReadMessages | discordgo.PermissionSendMessages | discordgo.PermissionReadMessageHistory |
+		discordgo.PermissionAttachFiles | discordgo.PermissionEmbedLinks
+	overwrites := []*discordgo.PermissionOverwrite{
+		{ID: guildID, Type: "role", Deny: discordgo.PermissionReadMessages},
+		{ID: userID, Type: "member", Allow: visible},
+		{ID: bot.Session.State.User.ID, Type: "member", Allow: visible | discordgo.PermissionManageChannels},
+	}
+	if staff := TicketConfig.Get(bot, guildID, "staff"); staff != "" {
+		overwrites = append(overwrites, &discordgo.PermissionOverwrite{ID: staff, Type: "role", Allow: visible})
+	}
+	channel, err := bot.Session.GuildChannelCreateComplex(guildID, discordgo.GuildChannelCreateData{
+		Name:                 fmt.Sprintf("ticket-%04d", number),
+		Type:                 discordgo.ChannelTypeGuildText,
+		Topic:                subject,
+		ParentID:             TicketConfig.Get(bot, guildID, "category"),
+		PermissionOverwrites: overwrites,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ticket := &Ticket{Number: number, GuildID: guildID, ChannelID: channel.ID, OwnerID: userID, Subject: subject, OpenedAt: time.Now()}
+	if err := bot.Settings.Set(guildID, ticketCountKey, strconv.Itoa(number)); err != nil {
+		return nil, err
+	}
+	if err := SetJSON(bot.Settings, guildID, ticketKeyPrefix+channel.ID, ticket); err != nil {
+		return nil, err
+	}
+	locale := bot.LocaleFor(guildID, channel.ID)
+	welcome := bot.localizeMessage(locale, "TICKET_WELCOME", "<@"+userID+">", Escape(subject), bot.ticketPrefix(guildID, channel.ID))
+	welcome.Components = []*Component{NewActionRow(NewButton("ticket:close", locale.Get("TICKET_CLOSE_BUTTON"), ButtonDanger))}
+	(&channelResponse{bot: bot, channelID: channel.ID}).Send(welcome)
+	return ticket, nil
+}
+
+// CloseTicket saves the transcript of a ticket to the log channel and deletes it's channel.
+func (bot *Bot) CloseTicket(ticket *Ticket, closer *discordgo.User, reason string) error {
+	if logChannel := TicketConfig.Get(bot, ticket.GuildID, "log"); logChannel != "" {
+		format := TranscriptFormat(TicketConfig.Get(bot, ticket.GuildID, "format"))
+		transcript, err := bot.ExportTranscript(ticket.ChannelID, TranscriptOptions{Format: format, Title: fmt.Sprintf("Ticket #%d", ticket.Number)})
+		if err != nil {
+			return err
+		}
+		locale := bot.LocaleFor(ticket.GuildID, logChannel)
+		if reason == "" {
+			reason = locale.Get("TICKET_NO_REASON")
+		}
+		content := locale.Get("TICKET_TRANSCRIPT", ticket.Number, ticket.OwnerID, closer.ID, Escape(reason))
+		if _, err := bot.Session.ChannelFileSendWithMessage(logChannel, content, transcript.Name, strings.NewReader(transcript.Content)); err != nil {
+			return err
+		}
+	}
+	if _, err := bot.Session.ChannelDelete(ticket.ChannelID); err != nil {
+		return err
+	}
+	return bot.Settings.Delete(ticket.GuildID, ticketKeyPrefix+ticket.ChannelID)
+}
+
+// ticketData is the data subject of the tickets a user opened.
+// Deleting forgets the user's open tickets, their channels are left to the staff to close.
+func ticketData(bot *Bot) DataSubject {
+	owned := func(userID string) ([]*Ticket, error) {
+		guilds, err := bot.settingsGuilds()
+		if err != nil {
+			return nil, err
+		}
+		var res []*Ticket
+		for _, guildID := range guilds {
+			tickets, err := bot.Tickets(guildID)
+			if err != nil {
+				return nil, err
+			}
+			for _, ticket := range tickets {
+				if ticket.OwnerID == userID {
+					res = append(res, ticket)
+				}
+			}
+		}
+		return res, nil
+	}
+	return &userData{
+		name: "tickets",
+		export: func(userID string) (interface{}, error) {
+			tickets, err := owned(userID)
+			if err != nil || len(tickets) == 0 {
+				return nil, err
+			}
+			return tickets, nil
+		},
+		delete: func(userID string) error {
+			tickets, err := owned(userID)
+			if err != nil {
+				return err
+			}
+			for _, ticket := range tickets {
+				if err := bot.Settings.Delete(ticket.GuildID, ticketKeyPrefix+ticket.ChannelID); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// ticketPrefix returns the command prefix used in a channel, for help texts sent outside of commands.
+func (bot *Bot) ticketPrefix(guildID, channelID string) string {
+	return bot.Prefix(bot, &discordgo.Message{GuildID: guildID, ChannelID: channelID}, false)
+}
+
+// canManageTicket checks if the member is the ticket's owner, staff or can manage channels.
+func canManageTicket(ctx *CommandContext, ticket *Ticket) bool {
+	if ticket.OwnerID == ctx.Author.ID || ctx.HasPermissions(discordgo.PermissionManageChannels) {
+		return true
+	}
+	staff := TicketConfig.Get(ctx.Bot, ctx.Message.GuildID, "staff")
+	return staff != "" && ctx.Message.Member != nil && hasRole(ctx.Message.Member, staff)
+}
+
+func ticketCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+	switch strings.ToLower(ctx.Arg(0).AsString()) {
+	case "open", "new":
+		// Editing the command runs it again, that shouldn't open another ticket.
+		var ticket *Ticket
+		ran, err := bot.Idempotent("ticket:"+ctx.Message.ID, func() (err error) {
+			ticket, err = bot.OpenTicket(ctx.Guild.ID, ctx.Author.ID, ctx.ArgString(1))
+			return err
+		})
+		if !ran {
+			return
+		}
+		if err == ErrTicketLimit {
+			ctx.ReplyLocale("TICKET_LIMIT", ctx.Locale.FormatCount(int64(TicketConfig.GetInt(bot, ctx.Guild.ID, "limit"))))
+			return
+		}
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_TICKET_OPENED", ticket.ChannelID)
+	case "close":
+		ticket := bot.Ticket(ctx.Guild.ID, ctx.Channel.ID)
+		if ticket == nil {
+			ctx.ReplyLocale("COMMAND_TICKET_NOT_TICKET")
+			return
+		}
+		if !canManageTicket(ctx, ticket) {
+			ctx.ReplyLocale("COMMAND_TICKET_NO_PERMISSION")
+			return
+		}
+		ctx.ReplyLocale("COMMAND_TICKET_CLOSING")
+		if err := bot.CloseTicket(ticket, ctx.Author, ctx.ArgString(1)); err != nil {
+			ctx.Error(err)
+		}
+	case "add", "remove":
+		ticket := bot.Ticket(ctx.Guild.ID, ctx.Channel.ID)
+		if ticket == nil {
+			ctx.ReplyLocale("COMMAND_TICKET_NOT_TICKET")
+			return
+		}
+		if !canManageTicket(ctx, ticket) {
+			ctx.ReplyLocale("COMMAND_TICKET_NO_PERMISSION")
+			return
+		}
+		user := ctx.GetFirstMentionedUser()
+		if user == nil {
+			ctx.ReplyLocale("COMMAND_TICKET_USAGE", ctx.Prefix)
+			return
+		}
+		var err error
+		if strings.ToLower(ctx.Arg(0).AsString()) == "add" {
+			err = ctx.Session.ChannelPermissionSet(ctx.Channel.ID, user.ID, "member",
+				discordgo.PermissionReadMessages|discordgo.PermissionSendMessages|discordgo.PermissionReadMessageHistory, 0)
+		} else {
+			err = ctx.Session.ChannelPermissionDelete(ctx.Channel.ID, user.ID)
+		}
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.React("✅")
+	case "panel":
+		if !ctx.HasPermissions(discordgo.PermissionManageServer) {
+			ctx.ReplyLocale("COMMAND_TICKET_PANEL_NO_PERMISSION")
+			return
+		}
+		text := ctx.ArgString(1)
+		if text == "" {
+			text = ctx.Locale.Get("TICKET_PANEL")
+		}
+		_, err := (&channelResponse{bot: bot, channelID: ctx.Channel.ID}).Send(&ResponseMessage{
+			Embed: NewEmbed().
+				SetTitle(ctx.Locale.Get("TICKET_PANEL_TITLE")).
+				SetDescription(text).
+				SetColor(bot.Color).
+				Build(),
+			Components: []*Component{NewActionRow(
+				NewButton("ticket:open", TicketEmoji+" "+ctx.Locale.Get("TICKET_OPEN_BUTTON"), ButtonPrimary),
+			)},
+		})
+		if err != nil {
+			ctx.Error(err)
+		}
+	default:
+		ctx.ReplyLocale("COMMAND_TICKET_USAGE", ctx.Prefix)
+	}
+}
+
+// ticketComponent handles the buttons of ticket panels and tickets, the custom IDs are "ticket:open" and "ticket:close".
+func ticketComponent(ctx *CommandContext) {
+	guildID := ctx.Message.GuildID
+	if len(ctx.RawArgs) == 0 || guildID == "" {
+		return
+	}
+	bot := ctx.Bot
+	reply := func(key string, args ...interface{}) {
+		content, _ := ctx.localize(key, args...)
+		ctx.response().Send(&ResponseMessage{Content: content, Ephemeral: true})
+	}
+
+	switch ctx.RawArgs[0] {
+	case "open":
+		ticket, err := bot.OpenTicket(guildID, ctx.Author.ID, "")
+		if err == ErrTicketLimit {
+			reply("TICKET_LIMIT", ctx.Locale.FormatCount(int64(TicketConfig.GetInt(bot, guildID, "limit"))))
+			return
+		}
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		reply("COMMAND_TICKET_OPENED", ticket.ChannelID)
+	case "close":
+		ticket := bot.Ticket(guildID, ctx.Channel.ID)
+		if ticket == nil {
+			reply("COMMAND_TICKET_NOT_TICKET")
+			return
+		}
+		if !canManageTicket(ctx, ticket) {
+			reply("COMMAND_TICKET_NO_PERMISSION")
+			return
+		}
+		ctx.ReplyLocale("COMMAND_TICKET_CLOSING")
+		if err := bot.CloseTicket(ticket, ctx.Author, ""); err != nil {
+			ctx.Error(err)
+		}
+	}
+}
+
+// ticketDeleteListener forgets tickets whose channel was deleted by hand.
+func ticketDeleteListener(bot *Bot) func(s *discordgo.Session, c *discordgo.ChannelDelete) {
+	return func(s *discordgo.Session, c *discordgo.ChannelDelete) {
+		if bot.Ticket(c.GuildID, c.ID) != nil {
+			if err := bot.Settings.Delete(c.GuildID, ticketKeyPrefix+c.ID); err != nil {
+				bot.ErrorHandler(bot, err)
+			}
+		}
+	}
+}
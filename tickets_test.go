@@ -0,0 +1,36 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"testing"
+)
+
+func TestTicketButtons(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	bot.SetSettingsProvider(NewMemorySettings())
+	bot.EnableTickets()
+	calls := recordREST(bot)
+	if err := SetJSON(bot.Settings, "g", ticketKeyPrefix+"t", &Ticket{Number: 1, GuildID: "g", ChannelID: "t", OwnerID: "u"}); err != nil {
+		t.Fatal(err)
+	}
+
+	click := func(button string) string {
+		dispatchInteraction(t, bot, `{"id":"i","application_id":"a","type":3,"token":"tok","channel_id":"c","guild_id":"g",
+			"member":{"user":{"id":"u"}},"message":{"id":"m","channel_id":"c"},"data":{"custom_id":"ticket:`+button+`","component_type":2}}`)
+		requests := calls()
+		answer := requests[len(requests)-1]
+		if answer.Method != "POST" || answer.Endpoint != "interactions/i/tok/callback?with_response=true" {
+			t.Fatalf("Expected the click to be answered but got %+v", requests)
+		}
+		data, _ := json.Marshal(answer.Data["data"])
+		return string(data)
+	}
+	if data := click("open"); !strings.Contains(data, "You can only have 1 tickets open") || !strings.Contains(data, `"flags":64`) {
+		t.Errorf("Expected an ephemeral reply about the ticket limit but got %s", data)
+	}
+	if data := click("close"); !strings.Contains(data, "not a ticket") || !strings.Contains(data, `"flags":64`) {
+		t.Errorf("Expected an ephemeral reply that the channel isn't a ticket but got %s", data)
+	}
+}
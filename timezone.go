@@ -0,0 +1,217 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"time"
+)
+
+// Settings keys timezones are stored under, guild timezones in the guild's scope and user timezones in the bot wide scope.
+const (
+	timezoneKey     = "timezone"
+	userTimezoneKey = "timezone.user."
+)
+
+// TimeLayouts are the layouts ParseTimeIn accepts, tried in order.
+var TimeLayouts = []string{
+	"2006-01-02 15:04",
+	"2006-01-02 3:04pm",
+	"2006-01-02",
+	"02/01/2006 15:04",
+	"02/01/2006",
+	"15:04",
+	"3:04pm",
+	"3pm",
+}
+
+// LoadTimezone loads an IANA timezone by name e.g Europe/Berlin, it also accepts UTC offsets like UTC+2 or GMT-5:30
+func LoadTimezone(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	upper := strings.ToUpper(name)
+	for _, base := range []string{"UTC", "GMT"} {
+		if !strings.HasPrefix(upper, base) || len(upper) == len(base) {
+			continue
+		}
+		offset := upper[len(base):]
+		sign := 1
+		switch offset[0] {
+		case '+':
+		case '-':
+			sign = -1
+		default:
+			return nil, fmt.Errorf("unknown timezone '%s'", name)
+		}
+		var hours, minutes int
+		if _, err := fmt.Sscanf(strings.Replace(offset[1:], ":", " ", 1), "%d %d", &hours, &minutes); err != nil {
+			if _, err := fmt.Sscanf(offset[1:], "%d", &hours); err != nil {
+				return nil, fmt.Errorf("unknown timezone '%s'", name)
+			}
+		}
+		if hours > 14 || minutes > 59 {
+			return nil, fmt.Errorf("unknown timezone '%s'", name)
+		}
+		return time.FixedZone(upper, sign*(hours*3600+minutes*60)), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone '%s'", name)
+	}
+	return loc, nil
+}
+
+// ParseTimeIn parses a date and/or time in one of the TimeLayouts in loc.
+// A time without a date is the next occurrence of that time after now.
+func ParseTimeIn(s string, loc *time.Location, now time.Time) (time.Time, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	now = now.In(loc)
+	for _, layout := range TimeLayouts {
+		t, err := time.ParseInLocation(layout, s, loc)
+		if err != nil {
+			continue
+		}
+		// Only a time of day, put it on today or tomorrow if that already passed.
+		if !strings.Contains(layout, "2006") {
+			t = time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+			if !t.After(now) {
+				t = t.AddDate(0, 0, 1)
+			}
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("**%s** is not a valid date or time.", s)
+}
+
+// SetDefaultTimezone sets the timezone used when neither the guild nor the user chose one. (default: UTC)
+func (bot *Bot) SetDefaultTimezone(loc *time.Location) *Bot {
+	bot.DefaultTimezone = loc
+	return bot
+}
+
+// GuildTimezone returns the guild's timezone or the default timezone if it didn't set one.
+func (bot *Bot) GuildTimezone(guildID string) *time.Location {
+	if guildID != "" {
+		if loc := bot.loadTimezoneSetting(guildID, timezoneKey); loc != nil {
+			return loc
+		}
+	}
+	return bot.DefaultTimezone
+}
+
+// SetGuildTimezone validates and stores the guild's timezone, an empty name resets it.
+func (bot *Bot) SetGuildTimezone(guildID, name string) error {
+	return bot.storeTimezoneSetting(guildID, timezoneKey, name)
+}
+
+// UserTimezone returns the user's own timezone, nil if they didn't set one.
+func (bot *Bot) UserTimezone(userID string) *time.Location {
+	return bot.loadTimezoneSetting("", userTimezoneKey+userID)
+}
+
+// SetUserTimezone validates and stores the user's timezone, an empty name resets it.
+func (bot *Bot) SetUserTimezone(userID, name string) error {
+	return bot.storeTimezoneSetting("", userTimezoneKey+userID, name)
+}
+
+// TimezoneFor returns the user's timezone if they set one, otherwise the guild's.
+func (bot *Bot) TimezoneFor(guildID, userID string) *time.Location {
+	if loc := bot.UserTimezone(userID); loc != nil {
+		return loc
+	}
+	return bot.GuildTimezone(guildID)
+}
+
+func (bot *Bot) loadTimezoneSetting(guildID, key string) *time.Location {
+	name, ok, err := bot.Settings.Get(guildID, key)
+	if err != nil || !ok {
+		return nil
+	}
+	loc, err := LoadTimezone(name)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+func (bot *Bot) storeTimezoneSetting(guildID, key, name string) error {
+	if name == "" {
+		return bot.Settings.Delete(guildID, key)
+	}
+	loc, err := LoadTimezone(name)
+	if err != nil {
+		return err
+	}
+	return bot.Settings.Set(guildID, key, loc.String())
+}
+
+// timezoneData is the data subject of the user timezones.
+func timezoneData(bot *Bot) DataSubject {
+	return &userData{
+		name: "timezone",
+		export: func(userID string) (interface{}, error) {
+			name, ok, err := bot.Settings.Get("", userTimezoneKey+userID)
+			if err != nil || !ok {
+				return nil, err
+			}
+			return name, nil
+		},
+		delete: func(userID string) error {
+			return bot.Settings.Delete("", userTimezoneKey+userID)
+		},
+	}
+}
+
+// Timezone returns the timezone of the command's author, falling back to the guild's.
+func (ctx *CommandContext) Timezone() *time.Location {
+	return ctx.Bot.TimezoneFor(ctx.Message.GuildID, ctx.Author.ID)
+}
+
+// FormatTime formats t in the author's timezone.
+func (ctx *CommandContext) FormatTime(t time.Time) string {
+	return t.In(ctx.Timezone()).Format("Mon, 02 Jan 2006 15:04 MST")
+}
+
+// ParseTime parses a date and/or time the author typed in their timezone, see ParseTimeIn
+func (ctx *CommandContext) ParseTime(s string) (time.Time, error) {
+	return ParseTimeIn(s, ctx.Timezone(), time.Now())
+}
+
+func timezoneCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+	server := ctx.HasFlag("server") && ctx.Guild != nil
+
+	if !ctx.Arg(0).IsProvided() {
+		if server {
+			ctx.ReplyLocale("COMMAND_TIMEZONE_SERVER", bot.GuildTimezone(ctx.Guild.ID).String())
+			return
+		}
+		ctx.ReplyLocale("COMMAND_TIMEZONE_CURRENT", ctx.Timezone().String(), ctx.FormatTime(time.Now()))
+		return
+	}
+
+	name := ctx.Arg(0).AsString()
+	if strings.EqualFold(name, "reset") {
+		name = ""
+	}
+
+	var err error
+	if server {
+		member := ctx.Member(ctx.Author.ID)
+		if member == nil || !PermissionsForMember(ctx.Guild, member).Has(discordgo.PermissionManageServer) {
+			ctx.ReplyLocale("COMMAND_TIMEZONE_NO_PERMISSION")
+			return
+		}
+		err = bot.SetGuildTimezone(ctx.Guild.ID, name)
+	} else {
+		err = bot.SetUserTimezone(ctx.Author.ID, name)
+	}
+	if err != nil {
+		ctx.ReplyLocale("COMMAND_TIMEZONE_INVALID", ctx.Arg(0).AsString())
+		return
+	}
+	if server {
+		ctx.ReplyLocale("COMMAND_TIMEZONE_SERVER_SET", bot.GuildTimezone(ctx.Guild.ID).String())
+		return
+	}
+	ctx.ReplyLocale("COMMAND_TIMEZONE_SET", ctx.Timezone().String(), ctx.FormatTime(time.Now()))
+}
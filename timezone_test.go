@@ -0,0 +1,49 @@
+package sapphire
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadTimezone(t *testing.T) {
+	tests := map[string]int{"UTC+2": 7200, "gmt-5:30": -19800, "UTC": 0}
+	now := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for name, offset := range tests {
+		loc, err := LoadTimezone(name)
+		if err != nil {
+			t.Errorf("LoadTimezone(%q) returned error: %v", name, err)
+			continue
+		}
+		if _, got := now.In(loc).Zone(); got != offset {
+			t.Errorf("LoadTimezone(%q): expected offset %d but got %d", name, offset, got)
+		}
+	}
+	for _, name := range []string{"UTC+20", "UTC*2", "Not/AZone"} {
+		if _, err := LoadTimezone(name); err == nil {
+			t.Errorf("Expected LoadTimezone(%q) to fail", name)
+		}
+	}
+}
+
+func TestParseTimeIn(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 7200)
+	now := time.Date(2021, time.March, 3, 16, 0, 0, 0, loc)
+	tests := map[string]time.Time{
+		"2021-04-01 09:30": time.Date(2021, time.April, 1, 9, 30, 0, 0, loc),
+		"18:00":            time.Date(2021, time.March, 3, 18, 0, 0, 0, loc),
+		"9am":              time.Date(2021, time.March, 4, 9, 0, 0, 0, loc),
+	}
+	for input, expected := range tests {
+		got, err := ParseTimeIn(input, loc, now)
+		if err != nil {
+			t.Errorf("ParseTimeIn(%q) returned error: %v", input, err)
+			continue
+		}
+		if !got.Equal(expected) {
+			t.Errorf("ParseTimeIn(%q): expected %v but got %v", input, expected, got)
+		}
+	}
+	if _, err := ParseTimeIn("tomorrow-ish", loc, now); err == nil {
+		t.Error("Expected ParseTimeIn to fail on invalid input")
+	}
+}
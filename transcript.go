@@ -0,0 +1,196 @@
+package sapphire
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/dustin/go-humanize"
+	"html"
+	"strings"
+	"time"
+)
+
+// MaxTranscriptMessages is the default limit of messages in a transcript, older ones are left out.
+const MaxTranscriptMessages = 5000
+
+// TranscriptFormat is the file format of a transcript.
+type TranscriptFormat string
+
+const (
+	TranscriptFormatText TranscriptFormat = "text" // Plain text, one line per message.
+	TranscriptFormatHTML TranscriptFormat = "html" // A standalone HTML page styled like the Discord client.
+)
+
+// Ext returns the file extension of the format.
+func (f TranscriptFormat) Ext() string {
+	if f == TranscriptFormatHTML {
+		return ".html"
+	}
+	return ".txt"
+}
+
+// TranscriptOptions are the options of ExportTranscript.
+type TranscriptOptions struct {
+	Format TranscriptFormat // (default: text)
+	Title  string           // Title at the top of the transcript. (default: the channel's name)
+	Limit  int              // Most messages to include, the newest are kept. (default: MaxTranscriptMessages)
+}
+
+// Transcript is an exported channel history.
+type Transcript struct {
+	Name     string // File name, the channel's name with the format's extension.
+	Content  string
+	Messages int // How many messages are in it.
+}
+
+// ExportTranscript fetches the history of a channel or thread and renders it as a transcript, e.g for ticket logs
+// or moderation archives. The bot needs the Read Message History permission, fetching is paginated 100 messages
+// at a time so large limits take a while.
+func (bot *Bot) ExportTranscript(channelID string, opts TranscriptOptions) (*Transcript, error) {
+	if opts.Limit < 1 {
+		opts.Limit = MaxTranscriptMessages
+	}
+	if opts.Format == "" {
+		opts.Format = TranscriptFormatText
+	}
+	name := channelID
+	if channel, err := bot.Session.State.Channel(channelID); err == nil {
+		name = channel.Name
+	} else if channel, err := bot.Session.Channel(channelID); err == nil {
+		name = channel.Name
+	}
+	if opts.Title == "" {
+		opts.Title = "#" + name
+	}
+
+	messages, err := bot.fetchHistory(channelID, opts.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return &Transcript{Name: name + opts.Format.Ext(), Content: RenderTranscript(messages, opts), Messages: len(messages)}, nil
+}
+
+// fetchHistory fetches up to limit of the newest messages in a channel, oldest first.
+func (bot *Bot) fetchHistory(channelID string, limit int) ([]*discordgo.Message, error) {
+	var messages []*discordgo.Message
+	err := bot.IterateMessages(channelID, HistoryOptions{Limit: limit}, func(msg *discordgo.Message) error {
+		messages = append(messages, msg)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
+
+// RenderTranscript renders messages, oldest first, in the format of the options.
+func RenderTranscript(messages []*discordgo.Message, opts TranscriptOptions) string {
+	if opts.Format == TranscriptFormatHTML {
+		return TranscriptHTML(opts.Title, messages)
+	}
+	text := TranscriptText(messages)
+	if opts.Title != "" {
+		text = fmt.Sprintf("%s\n%d messages, exported %s\n\n%s", opts.Title, len(messages), time.Now().UTC().Format("2006-01-02 15:04:05 UTC"), text)
+	}
+	return text
+}
+
+// transcriptTime formats the time of a message in transcripts.
+func transcriptTime(msg *discordgo.Message) string {
+	t, err := msg.Timestamp.Parse()
+	if err != nil {
+		return string(msg.Timestamp)
+	}
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// TranscriptText formats messages as a plain text transcript, one line per message followed by it's embeds and attachments.
+func TranscriptText(messages []*discordgo.Message) string {
+	var buf bytes.Buffer
+	for _, msg := range messages {
+		edited := ""
+		if msg.EditedTimestamp != "" {
+			edited = " (edited)"
+		}
+		fmt.Fprintf(&buf, "[%s] %s#%s: %s%s\n", transcriptTime(msg), msg.Author.Username, msg.Author.Discriminator, msg.Content, edited)
+		for _, embed := range msg.Embeds {
+			fmt.Fprintf(&buf, "    [Embed]")
+			if embed.Author != nil && embed.Author.Name != "" {
+				fmt.Fprintf(&buf, " %s -", embed.Author.Name)
+			}
+			fmt.Fprintf(&buf, " %s\n", strings.TrimSpace(embed.Title+" "+embed.Description))
+			for _, field := range embed.Fields {
+				fmt.Fprintf(&buf, "        %s: %s\n", field.Name, field.Value)
+			}
+			if embed.Footer != nil && embed.Footer.Text != "" {
+				fmt.Fprintf(&buf, "        %s\n", embed.Footer.Text)
+			}
+		}
+		for _, attachment := range msg.Attachments {
+			fmt.Fprintf(&buf, "    [Attachment] %s (%s) %s\n", attachment.Filename, humanize.Bytes(uint64(attachment.Size)), attachment.URL)
+		}
+	}
+	return buf.String()
+}
+
+// transcriptStyle is the stylesheet of HTML transcripts.
+const transcriptStyle = `body{font-family:sans-serif;background:#36393f;color:#dcddde;margin:16px}
+h1{color:#fff;font-size:20px}.info{color:#72767d;font-size:12px}
+.message{display:flex;margin:12px 0}.avatar{width:40px;height:40px;border-radius:50%;margin-right:12px}
+.author{font-weight:bold;color:#fff}.time,.edited{color:#72767d;font-size:12px;margin-left:6px}
+.content{white-space:pre-wrap;word-wrap:break-word}
+.embed{border-left:4px solid #202225;background:#2f3136;border-radius:4px;padding:8px 12px;margin-top:4px;max-width:520px}
+.embed-title{font-weight:bold;color:#fff}.embed-field{margin-top:4px}.embed-field b{display:block;color:#fff}
+.embed-footer{color:#72767d;font-size:12px;margin-top:4px}.attachment img{max-width:400px;max-height:300px;margin-top:4px}`
+
+// TranscriptHTML formats messages as a standalone HTML page.
+func TranscriptHTML(title string, messages []*discordgo.Message) string {
+	var buf bytes.Buffer
+	e := html.EscapeString
+	fmt.Fprintf(&buf, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>%s</style>\n</head>\n<body>\n", e(title), transcriptStyle)
+	fmt.Fprintf(&buf, "<h1>%s</h1>\n<div class=\"info\">%d messages, exported %s</div>\n", e(title), len(messages), time.Now().UTC().Format("2006-01-02 15:04:05 UTC"))
+	for _, msg := range messages {
+		buf.WriteString("<div class=\"message\">")
+		fmt.Fprintf(&buf, "<img class=\"avatar\" src=\"%s\" alt=\"\"><div>", e(msg.Author.AvatarURL("64")))
+		fmt.Fprintf(&buf, "<span class=\"author\" title=\"%s\">%s</span><span class=\"time\">%s</span>", e(msg.Author.ID), e(msg.Author.Username), transcriptTime(msg))
+		if msg.EditedTimestamp != "" {
+			buf.WriteString("<span class=\"edited\">(edited)</span>")
+		}
+		fmt.Fprintf(&buf, "<div class=\"content\">%s</div>", e(msg.Content))
+		for _, embed := range msg.Embeds {
+			fmt.Fprintf(&buf, "<div class=\"embed\" style=\"border-color:#%06x\">", embed.Color)
+			if embed.Author != nil && embed.Author.Name != "" {
+				fmt.Fprintf(&buf, "<div>%s</div>", e(embed.Author.Name))
+			}
+			if embed.Title != "" {
+				fmt.Fprintf(&buf, "<div class=\"embed-title\">%s</div>", e(embed.Title))
+			}
+			if embed.Description != "" {
+				fmt.Fprintf(&buf, "<div class=\"content\">%s</div>", e(embed.Description))
+			}
+			for _, field := range embed.Fields {
+				fmt.Fprintf(&buf, "<div class=\"embed-field\"><b>%s</b>%s</div>", e(field.Name), e(field.Value))
+			}
+			if embed.Image != nil && embed.Image.URL != "" {
+				fmt.Fprintf(&buf, "<div class=\"attachment\"><img src=\"%s\" alt=\"\"></div>", e(embed.Image.URL))
+			}
+			if embed.Footer != nil && embed.Footer.Text != "" {
+				fmt.Fprintf(&buf, "<div class=\"embed-footer\">%s</div>", e(embed.Footer.Text))
+			}
+			buf.WriteString("</div>")
+		}
+		for _, attachment := range msg.Attachments {
+			buf.WriteString("<div class=\"attachment\">")
+			if attachment.Width > 0 {
+				fmt.Fprintf(&buf, "<img src=\"%s\" alt=\"%s\"><br>", e(attachment.URL), e(attachment.Filename))
+			}
+			fmt.Fprintf(&buf, "<a href=\"%s\">%s</a> <span class=\"info\">%s</span></div>", e(attachment.URL), e(attachment.Filename), humanize.Bytes(uint64(attachment.Size)))
+		}
+		buf.WriteString("</div></div>\n")
+	}
+	buf.WriteString("</body>\n</html>\n")
+	return buf.String()
+}
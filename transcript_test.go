@@ -0,0 +1,23 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"testing"
+)
+
+func TestTranscript(t *testing.T) {
+	messages := []*discordgo.Message{
+		{Author: &discordgo.User{Username: "alice", Discriminator: "0001"}, Content: "hi <b>staff</b>", Timestamp: "2021-03-04T05:06:07+00:00"},
+		{Author: &discordgo.User{Username: "bob", Discriminator: "0002"}, Content: "hello", Timestamp: "2021-03-04T05:07:00+00:00",
+			Attachments: []*discordgo.MessageAttachment{{Filename: "log.txt", URL: "https://cdn.example/log.txt"}}},
+	}
+	text := TranscriptText(messages)
+	if !strings.HasPrefix(text, "[2021-03-04 05:06:07] alice#0001: hi <b>staff</b>\n") || !strings.Contains(text, "[Attachment] log.txt") || !strings.Contains(text, "https://cdn.example/log.txt") {
+		t.Errorf("Unexpected text transcript %q", text)
+	}
+	page := TranscriptHTML("Ticket #1", messages)
+	if strings.Contains(page, "<b>staff</b>") || !strings.Contains(page, "hi &lt;b&gt;staff&lt;/b&gt;") {
+		t.Error("Expected message content to be escaped in HTML transcripts")
+	}
+}
@@ -0,0 +1,219 @@
+package sapphire
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Translation is the result of a translation.
+type Translation struct {
+	Text   string // The translated text.
+	Source string // The language of the original text, the detected one if no source was given.
+	Target string
+}
+
+// Translator translates text, source is empty to detect the language. Language codes are ISO 639-1 e.g "en" or "de".
+type Translator interface {
+	Translate(text, source, target string) (*Translation, error)
+}
+
+// TranslateClient is the http client used by the built-in translators.
+var TranslateClient = &http.Client{Timeout: 10 * time.Second}
+
+// translateRequest posts body as JSON and decodes the JSON response into out.
+func translateRequest(endpoint string, headers map[string]string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	res, err := TranslateClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("Translation failed: %s %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// DeepLTranslator translates with the DeepL API, keys of free accounts end with ":fx" and use the free endpoint.
+type DeepLTranslator struct {
+	Key string
+	URL string // The API url. (default: https://api.deepl.com or https://api-free.deepl.com for free keys)
+}
+
+// Translate implements Translator
+func (d *DeepLTranslator) Translate(text, source, target string) (*Translation, error) {
+	endpoint := d.URL
+	if endpoint == "" {
+		endpoint = "https://api.deepl.com"
+		if strings.HasSuffix(d.Key, ":fx") {
+			endpoint = "https://api-free.deepl.com"
+		}
+	}
+	body := map[string]interface{}{"text": []string{text}, "target_lang": strings.ToUpper(target)}
+	if source != "" {
+		body["source_lang"] = strings.ToUpper(source)
+	}
+	var res struct {
+		Translations []struct {
+			DetectedSourceLanguage string `json:"detected_source_language"`
+			Text                   string `json:"text"`
+		} `json:"translations"`
+	}
+	err := translateRequest(strings.TrimSuffix(endpoint, "/")+"/v2/translate", map[string]string{"Authorization": "DeepL-Auth-Key " + d.Key}, body, &res)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Translations) == 0 {
+		return nil, errors.New("DeepL returned no translation")
+	}
+	t := res.Translations[0]
+	return &Translation{Text: t.Text, Source: strings.ToLower(t.DetectedSourceLanguage), Target: target}, nil
+}
+
+// GoogleTranslator translates with the Google Cloud Translation API (v2) using an API key.
+type GoogleTranslator struct {
+	Key string
+}
+
+// Translate implements Translator
+func (g *GoogleTranslator) Translate(text, source, target string) (*Translation, error) {
+	body := map[string]interface{}{"q": text, "target": target, "format": "text"}
+	if source != "" {
+		body["source"] = source
+	}
+	var res struct {
+		Data struct {
+			Translations []struct {
+				TranslatedText         string `json:"translatedText"`
+				DetectedSourceLanguage string `json:"detectedSourceLanguage"`
+			} `json:"translations"`
+		} `json:"data"`
+	}
+	endpoint := "https://translation.googleapis.com/language/translate/v2?key=" + url.QueryEscape(g.Key)
+	if err := translateRequest(endpoint, nil, body, &res); err != nil {
+		return nil, err
+	}
+	if len(res.Data.Translations) == 0 {
+		return nil, errors.New("Google returned no translation")
+	}
+	t := res.Data.Translations[0]
+	if t.DetectedSourceLanguage != "" {
+		source = t.DetectedSourceLanguage
+	}
+	return &Translation{Text: t.TranslatedText, Source: source, Target: target}, nil
+}
+
+// LibreTranslator translates with a LibreTranslate instance, which can be self-hosted.
+type LibreTranslator struct {
+	URL string // e.g https://libretranslate.com
+	Key string // Only needed if the instance requires one.
+}
+
+// Translate implements Translator
+func (l *LibreTranslator) Translate(text, source, target string) (*Translation, error) {
+	if source == "" {
+		source = "auto"
+	}
+	body := map[string]interface{}{"q": text, "source": source, "target": target, "format": "text"}
+	if l.Key != "" {
+		body["api_key"] = l.Key
+	}
+	var res struct {
+		TranslatedText   string `json:"translatedText"`
+		DetectedLanguage struct {
+			Language string `json:"language"`
+		} `json:"detectedLanguage"`
+	}
+	if err := translateRequest(strings.TrimSuffix(l.URL, "/")+"/translate", nil, body, &res); err != nil {
+		return nil, err
+	}
+	if res.DetectedLanguage.Language != "" {
+		source = res.DetectedLanguage.Language
+	}
+	return &Translation{Text: res.TranslatedText, Source: source, Target: target}, nil
+}
+
+// LoadTranslateCommands loads the translate command and the Translate message command using translator.
+// `translate <language> [text]` translates the text, or the message replied to if there is none.
+// The source language is detected unless given with --from=language.
+// The message command translates a message to the language of the user's Discord client, only they see it.
+func (bot *Bot) LoadTranslateCommands(translator Translator) *Bot {
+	bot.AddMessageCommand("Translate", func(ctx *CommandContext, msg *discordgo.Message) {
+		reply := &ResponseMessage{Ephemeral: true}
+		if strings.TrimSpace(msg.Content) == "" {
+			reply.Content, _ = ctx.localize("COMMAND_TRANSLATE_NOTHING")
+			ctx.response().Send(reply)
+			return
+		}
+		target := ctx.Interaction.Locale
+		if target == "" {
+			target = ctx.Locale.Name
+		}
+		translation, err := translator.Translate(msg.Content, "", strings.ToLower(strings.SplitN(target, "-", 2)[0]))
+		if err != nil {
+			reply.Content, _ = ctx.localize("COMMAND_TRANSLATE_FAILED", err.Error())
+			ctx.response().Send(reply)
+			return
+		}
+		reply.Embed = translationEmbed(ctx, translation).Build()
+		ctx.response().Send(reply)
+	})
+	return bot.AddCommand(NewCommand("translate", "Utility", func(ctx *CommandContext) {
+		target := strings.ToLower(ctx.Arg(0).AsString())
+		text := ctx.ArgString(1)
+		if text == "" {
+			// Replying to a message with just the language translates that message.
+			msg, err := ctx.ReferencedMessage()
+			if err != nil {
+				ctx.Error(err)
+				return
+			}
+			if msg != nil {
+				text = msg.Content
+			}
+		}
+		if strings.TrimSpace(text) == "" {
+			ctx.ReplyLocale("COMMAND_TRANSLATE_NOTHING")
+			return
+		}
+		translation, err := translator.Translate(text, strings.ToLower(ctx.Flag("from")), target)
+		if err != nil {
+			ctx.ReplyLocale("COMMAND_TRANSLATE_FAILED", err.Error())
+			return
+		}
+		ctx.BuildEmbed(translationEmbed(ctx, translation))
+	}).SetDescription("Translates text, or the message you reply to, to a language. Use --from=language to skip detection.").
+		SetUsage("<language:string> [text:string...]").
+		AddAliases("tr").
+		SetCooldown(5))
+}
+
+// translationEmbed shows the translated text with the languages in the footer.
+func translationEmbed(ctx *CommandContext, translation *Translation) *Embed {
+	return NewEmbed().
+		SetDescription(Escape(translation.Text)).
+		SetColor(ctx.Bot.Color).
+		SetFooter(ctx.Locale.Get("COMMAND_TRANSLATE_FOOTER", translation.Source, translation.Target)).
+		TruncateDescription()
+}
@@ -0,0 +1,60 @@
+package sapphire
+
+import (
+	"encoding/json"
+	"github.com/bwmarrin/discordgo"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLibreTranslator(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path != "/translate" || body["source"] != "auto" || body["target"] != "en" {
+			t.Errorf("Unexpected request to %s: %v", r.URL.Path, body)
+		}
+		w.Write([]byte(`{"translatedText": "Hello", "detectedLanguage": {"confidence": 90, "language": "de"}}`))
+	}))
+	defer server.Close()
+
+	translation, err := (&LibreTranslator{URL: server.URL + "/"}).Translate("Hallo", "", "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if translation.Text != "Hello" || translation.Source != "de" {
+		t.Errorf("Unexpected translation %+v", translation)
+	}
+}
+
+type upperTranslator struct{}
+
+func (upperTranslator) Translate(text, source, target string) (*Translation, error) {
+	return &Translation{Text: strings.ToUpper(text), Source: "en", Target: target}, nil
+}
+
+func TestTranslateMessageCommand(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	bot.LoadTranslateCommands(upperTranslator{})
+	calls := recordREST(bot)
+
+	commands := bot.ApplicationCommands("")
+	last := commands[len(commands)-1]
+	if last.Type != ApplicationCommandMessage || last.Name != "Translate" {
+		t.Errorf("Expected the Translate message command to be registered but got %+v", last)
+	}
+
+	dispatchInteraction(t, bot, `{"id":"i","application_id":"a","type":2,"token":"tok","channel_id":"c","locale":"de",
+		"user":{"id":"u"},"data":{"name":"Translate","type":3,"target_id":"m",
+		"resolved":{"messages":{"m":{"id":"m","channel_id":"c","content":"hello"}}}}}`)
+	requests := calls()
+	if len(requests) != 1 || requests[0].Method != "POST" || requests[0].Endpoint != "interactions/i/tok/callback?with_response=true" {
+		t.Fatalf("Expected one answer but got %+v", requests)
+	}
+	data, _ := json.Marshal(requests[0].Data["data"])
+	if !strings.Contains(string(data), "HELLO") || !strings.Contains(string(data), "en → de") || !strings.Contains(string(data), `"flags":64`) {
+		t.Errorf("Expected an ephemeral translation to the user's language but got %s", data)
+	}
+}
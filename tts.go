@@ -0,0 +1,100 @@
+package sapphire
+
+import (
+	"errors"
+)
+
+// TTSProvider turns text into speech, e.g with a cloud TTS service or a local engine piped through dca.
+// Language is the locale name of the guild e.g "en-US". Set it with bot.SetTTSProvider
+type TTSProvider interface {
+	Synthesize(text, language string) (*Clip, error)
+}
+
+// TTSProviderFunc is a function implementing TTSProvider
+type TTSProviderFunc func(text, language string) (*Clip, error)
+
+// Synthesize implements TTSProvider
+func (fn TTSProviderFunc) Synthesize(text, language string) (*Clip, error) {
+	return fn(text, language)
+}
+
+// ErrNoTTS is returned by bot.Speak when no TTSProvider is set.
+var ErrNoTTS = errors.New("no text-to-speech provider is set")
+
+// VoiceAnnounceConfig is the per-guild config of voice announcements, see bot.EnableVoiceAnnouncements
+var VoiceAnnounceConfig = NewConfigSchema("voiceannounce", "Spoken announcements of members joining and leaving voice channels.").
+	Add("enabled", ConfigBool, "false", "Whether joins and leaves are announced.").
+	Add("joins", ConfigBool, "true", "Whether members joining are announced.").
+	Add("leaves", ConfigBool, "true", "Whether members leaving are announced.")
+
+// SetTTSProvider sets the provider used to speak text in voice channels, see bot.Speak
+func (bot *Bot) SetTTSProvider(provider TTSProvider) *Bot {
+	bot.TTS = provider
+	return bot
+}
+
+// Speak synthesizes text in the guild's language and queues it to be played in a voice channel, see bot.PlayClip
+func (bot *Bot) Speak(guildID, channelID, text string) error {
+	if bot.TTS == nil {
+		return ErrNoTTS
+	}
+	clip, err := bot.TTS.Synthesize(text, bot.LocaleFor(guildID, channelID).Name)
+	if err != nil {
+		return err
+	}
+	return bot.PlayClip(guildID, channelID, clip)
+}
+
+// EnableVoiceAnnouncements speaks "X joined" and "X left" in voice channels once a server turns it on with
+// VoiceAnnounceConfig. It needs a TTSProvider and uses the voice tracker, see EnableVoiceTracking.
+func (bot *Bot) EnableVoiceAnnouncements() *Bot {
+	bot.EnableVoiceTracking()
+	bot.AddConfigSchema(VoiceAnnounceConfig)
+	bot.Subscribe(TopicUserJoinedVoice, func(event BusEvent) {
+		member := event.(*UserJoinedVoice).Member
+		bot.announceVoice(member, member.ChannelID, "joins", "VOICE_ANNOUNCE_JOINED")
+	})
+	bot.Subscribe(TopicUserMovedVoice, func(event BusEvent) {
+		moved := event.(*UserMovedVoice)
+		bot.announceVoice(moved.Member, moved.From, "leaves", "VOICE_ANNOUNCE_LEFT")
+		bot.announceVoice(moved.Member, moved.Member.ChannelID, "joins", "VOICE_ANNOUNCE_JOINED")
+	})
+	bot.Subscribe(TopicUserLeftVoice, func(event BusEvent) {
+		member := event.(*UserLeftVoice).Member
+		bot.announceVoice(member, member.ChannelID, "leaves", "VOICE_ANNOUNCE_LEFT")
+	})
+	return bot
+}
+
+// announceVoice speaks the announcement of a member in a channel if the server wants it and someone is there to hear it.
+func (bot *Bot) announceVoice(member VoiceMember, channelID, option, key string) {
+	if bot.TTS == nil || !VoiceAnnounceConfig.GetBool(bot, member.GuildID, "enabled") || !VoiceAnnounceConfig.GetBool(bot, member.GuildID, option) {
+		return
+	}
+	// Bots are skipped, including this one joining to speak.
+	m, err := bot.Session.State.Member(member.GuildID, member.UserID)
+	if err != nil || m.User.Bot {
+		return
+	}
+	if !bot.hasListeners(member.GuildID, channelID) {
+		return
+	}
+	name := m.Nick
+	if name == "" {
+		name = m.User.Username
+	}
+	text := bot.LocaleFor(member.GuildID, channelID).Get(key, name)
+	if err := bot.Speak(member.GuildID, channelID, text); err != nil && err != ErrClipQueueFull {
+		bot.ErrorHandler(bot, err)
+	}
+}
+
+// hasListeners checks if a member who isn't a bot is in a voice channel.
+func (bot *Bot) hasListeners(guildID, channelID string) bool {
+	for _, member := range bot.Voice.Members(guildID, channelID) {
+		if m, err := bot.Session.State.Member(guildID, member.UserID); err == nil && !m.User.Bot {
+			return true
+		}
+	}
+	return false
+}
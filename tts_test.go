@@ -0,0 +1,29 @@
+package sapphire
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"testing"
+)
+
+func TestSpeak(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	if err := bot.Speak("g", "c", "hello"); err != ErrNoTTS {
+		t.Errorf("Speak without a provider = %v", err)
+	}
+	failed := errors.New("engine down")
+	var language string
+	bot.SetTTSProvider(TTSProviderFunc(func(text, lang string) (*Clip, error) {
+		language = lang
+		return nil, failed
+	}))
+	if err := bot.Speak("g", "c", "hello"); err != failed {
+		t.Errorf("Speak = %v", err)
+	}
+	if language != bot.DefaultLocale.Name {
+		t.Errorf("language = %q", language)
+	}
+	if bot.QueuedClips("g") != 0 {
+		t.Error("a failed synthesis was queued")
+	}
+}
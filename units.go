@@ -0,0 +1,144 @@
+package sapphire
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// byteUnits are the byte size units, each one 1024 times the previous like Discord's own limits e.g 8MB.
+var byteUnits = []string{"B", "KB", "MB", "GB", "TB"}
+
+// countSuffixes are the shorthands ParseCount accepts.
+var countSuffixes = map[string]float64{"": 1, "K": 1e3, "M": 1e6, "B": 1e9}
+
+// durationUnits are the units ParseDuration accepts.
+var durationUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+}
+
+var durationPartRegex = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*([a-z]+)`)
+
+// splitUnit splits "1.5GB" into the number and the upper cased unit.
+func splitUnit(s string) (string, string) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-'
+	})
+	if i < 0 {
+		return s, ""
+	}
+	return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i:])
+}
+
+// ParseBytes parses a byte size like "1.5GB", "512kb", "10 MiB" or "100" for bytes.
+// Units are powers of 1024 whether they're written KB or KiB.
+func ParseBytes(s string) (int64, error) {
+	num, unit := splitUnit(s)
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a valid size", s)
+	}
+	unit = strings.Replace(unit, "I", "", 1)
+	if unit == "K" || unit == "M" || unit == "G" || unit == "T" {
+		unit += "B"
+	}
+	for i, name := range byteUnits {
+		if unit == name || (unit == "" && i == 0) {
+			return int64(n * math.Pow(1024, float64(i))), nil
+		}
+	}
+	return 0, fmt.Errorf("%q is not a valid size", s)
+}
+
+// ParsePercent parses a percentage like "12.5%" or "50" into a fraction, 0.125 and 0.5
+func ParsePercent(s string) (float64, error) {
+	n, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "%")), 64)
+	if err != nil || n < 0 || math.IsInf(n, 0) {
+		return 0, fmt.Errorf("%q is not a valid percentage", s)
+	}
+	return n / 100, nil
+}
+
+// ParseCount parses a count like "1,234", "1.5k" or "2M", k, m and b are thousands, millions and billions.
+func ParseCount(s string) (int64, error) {
+	num, suffix := splitUnit(strings.NewReplacer(",", "", "_", "").Replace(s))
+	mult, ok := countSuffixes[suffix]
+	n, err := strconv.ParseFloat(num, 64)
+	if !ok || err != nil || n < 0 || n*mult != math.Trunc(n*mult) || n*mult > math.MaxInt64 {
+		return 0, fmt.Errorf("%q is not a valid count", s)
+	}
+	return int64(n * mult), nil
+}
+
+// ParseDuration parses a duration like "1h30m", "2 days", "1.5h" or "1w", unlike time.ParseDuration days and weeks work.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	parts := durationPartRegex.FindAllStringSubmatchIndex(s, -1)
+	var d time.Duration
+	end := 0
+	for _, part := range parts {
+		unit, ok := durationUnits[s[part[4]:part[5]]]
+		if !ok || strings.TrimSpace(s[end:part[0]]) != "" {
+			return 0, fmt.Errorf("%q is not a valid duration", s)
+		}
+		n, _ := strconv.ParseFloat(s[part[2]:part[3]], 64)
+		d += time.Duration(n * float64(unit))
+		end = part[1]
+	}
+	if len(parts) == 0 || strings.TrimSpace(s[end:]) != "" || d <= 0 {
+		return 0, fmt.Errorf("%q is not a valid duration", s)
+	}
+	return d, nil
+}
+
+// formatNumber formats n with at most decimals decimal places using the locale's separators.
+func (l *Language) formatNumber(n float64, decimals int) string {
+	str := strconv.FormatFloat(n, 'f', decimals, 64)
+	if strings.Contains(str, ".") {
+		str = strings.TrimRight(strings.TrimRight(str, "0"), ".")
+	}
+	sign := ""
+	if strings.HasPrefix(str, "-") {
+		sign, str = "-", str[1:]
+	}
+	whole, fraction := str, ""
+	if i := strings.IndexByte(str, '.'); i >= 0 {
+		whole, fraction = str[:i], str[i+1:]
+	}
+	group := l.GetDefault("NUMBER_GROUP_SEPARATOR", ",")
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + group + whole[i:]
+	}
+	if fraction != "" {
+		whole += l.GetDefault("NUMBER_DECIMAL_SEPARATOR", ".") + fraction
+	}
+	return sign + whole
+}
+
+// FormatCount formats a count with the locale's digit grouping e.g 1,234,567
+func (l *Language) FormatCount(n int64) string {
+	return l.formatNumber(float64(n), 0)
+}
+
+// FormatBytes formats a byte size in the largest fitting unit e.g 1.5 GB, see ParseBytes
+func (l *Language) FormatBytes(n int64) string {
+	size, unit := float64(n), 0
+	for math.Abs(size) >= 1024 && unit < len(byteUnits)-1 {
+		size /= 1024
+		unit++
+	}
+	return l.formatNumber(size, 1) + " " + byteUnits[unit]
+}
+
+// FormatPercent formats a fraction as a percentage e.g 0.125 as 12.5%
+func (l *Language) FormatPercent(fraction float64) string {
+	return l.GetDefault("NUMBER_PERCENT", l.formatNumber(fraction*100, 1)+"%", l.formatNumber(fraction*100, 1))
+}
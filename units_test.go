@@ -0,0 +1,57 @@
+package sapphire
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnits(t *testing.T) {
+	for raw, want := range map[string]int64{"100": 100, "1.5GB": 1610612736, "512kb": 524288, "10 MiB": 10485760, "2K": 2048} {
+		if got, err := ParseBytes(raw); err != nil || got != want {
+			t.Errorf("ParseBytes(%q) = %d, %v, want %d", raw, got, err, want)
+		}
+	}
+	for raw, want := range map[string]int64{"1,234": 1234, "1.5k": 1500, "2M": 2000000} {
+		if got, err := ParseCount(raw); err != nil || got != want {
+			t.Errorf("ParseCount(%q) = %d, %v, want %d", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"", "GB", "-1MB", "5 parsecs"} {
+		if _, err := ParseBytes(raw); err == nil {
+			t.Errorf("ParseBytes(%q) should fail", raw)
+		}
+	}
+	if _, err := ParseCount("1.5"); err == nil {
+		t.Errorf("ParseCount should reject fractions")
+	}
+	if got, err := ParsePercent("12.5%"); err != nil || got != 0.125 {
+		t.Errorf("ParsePercent(12.5%%) = %v, %v", got, err)
+	}
+
+	if got := English.FormatBytes(1610612736); got != "1.5 GB" {
+		t.Errorf("FormatBytes = %q", got)
+	}
+	if got := English.FormatCount(-1234567); got != "-1,234,567" {
+		t.Errorf("FormatCount = %q", got)
+	}
+	german := NewLanguage("de-DE").Set("NUMBER_GROUP_SEPARATOR", ".").Set("NUMBER_DECIMAL_SEPARATOR", ",").Set("NUMBER_PERCENT", "%s %%")
+	if got := german.FormatBytes(1536); got != "1,5 KB" {
+		t.Errorf("FormatBytes = %q", got)
+	}
+	if got := german.FormatPercent(0.125); got != "12,5 %" {
+		t.Errorf("FormatPercent = %q", got)
+	}
+	if got := NewLanguage("xx").FormatPercent(0.5); got != "50%" {
+		t.Errorf("FormatPercent without keys = %q", got)
+	}
+	for raw, want := range map[string]time.Duration{"90s": 90 * time.Second, "1h30m": 90 * time.Minute, "2 days": 48 * time.Hour, "1.5h": 90 * time.Minute, "1w": 7 * 24 * time.Hour} {
+		if got, err := ParseDuration(raw); err != nil || got != want {
+			t.Errorf("ParseDuration(%q) = %s, %v, want %s", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"", "10", "5 parsecs", "1h and 2m", "0s"} {
+		if _, err := ParseDuration(raw); err == nil {
+			t.Errorf("ParseDuration(%q) should fail", raw)
+		}
+	}
+}
@@ -2,15 +2,22 @@ package sapphire
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type UsageTag struct {
-	Name     string // Name of the tag, e.g for <reason:string> the name is reason.
-	Type     string // Type of the tag, e.g for <reason:string> the type is string.
-	Rest     bool   // If this is rest of the arguments, e.g for <reason:string...> it is true.
-	Required bool   // If this argument is required, e.g <name> is required but [name] is not.
+	Name     string   // Name of the tag, e.g for <reason:string> the name is reason.
+	Type     string   // Type of the tag, e.g for <reason:string> the type is string.
+	Rest     bool     // If this is rest of the arguments, e.g for <reason:string...> it is true.
+	Required bool     // If this argument is required, e.g <name> is required but [name] is not.
+	Default  string   // Default for optionals, e.g for [user:member=author] it is author. See ResolveDefault
+	Min      *float64 // Minimum of the range, e.g for <amount:int{1,100}> it is 1. Lengths for strings and seconds for durations.
+	Max      *float64 // Maximum of the range, e.g for <amount:int{1,100}> it is 100, nil if it's unbounded like {1,}
+	parser   ArgumentParser
 }
 
 // Parse a usage string into tags.
@@ -70,22 +77,107 @@ func ParseUsage(usage string) ([]*UsageTag, error) {
 	}
 	// Now that we know enough about the tags and how many are there we can validate rest args.
 	for i, tag := range tags {
-		if strings.HasSuffix(tag.Type, "...") {
+		if idx := strings.Index(tag.Type, "="); idx != -1 {
+			tag.Default = tag.Type[idx+1:]
+			tag.Type = tag.Type[:idx]
+			// Allow both [count:int...=10] and [count:int=10...]
+			if strings.HasSuffix(tag.Default, "...") {
+				tag.Default = strings.TrimSuffix(tag.Default, "...")
+				tag.Type += "..."
+			}
+			if tag.Required {
+				return tags, errors.New("Only optional tags can have a default.")
+			}
+		}
+		// Allow both [reason:string...] and [reason:...string]
+		if strings.HasSuffix(tag.Type, "...") || strings.HasPrefix(tag.Type, "...") {
 			if i != len(tags)-1 {
 				return tags, errors.New("Rest parameters can only appear last.")
 			}
-			tag.Type = strings.TrimSuffix(tag.Type, "...")
+			tag.Type = strings.TrimPrefix(strings.TrimSuffix(tag.Type, "..."), "...")
 			tag.Rest = true
 		}
+		if idx := strings.Index(tag.Type, "{"); idx != -1 {
+			text := tag.Type[idx:]
+			tag.Type = tag.Type[:idx]
+			if err := parseRange(tag, text); err != nil {
+				return tags, err
+			}
+		}
 	}
 	return tags, nil
 }
 
+// parseRange parses a range like {1,100}, {1,} or {,100} into the tag's Min and Max.
+// Bounds of duration tags are durations e.g {1m,1d}
+func parseRange(tag *UsageTag, text string) error {
+	bounds := strings.Split(strings.TrimSuffix(strings.TrimPrefix(text, "{"), "}"), ",")
+	if !strings.HasSuffix(text, "}") || len(bounds) != 2 {
+		return fmt.Errorf("The range of '%s' must look like {min,max}.", tag.Name)
+	}
+	limits := make([]*float64, 2)
+	for i, bound := range bounds {
+		if bound == "" {
+			continue
+		}
+		var n float64
+		var err error
+		if tag.Type == "duration" {
+			var d time.Duration
+			d, err = ParseDuration(bound)
+			n = d.Seconds()
+		} else {
+			n, err = strconv.ParseFloat(bound, 64)
+		}
+		if err != nil {
+			return fmt.Errorf("The range of '%s' has an invalid bound '%s'.", tag.Name, bound)
+		}
+		limits[i] = &n
+	}
+	tag.Min, tag.Max = limits[0], limits[1]
+	return nil
+}
+
 // HumanizeUsageRegex is the regexp used for HuamnizeUsage
-var HumanizeUsageRegex = regexp.MustCompile("(<|\\[)(\\w+):[^.]+?(\\.\\.\\.)?(>|\\])")
+var HumanizeUsageRegex = regexp.MustCompile("(<|\\[)(\\w+):(\\.\\.\\.)?[^>\\]]*?(\\.\\.\\.)?(>|\\])")
 
 // HumanizeUsage removes the unneccessary types and shows only the names.
 // e.g <hello:string> <user:user> [rest:int...] => <hello> <user> [rest...]
 func HumanizeUsage(usage string) string {
-	return HumanizeUsageRegex.ReplaceAllString(usage, "$1$2$3$4")
+	return HumanizeUsageRegex.ReplaceAllString(usage, "$1$2$3$4$5")
+}
+
+// ArgumentSpec is the compiled form of a command's usage string.
+// It is built once when the usage is set so the command handler doesn't have to re-examine the tags on every message.
+// It is also useful for rendering help or generating option lists for other frontends.
+type ArgumentSpec struct {
+	Usage     string      // The source usage string.
+	Tags      []*UsageTag // The parsed tags, each one has it's argument parser resolved.
+	Required  int         // Amount of required tags.
+	Rest      bool        // Wether the last tag consumes the rest of the arguments.
+	Humanized string      // The usage string with types stripped, see HumanizeUsage
+}
+
+// CompileUsage parses the usage string and resolves the argument parser for each tag.
+// Unlike ParseUsage this also fails for unknown argument types.
+func CompileUsage(usage string) (*ArgumentSpec, error) {
+	tags, err := ParseUsage(usage)
+	if err != nil {
+		return nil, err
+	}
+	spec := &ArgumentSpec{Usage: usage, Tags: tags, Humanized: HumanizeUsage(usage)}
+	for _, tag := range tags {
+		parser, ok := argumentParsers[tag.Type]
+		if !ok {
+			return nil, fmt.Errorf("The argument type '%s' is invalid.", tag.Type)
+		}
+		tag.parser = parser
+		if tag.Required {
+			spec.Required++
+		}
+		if tag.Rest {
+			spec.Rest = true
+		}
+	}
+	return spec, nil
 }
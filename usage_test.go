@@ -2,7 +2,11 @@ package sapphire
 
 import (
 	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestParseUsage(t *testing.T) {
@@ -38,3 +42,113 @@ func TestParseUsage(t *testing.T) {
 		t.Errorf("Expected HumanizeUsage(\"%s\") to return \"%s\" but got \"%s\"", tag, expect, res)
 	}
 }
+
+func TestCompileUsage(t *testing.T) {
+	spec, err := CompileUsage("<name:user> [count:int] [rest:string...]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Required != 1 {
+		t.Errorf("Expected 1 required tag but got %d", spec.Required)
+	}
+	if !spec.Rest {
+		t.Error("Expected the spec to have a rest tag")
+	}
+	for _, tag := range spec.Tags {
+		if tag.parser == nil {
+			t.Errorf("Expected tag %s to have a parser resolved", tag.Name)
+		}
+	}
+	if spec.Humanized != "<name> [count] [rest...]" {
+		t.Errorf("Unexpected humanized usage \"%s\"", spec.Humanized)
+	}
+
+	spec, err = CompileUsage("[user:member=author] [channel:channel=current] [count:int=10...]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, def := range []string{"author", "current", "10"} {
+		if spec.Tags[i].Default != def {
+			t.Errorf("Expected tag %s to default to %s but got %s", spec.Tags[i].Name, def, spec.Tags[i].Default)
+		}
+	}
+	if spec.Tags[2].Type != "int" || !spec.Tags[2].Rest {
+		t.Errorf("Expected count to be a rest int but got %s", spec.Tags[2].Type)
+	}
+
+	if _, err := CompileUsage("<name:nonexistent>"); err == nil {
+		t.Error("Expected an error for an unknown argument type")
+	}
+}
+
+func TestTypedArguments(t *testing.T) {
+	spec, err := CompileUsage("<amount:int{1,100}> [wait:duration{1m,}=10m] [reason:...string{,5}]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount, reason := spec.Tags[0], spec.Tags[2]
+	if *amount.Min != 1 || *amount.Max != 100 || *spec.Tags[1].Min != 60 || spec.Tags[1].Max != nil {
+		t.Errorf("Unexpected ranges %v %v", amount, spec.Tags[1])
+	}
+	if reason.Type != "string" || !reason.Rest || reason.Min != nil || *reason.Max != 5 {
+		t.Errorf("Unexpected reason tag %+v", reason)
+	}
+	if humanized := HumanizeUsage(spec.Usage); humanized != "<amount> [wait] [reason...]" {
+		t.Errorf("Unexpected humanized usage %s", humanized)
+	}
+
+	ctx := &CommandContext{}
+	expectError := func(tag *UsageTag, raw, key string) {
+		_, err := ParseArgument(ctx, tag, raw)
+		if argErr, ok := err.(*ArgumentError); !ok || argErr.Key != key {
+			t.Errorf("Expected %s for %q but got %v", key, raw, err)
+		}
+	}
+	expectError(amount, "0", "ARGUMENT_TOO_SMALL")
+	expectError(amount, "101", "ARGUMENT_TOO_LARGE")
+	expectError(amount, "ten", "ARGUMENT_INT_INVALID")
+	expectError(spec.Tags[1], "30s", "ARGUMENT_TOO_SMALL")
+	expectError(reason, "longer", "ARGUMENT_TOO_LARGE_LENGTH")
+	if arg, err := ParseArgument(ctx, spec.Tags[1], "1h30m"); err != nil || arg.AsDuration() != 90*time.Minute {
+		t.Errorf("Expected 1h30m to parse but got %v", err)
+	}
+	if err := NewArgumentError("ARGUMENT_TOO_LARGE", "amount", "100"); err.Error() != "**amount** must be at most 100." {
+		t.Errorf("Unexpected message %s", err.Error())
+	}
+
+	RegisterArgumentType("hex", func(ctx *CommandContext, tag *UsageTag, raw string) (*Argument, error) {
+		n, err := strconv.ParseInt(strings.TrimPrefix(raw, "#"), 16, 32)
+		if err != nil {
+			return nil, NewArgumentError("ARGUMENT_INT_INVALID", tag.Name)
+		}
+		return NewArgument(int(n)), nil
+	})
+	defer delete(argumentParsers, "hex")
+	spec, err = CompileUsage("<color:hex{,16777215}>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if arg, err := ParseArgument(ctx, spec.Tags[0], "#ff0000"); err != nil || arg.Value() != 0xff0000 {
+		t.Errorf("Expected the custom type to parse but got %v", err)
+	}
+}
+
+func TestRequiredAfterOptional(t *testing.T) {
+	bot := New(&discordgo.Session{})
+	calls := recordREST(bot)
+	cmd := NewCommand("give", "General", func(ctx *CommandContext) {}).SetUsage("[count:int] <item:string>").
+		SetEditable(false)
+	ctx := &CommandContext{Bot: bot, Command: cmd, Session: bot.Session, Message: &discordgo.Message{},
+		Channel: &discordgo.Channel{ID: "c"}, Author: &discordgo.User{ID: "u"}, Locale: bot.DefaultLocale,
+		RawArgs: []string{"5"}}
+	if ctx.ParseArgs() {
+		t.Error("Expected the missing required argument after an optional one to fail")
+	}
+	if requests := calls(); len(requests) != 1 || requests[0].Method != "POST" || requests[0].Endpoint != "channels/c/messages" {
+		t.Errorf("Expected the missing argument to be reported in the channel but got %+v", requests)
+	}
+	ctx.RawArgs = []string{"5", "apple"}
+	if !ctx.ParseArgs() || ctx.Arg(1).AsString() != "apple" {
+		t.Error("Expected both arguments to parse")
+	}
+}
@@ -1,14 +1,74 @@
 package sapphire
 
 import (
+	"fmt"
 	"regexp"
+	"strconv"
+	"time"
 )
 
 var escapeReg = regexp.MustCompile("@(everyone|here)")
 
+// snowflakeMentionRegex matches an ID alone or in a user, member, role or channel mention.
+var snowflakeMentionRegex = regexp.MustCompile(`^(?:<(?:@[!&]?|#))?(\d{17,20})>?$`)
+
+// discordEpoch is the first millisecond of 2015, Discord snowflakes count from it.
+const discordEpoch = 1420070400000
+
 // Utilities to help in bot creation.
 
 // Escape escapes @everyone/@here mentions by adding an invisible character to avoid the ping.
 func Escape(input string) string {
 	return escapeReg.ReplaceAllString(input, "@\u200b$1")
 }
+
+// SnowflakeTime returns the creation time of a Discord ID, the zero time if it's not a valid ID.
+func SnowflakeTime(id string) time.Time {
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	ms := int64(n>>22) + discordEpoch
+	return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond))
+}
+
+// TimeSnowflake returns the smallest ID created at t, useful as a before or after cursor for fetching messages by time.
+func TimeSnowflake(t time.Time) string {
+	ms := t.UnixNano()/int64(time.Millisecond) - discordEpoch
+	if ms < 0 {
+		ms = 0
+	}
+	return strconv.FormatUint(uint64(ms)<<22, 10)
+}
+
+// IsSnowflake checks if id is a Discord ID, a number created after Discord's epoch and not in the future.
+func IsSnowflake(id string) bool {
+	if len(id) < 17 || len(id) > 20 {
+		return false
+	}
+	created := SnowflakeTime(id)
+	return !created.IsZero() && created.Before(time.Now().Add(time.Minute))
+}
+
+// ParseSnowflake returns the ID of raw, an ID or a user, member, role or channel mention.
+func ParseSnowflake(raw string) (string, error) {
+	match := snowflakeMentionRegex.FindStringSubmatch(raw)
+	if match == nil || !IsSnowflake(match[1]) {
+		return "", fmt.Errorf("**%s** is not a valid ID.", raw)
+	}
+	return match[1], nil
+}
+
+// SnowflakeBefore returns a before cursor matching every ID created before t.
+func SnowflakeBefore(t time.Time) string {
+	return TimeSnowflake(t)
+}
+
+// SnowflakeAfter returns an after cursor matching every ID created at t or later.
+func SnowflakeAfter(t time.Time) string {
+	n, _ := strconv.ParseUint(TimeSnowflake(t), 10, 64)
+	if n == 0 {
+		return "0"
+	}
+	return strconv.FormatUint(n-1, 10)
+}
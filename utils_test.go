@@ -2,6 +2,7 @@ package sapphire
 
 import (
 	"testing"
+	"time"
 )
 
 func TestEscape(t *testing.T) {
@@ -15,3 +16,37 @@ func TestEscape(t *testing.T) {
 		t.Error("Escape didn't return the expectd output for @here")
 	}
 }
+
+func TestSnowflakeTime(t *testing.T) {
+	created := SnowflakeTime("175928847299117063")
+	if created.UTC().Format(time.RFC3339) != "2016-04-30T11:18:25Z" {
+		t.Errorf("Unexpected snowflake time %s", created.UTC())
+	}
+	if !SnowflakeTime(TimeSnowflake(created)).Equal(created.Truncate(time.Millisecond)) {
+		t.Error("Expected TimeSnowflake to round trip")
+	}
+	if !SnowflakeTime("not an id").IsZero() {
+		t.Error("Expected invalid IDs to return the zero time")
+	}
+}
+
+func TestParseSnowflake(t *testing.T) {
+	for _, raw := range []string{"175928847299117063", "<@175928847299117063>", "<@!175928847299117063>", "<@&175928847299117063>", "<#175928847299117063>"} {
+		if id, err := ParseSnowflake(raw); err != nil || id != "175928847299117063" {
+			t.Errorf("ParseSnowflake(%q) = %q, %v", raw, id, err)
+		}
+	}
+	future := TimeSnowflake(time.Now().Add(time.Hour))
+	for _, raw := range []string{"", "123", "<@abc>", "18446744073709551616", future} {
+		if _, err := ParseSnowflake(raw); err == nil {
+			t.Errorf("Expected %q to be invalid", raw)
+		}
+	}
+	at := SnowflakeTime("175928847299117063")
+	if SnowflakeBefore(at) >= "175928847299117063" || SnowflakeAfter(at) >= "175928847299117063" {
+		t.Errorf("Expected the cursors to be before the ID created at that time")
+	}
+	if SnowflakeAfter(at.Add(time.Millisecond)) < "175928847299117063" {
+		t.Errorf("Expected the after cursor of a later time to be after the ID")
+	}
+}
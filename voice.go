@@ -0,0 +1,216 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Topics of the voice bus events.
+const (
+	TopicUserJoinedVoice = "voice.joined"
+	TopicUserMovedVoice  = "voice.moved"
+	TopicUserLeftVoice   = "voice.left"
+)
+
+// VoiceMember is a member connected to a voice channel.
+type VoiceMember struct {
+	GuildID     string
+	UserID      string
+	ChannelID   string
+	JoinedAt    time.Time // When they joined the channel.
+	ConnectedAt time.Time // When they connected to voice, moving between channels doesn't change it.
+	SelfMute    bool
+	SelfDeaf    bool
+	Mute        bool // Server muted.
+	Deaf        bool // Server deafened.
+}
+
+// UserJoinedVoice is published when a member connects to a voice channel.
+type UserJoinedVoice struct {
+	Member VoiceMember
+}
+
+// Topic implements BusEvent
+func (e *UserJoinedVoice) Topic() string { return TopicUserJoinedVoice }
+
+// UserMovedVoice is published when a member moves to another voice channel, Stayed is how long they were in From.
+type UserMovedVoice struct {
+	Member VoiceMember // In the new channel.
+	From   string
+	Stayed time.Duration
+}
+
+// Topic implements BusEvent
+func (e *UserMovedVoice) Topic() string { return TopicUserMovedVoice }
+
+// UserLeftVoice is published when a member disconnects from voice, Member is how they were before leaving.
+type UserLeftVoice struct {
+	Member VoiceMember
+	Stayed time.Duration // How long they were in the last channel.
+	Total  time.Duration // How long they were connected.
+}
+
+// Topic implements BusEvent
+func (e *UserLeftVoice) Topic() string { return TopicUserLeftVoice }
+
+// VoiceTracker keeps track of who's in which voice channel and since when, see bot.EnableVoiceTracking
+// Members already connected when the bot starts have their join times set to when the bot saw them first.
+type VoiceTracker struct {
+	// guild ID -> user ID -> member
+	guilds map[string]map[string]*VoiceMember
+	lock   sync.RWMutex
+}
+
+// EnableVoiceTracking starts tracking voice states in bot.Voice and publishing UserJoinedVoice, UserMovedVoice and
+// UserLeftVoice on the event bus, e.g for voice XP or logging. It needs IntentsGuildVoiceStates when intents are set.
+func (bot *Bot) EnableVoiceTracking() *Bot {
+	if bot.Voice != nil {
+		return bot
+	}
+	bot.Voice = &VoiceTracker{guilds: make(map[string]map[string]*VoiceMember)}
+	bot.AddHandler(func(s *discordgo.Session, g *discordgo.GuildCreate) {
+		bot.Voice.seed(g.Guild, time.Now())
+	})
+	bot.AddHandler(func(s *discordgo.Session, g *discordgo.GuildDelete) {
+		bot.Voice.lock.Lock()
+		delete(bot.Voice.guilds, g.ID)
+		bot.Voice.lock.Unlock()
+	})
+	bot.AddHandler(func(s *discordgo.Session, v *discordgo.VoiceStateUpdate) {
+		if event := bot.Voice.update(v.VoiceState, time.Now()); event != nil {
+			bot.Publish(event)
+		}
+	})
+	return bot
+}
+
+// seed replaces a guild's members with the voice states it was received with.
+func (t *VoiceTracker) seed(guild *discordgo.Guild, now time.Time) {
+	members := make(map[string]*VoiceMember, len(guild.VoiceStates))
+	for _, state := range guild.VoiceStates {
+		if state.ChannelID != "" {
+			members[state.UserID] = voiceMember(guild.ID, state, now, now)
+		}
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	// A resumed guild keeps the join times of members who didn't move meanwhile.
+	for id, member := range members {
+		if old, ok := t.guilds[guild.ID][id]; ok && old.ChannelID == member.ChannelID {
+			members[id] = old
+		}
+	}
+	t.guilds[guild.ID] = members
+}
+
+func voiceMember(guildID string, state *discordgo.VoiceState, joined, connected time.Time) *VoiceMember {
+	return &VoiceMember{GuildID: guildID, UserID: state.UserID, ChannelID: state.ChannelID, JoinedAt: joined, ConnectedAt: connected,
+		SelfMute: state.SelfMute, SelfDeaf: state.SelfDeaf, Mute: state.Mute, Deaf: state.Deaf}
+}
+
+// update applies a voice state and returns the event to publish, nil if the member didn't join, move or leave.
+func (t *VoiceTracker) update(state *discordgo.VoiceState, now time.Time) BusEvent {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	members := t.guilds[state.GuildID]
+	if members == nil {
+		members = make(map[string]*VoiceMember)
+		t.guilds[state.GuildID] = members
+	}
+	old, connected := members[state.UserID]
+
+	switch {
+	case state.ChannelID == "":
+		if !connected {
+			return nil
+		}
+		delete(members, state.UserID)
+		return &UserLeftVoice{Member: *old, Stayed: now.Sub(old.JoinedAt), Total: now.Sub(old.ConnectedAt)}
+	case !connected:
+		member := voiceMember(state.GuildID, state, now, now)
+		members[state.UserID] = member
+		return &UserJoinedVoice{Member: *member}
+	case old.ChannelID != state.ChannelID:
+		member := voiceMember(state.GuildID, state, now, old.ConnectedAt)
+		members[state.UserID] = member
+		return &UserMovedVoice{Member: *member, From: old.ChannelID, Stayed: now.Sub(old.JoinedAt)}
+	}
+	// Only muted or deafened.
+	members[state.UserID] = voiceMember(state.GuildID, state, old.JoinedAt, old.ConnectedAt)
+	return nil
+}
+
+// Member returns the voice state of a member, false if they're not connected.
+func (t *VoiceTracker) Member(guildID, userID string) (VoiceMember, bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	member, ok := t.guilds[guildID][userID]
+	if !ok {
+		return VoiceMember{}, false
+	}
+	return *member, true
+}
+
+// Members returns the members in a voice channel, the ones who joined first come first.
+func (t *VoiceTracker) Members(guildID, channelID string) []VoiceMember {
+	t.lock.RLock()
+	var members []VoiceMember
+	for _, member := range t.guilds[guildID] {
+		if member.ChannelID == channelID {
+			members = append(members, *member)
+		}
+	}
+	t.lock.RUnlock()
+	sort.Slice(members, func(i, j int) bool { return members[i].JoinedAt.Before(members[j].JoinedAt) })
+	return members
+}
+
+// Count returns how many members are in a voice channel.
+func (t *VoiceTracker) Count(guildID, channelID string) int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	count := 0
+	for _, member := range t.guilds[guildID] {
+		if member.ChannelID == channelID {
+			count++
+		}
+	}
+	return count
+}
+
+// Channels returns the occupied voice channels of a guild with how many members are in them.
+func (t *VoiceTracker) Channels(guildID string) map[string]int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	channels := make(map[string]int)
+	for _, member := range t.guilds[guildID] {
+		channels[member.ChannelID]++
+	}
+	return channels
+}
+
+// GuildMembers returns everyone connected to voice in a guild.
+func (t *VoiceTracker) GuildMembers(guildID string) []VoiceMember {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	members := make([]VoiceMember, 0, len(t.guilds[guildID]))
+	for _, member := range t.guilds[guildID] {
+		members = append(members, *member)
+	}
+	return members
+}
+
+// Guilds returns the IDs of the guilds where someone is connected to voice.
+func (t *VoiceTracker) Guilds() []string {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	var guilds []string
+	for id, members := range t.guilds {
+		if len(members) > 0 {
+			guilds = append(guilds, id)
+		}
+	}
+	return guilds
+}
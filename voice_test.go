@@ -0,0 +1,34 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"testing"
+	"time"
+)
+
+func TestVoiceTracker(t *testing.T) {
+	tracker := &VoiceTracker{guilds: make(map[string]map[string]*VoiceMember)}
+	start := time.Now()
+	tracker.seed(&discordgo.Guild{ID: "g", VoiceStates: []*discordgo.VoiceState{{UserID: "a", ChannelID: "1"}}}, start)
+
+	if _, ok := tracker.update(&discordgo.VoiceState{GuildID: "g", UserID: "b", ChannelID: "1"}, start.Add(time.Minute)).(*UserJoinedVoice); !ok {
+		t.Errorf("b joining should be a UserJoinedVoice")
+	}
+	if members := tracker.Members("g", "1"); len(members) != 2 || members[0].UserID != "a" {
+		t.Errorf("Members = %+v", members)
+	}
+	if tracker.update(&discordgo.VoiceState{GuildID: "g", UserID: "b", ChannelID: "1", SelfMute: true}, start.Add(2*time.Minute)) != nil {
+		t.Errorf("muting shouldn't be an event")
+	}
+	moved, ok := tracker.update(&discordgo.VoiceState{GuildID: "g", UserID: "a", ChannelID: "2"}, start.Add(3*time.Minute)).(*UserMovedVoice)
+	if !ok || moved.From != "1" || moved.Stayed != 3*time.Minute {
+		t.Errorf("a moving = %+v", moved)
+	}
+	left, ok := tracker.update(&discordgo.VoiceState{GuildID: "g", UserID: "a"}, start.Add(5*time.Minute)).(*UserLeftVoice)
+	if !ok || left.Stayed != 2*time.Minute || left.Total != 5*time.Minute {
+		t.Errorf("a leaving = %+v", left)
+	}
+	if channels := tracker.Channels("g"); len(channels) != 1 || channels["1"] != 1 {
+		t.Errorf("Channels = %v", channels)
+	}
+}
@@ -0,0 +1,284 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VoiceXPConfig is the per-guild config of voice XP, see bot.EnableVoiceXP
+var VoiceXPConfig = NewConfigSchema("voicexp", "XP for time spent in voice channels.").
+	Add("enabled", ConfigBool, "false", "Whether members earn XP in voice channels.").
+	Add("xp", ConfigInt, "10", "XP earned per minute in voice.").
+	Add("alone", ConfigBool, "false", "Whether members alone in a channel earn XP.")
+
+// voiceXPKey is the guild settings key the voice stats are stored under.
+const voiceXPKey = "voicexp.stats"
+
+// TopicVoiceXP is the topic of VoiceXPEarned
+const TopicVoiceXP = "voice.xp"
+
+// VoiceStats is the time a member spent in voice and the XP they earned with it.
+type VoiceStats struct {
+	UserID  string `json:"-"`
+	Minutes int    `json:"minutes"`
+	XP      int    `json:"xp"`
+}
+
+// VoiceXPEarned is published every minute a member earns voice XP, leveling modules add XP to their level.
+type VoiceXPEarned struct {
+	GuildID string
+	UserID  string
+	XP      int        // XP earned this minute.
+	Stats   VoiceStats // The totals including this minute.
+}
+
+// Topic implements BusEvent
+func (e *VoiceXPEarned) Topic() string { return TopicVoiceXP }
+
+type voiceXPTracker struct {
+	// Stats are a read, change and write so guilds are updated one at a time.
+	lock sync.Mutex
+}
+
+// EnableVoiceXP loads the voice command and gives members XP every minute they're in voice, servers turn it on
+// with VoiceXPConfig. Members in the AFK channel, deafened or alone in a channel (unless the server allows it) don't
+// earn anything. It uses the voice tracker, see EnableVoiceTracking.
+func (bot *Bot) EnableVoiceXP() *Bot {
+	if bot.voiceXP != nil {
+		return bot
+	}
+	bot.voiceXP = &voiceXPTracker{}
+	bot.EnableVoiceTracking()
+	bot.AddConfigSchema(VoiceXPConfig)
+	bot.AddDataSubject(voiceXPData(bot))
+	bot.AddCommand(NewCommand("voice", "Fun", voiceXPCommand).
+		SetDescription("Shows the time you spent in voice, use top for the leaderboard.").
+		SetUsage("[action:string]").
+		SetGuildOnly(true).
+		AddAliases("voicetime", "vc"))
+	bot.Scheduler.After(time.Minute, bot.accrueVoiceXP)
+	return bot
+}
+
+// accrueVoiceXP gives a minute of voice XP to every member earning it and schedules the next minute.
+func (bot *Bot) accrueVoiceXP() {
+	bot.Scheduler.After(time.Minute, bot.accrueVoiceXP)
+	for _, guildID := range bot.Voice.Guilds() {
+		if !VoiceXPConfig.GetBool(bot, guildID, "enabled") {
+			continue
+		}
+		afk := ""
+		if guild, err := bot.Session.State.Guild(guildID); err == nil {
+			afk = guild.AfkChannelID
+		}
+		earning := voiceXPEarners(bot.Voice.GuildMembers(guildID), afk, VoiceXPConfig.GetBool(bot, guildID, "alone"))
+		if len(earning) == 0 {
+			continue
+		}
+		if err := bot.addVoiceXP(guildID, earning, VoiceXPConfig.GetInt(bot, guildID, "xp")); err != nil {
+			bot.ErrorHandler(bot, err)
+		}
+	}
+}
+
+// voiceXPEarners returns the members earning XP: not in the AFK channel, not deafened and, unless alone is allowed,
+// with someone else who isn't deafened in their channel.
+func voiceXPEarners(members []VoiceMember, afk string, alone bool) []string {
+	listening := make(map[string]int)
+	for _, member := range members {
+		if !member.SelfDeaf && !member.Deaf {
+			listening[member.ChannelID]++
+		}
+	}
+	var earning []string
+	for _, member := range members {
+		if member.ChannelID == afk || member.SelfDeaf || member.Deaf || (!alone && listening[member.ChannelID] < 2) {
+			continue
+		}
+		earning = append(earning, member.UserID)
+	}
+	return earning
+}
+
+func (bot *Bot) addVoiceXP(guildID string, users []string, xp int) error {
+	bot.voiceXP.lock.Lock()
+	defer bot.voiceXP.lock.Unlock()
+	stats, err := bot.voiceStats(guildID)
+	if err != nil {
+		return err
+	}
+	var events []*VoiceXPEarned
+	for _, id := range users {
+		s := stats[id]
+		s.Minutes++
+		s.XP += xp
+		stats[id] = s
+		events = append(events, &VoiceXPEarned{GuildID: guildID, UserID: id, XP: xp, Stats: VoiceStats{UserID: id, Minutes: s.Minutes, XP: s.XP}})
+	}
+	if err := SetJSON(bot.Settings, guildID, voiceXPKey, stats); err != nil {
+		return err
+	}
+	// Published after saving so a failed save isn't counted by subscribers.
+	for _, event := range events {
+		bot.Publish(event)
+	}
+	return nil
+}
+
+func (bot *Bot) voiceStats(guildID string) (map[string]VoiceStats, error) {
+	stats := make(map[string]VoiceStats)
+	if _, err := GetJSON(bot.Settings, guildID, voiceXPKey, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// VoiceStats returns a member's voice time and XP in a guild.
+func (bot *Bot) VoiceStats(guildID, userID string) (VoiceStats, error) {
+	stats, err := bot.voiceStats(guildID)
+	if err != nil {
+		return VoiceStats{}, err
+	}
+	s := stats[userID]
+	s.UserID = userID
+	return s, nil
+}
+
+// VoiceLeaderboard returns the members with the most voice time of a guild, most first.
+func (bot *Bot) VoiceLeaderboard(guildID string, limit int) ([]VoiceStats, error) {
+	stats, err := bot.voiceStats(guildID)
+	if err != nil {
+		return nil, err
+	}
+	board := make([]VoiceStats, 0, len(stats))
+	for id, s := range stats {
+		s.UserID = id
+		board = append(board, s)
+	}
+	sort.Slice(board, func(i, j int) bool {
+		if board[i].Minutes == board[j].Minutes {
+			return board[i].UserID < board[j].UserID
+		}
+		return board[i].Minutes > board[j].Minutes
+	})
+	if len(board) > limit {
+		board = board[:limit]
+	}
+	return board, nil
+}
+
+// voiceXPData is the data subject of the voice stats, exported by guild ID.
+func voiceXPData(bot *Bot) DataSubject {
+	return &userData{
+		name: "voicexp",
+		export: func(userID string) (interface{}, error) {
+			guilds, err := bot.settingsGuilds()
+			if err != nil {
+				return nil, err
+			}
+			data := make(map[string]VoiceStats)
+			for _, guildID := range guilds {
+				stats, err := bot.voiceStats(guildID)
+				if err != nil {
+					return nil, err
+				}
+				if s, ok := stats[userID]; ok {
+					data[guildID] = s
+				}
+			}
+			if len(data) == 0 {
+				return nil, nil
+			}
+			return data, nil
+		},
+		delete: func(userID string) error {
+			guilds, err := bot.settingsGuilds()
+			if err != nil {
+				return err
+			}
+			bot.voiceXP.lock.Lock()
+			defer bot.voiceXP.lock.Unlock()
+			for _, guildID := range guilds {
+				stats, err := bot.voiceStats(guildID)
+				if err != nil {
+					return err
+				}
+				if _, ok := stats[userID]; !ok {
+					continue
+				}
+				delete(stats, userID)
+				if len(stats) == 0 {
+					err = bot.Settings.Delete(guildID, voiceXPKey)
+				} else {
+					err = SetJSON(bot.Settings, guildID, voiceXPKey, stats)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// ResetVoiceXP clears the voice stats of a guild.
+func (bot *Bot) ResetVoiceXP(guildID string) error {
+	bot.voiceXP.lock.Lock()
+	defer bot.voiceXP.lock.Unlock()
+	return bot.Settings.Delete(guildID, voiceXPKey)
+}
+
+// formatVoiceTime formats minutes like 3h 12m
+func formatVoiceTime(minutes int) string {
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+}
+
+func voiceXPCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+	switch strings.ToLower(ctx.ArgString(0)) {
+	case "":
+		s, err := bot.VoiceStats(ctx.Guild.ID, ctx.Author.ID)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_VOICE_STATS", formatVoiceTime(s.Minutes), ctx.Locale.FormatCount(int64(s.XP)))
+	case "top", "leaderboard", "lb":
+		board, err := bot.VoiceLeaderboard(ctx.Guild.ID, 10)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		if len(board) == 0 {
+			ctx.ReplyLocale("COMMAND_VOICE_NO_STATS")
+			return
+		}
+		lines := make([]string, len(board))
+		for i, s := range board {
+			lines[i] = fmt.Sprintf("**%d.** <@%s> - %s (%s XP)", i+1, s.UserID, formatVoiceTime(s.Minutes), ctx.Locale.FormatCount(int64(s.XP)))
+		}
+		ctx.BuildEmbed(NewEmbed().
+			SetTitle(ctx.Locale.Get("COMMAND_VOICE_LEADERBOARD")).
+			SetDescription(strings.Join(lines, "\n")).
+			SetColor(bot.Color))
+	case "reset":
+		if !ctx.HasPermissions(discordgo.PermissionManageServer) {
+			ctx.ReplyLocale("COMMAND_VOICE_NO_PERMISSION")
+			return
+		}
+		if err := bot.ResetVoiceXP(ctx.Guild.ID); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_VOICE_RESET")
+	default:
+		ctx.ReplyLocale("COMMAND_VOICE_USAGE", ctx.Prefix)
+	}
+}
@@ -0,0 +1,28 @@
+package sapphire
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestVoiceXPEarners(t *testing.T) {
+	members := []VoiceMember{
+		{UserID: "a", ChannelID: "1"},
+		{UserID: "b", ChannelID: "1"},
+		{UserID: "c", ChannelID: "1", SelfDeaf: true},
+		{UserID: "d", ChannelID: "2"},
+		{UserID: "e", ChannelID: "afk"},
+		{UserID: "f", ChannelID: "afk"},
+	}
+	earning := voiceXPEarners(members, "afk", false)
+	sort.Strings(earning)
+	if strings.Join(earning, ",") != "a,b" {
+		t.Errorf("earning = %v", earning)
+	}
+	earning = voiceXPEarners(members, "afk", true)
+	sort.Strings(earning)
+	if strings.Join(earning, ",") != "a,b,d" {
+		t.Errorf("earning alone = %v", earning)
+	}
+}
@@ -0,0 +1,140 @@
+package sapphire
+
+import (
+	"strings"
+	"time"
+)
+
+// warningKeyPrefix is the guild settings key prefix warnings are stored under, followed by the user ID.
+const warningKeyPrefix = "warnings."
+
+// Warning is a warning a member was given by a moderator.
+type Warning struct {
+	ModeratorID string    `json:"moderator_id"` // Empty when it's unknown, e.g for imported warnings.
+	Reason      string    `json:"reason"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Warnings returns the warnings of a member, oldest first.
+func (bot *Bot) Warnings(guildID, userID string) ([]*Warning, error) {
+	var warnings []*Warning
+	if _, err := GetJSON(bot.Settings, guildID, warningKeyPrefix+userID, &warnings); err != nil {
+		return nil, err
+	}
+	return warnings, nil
+}
+
+// Warn adds a warning to a member's history.
+func (bot *Bot) Warn(guildID, userID, moderatorID, reason string) (*Warning, error) {
+	warning := &Warning{ModeratorID: moderatorID, Reason: reason, CreatedAt: time.Now()}
+	_, err := bot.addWarnings(guildID, userID, []*Warning{warning})
+	return warning, err
+}
+
+// addWarnings adds warnings to a member's history in order of their time, warnings already in it are skipped
+// so importing the same export twice doesn't add them again. Returns how many were added.
+func (bot *Bot) addWarnings(guildID, userID string, warnings []*Warning) (int, error) {
+	unlock, err := bot.Lock("warnings:" + guildID + ":" + userID)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	history, err := bot.Warnings(guildID, userID)
+	if err != nil {
+		return 0, err
+	}
+	added := 0
+	for _, warning := range warnings {
+		if containsWarning(history, warning) {
+			continue
+		}
+		// Imported warnings are older than the ones given since, keep the history in order.
+		i := len(history)
+		for i > 0 && history[i-1].CreatedAt.After(warning.CreatedAt) {
+			i--
+		}
+		history = append(history[:i], append([]*Warning{warning}, history[i:]...)...)
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	return added, SetJSON(bot.Settings, guildID, warningKeyPrefix+userID, history)
+}
+
+func containsWarning(warnings []*Warning, warning *Warning) bool {
+	for _, w := range warnings {
+		if w.ModeratorID == warning.ModeratorID && w.Reason == warning.Reason && w.CreatedAt.Equal(warning.CreatedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// warningData is the data subject of the warnings members were given, keyed by guild ID.
+// Warnings a user gave as a moderator stay with the warned member, only their ID is removed from them.
+func warningData(bot *Bot) DataSubject {
+	return &userData{
+		name: "warnings",
+		export: func(userID string) (interface{}, error) {
+			guilds, err := bot.settingsGuilds()
+			if err != nil {
+				return nil, err
+			}
+			res := make(map[string][]*Warning)
+			for _, guildID := range guilds {
+				warnings, err := bot.Warnings(guildID, userID)
+				if err != nil {
+					return nil, err
+				}
+				if len(warnings) > 0 {
+					res[guildID] = warnings
+				}
+			}
+			if len(res) == 0 {
+				return nil, nil
+			}
+			return res, nil
+		},
+		delete: func(userID string) error {
+			guilds, err := bot.settingsGuilds()
+			if err != nil {
+				return err
+			}
+			it := bot.Settings.(SettingsIterator)
+			for _, guildID := range guilds {
+				if err := bot.Settings.Delete(guildID, warningKeyPrefix+userID); err != nil {
+					return err
+				}
+				keys, err := it.Keys(guildID)
+				if err != nil {
+					return err
+				}
+				for _, key := range keys {
+					if !strings.HasPrefix(key, warningKeyPrefix) {
+						continue
+					}
+					var warnings []*Warning
+					if ok, err := GetJSON(bot.Settings, guildID, key, &warnings); err != nil {
+						return err
+					} else if !ok {
+						continue
+					}
+					changed := false
+					for _, warning := range warnings {
+						if warning.ModeratorID == userID {
+							warning.ModeratorID = ""
+							changed = true
+						}
+					}
+					if changed {
+						if err := SetJSON(bot.Settings, guildID, key, warnings); err != nil {
+							return err
+						}
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,80 @@
+package sapphire
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"sync"
+)
+
+// WebhookName is the name of webhooks the bot creates to send messages as other users.
+const WebhookName = "Sapphire"
+
+type webhookCache struct {
+	// channel ID -> webhook
+	hooks map[string]*discordgo.Webhook
+	// IDs of our webhooks, messages from them are ignored by modules relaying messages.
+	own  map[string]bool
+	lock sync.Mutex
+}
+
+// ChannelWebhook returns the bot's webhook in a channel, creating it if there is none.
+// Webhooks are cached, the bot needs the Manage Webhooks permission to look them up or create them.
+func (bot *Bot) ChannelWebhook(channelID string) (*discordgo.Webhook, error) {
+	bot.webhooks.lock.Lock()
+	defer bot.webhooks.lock.Unlock()
+	if hook, ok := bot.webhooks.hooks[channelID]; ok {
+		return hook, nil
+	}
+
+	hooks, err := bot.Session.ChannelWebhooks(channelID)
+	if err != nil {
+		return nil, err
+	}
+	var hook *discordgo.Webhook
+	for _, h := range hooks {
+		// Webhooks made by other bots or users don't come with a token for us.
+		if h.User != nil && h.User.ID == bot.Session.State.User.ID && h.Token != "" {
+			hook = h
+			break
+		}
+	}
+	if hook == nil {
+		hook, err = bot.Session.WebhookCreate(channelID, WebhookName, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+	bot.webhooks.hooks[channelID] = hook
+	bot.webhooks.own[hook.ID] = true
+	return hook, nil
+}
+
+// SendWebhook sends a message through the bot's webhook in the channel, e.g to post with someone else's name and avatar.
+// Mentions are not parsed unless params sets AllowedMentions.
+func (bot *Bot) SendWebhook(channelID string, params *discordgo.WebhookParams) (*discordgo.Message, error) {
+	if params.AllowedMentions == nil {
+		params.AllowedMentions = &discordgo.MessageAllowedMentions{Parse: []discordgo.AllowedMentionType{}}
+	}
+	hook, err := bot.ChannelWebhook(channelID)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := bot.Session.WebhookExecute(hook.ID, hook.Token, true, params)
+	if err == nil {
+		return msg, nil
+	}
+	// Someone might have deleted the webhook, forget it and try once more with a fresh one.
+	bot.webhooks.lock.Lock()
+	delete(bot.webhooks.hooks, channelID)
+	bot.webhooks.lock.Unlock()
+	if hook, err = bot.ChannelWebhook(channelID); err != nil {
+		return nil, err
+	}
+	return bot.Session.WebhookExecute(hook.ID, hook.Token, true, params)
+}
+
+// IsOwnWebhook checks if a webhook ID belongs to one of the bot's webhooks used by SendWebhook.
+func (bot *Bot) IsOwnWebhook(webhookID string) bool {
+	bot.webhooks.lock.Lock()
+	defer bot.webhooks.lock.Unlock()
+	return bot.webhooks.own[webhookID]
+}
@@ -0,0 +1,303 @@
+package sapphire
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// MaxFilteredWords is the maximum amount of words of a guild's word filter.
+var MaxFilteredWords = 500
+
+// confusables maps look-alike letters of other scripts and accented letters to the plain latin letter.
+var confusables = map[rune]string{
+	// Cyrillic
+	'а': "a", 'в': "b", 'е': "e", 'ё': "e", 'к': "k", 'м': "m", 'н': "h", 'о': "o", 'р': "p", 'с': "c", 'т': "t",
+	'у': "y", 'х': "x", 'і': "i", 'ї': "i", 'ј': "j", 'ѕ': "s", 'һ': "h", 'ԁ': "d", 'ԛ': "q", 'ԝ': "w",
+	// Greek
+	'α': "a", 'β': "b", 'ε': "e", 'η': "n", 'ι': "i", 'κ': "k", 'ν': "v", 'ο': "o", 'ρ': "p", 'τ': "t", 'υ': "u",
+	'χ': "x", 'ω': "w",
+	// Latin letters with diacritics or ligatures
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a", 'æ': "ae",
+	'ç': "c", 'ć': "c", 'č': "c", 'ĉ': "c", 'ċ': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ĕ': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ĝ': "g", 'ğ': "g", 'ġ': "g", 'ģ': "g", 'ĥ': "h", 'ħ': "h",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ĩ': "i", 'ī': "i", 'ĭ': "i", 'į': "i", 'ı': "i",
+	'ĵ': "j", 'ķ': "k", 'ĺ': "l", 'ļ': "l", 'ľ': "l", 'ŀ': "l", 'ł': "l",
+	'ñ': "n", 'ń': "n", 'ņ': "n", 'ň': "n", 'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o",
+	'ŏ': "o", 'ő': "o", 'œ': "oe", 'ŕ': "r", 'ŗ': "r", 'ř': "r", 'ś': "s", 'ŝ': "s", 'ş': "s", 'š': "s", 'ß': "ss",
+	'ţ': "t", 'ť': "t", 'ŧ': "t", 'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ũ': "u", 'ū': "u", 'ŭ': "u", 'ů': "u",
+	'ű': "u", 'ų': "u", 'ŵ': "w", 'ý': "y", 'ÿ': "y", 'ŷ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// leetspeak maps digits used as letters.
+var leetspeak = map[rune]string{
+	'0': "o", '1': "i", '3': "e", '4': "a", '5': "s", '7': "t", '8': "b", '9': "g",
+}
+
+// leetSymbols maps symbols used as letters, only before a letter or digit so punctuation after a word stays.
+var leetSymbols = map[rune]string{
+	'@': "a", '$': "s", '!': "i", '|': "i", '+': "t", '€': "e",
+}
+
+// NormalizeText lowercases text with the rules of the locale, e.g Turkish dotted and dotless i, and replaces
+// confusables, diacritics, fullwidth letters and leetspeak with plain latin letters. Invisible characters are removed.
+func NormalizeText(text, locale string) string {
+	switch strings.SplitN(strings.ToLower(locale), "-", 2)[0] {
+	case "tr", "az":
+		text = strings.ToLowerSpecial(unicode.TurkishCase, text)
+	default:
+		text = strings.ToLower(text)
+	}
+	runes := []rune(text)
+	var b strings.Builder
+	for i, r := range runes {
+		switch {
+		case r >= 'ａ' && r <= 'ｚ':
+			b.WriteRune(r - 'ａ' + 'a')
+		case r >= '０' && r <= '９':
+			b.WriteString(leetspeak[r-'０'+'0'])
+		case unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Cf, r):
+			// Combining marks and zero width characters.
+		default:
+			if s, ok := confusables[r]; ok {
+				b.WriteString(s)
+			} else if s, ok := leetspeak[r]; ok {
+				b.WriteString(s)
+			} else if s, ok := leetSymbols[r]; ok && i+1 < len(runes) && (unicode.IsLetter(runes[i+1]) || unicode.IsDigit(runes[i+1])) {
+				b.WriteString(s)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+// normalizedWords splits normalized text into it's words.
+func normalizedWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+}
+
+// WordList matches text against banned words after normalizing both with NormalizeText.
+// A word matches whole words only, unless it starts or ends with * to also match inside other words,
+// e.g "*bad" matches "notbad" and "bad*" matches "badly". Words with spaces match phrases.
+type WordList struct {
+	whole  map[string]bool
+	inside []string // Words matched inside others, with the separators of the text removed.
+	prefix []string
+	suffix []string
+	phrase []string
+}
+
+// NewWordList creates a word list.
+func NewWordList(words ...string) *WordList {
+	list := &WordList{whole: make(map[string]bool)}
+	for _, word := range words {
+		start, end := strings.HasPrefix(word, "*"), strings.HasSuffix(word, "*")
+		normalized := strings.Join(normalizedWords(NormalizeText(strings.Trim(word, "*"), "")), " ")
+		switch {
+		case normalized == "":
+		case start && end:
+			list.inside = append(list.inside, strings.Replace(normalized, " ", "", -1))
+		case start:
+			list.suffix = append(list.suffix, normalized)
+		case end:
+			list.prefix = append(list.prefix, normalized)
+		case strings.Contains(normalized, " "):
+			list.phrase = append(list.phrase, " "+normalized+" ")
+		default:
+			list.whole[normalized] = true
+		}
+	}
+	return list
+}
+
+// Match returns the first banned word in the text or an empty string, locale picks the lowercasing rules.
+func (l *WordList) Match(text, locale string) string {
+	words := normalizedWords(NormalizeText(text, locale))
+	for _, word := range words {
+		if l.whole[word] {
+			return word
+		}
+		for _, prefix := range l.prefix {
+			if strings.HasPrefix(word, prefix) {
+				return prefix
+			}
+		}
+		for _, suffix := range l.suffix {
+			if strings.HasSuffix(word, suffix) {
+				return suffix
+			}
+		}
+	}
+	if len(l.phrase) > 0 {
+		joined := " " + strings.Join(words, " ") + " "
+		for _, phrase := range l.phrase {
+			if strings.Contains(joined, phrase) {
+				return strings.TrimSpace(phrase)
+			}
+		}
+	}
+	if len(l.inside) > 0 {
+		// Without separators so "b.a.d" or "b a d" are caught too.
+		joined := strings.Join(words, "")
+		for _, inside := range l.inside {
+			if strings.Contains(joined, inside) {
+				return inside
+			}
+		}
+	}
+	return ""
+}
+
+// The settings key of a guild's filtered words.
+const wordFilterKey = "automod.words"
+
+type wordFilterTracker struct {
+	actions AutomodAction
+	// guild ID -> word list
+	lists map[string]*WordList
+	lock  sync.Mutex
+}
+
+// EnableWordFilter adds an automod rule removing messages with words banned by each guild with the words command.
+// actions is what's done about matching messages, 0 deletes and warns.
+func (bot *Bot) EnableWordFilter(actions AutomodAction) *Bot {
+	if bot.wordFilter != nil {
+		return bot
+	}
+	if actions == 0 {
+		actions = AutomodDelete | AutomodWarn
+	}
+	bot.wordFilter = &wordFilterTracker{actions: actions, lists: make(map[string]*WordList)}
+	bot.AddAutomodRule(NewAutomodRule("words", wordFilterRule))
+	bot.AddCommand(NewCommand("words", "Moderation", wordFilterCommand).
+		SetDescription("Manages the words automod removes messages for, use *word* to also match inside other words.").
+		SetUsage("<action:string> [words:string...]").
+		SetRequiredPermissions(discordgo.PermissionManageServer).
+		SetGuildOnly(true))
+	return bot
+}
+
+// FilteredWords returns the banned words of a guild, sorted.
+func (bot *Bot) FilteredWords(guildID string) []string {
+	var words []string
+	if _, err := GetJSON(bot.Settings, guildID, wordFilterKey, &words); err != nil {
+		bot.ErrorHandler(bot, err)
+	}
+	return words
+}
+
+// SetFilteredWords replaces the banned words of a guild.
+func (bot *Bot) SetFilteredWords(guildID string, words []string) error {
+	if len(words) > MaxFilteredWords {
+		return fmt.Errorf("A server can have at most %d filtered words.", MaxFilteredWords)
+	}
+	sort.Strings(words)
+	var err error
+	if len(words) == 0 {
+		err = bot.Settings.Delete(guildID, wordFilterKey)
+	} else {
+		err = SetJSON(bot.Settings, guildID, wordFilterKey, words)
+	}
+	if err != nil {
+		return err
+	}
+	if bot.wordFilter != nil {
+		bot.wordFilter.lock.Lock()
+		bot.wordFilter.lists[guildID] = NewWordList(words...)
+		bot.wordFilter.lock.Unlock()
+	}
+	bot.filtersChanged(guildID)
+	return nil
+}
+
+// wordList returns the word list of a guild, building it once.
+func (bot *Bot) wordList(guildID string) *WordList {
+	bot.wordFilter.lock.Lock()
+	defer bot.wordFilter.lock.Unlock()
+	list, ok := bot.wordFilter.lists[guildID]
+	if !ok {
+		list = NewWordList(bot.FilteredWords(guildID)...)
+		bot.wordFilter.lists[guildID] = list
+	}
+	return list
+}
+
+func wordFilterRule(ctx *AutomodContext) *Violation {
+	bot := ctx.Bot
+	locale := bot.LocaleFor(ctx.Guild.ID, ctx.Channel.ID)
+	if word := bot.wordList(ctx.Guild.ID).Match(ctx.Text(), locale.Name); word != "" {
+		return &Violation{Reason: locale.Get("AUTOMOD_WORDS"), Actions: bot.wordFilter.actions}
+	}
+	return nil
+}
+
+func wordFilterCommand(ctx *CommandContext) {
+	bot := ctx.Bot
+	words := bot.FilteredWords(ctx.Guild.ID)
+	args := strings.Fields(strings.ToLower(ctx.ArgString(1)))
+	switch strings.ToLower(ctx.Arg(0).AsString()) {
+	case "list":
+		if len(words) == 0 {
+			ctx.ReplyLocale("COMMAND_WORDS_NONE", ctx.Prefix)
+			return
+		}
+		// Spoilered so listing them isn't what staff gets warned about.
+		ctx.Reply("||" + Escape(strings.Join(words, ", ")) + "||")
+	case "add":
+		if len(args) == 0 {
+			ctx.ReplyLocale("COMMAND_WORDS_USAGE", ctx.Prefix)
+			return
+		}
+		if ctx.HasFlag("phrase") {
+			args = []string{strings.Join(args, " ")}
+		}
+		existing := make(map[string]bool)
+		for _, word := range words {
+			existing[word] = true
+		}
+		for _, word := range args {
+			if !existing[word] {
+				existing[word] = true
+				words = append(words, word)
+			}
+		}
+		if err := bot.SetFilteredWords(ctx.Guild.ID, words); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_WORDS_ADDED", len(words))
+	case "remove":
+		if len(args) == 0 {
+			ctx.ReplyLocale("COMMAND_WORDS_USAGE", ctx.Prefix)
+			return
+		}
+		if ctx.HasFlag("phrase") {
+			args = []string{strings.Join(args, " ")}
+		}
+		remove := make(map[string]bool)
+		for _, word := range args {
+			remove[word] = true
+		}
+		kept := make([]string, 0, len(words))
+		for _, word := range words {
+			if !remove[word] {
+				kept = append(kept, word)
+			}
+		}
+		if err := bot.SetFilteredWords(ctx.Guild.ID, kept); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.ReplyLocale("COMMAND_WORDS_REMOVED", len(words)-len(kept))
+	default:
+		ctx.ReplyLocale("COMMAND_WORDS_USAGE", ctx.Prefix)
+	}
+}
@@ -0,0 +1,29 @@
+package sapphire
+
+import (
+	"testing"
+)
+
+func TestWordList(t *testing.T) {
+	list := NewWordList("bad", "*scam*", "free nitro", "spam*")
+	for text, expected := range map[string]string{
+		"this is BAD!":         "bad",
+		"this is b@d":          "bad",
+		"this is b\u0430d":     "bad", // Cyrillic a
+		"this is bäd":          "bad",
+		"this is ｂａｄ":          "bad",
+		"this is b\u200bad":    "bad", // Zero width space
+		"badge":                "",
+		"such a s.c.a.m":       "scam",
+		"get FREE   nitro now": "free nitro",
+		"spammer":              "spam",
+		"nothing to see":       "",
+	} {
+		if got := list.Match(text, "en-US"); got != expected {
+			t.Errorf("Expected %q to match %q, got %q", text, expected, got)
+		}
+	}
+	if got := NormalizeText("DİYARBAKIR", "tr"); got != "diyarbakir" {
+		t.Errorf("Expected Turkish lowercasing, got %q", got)
+	}
+}